@@ -17,6 +17,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -25,6 +26,81 @@ type HorizontalRunnerAutoscalerSpec struct {
 	// ScaleTargetRef sis the reference to scaled resource like RunnerDeployment
 	ScaleTargetRef ScaleTargetRef `json:"scaleTargetRef,omitempty"`
 
+	// AdditionalScaleTargetRefs are the references to the scale targets in the same namespace that are scaled to the same
+	// desired replicas as ScaleTargetRef, like a RunnerDeployment of arm runners paired with one of x86 runners.
+	// The metrics are computed against ScaleTargetRef only, and no scale target is scaled while any of them doesn't exist.
+	// +optional
+	AdditionalScaleTargetRefs []ScaleTargetRef `json:"additionalScaleTargetRefs,omitempty"`
+
+	// GitHubAppInstallation is the installation of the GitHub App to authenticate as when calling GitHub API for autoscaling,
+	// which is useful when the controller manages runners of many organizations under a single GitHub App.
+	// Defaults to the installation the controller is configured with.
+	// +optional
+	GitHubAppInstallation *GitHubAppInstallationRef `json:"githubAppInstallation,omitempty"`
+
+	// GitHubAPICredentialsFrom is the source of the credentials to authenticate as when calling GitHub API for autoscaling,
+	// so that e.g. the HorizontalRunnerAutoscalers of different teams use their own tokens.
+	// It can't be set along with GitHubAppInstallation. Defaults to the credentials the controller is configured with.
+	// +optional
+	GitHubAPICredentialsFrom *GitHubAPICredentialsFrom `json:"githubAPICredentialsFrom,omitempty"`
+
+	// ScaleDownReadinessGate prevents scaling down while the number of ready replicas of the scale target
+	// differs from its desired replicas, e.g. while runners are still being registered, so that scale changes
+	// don't compound on an in-flight one.
+	// MaxReplicas is still honored as a hard limit.
+	// +optional
+	ScaleDownReadinessGate bool `json:"scaleDownReadinessGate,omitempty"`
+
+	// MaxPendingRunnerPods enables limiting the scale up to one replica above the current desired replicas
+	// while at least this number of runner pods of the scale target are Pending, e.g. because the nodes can't fit more runners,
+	// so that replicas that can't be scheduled aren't requested up to MaxReplicas.
+	// The limit is lifted once the pending pods are scheduled.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	MaxPendingRunnerPods *int `json:"maxPendingRunnerPods,omitempty"`
+
+	// MaxScaleUpCount is the maximum number of replicas added to the scale target per reconciliation, so that
+	// a burst of demand doesn't overwhelm the cluster autoscaler with pending runner pods.
+	// Capacity reservations are added on top of it as they represent committed work. MinReplicas still applies.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	MaxScaleUpCount *int `json:"maxScaleUpCount,omitempty"`
+
+	// BurstMaxReplicas is the ceiling up to which the replicas can exceed MaxReplicas while burst credits remain,
+	// so that short spikes of demand are absorbed while sustained over-scaling is still limited by MaxReplicas.
+	// The credits are counted in replica-seconds. Each replica above MaxReplicas consumes one credit per second,
+	// and the credits refill at the constant rate set by BurstRefillSeconds, up to the capacity of
+	// (BurstMaxReplicas - MaxReplicas) * BurstDurationSeconds.
+	// It requires MaxReplicas, and must be greater than or equal to it.
+	// +optional
+	BurstMaxReplicas *int `json:"burstMaxReplicas,omitempty"`
+
+	// BurstDurationSeconds is how long the full credits last while all the replicas up to BurstMaxReplicas are used.
+	// Defaults to 600.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	BurstDurationSeconds *int `json:"burstDurationSeconds,omitempty"`
+
+	// BurstRefillSeconds is how long the exhausted credits take to refill, which sets the refill rate to
+	// the capacity divided by it per second.
+	// Defaults to 3600.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	BurstRefillSeconds *int `json:"burstRefillSeconds,omitempty"`
+
+	// Weight is the relative share of the controller-wide budget of replicas, set via the --global-max-replicas flag,
+	// allocated to this HorizontalRunnerAutoscaler.
+	// Defaults to 1.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Weight *int `json:"weight,omitempty"`
+
+	// PolicyRef is the reference to a ConfigMap in the same namespace whose `minReplicas`, `maxReplicas`, and
+	// `scaleDownDelaySeconds` keys override the corresponding fields of this spec, so that e.g. a platform team
+	// can manage them centrally.
+	// +optional
+	PolicyRef *PolicyRef `json:"policyRef,omitempty"`
+
 	// MinReplicas is the minimum number of replicas the deployment is allowed to scale
 	// +optional
 	MinReplicas *int `json:"minReplicas,omitempty"`
@@ -33,15 +109,146 @@ type HorizontalRunnerAutoscalerSpec struct {
 	// +optional
 	MaxReplicas *int `json:"maxReplicas,omitempty"`
 
+	// MinReplicasRamp raises the minimum replicas from MinReplicas toward MinReplicasRamp.MaxReplicas the longer the replicas
+	// computed by the metrics continuously exceed MinReplicas, so that a warm pool grows during active periods.
+	// The minimum replicas are reset to MinReplicas once the computed replicas drop to MinReplicas or below.
+	// +optional
+	MinReplicasRamp *MinReplicasRamp `json:"minReplicasRamp,omitempty"`
+
 	// ScaleDownDelaySecondsAfterScaleUp is the approximate delay for a scale down followed by a scale up
 	// Used to prevent flapping (down->up->down->... loop)
 	// +optional
 	ScaleDownDelaySecondsAfterScaleUp *int `json:"scaleDownDelaySecondsAfterScaleOut,omitempty"`
 
-	// Metrics is the collection of various metric targets to calculate desired number of runners
+	// ScaleDownDelayAnchor is the time ScaleDownDelaySecondsAfterScaleUp is measured from.
+	// LastScaleOut measures it from the last successful scale out, and LastBusy from the last time the number of busy runners dropped,
+	// so that the capacity is kept while work recently happened rather than while it was recently scaled.
+	// Defaults to LastScaleOut.
+	// +optional
+	// +kubebuilder:validation:Enum=LastScaleOut;LastBusy
+	ScaleDownDelayAnchor string `json:"scaleDownDelayAnchor,omitempty"`
+
+	// ScaleDownDecayHalfLifeSeconds makes the desired replicas decay exponentially from the desired replicas at the last scale out
+	// toward the replicas computed by the metrics, halving the difference every ScaleDownDecayHalfLifeSeconds,
+	// instead of holding them until ScaleDownDelaySecondsAfterScaleUp elapses and then dropping at once.
+	// ScaleDownDelaySecondsAfterScaleUp and ScaleDownDelayAnchor are ignored when it's set. MinReplicas still applies.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	ScaleDownDecayHalfLifeSeconds *int `json:"scaleDownDecayHalfLifeSeconds,omitempty"`
+
+	// ScaleDownStabilizationWindowSeconds is the window in which the desired replicas recommended by the metrics are remembered,
+	// so that a scale down goes no lower than the largest recommendation within the window, like the stabilization window of
+	// the Kubernetes HorizontalPodAutoscaler. It's applied after ScaleDownDelaySecondsAfterScaleUp and ScaleDownDecayHalfLifeSeconds.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	ScaleDownStabilizationWindowSeconds *int `json:"scaleDownStabilizationWindowSeconds,omitempty"`
+
+	// ScaleDownStabilization limits how fast the scale target is scaled down, so that runners are drained gradually.
+	// +optional
+	ScaleDownStabilization *ScaleDownStabilization `json:"scaleDownStabilization,omitempty"`
+
+	// ScaleDownGraceSeconds is the number of seconds to defer a scale down for since the number of busy runners last dropped,
+	// so that the runners that just finished jobs can be reused by incoming jobs rather than being removed.
+	// It's applied in addition to ScaleDownDelaySecondsAfterScaleUp, so a scale down happens only after both elapse.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	ScaleDownGraceSeconds *int `json:"scaleDownGraceSeconds,omitempty"`
+
+	// MaxScaleDownStallSeconds is the maximum number of seconds a scale down computed by the metrics can be deferred for
+	// by ScaleDownDelaySecondsAfterScaleUp, ScaleDownGraceSeconds, ScaleDownReadinessGate, and TolerancePercent.
+	// Once it elapses since the scale down started being deferred, the computed replicas are applied regardless of them,
+	// so that the replicas never stay elevated forever due to repeated computations near a boundary.
+	// Zero or omitted disables it.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxScaleDownStallSeconds *int `json:"maxScaleDownStallSeconds,omitempty"`
+
+	// RunnerStartupGraceSeconds is the number of seconds since the creation of a runner for which the runner isn't counted as idle
+	// by the PercentageRunnersBusy metric, so that the runners just added by a scale up aren't removed by a scale down
+	// before they register and pick up jobs.
+	// Defaults to 120. Set to 0 to count every runner.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	RunnerStartupGraceSeconds *int `json:"runnerStartupGraceSeconds,omitempty"`
+
+	// RunnerIdleTimeoutSeconds is the number of seconds after which a runner of the scale target exits on its own
+	// when it hasn't picked up any job, so that idle runners don't linger. It's propagated to the runners
+	// as the RUNNER_IDLE_TIMEOUT env var of the scale target's runner template, which rolls the runners out on a change.
+	// The controller recreates the runners that exited unless the scale target is scaled down.
+	// The env var is removed once it's unset.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	RunnerIdleTimeoutSeconds *int `json:"runnerIdleTimeoutSeconds,omitempty"`
+
+	// ScaleOutScheduling is the scheduling hints stamped onto the runner template of the scale targets on scale out,
+	// like the node selector and the tolerations for GPU nodes.
+	// They're merged into the ones of the scale target, whose own node selector keys and tolerations are kept as is.
+	// +optional
+	ScaleOutScheduling *ScaleOutScheduling `json:"scaleOutScheduling,omitempty"`
+
+	// TolerancePercent is the percentage of the current replicas within which a change of the desired replicas is ignored,
+	// so that the replicas don't oscillate when the metric hovers around a boundary.
+	// For example, with 10, scaling from 10 to 11 replicas is ignored while scaling from 10 to 13 replicas is not.
+	// Capacity reservations, MinReplicas and MaxReplicas are honored regardless of the tolerance.
+	// Defaults to 0, which disables the tolerance.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	TolerancePercent int `json:"tolerancePercent,omitempty"`
+
+	// ScaleUpDelaySeconds is the approximate delay for a scale up followed by another scale up.
+	// Used to prevent a brief spike of demand from rapidly scaling up the runners.
+	// Scale downs and capacity reservations are not affected by this delay.
+	// +optional
+	ScaleUpDelaySeconds *int `json:"scaleUpDelaySeconds,omitempty"`
+
+	// QueueGrowthPanic scales the scale target straight to MaxReplicas when the number of queued workflow jobs keeps growing
+	// despite scaling, which is usually a sign of stuck runners. It's detected from the queue depth observed by
+	// the TotalNumberOfQueuedAndInProgressWorkflowRuns metric, and the scale is back to the metrics once the queue stops growing.
+	// +optional
+	QueueGrowthPanic *QueueGrowthPanic `json:"queueGrowthPanic,omitempty"`
+
+	// CacheDurationSeconds is the duration for which the desired replicas computed from the metrics is cached.
+	// It overrides the controller-wide cache duration. Set to 0 for disabling the cache.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	CacheDurationSeconds *int `json:"cacheDurationSeconds,omitempty"`
+
+	// ScaleMode is how the desired replicas are computed.
+	// Metrics computes them from Metrics on each reconciliation, on top of which the capacity reservations added by the webhook-based autoscaler are applied.
+	// EventsOnly skips Metrics entirely and derives them from MinReplicas plus the active capacity reservations,
+	// so that the webhook-driven HorizontalRunnerAutoscaler doesn't poll GitHub API at all. It requires ScaleUpTriggers or ColdStartReservation.
+	// Defaults to Metrics.
+	// +optional
+	// +kubebuilder:validation:Enum=Metrics;EventsOnly
+	ScaleMode string `json:"scaleMode,omitempty"`
+
+	// Metrics is the collection of various metric targets to calculate desired number of runners.
+	// Each metric is evaluated independently and the largest number of desired runners wins,
+	// unless MetricAggregation is WeightedSum or MetricExpression is set.
 	// +optional
 	Metrics []MetricSpec `json:"metrics,omitempty"`
 
+	// MetricAggregation is how the desired replicas computed by the metrics are combined.
+	// Max takes the largest one. WeightedSum adds up the replicas demanded by the metrics, each multiplied by its Weight,
+	// e.g. for a pool of runners serving both the organization-wide queue and a few priority repositories.
+	// The sum is rounded up and bounded by MinReplicas and MaxReplicas. Every metric must succeed for the desired replicas to be updated,
+	// while a skipped one adds nothing. HistoricalDesiredReplicas and OfflineRunners metrics are applied to the sum as usual.
+	// It can't be WeightedSum along with MetricExpression. Defaults to Max.
+	// +optional
+	// +kubebuilder:validation:Enum=Max;WeightedSum
+	MetricAggregation string `json:"metricAggregation,omitempty"`
+
+	// MetricExpression combines the desired replicas computed by the named metrics into the desired replicas,
+	// in place of picking the largest one, like `max(queued, ceil(busy / 0.7))`.
+	// It supports numbers, the names of the metrics, busyRunners and queueDepth as observed by the metrics,
+	// the operators +, -, * and /, parentheses, and the functions max, min, ceil and floor.
+	// The result is rounded up and bounded by MinReplicas and MaxReplicas.
+	// Every metric referenced by the expression must succeed for the desired replicas to be updated.
+	// HistoricalDesiredReplicas and OfflineRunners metrics are applied to the result as usual, and can't be referenced.
+	// +optional
+	MetricExpression string `json:"metricExpression,omitempty"`
+
 	// ScaleUpTriggers is an experimental feature to increase the desired replicas by 1
 	// on each webhook requested received by the webhookBasedAutoscaler.
 	//
@@ -54,6 +261,146 @@ type HorizontalRunnerAutoscalerSpec struct {
 	ScaleUpTriggers []ScaleUpTrigger `json:"scaleUpTriggers,omitempty"`
 
 	CapacityReservations []CapacityReservation `json:"capacityReservations,omitempty" patchStrategy:"merge" patchMergeKey:"name"`
+
+	// HoldCapacityReservationsWhileBusy keeps expired capacity reservations in effect while the runners of the scale target
+	// remain busy, so that e.g. a long-running job outliving its reservation doesn't result in a premature scale down.
+	// Expired reservations are held from the most recently expired one, as long as the busy runners outnumber the replicas
+	// reserved by the reservations held so far.
+	// Enabling it results in an extra GitHub API call to list runners on each reconciliation while any reservation has expired.
+	// +optional
+	HoldCapacityReservationsWhileBusy bool `json:"holdCapacityReservationsWhileBusy,omitempty"`
+
+	// MaxCapacityReservationReplicas is the maximum number of replicas added by the capacity reservations in total,
+	// so that e.g. a misbehaving webhook client can't inflate the replicas up to MaxReplicas.
+	// It's applied before MaxReplicas, which is still honored as the overall limit.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxCapacityReservationReplicas *int `json:"maxCapacityReservationReplicas,omitempty"`
+
+	// ColdStartReservation makes the webhook-based autoscaler add a short capacity reservation on each queued workflow_job event
+	// while the scale target is scaled to zero, so that the first runner is started without waiting for the next sync of the metrics.
+	// It's ignored when the HorizontalRunnerAutoscaler has a workflowJob scale-up trigger, which already adds a reservation per job.
+	// +optional
+	ColdStartReservation *ColdStartReservation `json:"coldStartReservation,omitempty"`
+
+	// DryRun makes the controller compute the desired replicas and record it in the status without actually scaling the scale target.
+	// Useful for observing scaling decisions before enabling autoscaling.
+	// +optional
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// ScheduledOverrides is the list of ScheduledOverride.
+	// It can be used to override a few fields of HorizontalRunnerAutoscalerSpec on schedule.
+	// When two or more overrides are active at the same time, the one defined earlier in the list wins.
+	// +optional
+	ScheduledOverrides []ScheduledOverride `json:"scheduledOverrides,omitempty"`
+}
+
+type MinReplicasRamp struct {
+	// MaxReplicas is the minimum replicas at which the ramp stops.
+	// MaxReplicas of the HorizontalRunnerAutoscaler is still honored as the hard limit.
+	// +kubebuilder:validation:Minimum=0
+	MaxReplicas int `json:"maxReplicas"`
+
+	// StepReplicas is the number of replicas added to the minimum replicas per StepSeconds of continuous demand.
+	// Defaults to 1.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	StepReplicas *int `json:"stepReplicas,omitempty"`
+
+	// StepSeconds is the number of seconds of continuous demand per step of the ramp.
+	// +kubebuilder:validation:Minimum=1
+	StepSeconds int `json:"stepSeconds"`
+}
+
+type QueueGrowthPanic struct {
+	// ConsecutiveIncreases is the number of consecutive reconciliations observing the queue depth increase
+	// that triggers the scale to MaxReplicas.
+	// +kubebuilder:validation:Minimum=1
+	ConsecutiveIncreases int `json:"consecutiveIncreases"`
+}
+
+type ScaleDownStabilization struct {
+	// MaxScaleDownCount is the maximum number of replicas removed from the scale target per reconciliation.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	MaxScaleDownCount *int `json:"maxScaleDownCount,omitempty"`
+}
+
+// ScheduledOverride can be used to override a few fields of HorizontalRunnerAutoscalerSpec on schedule.
+// A schedule can optionally be recurring, so that the corresponding override happens every day, week, month, or year.
+type ScheduledOverride struct {
+	// StartTime is the time at which the first override starts.
+	StartTime metav1.Time `json:"startTime"`
+
+	// EndTime is the time at which the first override ends.
+	EndTime metav1.Time `json:"endTime"`
+
+	// MinReplicas is the number of runners while overriding.
+	// If omitted, it doesn't override minReplicas.
+	// +optional
+	// +nullable
+	// +kubebuilder:validation:Minimum=0
+	MinReplicas *int `json:"minReplicas,omitempty"`
+
+	// MaxReplicas is the maximum number of runners while overriding.
+	// If omitted, it doesn't override maxReplicas.
+	// +optional
+	// +nullable
+	// +kubebuilder:validation:Minimum=0
+	MaxReplicas *int `json:"maxReplicas,omitempty"`
+
+	// SoftMaxReplicas is the maximum number of runners the metrics can demand while overriding, like during off-hours to save cost.
+	// Unlike MaxReplicas, the replicas added by capacity reservations and the desired replicas override annotation can exceed it,
+	// so that the known work still runs. MaxReplicas is still honored as the hard limit on top of it.
+	// +optional
+	// +nullable
+	// +kubebuilder:validation:Minimum=0
+	SoftMaxReplicas *int `json:"softMaxReplicas,omitempty"`
+
+	// +optional
+	RecurrenceRule RecurrenceRule `json:"recurrenceRule,omitempty"`
+}
+
+type RecurrenceRule struct {
+	// Frequency is the name of a predefined interval of each recurrence.
+	// The valid values are "Daily", "Weekly", "Monthly", and "Yearly".
+	// If empty, the corresponding override happens only once.
+	// +optional
+	// +kubebuilder:validation:Enum=Daily;Weekly;Monthly;Yearly
+	Frequency string `json:"frequency,omitempty"`
+
+	// UntilTime is the time of the final recurrence.
+	// If empty, the schedule recurs forever.
+	// +optional
+	UntilTime metav1.Time `json:"untilTime,omitempty"`
+}
+
+// ScaleOutScheduling is the scheduling hints the HorizontalRunnerAutoscaler stamps onto the runner template of the scale target.
+// They're applied only when the HorizontalRunnerAutoscaler scales out, so that it never fights with the changes made directly
+// to the scale target. As they're a part of the runner template, applying them rolls out the runners.
+type ScaleOutScheduling struct {
+	// NodeSelector is merged into the node selector of the runners, without overriding the keys already set on the scale target.
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Tolerations are added to the tolerations of the runners, unless the scale target already has them.
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+}
+
+// ColdStartReservation is the capacity reservation added for scaling up from zero replicas.
+type ColdStartReservation struct {
+	// Replicas is the number of replicas reserved.
+	// Defaults to 1.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	Replicas int `json:"replicas,omitempty"`
+
+	// Duration is the duration of the reservation, which is meant to be just long enough for the first runner to be registered
+	// and the metrics to take over.
+	// Defaults to 5 minutes.
+	// +optional
+	Duration metav1.Duration `json:"duration,omitempty"`
 }
 
 type ScaleUpTrigger struct {
@@ -63,15 +410,44 @@ type ScaleUpTrigger struct {
 }
 
 type GitHubEventScaleUpTriggerSpec struct {
-	CheckRun    *CheckRunSpec    `json:"checkRun,omitempty"`
+	CheckRun *CheckRunSpec `json:"checkRun,omitempty"`
+
+	// CheckSuite enables adding a capacity reservation on each matching check_suite event.
+	// +optional
+	CheckSuite *CheckSuiteSpec `json:"checkSuite,omitempty"`
+
 	PullRequest *PullRequestSpec `json:"pullRequest,omitempty"`
 	Push        *PushSpec        `json:"push,omitempty"`
+
+	// WorkflowJob enables adding a capacity reservation on each queued workflow_job event,
+	// and removing it once the job completes.
+	// +optional
+	WorkflowJob *WorkflowJobSpec `json:"workflowJob,omitempty"`
 }
 
 // https://docs.github.com/en/actions/reference/events-that-trigger-workflows#check_run
 type CheckRunSpec struct {
 	Types  []string `json:"types,omitempty"`
 	Status string   `json:"status,omitempty"`
+
+	// ReleaseOnCompletion makes the capacity reservation added on a matching event identified by the check run ID,
+	// and removed once the check run completes, like the ones added on workflow_job events.
+	// The completed event releases the reservation regardless of Types and Status.
+	// +optional
+	ReleaseOnCompletion bool `json:"releaseOnCompletion,omitempty"`
+}
+
+// CheckSuiteSpec is the condition for triggering scale-up on check_suite event.
+// Also see https://docs.github.com/en/developers/webhooks-and-events/webhook-events-and-payloads#check_suite
+type CheckSuiteSpec struct {
+	Types  []string `json:"types,omitempty"`
+	Status string   `json:"status,omitempty"`
+
+	// ReleaseOnCompletion makes the capacity reservation added on a matching event identified by the check suite ID,
+	// and removed once the check suite completes.
+	// The completed event releases the reservation regardless of Types and Status.
+	// +optional
+	ReleaseOnCompletion bool `json:"releaseOnCompletion,omitempty"`
 }
 
 // https://docs.github.com/en/actions/reference/events-that-trigger-workflows#pull_request
@@ -85,28 +461,174 @@ type PullRequestSpec struct {
 type PushSpec struct {
 }
 
+// WorkflowJobSpec is the condition for triggering scale-up on workflow_job event.
+// The event is matched against the scale target by comparing the job's labels with the runner labels.
+// Also see https://docs.github.com/en/developers/webhooks-and-events/webhook-events-and-payloads#workflow_job
+type WorkflowJobSpec struct {
+	// Labels limits the trigger to the workflow jobs requesting all these labels via `runs-on`.
+	// They must be among the runner labels of the scale target, as the jobs requesting them could never be run by it otherwise.
+	// +optional
+	Labels []string `json:"labels,omitempty"`
+}
+
 // CapacityReservation specifies the number of replicas temporarily added
 // to the scale target until ExpirationTime.
 type CapacityReservation struct {
 	Name           string      `json:"name,omitempty"`
 	ExpirationTime metav1.Time `json:"expirationTime,omitempty"`
 	Replicas       int         `json:"replicas,omitempty"`
+
+	// Duration can be specified instead of ExpirationTime, so that the client doesn't need to compute the absolute time.
+	// The controller sets EffectiveTime and ExpirationTime when it observes the reservation for the first time,
+	// and the reservation is treated like the one with ExpirationTime afterwards.
+	// +optional
+	Duration metav1.Duration `json:"duration,omitempty"`
+
+	// EffectiveTime is the time at which the controller observed the reservation specified by Duration.
+	// +optional
+	EffectiveTime metav1.Time `json:"effectiveTime,omitempty"`
+
+	// WorkflowJobID is the ID of the workflow job the reservation is added for.
+	// Only one reservation is counted per workflow job, so that a redelivered webhook event never results in double counting.
+	// +optional
+	WorkflowJobID int64 `json:"workflowJobID,omitempty"`
+
+	// ReservationID is the client-supplied ID of the reservation, like the delivery ID of the webhook event the reservation is added for.
+	// Only one reservation is counted per ID, and adding a reservation with an existing ID extends the expiration time
+	// of the existing one rather than adding another, so that a redelivered webhook event is idempotent.
+	// +optional
+	ReservationID string `json:"reservationID,omitempty"`
 }
 
 type ScaleTargetRef struct {
+	// Kind is the kind of the scale target.
+	// Only RunnerDeployment is supported for now, and the HorizontalRunnerAutoscaler with any other kind is not reconciled.
+	// Defaults to RunnerDeployment.
+	// +optional
+	Kind string `json:"kind,omitempty"`
+
 	Name string `json:"name,omitempty"`
+
+	// MatchLabels selects the scale targets in the same namespace by their labels, in place of Name,
+	// so that all the RunnerDeployments matching the labels are scaled to the same desired replicas.
+	// The metrics are computed against the first of the matching RunnerDeployments sorted by name.
+	// It's supported only in ScaleTargetRef.
+	// +optional
+	MatchLabels map[string]string `json:"matchLabels,omitempty"`
 }
 
+// GitHubAppInstallationRef is the reference to an installation of the GitHub App.
+// Exactly one of ID and Organization must be set.
+type GitHubAppInstallationRef struct {
+	// ID is the installation ID.
+	// +optional
+	ID int64 `json:"id,omitempty"`
+
+	// Organization is the organization on which the GitHub App is installed.
+	// +optional
+	Organization string `json:"organization,omitempty"`
+}
+
+// GitHubAPICredentialsFrom is the source of the credentials to call GitHub API with.
+type GitHubAPICredentialsFrom struct {
+	// SecretRef is the reference to a Secret in the same namespace with either the `github_token` key,
+	// or the `github_app_id`, `github_app_installation_id`, and `github_app_private_key` keys, like the Secret of the controller.
+	SecretRef SecretReference `json:"secretRef"`
+}
+
+type SecretReference struct {
+	// Name is the name of the Secret
+	Name string `json:"name"`
+}
+
+type PolicyRef struct {
+	// Name is the name of the ConfigMap
+	Name string `json:"name,omitempty"`
+}
+
+const (
+	// DefaultScaleHistoryLookbackDays and DefaultScaleHistoryBucketSeconds are the defaults of LookbackDays and BucketSeconds.
+	DefaultScaleHistoryLookbackDays  = 7
+	DefaultScaleHistoryBucketSeconds = 3600
+
+	// MaxScaleHistoryEntries is the maximum number of the entries of the scale history recorded for the HistoricalDesiredReplicas metric,
+	// which keeps the status of the HorizontalRunnerAutoscaler well within the size limit of a Kubernetes object.
+	MaxScaleHistoryEntries = 1024
+)
+
 type MetricSpec struct {
 	// Type is the type of metric to be used for autoscaling.
-	// The only supported Type is TotalNumberOfQueuedAndInProgressWorkflowRuns
+	// The supported types are TotalNumberOfQueuedAndInProgressWorkflowRuns, DurationWeightedQueuedAndInProgressWorkflowRuns,
+	// PercentageRunnersBusy, PercentageRunnerGroupBusy, HistoricalDesiredReplicas, OfflineRunners, HTTPEndpoint, Prometheus, ExternalObject,
+	// and RecentPushAndPullRequestEvents.
+	// HistoricalDesiredReplicas never scales down on its own. It only raises the desired replicas computed by the other metrics.
+	// OfflineRunners never scales on its own either. It adds the number of the runners registered to GitHub but offline,
+	// like the ones on crashed nodes, to the desired replicas computed by the other metrics, up to MaxReplicas.
+	// DurationWeightedQueuedAndInProgressWorkflowRuns counts workflow runs and jobs like TotalNumberOfQueuedAndInProgressWorkflowRuns,
+	// but weights each of them by the average duration of the recently completed runs of its workflow relative to ReferenceDurationSeconds,
+	// so that long jobs result in more replicas than short ones. Workflows without completed runs are weighted 1.
+	// RecentPushAndPullRequestEvents counts the push events and the pull_request events opening or updating pull requests
+	// of the repositories within EventLookbackSeconds, as a leading indicator of the workflow runs they are about to trigger.
+	// It's meant to be combined with TotalNumberOfQueuedAndInProgressWorkflowRuns, whose demand it raises slightly ahead of the queue.
+	// Defaults to TotalNumberOfQueuedAndInProgressWorkflowRuns.
 	Type string `json:"type,omitempty"`
 
+	// Name identifies the metric in MetricExpression, which requires every metric it can reference to be named.
+	// It must be unique within the metrics, start with a letter or an underscore followed by letters, digits, and underscores,
+	// and be none of the function names and busyRunners and queueDepth.
+	// +optional
+	Name string `json:"name,omitempty"`
+
 	// RepositoryNames is the list of repository names to be used for calculating the metric.
 	// For example, a repository name is the REPO part of `github.com/USER/REPO`.
 	// +optional
 	RepositoryNames []string `json:"repositoryNames,omitempty"`
 
+	// IncludeInProgress is whether in-progress workflow runs and jobs are counted in addition to queued ones
+	// by the TotalNumberOfQueuedAndInProgressWorkflowRuns and DurationWeightedQueuedAndInProgressWorkflowRuns metrics.
+	// Set it to false to scale only on the jobs waiting for runners.
+	// Defaults to true.
+	// +optional
+	IncludeInProgress *bool `json:"includeInProgress,omitempty"`
+
+	// ReplicasPerRun is the multiplicative factor applied to the number of workflow runs counted by
+	// the TotalNumberOfQueuedAndInProgressWorkflowRuns metric, the weighted number counted by
+	// the DurationWeightedQueuedAndInProgressWorkflowRuns metric, or the queue depth reported to the HTTPEndpoint metric
+	// or queried by the Prometheus metric, to determine the desired replicas.
+	// The result is rounded up, so for example "0.5" results in two runners for three runs.
+	// It must be greater than 0. Defaults to "1".
+	// +optional
+	ReplicasPerRun string `json:"replicasPerRun,omitempty"`
+
+	// ReplicasPerEvent is the multiplicative factor applied to the number of events counted by the RecentPushAndPullRequestEvents metric
+	// to determine the desired replicas. The result is rounded up.
+	// It must be greater than 0. Defaults to "0.25", so that a burst of events doesn't over-scale on its own.
+	// +optional
+	ReplicasPerEvent string `json:"replicasPerEvent,omitempty"`
+
+	// EventLookbackSeconds is the duration of the past within which the events are counted by the RecentPushAndPullRequestEvents metric.
+	// Defaults to 300.
+	// +optional
+	// +kubebuilder:validation:Minimum=60
+	EventLookbackSeconds *int `json:"eventLookbackSeconds,omitempty"`
+
+	// Weight is the multiplicative factor applied to the replicas demanded by the metric when MetricAggregation is WeightedSum.
+	// It must be greater than 0, and can't be set for HistoricalDesiredReplicas and OfflineRunners. Defaults to "1".
+	// +optional
+	Weight string `json:"weight,omitempty"`
+
+	// ReferenceDurationSeconds is the average duration of the workflow runs weighted 1 by
+	// the DurationWeightedQueuedAndInProgressWorkflowRuns metric. A workflow whose runs take twice as long is weighted 2.
+	// Defaults to 600.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	ReferenceDurationSeconds *int `json:"referenceDurationSeconds,omitempty"`
+
+	// RunnerGroup is the name of the runner group whose runners are counted by the PercentageRunnerGroupBusy metric.
+	// The runner group must belong to the organization or the enterprise of the scale target.
+	// +optional
+	RunnerGroup string `json:"runnerGroup,omitempty"`
+
 	// ScaleUpThreshold is the percentage of busy runners greater than which will
 	// trigger the hpa to scale runners up.
 	// +optional
@@ -127,6 +649,16 @@ type MetricSpec struct {
 	// +optional
 	ScaleDownFactor string `json:"scaleDownFactor,omitempty"`
 
+	// TargetUtilizationPercent is the percentage of busy runners the PercentageRunnersBusy metric keeps the runners at,
+	// like the target utilization of the Kubernetes HorizontalPodAutoscaler.
+	// The desired replicas are the number of busy runners divided by it, rounded up to a whole runner,
+	// so for example 70 results in 10 runners for 7 busy runners, and 12 runners for 8 busy runners.
+	// The thresholds, the factors, and the adjustments are ignored when it's set.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	TargetUtilizationPercent *int `json:"targetUtilizationPercent,omitempty"`
+
 	// ScaleUpAdjustment is the number of runners added on scale-up.
 	// You can only specify either ScaleUpFactor or ScaleUpAdjustment.
 	// +optional
@@ -136,11 +668,113 @@ type MetricSpec struct {
 	// You can only specify either ScaleDownFactor or ScaleDownAdjustment.
 	// +optional
 	ScaleDownAdjustment int `json:"scaleDownAdjustment,omitempty"`
+
+	// LookbackDays is the number of past days whose scale history is used by the HistoricalDesiredReplicas metric
+	// to anticipate the desired replicas at the same time of day.
+	// The metric has no effect until the history covers the whole lookback window.
+	// The lookback window must fit in MaxScaleHistoryEntries buckets, as the history is kept in the status.
+	// Defaults to 7.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	LookbackDays int `json:"lookbackDays,omitempty"`
+
+	// BucketSeconds is the length of the time windows in which the scale history is aggregated
+	// for the HistoricalDesiredReplicas metric.
+	// Defaults to 3600.
+	// +optional
+	// +kubebuilder:validation:Minimum=60
+	BucketSeconds int `json:"bucketSeconds,omitempty"`
+
+	// HTTPEndpoint is the endpoint queried by the HTTPEndpoint metric.
+	// +optional
+	HTTPEndpoint *HTTPEndpointMetricSource `json:"httpEndpoint,omitempty"`
+
+	// Prometheus is the Prometheus server and the query used by the Prometheus metric.
+	// +optional
+	Prometheus *PrometheusMetricSource `json:"prometheus,omitempty"`
+
+	// ExternalObject is the object whose field is read as the desired replicas by the ExternalObject metric.
+	// +optional
+	ExternalObject *ExternalObjectMetricSource `json:"externalObject,omitempty"`
+}
+
+// HTTPEndpointMetricSource is an HTTP endpoint reporting the demand for runners that doesn't come from GitHub,
+// like the depth of an in-cluster job queue.
+// The endpoint must respond to GET with a JSON object like `{"desiredReplicas": N}` or `{"queueDepth": N}`.
+// A response with a status other than 200 skips the metric, so that the other metrics decide the desired replicas.
+type HTTPEndpointMetricSource struct {
+	// URL is the URL of the endpoint.
+	URL string `json:"url"`
+
+	// AuthHeader is the name of the request header the value of AuthSecretRef is sent in.
+	// Defaults to Authorization.
+	// +optional
+	AuthHeader string `json:"authHeader,omitempty"`
+
+	// AuthSecretRef is the key of the secret in the namespace of the HorizontalRunnerAutoscaler whose value,
+	// like "Bearer TOKEN", is sent in AuthHeader.
+	// +optional
+	AuthSecretRef *corev1.SecretKeySelector `json:"authSecretRef,omitempty"`
+}
+
+// PrometheusMetricSource is a PromQL query evaluated by a Prometheus server, whose result is used as the queue depth,
+// like the number of queued jobs already exported to Prometheus.
+// The query must result in a scalar or a vector of a single sample, like `sum(ci_queued_jobs{team="a"})`.
+// A failed query skips the metric, so that the other metrics decide the desired replicas.
+type PrometheusMetricSource struct {
+	// URL is the URL of the Prometheus server, like http://prometheus.monitoring.svc:9090.
+	// The query is sent to its /api/v1/query endpoint.
+	URL string `json:"url"`
+
+	// Query is the PromQL expression resulting in the queue depth.
+	Query string `json:"query"`
+
+	// AuthHeader is the name of the request header the value of AuthSecretRef is sent in.
+	// Defaults to Authorization.
+	// +optional
+	AuthHeader string `json:"authHeader,omitempty"`
+
+	// AuthSecretRef is the key of the secret in the namespace of the HorizontalRunnerAutoscaler whose value,
+	// like "Bearer TOKEN", is sent in AuthHeader.
+	// +optional
+	AuthSecretRef *corev1.SecretKeySelector `json:"authSecretRef,omitempty"`
+}
+
+// ExternalObjectMetricSource is an object in the namespace of the HorizontalRunnerAutoscaler recording the desired replicas
+// recommended by another autoscaler, like the HorizontalPodAutoscaler managed by a KEDA ScaledObject,
+// so that the recommendation can be used along with the other metrics, e.g. while migrating from or to the other autoscaler.
+// The controller must be granted get on the resource of the object.
+// A missing object or field skips the metric, so that the other metrics decide the desired replicas.
+type ExternalObjectMetricSource struct {
+	// APIVersion is the API version of the object, like autoscaling/v1.
+	APIVersion string `json:"apiVersion"`
+
+	// Kind is the kind of the object, like HorizontalPodAutoscaler.
+	Kind string `json:"kind"`
+
+	// Name is the name of the object.
+	Name string `json:"name"`
+
+	// ReplicasPath is the dot-separated path to the number field of the object holding the desired replicas.
+	// A fraction is rounded up.
+	// Defaults to status.desiredReplicas.
+	// +optional
+	ReplicasPath string `json:"replicasPath,omitempty"`
+}
+
+// BurstCreditsStatus is the accounting of the burst credits of BurstMaxReplicas.
+type BurstCreditsStatus struct {
+	// ConsumedReplicaSeconds is the credits consumed and not yet refilled, in replica-seconds.
+	// The credits remaining are the capacity minus it.
+	ConsumedReplicaSeconds int `json:"consumedReplicaSeconds"`
+
+	// LastUpdateTime is the time up to which the credits are accounted.
+	LastUpdateTime metav1.Time `json:"lastUpdateTime"`
 }
 
 type HorizontalRunnerAutoscalerStatus struct {
-	// ObservedGeneration is the most recent generation observed for the target. It corresponds to e.g.
-	// RunnerDeployment's generation, which is updated on mutation by the API Server.
+	// ObservedGeneration is the most recent generation of the HorizontalRunnerAutoscaler successfully reconciled by the controller.
+	// It's equal to metadata.generation once the controller has processed the latest change to the spec.
 	// +optional
 	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 
@@ -152,16 +786,247 @@ type HorizontalRunnerAutoscalerStatus struct {
 	// +optional
 	LastSuccessfulScaleOutTime *metav1.Time `json:"lastSuccessfulScaleOutTime,omitempty"`
 
+	// LastScaleOutDesiredReplicas is the desired replicas at LastSuccessfulScaleOutTime, from which ScaleDownDecayHalfLifeSeconds
+	// decays the desired replicas.
+	// +optional
+	LastScaleOutDesiredReplicas *int `json:"lastScaleOutDesiredReplicas,omitempty"`
+
+	// LastScaleUpTime is the last time the desired replicas was increased.
+	// It is used for deferring subsequent scale ups until ScaleUpDelaySeconds elapses.
+	// +optional
+	LastScaleUpTime *metav1.Time `json:"lastScaleUpTime,omitempty"`
+
 	// +optional
 	CacheEntries []CacheEntry `json:"cacheEntries,omitempty"`
+
+	// CacheExpiresAt is the time the desired replicas cached from the metrics expire, after which the next reconciliation
+	// computes them afresh, calling GitHub API. The controller requeues the HorizontalRunnerAutoscaler right at the time.
+	// It's unset while nothing is cached, like the desired replicas are overridden by the annotation or the cache is disabled.
+	// +optional
+	CacheExpiresAt *metav1.Time `json:"cacheExpiresAt,omitempty"`
+
+	// BusyRunners is the number of busy runners, like the one of in-progress workflow jobs, observed at the last computation.
+	// +optional
+	BusyRunners *int `json:"busyRunners,omitempty"`
+
+	// LastBusyTime is the last time the number of busy runners was observed to drop.
+	// It is used for deferring scale downs until ScaleDownGraceSeconds elapses.
+	// +optional
+	LastBusyTime *metav1.Time `json:"lastBusyTime,omitempty"`
+
+	// UncappedDesiredReplicas is the desired replicas demanded by the metrics and the capacity reservations beyond MaxReplicas.
+	// It's set only while MaxReplicas is limiting the scale, which is a sign to raise MaxReplicas.
+	// +optional
+	UncappedDesiredReplicas *int `json:"uncappedDesiredReplicas,omitempty"`
+
+	// LastMaxReplicasReachedTime is the last time the MaxReplicasReached event was emitted.
+	// It is used for emitting the event at most once per MaxReplicasReachedEventInterval.
+	// +optional
+	LastMaxReplicasReachedTime *metav1.Time `json:"lastMaxReplicasReachedTime,omitempty"`
+
+	// BurstCredits is the accounting of the burst credits consumed beyond MaxReplicas.
+	// It's set only while the replicas are above MaxReplicas or the consumed credits are refilling, and cleared once they're full.
+	// +optional
+	BurstCredits *BurstCreditsStatus `json:"burstCredits,omitempty"`
+
+	// ScaleDownStalledSince is the time since which the scale down computed by the metrics has been deferred.
+	// It's tracked only when MaxScaleDownStallSeconds is set, and cleared once the replicas are scaled down.
+	// +optional
+	ScaleDownStalledSince *metav1.Time `json:"scaleDownStalledSince,omitempty"`
+
+	// ContinuousDemandSince is the time since which the replicas computed by the metrics have continuously exceeded MinReplicas.
+	// It's tracked only when MinReplicasRamp is set, and cleared once the computed replicas drop to MinReplicas or below.
+	// +optional
+	ContinuousDemandSince *metav1.Time `json:"continuousDemandSince,omitempty"`
+
+	// WinningMetricType is the type of the metric that resulted in the largest number of desired replicas
+	// among all the metrics at the last computation.
+	// +optional
+	WinningMetricType string `json:"winningMetricType,omitempty"`
+
+	// ConsecutiveFailures is the number of consecutive reconciliations that failed to compute the desired replicas.
+	// It's reset to zero on success.
+	// +optional
+	ConsecutiveFailures int `json:"consecutiveFailures,omitempty"`
+
+	// BackoffSeconds is the number of seconds the controller waits before retrying after the last failure,
+	// which grows exponentially with ConsecutiveFailures.
+	// +optional
+	BackoffSeconds int `json:"backoffSeconds,omitempty"`
+
+	// LastError is the error of the last failure to compute the desired replicas, so that one can tell why scaling isn't happening
+	// after the events and the logs rotate away. It's cleared once the desired replicas are computed successfully.
+	// +optional
+	LastError *LastError `json:"lastError,omitempty"`
+
+	// Conditions is the list of the latest observations of the HorizontalRunnerAutoscaler's state.
+	// +optional
+	Conditions []HorizontalRunnerAutoscalerCondition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+
+	// ActiveCapacityReservations summarizes the capacity reservations that added replicas at the last reconciliation,
+	// so that one can tell whether the desired replicas are driven by the metrics or by the reservations.
+	// +optional
+	ActiveCapacityReservations ActiveCapacityReservations `json:"activeCapacityReservations,omitempty"`
+
+	// ScheduledOverridesSummary is the summary of active and upcoming scheduled overrides to be shown in e.g. a column of a `kubectl get hra` output
+	// for observability.
+	// +optional
+	ScheduledOverridesSummary *string `json:"scheduledOverridesSummary,omitempty"`
+
+	// Recommendations are the desired replicas recommended by the metrics within ScaleDownStabilizationWindowSeconds, oldest first.
+	// A recommendation is dropped once a later one is as large as it, as it can no longer be the largest within the window.
+	// It's recorded only when ScaleDownStabilizationWindowSeconds is set.
+	// +optional
+	Recommendations []ReplicasRecommendation `json:"recommendations,omitempty"`
+
+	// QueueDepthHistory is the queue depths observed by the metrics at the last consecutive reconciliations
+	// in which the queue depth increased, oldest first. It's recorded only when QueueGrowthPanic is set, and
+	// restarts from the latest queue depth once the queue depth stops increasing.
+	// +optional
+	QueueDepthHistory []int `json:"queueDepthHistory,omitempty"`
+
+	// ScaleHistory is the ring buffer of the largest desired replicas computed by the metrics other than HistoricalDesiredReplicas
+	// in each time bucket, oldest first. It's recorded only when the HistoricalDesiredReplicas metric is used.
+	// +optional
+	ScaleHistory []ScaleHistoryEntry `json:"scaleHistory,omitempty"`
+
+	// ScaleEvents is the last 10 changes of the desired replicas of the scale target, oldest first,
+	// so that one can audit why it was scaled without scraping the logs or the events, which rotate away.
+	// Nothing is recorded in dryRun, as the scale target isn't scaled.
+	// +optional
+	ScaleEvents []ScaleEvent `json:"scaleEvents,omitempty"`
+}
+
+// ActiveCapacityReservations is the number of the capacity reservations in effect and the replicas they reserve.
+// The expired reservations held by busy runners are included, as they still add replicas.
+type ActiveCapacityReservations struct {
+	// Count is the number of capacity reservations in effect.
+	Count int `json:"count"`
+
+	// Replicas is the sum of the replicas reserved by the capacity reservations in effect.
+	// The reservations for the same workflow job are counted only once.
+	Replicas int `json:"replicas"`
+}
+
+// ScaleHistoryEntry is the largest desired replicas computed in the time bucket starting at Time.
+type ScaleHistoryEntry struct {
+	Time     metav1.Time `json:"time"`
+	Replicas int         `json:"replicas"`
+}
+
+// ScaleEvent is a change of the desired replicas of the scale target.
+type ScaleEvent struct {
+	Time         metav1.Time `json:"time"`
+	FromReplicas int         `json:"fromReplicas"`
+	ToReplicas   int         `json:"toReplicas"`
+
+	// Reason is what drove the desired replicas, one of Metric, CapacityReservation, Override, and MinReplicas.
+	Reason string `json:"reason"`
+
+	// Metric is the type of the metric that computed the desired replicas, set only when Reason is Metric.
+	// +optional
+	Metric string `json:"metric,omitempty"`
 }
 
+const (
+	// ScaleEventReasonMetric is the reason of the scale driven by the metrics.
+	ScaleEventReasonMetric = "Metric"
+
+	// ScaleEventReasonCapacityReservation is the reason of the scale driven by the active capacity reservations.
+	ScaleEventReasonCapacityReservation = "CapacityReservation"
+
+	// ScaleEventReasonOverride is the reason of the scale to the replicas overridden by the annotation.
+	ScaleEventReasonOverride = "Override"
+
+	// ScaleEventReasonMinReplicas is the reason of the scale to MinReplicas, as neither the metrics nor the reservations demand more.
+	ScaleEventReasonMinReplicas = "MinReplicas"
+)
+
+// LastError is an error that prevented the desired replicas from being computed.
+type LastError struct {
+	// Message is the error message, truncated when it's too long to keep the object small.
+	Message string `json:"message"`
+
+	// Time is the time the error occurred at.
+	Time metav1.Time `json:"time"`
+}
+
+// ReplicasRecommendation is the desired replicas recommended by the metrics at a certain time.
+type ReplicasRecommendation struct {
+	Time     metav1.Time `json:"time"`
+	Replicas int         `json:"replicas"`
+}
+
+const (
+	// HorizontalRunnerAutoscalerConditionReady is True when the last reconciliation determined the desired replicas
+	// and applied it to the scale target successfully.
+	HorizontalRunnerAutoscalerConditionReady = "Ready"
+
+	// HorizontalRunnerAutoscalerConditionPaused is True while the HorizontalRunnerAutoscaler is paused by the annotation,
+	// during which the scale target is left as is.
+	HorizontalRunnerAutoscalerConditionPaused = "Paused"
+)
+
+// HorizontalRunnerAutoscalerCondition describes the state of a HorizontalRunnerAutoscaler at a certain point.
+type HorizontalRunnerAutoscalerCondition struct {
+	// Type is the type of the condition, like Ready.
+	Type string `json:"type"`
+
+	// Status is the status of the condition, one of True, False, and Unknown.
+	Status corev1.ConditionStatus `json:"status"`
+
+	// Reason is a brief CamelCase reason for the condition's last transition.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// Message is a human-readable message indicating details about the last transition.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// LastTransitionTime is the last time the condition transitioned from one status to another.
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+}
+
+const (
+	ScaleDownDelayAnchorLastScaleOut = "LastScaleOut"
+	ScaleDownDelayAnchorLastBusy     = "LastBusy"
+)
+
+const (
+	MetricAggregationMax         = "Max"
+	MetricAggregationWeightedSum = "WeightedSum"
+)
+
+const (
+	ScaleModeMetrics    = "Metrics"
+	ScaleModeEventsOnly = "EventsOnly"
+)
+
+const (
+	// MetricExpressionVariableBusyRunners is the variable of MetricExpression holding the largest number of busy runners observed by the metrics.
+	MetricExpressionVariableBusyRunners = "busyRunners"
+
+	// MetricExpressionVariableQueueDepth is the variable of MetricExpression holding the largest number of queued workflow jobs observed by the metrics.
+	MetricExpressionVariableQueueDepth = "queueDepth"
+)
+
 const CacheEntryKeyDesiredReplicas = "desiredReplicas"
 
 type CacheEntry struct {
 	Key            string      `json:"key,omitempty"`
 	Value          int         `json:"value,omitempty"`
 	ExpirationTime metav1.Time `json:"expirationTime,omitempty"`
+
+	// CreationTime is the time the value was cached at.
+	// +optional
+	CreationTime metav1.Time `json:"creationTime,omitempty"`
+
+	// InputsKey encodes the inputs the value was computed against, like the replicas of the scale target,
+	// so that the entry is ignored before its expiration once the inputs change.
+	// An empty InputsKey matches any inputs.
+	// +optional
+	InputsKey string `json:"inputsKey,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -169,6 +1034,8 @@ type CacheEntry struct {
 // +kubebuilder:printcolumn:JSONPath=".spec.minReplicas",name=Min,type=number
 // +kubebuilder:printcolumn:JSONPath=".spec.maxReplicas",name=Max,type=number
 // +kubebuilder:printcolumn:JSONPath=".status.desiredReplicas",name=Desired,type=number
+// +kubebuilder:printcolumn:JSONPath=".status.activeCapacityReservations.replicas",name=Reserved,type=number
+// +kubebuilder:printcolumn:JSONPath=".status.scheduledOverridesSummary",name=Schedule,type=string
 
 // HorizontalRunnerAutoscaler is the Schema for the horizontalrunnerautoscaler API
 type HorizontalRunnerAutoscaler struct {
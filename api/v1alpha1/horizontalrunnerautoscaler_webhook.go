@@ -0,0 +1,425 @@
+/*
+Copyright 2020 The actions-runner-controller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/summerwind/actions-runner-controller/expression"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var horizontalRunnerAutoscalerLog = logf.Log.WithName("horizontalrunnerautoscaler-resource")
+
+// webhookClient is used by the validations that look up the objects referenced by the one being validated,
+// like the RunnerDeployment scaled by a HorizontalRunnerAutoscaler.
+// It's nil until a webhook is set up with the manager, in which case those validations are skipped.
+var webhookClient client.Client
+
+func (r *HorizontalRunnerAutoscaler) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	webhookClient = mgr.GetClient()
+
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-actions-summerwind-dev-v1alpha1-horizontalrunnerautoscaler,verbs=create;update,mutating=true,failurePolicy=fail,groups=actions.summerwind.dev,resources=horizontalrunnerautoscalers,versions=v1alpha1,name=mutate.horizontalrunnerautoscaler.actions.summerwind.dev
+
+var _ webhook.Defaulter = &HorizontalRunnerAutoscaler{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type
+func (r *HorizontalRunnerAutoscaler) Default() {
+	// Nothing to do.
+}
+
+// +kubebuilder:webhook:path=/validate-actions-summerwind-dev-v1alpha1-horizontalrunnerautoscaler,verbs=create;update,mutating=false,failurePolicy=fail,groups=actions.summerwind.dev,resources=horizontalrunnerautoscalers,versions=v1alpha1,name=validate.horizontalrunnerautoscaler.actions.summerwind.dev
+
+var _ webhook.Validator = &HorizontalRunnerAutoscaler{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *HorizontalRunnerAutoscaler) ValidateCreate() error {
+	horizontalRunnerAutoscalerLog.Info("validate resource to be created", "name", r.Name)
+	return r.Validate()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *HorizontalRunnerAutoscaler) ValidateUpdate(old runtime.Object) error {
+	horizontalRunnerAutoscalerLog.Info("validate resource to be updated", "name", r.Name)
+	return r.Validate()
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *HorizontalRunnerAutoscaler) ValidateDelete() error {
+	return nil
+}
+
+// Validate validates resource spec.
+func (r *HorizontalRunnerAutoscaler) Validate() error {
+	var errList field.ErrorList
+
+	spec := field.NewPath("spec")
+
+	if r.Spec.ScaleTargetRef.Name == "" && len(r.Spec.ScaleTargetRef.MatchLabels) == 0 {
+		errList = append(errList, field.Required(spec.Child("scaleTargetRef", "name"), "must be the name of the scale target unless matchLabels is set"))
+	} else if r.Spec.ScaleTargetRef.Name != "" && len(r.Spec.ScaleTargetRef.MatchLabels) > 0 {
+		errList = append(errList, field.Invalid(spec.Child("scaleTargetRef", "matchLabels"), r.Spec.ScaleTargetRef.MatchLabels, "must not be set along with name"))
+	}
+
+	scaleTargetNames := map[string]bool{}
+	if r.Spec.ScaleTargetRef.Name != "" {
+		scaleTargetNames[r.Spec.ScaleTargetRef.Name] = true
+	}
+
+	for i, ref := range r.Spec.AdditionalScaleTargetRefs {
+		if len(ref.MatchLabels) > 0 {
+			errList = append(errList, field.Forbidden(spec.Child("additionalScaleTargetRefs").Index(i).Child("matchLabels"), "is supported only in scaleTargetRef"))
+		}
+
+		if ref.Name == "" {
+			errList = append(errList, field.Required(spec.Child("additionalScaleTargetRefs").Index(i).Child("name"), "must be the name of the scale target"))
+		} else if scaleTargetNames[ref.Name] {
+			errList = append(errList, field.Duplicate(spec.Child("additionalScaleTargetRefs").Index(i).Child("name"), ref.Name))
+		}
+
+		scaleTargetNames[ref.Name] = true
+	}
+
+	if r.Spec.MinReplicas != nil && *r.Spec.MinReplicas < 0 {
+		errList = append(errList, field.Invalid(spec.Child("minReplicas"), *r.Spec.MinReplicas, "must be greater than or equal to 0"))
+	}
+
+	if r.Spec.MinReplicas != nil && r.Spec.MaxReplicas != nil && *r.Spec.MinReplicas > *r.Spec.MaxReplicas {
+		errList = append(errList, field.Invalid(spec.Child("minReplicas"), *r.Spec.MinReplicas, fmt.Sprintf("must be less than or equal to maxReplicas(%d)", *r.Spec.MaxReplicas)))
+	}
+
+	if ramp := r.Spec.MinReplicasRamp; ramp != nil {
+		if ramp.StepSeconds < 1 {
+			errList = append(errList, field.Invalid(spec.Child("minReplicasRamp", "stepSeconds"), ramp.StepSeconds, "must be greater than or equal to 1"))
+		}
+
+		if ramp.StepReplicas != nil && *ramp.StepReplicas < 1 {
+			errList = append(errList, field.Invalid(spec.Child("minReplicasRamp", "stepReplicas"), *ramp.StepReplicas, "must be greater than or equal to 1"))
+		}
+
+		if r.Spec.MinReplicas != nil && ramp.MaxReplicas < *r.Spec.MinReplicas {
+			errList = append(errList, field.Invalid(spec.Child("minReplicasRamp", "maxReplicas"), ramp.MaxReplicas, fmt.Sprintf("must be greater than or equal to minReplicas(%d)", *r.Spec.MinReplicas)))
+		}
+	}
+
+	if r.Spec.ScaleDownDecayHalfLifeSeconds != nil && *r.Spec.ScaleDownDecayHalfLifeSeconds < 1 {
+		errList = append(errList, field.Invalid(spec.Child("scaleDownDecayHalfLifeSeconds"), *r.Spec.ScaleDownDecayHalfLifeSeconds, "must be greater than or equal to 1"))
+	}
+
+	if r.Spec.ScaleDownStabilizationWindowSeconds != nil && *r.Spec.ScaleDownStabilizationWindowSeconds < 1 {
+		errList = append(errList, field.Invalid(spec.Child("scaleDownStabilizationWindowSeconds"), *r.Spec.ScaleDownStabilizationWindowSeconds, "must be greater than or equal to 1"))
+	}
+
+	if p := r.Spec.QueueGrowthPanic; p != nil && p.ConsecutiveIncreases < 1 {
+		errList = append(errList, field.Invalid(spec.Child("queueGrowthPanic", "consecutiveIncreases"), p.ConsecutiveIncreases, "must be greater than or equal to 1"))
+	}
+
+	if r.Spec.MaxScaleUpCount != nil && *r.Spec.MaxScaleUpCount < 1 {
+		errList = append(errList, field.Invalid(spec.Child("maxScaleUpCount"), *r.Spec.MaxScaleUpCount, "must be greater than or equal to 1"))
+	}
+
+	if b := r.Spec.BurstMaxReplicas; b != nil {
+		if r.Spec.MaxReplicas == nil {
+			errList = append(errList, field.Required(spec.Child("maxReplicas"), "must be set along with burstMaxReplicas"))
+		} else if *b < *r.Spec.MaxReplicas {
+			errList = append(errList, field.Invalid(spec.Child("burstMaxReplicas"), *b, fmt.Sprintf("must be greater than or equal to maxReplicas(%d)", *r.Spec.MaxReplicas)))
+		}
+	}
+
+	if r.Spec.BurstDurationSeconds != nil && *r.Spec.BurstDurationSeconds < 1 {
+		errList = append(errList, field.Invalid(spec.Child("burstDurationSeconds"), *r.Spec.BurstDurationSeconds, "must be greater than or equal to 1"))
+	}
+
+	if r.Spec.BurstRefillSeconds != nil && *r.Spec.BurstRefillSeconds < 1 {
+		errList = append(errList, field.Invalid(spec.Child("burstRefillSeconds"), *r.Spec.BurstRefillSeconds, "must be greater than or equal to 1"))
+	}
+
+	if r.Spec.RunnerIdleTimeoutSeconds != nil && *r.Spec.RunnerIdleTimeoutSeconds < 1 {
+		errList = append(errList, field.Invalid(spec.Child("runnerIdleTimeoutSeconds"), *r.Spec.RunnerIdleTimeoutSeconds, "must be greater than or equal to 1"))
+	}
+
+	switch r.Spec.ScaleDownDelayAnchor {
+	case "", ScaleDownDelayAnchorLastScaleOut, ScaleDownDelayAnchorLastBusy:
+	default:
+		errList = append(errList, field.NotSupported(spec.Child("scaleDownDelayAnchor"), r.Spec.ScaleDownDelayAnchor, []string{ScaleDownDelayAnchorLastScaleOut, ScaleDownDelayAnchorLastBusy}))
+	}
+
+	switch r.Spec.ScaleMode {
+	case "", ScaleModeMetrics:
+	case ScaleModeEventsOnly:
+		// The metrics would be silently ignored, and without a webhook source nothing but MinReplicas would ever be provisioned
+		if len(r.Spec.Metrics) > 0 {
+			errList = append(errList, field.Forbidden(spec.Child("metrics"), "must not be set when scaleMode is EventsOnly"))
+		}
+
+		if r.Spec.MetricExpression != "" {
+			errList = append(errList, field.Forbidden(spec.Child("metricExpression"), "must not be set when scaleMode is EventsOnly"))
+		}
+
+		if len(r.Spec.ScaleUpTriggers) == 0 && r.Spec.ColdStartReservation == nil {
+			errList = append(errList, field.Required(spec.Child("scaleUpTriggers"), "must be set unless coldStartReservation is set when scaleMode is EventsOnly"))
+		}
+	default:
+		errList = append(errList, field.NotSupported(spec.Child("scaleMode"), r.Spec.ScaleMode, []string{ScaleModeMetrics, ScaleModeEventsOnly}))
+	}
+
+	if r.Spec.MaxCapacityReservationReplicas != nil && *r.Spec.MaxCapacityReservationReplicas < 0 {
+		errList = append(errList, field.Invalid(spec.Child("maxCapacityReservationReplicas"), *r.Spec.MaxCapacityReservationReplicas, "must be greater than or equal to 0"))
+	}
+
+	delays := []struct {
+		name  string
+		value *int
+	}{
+		{"scaleDownDelaySecondsAfterScaleOut", r.Spec.ScaleDownDelaySecondsAfterScaleUp},
+		{"scaleDownGraceSeconds", r.Spec.ScaleDownGraceSeconds},
+		{"maxScaleDownStallSeconds", r.Spec.MaxScaleDownStallSeconds},
+		{"scaleUpDelaySeconds", r.Spec.ScaleUpDelaySeconds},
+		{"runnerStartupGraceSeconds", r.Spec.RunnerStartupGraceSeconds},
+		{"cacheDurationSeconds", r.Spec.CacheDurationSeconds},
+	}
+
+	for _, d := range delays {
+		if d.value != nil && *d.value < 0 {
+			errList = append(errList, field.Invalid(spec.Child(d.name), *d.value, "must be greater than or equal to 0"))
+		}
+	}
+
+	for i, reservation := range r.Spec.CapacityReservations {
+		if reservation.Duration.Duration < 0 {
+			errList = append(errList, field.Invalid(spec.Child("capacityReservations").Index(i).Child("duration"), reservation.Duration.Duration.String(), "must be greater than or equal to 0"))
+		}
+	}
+
+	errList = append(errList, r.validateWorkflowJobLabels(spec)...)
+	errList = append(errList, r.validateScaleHistory(spec)...)
+	errList = append(errList, r.validateMetricExpression(spec)...)
+	errList = append(errList, r.validateMetricAggregation(spec)...)
+
+	// The enterprise, organization, and repository of the runners are validated by the RunnerDeployment webhook,
+	// so the only scope to be validated here is the GitHub App installation used for autoscaling.
+	if ref := r.Spec.GitHubAppInstallation; ref != nil && (ref.ID == 0) == (ref.Organization == "") {
+		errList = append(errList, field.Invalid(spec.Child("githubAppInstallation"), *ref, "exactly one of id and organization must be set"))
+	}
+
+	if from := r.Spec.GitHubAPICredentialsFrom; from != nil {
+		if from.SecretRef.Name == "" {
+			errList = append(errList, field.Required(spec.Child("githubAPICredentialsFrom", "secretRef", "name"), "must be the name of the secret"))
+		}
+
+		if r.Spec.GitHubAppInstallation != nil {
+			errList = append(errList, field.Forbidden(spec.Child("githubAPICredentialsFrom"), "must not be set along with githubAppInstallation"))
+		}
+	}
+
+	if len(errList) > 0 {
+		return apierrors.NewInvalid(r.GroupVersionKind().GroupKind(), r.Name, errList)
+	}
+
+	return nil
+}
+
+// validateWorkflowJobLabels rejects the labels of the workflowJob scale-up triggers that aren't among the runner labels
+// of a scale target, as no job requesting them could be run by its runners, so the triggers would never fire.
+// The scale targets that don't exist yet are skipped, as the RunnerDeployment webhook validates the labels once they are created.
+func (r *HorizontalRunnerAutoscaler) validateWorkflowJobLabels(spec *field.Path) field.ErrorList {
+	if webhookClient == nil {
+		return nil
+	}
+
+	var errList field.ErrorList
+
+	for i, trigger := range r.Spec.ScaleUpTriggers {
+		if trigger.GitHubEvent == nil || trigger.GitHubEvent.WorkflowJob == nil || len(trigger.GitHubEvent.WorkflowJob.Labels) == 0 {
+			continue
+		}
+
+		labels := trigger.GitHubEvent.WorkflowJob.Labels
+		path := spec.Child("scaleUpTriggers").Index(i).Child("githubEvent", "workflowJob", "labels")
+
+		for _, name := range r.runnerDeploymentNames() {
+			var rd RunnerDeployment
+
+			if err := webhookClient.Get(context.TODO(), types.NamespacedName{Namespace: r.Namespace, Name: name}, &rd); err != nil {
+				if !apierrors.IsNotFound(err) {
+					errList = append(errList, field.InternalError(path, err))
+				}
+
+				continue
+			}
+
+			if !RunnerLabelsMatchJobLabels(rd.Spec.Template.Spec.Labels, labels) {
+				errList = append(errList, field.Invalid(path, labels, fmt.Sprintf("must be among the runner labels of RunnerDeployment %s", name)))
+			}
+		}
+	}
+
+	return errList
+}
+
+// runnerDeploymentNames returns the names of the RunnerDeployments referenced by name as the scale targets.
+func (r *HorizontalRunnerAutoscaler) runnerDeploymentNames() []string {
+	var names []string
+
+	for _, ref := range append([]ScaleTargetRef{r.Spec.ScaleTargetRef}, r.Spec.AdditionalScaleTargetRefs...) {
+		if ref.Name != "" && (ref.Kind == "" || ref.Kind == "RunnerDeployment") {
+			names = append(names, ref.Name)
+		}
+	}
+
+	return names
+}
+
+// validateScaleHistory ensures that the scale history recorded for the HistoricalDesiredReplicas metric fits in MaxScaleHistoryEntries,
+// which is the number of the buckets in the lookback window plus the ones the window partially overlaps.
+func (r *HorizontalRunnerAutoscaler) validateScaleHistory(spec *field.Path) field.ErrorList {
+	var errList field.ErrorList
+
+	for i, metric := range r.Spec.Metrics {
+		if metric.Type != AutoscalingMetricTypeHistoricalDesiredReplicas {
+			continue
+		}
+
+		lookbackDays := DefaultScaleHistoryLookbackDays
+		if metric.LookbackDays > 0 {
+			lookbackDays = metric.LookbackDays
+		}
+
+		bucketSeconds := DefaultScaleHistoryBucketSeconds
+		if metric.BucketSeconds > 0 {
+			bucketSeconds = metric.BucketSeconds
+		}
+
+		lookbackSeconds := lookbackDays * 24 * 60 * 60
+
+		if lookbackSeconds/bucketSeconds+2 > MaxScaleHistoryEntries {
+			minBucketSeconds := lookbackSeconds/(MaxScaleHistoryEntries-1) + 1
+
+			errList = append(errList, field.Invalid(spec.Child("metrics").Index(i).Child("bucketSeconds"), bucketSeconds, fmt.Sprintf("must be greater than or equal to %d for lookbackDays(%d), so that the scale history fits in %d entries", minBucketSeconds, lookbackDays, MaxScaleHistoryEntries)))
+		}
+	}
+
+	return errList
+}
+
+// validateMetricExpression validates the names of the metrics and the MetricExpression referencing them.
+func (r *HorizontalRunnerAutoscaler) validateMetricExpression(spec *field.Path) field.ErrorList {
+	var errList field.ErrorList
+
+	reserved := map[string]bool{
+		MetricExpressionVariableBusyRunners: true,
+		MetricExpressionVariableQueueDepth:  true,
+	}
+
+	// names are the ones that can be referenced, among all the seen ones
+	names, seen := map[string]bool{}, map[string]bool{}
+
+	for i, metric := range r.Spec.Metrics {
+		path := spec.Child("metrics").Index(i).Child("name")
+
+		if metric.Name == "" {
+			// HistoricalDesiredReplicas and OfflineRunners are applied after the expression, so they have nothing to be named for
+			if r.Spec.MetricExpression != "" && metric.Type != AutoscalingMetricTypeHistoricalDesiredReplicas && metric.Type != AutoscalingMetricTypeOfflineRunners {
+				errList = append(errList, field.Required(path, "must be set when metricExpression is set"))
+			}
+
+			continue
+		}
+
+		if !expression.IsValidVariable(metric.Name) || reserved[metric.Name] {
+			errList = append(errList, field.Invalid(path, metric.Name, fmt.Sprintf("must start with a letter or an underscore followed by letters, digits, and underscores, and be none of %v, %s, and %s", expression.Functions, MetricExpressionVariableBusyRunners, MetricExpressionVariableQueueDepth)))
+		} else if seen[metric.Name] {
+			errList = append(errList, field.Duplicate(path, metric.Name))
+		}
+
+		seen[metric.Name] = true
+
+		if metric.Type != AutoscalingMetricTypeHistoricalDesiredReplicas && metric.Type != AutoscalingMetricTypeOfflineRunners {
+			names[metric.Name] = true
+		}
+	}
+
+	if r.Spec.MetricExpression == "" {
+		return errList
+	}
+
+	path := spec.Child("metricExpression")
+
+	expr, err := expression.Parse(r.Spec.MetricExpression)
+	if err != nil {
+		return append(errList, field.Invalid(path, r.Spec.MetricExpression, err.Error()))
+	}
+
+	for _, v := range expr.Variables() {
+		if !names[v] && !reserved[v] {
+			errList = append(errList, field.Invalid(path, r.Spec.MetricExpression, fmt.Sprintf("references %q, which is neither the name of a metric other than %s and %s, nor one of %s and %s", v, AutoscalingMetricTypeHistoricalDesiredReplicas, AutoscalingMetricTypeOfflineRunners, MetricExpressionVariableBusyRunners, MetricExpressionVariableQueueDepth)))
+		}
+	}
+
+	return errList
+}
+
+// validateMetricAggregation validates MetricAggregation and the weights of the metrics it sums.
+func (r *HorizontalRunnerAutoscaler) validateMetricAggregation(spec *field.Path) field.ErrorList {
+	var errList field.ErrorList
+
+	weighted := r.Spec.MetricAggregation == MetricAggregationWeightedSum
+
+	switch r.Spec.MetricAggregation {
+	case "", MetricAggregationMax:
+	case MetricAggregationWeightedSum:
+		if r.Spec.MetricExpression != "" {
+			errList = append(errList, field.Forbidden(spec.Child("metricAggregation"), "must not be WeightedSum when metricExpression is set"))
+		}
+	default:
+		errList = append(errList, field.NotSupported(spec.Child("metricAggregation"), r.Spec.MetricAggregation, []string{MetricAggregationMax, MetricAggregationWeightedSum}))
+	}
+
+	for i, metric := range r.Spec.Metrics {
+		if metric.Weight == "" {
+			continue
+		}
+
+		path := spec.Child("metrics").Index(i).Child("weight")
+
+		if !weighted {
+			errList = append(errList, field.Forbidden(path, "must not be set unless metricAggregation is WeightedSum"))
+		} else if metric.Type == AutoscalingMetricTypeHistoricalDesiredReplicas || metric.Type == AutoscalingMetricTypeOfflineRunners {
+			errList = append(errList, field.Forbidden(path, fmt.Sprintf("must not be set for %s", metric.Type)))
+		} else if w, err := strconv.ParseFloat(metric.Weight, 64); err != nil || !(w > 0) || math.IsInf(w, 0) {
+			errList = append(errList, field.Invalid(path, metric.Weight, "must be a number greater than 0"))
+		}
+	}
+
+	return errList
+}
@@ -0,0 +1,615 @@
+package v1alpha1
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestHorizontalRunnerAutoscalerValidate(t *testing.T) {
+	intPtr := func(v int) *int {
+		return &v
+	}
+
+	valid := func() HorizontalRunnerAutoscalerSpec {
+		return HorizontalRunnerAutoscalerSpec{
+			ScaleTargetRef: ScaleTargetRef{Name: "example-runnerdeploy"},
+			MinReplicas:    intPtr(1),
+			MaxReplicas:    intPtr(3),
+		}
+	}
+
+	testcases := []struct {
+		name   string
+		modify func(*HorizontalRunnerAutoscalerSpec)
+		err    string
+	}{
+		{
+			name:   "valid",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {},
+		},
+		{
+			name: "min equal to max",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.MinReplicas = intPtr(3)
+			},
+		},
+		{
+			name: "missing scale target name",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.ScaleTargetRef.Name = ""
+			},
+			err: "spec.scaleTargetRef.name: Required value",
+		},
+		{
+			name: "scale target selector",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.ScaleTargetRef = ScaleTargetRef{MatchLabels: map[string]string{"team": "example"}}
+			},
+		},
+		{
+			name: "scale target selector along with name",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.ScaleTargetRef.MatchLabels = map[string]string{"team": "example"}
+			},
+			err: "spec.scaleTargetRef.matchLabels: Invalid value",
+		},
+		{
+			name: "additional scale target selector",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.AdditionalScaleTargetRefs = []ScaleTargetRef{{Name: "example-runnerdeploy-arm", MatchLabels: map[string]string{"team": "example"}}}
+			},
+			err: "spec.additionalScaleTargetRefs[0].matchLabels: Forbidden",
+		},
+		{
+			name: "additional scale targets",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.AdditionalScaleTargetRefs = []ScaleTargetRef{{Name: "example-runnerdeploy-arm"}}
+			},
+		},
+		{
+			name: "additional scale target same as the scale target",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.AdditionalScaleTargetRefs = []ScaleTargetRef{{Name: "example-runnerdeploy"}}
+			},
+			err: `spec.additionalScaleTargetRefs[0].name: Duplicate value: "example-runnerdeploy"`,
+		},
+		{
+			name: "missing additional scale target name",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.AdditionalScaleTargetRefs = []ScaleTargetRef{{}}
+			},
+			err: "spec.additionalScaleTargetRefs[0].name: Required value",
+		},
+		{
+			name: "negative min",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.MinReplicas = intPtr(-1)
+			},
+			err: "spec.minReplicas: Invalid value: -1: must be greater than or equal to 0",
+		},
+		{
+			name: "min greater than max",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.MinReplicas = intPtr(4)
+			},
+			err: "spec.minReplicas: Invalid value: 4: must be less than or equal to maxReplicas(3)",
+		},
+		{
+			name: "min replicas ramp",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.MinReplicasRamp = &MinReplicasRamp{MaxReplicas: 3, StepSeconds: 60}
+			},
+		},
+		{
+			name: "min replicas ramp below min",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.MinReplicasRamp = &MinReplicasRamp{MaxReplicas: 0, StepSeconds: 60}
+			},
+			err: "spec.minReplicasRamp.maxReplicas: Invalid value: 0: must be greater than or equal to minReplicas(1)",
+		},
+		{
+			name: "min replicas ramp without step seconds",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.MinReplicasRamp = &MinReplicasRamp{MaxReplicas: 3}
+			},
+			err: "spec.minReplicasRamp.stepSeconds: Invalid value: 0: must be greater than or equal to 1",
+		},
+		{
+			name: "scale down delay anchored on the last busy time",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.ScaleDownDelayAnchor = ScaleDownDelayAnchorLastBusy
+			},
+		},
+		{
+			name: "unsupported scale down delay anchor",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.ScaleDownDelayAnchor = "LastScaleIn"
+			},
+			err: `spec.scaleDownDelayAnchor: Unsupported value: "LastScaleIn"`,
+		},
+		{
+			name: "events only mode",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.ScaleMode = ScaleModeEventsOnly
+				s.ScaleUpTriggers = []ScaleUpTrigger{{GitHubEvent: &GitHubEventScaleUpTriggerSpec{}, Amount: 1}}
+			},
+		},
+		{
+			name: "events only mode with cold start reservation",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.ScaleMode = ScaleModeEventsOnly
+				s.ColdStartReservation = &ColdStartReservation{}
+			},
+		},
+		{
+			name: "events only mode without webhook source",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.ScaleMode = ScaleModeEventsOnly
+			},
+			err: "spec.scaleUpTriggers: Required value",
+		},
+		{
+			name: "events only mode with metrics",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.ScaleMode = ScaleModeEventsOnly
+				s.ScaleUpTriggers = []ScaleUpTrigger{{GitHubEvent: &GitHubEventScaleUpTriggerSpec{}, Amount: 1}}
+				s.Metrics = []MetricSpec{{Type: AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns}}
+			},
+			err: "spec.metrics: Forbidden",
+		},
+		{
+			name: "unsupported scale mode",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.ScaleMode = "Webhook"
+			},
+			err: `spec.scaleMode: Unsupported value: "Webhook"`,
+		},
+		{
+			name: "scale down decay",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.ScaleDownDecayHalfLifeSeconds = intPtr(600)
+			},
+		},
+		{
+			name: "zero scale down decay half-life",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.ScaleDownDecayHalfLifeSeconds = intPtr(0)
+			},
+			err: "spec.scaleDownDecayHalfLifeSeconds: Invalid value: 0: must be greater than or equal to 1",
+		},
+		{
+			name: "scale down stabilization window",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.ScaleDownStabilizationWindowSeconds = intPtr(300)
+			},
+		},
+		{
+			name: "zero scale down stabilization window",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.ScaleDownStabilizationWindowSeconds = intPtr(0)
+			},
+			err: "spec.scaleDownStabilizationWindowSeconds: Invalid value: 0: must be greater than or equal to 1",
+		},
+		{
+			name: "queue growth panic",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.QueueGrowthPanic = &QueueGrowthPanic{ConsecutiveIncreases: 3}
+			},
+		},
+		{
+			name: "queue growth panic without consecutive increases",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.QueueGrowthPanic = &QueueGrowthPanic{}
+			},
+			err: "spec.queueGrowthPanic.consecutiveIncreases: Invalid value: 0: must be greater than or equal to 1",
+		},
+		{
+			name: "zero max scale up count",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.MaxScaleUpCount = intPtr(0)
+			},
+			err: "spec.maxScaleUpCount: Invalid value: 0: must be greater than or equal to 1",
+		},
+		{
+			name: "burst max replicas",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.BurstMaxReplicas = intPtr(5)
+			},
+		},
+		{
+			name: "burst max replicas below max replicas",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.BurstMaxReplicas = intPtr(2)
+			},
+			err: "spec.burstMaxReplicas: Invalid value: 2: must be greater than or equal to maxReplicas(3)",
+		},
+		{
+			name: "burst max replicas without max replicas",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.MaxReplicas = nil
+				s.BurstMaxReplicas = intPtr(5)
+			},
+			err: "spec.maxReplicas: Required value: must be set along with burstMaxReplicas",
+		},
+		{
+			name: "zero burst refill",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.BurstMaxReplicas = intPtr(5)
+				s.BurstRefillSeconds = intPtr(0)
+			},
+			err: "spec.burstRefillSeconds: Invalid value: 0: must be greater than or equal to 1",
+		},
+		{
+			name: "zero runner idle timeout",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.RunnerIdleTimeoutSeconds = intPtr(0)
+			},
+			err: "spec.runnerIdleTimeoutSeconds: Invalid value: 0: must be greater than or equal to 1",
+		},
+		{
+			name: "negative max capacity reservation replicas",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.MaxCapacityReservationReplicas = intPtr(-1)
+			},
+			err: "spec.maxCapacityReservationReplicas: Invalid value: -1: must be greater than or equal to 0",
+		},
+		{
+			name: "negative scale down delay",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.ScaleDownDelaySecondsAfterScaleUp = intPtr(-1)
+			},
+			err: "spec.scaleDownDelaySecondsAfterScaleOut: Invalid value: -1: must be greater than or equal to 0",
+		},
+		{
+			name: "negative scale down grace",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.ScaleDownGraceSeconds = intPtr(-1)
+			},
+			err: "spec.scaleDownGraceSeconds: Invalid value: -1: must be greater than or equal to 0",
+		},
+		{
+			name: "negative scale up delay",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.ScaleUpDelaySeconds = intPtr(-1)
+			},
+			err: "spec.scaleUpDelaySeconds: Invalid value: -1: must be greater than or equal to 0",
+		},
+		{
+			name: "negative cache duration",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.CacheDurationSeconds = intPtr(-1)
+			},
+			err: "spec.cacheDurationSeconds: Invalid value: -1: must be greater than or equal to 0",
+		},
+		{
+			name: "capacity reservation with duration",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.CapacityReservations = []CapacityReservation{{Duration: metav1.Duration{Duration: 5 * time.Minute}, Replicas: 1}}
+			},
+		},
+		{
+			name: "capacity reservation with negative duration",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.CapacityReservations = []CapacityReservation{{Duration: metav1.Duration{Duration: -time.Minute}, Replicas: 1}}
+			},
+			err: `spec.capacityReservations[0].duration: Invalid value: "-1m0s": must be greater than or equal to 0`,
+		},
+		{
+			name: "github app installation with both id and organization",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.GitHubAppInstallation = &GitHubAppInstallationRef{ID: 1, Organization: "myorg"}
+			},
+			err: "spec.githubAppInstallation: Invalid value",
+		},
+		{
+			name: "github app installation with neither id nor organization",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.GitHubAppInstallation = &GitHubAppInstallationRef{}
+			},
+			err: "spec.githubAppInstallation: Invalid value",
+		},
+		{
+			name: "github api credentials from secret",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.GitHubAPICredentialsFrom = &GitHubAPICredentialsFrom{SecretRef: SecretReference{Name: "team-a-github"}}
+			},
+		},
+		{
+			name: "github api credentials from secret without name",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.GitHubAPICredentialsFrom = &GitHubAPICredentialsFrom{}
+			},
+			err: "spec.githubAPICredentialsFrom.secretRef.name: Required value",
+		},
+		{
+			name: "github api credentials from secret along with github app installation",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.GitHubAPICredentialsFrom = &GitHubAPICredentialsFrom{SecretRef: SecretReference{Name: "team-a-github"}}
+				s.GitHubAppInstallation = &GitHubAppInstallationRef{ID: 1}
+			},
+			err: "spec.githubAPICredentialsFrom: Forbidden",
+		},
+		{
+			name: "metric expression",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.Metrics = []MetricSpec{
+					{Type: AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns, Name: "queued"},
+					{Type: AutoscalingMetricTypePercentageRunnersBusy, Name: "busy"},
+					{Type: AutoscalingMetricTypeHistoricalDesiredReplicas},
+				}
+				s.MetricExpression = "max(queued, ceil(busy / 0.7), busyRunners + queueDepth)"
+			},
+		},
+		{
+			name: "metric expression with syntax error",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.Metrics = []MetricSpec{{Name: "queued"}}
+				s.MetricExpression = "max(queued,"
+			},
+			err: "spec.metricExpression: Invalid value",
+		},
+		{
+			name: "metric expression with unknown function",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.Metrics = []MetricSpec{{Name: "queued"}}
+				s.MetricExpression = "round(queued)"
+			},
+			err: "unknown function",
+		},
+		{
+			name: "metric expression referencing unknown metric",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.Metrics = []MetricSpec{{Name: "queued"}}
+				s.MetricExpression = "queued + busy"
+			},
+			err: `references "busy"`,
+		},
+		{
+			name: "metric expression referencing historical metric",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.Metrics = []MetricSpec{{Name: "queued"}, {Type: AutoscalingMetricTypeHistoricalDesiredReplicas, Name: "history"}}
+				s.MetricExpression = "max(queued, history)"
+			},
+			err: `references "history"`,
+		},
+		{
+			name: "metric expression with unnamed metric",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.Metrics = []MetricSpec{{Name: "queued"}, {Type: AutoscalingMetricTypePercentageRunnersBusy}}
+				s.MetricExpression = "queued"
+			},
+			err: "spec.metrics[1].name: Required value",
+		},
+		{
+			name: "weighted sum",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.Metrics = []MetricSpec{{}, {RepositoryNames: []string{"priority"}, Weight: "2"}, {Type: AutoscalingMetricTypeOfflineRunners}}
+				s.MetricAggregation = MetricAggregationWeightedSum
+			},
+		},
+		{
+			name: "weight without weighted sum",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.Metrics = []MetricSpec{{Weight: "2"}}
+			},
+			err: "spec.metrics[0].weight: Forbidden",
+		},
+		{
+			name: "non-positive weight",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.Metrics = []MetricSpec{{Weight: "0"}}
+				s.MetricAggregation = MetricAggregationWeightedSum
+			},
+			err: "spec.metrics[0].weight: Invalid value",
+		},
+		{
+			name: "invalid weight",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.Metrics = []MetricSpec{{Weight: "two"}}
+				s.MetricAggregation = MetricAggregationWeightedSum
+			},
+			err: "spec.metrics[0].weight: Invalid value",
+		},
+		{
+			name: "weight of offline runners",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.Metrics = []MetricSpec{{}, {Type: AutoscalingMetricTypeOfflineRunners, Weight: "2"}}
+				s.MetricAggregation = MetricAggregationWeightedSum
+			},
+			err: "spec.metrics[1].weight: Forbidden",
+		},
+		{
+			name: "weighted sum with metric expression",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.Metrics = []MetricSpec{{Name: "queued"}}
+				s.MetricExpression = "queued"
+				s.MetricAggregation = MetricAggregationWeightedSum
+			},
+			err: "spec.metricAggregation: Forbidden",
+		},
+		{
+			name: "unsupported metric aggregation",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.MetricAggregation = "Sum"
+			},
+			err: `spec.metricAggregation: Unsupported value: "Sum"`,
+		},
+		{
+			name: "invalid metric name",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.Metrics = []MetricSpec{{Name: "queued-runs"}}
+			},
+			err: "spec.metrics[0].name: Invalid value",
+		},
+		{
+			name: "reserved metric name",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.Metrics = []MetricSpec{{Name: MetricExpressionVariableBusyRunners}}
+			},
+			err: "spec.metrics[0].name: Invalid value",
+		},
+		{
+			name: "scale history within max entries",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.Metrics = []MetricSpec{{}, {Type: AutoscalingMetricTypeHistoricalDesiredReplicas, LookbackDays: 60, BucketSeconds: 5068}}
+			},
+		},
+		{
+			name: "scale history exceeding max entries",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.Metrics = []MetricSpec{{}, {Type: AutoscalingMetricTypeHistoricalDesiredReplicas, LookbackDays: 60, BucketSeconds: 5067}}
+			},
+			err: "spec.metrics[1].bucketSeconds: Invalid value: 5067: must be greater than or equal to 5068",
+		},
+		{
+			name: "scale history exceeding max entries with default bucket",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.Metrics = []MetricSpec{{}, {Type: AutoscalingMetricTypeHistoricalDesiredReplicas, LookbackDays: 60}}
+			},
+			err: "spec.metrics[1].bucketSeconds: Invalid value: 3600",
+		},
+		{
+			name: "duplicate metric name",
+			modify: func(s *HorizontalRunnerAutoscalerSpec) {
+				s.Metrics = []MetricSpec{{Name: "queued"}, {Type: AutoscalingMetricTypeHistoricalDesiredReplicas, Name: "queued"}}
+			},
+			err: "spec.metrics[1].name: Duplicate value",
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			spec := valid()
+			tc.modify(&spec)
+
+			hra := &HorizontalRunnerAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "example-hra"},
+				Spec:       spec,
+			}
+
+			err := hra.Validate()
+
+			if tc.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("expected error containing %q, got none", tc.err)
+			}
+
+			if !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("expected error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+// newTestWebhookClient makes the validations look up the given objects, until the test completes.
+func newTestWebhookClient(t *testing.T, objs ...runtime.Object) {
+	t.Helper()
+
+	scheme := runtime.NewScheme()
+	if err := AddToScheme(scheme); err != nil {
+		t.Fatalf("failed adding to scheme: %v", err)
+	}
+
+	webhookClient = clientfake.NewFakeClientWithScheme(scheme, objs...)
+
+	t.Cleanup(func() {
+		webhookClient = nil
+	})
+}
+
+func newTestRunnerDeployment(labels ...string) *RunnerDeployment {
+	return &RunnerDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "example-runnerdeploy", Namespace: "default"},
+		Spec: RunnerDeploymentSpec{
+			Template: RunnerTemplate{
+				Spec: RunnerSpec{Repository: "test/valid", Labels: labels},
+			},
+		},
+	}
+}
+
+func newTestWorkflowJobHRA(labels ...string) *HorizontalRunnerAutoscaler {
+	return &HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "example-hra", Namespace: "default"},
+		Spec: HorizontalRunnerAutoscalerSpec{
+			ScaleTargetRef: ScaleTargetRef{Name: "example-runnerdeploy"},
+			ScaleUpTriggers: []ScaleUpTrigger{
+				{GitHubEvent: &GitHubEventScaleUpTriggerSpec{WorkflowJob: &WorkflowJobSpec{Labels: labels}}},
+			},
+		},
+	}
+}
+
+func TestHorizontalRunnerAutoscalerValidate_WorkflowJobLabels(t *testing.T) {
+	testcases := []struct {
+		name         string
+		runnerLabels []string
+		noRD         bool
+		jobLabels    []string
+		err          string
+	}{
+		{
+			name:         "matching labels",
+			runnerLabels: []string{"linux", "gpu"},
+			jobLabels:    []string{"self-hosted", "GPU"},
+		},
+		{
+			name:         "no labels",
+			runnerLabels: []string{"linux"},
+		},
+		{
+			name:         "label missing from the runners",
+			runnerLabels: []string{"linux"},
+			jobLabels:    []string{"gpu"},
+			err:          "spec.scaleUpTriggers[0].githubEvent.workflowJob.labels: Invalid value",
+		},
+		{
+			name:      "runner deployment not created yet",
+			noRD:      true,
+			jobLabels: []string{"gpu"},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			var objs []runtime.Object
+			if !tc.noRD {
+				objs = append(objs, newTestRunnerDeployment(tc.runnerLabels...))
+			}
+
+			newTestWebhookClient(t, objs...)
+
+			err := newTestWorkflowJobHRA(tc.jobLabels...).Validate()
+
+			if tc.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("expected error containing %q, got none", tc.err)
+			}
+
+			if !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("expected error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
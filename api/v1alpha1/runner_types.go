@@ -18,6 +18,7 @@ package v1alpha1
 
 import (
 	"errors"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -117,6 +118,33 @@ func (rs *RunnerSpec) ValidateRepository() error {
 	return nil
 }
 
+// RunnerLabelsMatchJobLabels returns true when the runners have all the labels requested by the job.
+// Labels are compared case-insensitively as GitHub does.
+// The "self-hosted" label is ignored, as all the runners managed by the controller are self-hosted.
+func RunnerLabelsMatchJobLabels(runnerLabels, jobLabels []string) bool {
+	for _, l := range jobLabels {
+		if strings.EqualFold(l, "self-hosted") {
+			continue
+		}
+
+		var matched bool
+
+		for _, rl := range runnerLabels {
+			if strings.EqualFold(l, rl) {
+				matched = true
+
+				break
+			}
+		}
+
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
 // RunnerStatus defines the observed state of Runner
 type RunnerStatus struct {
 	Registration RunnerStatusRegistration `json:"registration"`
@@ -23,6 +23,15 @@ import (
 const (
 	AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns = "TotalNumberOfQueuedAndInProgressWorkflowRuns"
 	AutoscalingMetricTypePercentageRunnersBusy                        = "PercentageRunnersBusy"
+	AutoscalingMetricTypeHistoricalDesiredReplicas                    = "HistoricalDesiredReplicas"
+	AutoscalingMetricTypePercentageRunnerGroupBusy                    = "PercentageRunnerGroupBusy"
+	AutoscalingMetricTypeHTTPEndpoint                                 = "HTTPEndpoint"
+	AutoscalingMetricTypePrometheus                                   = "Prometheus"
+
+	AutoscalingMetricTypeDurationWeightedQueuedAndInProgressWorkflowRuns = "DurationWeightedQueuedAndInProgressWorkflowRuns"
+	AutoscalingMetricTypeOfflineRunners                                  = "OfflineRunners"
+	AutoscalingMetricTypeExternalObject                                  = "ExternalObject"
+	AutoscalingMetricTypeRecentPushAndPullRequestEvents                  = "RecentPushAndPullRequestEvents"
 )
 
 // RunnerReplicaSetSpec defines the desired state of RunnerDeployment
@@ -42,6 +51,11 @@ type RunnerDeploymentStatus struct {
 	// This doesn't include outdated pods while upgrading the deployment and replacing the runnerset.
 	// +optional
 	Replicas *int `json:"desiredReplicas,omitempty"`
+
+	// ObservedGeneration is the most recent generation of the RunnerDeployment whose spec is reflected
+	// in the newest RunnerReplicaSet by the controller.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 }
 
 // +kubebuilder:object:root=true
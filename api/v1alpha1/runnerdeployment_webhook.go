@@ -17,10 +17,14 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"context"
+	"fmt"
+
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 )
@@ -29,6 +33,8 @@ import (
 var runenrDeploymentLog = logf.Log.WithName("runnerdeployment-resource")
 
 func (r *RunnerDeployment) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	webhookClient = mgr.GetClient()
+
 	return ctrl.NewWebhookManagedBy(mgr).
 		For(r).
 		Complete()
@@ -76,9 +82,57 @@ func (r *RunnerDeployment) Validate() error {
 		errList = append(errList, field.Invalid(field.NewPath("spec", "template", "spec", "repository"), r.Spec.Template.Spec.Repository, err.Error()))
 	}
 
+	errList = append(errList, r.validateWorkflowJobLabels()...)
+
 	if len(errList) > 0 {
 		return apierrors.NewInvalid(r.GroupVersionKind().GroupKind(), r.Name, errList)
 	}
 
 	return nil
 }
+
+// validateWorkflowJobLabels rejects the runner labels missing any of the labels of the workflowJob scale-up triggers
+// of the HorizontalRunnerAutoscalers scaling the RunnerDeployment, as the triggers would never fire otherwise.
+func (r *RunnerDeployment) validateWorkflowJobLabels() field.ErrorList {
+	if webhookClient == nil {
+		return nil
+	}
+
+	path := field.NewPath("spec", "template", "spec", "labels")
+
+	var hras HorizontalRunnerAutoscalerList
+
+	if err := webhookClient.List(context.TODO(), &hras, client.InNamespace(r.Namespace)); err != nil {
+		return field.ErrorList{field.InternalError(path, err)}
+	}
+
+	var errList field.ErrorList
+
+	for _, hra := range hras.Items {
+		var targeted bool
+
+		for _, name := range hra.runnerDeploymentNames() {
+			if name == r.Name {
+				targeted = true
+
+				break
+			}
+		}
+
+		if !targeted {
+			continue
+		}
+
+		for _, trigger := range hra.Spec.ScaleUpTriggers {
+			if trigger.GitHubEvent == nil || trigger.GitHubEvent.WorkflowJob == nil {
+				continue
+			}
+
+			if labels := trigger.GitHubEvent.WorkflowJob.Labels; !RunnerLabelsMatchJobLabels(r.Spec.Template.Spec.Labels, labels) {
+				errList = append(errList, field.Invalid(path, r.Spec.Template.Spec.Labels, fmt.Sprintf("must include the labels %v of the workflowJob scale-up trigger of HorizontalRunnerAutoscaler %s", labels, hra.Name)))
+			}
+		}
+	}
+
+	return errList
+}
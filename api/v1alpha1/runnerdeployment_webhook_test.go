@@ -0,0 +1,65 @@
+package v1alpha1
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestRunnerDeploymentValidate_WorkflowJobLabels(t *testing.T) {
+	testcases := []struct {
+		name         string
+		runnerLabels []string
+		noHRA        bool
+		jobLabels    []string
+		err          string
+	}{
+		{
+			name:         "matching labels",
+			runnerLabels: []string{"linux", "gpu"},
+			jobLabels:    []string{"gpu"},
+		},
+		{
+			name:         "label removed from the runners",
+			runnerLabels: []string{"linux"},
+			jobLabels:    []string{"gpu"},
+			err:          "spec.template.spec.labels: Invalid value",
+		},
+		{
+			name:         "not scaled by any autoscaler",
+			runnerLabels: []string{"linux"},
+			noHRA:        true,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			var objs []runtime.Object
+			if !tc.noHRA {
+				objs = append(objs, newTestWorkflowJobHRA(tc.jobLabels...))
+			}
+
+			newTestWebhookClient(t, objs...)
+
+			err := newTestRunnerDeployment(tc.runnerLabels...).Validate()
+
+			if tc.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("expected error containing %q, got none", tc.err)
+			}
+
+			if !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("expected error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
@@ -25,10 +25,42 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ActiveCapacityReservations) DeepCopyInto(out *ActiveCapacityReservations) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActiveCapacityReservations.
+func (in *ActiveCapacityReservations) DeepCopy() *ActiveCapacityReservations {
+	if in == nil {
+		return nil
+	}
+	out := new(ActiveCapacityReservations)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BurstCreditsStatus) DeepCopyInto(out *BurstCreditsStatus) {
+	*out = *in
+	in.LastUpdateTime.DeepCopyInto(&out.LastUpdateTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BurstCreditsStatus.
+func (in *BurstCreditsStatus) DeepCopy() *BurstCreditsStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BurstCreditsStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CacheEntry) DeepCopyInto(out *CacheEntry) {
 	*out = *in
 	in.ExpirationTime.DeepCopyInto(&out.ExpirationTime)
+	in.CreationTime.DeepCopyInto(&out.CreationTime)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CacheEntry.
@@ -45,6 +77,8 @@ func (in *CacheEntry) DeepCopy() *CacheEntry {
 func (in *CapacityReservation) DeepCopyInto(out *CapacityReservation) {
 	*out = *in
 	in.ExpirationTime.DeepCopyInto(&out.ExpirationTime)
+	out.Duration = in.Duration
+	in.EffectiveTime.DeepCopyInto(&out.EffectiveTime)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CapacityReservation.
@@ -77,6 +111,88 @@ func (in *CheckRunSpec) DeepCopy() *CheckRunSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CheckSuiteSpec) DeepCopyInto(out *CheckSuiteSpec) {
+	*out = *in
+	if in.Types != nil {
+		in, out := &in.Types, &out.Types
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CheckSuiteSpec.
+func (in *CheckSuiteSpec) DeepCopy() *CheckSuiteSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CheckSuiteSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ColdStartReservation) DeepCopyInto(out *ColdStartReservation) {
+	*out = *in
+	out.Duration = in.Duration
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ColdStartReservation.
+func (in *ColdStartReservation) DeepCopy() *ColdStartReservation {
+	if in == nil {
+		return nil
+	}
+	out := new(ColdStartReservation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExternalObjectMetricSource) DeepCopyInto(out *ExternalObjectMetricSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExternalObjectMetricSource.
+func (in *ExternalObjectMetricSource) DeepCopy() *ExternalObjectMetricSource {
+	if in == nil {
+		return nil
+	}
+	out := new(ExternalObjectMetricSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitHubAPICredentialsFrom) DeepCopyInto(out *GitHubAPICredentialsFrom) {
+	*out = *in
+	out.SecretRef = in.SecretRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitHubAPICredentialsFrom.
+func (in *GitHubAPICredentialsFrom) DeepCopy() *GitHubAPICredentialsFrom {
+	if in == nil {
+		return nil
+	}
+	out := new(GitHubAPICredentialsFrom)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitHubAppInstallationRef) DeepCopyInto(out *GitHubAppInstallationRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitHubAppInstallationRef.
+func (in *GitHubAppInstallationRef) DeepCopy() *GitHubAppInstallationRef {
+	if in == nil {
+		return nil
+	}
+	out := new(GitHubAppInstallationRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GitHubEventScaleUpTriggerSpec) DeepCopyInto(out *GitHubEventScaleUpTriggerSpec) {
 	*out = *in
@@ -85,6 +201,11 @@ func (in *GitHubEventScaleUpTriggerSpec) DeepCopyInto(out *GitHubEventScaleUpTri
 		*out = new(CheckRunSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.CheckSuite != nil {
+		in, out := &in.CheckSuite, &out.CheckSuite
+		*out = new(CheckSuiteSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.PullRequest != nil {
 		in, out := &in.PullRequest, &out.PullRequest
 		*out = new(PullRequestSpec)
@@ -95,6 +216,11 @@ func (in *GitHubEventScaleUpTriggerSpec) DeepCopyInto(out *GitHubEventScaleUpTri
 		*out = new(PushSpec)
 		**out = **in
 	}
+	if in.WorkflowJob != nil {
+		in, out := &in.WorkflowJob, &out.WorkflowJob
+		*out = new(WorkflowJobSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitHubEventScaleUpTriggerSpec.
@@ -107,6 +233,26 @@ func (in *GitHubEventScaleUpTriggerSpec) DeepCopy() *GitHubEventScaleUpTriggerSp
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HTTPEndpointMetricSource) DeepCopyInto(out *HTTPEndpointMetricSource) {
+	*out = *in
+	if in.AuthSecretRef != nil {
+		in, out := &in.AuthSecretRef, &out.AuthSecretRef
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPEndpointMetricSource.
+func (in *HTTPEndpointMetricSource) DeepCopy() *HTTPEndpointMetricSource {
+	if in == nil {
+		return nil
+	}
+	out := new(HTTPEndpointMetricSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HorizontalRunnerAutoscaler) DeepCopyInto(out *HorizontalRunnerAutoscaler) {
 	*out = *in
@@ -134,6 +280,22 @@ func (in *HorizontalRunnerAutoscaler) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HorizontalRunnerAutoscalerCondition) DeepCopyInto(out *HorizontalRunnerAutoscalerCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HorizontalRunnerAutoscalerCondition.
+func (in *HorizontalRunnerAutoscalerCondition) DeepCopy() *HorizontalRunnerAutoscalerCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(HorizontalRunnerAutoscalerCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HorizontalRunnerAutoscalerList) DeepCopyInto(out *HorizontalRunnerAutoscalerList) {
 	*out = *in
@@ -169,7 +331,59 @@ func (in *HorizontalRunnerAutoscalerList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HorizontalRunnerAutoscalerSpec) DeepCopyInto(out *HorizontalRunnerAutoscalerSpec) {
 	*out = *in
-	out.ScaleTargetRef = in.ScaleTargetRef
+	in.ScaleTargetRef.DeepCopyInto(&out.ScaleTargetRef)
+	if in.AdditionalScaleTargetRefs != nil {
+		in, out := &in.AdditionalScaleTargetRefs, &out.AdditionalScaleTargetRefs
+		*out = make([]ScaleTargetRef, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.GitHubAppInstallation != nil {
+		in, out := &in.GitHubAppInstallation, &out.GitHubAppInstallation
+		*out = new(GitHubAppInstallationRef)
+		**out = **in
+	}
+	if in.GitHubAPICredentialsFrom != nil {
+		in, out := &in.GitHubAPICredentialsFrom, &out.GitHubAPICredentialsFrom
+		*out = new(GitHubAPICredentialsFrom)
+		**out = **in
+	}
+	if in.MaxPendingRunnerPods != nil {
+		in, out := &in.MaxPendingRunnerPods, &out.MaxPendingRunnerPods
+		*out = new(int)
+		**out = **in
+	}
+	if in.MaxScaleUpCount != nil {
+		in, out := &in.MaxScaleUpCount, &out.MaxScaleUpCount
+		*out = new(int)
+		**out = **in
+	}
+	if in.BurstMaxReplicas != nil {
+		in, out := &in.BurstMaxReplicas, &out.BurstMaxReplicas
+		*out = new(int)
+		**out = **in
+	}
+	if in.BurstDurationSeconds != nil {
+		in, out := &in.BurstDurationSeconds, &out.BurstDurationSeconds
+		*out = new(int)
+		**out = **in
+	}
+	if in.BurstRefillSeconds != nil {
+		in, out := &in.BurstRefillSeconds, &out.BurstRefillSeconds
+		*out = new(int)
+		**out = **in
+	}
+	if in.Weight != nil {
+		in, out := &in.Weight, &out.Weight
+		*out = new(int)
+		**out = **in
+	}
+	if in.PolicyRef != nil {
+		in, out := &in.PolicyRef, &out.PolicyRef
+		*out = new(PolicyRef)
+		**out = **in
+	}
 	if in.MinReplicas != nil {
 		in, out := &in.MinReplicas, &out.MinReplicas
 		*out = new(int)
@@ -180,11 +394,71 @@ func (in *HorizontalRunnerAutoscalerSpec) DeepCopyInto(out *HorizontalRunnerAuto
 		*out = new(int)
 		**out = **in
 	}
+	if in.MinReplicasRamp != nil {
+		in, out := &in.MinReplicasRamp, &out.MinReplicasRamp
+		*out = new(MinReplicasRamp)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.ScaleDownDelaySecondsAfterScaleUp != nil {
 		in, out := &in.ScaleDownDelaySecondsAfterScaleUp, &out.ScaleDownDelaySecondsAfterScaleUp
 		*out = new(int)
 		**out = **in
 	}
+	if in.ScaleDownDecayHalfLifeSeconds != nil {
+		in, out := &in.ScaleDownDecayHalfLifeSeconds, &out.ScaleDownDecayHalfLifeSeconds
+		*out = new(int)
+		**out = **in
+	}
+	if in.ScaleDownStabilizationWindowSeconds != nil {
+		in, out := &in.ScaleDownStabilizationWindowSeconds, &out.ScaleDownStabilizationWindowSeconds
+		*out = new(int)
+		**out = **in
+	}
+	if in.ScaleDownStabilization != nil {
+		in, out := &in.ScaleDownStabilization, &out.ScaleDownStabilization
+		*out = new(ScaleDownStabilization)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ScaleDownGraceSeconds != nil {
+		in, out := &in.ScaleDownGraceSeconds, &out.ScaleDownGraceSeconds
+		*out = new(int)
+		**out = **in
+	}
+	if in.MaxScaleDownStallSeconds != nil {
+		in, out := &in.MaxScaleDownStallSeconds, &out.MaxScaleDownStallSeconds
+		*out = new(int)
+		**out = **in
+	}
+	if in.RunnerStartupGraceSeconds != nil {
+		in, out := &in.RunnerStartupGraceSeconds, &out.RunnerStartupGraceSeconds
+		*out = new(int)
+		**out = **in
+	}
+	if in.RunnerIdleTimeoutSeconds != nil {
+		in, out := &in.RunnerIdleTimeoutSeconds, &out.RunnerIdleTimeoutSeconds
+		*out = new(int)
+		**out = **in
+	}
+	if in.ScaleOutScheduling != nil {
+		in, out := &in.ScaleOutScheduling, &out.ScaleOutScheduling
+		*out = new(ScaleOutScheduling)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ScaleUpDelaySeconds != nil {
+		in, out := &in.ScaleUpDelaySeconds, &out.ScaleUpDelaySeconds
+		*out = new(int)
+		**out = **in
+	}
+	if in.QueueGrowthPanic != nil {
+		in, out := &in.QueueGrowthPanic, &out.QueueGrowthPanic
+		*out = new(QueueGrowthPanic)
+		**out = **in
+	}
+	if in.CacheDurationSeconds != nil {
+		in, out := &in.CacheDurationSeconds, &out.CacheDurationSeconds
+		*out = new(int)
+		**out = **in
+	}
 	if in.Metrics != nil {
 		in, out := &in.Metrics, &out.Metrics
 		*out = make([]MetricSpec, len(*in))
@@ -206,6 +480,23 @@ func (in *HorizontalRunnerAutoscalerSpec) DeepCopyInto(out *HorizontalRunnerAuto
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.MaxCapacityReservationReplicas != nil {
+		in, out := &in.MaxCapacityReservationReplicas, &out.MaxCapacityReservationReplicas
+		*out = new(int)
+		**out = **in
+	}
+	if in.ColdStartReservation != nil {
+		in, out := &in.ColdStartReservation, &out.ColdStartReservation
+		*out = new(ColdStartReservation)
+		**out = **in
+	}
+	if in.ScheduledOverrides != nil {
+		in, out := &in.ScheduledOverrides, &out.ScheduledOverrides
+		*out = make([]ScheduledOverride, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HorizontalRunnerAutoscalerSpec.
@@ -230,6 +521,15 @@ func (in *HorizontalRunnerAutoscalerStatus) DeepCopyInto(out *HorizontalRunnerAu
 		in, out := &in.LastSuccessfulScaleOutTime, &out.LastSuccessfulScaleOutTime
 		*out = (*in).DeepCopy()
 	}
+	if in.LastScaleOutDesiredReplicas != nil {
+		in, out := &in.LastScaleOutDesiredReplicas, &out.LastScaleOutDesiredReplicas
+		*out = new(int)
+		**out = **in
+	}
+	if in.LastScaleUpTime != nil {
+		in, out := &in.LastScaleUpTime, &out.LastScaleUpTime
+		*out = (*in).DeepCopy()
+	}
 	if in.CacheEntries != nil {
 		in, out := &in.CacheEntries, &out.CacheEntries
 		*out = make([]CacheEntry, len(*in))
@@ -237,6 +537,85 @@ func (in *HorizontalRunnerAutoscalerStatus) DeepCopyInto(out *HorizontalRunnerAu
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.CacheExpiresAt != nil {
+		in, out := &in.CacheExpiresAt, &out.CacheExpiresAt
+		*out = (*in).DeepCopy()
+	}
+	if in.BusyRunners != nil {
+		in, out := &in.BusyRunners, &out.BusyRunners
+		*out = new(int)
+		**out = **in
+	}
+	if in.LastBusyTime != nil {
+		in, out := &in.LastBusyTime, &out.LastBusyTime
+		*out = (*in).DeepCopy()
+	}
+	if in.UncappedDesiredReplicas != nil {
+		in, out := &in.UncappedDesiredReplicas, &out.UncappedDesiredReplicas
+		*out = new(int)
+		**out = **in
+	}
+	if in.LastMaxReplicasReachedTime != nil {
+		in, out := &in.LastMaxReplicasReachedTime, &out.LastMaxReplicasReachedTime
+		*out = (*in).DeepCopy()
+	}
+	if in.BurstCredits != nil {
+		in, out := &in.BurstCredits, &out.BurstCredits
+		*out = new(BurstCreditsStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ScaleDownStalledSince != nil {
+		in, out := &in.ScaleDownStalledSince, &out.ScaleDownStalledSince
+		*out = (*in).DeepCopy()
+	}
+	if in.ContinuousDemandSince != nil {
+		in, out := &in.ContinuousDemandSince, &out.ContinuousDemandSince
+		*out = (*in).DeepCopy()
+	}
+	if in.LastError != nil {
+		in, out := &in.LastError, &out.LastError
+		*out = new(LastError)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]HorizontalRunnerAutoscalerCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	out.ActiveCapacityReservations = in.ActiveCapacityReservations
+	if in.ScheduledOverridesSummary != nil {
+		in, out := &in.ScheduledOverridesSummary, &out.ScheduledOverridesSummary
+		*out = new(string)
+		**out = **in
+	}
+	if in.Recommendations != nil {
+		in, out := &in.Recommendations, &out.Recommendations
+		*out = make([]ReplicasRecommendation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.QueueDepthHistory != nil {
+		in, out := &in.QueueDepthHistory, &out.QueueDepthHistory
+		*out = make([]int, len(*in))
+		copy(*out, *in)
+	}
+	if in.ScaleHistory != nil {
+		in, out := &in.ScaleHistory, &out.ScaleHistory
+		*out = make([]ScaleHistoryEntry, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ScaleEvents != nil {
+		in, out := &in.ScaleEvents, &out.ScaleEvents
+		*out = make([]ScaleEvent, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HorizontalRunnerAutoscalerStatus.
@@ -249,6 +628,22 @@ func (in *HorizontalRunnerAutoscalerStatus) DeepCopy() *HorizontalRunnerAutoscal
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LastError) DeepCopyInto(out *LastError) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LastError.
+func (in *LastError) DeepCopy() *LastError {
+	if in == nil {
+		return nil
+	}
+	out := new(LastError)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MetricSpec) DeepCopyInto(out *MetricSpec) {
 	*out = *in
@@ -257,6 +652,41 @@ func (in *MetricSpec) DeepCopyInto(out *MetricSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.IncludeInProgress != nil {
+		in, out := &in.IncludeInProgress, &out.IncludeInProgress
+		*out = new(bool)
+		**out = **in
+	}
+	if in.EventLookbackSeconds != nil {
+		in, out := &in.EventLookbackSeconds, &out.EventLookbackSeconds
+		*out = new(int)
+		**out = **in
+	}
+	if in.ReferenceDurationSeconds != nil {
+		in, out := &in.ReferenceDurationSeconds, &out.ReferenceDurationSeconds
+		*out = new(int)
+		**out = **in
+	}
+	if in.TargetUtilizationPercent != nil {
+		in, out := &in.TargetUtilizationPercent, &out.TargetUtilizationPercent
+		*out = new(int)
+		**out = **in
+	}
+	if in.HTTPEndpoint != nil {
+		in, out := &in.HTTPEndpoint, &out.HTTPEndpoint
+		*out = new(HTTPEndpointMetricSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Prometheus != nil {
+		in, out := &in.Prometheus, &out.Prometheus
+		*out = new(PrometheusMetricSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ExternalObject != nil {
+		in, out := &in.ExternalObject, &out.ExternalObject
+		*out = new(ExternalObjectMetricSource)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetricSpec.
@@ -269,6 +699,61 @@ func (in *MetricSpec) DeepCopy() *MetricSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MinReplicasRamp) DeepCopyInto(out *MinReplicasRamp) {
+	*out = *in
+	if in.StepReplicas != nil {
+		in, out := &in.StepReplicas, &out.StepReplicas
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MinReplicasRamp.
+func (in *MinReplicasRamp) DeepCopy() *MinReplicasRamp {
+	if in == nil {
+		return nil
+	}
+	out := new(MinReplicasRamp)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PolicyRef) DeepCopyInto(out *PolicyRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolicyRef.
+func (in *PolicyRef) DeepCopy() *PolicyRef {
+	if in == nil {
+		return nil
+	}
+	out := new(PolicyRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrometheusMetricSource) DeepCopyInto(out *PrometheusMetricSource) {
+	*out = *in
+	if in.AuthSecretRef != nil {
+		in, out := &in.AuthSecretRef, &out.AuthSecretRef
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusMetricSource.
+func (in *PrometheusMetricSource) DeepCopy() *PrometheusMetricSource {
+	if in == nil {
+		return nil
+	}
+	out := new(PrometheusMetricSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PullRequestSpec) DeepCopyInto(out *PullRequestSpec) {
 	*out = *in
@@ -309,6 +794,53 @@ func (in *PushSpec) DeepCopy() *PushSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *QueueGrowthPanic) DeepCopyInto(out *QueueGrowthPanic) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QueueGrowthPanic.
+func (in *QueueGrowthPanic) DeepCopy() *QueueGrowthPanic {
+	if in == nil {
+		return nil
+	}
+	out := new(QueueGrowthPanic)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RecurrenceRule) DeepCopyInto(out *RecurrenceRule) {
+	*out = *in
+	in.UntilTime.DeepCopyInto(&out.UntilTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RecurrenceRule.
+func (in *RecurrenceRule) DeepCopy() *RecurrenceRule {
+	if in == nil {
+		return nil
+	}
+	out := new(RecurrenceRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplicasRecommendation) DeepCopyInto(out *ReplicasRecommendation) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplicasRecommendation.
+func (in *ReplicasRecommendation) DeepCopy() *ReplicasRecommendation {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplicasRecommendation)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Runner) DeepCopyInto(out *Runner) {
 	*out = *in
@@ -744,9 +1276,97 @@ func (in *RunnerTemplate) DeepCopy() *RunnerTemplate {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScaleDownStabilization) DeepCopyInto(out *ScaleDownStabilization) {
+	*out = *in
+	if in.MaxScaleDownCount != nil {
+		in, out := &in.MaxScaleDownCount, &out.MaxScaleDownCount
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScaleDownStabilization.
+func (in *ScaleDownStabilization) DeepCopy() *ScaleDownStabilization {
+	if in == nil {
+		return nil
+	}
+	out := new(ScaleDownStabilization)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScaleEvent) DeepCopyInto(out *ScaleEvent) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScaleEvent.
+func (in *ScaleEvent) DeepCopy() *ScaleEvent {
+	if in == nil {
+		return nil
+	}
+	out := new(ScaleEvent)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScaleHistoryEntry) DeepCopyInto(out *ScaleHistoryEntry) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScaleHistoryEntry.
+func (in *ScaleHistoryEntry) DeepCopy() *ScaleHistoryEntry {
+	if in == nil {
+		return nil
+	}
+	out := new(ScaleHistoryEntry)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScaleOutScheduling) DeepCopyInto(out *ScaleOutScheduling) {
+	*out = *in
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]v1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScaleOutScheduling.
+func (in *ScaleOutScheduling) DeepCopy() *ScaleOutScheduling {
+	if in == nil {
+		return nil
+	}
+	out := new(ScaleOutScheduling)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ScaleTargetRef) DeepCopyInto(out *ScaleTargetRef) {
 	*out = *in
+	if in.MatchLabels != nil {
+		in, out := &in.MatchLabels, &out.MatchLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScaleTargetRef.
@@ -779,3 +1399,71 @@ func (in *ScaleUpTrigger) DeepCopy() *ScaleUpTrigger {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScheduledOverride) DeepCopyInto(out *ScheduledOverride) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	in.EndTime.DeepCopyInto(&out.EndTime)
+	if in.MinReplicas != nil {
+		in, out := &in.MinReplicas, &out.MinReplicas
+		*out = new(int)
+		**out = **in
+	}
+	if in.MaxReplicas != nil {
+		in, out := &in.MaxReplicas, &out.MaxReplicas
+		*out = new(int)
+		**out = **in
+	}
+	if in.SoftMaxReplicas != nil {
+		in, out := &in.SoftMaxReplicas, &out.SoftMaxReplicas
+		*out = new(int)
+		**out = **in
+	}
+	in.RecurrenceRule.DeepCopyInto(&out.RecurrenceRule)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScheduledOverride.
+func (in *ScheduledOverride) DeepCopy() *ScheduledOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(ScheduledOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretReference) DeepCopyInto(out *SecretReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretReference.
+func (in *SecretReference) DeepCopy() *SecretReference {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkflowJobSpec) DeepCopyInto(out *WorkflowJobSpec) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkflowJobSpec.
+func (in *WorkflowJobSpec) DeepCopy() *WorkflowJobSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkflowJobSpec)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -22,6 +22,7 @@ import (
 	"flag"
 	"net/http"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -61,6 +62,10 @@ func main() {
 
 		watchNamespace string
 
+		workflowJobCapacityReservationTTL time.Duration
+
+		enabledEvents string
+
 		enableLeaderElection bool
 		syncPeriod           time.Duration
 	)
@@ -73,8 +78,36 @@ func main() {
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. Enabling this will ensure there is only one active controller manager.")
 	flag.DurationVar(&syncPeriod, "sync-period", 10*time.Minute, "Determines the minimum frequency at which K8s resources managed by this controller are reconciled. When you use autoscaling, set to a lower value like 10 minute, because this corresponds to the minimum time to react on demand change")
+	flag.DurationVar(&workflowJobCapacityReservationTTL, "workflow-job-capacity-reservation-ttl", controllers.DefaultWorkflowJobCapacityReservationTTL, "The duration of a capacity reservation added on each queued workflow_job event, used when the scale-up trigger of the HorizontalRunnerAutoscaler doesn't specify its duration.")
+	flag.StringVar(&enabledEvents, "enabled-events", "", "Comma-separated list of the GitHub webhook event types to scale on, out of "+strings.Join(controllers.SupportedGitHubWebhookEvents, ", ")+". The events of the other types are ignored. Set to empty for enabling all of them.")
 	flag.Parse()
 
+	var events []string
+
+	for _, e := range strings.Split(enabledEvents, ",") {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+
+		var supported bool
+
+		for _, s := range controllers.SupportedGitHubWebhookEvents {
+			if e == s {
+				supported = true
+
+				break
+			}
+		}
+
+		if !supported {
+			setupLog.Error(errors.New("unsupported event type"), "-enabled-events contains an event type the webhook server can't scale on", "eventType", e)
+			os.Exit(1)
+		}
+
+		events = append(events, e)
+	}
+
 	if webhookSecretToken == "" {
 		setupLog.Info("-webhook-secret-token is missing or empty. Create one following https://docs.github.com/en/developers/webhooks-and-events/securing-your-webhooks")
 	}
@@ -111,6 +144,9 @@ func main() {
 		Scheme:         mgr.GetScheme(),
 		SecretKeyBytes: []byte(webhookSecretToken),
 		WatchNamespace: watchNamespace,
+
+		WorkflowJobCapacityReservationTTL: workflowJobCapacityReservationTTL,
+		EnabledEvents:                     events,
 	}
 
 	if err = hraGitHubWebhook.SetupWithManager(mgr); err != nil {
@@ -0,0 +1,114 @@
+/*
+Copyright 2021 The actions-runner-controller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// simulate prints the desired replicas the controller would scale a RunnerDeployment to, given the HorizontalRunnerAutoscaler
+// and the RunnerDeployment manifests along with a mocked metric, without accessing a cluster nor GitHub API.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	actionsv1alpha1 "github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	"github.com/summerwind/actions-runner-controller/controllers"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func main() {
+	var (
+		hraPath string
+		rdPath  string
+
+		metricReplicas    int
+		busyRunners       int
+		nilReplicasPolicy string
+
+		verbose bool
+	)
+
+	flag.StringVar(&hraPath, "horizontal-runner-autoscaler", "", "The path to the HorizontalRunnerAutoscaler manifest in YAML or JSON, optionally with its status.")
+	flag.StringVar(&rdPath, "runner-deployment", "", "The path to the RunnerDeployment manifest in YAML or JSON, optionally with its status.")
+	flag.IntVar(&metricReplicas, "metric-replicas", 0, "The mocked desired replicas computed by the metrics, before the delays, capacity reservations, minReplicas and maxReplicas are applied.")
+	flag.IntVar(&busyRunners, "busy-runners", -1, "The mocked number of busy runners observed by the metrics. Set to a negative value when it's unknown.")
+	flag.StringVar(&nilReplicasPolicy, "nil-replicas-policy", string(controllers.DefaultNilReplicasPolicy), "The --nil-replicas-policy of the controller, either MinReplicas or Observed, used when the RunnerDeployment has no spec.replicas.")
+	flag.BoolVar(&verbose, "verbose", false, "Log how the desired replicas are decided.")
+	flag.Parse()
+
+	if hraPath == "" || rdPath == "" {
+		fmt.Fprintln(os.Stderr, "-horizontal-runner-autoscaler and -runner-deployment are required")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	policy, err := controllers.ParseNilReplicasPolicy(nilReplicasPolicy)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var hra actionsv1alpha1.HorizontalRunnerAutoscaler
+	if err := decodeFile(hraPath, &hra); err != nil {
+		fmt.Fprintf(os.Stderr, "reading horizontalrunnerautoscaler: %v\n", err)
+		os.Exit(1)
+	}
+
+	var rd actionsv1alpha1.RunnerDeployment
+	if err := decodeFile(rdPath, &rd); err != nil {
+		fmt.Fprintf(os.Stderr, "reading runnerdeployment: %v\n", err)
+		os.Exit(1)
+	}
+
+	logger := zap.New(func(o *zap.Options) {
+		o.Development = true
+
+		if !verbose {
+			o.DestWritter = ioutil.Discard
+		}
+	})
+
+	var busy *int
+	if busyRunners >= 0 {
+		busy = &busyRunners
+	}
+
+	sim, err := controllers.SimulateScaling(logger, hra, rd, policy, metricReplicas, busy)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "simulating scaling: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("desired replicas: %d\n", sim.DesiredReplicas)
+	fmt.Printf("computed replicas: %d\n", sim.ComputedReplicas)
+	fmt.Printf("reserved replicas: %d\n", sim.ReservedReplicas)
+	fmt.Printf("min replicas: %d\n", sim.MinReplicas)
+	fmt.Printf("max replicas applied: %v\n", sim.MaxReplicasApplied)
+	fmt.Printf("soft max replicas applied: %v\n", sim.SoftMaxReplicasApplied)
+	fmt.Printf("max scale up count applied: %v\n", sim.MaxScaleUpCountApplied)
+}
+
+func decodeFile(path string, obj interface{}) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return yaml.NewYAMLOrJSONDecoder(f, 4096).Decode(obj)
+}
@@ -7,13 +7,22 @@ import (
 	"math"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	gogithub "github.com/google/go-github/v33/github"
 	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	"github.com/summerwind/actions-runner-controller/github"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 const (
+	// DefaultRunnerStartupGrace is the default duration since the creation of a runner for which the runner isn't counted as idle
+	// by the PercentageRunnersBusy metric.
+	DefaultRunnerStartupGrace = 2 * time.Minute
+
 	defaultScaleUpThreshold   = 0.8
 	defaultScaleDownThreshold = 0.3
 	defaultScaleUpFactor      = 1.3
@@ -32,7 +41,41 @@ func getValueAvailableAt(now time.Time, from, to *time.Time, reservedValue int)
 	return &reservedValue
 }
 
-func (r *HorizontalRunnerAutoscalerReconciler) getDesiredReplicasFromCache(hra v1alpha1.HorizontalRunnerAutoscaler) *int {
+// getCacheInputsKey encodes the inputs that the cached desired replicas depend on, cheap enough to compare on every reconciliation.
+// The replicas are the ones the scale target has, or will have once scaled, so that e.g. a manual scale of the runnerdeployment
+// or a change to minReplicas or maxReplicas invalidates the cache before it expires.
+func getCacheInputsKey(hra v1alpha1.HorizontalRunnerAutoscaler, replicas int) string {
+	return fmt.Sprintf("replicas=%d,minReplicas=%d,maxReplicas=%d", replicas, getIntOrDefault(hra.Spec.MinReplicas, -1), getIntOrDefault(hra.Spec.MaxReplicas, -1))
+}
+
+// rekeyCachedDesiredReplicas returns the cache entries with the unexpired desired replicas keyed by the inputs from
+// rekeyed to the inputs to, and whether any entry is rekeyed.
+func rekeyCachedDesiredReplicas(entries []v1alpha1.CacheEntry, from, to string, now time.Time) ([]v1alpha1.CacheEntry, bool) {
+	var rekeyed bool
+
+	result := make([]v1alpha1.CacheEntry, len(entries))
+
+	for i, ent := range entries {
+		if ent.Key == v1alpha1.CacheEntryKeyDesiredReplicas && ent.InputsKey == from && now.Before(ent.ExpirationTime.Time) {
+			ent.InputsKey = to
+			rekeyed = true
+		}
+
+		result[i] = ent
+	}
+
+	return result, rekeyed
+}
+
+func (r *HorizontalRunnerAutoscalerReconciler) getDesiredReplicasFromCache(hra v1alpha1.HorizontalRunnerAutoscaler, inputsKey string, bustTime *time.Time) *int {
+	replicas, _ := r.getCachedDesiredReplicas(hra, inputsKey, bustTime)
+
+	return replicas
+}
+
+// getCachedDesiredReplicas returns the cached desired replicas along with the expiration time of the cache entry,
+// or nils when nothing valid is cached.
+func (r *HorizontalRunnerAutoscalerReconciler) getCachedDesiredReplicas(hra v1alpha1.HorizontalRunnerAutoscaler, inputsKey string, bustTime *time.Time) (*int, *metav1.Time) {
 	var entry *v1alpha1.CacheEntry
 
 	for i := range hra.Status.CacheEntries {
@@ -42,10 +85,24 @@ func (r *HorizontalRunnerAutoscalerReconciler) getDesiredReplicasFromCache(hra v
 			continue
 		}
 
+		// Entries written before the inputs key was introduced are honored until they expire
+		if ent.InputsKey != "" && ent.InputsKey != inputsKey {
+			r.Log.V(1).Info("Ignoring the cache as the inputs changed", "namespace", hra.Namespace, "horizontal_runner_autoscaler", hra.Name, "cached_inputs", ent.InputsKey, "inputs", inputsKey)
+
+			continue
+		}
+
 		if !time.Now().Before(ent.ExpirationTime.Time) {
 			continue
 		}
 
+		// Entries without the creation time are busted by any cache bust
+		if bustTime != nil && bustTime.After(ent.CreationTime.Time) {
+			r.Log.Info("Ignoring the cache as requested by the annotation", "namespace", hra.Namespace, "horizontal_runner_autoscaler", hra.Name, "annotation", AnnotationKeyCacheBust, "cached_at", ent.CreationTime.Format(time.RFC3339))
+
+			continue
+		}
+
 		entry = &ent
 
 		break
@@ -54,69 +111,661 @@ func (r *HorizontalRunnerAutoscalerReconciler) getDesiredReplicasFromCache(hra v
 	if entry != nil {
 		v := getValueAvailableAt(time.Now(), nil, &entry.ExpirationTime.Time, entry.Value)
 		if v != nil {
-			return v
+			return v, &entry.ExpirationTime
 		}
 	}
 
-	return nil
+	return nil, nil
+}
+
+// getLastCachedDesiredReplicas returns the value of the most recently created cache entry of the desired replicas,
+// regardless of its expiration and inputs. It's served while the GitHub API circuit breaker is open,
+// as the stale value is the best guess we have while GitHub API can't be called.
+func getLastCachedDesiredReplicas(hra v1alpha1.HorizontalRunnerAutoscaler) *int {
+	var entry *v1alpha1.CacheEntry
+
+	for i := range hra.Status.CacheEntries {
+		ent := hra.Status.CacheEntries[i]
+
+		if ent.Key != v1alpha1.CacheEntryKeyDesiredReplicas {
+			continue
+		}
+
+		if entry == nil || !ent.CreationTime.Before(&entry.CreationTime) {
+			entry = &ent
+		}
+	}
+
+	if entry == nil {
+		return nil
+	}
+
+	v := entry.Value
+
+	return &v
+}
+
+// usesGitHubAPIMetrics returns true when computing the desired replicas of the HorizontalRunnerAutoscaler calls GitHub API.
+// Metrics of the types provided by MetricProviders are assumed to call GitHub API, as we can't tell.
+func usesGitHubAPIMetrics(hra v1alpha1.HorizontalRunnerAutoscaler) bool {
+	if isEventsOnly(hra) {
+		return false
+	}
+
+	for _, metric := range hra.Spec.Metrics {
+		switch metric.Type {
+		case v1alpha1.AutoscalingMetricTypeHTTPEndpoint, v1alpha1.AutoscalingMetricTypePrometheus, v1alpha1.AutoscalingMetricTypeExternalObject, v1alpha1.AutoscalingMetricTypeHistoricalDesiredReplicas:
+		default:
+			return true
+		}
+	}
+
+	// The default metric is TotalNumberOfQueuedAndInProgressWorkflowRuns in case there's nothing but the historical one
+	for _, metric := range hra.Spec.Metrics {
+		if metric.Type != v1alpha1.AutoscalingMetricTypeHistoricalDesiredReplicas {
+			return false
+		}
+	}
+
+	return true
+}
+
+// metricResult is the number of desired replicas calculated from a metric.
+type metricResult struct {
+	// Type is the type of the metric, like TotalNumberOfQueuedAndInProgressWorkflowRuns.
+	Type string
+
+	Replicas int
+
+	// ObservedValue describes the raw value observed for the metric, like the number of queued workflow jobs
+	// or the percentage of busy runners, so that one can tell why the replicas were calculated so.
+	ObservedValue string
+
+	// ReactiveReplicas is the largest number of replicas calculated by the metrics other than HistoricalDesiredReplicas.
+	// It's what gets recorded into the scale history, so that the anticipated replicas don't feed themselves.
+	ReactiveReplicas int
+
+	// BusyRunners is the number of busy runners, like the one of in-progress workflow jobs, observed by the metric.
+	// It's nil when the metric doesn't observe it.
+	// determineDesiredReplicas sets it to the largest one observed by all the metrics.
+	BusyRunners *int
+
+	// QueueDepth is the number of queued workflow jobs observed by the metric.
+	// It's nil when the metric doesn't observe it.
+	// determineDesiredReplicas sets it to the largest one observed by all the metrics.
+	QueueDepth *int
+
+	// UncappedReplicas is Replicas before being capped by MaxReplicas, which tells how many replicas the metric actually demands.
+	// Zero means it's equal to Replicas.
+	// determineDesiredReplicas sets it to the largest one demanded by all the metrics.
+	UncappedReplicas int
 }
 
-func (r *HorizontalRunnerAutoscalerReconciler) determineDesiredReplicas(rd v1alpha1.RunnerDeployment, hra v1alpha1.HorizontalRunnerAutoscaler) (*int, error) {
+// uncappedReplicas returns the replicas demanded by the metric regardless of MaxReplicas.
+func (m *metricResult) uncappedReplicas() int {
+	if m.UncappedReplicas > m.Replicas {
+		return m.UncappedReplicas
+	}
+
+	return m.Replicas
+}
+
+// determineDesiredReplicas evaluates each metric independently and returns the one that resulted in the largest number of replicas.
+// A metric that failed to be evaluated is ignored as long as another metric succeeded, so that e.g. a GitHub API failure
+// specific to one metric doesn't break autoscaling as a whole. A skipped metric is ignored in the same way without being logged as an error.
+// HistoricalDesiredReplicas metrics are evaluated only after any of the other metrics succeeded, so that
+// they can only raise the replicas computed from the current state.
+// The OfflineRunners metric is evaluated last, and adds to the replicas rather than competing with the others.
+// When MetricExpression is set, it combines the named metrics in place of picking the largest one, and fails when any of the referenced ones failed.
+// Likewise, the WeightedSum MetricAggregation sums the weighted metrics in place of picking the largest one, and fails when any of them failed.
+func (r *HorizontalRunnerAutoscalerReconciler) determineDesiredReplicas(ctx context.Context, rd v1alpha1.RunnerDeployment, hra v1alpha1.HorizontalRunnerAutoscaler) (*metricResult, error) {
 	if hra.Spec.MinReplicas == nil {
 		return nil, fmt.Errorf("horizontalrunnerautoscaler %s/%s is missing minReplicas", hra.Namespace, hra.Name)
 	} else if hra.Spec.MaxReplicas == nil {
 		return nil, fmt.Errorf("horizontalrunnerautoscaler %s/%s is missing maxReplicas", hra.Namespace, hra.Name)
 	}
 
-	metrics := hra.Spec.Metrics
-	if len(metrics) == 0 || metrics[0].Type == v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns {
-		return r.calculateReplicasByQueuedAndInProgressWorkflowRuns(rd, hra)
-	} else if metrics[0].Type == v1alpha1.AutoscalingMetricTypePercentageRunnersBusy {
-		return r.calculateReplicasByPercentageRunnersBusy(rd, hra)
-	} else {
-		return nil, fmt.Errorf("validting autoscaling metrics: unsupported metric type %q", metrics[0].Type)
+	// Fail early, rather than letting every metric fail in the same way
+	if _, _, _, err := getScaleTargetScope(rd); err != nil {
+		return nil, err
+	}
+
+	ghc, err := r.getGitHubClient(ctx, hra)
+	if err != nil {
+		return nil, err
+	}
+
+	if usesGitHubAPIMetrics(hra) {
+		if err := r.checkGitHubAPIBudget(hra, rd); err != nil {
+			return nil, err
+		}
+	}
+
+	var metrics, historyMetrics, offlineMetrics []v1alpha1.MetricSpec
+
+	for _, metric := range hra.Spec.Metrics {
+		switch metric.Type {
+		case v1alpha1.AutoscalingMetricTypeHistoricalDesiredReplicas:
+			historyMetrics = append(historyMetrics, metric)
+		case v1alpha1.AutoscalingMetricTypeOfflineRunners:
+			offlineMetrics = append(offlineMetrics, metric)
+		default:
+			metrics = append(metrics, metric)
+		}
+	}
+
+	if len(metrics) == 0 {
+		metrics = []v1alpha1.MetricSpec{{Type: v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns}}
 	}
+
+	var (
+		result      *metricResult
+		busyRunners *int
+		queueDepth  *int
+		uncapped    int
+		errs        []error
+		skipped     []error
+		rateLimited *rateLimitedError
+
+		// named and namedFailures are the results and the errors of the named metrics, referenced by MetricExpression
+		named         = map[string]*metricResult{}
+		namedFailures = map[string]error{}
+
+		// weighted are the results of all the succeeded metrics, summed by the WeightedSum MetricAggregation
+		weighted []weightedMetricResult
+	)
+
+	for i, metric := range metrics {
+		if metric.Type == "" {
+			metric.Type = v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns
+		}
+
+		res, err := r.calculateReplicasByMetric(ghc, rd, hra, metric)
+		if errors.Is(err, errMetricSkipped) {
+			r.Log.Info("Skipping metric", "index", i, "type", metric.Type, "reason", err.Error(), "horizontal_runner_autoscaler", hra.Name, "namespace", hra.Namespace)
+
+			skipped = append(skipped, fmt.Errorf("metrics[%d]: %w", i, err))
+
+			if metric.Name != "" {
+				namedFailures[metric.Name] = err
+			}
+
+			continue
+		}
+
+		if err != nil {
+			if isGitHubAPIUnreachable(err) {
+				r.GitHubAPIReachability.RecordFailure(time.Now())
+
+				if r.GitHubAPICircuitBreaker.RecordFailure(time.Now()) {
+					r.event(&hra, corev1.EventTypeWarning, "GitHubAPICircuitBreakerOpened", fmt.Sprintf("Skipping GitHub API calls for all the horizontalrunnerautoscalers until GitHub API recovers, as it's been unreachable: %v", err))
+				}
+			}
+
+			r.Log.Error(err, "Could not calculate desired replicas by metric", "index", i, "type", metric.Type, "horizontal_runner_autoscaler", hra.Name, "namespace", hra.Namespace)
+
+			errs = append(errs, fmt.Errorf("metrics[%d]: %w", i, err))
+
+			if resetTime := getRateLimitResetTime(err, time.Now()); resetTime != nil && (rateLimited == nil || resetTime.After(rateLimited.ResetTime)) {
+				rateLimited = &rateLimitedError{ResetTime: *resetTime, Err: err}
+			}
+
+			if metric.Name != "" {
+				namedFailures[metric.Name] = err
+			}
+
+			continue
+		}
+
+		// The HTTPEndpoint, Prometheus, and ExternalObject metrics don't call GitHub API, so their success says nothing about the reachability
+		if metric.Type != v1alpha1.AutoscalingMetricTypeHTTPEndpoint && metric.Type != v1alpha1.AutoscalingMetricTypePrometheus && metric.Type != v1alpha1.AutoscalingMetricTypeExternalObject {
+			r.GitHubAPIReachability.RecordSuccess(time.Now())
+
+			if r.GitHubAPICircuitBreaker.RecordSuccess(time.Now()) {
+				r.event(&hra, corev1.EventTypeNormal, "GitHubAPICircuitBreakerClosed", "Resuming GitHub API calls as GitHub API has recovered")
+			}
+		}
+
+		if res.BusyRunners != nil && (busyRunners == nil || *res.BusyRunners > *busyRunners) {
+			busyRunners = res.BusyRunners
+		}
+
+		if res.QueueDepth != nil && (queueDepth == nil || *res.QueueDepth > *queueDepth) {
+			queueDepth = res.QueueDepth
+		}
+
+		if res.uncappedReplicas() > uncapped {
+			uncapped = res.uncappedReplicas()
+		}
+
+		if metric.Name != "" {
+			named[metric.Name] = res
+		}
+
+		weighted = append(weighted, weightedMetricResult{metric: metric, result: res})
+
+		if result == nil || res.Replicas > result.Replicas {
+			result = res
+		}
+	}
+
+	if result == nil {
+		// Let the caller back off until the rate limit resets, rather than retrying immediately
+		if rateLimited != nil {
+			return nil, rateLimited
+		}
+
+		// There's nothing but the skipped metrics to decide the desired replicas, which is a failure as well
+		errs = append(errs, skipped...)
+
+		if len(errs) == 1 {
+			return nil, errors.Unwrap(errs[0])
+		}
+
+		var msgs []string
+		for _, err := range errs {
+			msgs = append(msgs, err.Error())
+		}
+
+		return nil, fmt.Errorf("all the metrics failed: %s", strings.Join(msgs, "; "))
+	}
+
+	if hra.Spec.MetricAggregation == v1alpha1.MetricAggregationWeightedSum {
+		// The sum without a failed metric would under-provision the runners, unlike the largest one
+		if len(errs) > 0 {
+			if rateLimited != nil {
+				return nil, rateLimited
+			}
+
+			return nil, fmt.Errorf("summing the weighted metrics: %w", errs[0])
+		}
+
+		res, err := sumWeightedMetrics(hra, weighted)
+		if err != nil {
+			return nil, err
+		}
+
+		result = res
+		uncapped = res.uncappedReplicas()
+	}
+
+	if hra.Spec.MetricExpression != "" {
+		res, err := evaluateMetricExpression(hra, named, namedFailures, busyRunners, queueDepth)
+		if err != nil {
+			return nil, err
+		}
+
+		result = res
+		uncapped = res.uncappedReplicas()
+	}
+
+	reactiveReplicas := result.Replicas
+
+	for _, metric := range historyMetrics {
+		res, err := r.calculateReplicasByMetric(ghc, rd, hra, metric)
+		if err != nil {
+			return nil, err
+		}
+
+		if res.Replicas > result.Replicas {
+			result = res
+		}
+	}
+
+	result.ReactiveReplicas = reactiveReplicas
+	result.BusyRunners = busyRunners
+	result.QueueDepth = queueDepth
+	result.UncappedReplicas = uncapped
+
+	// Only the first one is honored, as they would otherwise compensate the same offline runners more than once.
+	// A failure is ignored like the one of any other metric, leaving the desired replicas computed by the others as is.
+	if len(offlineMetrics) > 0 {
+		res, err := r.calculateReplicasByMetric(ghc, rd, hra, offlineMetrics[0])
+		if err != nil {
+			r.Log.Error(err, "Could not count offline runners", "type", offlineMetrics[0].Type, "horizontal_runner_autoscaler", hra.Name, "namespace", hra.Namespace)
+		} else {
+			addOfflineRunners(result, res, *hra.Spec.MaxReplicas)
+		}
+	}
+
+	return result, nil
 }
 
-func (r *HorizontalRunnerAutoscalerReconciler) calculateReplicasByQueuedAndInProgressWorkflowRuns(rd v1alpha1.RunnerDeployment, hra v1alpha1.HorizontalRunnerAutoscaler) (*int, error) {
+// getGitHubClient returns the client authenticated with the credentials or as the GitHub App installation specified
+// by the HorizontalRunnerAutoscaler, or the default client when it specifies none.
+func (r *HorizontalRunnerAutoscalerReconciler) getGitHubClient(ctx context.Context, hra v1alpha1.HorizontalRunnerAutoscaler) (*github.Client, error) {
+	if hra.Spec.GitHubAPICredentialsFrom != nil {
+		return r.getGitHubClientFromCredentials(ctx, hra)
+	}
 
-	var repos [][]string
-	metrics := hra.Spec.Metrics
-	repoID := rd.Spec.Template.Spec.Repository
-	if repoID == "" {
-		orgName := rd.Spec.Template.Spec.Organization
-		if orgName == "" {
-			return nil, fmt.Errorf("asserting runner deployment spec to detect bug: spec.template.organization should not be empty on this code path")
+	ref := hra.Spec.GitHubAppInstallation
+	if ref == nil {
+		return r.GitHubClient, nil
+	}
+
+	if (ref.ID == 0) == (ref.Organization == "") {
+		return nil, fmt.Errorf("validating githubAppInstallation: exactly one of id and organization must be set for horizontalrunnerautoscaler %s/%s", hra.Namespace, hra.Name)
+	}
+
+	if r.GitHubClientPool == nil {
+		return nil, fmt.Errorf("horizontalrunnerautoscaler %s/%s specifies githubAppInstallation, but the controller is not authenticated as a github app", hra.Namespace, hra.Name)
+	}
+
+	if ref.ID != 0 {
+		return r.GitHubClientPool.GetInstallationClient(ctx, ref.ID)
+	}
+
+	return r.GitHubClientPool.GetOrganizationClient(ctx, ref.Organization)
+}
+
+// getScaleTargetScope returns the enterprise, organization, and repository that the runners of the RunnerDeployment belong to.
+// Exactly one of them is non-empty.
+func getScaleTargetScope(rd v1alpha1.RunnerDeployment) (string, string, string, error) {
+	var (
+		enterprise   = rd.Spec.Template.Spec.Enterprise
+		organization = rd.Spec.Template.Spec.Organization
+		repository   = rd.Spec.Template.Spec.Repository
+	)
+
+	var set []string
+
+	if enterprise != "" {
+		set = append(set, "enterprise")
+	}
+	if organization != "" {
+		set = append(set, "organization")
+	}
+	if repository != "" {
+		set = append(set, "repository")
+	}
+
+	if len(set) != 1 {
+		return "", "", "", fmt.Errorf(
+			"validating scale target: exactly one of spec.template.spec.enterprise, organization, and repository must be set for runnerdeployment %s/%s, but got %d: %s",
+			rd.Namespace, rd.Name, len(set), strings.Join(set, ", "),
+		)
+	}
+
+	return enterprise, organization, repository, nil
+}
+
+// rateLimitedError is returned when the desired replicas couldn't be determined due to the GitHub API rate limit.
+type rateLimitedError struct {
+	// ResetTime is the time at which the rate limit resets
+	ResetTime time.Time
+
+	Err error
+}
+
+func (e *rateLimitedError) Error() string {
+	return fmt.Sprintf("github api rate limit exceeded until %s: %v", e.ResetTime.Format(time.RFC3339), e.Err)
+}
+
+func (e *rateLimitedError) Unwrap() error {
+	return e.Err
+}
+
+// getRateLimitResetTime returns the time at which the rate limit resets when err is due to the GitHub API rate limit, or nil otherwise.
+// The reset time comes from the X-RateLimit-Reset header for the primary rate limit, and from the Retry-After header for the abuse rate limit.
+func getRateLimitResetTime(err error, now time.Time) *time.Time {
+	var rle *gogithub.RateLimitError
+	if errors.As(err, &rle) {
+		t := rle.Rate.Reset.Time
+		return &t
+	}
+
+	var arle *gogithub.AbuseRateLimitError
+	if errors.As(err, &arle) {
+		retryAfter := DefaultAbuseRateLimitRetryAfter
+		if arle.RetryAfter != nil {
+			retryAfter = *arle.RetryAfter
 		}
 
-		if len(metrics[0].RepositoryNames) == 0 {
-			return nil, errors.New("validating autoscaling metrics: spec.autoscaling.metrics[].repositoryNames is required and must have one more more entries for organizational runner deployment")
+		t := now.Add(retryAfter)
+		return &t
+	}
+
+	return nil
+}
+
+func (r *HorizontalRunnerAutoscalerReconciler) calculateReplicasByQueuedAndInProgressWorkflowRuns(ctx context.Context, ghc *github.Client, rd v1alpha1.RunnerDeployment, hra v1alpha1.HorizontalRunnerAutoscaler, metrics v1alpha1.MetricSpec) (*metricResult, error) {
+
+	enterprise, orgName, repoID, err := getScaleTargetScope(rd)
+	if err != nil {
+		return nil, err
+	}
+
+	replicasPerRun, err := getReplicasPerRun(metrics)
+	if err != nil {
+		return nil, err
+	}
+
+	weighted := metrics.Type == v1alpha1.AutoscalingMetricTypeDurationWeightedQueuedAndInProgressWorkflowRuns
+
+	repos, err := getMetricRepositories(enterprise, orgName, repoID, metrics)
+	if err != nil {
+		return nil, err
+	}
+
+	// The repositories are listed concurrently, as listing the workflow jobs of each run one by one can take long for many repositories
+	results := make([]workflowRunCounts, len(repos))
+	errs := make([]error, len(repos))
+
+	var wg sync.WaitGroup
+
+	sem := make(chan struct{}, maxConcurrentWorkflowRunListings)
+
+	for i := range repos {
+		i := i
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			var weigh func(int64) float64
+			if weighted {
+				weigh = r.workflowRunWeigher(ctx, ghc, metrics, repos[i][0], repos[i][1])
+			}
+
+			results[i], errs[i] = r.countRepositoryWorkflowRuns(ctx, ghc, rd, repos[i][0], repos[i][1], weigh)
+		}()
+	}
+
+	wg.Wait()
+
+	var (
+		counts   workflowRunCounts
+		failed   int
+		firstErr error
+	)
+
+	// A shared runner pool is still scaled by the repositories that succeeded, so that a single broken repository doesn't block autoscaling
+	for i, err := range errs {
+		if err != nil {
+			r.Log.Error(err, "Could not list workflow runs", "owner", repos[i][0], "repository", repos[i][1], "horizontal_runner_autoscaler", hra.Name, "namespace", hra.Namespace)
+
+			if firstErr == nil {
+				firstErr = err
+			}
+
+			failed++
+
+			continue
 		}
 
-		for _, repoName := range metrics[0].RepositoryNames {
-			repos = append(repos, []string{orgName, repoName})
+		counts.add(results[i])
+	}
+
+	if failed == len(repos) {
+		if len(repos) == 1 {
+			return nil, firstErr
 		}
+
+		return nil, fmt.Errorf("listing workflow runs of all the %d repositories: %w", len(repos), firstErr)
+	}
+
+	inProgress, queued, completed, unknown, unmatched := counts.inProgress, counts.queued, counts.completed, counts.unknown, counts.unmatched
+
+	minReplicas := *hra.Spec.MinReplicas
+	maxReplicas := *hra.Spec.MaxReplicas
+	numRuns := queued
+	load := counts.weightedQueued
+	if metrics.IncludeInProgress == nil || *metrics.IncludeInProgress {
+		numRuns += inProgress
+		load += counts.weightedInProgress
+	}
+
+	necessaryReplicas := replicasForRuns(numRuns, replicasPerRun)
+	if weighted {
+		necessaryReplicas = replicasForLoad(load, replicasPerRun)
+	}
+
+	var desiredReplicas int
+
+	if necessaryReplicas < minReplicas {
+		desiredReplicas = minReplicas
+	} else if necessaryReplicas > maxReplicas {
+		desiredReplicas = maxReplicas
 	} else {
+		desiredReplicas = necessaryReplicas
+	}
+
+	rd.Status.Replicas = &desiredReplicas
+	replicas := desiredReplicas
+
+	r.Log.V(1).Info(
+		"Calculated desired replicas",
+		"computed_replicas_desired", desiredReplicas,
+		"spec_replicas_min", minReplicas,
+		"spec_replicas_max", maxReplicas,
+		"workflow_runs_completed", completed,
+		"workflow_runs_in_progress", inProgress,
+		"workflow_runs_queued", queued,
+		"workflow_runs_unknown", unknown,
+		"workflow_jobs_unmatched", unmatched,
+		"replicas_per_run", replicasPerRun,
+		"namespace", hra.Namespace,
+		"runner_deployment", rd.Name,
+		"horizontal_runner_autoscaler", hra.Name,
+	)
+
+	observed := fmt.Sprintf("%d queued", queued)
+	if metrics.IncludeInProgress == nil || *metrics.IncludeInProgress {
+		observed += fmt.Sprintf(" and %d in-progress", inProgress)
+	}
+	observed += " workflow runs and jobs"
+	if weighted {
+		observed += fmt.Sprintf(" weighted to %.2f by duration", load)
+	}
+	if failed > 0 {
+		observed += fmt.Sprintf(" in %d of %d repositories", len(repos)-failed, len(repos))
+	}
+
+	return &metricResult{Replicas: replicas, ObservedValue: observed, BusyRunners: &inProgress, QueueDepth: &queued, UncappedReplicas: necessaryReplicas}, nil
+}
+
+// getMetricRepositories returns the owners and the names of the repositories whose workflow runs or events are counted by the metric,
+// which are the repository of the scale target, or the RepositoryNames of the metric for the organization or the enterprise.
+func getMetricRepositories(enterprise, orgName, repoID string, metrics v1alpha1.MetricSpec) ([][]string, error) {
+	var repos [][]string
+	switch {
+	case repoID != "":
 		repo := strings.Split(repoID, "/")
 
 		repos = append(repos, repo)
+	case orgName != "":
+		if len(metrics.RepositoryNames) == 0 {
+			return nil, errors.New("validating autoscaling metrics: spec.autoscaling.metrics[].repositoryNames is required and must have one more more entries for organizational runner deployment")
+		}
+
+		for _, repoName := range metrics.RepositoryNames {
+			repos = append(repos, []string{orgName, repoName})
+		}
+	default:
+		// GitHub provides no API to list workflow runs or events across an enterprise,
+		// so we need the full names of the repositories whose workflow runs or events are counted.
+		if len(metrics.RepositoryNames) == 0 {
+			return nil, fmt.Errorf("validating autoscaling metrics: spec.autoscaling.metrics[].repositoryNames is required and must have one more more entries in the form of OWNER/REPO for enterprise runner deployment of %q", enterprise)
+		}
+
+		for _, repoName := range metrics.RepositoryNames {
+			repo := strings.Split(repoName, "/")
+			if len(repo) != 2 || repo[0] == "" || repo[1] == "" {
+				return nil, fmt.Errorf("validating autoscaling metrics: spec.autoscaling.metrics[].repositoryNames must be in the form of OWNER/REPO for enterprise runner deployment, but got %q", repoName)
+			}
+
+			repos = append(repos, repo)
+		}
 	}
 
-	var total, inProgress, queued, completed, unknown int
-	type callback func()
-	listWorkflowJobs := func(user string, repoName string, runID int64, fallback_cb callback) {
+	return repos, nil
+}
+
+// maxConcurrentWorkflowRunListings is the maximum number of repositories whose workflow runs are listed concurrently
+// by the TotalNumberOfQueuedAndInProgressWorkflowRuns metric, which bounds the burst of GitHub API calls.
+const maxConcurrentWorkflowRunListings = 4
+
+// workflowRunCounts is the number of workflow runs and jobs by status counted by the TotalNumberOfQueuedAndInProgressWorkflowRuns metric.
+type workflowRunCounts struct {
+	total, inProgress, queued, completed, unknown, unmatched int
+
+	// weightedInProgress and weightedQueued are inProgress and queued, each weighted by the duration of its workflow
+	// for the DurationWeightedQueuedAndInProgressWorkflowRuns metric
+	weightedInProgress, weightedQueued float64
+}
+
+func (c *workflowRunCounts) add(o workflowRunCounts) {
+	c.total += o.total
+	c.inProgress += o.inProgress
+	c.queued += o.queued
+	c.completed += o.completed
+	c.unknown += o.unknown
+	c.unmatched += o.unmatched
+	c.weightedInProgress += o.weightedInProgress
+	c.weightedQueued += o.weightedQueued
+}
+
+// countRepositoryWorkflowRuns counts the queued and in-progress workflow runs of the repository.
+// The jobs of each run are counted instead when they can be listed, except the ones not targeting the runners of the RunnerDeployment.
+// weigh, when set, weights each run and its jobs by its workflow.
+func (r *HorizontalRunnerAutoscalerReconciler) countRepositoryWorkflowRuns(ctx context.Context, ghc *github.Client, rd v1alpha1.RunnerDeployment, user, repoName string, weigh func(workflowID int64) float64) (workflowRunCounts, error) {
+	if r.UseGraphQL {
+		c, err := r.countRepositoryWorkflowRunsGraphQL(ctx, ghc, user, repoName, weigh)
+		if err == nil {
+			return c, nil
+		}
+
+		r.Log.Error(err, "Falling back to GitHub REST API for listing workflow runs", "owner", user, "repository", repoName)
+	}
+
+	var c workflowRunCounts
+
+	listWorkflowJobs := func(runID int64, weight float64, fallback func()) {
 		if runID == 0 {
-			fallback_cb()
+			fallback()
 			return
 		}
-		jobs, _, err := r.GitHubClient.Actions.ListWorkflowJobs(context.TODO(), user, repoName, runID, nil)
+		start := time.Now()
+		jobs, err := ghc.ListWorkflowJobs(ctx, user, repoName, runID)
+		observeGitHubAPICall(ctx, githubAPICallEndpointListWorkflowJobs, start, err)
 		if err != nil {
 			r.Log.Error(err, "Error listing workflow jobs")
-			fallback_cb()
-		} else if len(jobs.Jobs) == 0 {
-			fallback_cb()
+			fallback()
+		} else if len(jobs) == 0 {
+			fallback()
 		} else {
-			for _, job := range jobs.Jobs {
+			for _, job := range jobs {
+				// Jobs without labels, e.g. the ones of older workflow runs, are counted to stay safe.
+				if len(job.Labels) > 0 && !v1alpha1.RunnerLabelsMatchJobLabels(rd.Spec.Template.Spec.Labels, job.Labels) {
+					c.unmatched++
+
+					continue
+				}
+
 				switch job.GetStatus() {
 				case "completed":
 					// We add a case for `completed` so it is not counted in `unknown`.
@@ -126,82 +775,229 @@ func (r *HorizontalRunnerAutoscalerReconciler) calculateReplicasByQueuedAndInPro
 					// completed workflows in order to keep the number of API
 					// calls to a minimum.
 				case "in_progress":
-					inProgress++
+					c.inProgress++
+					c.weightedInProgress += weight
 				case "queued":
-					queued++
+					c.queued++
+					c.weightedQueued += weight
 				default:
-					unknown++
+					c.unknown++
 				}
 			}
 		}
 	}
 
-	for _, repo := range repos {
-		user, repoName := repo[0], repo[1]
-		workflowRuns, err := r.GitHubClient.ListRepositoryWorkflowRuns(context.TODO(), user, repoName)
-		if err != nil {
-			return nil, err
+	start := time.Now()
+	workflowRuns, err := ghc.ListRepositoryWorkflowRuns(ctx, user, repoName)
+	observeGitHubAPICall(ctx, githubAPICallEndpointListRepositoryWorkflowRuns, start, err)
+	if err != nil {
+		return c, err
+	}
+
+	for _, run := range workflowRuns {
+		c.total++
+
+		weight := 1.0
+		if weigh != nil && (run.GetStatus() == "in_progress" || run.GetStatus() == "queued") {
+			weight = weigh(run.GetWorkflowID())
+		}
+
+		// In May 2020, there are only 3 statuses.
+		// Follow the below links for more details:
+		// - https://developer.github.com/v3/actions/workflow-runs/#list-repository-workflow-runs
+		// - https://developer.github.com/v3/checks/runs/#create-a-check-run
+		switch run.GetStatus() {
+		case "completed":
+			c.completed++
+		case "in_progress":
+			listWorkflowJobs(run.GetID(), weight, func() { c.inProgress++; c.weightedInProgress += weight })
+		case "queued":
+			listWorkflowJobs(run.GetID(), weight, func() { c.queued++; c.weightedQueued += weight })
+		default:
+			c.unknown++
 		}
+	}
+
+	return c, nil
+}
 
-		for _, run := range workflowRuns {
-			total++
-
-			// In May 2020, there are only 3 statuses.
-			// Follow the below links for more details:
-			// - https://developer.github.com/v3/actions/workflow-runs/#list-repository-workflow-runs
-			// - https://developer.github.com/v3/checks/runs/#create-a-check-run
-			switch run.GetStatus() {
-			case "completed":
-				completed++
-			case "in_progress":
-				listWorkflowJobs(user, repoName, run.GetID(), func() { inProgress++ })
-			case "queued":
-				listWorkflowJobs(user, repoName, run.GetID(), func() { queued++ })
-			default:
-				unknown++
+// countRepositoryWorkflowRunsGraphQL is countRepositoryWorkflowRuns listing the runs along with the numbers of their jobs
+// in a single GitHub GraphQL API call. The labels of the jobs aren't available via GraphQL, so the jobs are counted
+// regardless of the runners of the RunnerDeployment, like the ones listed without labels over REST.
+func (r *HorizontalRunnerAutoscalerReconciler) countRepositoryWorkflowRunsGraphQL(ctx context.Context, ghc *github.Client, user, repoName string, weigh func(workflowID int64) float64) (workflowRunCounts, error) {
+	var c workflowRunCounts
+
+	start := time.Now()
+	workflowRuns, err := ghc.ListWorkflowRunsGraphQL(ctx, user, repoName)
+	observeGitHubAPICall(ctx, githubAPICallEndpointListWorkflowRunsGraphQL, start, err)
+	if err != nil {
+		return c, err
+	}
+
+	for _, run := range workflowRuns {
+		c.total++
+
+		switch run.Status {
+		case "in_progress", "queued":
+			weight := 1.0
+			if weigh != nil {
+				weight = weigh(run.WorkflowID)
 			}
+
+			queued, inProgress := run.QueuedJobs, run.InProgressJobs
+
+			// A run whose jobs aren't created yet is counted on its own, like the run without jobs listed over REST
+			if queued == 0 && inProgress == 0 {
+				if run.Status == "queued" {
+					queued = 1
+				} else {
+					inProgress = 1
+				}
+			}
+
+			c.queued += queued
+			c.weightedQueued += weight * float64(queued)
+			c.inProgress += inProgress
+			c.weightedInProgress += weight * float64(inProgress)
+		default:
+			c.unknown++
 		}
 	}
 
+	return c, nil
+}
+
+func (r *HorizontalRunnerAutoscalerReconciler) calculateReplicasByPercentageRunnersBusy(ctx context.Context, ghc *github.Client, rd v1alpha1.RunnerDeployment, hra v1alpha1.HorizontalRunnerAutoscaler, metrics v1alpha1.MetricSpec) (*metricResult, error) {
 	minReplicas := *hra.Spec.MinReplicas
 	maxReplicas := *hra.Spec.MaxReplicas
-	necessaryReplicas := queued + inProgress
+
+	params, err := getPercentageBusyParams(metrics)
+	if err != nil {
+		return nil, err
+	}
+
+	scaleUpThreshold := params.scaleUpThreshold
+	scaleDownThreshold := params.scaleDownThreshold
+	scaleUpFactor := params.scaleUpFactor
+	scaleDownFactor := params.scaleDownFactor
+	scaleUpAdjustment := params.scaleUpAdjustment
+	scaleDownAdjustment := params.scaleDownAdjustment
+
+	counts, err := r.countRunners(ctx, ghc, rd, getRunnerStartupGrace(hra))
+	if err != nil {
+		return nil, err
+	}
+
+	numRunners := counts.runners
+	numRunnersBusy := counts.busy
+	numRunnersStartingUp := counts.startingUp
+
+	enterprise, organization, repository, err := getScaleTargetScope(rd)
+	if err != nil {
+		return nil, err
+	}
 
 	var desiredReplicas int
 
-	if necessaryReplicas < minReplicas {
-		desiredReplicas = minReplicas
-	} else if necessaryReplicas > maxReplicas {
-		desiredReplicas = maxReplicas
+	// There can be no runners at all, e.g. when the deployment is scaled to zero.
+	// We treat it as 0% busy so that it never results in dividing by zero.
+	var fractionBusy float64
+	if numRunners > 0 {
+		fractionBusy = float64(numRunnersBusy) / float64(numRunners)
+	}
+
+	// Idle runners that have just started are not counted for scaling down, so that the runners added by
+	// a scale up don't immediately result in a scale down before they register and pick up jobs.
+	numRunnersNotStartingUp := numRunners - numRunnersStartingUp
+
+	var fractionBusyNotStartingUp float64
+	if numRunnersNotStartingUp > 0 {
+		fractionBusyNotStartingUp = float64(numRunnersBusy) / float64(numRunnersNotStartingUp)
+	}
+
+	if target := metrics.TargetUtilizationPercent; target != nil {
+		if *target < 1 || *target > 100 {
+			return nil, fmt.Errorf("validating autoscaling metrics: spec.autoscaling.metrics[].targetUtilizationPercent must be between 1 and 100, but got %d", *target)
+		}
+
+		desiredReplicas = replicasForTargetUtilization(numRunnersBusy, *target)
+
+		// The runners starting up are kept as is, as they would otherwise be removed before picking up jobs
+		if keep := numRunnersBusy + numRunnersStartingUp; desiredReplicas < keep {
+			desiredReplicas = keep
+		}
+	} else if fractionBusy >= scaleUpThreshold {
+		if scaleUpAdjustment > 0 {
+			desiredReplicas = numRunners + scaleUpAdjustment
+		} else {
+			desiredReplicas = int(math.Ceil(float64(numRunners) * scaleUpFactor))
+		}
+	} else if fractionBusyNotStartingUp < scaleDownThreshold {
+		// The runners starting up are kept as is, as they would otherwise be removed before picking up jobs
+		if scaleDownAdjustment > 0 {
+			desiredReplicas = numRunnersNotStartingUp - scaleDownAdjustment + numRunnersStartingUp
+		} else {
+			desiredReplicas = int(float64(numRunnersNotStartingUp)*scaleDownFactor) + numRunnersStartingUp
+		}
 	} else {
-		desiredReplicas = necessaryReplicas
+		desiredReplicas = getIntOrDefault(rd.Spec.Replicas, numRunners)
 	}
 
-	rd.Status.Replicas = &desiredReplicas
-	replicas := desiredReplicas
+	uncappedReplicas := desiredReplicas
+
+	if desiredReplicas < minReplicas {
+		desiredReplicas = minReplicas
+	} else if desiredReplicas > maxReplicas {
+		desiredReplicas = maxReplicas
+	}
 
 	r.Log.V(1).Info(
 		"Calculated desired replicas",
 		"computed_replicas_desired", desiredReplicas,
 		"spec_replicas_min", minReplicas,
 		"spec_replicas_max", maxReplicas,
-		"workflow_runs_completed", completed,
-		"workflow_runs_in_progress", inProgress,
-		"workflow_runs_queued", queued,
-		"workflow_runs_unknown", unknown,
+		"current_replicas", rd.Spec.Replicas,
+		"num_runners", numRunners,
+		"num_runners_busy", numRunnersBusy,
+		"num_runners_starting_up", numRunnersStartingUp,
 		"namespace", hra.Namespace,
 		"runner_deployment", rd.Name,
 		"horizontal_runner_autoscaler", hra.Name,
+		"enterprise", enterprise,
+		"organization", organization,
+		"repository", repository,
 	)
 
-	return &replicas, nil
+	rd.Status.Replicas = &desiredReplicas
+	replicas := desiredReplicas
+
+	observed := fmt.Sprintf("%d of %d runners busy (%.0f%%)", numRunnersBusy, numRunners, fractionBusy*100)
+	if numRunnersStartingUp > 0 {
+		observed += fmt.Sprintf(", %d starting up", numRunnersStartingUp)
+	}
+
+	return &metricResult{Replicas: replicas, ObservedValue: observed, BusyRunners: &numRunnersBusy, UncappedReplicas: uncappedReplicas}, nil
 }
 
-func (r *HorizontalRunnerAutoscalerReconciler) calculateReplicasByPercentageRunnersBusy(rd v1alpha1.RunnerDeployment, hra v1alpha1.HorizontalRunnerAutoscaler) (*int, error) {
-	ctx := context.Background()
-	minReplicas := *hra.Spec.MinReplicas
-	maxReplicas := *hra.Spec.MaxReplicas
-	metrics := hra.Spec.Metrics[0]
+// replicasForTargetUtilization returns the number of replicas at which busy runners are targetPercent of them, rounded up
+// so that the utilization never exceeds the target. It's computed in integers, as e.g. 7 / 0.7 in floats is slightly above 10.
+// It's zero when no runners are busy.
+func replicasForTargetUtilization(busy, targetPercent int) int {
+	return (busy*100 + targetPercent - 1) / targetPercent
+}
+
+// percentageBusyParams is the thresholds and the factors or the adjustments shared by the metrics
+// scaling by the percentage of busy runners.
+type percentageBusyParams struct {
+	scaleUpThreshold    float64
+	scaleDownThreshold  float64
+	scaleUpFactor       float64
+	scaleDownFactor     float64
+	scaleUpAdjustment   int
+	scaleDownAdjustment int
+}
+
+func getPercentageBusyParams(metrics v1alpha1.MetricSpec) (*percentageBusyParams, error) {
 	scaleUpThreshold := defaultScaleUpThreshold
 	scaleDownThreshold := defaultScaleDownThreshold
 	scaleUpFactor := defaultScaleUpFactor
@@ -223,6 +1019,10 @@ func (r *HorizontalRunnerAutoscalerReconciler) calculateReplicasByPercentageRunn
 		scaleDownThreshold = sdt
 	}
 
+	if scaleDownThreshold > scaleUpThreshold {
+		return nil, fmt.Errorf("validating autoscaling metrics: spec.autoscaling.metrics[].scaleDownThreshold (%v) cannot be greater than scaleUpThreshold (%v)", scaleDownThreshold, scaleUpThreshold)
+	}
+
 	scaleUpAdjustment := metrics.ScaleUpAdjustment
 	if scaleUpAdjustment != 0 {
 		if metrics.ScaleUpAdjustment < 0 {
@@ -257,81 +1057,125 @@ func (r *HorizontalRunnerAutoscalerReconciler) calculateReplicasByPercentageRunn
 		scaleDownFactor = sdf
 	}
 
+	return &percentageBusyParams{
+		scaleUpThreshold:    scaleUpThreshold,
+		scaleDownThreshold:  scaleDownThreshold,
+		scaleUpFactor:       scaleUpFactor,
+		scaleDownFactor:     scaleDownFactor,
+		scaleUpAdjustment:   scaleUpAdjustment,
+		scaleDownAdjustment: scaleDownAdjustment,
+	}, nil
+}
+
+type runnerCounts struct {
+	// runners is the number of runners of the RunnerDeployment
+	runners int
+
+	// busy is the number of runners busy running jobs
+	busy int
+
+	// startingUp is the number of runners that aren't busy but were created within the startup grace period,
+	// which are likely still registering or about to pick up jobs.
+	startingUp int
+
+	// offline is the number of runners registered to GitHub but offline, excluding the ones created within the startup grace period
+	offline int
+}
+
+// countRunners returns the number of runners of the RunnerDeployment, how many of them are busy running jobs,
+// and how many of the rest were created within the startupGrace.
+func (r *HorizontalRunnerAutoscalerReconciler) countRunners(ctx context.Context, ghc *github.Client, rd v1alpha1.RunnerDeployment, startupGrace time.Duration) (*runnerCounts, error) {
 	// return the list of runners in namespace. Horizontal Runner Autoscaler should only be responsible for scaling resources in its own ns.
 	var runnerList v1alpha1.RunnerList
 	if err := r.List(ctx, &runnerList, client.InNamespace(rd.Namespace)); err != nil {
 		return nil, err
 	}
-	runnerMap := make(map[string]struct{})
+	runnerMap := make(map[string]v1alpha1.Runner)
 	for _, items := range runnerList.Items {
-		runnerMap[items.Name] = struct{}{}
+		runnerMap[items.Name] = items
 	}
 
-	var (
-		enterprise   = rd.Spec.Template.Spec.Enterprise
-		organization = rd.Spec.Template.Spec.Organization
-		repository   = rd.Spec.Template.Spec.Repository
-	)
+	enterprise, organization, repository, err := getScaleTargetScope(rd)
+	if err != nil {
+		return nil, err
+	}
 
 	// ListRunners will return all runners managed by GitHub - not restricted to ns
-	runners, err := r.GitHubClient.ListRunners(
-		ctx,
-		enterprise,
-		organization,
-		repository)
+	runners, err := r.listRunners(ctx, ghc, enterprise, organization, repository)
 	if err != nil {
 		return nil, err
 	}
-	numRunners := len(runnerList.Items)
-	numRunnersBusy := 0
+
+	busy := make(map[string]bool)
+	offline := make(map[string]bool)
 	for _, runner := range runners {
-		if _, ok := runnerMap[*runner.Name]; ok && runner.GetBusy() {
-			numRunnersBusy++
+		if _, ok := runnerMap[*runner.Name]; !ok {
+			continue
 		}
-	}
 
-	var desiredReplicas int
-	fractionBusy := float64(numRunnersBusy) / float64(numRunners)
-	if fractionBusy >= scaleUpThreshold {
-		if scaleUpAdjustment > 0 {
-			desiredReplicas = numRunners + scaleUpAdjustment
-		} else {
-			desiredReplicas = int(math.Ceil(float64(numRunners) * scaleUpFactor))
+		if runner.GetBusy() {
+			busy[*runner.Name] = true
 		}
-	} else if fractionBusy < scaleDownThreshold {
-		if scaleDownAdjustment > 0 {
-			desiredReplicas = numRunners - scaleDownAdjustment
-		} else {
-			desiredReplicas = int(float64(numRunners) * scaleDownFactor)
+
+		if runner.GetStatus() == "offline" {
+			offline[*runner.Name] = true
 		}
-	} else {
-		desiredReplicas = *rd.Spec.Replicas
 	}
 
-	if desiredReplicas < minReplicas {
-		desiredReplicas = minReplicas
-	} else if desiredReplicas > maxReplicas {
-		desiredReplicas = maxReplicas
+	counts := &runnerCounts{
+		runners: len(runnerList.Items),
+		busy:    len(busy),
 	}
 
-	r.Log.V(1).Info(
-		"Calculated desired replicas",
-		"computed_replicas_desired", desiredReplicas,
-		"spec_replicas_min", minReplicas,
-		"spec_replicas_max", maxReplicas,
-		"current_replicas", rd.Spec.Replicas,
-		"num_runners", numRunners,
-		"num_runners_busy", numRunnersBusy,
-		"namespace", hra.Namespace,
-		"runner_deployment", rd.Name,
-		"horizontal_runner_autoscaler", hra.Name,
-		"enterprise", enterprise,
-		"organization", organization,
-		"repository", repository,
-	)
+	startedAfter := time.Now().Add(-startupGrace)
 
-	rd.Status.Replicas = &desiredReplicas
-	replicas := desiredReplicas
+	for name, runner := range runnerMap {
+		// A runner not yet registered to GitHub is not busy either
+		if !busy[name] && runner.CreationTimestamp.Time.After(startedAfter) {
+			counts.startingUp++
+		} else if offline[name] {
+			counts.offline++
+		}
+	}
+
+	return counts, nil
+}
+
+// getRunnerStartupGrace returns the duration since the creation of a runner for which the runner isn't counted as idle.
+func getRunnerStartupGrace(hra v1alpha1.HorizontalRunnerAutoscaler) time.Duration {
+	if hra.Spec.RunnerStartupGraceSeconds != nil {
+		return time.Duration(*hra.Spec.RunnerStartupGraceSeconds) * time.Second
+	}
+
+	return DefaultRunnerStartupGrace
+}
+
+// getReplicasPerRun returns the ReplicasPerRun factor of the metric, defaulting to 1.
+func getReplicasPerRun(metrics v1alpha1.MetricSpec) (float64, error) {
+	if metrics.ReplicasPerRun == "" {
+		return 1, nil
+	}
+
+	replicasPerRun, err := strconv.ParseFloat(metrics.ReplicasPerRun, 64)
+	if err != nil {
+		return 0, errors.New("validating autoscaling metrics: spec.autoscaling.metrics[].replicasPerRun cannot be parsed into a float64")
+	}
+
+	if replicasPerRun <= 0 || math.IsInf(replicasPerRun, 0) || math.IsNaN(replicasPerRun) {
+		return 0, fmt.Errorf("validating autoscaling metrics: spec.autoscaling.metrics[].replicasPerRun must be greater than 0, but got %s", metrics.ReplicasPerRun)
+	}
+
+	return replicasPerRun, nil
+}
+
+// replicasForRuns returns the number of replicas necessary for the workflow runs, rounded up
+// so that a fraction of a runner results in a whole runner.
+func replicasForRuns(numRuns int, replicasPerRun float64) int {
+	return replicasForLoad(float64(numRuns), replicasPerRun)
+}
 
-	return &replicas, nil
+// replicasForLoad is replicasForRuns for the workflow runs weighted by their durations.
+func replicasForLoad(load float64, replicasPerRun float64) int {
+	// Subtract a small epsilon before rounding up, so that e.g. 10 * 0.3 doesn't result in 4 due to the floating point error
+	return int(math.Ceil(load*replicasPerRun - 1e-9))
 }
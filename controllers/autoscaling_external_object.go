@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// defaultExternalObjectReplicasPath is the field of the desired replicas of a HorizontalPodAutoscaler,
+// including the one managed by a KEDA ScaledObject.
+const defaultExternalObjectReplicasPath = "status.desiredReplicas"
+
+// calculateReplicasByExternalObject computes the desired replicas from the field of the object recording the recommendation of another autoscaler.
+// A missing object or field skips the metric, as the other autoscaler may not have created or populated it yet.
+func (r *HorizontalRunnerAutoscalerReconciler) calculateReplicasByExternalObject(ctx context.Context, hra v1alpha1.HorizontalRunnerAutoscaler, metrics v1alpha1.MetricSpec) (*metricResult, error) {
+	minReplicas := *hra.Spec.MinReplicas
+	maxReplicas := *hra.Spec.MaxReplicas
+
+	source := metrics.ExternalObject
+	if source == nil || source.APIVersion == "" || source.Kind == "" || source.Name == "" {
+		return nil, errors.New("validating autoscaling metrics: spec.autoscaling.metrics[].externalObject.apiVersion, kind, and name must be set for ExternalObject")
+	}
+
+	gv, err := schema.ParseGroupVersion(source.APIVersion)
+	if err != nil {
+		return nil, fmt.Errorf("validating autoscaling metrics: spec.autoscaling.metrics[].externalObject.apiVersion is invalid: %v", err)
+	}
+
+	path := source.ReplicasPath
+	if path == "" {
+		path = defaultExternalObjectReplicasPath
+	}
+
+	var obj unstructured.Unstructured
+
+	obj.SetGroupVersionKind(gv.WithKind(source.Kind))
+
+	if err := r.Get(ctx, types.NamespacedName{Namespace: hra.Namespace, Name: source.Name}, &obj); err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil, fmt.Errorf("%w: %s %s/%s not found", errMetricSkipped, source.Kind, hra.Namespace, source.Name)
+		}
+
+		// Formatted with %v rather than wrapped, so that the failure isn't mistaken for an unreachable GitHub API
+		return nil, fmt.Errorf("getting %s %s/%s for externalObject: %v", source.Kind, hra.Namespace, source.Name, err)
+	}
+
+	value, found, err := unstructured.NestedFieldNoCopy(obj.Object, strings.Split(path, ".")...)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s of %s %s/%s: %v", path, source.Kind, hra.Namespace, source.Name, err)
+	} else if !found {
+		return nil, fmt.Errorf("%w: %s %s/%s has no %s", errMetricSkipped, source.Kind, hra.Namespace, source.Name, path)
+	}
+
+	var replicas float64
+
+	switch v := value.(type) {
+	case int64:
+		replicas = float64(v)
+	case float64:
+		replicas = v
+	default:
+		return nil, fmt.Errorf("%s of %s %s/%s must be a number, but got %T", path, source.Kind, hra.Namespace, source.Name, value)
+	}
+
+	desiredReplicas := int(math.Ceil(replicas))
+	if desiredReplicas < 0 {
+		desiredReplicas = 0
+	}
+
+	uncappedReplicas := desiredReplicas
+
+	if desiredReplicas < minReplicas {
+		desiredReplicas = minReplicas
+	} else if desiredReplicas > maxReplicas {
+		desiredReplicas = maxReplicas
+	}
+
+	observed := fmt.Sprintf("%s of %s %s is %g", path, source.Kind, source.Name, replicas)
+
+	r.Log.V(1).Info(
+		"Calculated desired replicas",
+		"computed_replicas_desired", desiredReplicas,
+		"spec_replicas_min", minReplicas,
+		"spec_replicas_max", maxReplicas,
+		"observed", observed,
+		"namespace", hra.Namespace,
+		"horizontal_runner_autoscaler", hra.Name,
+	)
+
+	return &metricResult{Replicas: desiredReplicas, ObservedValue: observed, UncappedReplicas: uncappedReplicas}, nil
+}
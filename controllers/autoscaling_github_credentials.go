@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	"github.com/summerwind/actions-runner-controller/github"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// The keys of the Secret referenced by GitHubAPICredentialsFrom, which are the same as the ones of the Secret of the controller.
+const (
+	githubAPICredentialsKeyToken             = "github_token"
+	githubAPICredentialsKeyAppID             = "github_app_id"
+	githubAPICredentialsKeyAppInstallationID = "github_app_installation_id"
+	githubAPICredentialsKeyAppPrivateKey     = "github_app_private_key"
+)
+
+// githubAPICredentialsError is the failure to get the client authenticated with the credentials of GitHubAPICredentialsFrom,
+// which is surfaced separately from the failures of GitHub API calls, as it's fixed only by updating the Secret.
+type githubAPICredentialsError struct {
+	Err error
+}
+
+func (e *githubAPICredentialsError) Error() string {
+	return fmt.Sprintf("getting github api credentials: %v", e.Err)
+}
+
+func (e *githubAPICredentialsError) Unwrap() error {
+	return e.Err
+}
+
+// githubCredentialsClients caches the clients created from the Secrets referenced by GitHubAPICredentialsFrom,
+// so that e.g. the installation access token of a GitHub App is reused across reconciliations.
+// A client is recreated when the Secret's resource version changes, so that rotated credentials take effect.
+type githubCredentialsClients struct {
+	mu sync.Mutex
+
+	entries map[types.NamespacedName]githubCredentialsClient
+}
+
+type githubCredentialsClient struct {
+	resourceVersion string
+	client          *github.Client
+}
+
+// getGitHubClientFromCredentials returns the client authenticated with the credentials in the Secret referenced by GitHubAPICredentialsFrom.
+func (r *HorizontalRunnerAutoscalerReconciler) getGitHubClientFromCredentials(ctx context.Context, hra v1alpha1.HorizontalRunnerAutoscaler) (*github.Client, error) {
+	key := types.NamespacedName{Namespace: hra.Namespace, Name: hra.Spec.GitHubAPICredentialsFrom.SecretRef.Name}
+
+	var secret corev1.Secret
+	if err := r.Get(ctx, key, &secret); err != nil {
+		return nil, &githubAPICredentialsError{Err: fmt.Errorf("getting secret %s referenced by githubAPICredentialsFrom: %w", key, err)}
+	}
+
+	c := &r.githubCredentialsClients
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if ent, ok := c.entries[key]; ok && ent.resourceVersion == secret.ResourceVersion {
+		return ent.client, nil
+	}
+
+	creds, err := getGitHubCredentials(secret)
+	if err != nil {
+		return nil, &githubAPICredentialsError{Err: fmt.Errorf("secret %s referenced by githubAPICredentialsFrom: %w", key, err)}
+	}
+
+	client, err := r.GitHubConfig.NewClientWithCredentials(*creds)
+	if err != nil {
+		return nil, &githubAPICredentialsError{Err: fmt.Errorf("creating github client from secret %s: %w", key, err)}
+	}
+
+	if c.entries == nil {
+		c.entries = map[types.NamespacedName]githubCredentialsClient{}
+	}
+
+	c.entries[key] = githubCredentialsClient{resourceVersion: secret.ResourceVersion, client: client}
+
+	return client, nil
+}
+
+// getGitHubCredentials reads either the token or the GitHub App credentials from the Secret.
+func getGitHubCredentials(secret corev1.Secret) (*github.Credentials, error) {
+	if token := strings.TrimSpace(string(secret.Data[githubAPICredentialsKeyToken])); token != "" {
+		return &github.Credentials{Token: token}, nil
+	}
+
+	appID, err := getSecretInt64(secret, githubAPICredentialsKeyAppID)
+	if err != nil {
+		return nil, err
+	}
+
+	installationID, err := getSecretInt64(secret, githubAPICredentialsKeyAppInstallationID)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey := secret.Data[githubAPICredentialsKeyAppPrivateKey]
+	if len(privateKey) == 0 {
+		return nil, fmt.Errorf("either %s or %s must be set", githubAPICredentialsKeyToken, githubAPICredentialsKeyAppPrivateKey)
+	}
+
+	return &github.Credentials{AppID: appID, AppInstallationID: installationID, AppPrivateKey: privateKey}, nil
+}
+
+func getSecretInt64(secret corev1.Secret, key string) (int64, error) {
+	v, ok := secret.Data[key]
+	if !ok {
+		return 0, fmt.Errorf("either %s or %s must be set", githubAPICredentialsKeyToken, key)
+	}
+
+	i, err := strconv.ParseInt(strings.TrimSpace(string(v)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parsing %s: %w", key, err)
+	}
+
+	return i, nil
+}
@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	DefaultScaleHistoryLookbackDays = v1alpha1.DefaultScaleHistoryLookbackDays
+	DefaultScaleHistoryBucket       = v1alpha1.DefaultScaleHistoryBucketSeconds * time.Second
+)
+
+// getScaleHistoryMetric returns the first HistoricalDesiredReplicas metric of the HorizontalRunnerAutoscaler, or nil when there's none.
+func getScaleHistoryMetric(hra v1alpha1.HorizontalRunnerAutoscaler) *v1alpha1.MetricSpec {
+	for i := range hra.Spec.Metrics {
+		if hra.Spec.Metrics[i].Type == v1alpha1.AutoscalingMetricTypeHistoricalDesiredReplicas {
+			return &hra.Spec.Metrics[i]
+		}
+	}
+
+	return nil
+}
+
+func getScaleHistoryLookbackAndBucket(metric v1alpha1.MetricSpec) (time.Duration, time.Duration) {
+	lookback := DefaultScaleHistoryLookbackDays * 24 * time.Hour
+	if metric.LookbackDays > 0 {
+		lookback = time.Duration(metric.LookbackDays) * 24 * time.Hour
+	}
+
+	bucket := DefaultScaleHistoryBucket
+	if metric.BucketSeconds > 0 {
+		bucket = time.Duration(metric.BucketSeconds) * time.Second
+	}
+
+	// The HRAs admitted before the number of the entries was validated get longer buckets rather than an unbounded history
+	if int(lookback/bucket)+2 > v1alpha1.MaxScaleHistoryEntries {
+		bucket = (lookback/(v1alpha1.MaxScaleHistoryEntries-1)/time.Second + 1) * time.Second
+	}
+
+	return lookback, bucket
+}
+
+// recordScaleHistory returns the scale history with the replicas recorded into the bucket containing now.
+// The bucket keeps the largest replicas recorded in it, and the buckets older than the lookback window are dropped,
+// so that the history never grows beyond the number of buckets in the lookback window.
+func recordScaleHistory(history []v1alpha1.ScaleHistoryEntry, now time.Time, replicas int, lookback, bucket time.Duration) []v1alpha1.ScaleHistoryEntry {
+	start := now.Truncate(bucket)
+
+	var entries []v1alpha1.ScaleHistoryEntry
+
+	for _, ent := range history {
+		if ent.Time.Add(lookback + bucket).Before(start) {
+			continue
+		}
+
+		entries = append(entries, ent)
+	}
+
+	if n := len(entries); n > 0 && entries[n-1].Time.Time.Equal(start) {
+		if entries[n-1].Replicas < replicas {
+			entries[n-1].Replicas = replicas
+		}
+	} else {
+		entries = append(entries, v1alpha1.ScaleHistoryEntry{Time: metav1.Time{Time: start}, Replicas: replicas})
+	}
+
+	// Guard against the history growing unbounded when the system clock goes backward
+	if max := int(lookback/bucket) + 2; len(entries) > max {
+		entries = entries[len(entries)-max:]
+	}
+
+	return entries
+}
+
+// calculateReplicasByHistoricalDesiredReplicas anticipates the desired replicas from the scale history, by averaging the largest
+// replicas recorded in each of the past days around the same time of day, including the bucket that follows it
+// so that runners are added before the usual spike.
+// It results in minReplicas until the history covers the whole lookback window.
+func (r *HorizontalRunnerAutoscalerReconciler) calculateReplicasByHistoricalDesiredReplicas(hra v1alpha1.HorizontalRunnerAutoscaler, metric v1alpha1.MetricSpec) (*metricResult, error) {
+	now := time.Now()
+
+	lookback, bucket := getScaleHistoryLookbackAndBucket(metric)
+
+	minReplicas := *hra.Spec.MinReplicas
+	maxReplicas := *hra.Spec.MaxReplicas
+
+	history := hra.Status.ScaleHistory
+
+	if len(history) == 0 {
+		return &metricResult{Replicas: minReplicas, ObservedValue: "no scale history yet"}, nil
+	}
+
+	if collectedUntil := history[0].Time.Add(lookback); collectedUntil.After(now) {
+		return &metricResult{
+			Replicas:      minReplicas,
+			ObservedValue: fmt.Sprintf("collecting scale history until %s", collectedUntil.Format(time.RFC3339)),
+		}, nil
+	}
+
+	var sum, days int
+
+	for d := 1; time.Duration(d)*24*time.Hour <= lookback; d++ {
+		from := now.Add(-time.Duration(d) * 24 * time.Hour)
+		to := from.Add(bucket)
+
+		peak := -1
+
+		for _, ent := range history {
+			if !ent.Time.Time.Before(to) || !ent.Time.Add(bucket).After(from) {
+				continue
+			}
+
+			if ent.Replicas > peak {
+				peak = ent.Replicas
+			}
+		}
+
+		if peak < 0 {
+			continue
+		}
+
+		sum += peak
+		days++
+	}
+
+	if days == 0 {
+		return &metricResult{Replicas: minReplicas, ObservedValue: "no scale history at this time of day"}, nil
+	}
+
+	// Round up, as the metric is meant to prepare enough runners for the usual spike
+	anticipated := (sum + days - 1) / days
+
+	replicas := anticipated
+
+	if replicas < minReplicas {
+		replicas = minReplicas
+	} else if replicas > maxReplicas {
+		replicas = maxReplicas
+	}
+
+	observed := fmt.Sprintf("%d replicas on average at this time of day over the last %d days", anticipated, days)
+
+	return &metricResult{Replicas: replicas, ObservedValue: observed}, nil
+}
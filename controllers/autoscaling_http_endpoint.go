@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	defaultHTTPEndpointAuthHeader = "Authorization"
+
+	// maxHTTPEndpointResponseBytes bounds the response body read from the endpoint, as it's expected to be a tiny JSON object.
+	maxHTTPEndpointResponseBytes = 1 << 20
+)
+
+// errMetricSkipped is returned by a metric that has nothing to say in this reconciliation, like an HTTPEndpoint responding
+// with a non-200 status. Unlike other errors, it's never treated as a GitHub API failure.
+var errMetricSkipped = errors.New("metric skipped")
+
+type httpEndpointResponse struct {
+	DesiredReplicas *int `json:"desiredReplicas"`
+	QueueDepth      *int `json:"queueDepth"`
+}
+
+// calculateReplicasByHTTPEndpoint computes the desired replicas from the JSON reported by the HTTP endpoint of the metric,
+// which is either the desired replicas as is or the queue depth multiplied by ReplicasPerRun.
+func (r *HorizontalRunnerAutoscalerReconciler) calculateReplicasByHTTPEndpoint(ctx context.Context, hra v1alpha1.HorizontalRunnerAutoscaler, metrics v1alpha1.MetricSpec) (*metricResult, error) {
+	minReplicas := *hra.Spec.MinReplicas
+	maxReplicas := *hra.Spec.MaxReplicas
+
+	endpoint := metrics.HTTPEndpoint
+	if endpoint == nil || endpoint.URL == "" {
+		return nil, errors.New("validating autoscaling metrics: spec.autoscaling.metrics[].httpEndpoint.url must be set for HTTPEndpoint")
+	}
+
+	replicasPerRun, err := getReplicasPerRun(metrics)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("validating autoscaling metrics: spec.autoscaling.metrics[].httpEndpoint.url is invalid: %v", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	if err := r.setMetricAuthHeader(ctx, req, hra, endpoint.AuthHeader, endpoint.AuthSecretRef, "httpEndpoint"); err != nil {
+		return nil, err
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		// Formatted with %v rather than wrapped, so that the failure isn't mistaken for an unreachable GitHub API
+		return nil, fmt.Errorf("requesting httpEndpoint: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: httpEndpoint responded with status %d", errMetricSkipped, res.StatusCode)
+	}
+
+	var body httpEndpointResponse
+	if err := json.NewDecoder(io.LimitReader(res.Body, maxHTTPEndpointResponseBytes)).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decoding httpEndpoint response: %v", err)
+	}
+
+	var (
+		desiredReplicas int
+		observed        string
+	)
+
+	switch {
+	case body.DesiredReplicas != nil:
+		desiredReplicas = *body.DesiredReplicas
+		observed = fmt.Sprintf("%d replicas desired", desiredReplicas)
+	case body.QueueDepth != nil:
+		desiredReplicas = replicasForRuns(*body.QueueDepth, replicasPerRun)
+		observed = fmt.Sprintf("queue depth of %d", *body.QueueDepth)
+	default:
+		return nil, errors.New("httpEndpoint responded with neither desiredReplicas nor queueDepth")
+	}
+
+	uncappedReplicas := desiredReplicas
+
+	if desiredReplicas < minReplicas {
+		desiredReplicas = minReplicas
+	} else if desiredReplicas > maxReplicas {
+		desiredReplicas = maxReplicas
+	}
+
+	r.Log.V(1).Info(
+		"Calculated desired replicas",
+		"computed_replicas_desired", desiredReplicas,
+		"spec_replicas_min", minReplicas,
+		"spec_replicas_max", maxReplicas,
+		"observed", observed,
+		"namespace", hra.Namespace,
+		"horizontal_runner_autoscaler", hra.Name,
+	)
+
+	return &metricResult{Replicas: desiredReplicas, ObservedValue: observed, UncappedReplicas: uncappedReplicas}, nil
+}
+
+// setMetricAuthHeader sets the value of the secret key to the auth header of the request sent by the HTTPEndpoint or Prometheus metric.
+// The source is the name of the metric field the secret is referenced from, included in errors.
+func (r *HorizontalRunnerAutoscalerReconciler) setMetricAuthHeader(ctx context.Context, req *http.Request, hra v1alpha1.HorizontalRunnerAutoscaler, header string, ref *corev1.SecretKeySelector, source string) error {
+	if ref == nil {
+		return nil
+	}
+
+	var secret corev1.Secret
+	if err := r.Get(ctx, types.NamespacedName{Namespace: hra.Namespace, Name: ref.Name}, &secret); err != nil {
+		return fmt.Errorf("getting secret %s/%s for %s: %w", hra.Namespace, ref.Name, source, err)
+	}
+
+	value, ok := secret.Data[ref.Key]
+	if !ok {
+		return fmt.Errorf("secret %s/%s for %s has no key %q", hra.Namespace, ref.Name, source, ref.Key)
+	}
+
+	if header == "" {
+		header = defaultHTTPEndpointAuthHeader
+	}
+
+	req.Header.Set(header, string(value))
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	"github.com/summerwind/actions-runner-controller/expression"
+)
+
+// metricTypeMetricExpression is the type of the metricResult computed by MetricExpression.
+const metricTypeMetricExpression = "MetricExpression"
+
+// evaluateMetricExpression computes the desired replicas by evaluating MetricExpression over the replicas demanded by the named metrics,
+// regardless of MaxReplicas, along with busyRunners and queueDepth observed by the metrics.
+// It fails when any of the referenced metrics or variables has no value, so that the expression is never evaluated
+// over a partial view, which could result in a wildly different number of replicas.
+func evaluateMetricExpression(hra v1alpha1.HorizontalRunnerAutoscaler, results map[string]*metricResult, failures map[string]error, busyRunners, queueDepth *int) (*metricResult, error) {
+	expr, err := expression.Parse(hra.Spec.MetricExpression)
+	if err != nil {
+		return nil, fmt.Errorf("parsing metricExpression: %w", err)
+	}
+
+	vars := map[string]float64{}
+
+	for name, res := range results {
+		vars[name] = float64(res.uncappedReplicas())
+	}
+
+	if busyRunners != nil {
+		vars[v1alpha1.MetricExpressionVariableBusyRunners] = float64(*busyRunners)
+	}
+
+	if queueDepth != nil {
+		vars[v1alpha1.MetricExpressionVariableQueueDepth] = float64(*queueDepth)
+	}
+
+	var observed []string
+
+	for _, name := range expr.Variables() {
+		if err, ok := failures[name]; ok {
+			return nil, fmt.Errorf("metric %q referenced by metricExpression failed: %w", name, err)
+		}
+
+		v, ok := vars[name]
+		if !ok {
+			return nil, fmt.Errorf("evaluating metricExpression: %q has no value, as no metric observed it", name)
+		}
+
+		observed = append(observed, fmt.Sprintf("%s=%g", name, v))
+	}
+
+	v, err := expr.Eval(vars)
+	if err != nil {
+		return nil, fmt.Errorf("evaluating metricExpression: %w", err)
+	}
+
+	// Subtract a small epsilon before rounding up, like replicasForLoad, so that the floating point error doesn't add a replica
+	desired := int(math.Ceil(v - 1e-9))
+	if desired < 0 {
+		desired = 0
+	}
+
+	replicas := desired
+
+	if min := *hra.Spec.MinReplicas; replicas < min {
+		replicas = min
+	} else if max := *hra.Spec.MaxReplicas; replicas > max {
+		replicas = max
+	}
+
+	observedValue := fmt.Sprintf("%s = %g", expr, v)
+	if len(observed) > 0 {
+		observedValue += fmt.Sprintf(" with %s", strings.Join(observed, ", "))
+	}
+
+	return &metricResult{
+		Type:             metricTypeMetricExpression,
+		Replicas:         replicas,
+		ObservedValue:    observedValue,
+		UncappedReplicas: desired,
+	}, nil
+}
@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	"github.com/summerwind/actions-runner-controller/github"
+)
+
+// calculateReplicasByOfflineRunners returns the number of the runners of the RunnerDeployment that are registered to GitHub but offline,
+// like the ones on crashed nodes, as the replicas to add to the ones computed by the other metrics.
+// Unlike the other metrics, the result isn't bounded by MinReplicas and MaxReplicas, as it's not the desired replicas on its own.
+func (r *HorizontalRunnerAutoscalerReconciler) calculateReplicasByOfflineRunners(ctx context.Context, ghc *github.Client, rd v1alpha1.RunnerDeployment, hra v1alpha1.HorizontalRunnerAutoscaler) (*metricResult, error) {
+	counts, err := r.countRunners(ctx, ghc, rd, getRunnerStartupGrace(hra))
+	if err != nil {
+		return nil, err
+	}
+
+	r.Log.V(1).Info(
+		"Counted offline runners",
+		"num_runners", counts.runners,
+		"num_runners_offline", counts.offline,
+		"namespace", hra.Namespace,
+		"runner_deployment", rd.Name,
+		"horizontal_runner_autoscaler", hra.Name,
+	)
+
+	return &metricResult{Replicas: counts.offline, ObservedValue: fmt.Sprintf("%d of %d runners offline", counts.offline, counts.runners)}, nil
+}
+
+// addOfflineRunners adds the replicas compensating the offline runners to the result of the other metrics, up to MaxReplicas,
+// so that the healthy capacity is maintained while the offline runners are still counted as replicas.
+func addOfflineRunners(result *metricResult, offline *metricResult, maxReplicas int) {
+	if offline.Replicas <= 0 {
+		return
+	}
+
+	uncapped := result.uncappedReplicas() + offline.Replicas
+
+	replicas := result.Replicas + offline.Replicas
+	if replicas > maxReplicas {
+		replicas = maxReplicas
+	}
+
+	result.Replicas = replicas
+	result.UncappedReplicas = uncapped
+
+	if result.ObservedValue != "" {
+		result.ObservedValue += ", "
+	}
+
+	result.ObservedValue += fmt.Sprintf("plus %s", offline.ObservedValue)
+}
@@ -0,0 +1,173 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// maxPrometheusResponseBytes bounds the response body read from the Prometheus server.
+// It's larger than the one of HTTPEndpoint, as a vector carries the labels of its sample too.
+const maxPrometheusResponseBytes = 4 << 20
+
+// prometheusQueryResponse is the response of the Prometheus instant query API.
+// See https://prometheus.io/docs/prometheus/latest/querying/api/#instant-queries
+type prometheusQueryResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+// calculateReplicasByPrometheus computes the desired replicas from the queue depth resulted by the PromQL query of the metric,
+// multiplied by ReplicasPerRun.
+// A failed query skips the metric with a warning event, so that an outage of the Prometheus server doesn't break autoscaling.
+func (r *HorizontalRunnerAutoscalerReconciler) calculateReplicasByPrometheus(ctx context.Context, hra v1alpha1.HorizontalRunnerAutoscaler, metrics v1alpha1.MetricSpec) (*metricResult, error) {
+	minReplicas := *hra.Spec.MinReplicas
+	maxReplicas := *hra.Spec.MaxReplicas
+
+	source := metrics.Prometheus
+	if source == nil || source.URL == "" || source.Query == "" {
+		return nil, errors.New("validating autoscaling metrics: spec.autoscaling.metrics[].prometheus.url and query must be set for Prometheus")
+	}
+
+	replicasPerRun, err := getReplicasPerRun(metrics)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := url.Parse(source.URL)
+	if err != nil {
+		return nil, fmt.Errorf("validating autoscaling metrics: spec.autoscaling.metrics[].prometheus.url is invalid: %v", err)
+	}
+
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/api/v1/query"
+	u.RawQuery = url.Values{"query": []string{source.Query}}.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("validating autoscaling metrics: spec.autoscaling.metrics[].prometheus.url is invalid: %v", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	if err := r.setMetricAuthHeader(ctx, req, hra, source.AuthHeader, source.AuthSecretRef, "prometheus"); err != nil {
+		return nil, err
+	}
+
+	queueDepth, err := queryPrometheus(req)
+	if err != nil {
+		msg := fmt.Sprintf("Skipping the Prometheus metric as the query %q failed: %v", source.Query, err)
+
+		r.event(&hra, corev1.EventTypeWarning, "PrometheusQueryFailed", msg)
+
+		return nil, fmt.Errorf("%w: prometheus query failed: %v", errMetricSkipped, err)
+	}
+
+	desiredReplicas := replicasForRuns(queueDepth, replicasPerRun)
+	uncappedReplicas := desiredReplicas
+
+	if desiredReplicas < minReplicas {
+		desiredReplicas = minReplicas
+	} else if desiredReplicas > maxReplicas {
+		desiredReplicas = maxReplicas
+	}
+
+	observed := fmt.Sprintf("queue depth of %d", queueDepth)
+
+	r.Log.V(1).Info(
+		"Calculated desired replicas",
+		"computed_replicas_desired", desiredReplicas,
+		"spec_replicas_min", minReplicas,
+		"spec_replicas_max", maxReplicas,
+		"observed", observed,
+		"namespace", hra.Namespace,
+		"horizontal_runner_autoscaler", hra.Name,
+	)
+
+	return &metricResult{Replicas: desiredReplicas, ObservedValue: observed, UncappedReplicas: uncappedReplicas}, nil
+}
+
+// queryPrometheus sends the instant query and returns its result as the queue depth, rounded up to a whole job.
+func queryPrometheus(req *http.Request) (int, error) {
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	var body prometheusQueryResponse
+	if err := json.NewDecoder(io.LimitReader(res.Body, maxPrometheusResponseBytes)).Decode(&body); err != nil {
+		return 0, fmt.Errorf("decoding response with status %d: %v", res.StatusCode, err)
+	}
+
+	if body.Status != "success" {
+		return 0, fmt.Errorf("responded with status %d: %s", res.StatusCode, body.Error)
+	}
+
+	// A sample is a pair of the timestamp and the value in a string
+	var sample []interface{}
+
+	switch body.Data.ResultType {
+	case "scalar":
+		if err := json.Unmarshal(body.Data.Result, &sample); err != nil {
+			return 0, fmt.Errorf("decoding scalar: %v", err)
+		}
+	case "vector":
+		var vector []struct {
+			Value []interface{} `json:"value"`
+		}
+
+		if err := json.Unmarshal(body.Data.Result, &vector); err != nil {
+			return 0, fmt.Errorf("decoding vector: %v", err)
+		}
+
+		if len(vector) != 1 {
+			return 0, fmt.Errorf("resulted in a vector of %d samples, but it must be a single sample", len(vector))
+		}
+
+		sample = vector[0].Value
+	default:
+		return 0, fmt.Errorf("resulted in an unsupported type %q, but it must be a scalar or a vector", body.Data.ResultType)
+	}
+
+	if len(sample) != 2 {
+		return 0, fmt.Errorf("resulted in a malformed sample %v", sample)
+	}
+
+	s, ok := sample[1].(string)
+	if !ok {
+		return 0, fmt.Errorf("resulted in a malformed sample %v", sample)
+	}
+
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("resulted in a malformed value %q: %v", s, err)
+	}
+
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("resulted in %s", s)
+	}
+
+	// A negative value counts as an empty queue, and a huge one is capped to avoid overflowing int,
+	// as the desired replicas are capped by maxReplicas anyway
+	if v < 0 {
+		return 0, nil
+	} else if v > math.MaxInt32 {
+		return math.MaxInt32, nil
+	}
+
+	return int(math.Ceil(v)), nil
+}
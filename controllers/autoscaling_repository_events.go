@@ -0,0 +1,166 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	gogithub "github.com/google/go-github/v33/github"
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	"github.com/summerwind/actions-runner-controller/github"
+)
+
+const (
+	// defaultEventLookback is the duration within which the RecentPushAndPullRequestEvents metric counts the events by default.
+	// It's short so that the events whose workflow runs are already queued, and so counted by the other metrics, aren't counted for long.
+	defaultEventLookback = 5 * time.Minute
+
+	// defaultReplicasPerEvent is small by default, as not every event triggers a workflow run that needs a runner.
+	defaultReplicasPerEvent = 0.25
+)
+
+func getReplicasPerEvent(metrics v1alpha1.MetricSpec) (float64, error) {
+	if metrics.ReplicasPerEvent == "" {
+		return defaultReplicasPerEvent, nil
+	}
+
+	replicasPerEvent, err := strconv.ParseFloat(metrics.ReplicasPerEvent, 64)
+	if err != nil {
+		return 0, errors.New("validating autoscaling metrics: spec.autoscaling.metrics[].replicasPerEvent cannot be parsed into a float64")
+	}
+
+	if replicasPerEvent <= 0 || math.IsInf(replicasPerEvent, 0) || math.IsNaN(replicasPerEvent) {
+		return 0, fmt.Errorf("validating autoscaling metrics: spec.autoscaling.metrics[].replicasPerEvent must be greater than 0, but got %s", metrics.ReplicasPerEvent)
+	}
+
+	return replicasPerEvent, nil
+}
+
+func getEventLookback(metrics v1alpha1.MetricSpec) time.Duration {
+	if metrics.EventLookbackSeconds == nil || *metrics.EventLookbackSeconds <= 0 {
+		return defaultEventLookback
+	}
+
+	return time.Duration(*metrics.EventLookbackSeconds) * time.Second
+}
+
+// isWorkflowTriggeringEvent returns true for the push events, and the pull_request events opening or updating pull requests,
+// which are the ones that usually trigger workflow runs. The other actions like labeling a pull request are ignored to avoid over-scaling.
+func isWorkflowTriggeringEvent(e *gogithub.Event) bool {
+	switch e.GetType() {
+	case "PushEvent":
+		return true
+	case "PullRequestEvent":
+		payload, err := e.ParsePayload()
+		if err != nil {
+			return false
+		}
+
+		pr, ok := payload.(*gogithub.PullRequestEvent)
+		if !ok {
+			return false
+		}
+
+		switch pr.GetAction() {
+		case "opened", "reopened", "synchronize":
+			return true
+		}
+	}
+
+	return false
+}
+
+// calculateReplicasByRecentPushAndPullRequestEvents computes the desired replicas from the number of the recent events
+// of the repositories likely to trigger workflow runs, so that the runners are added slightly before the runs are queued.
+func (r *HorizontalRunnerAutoscalerReconciler) calculateReplicasByRecentPushAndPullRequestEvents(ctx context.Context, ghc *github.Client, rd v1alpha1.RunnerDeployment, hra v1alpha1.HorizontalRunnerAutoscaler, metrics v1alpha1.MetricSpec) (*metricResult, error) {
+	enterprise, orgName, repoID, err := getScaleTargetScope(rd)
+	if err != nil {
+		return nil, err
+	}
+
+	replicasPerEvent, err := getReplicasPerEvent(metrics)
+	if err != nil {
+		return nil, err
+	}
+
+	repos, err := getMetricRepositories(enterprise, orgName, repoID, metrics)
+	if err != nil {
+		return nil, err
+	}
+
+	lookback := getEventLookback(metrics)
+	since := time.Now().Add(-lookback)
+
+	var (
+		numEvents int
+		failed    int
+		firstErr  error
+	)
+
+	// Like the workflow runs, the events of the repositories that succeeded are still counted
+	for _, repo := range repos {
+		start := time.Now()
+		events, err := ghc.ListRepositoryEventsSince(ctx, repo[0], repo[1], since)
+		observeGitHubAPICall(ctx, githubAPICallEndpointListRepositoryEvents, start, err)
+		if err != nil {
+			r.Log.Error(err, "Could not list repository events", "owner", repo[0], "repository", repo[1], "horizontal_runner_autoscaler", hra.Name, "namespace", hra.Namespace)
+
+			if firstErr == nil {
+				firstErr = err
+			}
+
+			failed++
+
+			continue
+		}
+
+		for _, e := range events {
+			if isWorkflowTriggeringEvent(e) {
+				numEvents++
+			}
+		}
+	}
+
+	if failed == len(repos) {
+		if len(repos) == 1 {
+			return nil, firstErr
+		}
+
+		return nil, fmt.Errorf("listing events of all the %d repositories: %w", len(repos), firstErr)
+	}
+
+	minReplicas := *hra.Spec.MinReplicas
+	maxReplicas := *hra.Spec.MaxReplicas
+
+	necessaryReplicas := replicasForLoad(float64(numEvents), replicasPerEvent)
+
+	desiredReplicas := necessaryReplicas
+	if desiredReplicas < minReplicas {
+		desiredReplicas = minReplicas
+	} else if desiredReplicas > maxReplicas {
+		desiredReplicas = maxReplicas
+	}
+
+	r.Log.V(1).Info(
+		"Calculated desired replicas",
+		"computed_replicas_desired", desiredReplicas,
+		"spec_replicas_min", minReplicas,
+		"spec_replicas_max", maxReplicas,
+		"events", numEvents,
+		"event_lookback", lookback.String(),
+		"replicas_per_event", replicasPerEvent,
+		"namespace", hra.Namespace,
+		"runner_deployment", rd.Name,
+		"horizontal_runner_autoscaler", hra.Name,
+	)
+
+	observed := fmt.Sprintf("%d push and pull_request events in the last %s", numEvents, lookback)
+	if failed > 0 {
+		observed += fmt.Sprintf(" in %d of %d repositories", len(repos)-failed, len(repos))
+	}
+
+	return &metricResult{Replicas: desiredReplicas, ObservedValue: observed, UncappedReplicas: necessaryReplicas}, nil
+}
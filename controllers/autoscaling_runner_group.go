@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	"github.com/summerwind/actions-runner-controller/github"
+)
+
+// calculateReplicasByPercentageRunnerGroupBusy is PercentageRunnersBusy computed over the online runners registered in the runner group,
+// regardless of which RunnerDeployment they belong to, so that runners sharing a runner group are scaled by the utilization of the group.
+// It results in minReplicas when the runner group has no online runners, as there's nothing to measure the utilization of.
+func (r *HorizontalRunnerAutoscalerReconciler) calculateReplicasByPercentageRunnerGroupBusy(ctx context.Context, ghc *github.Client, rd v1alpha1.RunnerDeployment, hra v1alpha1.HorizontalRunnerAutoscaler, metrics v1alpha1.MetricSpec) (*metricResult, error) {
+	minReplicas := *hra.Spec.MinReplicas
+	maxReplicas := *hra.Spec.MaxReplicas
+
+	if metrics.RunnerGroup == "" {
+		return nil, errors.New("validating autoscaling metrics: spec.autoscaling.metrics[].runnerGroup must be set for PercentageRunnerGroupBusy")
+	}
+
+	if metrics.TargetUtilizationPercent != nil {
+		return nil, errors.New("validating autoscaling metrics: spec.autoscaling.metrics[].targetUtilizationPercent is supported only by PercentageRunnersBusy")
+	}
+
+	params, err := getPercentageBusyParams(metrics)
+	if err != nil {
+		return nil, err
+	}
+
+	enterprise, organization, repository, err := getScaleTargetScope(rd)
+	if err != nil {
+		return nil, err
+	}
+
+	if repository != "" {
+		return nil, fmt.Errorf("validating autoscaling metrics: PercentageRunnerGroupBusy requires an organization or enterprise runnerdeployment, but %s/%s is for repository %s", rd.Namespace, rd.Name, repository)
+	}
+
+	runners, err := r.listRunnerGroupRunners(ctx, ghc, enterprise, organization, metrics.RunnerGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	var numRunners, numRunnersBusy int
+
+	for _, runner := range runners {
+		// Offline runners are stale registrations that would otherwise be counted as idle
+		if runner.GetStatus() == "offline" {
+			continue
+		}
+
+		numRunners++
+
+		if runner.GetBusy() {
+			numRunnersBusy++
+		}
+	}
+
+	if numRunners == 0 {
+		return &metricResult{
+			Replicas:      minReplicas,
+			ObservedValue: fmt.Sprintf("no online runners in runner group %s", metrics.RunnerGroup),
+			BusyRunners:   &numRunnersBusy,
+		}, nil
+	}
+
+	fractionBusy := float64(numRunnersBusy) / float64(numRunners)
+
+	var desiredReplicas int
+
+	if fractionBusy >= params.scaleUpThreshold {
+		if params.scaleUpAdjustment > 0 {
+			desiredReplicas = numRunners + params.scaleUpAdjustment
+		} else {
+			desiredReplicas = int(math.Ceil(float64(numRunners) * params.scaleUpFactor))
+		}
+	} else if fractionBusy < params.scaleDownThreshold {
+		if params.scaleDownAdjustment > 0 {
+			desiredReplicas = numRunners - params.scaleDownAdjustment
+		} else {
+			desiredReplicas = int(float64(numRunners) * params.scaleDownFactor)
+		}
+	} else {
+		desiredReplicas = getIntOrDefault(rd.Spec.Replicas, numRunners)
+	}
+
+	uncappedReplicas := desiredReplicas
+
+	if desiredReplicas < minReplicas {
+		desiredReplicas = minReplicas
+	} else if desiredReplicas > maxReplicas {
+		desiredReplicas = maxReplicas
+	}
+
+	r.Log.V(1).Info(
+		"Calculated desired replicas",
+		"computed_replicas_desired", desiredReplicas,
+		"spec_replicas_min", minReplicas,
+		"spec_replicas_max", maxReplicas,
+		"current_replicas", rd.Spec.Replicas,
+		"runner_group", metrics.RunnerGroup,
+		"num_runners", numRunners,
+		"num_runners_busy", numRunnersBusy,
+		"namespace", hra.Namespace,
+		"runner_deployment", rd.Name,
+		"horizontal_runner_autoscaler", hra.Name,
+		"enterprise", enterprise,
+		"organization", organization,
+	)
+
+	observed := fmt.Sprintf("%d of %d runners busy in runner group %s (%.0f%%)", numRunnersBusy, numRunners, metrics.RunnerGroup, fractionBusy*100)
+
+	return &metricResult{Replicas: desiredReplicas, ObservedValue: observed, BusyRunners: &numRunnersBusy, UncappedReplicas: uncappedReplicas}, nil
+}
@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	gogithub "github.com/google/go-github/v33/github"
+	"github.com/summerwind/actions-runner-controller/github"
+)
+
+const (
+	// DefaultRunnerListCacheTTL is the default duration for which a listing of the runners registered to GitHub
+	// is reused across HorizontalRunnerAutoscalers.
+	DefaultRunnerListCacheTTL = 30 * time.Second
+)
+
+// runnerListCache keeps the recent listings of the runners registered to GitHub, so that HorizontalRunnerAutoscalers
+// sharing the same organization or runner group don't list the same runners on each of their reconciliations.
+// It's distinct from the per-HorizontalRunnerAutoscaler cache of the desired replicas in the status.
+type runnerListCache struct {
+	mu sync.Mutex
+
+	entries map[runnerListCacheKey]runnerListCacheEntry
+}
+
+// runnerListCacheKey is the scope of a runner listing. runnerGroup is set only for the runners of a runner group.
+type runnerListCacheKey struct {
+	enterprise, organization, repository, runnerGroup string
+}
+
+type runnerListCacheEntry struct {
+	runners        []*gogithub.Runner
+	expirationTime time.Time
+}
+
+func (c *runnerListCache) get(key runnerListCacheKey, now time.Time) ([]*gogithub.Runner, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ent, ok := c.entries[key]
+	if !ok || !now.Before(ent.expirationTime) {
+		return nil, false
+	}
+
+	return ent.runners, true
+}
+
+func (c *runnerListCache) set(key runnerListCacheKey, runners []*gogithub.Runner, now time.Time, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = map[runnerListCacheKey]runnerListCacheEntry{}
+	}
+
+	// Drop the expired entries so that the cache doesn't grow with the scopes no longer autoscaled
+	for k, ent := range c.entries {
+		if !now.Before(ent.expirationTime) {
+			delete(c.entries, k)
+		}
+	}
+
+	c.entries[key] = runnerListCacheEntry{runners: runners, expirationTime: now.Add(ttl)}
+}
+
+func (r *HorizontalRunnerAutoscalerReconciler) runnerListCacheTTL() time.Duration {
+	if r.RunnerListCacheTTL == 0 {
+		return DefaultRunnerListCacheTTL
+	}
+
+	if r.RunnerListCacheTTL < 0 {
+		return 0
+	}
+
+	return r.RunnerListCacheTTL
+}
+
+// listRunners lists the runners registered to the enterprise, the organization, or the repository,
+// reusing the listing made for another HorizontalRunnerAutoscaler within the runner list cache TTL.
+func (r *HorizontalRunnerAutoscalerReconciler) listRunners(ctx context.Context, ghc *github.Client, enterprise, organization, repository string) ([]*gogithub.Runner, error) {
+	key := runnerListCacheKey{enterprise: enterprise, organization: organization, repository: repository}
+
+	return r.listRunnersWithCache(key, func() ([]*gogithub.Runner, error) {
+		start := time.Now()
+		runners, err := ghc.ListRunners(ctx, enterprise, organization, repository)
+		observeGitHubAPICall(ctx, githubAPICallEndpointListRunners, start, err)
+
+		return runners, err
+	})
+}
+
+// listRunnerGroupRunners lists the runners in the runner group of the enterprise or the organization,
+// reusing the listing made for another HorizontalRunnerAutoscaler within the runner list cache TTL.
+func (r *HorizontalRunnerAutoscalerReconciler) listRunnerGroupRunners(ctx context.Context, ghc *github.Client, enterprise, organization, runnerGroup string) ([]*gogithub.Runner, error) {
+	key := runnerListCacheKey{enterprise: enterprise, organization: organization, runnerGroup: runnerGroup}
+
+	return r.listRunnersWithCache(key, func() ([]*gogithub.Runner, error) {
+		start := time.Now()
+		runners, err := ghc.ListRunnerGroupRunners(ctx, enterprise, organization, runnerGroup)
+		observeGitHubAPICall(ctx, githubAPICallEndpointListRunnerGroupRunners, start, err)
+
+		return runners, err
+	})
+}
+
+// listRunnersWithCache returns the cached runners for the key, or the ones listed by list otherwise.
+// Failed listings aren't cached, so that the next reconciliation retries right away.
+func (r *HorizontalRunnerAutoscalerReconciler) listRunnersWithCache(key runnerListCacheKey, list func() ([]*gogithub.Runner, error)) ([]*gogithub.Runner, error) {
+	ttl := r.runnerListCacheTTL()
+
+	if ttl > 0 {
+		if runners, ok := r.runnerListCache.get(key, time.Now()); ok {
+			return runners, nil
+		}
+	}
+
+	runners, err := list()
+	if err != nil {
+		return nil, err
+	}
+
+	if ttl > 0 {
+		r.runnerListCache.set(key, runners, time.Now(), ttl)
+	}
+
+	return runners, nil
+}
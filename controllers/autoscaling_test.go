@@ -1,17 +1,28 @@
 package controllers
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
+	"time"
 
+	gogithub "github.com/google/go-github/v33/github"
 	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
 	"github.com/summerwind/actions-runner-controller/github"
 	"github.com/summerwind/actions-runner-controller/github/fake"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 )
 
@@ -34,7 +45,7 @@ func newGithubClient(server *httptest.Server) *github.Client {
 }
 
 func TestDetermineDesiredReplicas_RepositoryRunner(t *testing.T) {
-	intPtr := func(v int) *int {
+	boolPtr := func(v bool) *bool {
 		return &v
 	}
 
@@ -48,6 +59,9 @@ func TestDetermineDesiredReplicas_RepositoryRunner(t *testing.T) {
 		sReplicas *int
 		sTime     *metav1.Time
 
+		scaleUpDelay *int
+		sScaleUpTime *metav1.Time
+
 		workflowRuns             string
 		workflowRuns_queued      string
 		workflowRuns_in_progress string
@@ -55,6 +69,14 @@ func TestDetermineDesiredReplicas_RepositoryRunner(t *testing.T) {
 		workflowJobs map[int]string
 		want         int
 		err          string
+
+		includeInProgress *bool
+		replicasPerRun    string
+		labels            []string
+
+		// graphQL is the response of GitHub GraphQL API, which enables UseGraphQL when set
+		graphQL       string
+		graphQLStatus int
 	}{
 		// Legacy functionality
 		// 3 demanded, max at 3
@@ -151,6 +173,47 @@ func TestDetermineDesiredReplicas_RepositoryRunner(t *testing.T) {
 			want:                     3,
 		},
 
+		// Scale-up delay
+		// 3 demanded, currently 2, defer scaling up due to scale-up delay
+		{
+			repo:                     "test/valid",
+			min:                      intPtr(1),
+			max:                      intPtr(3),
+			sReplicas:                intPtr(2),
+			scaleUpDelay:             intPtr(300),
+			sScaleUpTime:             &metav1Now,
+			workflowRuns:             `{"total_count": 4, "workflow_runs":[{"status":"queued"}, {"status":"in_progress"}, {"status":"in_progress"}, {"status":"completed"}]}"`,
+			workflowRuns_queued:      `{"total_count": 1, "workflow_runs":[{"status":"queued"}]}"`,
+			workflowRuns_in_progress: `{"total_count": 2, "workflow_runs":[{"status":"in_progress"}, {"status":"in_progress"}]}"`,
+			want:                     2,
+		},
+		// 3 demanded, currently 2, scale up as the scale-up delay has elapsed
+		{
+			repo:                     "test/valid",
+			min:                      intPtr(1),
+			max:                      intPtr(3),
+			sReplicas:                intPtr(2),
+			scaleUpDelay:             intPtr(300),
+			sScaleUpTime:             &metav1.Time{Time: metav1Now.Add(-301 * time.Second)},
+			workflowRuns:             `{"total_count": 4, "workflow_runs":[{"status":"queued"}, {"status":"in_progress"}, {"status":"in_progress"}, {"status":"completed"}]}"`,
+			workflowRuns_queued:      `{"total_count": 1, "workflow_runs":[{"status":"queued"}]}"`,
+			workflowRuns_in_progress: `{"total_count": 2, "workflow_runs":[{"status":"in_progress"}, {"status":"in_progress"}]}"`,
+			want:                     3,
+		},
+		// 1 demanded, currently 3, the scale-up delay doesn't block scaling down
+		{
+			repo:                     "test/valid",
+			min:                      intPtr(1),
+			max:                      intPtr(3),
+			sReplicas:                intPtr(3),
+			scaleUpDelay:             intPtr(300),
+			sScaleUpTime:             &metav1Now,
+			workflowRuns:             `{"total_count": 2, "workflow_runs":[{"status":"in_progress"}, {"status":"completed"}]}"`,
+			workflowRuns_queued:      `{"total_count": 0, "workflow_runs":[]}"`,
+			workflowRuns_in_progress: `{"total_count": 1, "workflow_runs":[{"status":"in_progress"}]}"`,
+			want:                     1,
+		},
+
 		// Job-level autoscaling
 		// 5 requested from 3 workflows
 		{
@@ -167,6 +230,128 @@ func TestDetermineDesiredReplicas_RepositoryRunner(t *testing.T) {
 			},
 			want: 5,
 		},
+		// 1 queued and 2 in-progress, in-progress runs are ignored
+		{
+			repo:                     "test/valid",
+			min:                      intPtr(1),
+			max:                      intPtr(5),
+			includeInProgress:        boolPtr(false),
+			workflowRuns:             `{"total_count": 3, "workflow_runs":[{"status":"queued"}, {"status":"in_progress"}, {"status":"in_progress"}]}"`,
+			workflowRuns_queued:      `{"total_count": 1, "workflow_runs":[{"status":"queued"}]}"`,
+			workflowRuns_in_progress: `{"total_count": 2, "workflow_runs":[{"status":"in_progress"}, {"status":"in_progress"}]}"`,
+			want:                     1,
+		},
+		// 1 queued and 2 in-progress, in-progress runs are explicitly included
+		{
+			repo:                     "test/valid",
+			min:                      intPtr(1),
+			max:                      intPtr(5),
+			includeInProgress:        boolPtr(true),
+			workflowRuns:             `{"total_count": 3, "workflow_runs":[{"status":"queued"}, {"status":"in_progress"}, {"status":"in_progress"}]}"`,
+			workflowRuns_queued:      `{"total_count": 1, "workflow_runs":[{"status":"queued"}]}"`,
+			workflowRuns_in_progress: `{"total_count": 2, "workflow_runs":[{"status":"in_progress"}, {"status":"in_progress"}]}"`,
+			want:                     3,
+		},
+		// 3 runs with a runner per two runs, rounded up
+		{
+			repo:                     "test/valid",
+			min:                      intPtr(1),
+			max:                      intPtr(10),
+			includeInProgress:        boolPtr(true),
+			replicasPerRun:           "0.5",
+			workflowRuns:             `{"total_count": 3, "workflow_runs":[{"status":"queued"}, {"status":"in_progress"}, {"status":"in_progress"}]}"`,
+			workflowRuns_queued:      `{"total_count": 1, "workflow_runs":[{"status":"queued"}]}"`,
+			workflowRuns_in_progress: `{"total_count": 2, "workflow_runs":[{"status":"in_progress"}, {"status":"in_progress"}]}"`,
+			want:                     2,
+		},
+		// 3 runs with two runners per run, capped by max
+		{
+			repo:                     "test/valid",
+			min:                      intPtr(1),
+			max:                      intPtr(5),
+			includeInProgress:        boolPtr(true),
+			replicasPerRun:           "2",
+			workflowRuns:             `{"total_count": 3, "workflow_runs":[{"status":"queued"}, {"status":"in_progress"}, {"status":"in_progress"}]}"`,
+			workflowRuns_queued:      `{"total_count": 1, "workflow_runs":[{"status":"queued"}]}"`,
+			workflowRuns_in_progress: `{"total_count": 2, "workflow_runs":[{"status":"in_progress"}, {"status":"in_progress"}]}"`,
+			want:                     5,
+		},
+		// replicasPerRun must be greater than 0
+		{
+			repo:                     "test/valid",
+			min:                      intPtr(1),
+			max:                      intPtr(5),
+			includeInProgress:        boolPtr(true),
+			replicasPerRun:           "0",
+			workflowRuns:             `{"total_count": 3, "workflow_runs":[{"status":"queued"}, {"status":"in_progress"}, {"status":"in_progress"}]}"`,
+			workflowRuns_queued:      `{"total_count": 1, "workflow_runs":[{"status":"queued"}]}"`,
+			workflowRuns_in_progress: `{"total_count": 2, "workflow_runs":[{"status":"in_progress"}, {"status":"in_progress"}]}"`,
+			err:                      "validating autoscaling metrics: spec.autoscaling.metrics[].replicasPerRun must be greater than 0, but got 0",
+		},
+		// 5 requested from 3 workflows, but only 3 jobs have labels matching the runners.
+		// The job without labels is counted to stay safe.
+		{
+			repo:                     "test/valid",
+			min:                      intPtr(1),
+			max:                      intPtr(10),
+			labels:                   []string{"gpu"},
+			workflowRuns:             `{"total_count": 3, "workflow_runs":[{"id": 1, "status":"queued"}, {"id": 2, "status":"in_progress"}, {"id": 3, "status":"in_progress"}]}"`,
+			workflowRuns_queued:      `{"total_count": 1, "workflow_runs":[{"id": 1, "status":"queued"}]}"`,
+			workflowRuns_in_progress: `{"total_count": 2, "workflow_runs":[{"id": 2, "status":"in_progress"}, {"id": 3, "status":"in_progress"}]}"`,
+			workflowJobs: map[int]string{
+				1: `{"jobs": [{"status":"queued", "labels":["self-hosted", "GPU"]}, {"status":"queued", "labels":["self-hosted", "cpu"]}]}`,
+				2: `{"jobs": [{"status": "in_progress", "labels":["self-hosted", "gpu"]}, {"status":"completed", "labels":["self-hosted", "gpu"]}]}`,
+				3: `{"jobs": [{"status": "in_progress"}, {"status":"queued", "labels":["self-hosted", "cpu"]}]}`,
+			},
+			want: 3,
+		},
+		// 4 jobs of 3 workflow runs listed via GraphQL. The run listed both as a branch head and a pull request is counted once,
+		// and the run without jobs yet is counted on its own.
+		{
+			repo:                     "test/valid",
+			min:                      intPtr(1),
+			max:                      intPtr(10),
+			workflowRuns:             `{"total_count": 0, "workflow_runs":[]}"`,
+			workflowRuns_queued:      `{"total_count": 0, "workflow_runs":[]}"`,
+			workflowRuns_in_progress: `{"total_count": 0, "workflow_runs":[]}"`,
+			graphQL: `{"data": {"repository": {` +
+				`"refs": {"pageInfo": {"hasNextPage": false}, "nodes": [{"target": {"checkSuites": {"nodes": [` +
+				`{"status": "QUEUED", "workflowRun": {"databaseId": 1, "workflow": {"databaseId": 10}}, "queued": {"totalCount": 2}, "inProgress": {"totalCount": 0}},` +
+				`{"status": "IN_PROGRESS", "workflowRun": {"databaseId": 2, "workflow": {"databaseId": 10}}, "queued": {"totalCount": 0}, "inProgress": {"totalCount": 1}},` +
+				`{"status": "COMPLETED", "workflowRun": {"databaseId": 3, "workflow": {"databaseId": 10}}, "queued": {"totalCount": 0}, "inProgress": {"totalCount": 0}},` +
+				`{"status": "QUEUED", "queued": {"totalCount": 5}, "inProgress": {"totalCount": 0}}` +
+				`]}}}]},` +
+				`"pullRequests": {"pageInfo": {"hasNextPage": false}, "nodes": [{"commits": {"nodes": [{"commit": {"checkSuites": {"nodes": [` +
+				`{"status": "QUEUED", "workflowRun": {"databaseId": 1, "workflow": {"databaseId": 10}}, "queued": {"totalCount": 2}, "inProgress": {"totalCount": 0}},` +
+				`{"status": "QUEUED", "workflowRun": {"databaseId": 4, "workflow": {"databaseId": 20}}, "queued": {"totalCount": 0}, "inProgress": {"totalCount": 0}}` +
+				`]}}}]}}]}}}}`,
+			graphQLStatus: 200,
+			want:          4,
+		},
+		// Falls back to REST when the GraphQL call fails
+		{
+			repo:                     "test/valid",
+			min:                      intPtr(1),
+			max:                      intPtr(10),
+			workflowRuns:             `{"total_count": 2, "workflow_runs":[{"status":"queued"}, {"status":"in_progress"}]}"`,
+			workflowRuns_queued:      `{"total_count": 1, "workflow_runs":[{"status":"queued"}]}"`,
+			workflowRuns_in_progress: `{"total_count": 1, "workflow_runs":[{"status":"in_progress"}]}"`,
+			graphQL:                  `{"message": "Something went wrong"}`,
+			graphQLStatus:            500,
+			want:                     2,
+		},
+		// Falls back to REST when the repository has too many branches for a single query
+		{
+			repo:                     "test/valid",
+			min:                      intPtr(1),
+			max:                      intPtr(10),
+			workflowRuns:             `{"total_count": 2, "workflow_runs":[{"status":"queued"}, {"status":"in_progress"}]}"`,
+			workflowRuns_queued:      `{"total_count": 1, "workflow_runs":[{"status":"queued"}]}"`,
+			workflowRuns_in_progress: `{"total_count": 1, "workflow_runs":[{"status":"in_progress"}]}"`,
+			graphQL:                  `{"data": {"repository": {"refs": {"pageInfo": {"hasNextPage": true}, "nodes": []}, "pullRequests": {"pageInfo": {"hasNextPage": false}, "nodes": []}}}}`,
+			graphQLStatus:            200,
+			want:                     2,
+		},
 	}
 
 	for i := range testcases {
@@ -185,6 +370,7 @@ func TestDetermineDesiredReplicas_RepositoryRunner(t *testing.T) {
 				fake.WithListRepositoryWorkflowRunsResponse(200, tc.workflowRuns, tc.workflowRuns_queued, tc.workflowRuns_in_progress),
 				fake.WithListWorkflowJobsResponse(200, tc.workflowJobs),
 				fake.WithListRunnersResponse(200, fake.RunnersListBody),
+				fake.WithGraphQLResponse(tc.graphQLStatus, tc.graphQL),
 			)
 			defer server.Close()
 			client := newGithubClient(server)
@@ -193,6 +379,7 @@ func TestDetermineDesiredReplicas_RepositoryRunner(t *testing.T) {
 				Log:          log,
 				GitHubClient: client,
 				Scheme:       scheme,
+				UseGraphQL:   tc.graphQL != "",
 			}
 
 			rd := v1alpha1.RunnerDeployment{
@@ -204,6 +391,7 @@ func TestDetermineDesiredReplicas_RepositoryRunner(t *testing.T) {
 					Template: v1alpha1.RunnerTemplate{
 						Spec: v1alpha1.RunnerSpec{
 							Repository: tc.repo,
+							Labels:     tc.labels,
 						},
 					},
 					Replicas: tc.fixed,
@@ -215,16 +403,28 @@ func TestDetermineDesiredReplicas_RepositoryRunner(t *testing.T) {
 
 			hra := v1alpha1.HorizontalRunnerAutoscaler{
 				Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
-					MaxReplicas: tc.max,
-					MinReplicas: tc.min,
+					MaxReplicas:         tc.max,
+					MinReplicas:         tc.min,
+					ScaleUpDelaySeconds: tc.scaleUpDelay,
 				},
 				Status: v1alpha1.HorizontalRunnerAutoscalerStatus{
 					DesiredReplicas:            tc.sReplicas,
 					LastSuccessfulScaleOutTime: tc.sTime,
+					LastScaleUpTime:            tc.sScaleUpTime,
 				},
 			}
 
-			got, err := h.computeReplicas(rd, hra)
+			if tc.includeInProgress != nil {
+				hra.Spec.Metrics = []v1alpha1.MetricSpec{
+					{
+						Type:              v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns,
+						IncludeInProgress: tc.includeInProgress,
+						ReplicasPerRun:    tc.replicasPerRun,
+					},
+				}
+			}
+
+			got, _, err := h.computeReplicas(context.Background(), rd, hra)
 			if err != nil {
 				if tc.err == "" {
 					t.Fatalf("unexpected error: expected none, got %v", err)
@@ -246,10 +446,6 @@ func TestDetermineDesiredReplicas_RepositoryRunner(t *testing.T) {
 }
 
 func TestDetermineDesiredReplicas_OrganizationalRunner(t *testing.T) {
-	intPtr := func(v int) *int {
-		return &v
-	}
-
 	metav1Now := metav1.Now()
 	testcases := []struct {
 		repos     []string
@@ -475,7 +671,7 @@ func TestDetermineDesiredReplicas_OrganizationalRunner(t *testing.T) {
 				},
 			}
 
-			got, err := h.computeReplicas(rd, hra)
+			got, _, err := h.computeReplicas(context.Background(), rd, hra)
 			if err != nil {
 				if tc.err == "" {
 					t.Fatalf("unexpected error: expected none, got %v", err)
@@ -495,3 +691,2851 @@ func TestDetermineDesiredReplicas_OrganizationalRunner(t *testing.T) {
 		})
 	}
 }
+
+func TestDetermineDesiredReplicas_MultipleRepositories(t *testing.T) {
+	testcases := []struct {
+		repos []string
+
+		want         int
+		wantObserved string
+		err          string
+	}{
+		// The repositories share the runner pool, so their workflow runs are summed up
+		{
+			repos:        []string{"valid", "valid", "valid"},
+			want:         9,
+			wantObserved: "3 queued and 6 in-progress workflow runs and jobs",
+		},
+		// Scaled by the repositories that succeeded
+		{
+			repos:        []string{"valid", "missing", "valid"},
+			want:         6,
+			wantObserved: "2 queued and 4 in-progress workflow runs and jobs in 2 of 3 repositories",
+		},
+		{
+			repos: []string{"missing", "missing2"},
+			err:   "listing workflow runs of all the 2 repositories: listing queued workflow runs: failed to list workflow runs: GET ",
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		log := zap.New(func(o *zap.Options) {
+			o.Development = true
+		})
+
+		scheme := runtime.NewScheme()
+		_ = clientgoscheme.AddToScheme(scheme)
+		_ = v1alpha1.AddToScheme(scheme)
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			server := fake.NewServer(
+				fake.WithListRepositoryWorkflowRunsResponse(200,
+					`{"total_count": 3, "workflow_runs":[{"status":"queued"}, {"status":"in_progress"}, {"status":"in_progress"}]}"`,
+					`{"total_count": 1, "workflow_runs":[{"status":"queued"}]}"`,
+					`{"total_count": 2, "workflow_runs":[{"status":"in_progress"}, {"status":"in_progress"}]}"`,
+				),
+				fake.WithListWorkflowJobsResponse(200, nil),
+				fake.WithListRunnersResponse(200, fake.RunnersListBody),
+			)
+			defer server.Close()
+			client := newGithubClient(server)
+
+			h := &HorizontalRunnerAutoscalerReconciler{
+				Log:          log,
+				Scheme:       scheme,
+				GitHubClient: client,
+			}
+
+			rd := v1alpha1.RunnerDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "testrd",
+				},
+				Spec: v1alpha1.RunnerDeploymentSpec{
+					Template: v1alpha1.RunnerTemplate{
+						Spec: v1alpha1.RunnerSpec{
+							Organization: "test",
+						},
+					},
+				},
+			}
+
+			hra := v1alpha1.HorizontalRunnerAutoscaler{
+				Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+					ScaleTargetRef: v1alpha1.ScaleTargetRef{
+						Name: "testrd",
+					},
+					MinReplicas: intPtr(1),
+					MaxReplicas: intPtr(10),
+					Metrics: []v1alpha1.MetricSpec{
+						{
+							Type:            v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns,
+							RepositoryNames: tc.repos,
+						},
+					},
+				},
+			}
+
+			got, metric, err := h.computeReplicas(context.Background(), rd, hra)
+			if err != nil {
+				if tc.err == "" {
+					t.Fatalf("unexpected error: expected none, got %v", err)
+				} else if !strings.HasPrefix(err.Error(), tc.err) {
+					t.Fatalf("unexpected error: expected %v, got %v", tc.err, err)
+				}
+				return
+			}
+
+			if tc.err != "" {
+				t.Fatalf("expected error %q, got none", tc.err)
+			}
+
+			if *got != tc.want {
+				t.Errorf("incorrect desired replicas: want %d, got %d", tc.want, *got)
+			}
+
+			if metric.ObservedValue != tc.wantObserved {
+				t.Errorf("unexpected observed value: want %q, got %q", tc.wantObserved, metric.ObservedValue)
+			}
+		})
+	}
+}
+
+func TestDetermineDesiredReplicas_PercentageRunnersBusy(t *testing.T) {
+	runnersListBody := func(busy ...bool) string {
+		var runners []string
+		for i, b := range busy {
+			runners = append(runners, fmt.Sprintf(`{"id": %d, "name": "test%d", "os": "linux", "status": "online", "busy": %v}`, i+1, i+1, b))
+		}
+		return fmt.Sprintf(`{"total_count": %d, "runners": [%s]}`, len(busy), strings.Join(runners, ","))
+	}
+
+	testcases := []struct {
+		fixed   *int
+		max     *int
+		min     *int
+		metric  v1alpha1.MetricSpec
+		runners []bool
+
+		// startingUp is the number of the last runners created just now
+		startingUp   int
+		startupGrace *int
+
+		want int
+		err  string
+	}{
+		// 2 of 2 busy, scale up by the default factor
+		{
+			min:     intPtr(1),
+			max:     intPtr(10),
+			fixed:   intPtr(2),
+			runners: []bool{true, true},
+			want:    3,
+		},
+		// 1 of 4 busy, scale down by the default factor
+		{
+			min:     intPtr(1),
+			max:     intPtr(10),
+			fixed:   intPtr(4),
+			runners: []bool{true, false, false, false},
+			want:    2,
+		},
+		// 1 of 2 busy, stays within the thresholds
+		{
+			min:     intPtr(1),
+			max:     intPtr(10),
+			fixed:   intPtr(2),
+			runners: []bool{true, false},
+			want:    2,
+		},
+		// 1 of 2 busy, stays within the thresholds even when the current replicas is nil
+		{
+			min:     intPtr(1),
+			max:     intPtr(10),
+			runners: []bool{true, false},
+			want:    2,
+		},
+		// 1 of 4 busy, but 2 of the idle runners have just started, so 1 of 2 busy stays within the thresholds
+		{
+			min:        intPtr(1),
+			max:        intPtr(10),
+			fixed:      intPtr(4),
+			runners:    []bool{true, false, false, false},
+			startingUp: 2,
+			want:       4,
+		},
+		// The startup grace is disabled
+		{
+			min:          intPtr(1),
+			max:          intPtr(10),
+			fixed:        intPtr(4),
+			runners:      []bool{true, false, false, false},
+			startingUp:   2,
+			startupGrace: intPtr(0),
+			want:         2,
+		},
+		// All the runners have just started, and are kept as is
+		{
+			min:        intPtr(1),
+			max:        intPtr(10),
+			fixed:      intPtr(3),
+			runners:    []bool{false, false, false},
+			startingUp: 3,
+			want:       3,
+		},
+		// No runners at all, falls back to min
+		{
+			min:  intPtr(2),
+			max:  intPtr(10),
+			want: 2,
+		},
+		// 2 of 2 busy, scale up by adjustment, capped by max
+		{
+			min:     intPtr(1),
+			max:     intPtr(3),
+			fixed:   intPtr(2),
+			metric:  v1alpha1.MetricSpec{ScaleUpAdjustment: 5},
+			runners: []bool{true, true},
+			want:    3,
+		},
+		// Inverted thresholds
+		{
+			min:     intPtr(1),
+			max:     intPtr(3),
+			metric:  v1alpha1.MetricSpec{ScaleUpThreshold: "0.3", ScaleDownThreshold: "0.8"},
+			runners: []bool{true, true},
+			err:     "validating autoscaling metrics: spec.autoscaling.metrics[].scaleDownThreshold (0.8) cannot be greater than scaleUpThreshold (0.3)",
+		},
+		// 7 of 10 busy, exactly at the target utilization
+		{
+			min:     intPtr(1),
+			max:     intPtr(20),
+			fixed:   intPtr(10),
+			metric:  v1alpha1.MetricSpec{TargetUtilizationPercent: intPtr(70)},
+			runners: []bool{true, true, true, true, true, true, true, false, false, false},
+			want:    10,
+		},
+		// 8 of 10 busy, 11.4 runners for the target utilization rounded up
+		{
+			min:     intPtr(1),
+			max:     intPtr(20),
+			fixed:   intPtr(10),
+			metric:  v1alpha1.MetricSpec{TargetUtilizationPercent: intPtr(70)},
+			runners: []bool{true, true, true, true, true, true, true, true, false, false},
+			want:    12,
+		},
+		// 1 of 4 busy, scale down to the target utilization
+		{
+			min:     intPtr(1),
+			max:     intPtr(10),
+			fixed:   intPtr(4),
+			metric:  v1alpha1.MetricSpec{TargetUtilizationPercent: intPtr(50)},
+			runners: []bool{true, false, false, false},
+			want:    2,
+		},
+		// 1 of 4 busy, but 2 of the idle runners have just started and are kept as is
+		{
+			min:        intPtr(1),
+			max:        intPtr(10),
+			fixed:      intPtr(4),
+			metric:     v1alpha1.MetricSpec{TargetUtilizationPercent: intPtr(50)},
+			runners:    []bool{true, false, false, false},
+			startingUp: 2,
+			want:       3,
+		},
+		// No busy runners, falls back to min
+		{
+			min:     intPtr(2),
+			max:     intPtr(10),
+			fixed:   intPtr(3),
+			metric:  v1alpha1.MetricSpec{TargetUtilizationPercent: intPtr(70)},
+			runners: []bool{false, false, false},
+			want:    2,
+		},
+		// Out of range target utilization
+		{
+			min:     intPtr(1),
+			max:     intPtr(10),
+			metric:  v1alpha1.MetricSpec{TargetUtilizationPercent: intPtr(0)},
+			runners: []bool{true, true},
+			err:     "validating autoscaling metrics: spec.autoscaling.metrics[].targetUtilizationPercent must be between 1 and 100, but got 0",
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		log := zap.New(func(o *zap.Options) {
+			o.Development = true
+		})
+
+		scheme := runtime.NewScheme()
+		_ = clientgoscheme.AddToScheme(scheme)
+		_ = v1alpha1.AddToScheme(scheme)
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			server := fake.NewServer(
+				fake.WithListRunnersResponse(200, runnersListBody(tc.runners...)),
+			)
+			defer server.Close()
+			client := newGithubClient(server)
+
+			var runners []runtime.Object
+			for i := range tc.runners {
+				runner := &v1alpha1.Runner{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      fmt.Sprintf("test%d", i+1),
+						Namespace: "default",
+					},
+				}
+
+				if i >= len(tc.runners)-tc.startingUp {
+					runner.CreationTimestamp = metav1.Now()
+				}
+
+				runners = append(runners, runner)
+			}
+
+			h := &HorizontalRunnerAutoscalerReconciler{
+				Client:       clientfake.NewFakeClientWithScheme(scheme, runners...),
+				Log:          log,
+				GitHubClient: client,
+				Scheme:       scheme,
+			}
+
+			rd := v1alpha1.RunnerDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "testrd",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.RunnerDeploymentSpec{
+					Template: v1alpha1.RunnerTemplate{
+						Spec: v1alpha1.RunnerSpec{
+							Repository: "test/valid",
+						},
+					},
+					Replicas: tc.fixed,
+				},
+			}
+
+			metric := tc.metric
+			metric.Type = v1alpha1.AutoscalingMetricTypePercentageRunnersBusy
+
+			hra := v1alpha1.HorizontalRunnerAutoscaler{
+				Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+					MaxReplicas:               tc.max,
+					MinReplicas:               tc.min,
+					Metrics:                   []v1alpha1.MetricSpec{metric},
+					RunnerStartupGraceSeconds: tc.startupGrace,
+				},
+			}
+
+			got, _, err := h.computeReplicas(context.Background(), rd, hra)
+			if err != nil {
+				if tc.err == "" {
+					t.Fatalf("unexpected error: expected none, got %v", err)
+				} else if err.Error() != tc.err {
+					t.Fatalf("unexpected error: expected %v, got %v", tc.err, err)
+				}
+				return
+			}
+
+			if tc.err != "" {
+				t.Fatalf("expected error %q, got none", tc.err)
+			}
+
+			if got == nil {
+				t.Fatalf("unexpected value of rs.Spec.Replicas: nil")
+			}
+
+			if *got != tc.want {
+				t.Errorf("%d: incorrect desired replicas: want %d, got %d", i, tc.want, *got)
+			}
+		})
+	}
+}
+
+func TestDetermineDesiredReplicas_PercentageRunnerGroupBusy(t *testing.T) {
+	runnersListBody := func(statuses ...string) string {
+		var runners []string
+		for i, s := range statuses {
+			runners = append(runners, fmt.Sprintf(`{"id": %d, "name": "group%d", "os": "linux", "status": "%s", "busy": %v}`, i+1, i+1, strings.TrimSuffix(s, "/busy"), strings.HasSuffix(s, "/busy")))
+		}
+		return fmt.Sprintf(`{"total_count": %d, "runners": [%s]}`, len(statuses), strings.Join(runners, ","))
+	}
+
+	testcases := []struct {
+		fixed      *int
+		max        *int
+		min        *int
+		enterprise string
+		org        string
+		repo       string
+		group      string
+		metric     v1alpha1.MetricSpec
+
+		// runners is the status of each runner in the group, suffixed by "/busy" when it's busy
+		runners []string
+
+		want int
+		err  string
+	}{
+		// 2 of 2 busy, scale up by the default factor
+		{
+			org:     "test",
+			group:   "test-group",
+			min:     intPtr(1),
+			max:     intPtr(10),
+			fixed:   intPtr(2),
+			runners: []string{"online/busy", "online/busy"},
+			want:    3,
+		},
+		// 1 of 4 busy, scale down by the default factor
+		{
+			org:     "test",
+			group:   "test-group",
+			min:     intPtr(1),
+			max:     intPtr(10),
+			fixed:   intPtr(4),
+			runners: []string{"online/busy", "online", "online", "online"},
+			want:    2,
+		},
+		// 1 of 2 busy as the offline runner isn't counted, stays within the thresholds
+		{
+			org:     "test",
+			group:   "test-group",
+			min:     intPtr(1),
+			max:     intPtr(10),
+			fixed:   intPtr(2),
+			runners: []string{"online/busy", "online", "offline"},
+			want:    2,
+		},
+		// The enterprise runner group, 2 of 2 busy, scale up by adjustment capped by max
+		{
+			enterprise: "test",
+			group:      "test-group",
+			min:        intPtr(1),
+			max:        intPtr(3),
+			fixed:      intPtr(2),
+			metric:     v1alpha1.MetricSpec{ScaleUpAdjustment: 5},
+			runners:    []string{"online/busy", "online/busy"},
+			want:       3,
+		},
+		// The runner group has no runners, falls back to min
+		{
+			org:   "test",
+			group: "Default",
+			min:   intPtr(2),
+			max:   intPtr(10),
+			fixed: intPtr(5),
+			want:  2,
+		},
+		// The runner group has only offline runners, falls back to min
+		{
+			org:     "test",
+			group:   "test-group",
+			min:     intPtr(0),
+			max:     intPtr(10),
+			fixed:   intPtr(3),
+			runners: []string{"offline", "offline"},
+			want:    0,
+		},
+		// The runner group doesn't exist
+		{
+			org:   "test",
+			group: "missing",
+			min:   intPtr(1),
+			max:   intPtr(10),
+			err:   `runner group "missing" not found`,
+		},
+		// The runner group is missing
+		{
+			org: "test",
+			min: intPtr(1),
+			max: intPtr(10),
+			err: "validating autoscaling metrics: spec.autoscaling.metrics[].runnerGroup must be set for PercentageRunnerGroupBusy",
+		},
+		// Repository runners can't be in runner groups
+		{
+			repo:  "test/valid",
+			group: "test-group",
+			min:   intPtr(1),
+			max:   intPtr(10),
+			err:   "validating autoscaling metrics: PercentageRunnerGroupBusy requires an organization or enterprise runnerdeployment, but default/testrd is for repository test/valid",
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		log := zap.New(func(o *zap.Options) {
+			o.Development = true
+		})
+
+		scheme := runtime.NewScheme()
+		_ = clientgoscheme.AddToScheme(scheme)
+		_ = v1alpha1.AddToScheme(scheme)
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			server := fake.NewServer(
+				fake.WithListRunnersResponse(200, fake.RunnersListBody),
+				fake.WithListRunnerGroupRunnersResponse(200, runnersListBody(tc.runners...)),
+			)
+			defer server.Close()
+			client := newGithubClient(server)
+
+			h := &HorizontalRunnerAutoscalerReconciler{
+				Log:          log,
+				GitHubClient: client,
+				Scheme:       scheme,
+			}
+
+			rd := v1alpha1.RunnerDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "testrd",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.RunnerDeploymentSpec{
+					Template: v1alpha1.RunnerTemplate{
+						Spec: v1alpha1.RunnerSpec{
+							Enterprise:   tc.enterprise,
+							Organization: tc.org,
+							Repository:   tc.repo,
+						},
+					},
+					Replicas: tc.fixed,
+				},
+			}
+
+			metric := tc.metric
+			metric.Type = v1alpha1.AutoscalingMetricTypePercentageRunnerGroupBusy
+			metric.RunnerGroup = tc.group
+
+			hra := v1alpha1.HorizontalRunnerAutoscaler{
+				Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+					MaxReplicas: tc.max,
+					MinReplicas: tc.min,
+					Metrics:     []v1alpha1.MetricSpec{metric},
+				},
+			}
+
+			got, _, err := h.computeReplicas(context.Background(), rd, hra)
+			if err != nil {
+				if tc.err == "" {
+					t.Fatalf("unexpected error: expected none, got %v", err)
+				} else if err.Error() != tc.err {
+					t.Fatalf("unexpected error: expected %v, got %v", tc.err, err)
+				}
+				return
+			}
+
+			if tc.err != "" {
+				t.Fatalf("expected error %q, got none", tc.err)
+			}
+
+			if got == nil {
+				t.Fatalf("unexpected value of rs.Spec.Replicas: nil")
+			}
+
+			if *got != tc.want {
+				t.Errorf("%d: incorrect desired replicas: want %d, got %d", i, tc.want, *got)
+			}
+		})
+	}
+}
+
+func TestDetermineDesiredReplicas_MultipleMetrics(t *testing.T) {
+	runnersListBody := func(busy ...bool) string {
+		var runners []string
+		for i, b := range busy {
+			runners = append(runners, fmt.Sprintf(`{"id": %d, "name": "test%d", "os": "linux", "status": "online", "busy": %v}`, i+1, i+1, b))
+		}
+		return fmt.Sprintf(`{"total_count": %d, "runners": [%s]}`, len(busy), strings.Join(runners, ","))
+	}
+
+	queued := v1alpha1.MetricSpec{Type: v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns}
+	busy := v1alpha1.MetricSpec{Type: v1alpha1.AutoscalingMetricTypePercentageRunnersBusy}
+	invalidBusy := v1alpha1.MetricSpec{Type: v1alpha1.AutoscalingMetricTypePercentageRunnersBusy, ScaleUpThreshold: "0.3", ScaleDownThreshold: "0.8"}
+	unsupported := v1alpha1.MetricSpec{Type: "Unsupported"}
+
+	testcases := []struct {
+		fixed   *int
+		metrics []v1alpha1.MetricSpec
+		runners []bool
+
+		want       int
+		wantMetric string
+		err        string
+	}{
+		// 3 demanded by workflow runs, 2 by busy runners
+		{
+			fixed:      intPtr(2),
+			metrics:    []v1alpha1.MetricSpec{busy, queued},
+			runners:    []bool{true, false},
+			want:       3,
+			wantMetric: v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns,
+		},
+		// 3 demanded by workflow runs, 6 by busy runners
+		{
+			fixed:      intPtr(4),
+			metrics:    []v1alpha1.MetricSpec{queued, busy},
+			runners:    []bool{true, true, true, true},
+			want:       6,
+			wantMetric: v1alpha1.AutoscalingMetricTypePercentageRunnersBusy,
+		},
+		// 3 demanded by both, the one defined earlier wins
+		{
+			fixed:      intPtr(2),
+			metrics:    []v1alpha1.MetricSpec{busy, queued},
+			runners:    []bool{true, true},
+			want:       3,
+			wantMetric: v1alpha1.AutoscalingMetricTypePercentageRunnersBusy,
+		},
+		// The failed metric is ignored as long as another one succeeded
+		{
+			fixed:      intPtr(4),
+			metrics:    []v1alpha1.MetricSpec{invalidBusy, queued},
+			runners:    []bool{true, true, true, true},
+			want:       3,
+			wantMetric: v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns,
+		},
+		// All the metrics failed
+		{
+			fixed:   intPtr(2),
+			metrics: []v1alpha1.MetricSpec{unsupported, invalidBusy},
+			runners: []bool{true, true},
+			err:     `all the metrics failed: metrics[0]: validting autoscaling metrics: unsupported metric type "Unsupported"; metrics[1]: validating autoscaling metrics: spec.autoscaling.metrics[].scaleDownThreshold (0.8) cannot be greater than scaleUpThreshold (0.3)`,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		log := zap.New(func(o *zap.Options) {
+			o.Development = true
+		})
+
+		scheme := runtime.NewScheme()
+		_ = clientgoscheme.AddToScheme(scheme)
+		_ = v1alpha1.AddToScheme(scheme)
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			server := fake.NewServer(
+				fake.WithListRepositoryWorkflowRunsResponse(200,
+					`{"total_count": 4, "workflow_runs":[{"status":"queued"}, {"status":"in_progress"}, {"status":"in_progress"}, {"status":"completed"}]}"`,
+					`{"total_count": 1, "workflow_runs":[{"status":"queued"}]}"`,
+					`{"total_count": 2, "workflow_runs":[{"status":"in_progress"}, {"status":"in_progress"}]}"`,
+				),
+				fake.WithListWorkflowJobsResponse(200, nil),
+				fake.WithListRunnersResponse(200, runnersListBody(tc.runners...)),
+			)
+			defer server.Close()
+			client := newGithubClient(server)
+
+			var runners []runtime.Object
+			for i := range tc.runners {
+				runners = append(runners, &v1alpha1.Runner{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      fmt.Sprintf("test%d", i+1),
+						Namespace: "default",
+					},
+				})
+			}
+
+			h := &HorizontalRunnerAutoscalerReconciler{
+				Client:       clientfake.NewFakeClientWithScheme(scheme, runners...),
+				Log:          log,
+				GitHubClient: client,
+				Scheme:       scheme,
+			}
+
+			rd := v1alpha1.RunnerDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "testrd",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.RunnerDeploymentSpec{
+					Template: v1alpha1.RunnerTemplate{
+						Spec: v1alpha1.RunnerSpec{
+							Repository: "test/valid",
+						},
+					},
+					Replicas: tc.fixed,
+				},
+			}
+
+			hra := v1alpha1.HorizontalRunnerAutoscaler{
+				Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+					MaxReplicas: intPtr(10),
+					MinReplicas: intPtr(1),
+					Metrics:     tc.metrics,
+				},
+			}
+
+			got, metric, err := h.computeReplicas(context.Background(), rd, hra)
+			if err != nil {
+				if tc.err == "" {
+					t.Fatalf("unexpected error: expected none, got %v", err)
+				} else if err.Error() != tc.err {
+					t.Fatalf("unexpected error: expected %v, got %v", tc.err, err)
+				}
+				return
+			}
+
+			if tc.err != "" {
+				t.Fatalf("expected error %q, got none", tc.err)
+			}
+
+			if *got != tc.want {
+				t.Errorf("%d: incorrect desired replicas: want %d, got %d", i, tc.want, *got)
+			}
+
+			if metric.Type != tc.wantMetric {
+				t.Errorf("%d: incorrect winning metric: want %s, got %s", i, tc.wantMetric, metric.Type)
+			}
+		})
+	}
+}
+
+func TestDetermineDesiredReplicas_HistoricalDesiredReplicas(t *testing.T) {
+	queued := v1alpha1.MetricSpec{Type: v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns}
+	history := v1alpha1.MetricSpec{Type: v1alpha1.AutoscalingMetricTypeHistoricalDesiredReplicas, LookbackDays: 2, BucketSeconds: 3600}
+
+	now := time.Now()
+
+	// daysAgo returns the history entry of the bucket at the same time of day d days ago
+	daysAgo := func(d, replicas int) v1alpha1.ScaleHistoryEntry {
+		return v1alpha1.ScaleHistoryEntry{
+			Time:     metav1.Time{Time: now.Add(-time.Duration(d) * 24 * time.Hour).Truncate(time.Hour)},
+			Replicas: replicas,
+		}
+	}
+
+	testcases := []struct {
+		metrics []v1alpha1.MetricSpec
+		history []v1alpha1.ScaleHistoryEntry
+
+		want         int
+		wantMetric   string
+		wantReactive int
+	}{
+		// No history yet
+		{
+			metrics:      []v1alpha1.MetricSpec{queued, history},
+			want:         3,
+			wantMetric:   v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns,
+			wantReactive: 3,
+		},
+		// The history doesn't cover the lookback window yet
+		{
+			metrics:      []v1alpha1.MetricSpec{queued, history},
+			history:      []v1alpha1.ScaleHistoryEntry{daysAgo(1, 8)},
+			want:         3,
+			wantMetric:   v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns,
+			wantReactive: 3,
+		},
+		// 7 anticipated on average from 8 and 6 at the same time of day
+		{
+			metrics:      []v1alpha1.MetricSpec{queued, history},
+			history:      []v1alpha1.ScaleHistoryEntry{daysAgo(2, 6), daysAgo(1, 8)},
+			want:         7,
+			wantMetric:   v1alpha1.AutoscalingMetricTypeHistoricalDesiredReplicas,
+			wantReactive: 3,
+		},
+		// The reactive metric wins when the history anticipates less
+		{
+			metrics:      []v1alpha1.MetricSpec{history, queued},
+			history:      []v1alpha1.ScaleHistoryEntry{daysAgo(2, 1), daysAgo(1, 2)},
+			want:         3,
+			wantMetric:   v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns,
+			wantReactive: 3,
+		},
+		// The anticipated replicas are capped by maxReplicas
+		{
+			metrics:      []v1alpha1.MetricSpec{queued, history},
+			history:      []v1alpha1.ScaleHistoryEntry{daysAgo(2, 20), daysAgo(1, 20)},
+			want:         10,
+			wantMetric:   v1alpha1.AutoscalingMetricTypeHistoricalDesiredReplicas,
+			wantReactive: 3,
+		},
+		// The default metric is used as the reactive one when there's no other metric
+		{
+			metrics:      []v1alpha1.MetricSpec{history},
+			history:      []v1alpha1.ScaleHistoryEntry{daysAgo(2, 6), daysAgo(1, 8)},
+			want:         7,
+			wantMetric:   v1alpha1.AutoscalingMetricTypeHistoricalDesiredReplicas,
+			wantReactive: 3,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		log := zap.New(func(o *zap.Options) {
+			o.Development = true
+		})
+
+		scheme := runtime.NewScheme()
+		_ = clientgoscheme.AddToScheme(scheme)
+		_ = v1alpha1.AddToScheme(scheme)
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			server := fake.NewServer(
+				fake.WithListRepositoryWorkflowRunsResponse(200,
+					`{"total_count": 4, "workflow_runs":[{"status":"queued"}, {"status":"in_progress"}, {"status":"in_progress"}, {"status":"completed"}]}"`,
+					`{"total_count": 1, "workflow_runs":[{"status":"queued"}]}"`,
+					`{"total_count": 2, "workflow_runs":[{"status":"in_progress"}, {"status":"in_progress"}]}"`,
+				),
+				fake.WithListWorkflowJobsResponse(200, nil),
+				fake.WithListRunnersResponse(200, fake.RunnersListBody),
+			)
+			defer server.Close()
+			client := newGithubClient(server)
+
+			h := &HorizontalRunnerAutoscalerReconciler{
+				Log:          log,
+				GitHubClient: client,
+				Scheme:       scheme,
+			}
+
+			rd := v1alpha1.RunnerDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "testrd",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.RunnerDeploymentSpec{
+					Template: v1alpha1.RunnerTemplate{
+						Spec: v1alpha1.RunnerSpec{
+							Repository: "test/valid",
+						},
+					},
+				},
+			}
+
+			hra := v1alpha1.HorizontalRunnerAutoscaler{
+				Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+					MaxReplicas: intPtr(10),
+					MinReplicas: intPtr(1),
+					Metrics:     tc.metrics,
+				},
+				Status: v1alpha1.HorizontalRunnerAutoscalerStatus{
+					ScaleHistory: tc.history,
+				},
+			}
+
+			got, err := h.determineDesiredReplicas(context.Background(), rd, hra)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got.Replicas != tc.want {
+				t.Errorf("%d: incorrect desired replicas: want %d, got %d", i, tc.want, got.Replicas)
+			}
+
+			if got.Type != tc.wantMetric {
+				t.Errorf("%d: incorrect winning metric: want %s, got %s", i, tc.wantMetric, got.Type)
+			}
+
+			if got.ReactiveReplicas != tc.wantReactive {
+				t.Errorf("%d: incorrect reactive replicas: want %d, got %d", i, tc.wantReactive, got.ReactiveReplicas)
+			}
+		})
+	}
+}
+
+func TestRecordScaleHistory(t *testing.T) {
+	start := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	at := func(d time.Duration, replicas int) v1alpha1.ScaleHistoryEntry {
+		return v1alpha1.ScaleHistoryEntry{Time: metav1.Time{Time: start.Add(d)}, Replicas: replicas}
+	}
+
+	testcases := []struct {
+		history  []v1alpha1.ScaleHistoryEntry
+		now      time.Time
+		replicas int
+		want     []v1alpha1.ScaleHistoryEntry
+	}{
+		// The first entry
+		{
+			now:      start.Add(10 * time.Minute),
+			replicas: 2,
+			want:     []v1alpha1.ScaleHistoryEntry{at(0, 2)},
+		},
+		// The bucket keeps the largest replicas
+		{
+			history:  []v1alpha1.ScaleHistoryEntry{at(0, 3)},
+			now:      start.Add(50 * time.Minute),
+			replicas: 2,
+			want:     []v1alpha1.ScaleHistoryEntry{at(0, 3)},
+		},
+		{
+			history:  []v1alpha1.ScaleHistoryEntry{at(0, 3)},
+			now:      start.Add(50 * time.Minute),
+			replicas: 4,
+			want:     []v1alpha1.ScaleHistoryEntry{at(0, 4)},
+		},
+		// A new bucket
+		{
+			history:  []v1alpha1.ScaleHistoryEntry{at(0, 3)},
+			now:      start.Add(70 * time.Minute),
+			replicas: 1,
+			want:     []v1alpha1.ScaleHistoryEntry{at(0, 3), at(time.Hour, 1)},
+		},
+		// The buckets older than the lookback window are dropped
+		{
+			history:  []v1alpha1.ScaleHistoryEntry{at(0, 3), at(time.Hour, 1), at(2*time.Hour, 2)},
+			now:      start.Add(26*time.Hour + 10*time.Minute),
+			replicas: 5,
+			want:     []v1alpha1.ScaleHistoryEntry{at(time.Hour, 1), at(2*time.Hour, 2), at(26*time.Hour, 5)},
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			got := recordScaleHistory(tc.history, tc.now, tc.replicas, 24*time.Hour, time.Hour)
+
+			if len(got) != len(tc.want) {
+				t.Fatalf("unexpected number of entries: want %v, got %v", tc.want, got)
+			}
+
+			for j := range got {
+				if !got[j].Time.Equal(&tc.want[j].Time) || got[j].Replicas != tc.want[j].Replicas {
+					t.Errorf("unexpected entry at %d: want %v, got %v", j, tc.want[j], got[j])
+				}
+			}
+		})
+	}
+}
+
+func TestGetScaleHistoryLookbackAndBucket(t *testing.T) {
+	testcases := []struct {
+		metric       v1alpha1.MetricSpec
+		wantLookback time.Duration
+		wantBucket   time.Duration
+	}{
+		{
+			wantLookback: 7 * 24 * time.Hour,
+			wantBucket:   time.Hour,
+		},
+		{
+			metric:       v1alpha1.MetricSpec{LookbackDays: 1, BucketSeconds: 300},
+			wantLookback: 24 * time.Hour,
+			wantBucket:   5 * time.Minute,
+		},
+		// The buckets are made longer so that the history never exceeds the max entries
+		{
+			metric:       v1alpha1.MetricSpec{LookbackDays: 60, BucketSeconds: 60},
+			wantLookback: 60 * 24 * time.Hour,
+			wantBucket:   5068 * time.Second,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			lookback, bucket := getScaleHistoryLookbackAndBucket(tc.metric)
+
+			if lookback != tc.wantLookback {
+				t.Errorf("unexpected lookback: want %s, got %s", tc.wantLookback, lookback)
+			}
+
+			if bucket != tc.wantBucket {
+				t.Errorf("unexpected bucket: want %s, got %s", tc.wantBucket, bucket)
+			}
+
+			if n := int(lookback/bucket) + 2; n > v1alpha1.MaxScaleHistoryEntries {
+				t.Errorf("unexpected max number of entries: %d", n)
+			}
+		})
+	}
+}
+
+func TestDetermineDesiredReplicas_EnterpriseRunner(t *testing.T) {
+	testcases := []struct {
+		enterprise string
+		org        string
+		repos      []string
+		metricType string
+		want       int
+		err        string
+	}{
+		// 3 demanded, counted in the specified repositories
+		{
+			enterprise: "test",
+			repos:      []string{"test/valid"},
+			want:       3,
+		},
+		// Runners are counted via the enterprise endpoint
+		{
+			enterprise: "test",
+			metricType: v1alpha1.AutoscalingMetricTypePercentageRunnersBusy,
+			want:       1,
+		},
+		{
+			enterprise: "test",
+			err:        `validating autoscaling metrics: spec.autoscaling.metrics[].repositoryNames is required and must have one more more entries in the form of OWNER/REPO for enterprise runner deployment of "test"`,
+		},
+		{
+			enterprise: "test",
+			repos:      []string{"valid"},
+			err:        `validating autoscaling metrics: spec.autoscaling.metrics[].repositoryNames must be in the form of OWNER/REPO for enterprise runner deployment, but got "valid"`,
+		},
+		{
+			enterprise: "test",
+			org:        "test",
+			repos:      []string{"test/valid"},
+			err:        "validating scale target: exactly one of spec.template.spec.enterprise, organization, and repository must be set for runnerdeployment default/testrd, but got 2: enterprise, organization",
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		log := zap.New(func(o *zap.Options) {
+			o.Development = true
+		})
+
+		scheme := runtime.NewScheme()
+		_ = clientgoscheme.AddToScheme(scheme)
+		_ = v1alpha1.AddToScheme(scheme)
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			server := fake.NewServer(
+				fake.WithListRepositoryWorkflowRunsResponse(200,
+					`{"total_count": 4, "workflow_runs":[{"status":"queued"}, {"status":"in_progress"}, {"status":"in_progress"}, {"status":"completed"}]}"`,
+					`{"total_count": 1, "workflow_runs":[{"status":"queued"}]}"`,
+					`{"total_count": 2, "workflow_runs":[{"status":"in_progress"}, {"status":"in_progress"}]}"`,
+				),
+				fake.WithListWorkflowJobsResponse(200, nil),
+				fake.WithListRunnersResponse(200, fake.RunnersListBody),
+			)
+			defer server.Close()
+			client := newGithubClient(server)
+
+			h := &HorizontalRunnerAutoscalerReconciler{
+				Client:       clientfake.NewFakeClientWithScheme(scheme),
+				Log:          log,
+				GitHubClient: client,
+				Scheme:       scheme,
+			}
+
+			rd := v1alpha1.RunnerDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "testrd",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.RunnerDeploymentSpec{
+					Template: v1alpha1.RunnerTemplate{
+						Spec: v1alpha1.RunnerSpec{
+							Enterprise:   tc.enterprise,
+							Organization: tc.org,
+						},
+					},
+				},
+			}
+
+			hra := v1alpha1.HorizontalRunnerAutoscaler{
+				Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+					MaxReplicas: intPtr(10),
+					MinReplicas: intPtr(1),
+					Metrics: []v1alpha1.MetricSpec{
+						{
+							Type:            tc.metricType,
+							RepositoryNames: tc.repos,
+						},
+					},
+				},
+			}
+
+			got, _, err := h.computeReplicas(context.Background(), rd, hra)
+			if err != nil {
+				if tc.err == "" {
+					t.Fatalf("unexpected error: expected none, got %v", err)
+				} else if err.Error() != tc.err {
+					t.Fatalf("unexpected error: expected %v, got %v", tc.err, err)
+				}
+				return
+			}
+
+			if tc.err != "" {
+				t.Fatalf("expected error %q, got none", tc.err)
+			}
+
+			if *got != tc.want {
+				t.Errorf("%d: incorrect desired replicas: want %d, got %d", i, tc.want, *got)
+			}
+		})
+	}
+}
+
+func TestGetGitHubClient(t *testing.T) {
+	defaultClient := &github.Client{}
+
+	testcases := []struct {
+		ref  *v1alpha1.GitHubAppInstallationRef
+		want *github.Client
+		err  string
+	}{
+		{
+			want: defaultClient,
+		},
+		{
+			ref: &v1alpha1.GitHubAppInstallationRef{ID: 1, Organization: "test"},
+			err: "validating githubAppInstallation: exactly one of id and organization must be set for horizontalrunnerautoscaler default/testhra",
+		},
+		{
+			ref: &v1alpha1.GitHubAppInstallationRef{},
+			err: "validating githubAppInstallation: exactly one of id and organization must be set for horizontalrunnerautoscaler default/testhra",
+		},
+		{
+			ref: &v1alpha1.GitHubAppInstallationRef{ID: 1},
+			err: "horizontalrunnerautoscaler default/testhra specifies githubAppInstallation, but the controller is not authenticated as a github app",
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			h := &HorizontalRunnerAutoscalerReconciler{
+				GitHubClient: defaultClient,
+			}
+
+			hra := v1alpha1.HorizontalRunnerAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "testhra",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+					GitHubAppInstallation: tc.ref,
+				},
+			}
+
+			got, err := h.getGitHubClient(context.Background(), hra)
+			if err != nil {
+				if tc.err == "" {
+					t.Fatalf("unexpected error: %v", err)
+				} else if err.Error() != tc.err {
+					t.Fatalf("unexpected error: want %q, got %q", tc.err, err.Error())
+				}
+				return
+			}
+
+			if tc.err != "" {
+				t.Fatalf("expected error %q, got none", tc.err)
+			}
+
+			if got != tc.want {
+				t.Errorf("unexpected client")
+			}
+		})
+	}
+}
+
+func TestGetGitHubClientFromCredentials(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = v1alpha1.AddToScheme(scheme)
+
+	newSecret := func(name string, data map[string]string) *corev1.Secret {
+		s := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+			},
+			Data: map[string][]byte{},
+		}
+
+		for k, v := range data {
+			s.Data[k] = []byte(v)
+		}
+
+		return s
+	}
+
+	defaultClient := &github.Client{}
+
+	h := &HorizontalRunnerAutoscalerReconciler{
+		Client: clientfake.NewFakeClientWithScheme(scheme,
+			newSecret("token", map[string]string{"github_token": "team-a-token"}),
+			newSecret("incomplete", map[string]string{"github_app_id": "1"}),
+			newSecret("invalid", map[string]string{"github_app_id": "one", "github_app_installation_id": "2", "github_app_private_key": "key"}),
+		),
+		GitHubClient: defaultClient,
+	}
+
+	newHRA := func(secretName string) v1alpha1.HorizontalRunnerAutoscaler {
+		return v1alpha1.HorizontalRunnerAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "testhra",
+				Namespace: "default",
+			},
+			Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+				GitHubAPICredentialsFrom: &v1alpha1.GitHubAPICredentialsFrom{
+					SecretRef: v1alpha1.SecretReference{Name: secretName},
+				},
+			},
+		}
+	}
+
+	first, err := h.getGitHubClient(context.Background(), newHRA("token"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if first == nil || first == defaultClient {
+		t.Fatalf("unexpected client: %v", first)
+	}
+
+	// The client is reused until the secret changes
+	if got, err := h.getGitHubClient(context.Background(), newHRA("token")); err != nil || got != first {
+		t.Errorf("unexpected client on the second call: err=%v", err)
+	}
+
+	var secret corev1.Secret
+	if err := h.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "token"}, &secret); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	secret.Data["github_token"] = []byte("team-a-rotated-token")
+
+	if err := h.Update(context.Background(), &secret); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got, err := h.getGitHubClient(context.Background(), newHRA("token")); err != nil || got == first {
+		t.Errorf("unexpected client after the secret is updated: err=%v", err)
+	}
+
+	for _, tc := range []struct {
+		secret string
+		err    string
+	}{
+		{
+			secret: "missing",
+			err:    `getting github api credentials: getting secret default/missing referenced by githubAPICredentialsFrom: secrets "missing" not found`,
+		},
+		{
+			secret: "incomplete",
+			err:    "getting github api credentials: secret default/incomplete referenced by githubAPICredentialsFrom: either github_token or github_app_installation_id must be set",
+		},
+		{
+			secret: "invalid",
+			err:    `getting github api credentials: secret default/invalid referenced by githubAPICredentialsFrom: parsing github_app_id: strconv.ParseInt: parsing "one": invalid syntax`,
+		},
+	} {
+		_, err := h.getGitHubClient(context.Background(), newHRA(tc.secret))
+
+		var credentialsErr *githubAPICredentialsError
+		if !errors.As(err, &credentialsErr) {
+			t.Errorf("%s: unexpected error: want a credentials error, got %v", tc.secret, err)
+		} else if err.Error() != tc.err {
+			t.Errorf("%s: unexpected error: want %q, got %q", tc.secret, tc.err, err.Error())
+		}
+	}
+}
+
+type fakeMetricProvider struct {
+	replicas int
+	err      error
+	// hang makes the provider block until the context is done, like a hung GitHub API call
+	hang bool
+}
+
+func (p *fakeMetricProvider) DesiredReplicas(ctx context.Context, _ v1alpha1.RunnerDeployment, _ v1alpha1.HorizontalRunnerAutoscaler) (int, error) {
+	if p.hang {
+		<-ctx.Done()
+
+		return 0, ctx.Err()
+	}
+
+	return p.replicas, p.err
+}
+
+func TestDetermineDesiredReplicas_MetricProvider(t *testing.T) {
+	const fakeMetricType = "FakeMetric"
+
+	queued := v1alpha1.MetricSpec{Type: v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns}
+	fakeMetric := v1alpha1.MetricSpec{Type: fakeMetricType}
+
+	testcases := []struct {
+		providers map[string]*fakeMetricProvider
+		metrics   []v1alpha1.MetricSpec
+
+		want       int
+		wantMetric string
+		err        string
+	}{
+		// The custom metric wins over the 3 queued and in-progress workflow runs
+		{
+			providers:  map[string]*fakeMetricProvider{fakeMetricType: {replicas: 5}},
+			metrics:    []v1alpha1.MetricSpec{queued, fakeMetric},
+			want:       5,
+			wantMetric: fakeMetricType,
+		},
+		{
+			providers:  map[string]*fakeMetricProvider{fakeMetricType: {replicas: 2}},
+			metrics:    []v1alpha1.MetricSpec{queued, fakeMetric},
+			want:       3,
+			wantMetric: v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns,
+		},
+		// The custom metric is capped by maxReplicas
+		{
+			providers:  map[string]*fakeMetricProvider{fakeMetricType: {replicas: 20}},
+			metrics:    []v1alpha1.MetricSpec{fakeMetric},
+			want:       10,
+			wantMetric: fakeMetricType,
+		},
+		// The failing custom metric is ignored as the other metric succeeded
+		{
+			providers:  map[string]*fakeMetricProvider{fakeMetricType: {err: errors.New("fake error")}},
+			metrics:    []v1alpha1.MetricSpec{queued, fakeMetric},
+			want:       3,
+			wantMetric: v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns,
+		},
+		// A built-in metric type can be overridden
+		{
+			providers:  map[string]*fakeMetricProvider{v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns: {replicas: 7}},
+			metrics:    []v1alpha1.MetricSpec{queued},
+			want:       7,
+			wantMetric: v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns,
+		},
+		{
+			metrics: []v1alpha1.MetricSpec{fakeMetric},
+			err:     `validting autoscaling metrics: unsupported metric type "FakeMetric"`,
+		},
+		// The hung custom metric times out and is ignored as the other metric succeeded
+		{
+			providers:  map[string]*fakeMetricProvider{fakeMetricType: {hang: true}},
+			metrics:    []v1alpha1.MetricSpec{queued, fakeMetric},
+			want:       3,
+			wantMetric: v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns,
+		},
+		{
+			providers: map[string]*fakeMetricProvider{fakeMetricType: {hang: true}},
+			metrics:   []v1alpha1.MetricSpec{fakeMetric},
+			err:       "timed out after 10ms: context deadline exceeded",
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		log := zap.New(func(o *zap.Options) {
+			o.Development = true
+		})
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			server := fake.NewServer(
+				fake.WithListRepositoryWorkflowRunsResponse(200,
+					`{"total_count": 3, "workflow_runs":[{"status":"queued"}, {"status":"in_progress"}, {"status":"in_progress"}]}"`,
+					`{"total_count": 1, "workflow_runs":[{"status":"queued"}]}"`,
+					`{"total_count": 2, "workflow_runs":[{"status":"in_progress"}, {"status":"in_progress"}]}"`,
+				),
+				fake.WithListWorkflowJobsResponse(200, nil),
+				fake.WithListRunnersResponse(200, fake.RunnersListBody),
+			)
+			defer server.Close()
+			client := newGithubClient(server)
+
+			providers := map[string]MetricProviderFactory{}
+			for metricType, p := range tc.providers {
+				p := p
+				providers[metricType] = func(_ *github.Client, _ v1alpha1.MetricSpec) MetricProvider {
+					return p
+				}
+			}
+
+			h := &HorizontalRunnerAutoscalerReconciler{
+				Log:             log,
+				GitHubClient:    client,
+				MetricProviders: providers,
+				MetricTimeout:   10 * time.Millisecond,
+			}
+
+			rd := v1alpha1.RunnerDeployment{
+				Spec: v1alpha1.RunnerDeploymentSpec{
+					Template: v1alpha1.RunnerTemplate{
+						Spec: v1alpha1.RunnerSpec{
+							Repository: "test/valid",
+						},
+					},
+				},
+			}
+
+			hra := v1alpha1.HorizontalRunnerAutoscaler{
+				Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+					MaxReplicas: intPtr(10),
+					MinReplicas: intPtr(1),
+					Metrics:     tc.metrics,
+				},
+			}
+
+			got, err := h.determineDesiredReplicas(context.Background(), rd, hra)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("unexpected error: want %q, got %v", tc.err, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got.Replicas != tc.want {
+				t.Errorf("incorrect desired replicas: want %d, got %d", tc.want, got.Replicas)
+			}
+
+			if got.Type != tc.wantMetric {
+				t.Errorf("incorrect winning metric: want %s, got %s", tc.wantMetric, got.Type)
+			}
+		})
+	}
+}
+
+func TestDetermineDesiredReplicas_HTTPEndpoint(t *testing.T) {
+	queued := v1alpha1.MetricSpec{Type: v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns}
+
+	testcases := []struct {
+		status         int
+		body           string
+		replicasPerRun string
+		authSecretRef  *corev1.SecretKeySelector
+		withQueued     bool
+
+		want       int
+		wantMetric string
+		wantAuth   string
+		err        string
+	}{
+		{
+			status:     200,
+			body:       `{"desiredReplicas": 4}`,
+			want:       4,
+			wantMetric: v1alpha1.AutoscalingMetricTypeHTTPEndpoint,
+		},
+		// The queue depth is multiplied by replicasPerRun and rounded up
+		{
+			status:         200,
+			body:           `{"queueDepth": 3}`,
+			replicasPerRun: "0.5",
+			want:           2,
+			wantMetric:     v1alpha1.AutoscalingMetricTypeHTTPEndpoint,
+		},
+		// Capped by maxReplicas
+		{
+			status:     200,
+			body:       `{"queueDepth": 30}`,
+			want:       10,
+			wantMetric: v1alpha1.AutoscalingMetricTypeHTTPEndpoint,
+		},
+		{
+			status: 200,
+			body:   `{"desiredReplicas": 4}`,
+			authSecretRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "endpoint-auth"},
+				Key:                  "token",
+			},
+			want:       4,
+			wantMetric: v1alpha1.AutoscalingMetricTypeHTTPEndpoint,
+			wantAuth:   "Bearer secret",
+		},
+		// The endpoint responding with non-200 is skipped in favor of the other metric
+		{
+			status:     503,
+			withQueued: true,
+			want:       3,
+			wantMetric: v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns,
+		},
+		{
+			status: 503,
+			err:    "metric skipped: httpEndpoint responded with status 503",
+		},
+		{
+			status: 200,
+			body:   `{}`,
+			err:    "httpEndpoint responded with neither desiredReplicas nor queueDepth",
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		log := zap.New(func(o *zap.Options) {
+			o.Development = true
+		})
+
+		scheme := runtime.NewScheme()
+		_ = clientgoscheme.AddToScheme(scheme)
+		_ = v1alpha1.AddToScheme(scheme)
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			server := fake.NewServer(
+				fake.WithListRepositoryWorkflowRunsResponse(200,
+					`{"total_count": 3, "workflow_runs":[{"status":"queued"}, {"status":"in_progress"}, {"status":"in_progress"}]}"`,
+					`{"total_count": 1, "workflow_runs":[{"status":"queued"}]}"`,
+					`{"total_count": 2, "workflow_runs":[{"status":"in_progress"}, {"status":"in_progress"}]}"`,
+				),
+				fake.WithListWorkflowJobsResponse(200, nil),
+				fake.WithListRunnersResponse(200, fake.RunnersListBody),
+			)
+			defer server.Close()
+			client := newGithubClient(server)
+
+			var gotAuth string
+
+			endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				gotAuth = req.Header.Get("Authorization")
+
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			defer endpoint.Close()
+
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "endpoint-auth",
+					Namespace: "default",
+				},
+				Data: map[string][]byte{
+					"token": []byte("Bearer secret"),
+				},
+			}
+
+			h := &HorizontalRunnerAutoscalerReconciler{
+				Client:       clientfake.NewFakeClientWithScheme(scheme, secret),
+				Log:          log,
+				GitHubClient: client,
+			}
+
+			rd := v1alpha1.RunnerDeployment{
+				Spec: v1alpha1.RunnerDeploymentSpec{
+					Template: v1alpha1.RunnerTemplate{
+						Spec: v1alpha1.RunnerSpec{
+							Repository: "test/valid",
+						},
+					},
+				},
+			}
+
+			metrics := []v1alpha1.MetricSpec{
+				{
+					Type:           v1alpha1.AutoscalingMetricTypeHTTPEndpoint,
+					ReplicasPerRun: tc.replicasPerRun,
+					HTTPEndpoint: &v1alpha1.HTTPEndpointMetricSource{
+						URL:           endpoint.URL,
+						AuthSecretRef: tc.authSecretRef,
+					},
+				},
+			}
+
+			if tc.withQueued {
+				metrics = append([]v1alpha1.MetricSpec{queued}, metrics...)
+			}
+
+			hra := v1alpha1.HorizontalRunnerAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+				},
+				Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+					MaxReplicas: intPtr(10),
+					MinReplicas: intPtr(1),
+					Metrics:     metrics,
+				},
+			}
+
+			got, err := h.determineDesiredReplicas(context.Background(), rd, hra)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("unexpected error: want %q, got %v", tc.err, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got.Replicas != tc.want {
+				t.Errorf("incorrect desired replicas: want %d, got %d", tc.want, got.Replicas)
+			}
+
+			if got.Type != tc.wantMetric {
+				t.Errorf("incorrect winning metric: want %s, got %s", tc.wantMetric, got.Type)
+			}
+
+			if gotAuth != tc.wantAuth {
+				t.Errorf("incorrect authorization header: want %q, got %q", tc.wantAuth, gotAuth)
+			}
+		})
+	}
+}
+
+func TestDetermineDesiredReplicas_Prometheus(t *testing.T) {
+	queued := v1alpha1.MetricSpec{Type: v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns}
+
+	testcases := []struct {
+		status         int
+		body           string
+		replicasPerRun string
+		authSecretRef  *corev1.SecretKeySelector
+		withQueued     bool
+
+		want        int
+		wantMetric  string
+		wantAuth    string
+		wantWarning bool
+		err         string
+	}{
+		{
+			status:     200,
+			body:       `{"status":"success","data":{"resultType":"scalar","result":[1600000000.0,"4"]}}`,
+			want:       4,
+			wantMetric: v1alpha1.AutoscalingMetricTypePrometheus,
+		},
+		// The queue depth is rounded up and multiplied by replicasPerRun
+		{
+			status:         200,
+			body:           `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1600000000.0,"2.5"]}]}}`,
+			replicasPerRun: "0.5",
+			want:           2,
+			wantMetric:     v1alpha1.AutoscalingMetricTypePrometheus,
+		},
+		// Capped by maxReplicas
+		{
+			status:     200,
+			body:       `{"status":"success","data":{"resultType":"scalar","result":[1600000000.0,"30"]}}`,
+			want:       10,
+			wantMetric: v1alpha1.AutoscalingMetricTypePrometheus,
+		},
+		{
+			status: 200,
+			body:   `{"status":"success","data":{"resultType":"scalar","result":[1600000000.0,"4"]}}`,
+			authSecretRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "prometheus-auth"},
+				Key:                  "token",
+			},
+			want:       4,
+			wantMetric: v1alpha1.AutoscalingMetricTypePrometheus,
+			wantAuth:   "Bearer secret",
+		},
+		// The failed query is skipped in favor of the other metric
+		{
+			status:      400,
+			body:        `{"status":"error","errorType":"bad_data","error":"parse error"}`,
+			withQueued:  true,
+			want:        3,
+			wantMetric:  v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns,
+			wantWarning: true,
+		},
+		{
+			status:      400,
+			body:        `{"status":"error","errorType":"bad_data","error":"parse error"}`,
+			wantWarning: true,
+			err:         "metric skipped: prometheus query failed: responded with status 400: parse error",
+		},
+		{
+			status:      200,
+			body:        `{"status":"success","data":{"resultType":"vector","result":[]}}`,
+			wantWarning: true,
+			err:         "metric skipped: prometheus query failed: resulted in a vector of 0 samples, but it must be a single sample",
+		},
+		{
+			status:      200,
+			body:        `{"status":"success","data":{"resultType":"matrix","result":[]}}`,
+			wantWarning: true,
+			err:         `metric skipped: prometheus query failed: resulted in an unsupported type "matrix", but it must be a scalar or a vector`,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		log := zap.New(func(o *zap.Options) {
+			o.Development = true
+		})
+
+		scheme := runtime.NewScheme()
+		_ = clientgoscheme.AddToScheme(scheme)
+		_ = v1alpha1.AddToScheme(scheme)
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			server := fake.NewServer(
+				fake.WithListRepositoryWorkflowRunsResponse(200,
+					`{"total_count": 3, "workflow_runs":[{"status":"queued"}, {"status":"in_progress"}, {"status":"in_progress"}]}"`,
+					`{"total_count": 1, "workflow_runs":[{"status":"queued"}]}"`,
+					`{"total_count": 2, "workflow_runs":[{"status":"in_progress"}, {"status":"in_progress"}]}"`,
+				),
+				fake.WithListWorkflowJobsResponse(200, nil),
+				fake.WithListRunnersResponse(200, fake.RunnersListBody),
+			)
+			defer server.Close()
+			client := newGithubClient(server)
+
+			var gotAuth, gotPath, gotQuery string
+
+			prometheus := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				gotAuth = req.Header.Get("Authorization")
+				gotPath = req.URL.Path
+				gotQuery = req.URL.Query().Get("query")
+
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			defer prometheus.Close()
+
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "prometheus-auth",
+					Namespace: "default",
+				},
+				Data: map[string][]byte{
+					"token": []byte("Bearer secret"),
+				},
+			}
+
+			recorder := record.NewFakeRecorder(10)
+
+			h := &HorizontalRunnerAutoscalerReconciler{
+				Client:       clientfake.NewFakeClientWithScheme(scheme, secret),
+				Log:          log,
+				Recorder:     recorder,
+				GitHubClient: client,
+			}
+
+			rd := v1alpha1.RunnerDeployment{
+				Spec: v1alpha1.RunnerDeploymentSpec{
+					Template: v1alpha1.RunnerTemplate{
+						Spec: v1alpha1.RunnerSpec{
+							Repository: "test/valid",
+						},
+					},
+				},
+			}
+
+			const query = `sum(ci_queued_jobs{team="a"})`
+
+			metrics := []v1alpha1.MetricSpec{
+				{
+					Type:           v1alpha1.AutoscalingMetricTypePrometheus,
+					ReplicasPerRun: tc.replicasPerRun,
+					Prometheus: &v1alpha1.PrometheusMetricSource{
+						URL:           prometheus.URL + "/",
+						Query:         query,
+						AuthSecretRef: tc.authSecretRef,
+					},
+				},
+			}
+
+			if tc.withQueued {
+				metrics = append([]v1alpha1.MetricSpec{queued}, metrics...)
+			}
+
+			hra := v1alpha1.HorizontalRunnerAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+				},
+				Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+					MaxReplicas: intPtr(10),
+					MinReplicas: intPtr(1),
+					Metrics:     metrics,
+				},
+			}
+
+			got, err := h.determineDesiredReplicas(context.Background(), rd, hra)
+
+			var gotWarning bool
+			for len(recorder.Events) > 0 {
+				if e := <-recorder.Events; strings.Contains(e, "PrometheusQueryFailed") {
+					gotWarning = true
+				}
+			}
+
+			if gotWarning != tc.wantWarning {
+				t.Errorf("unexpected PrometheusQueryFailed event: want %v, got %v", tc.wantWarning, gotWarning)
+			}
+
+			if gotPath != "/api/v1/query" || gotQuery != query {
+				t.Errorf("unexpected query: want /api/v1/query?query=%s, got %s?query=%s", query, gotPath, gotQuery)
+			}
+
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("unexpected error: want %q, got %v", tc.err, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got.Replicas != tc.want {
+				t.Errorf("incorrect desired replicas: want %d, got %d", tc.want, got.Replicas)
+			}
+
+			if got.Type != tc.wantMetric {
+				t.Errorf("incorrect winning metric: want %s, got %s", tc.wantMetric, got.Type)
+			}
+
+			if gotAuth != tc.wantAuth {
+				t.Errorf("incorrect authorization header: want %q, got %q", tc.wantAuth, gotAuth)
+			}
+		})
+	}
+}
+
+func TestListRunnersWithCache(t *testing.T) {
+	org1 := runnerListCacheKey{organization: "org1"}
+	org2 := runnerListCacheKey{organization: "org2"}
+	group := runnerListCacheKey{organization: "org1", runnerGroup: "group"}
+
+	testcases := []struct {
+		ttl     time.Duration
+		expired bool
+		keys    []runnerListCacheKey
+
+		wantCalls int
+	}{
+		// The listing is shared across the reconciliations for the same scope
+		{
+			keys:      []runnerListCacheKey{org1, org1, org1},
+			wantCalls: 1,
+		},
+		{
+			keys:      []runnerListCacheKey{org1, org2, group, org1},
+			wantCalls: 3,
+		},
+		{
+			ttl:       -1,
+			keys:      []runnerListCacheKey{org1, org1},
+			wantCalls: 2,
+		},
+		{
+			expired:   true,
+			keys:      []runnerListCacheKey{org1, org1},
+			wantCalls: 2,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			h := &HorizontalRunnerAutoscalerReconciler{
+				RunnerListCacheTTL: tc.ttl,
+			}
+
+			var calls int
+
+			for _, key := range tc.keys {
+				_, err := h.listRunnersWithCache(key, func() ([]*gogithub.Runner, error) {
+					calls++
+
+					return []*gogithub.Runner{}, nil
+				})
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				if tc.expired {
+					h.runnerListCache.set(key, nil, time.Now().Add(-time.Minute), DefaultRunnerListCacheTTL)
+				}
+			}
+
+			if calls != tc.wantCalls {
+				t.Errorf("unexpected number of runner listings: want %d, got %d", tc.wantCalls, calls)
+			}
+		})
+	}
+
+	// Failures aren't cached
+	h := &HorizontalRunnerAutoscalerReconciler{}
+
+	var calls int
+
+	for i := 0; i < 2; i++ {
+		_, _ = h.listRunnersWithCache(org1, func() ([]*gogithub.Runner, error) {
+			calls++
+
+			return nil, errors.New("fake error")
+		})
+	}
+
+	if calls != 2 {
+		t.Errorf("unexpected number of runner listings after failures: want 2, got %d", calls)
+	}
+}
+
+func TestDetermineDesiredReplicas_DurationWeightedQueuedAndInProgressWorkflowRuns(t *testing.T) {
+	boolPtr := func(v bool) *bool {
+		return &v
+	}
+
+	// completedRuns returns the completed runs of a workflow, each of which took d
+	completedRuns := func(d time.Duration, n int) string {
+		created := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+
+		var runs []string
+		for i := 0; i < n; i++ {
+			runs = append(runs, fmt.Sprintf(`{"status":"completed","created_at":%q,"updated_at":%q}`, created.Format(time.RFC3339), created.Add(d).Format(time.RFC3339)))
+		}
+
+		return fmt.Sprintf(`{"total_count": %d, "workflow_runs":[%s]}`, n, strings.Join(runs, ","))
+	}
+
+	testcases := []struct {
+		workflowRuns_queued      string
+		workflowRuns_in_progress string
+		completedRuns            map[int]string
+
+		referenceDurationSeconds *int
+		includeInProgress        *bool
+		replicasPerRun           string
+
+		want int
+	}{
+		// Weighted 1 without any completed runs
+		{
+			workflowRuns_queued:      `{"total_count": 1, "workflow_runs":[{"status":"queued","workflow_id":1}]}"`,
+			workflowRuns_in_progress: `{"total_count": 1, "workflow_runs":[{"status":"in_progress","workflow_id":2}]}"`,
+			want:                     2,
+		},
+		// A workflow taking 30 minutes is weighted 3 by the default reference duration of 10 minutes
+		{
+			workflowRuns_queued:      `{"total_count": 1, "workflow_runs":[{"status":"queued","workflow_id":1}]}"`,
+			workflowRuns_in_progress: `{"total_count": 1, "workflow_runs":[{"status":"in_progress","workflow_id":2}]}"`,
+			completedRuns: map[int]string{
+				1: completedRuns(30*time.Minute, 2),
+			},
+			want: 4,
+		},
+		// Short workflows are weighted less than 1 and rounded up
+		{
+			workflowRuns_queued:      `{"total_count": 3, "workflow_runs":[{"status":"queued","workflow_id":2}, {"status":"queued","workflow_id":2}, {"status":"queued","workflow_id":2}]}"`,
+			workflowRuns_in_progress: `{"total_count": 0, "workflow_runs":[]}"`,
+			completedRuns: map[int]string{
+				2: completedRuns(5*time.Minute, 3),
+			},
+			want: 2,
+		},
+		{
+			workflowRuns_queued:      `{"total_count": 1, "workflow_runs":[{"status":"queued","workflow_id":1}]}"`,
+			workflowRuns_in_progress: `{"total_count": 1, "workflow_runs":[{"status":"in_progress","workflow_id":1}]}"`,
+			completedRuns: map[int]string{
+				1: completedRuns(30*time.Minute, 1),
+			},
+			referenceDurationSeconds: intPtr(900),
+			want:                     4,
+		},
+		{
+			workflowRuns_queued:      `{"total_count": 1, "workflow_runs":[{"status":"queued","workflow_id":1}]}"`,
+			workflowRuns_in_progress: `{"total_count": 1, "workflow_runs":[{"status":"in_progress","workflow_id":1}]}"`,
+			completedRuns: map[int]string{
+				1: completedRuns(30*time.Minute, 1),
+			},
+			includeInProgress: boolPtr(false),
+			replicasPerRun:    "2",
+			want:              6,
+		},
+		// Capped by maxReplicas
+		{
+			workflowRuns_queued:      `{"total_count": 2, "workflow_runs":[{"status":"queued","workflow_id":1}, {"status":"queued","workflow_id":1}]}"`,
+			workflowRuns_in_progress: `{"total_count": 0, "workflow_runs":[]}"`,
+			completedRuns: map[int]string{
+				1: completedRuns(time.Hour, 1),
+			},
+			want: 10,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		log := zap.New(func(o *zap.Options) {
+			o.Development = true
+		})
+
+		scheme := runtime.NewScheme()
+		_ = clientgoscheme.AddToScheme(scheme)
+		_ = v1alpha1.AddToScheme(scheme)
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			server := fake.NewServer(
+				fake.WithListRepositoryWorkflowRunsResponse(200, "", tc.workflowRuns_queued, tc.workflowRuns_in_progress),
+				fake.WithListWorkflowJobsResponse(200, nil),
+				fake.WithListWorkflowRunsByIDResponse(200, tc.completedRuns),
+				fake.WithListRunnersResponse(200, fake.RunnersListBody),
+			)
+			defer server.Close()
+			client := newGithubClient(server)
+
+			h := &HorizontalRunnerAutoscalerReconciler{
+				Log:          log,
+				GitHubClient: client,
+				Scheme:       scheme,
+			}
+
+			rd := v1alpha1.RunnerDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "testrd",
+				},
+				Spec: v1alpha1.RunnerDeploymentSpec{
+					Template: v1alpha1.RunnerTemplate{
+						Spec: v1alpha1.RunnerSpec{
+							Repository: "test/valid",
+						},
+					},
+				},
+			}
+
+			hra := v1alpha1.HorizontalRunnerAutoscaler{
+				Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+					MaxReplicas: intPtr(10),
+					MinReplicas: intPtr(1),
+					Metrics: []v1alpha1.MetricSpec{
+						{
+							Type:                     v1alpha1.AutoscalingMetricTypeDurationWeightedQueuedAndInProgressWorkflowRuns,
+							ReferenceDurationSeconds: tc.referenceDurationSeconds,
+							IncludeInProgress:        tc.includeInProgress,
+							ReplicasPerRun:           tc.replicasPerRun,
+						},
+					},
+				},
+			}
+
+			got, _, err := h.computeReplicas(context.Background(), rd, hra)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if *got != tc.want {
+				t.Errorf("incorrect desired replicas: want %d, got %d", tc.want, *got)
+			}
+		})
+	}
+}
+
+func TestDetermineDesiredReplicas_OfflineRunners(t *testing.T) {
+	const fakeMetricType = "FakeMetric"
+
+	runnersListBody := func(offline ...bool) string {
+		var runners []string
+		for i, o := range offline {
+			status := "online"
+			if o {
+				status = "offline"
+			}
+			runners = append(runners, fmt.Sprintf(`{"id": %d, "name": "test%d", "os": "linux", "status": "%s", "busy": false}`, i+1, i+1, status))
+		}
+		return fmt.Sprintf(`{"total_count": %d, "runners": [%s]}`, len(offline), strings.Join(runners, ","))
+	}
+
+	testcases := []struct {
+		computed int
+		offline  []bool
+		metrics  []v1alpha1.MetricSpec
+
+		// startingUp is the number of the last runners created just now
+		startingUp int
+
+		listRunnersStatus int
+
+		want         int
+		wantUncapped int
+	}{
+		// 2 of the 4 runners are offline, compensated by 2 more
+		{
+			computed:     3,
+			offline:      []bool{false, true, false, true},
+			want:         5,
+			wantUncapped: 5,
+		},
+		// Bounded by maxReplicas
+		{
+			computed:     9,
+			offline:      []bool{false, true, false, true},
+			want:         10,
+			wantUncapped: 11,
+		},
+		{
+			computed:     3,
+			offline:      []bool{false, false, false},
+			want:         3,
+			wantUncapped: 3,
+		},
+		// The runner created just now may be offline as it's still starting up
+		{
+			computed:     3,
+			offline:      []bool{false, true, true},
+			startingUp:   1,
+			want:         4,
+			wantUncapped: 4,
+		},
+		// Opt-in
+		{
+			computed:     3,
+			offline:      []bool{false, true, false, true},
+			metrics:      []v1alpha1.MetricSpec{{Type: fakeMetricType}},
+			want:         3,
+			wantUncapped: 3,
+		},
+		// The failure to list the runners leaves the desired replicas computed by the other metrics as is
+		{
+			computed:          3,
+			offline:           []bool{false, true, false, true},
+			listRunnersStatus: 500,
+			want:              3,
+			wantUncapped:      3,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		log := zap.New(func(o *zap.Options) {
+			o.Development = true
+		})
+
+		scheme := runtime.NewScheme()
+		_ = clientgoscheme.AddToScheme(scheme)
+		_ = v1alpha1.AddToScheme(scheme)
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			listRunnersStatus := 200
+			if tc.listRunnersStatus != 0 {
+				listRunnersStatus = tc.listRunnersStatus
+			}
+
+			server := fake.NewServer(
+				fake.WithListRunnersResponse(listRunnersStatus, runnersListBody(tc.offline...)),
+			)
+			defer server.Close()
+			client := newGithubClient(server)
+
+			var runners []runtime.Object
+			for i := range tc.offline {
+				runner := &v1alpha1.Runner{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      fmt.Sprintf("test%d", i+1),
+						Namespace: "default",
+					},
+				}
+
+				if i >= len(tc.offline)-tc.startingUp {
+					runner.CreationTimestamp = metav1.Now()
+				}
+
+				runners = append(runners, runner)
+			}
+
+			h := &HorizontalRunnerAutoscalerReconciler{
+				Client:       clientfake.NewFakeClientWithScheme(scheme, runners...),
+				Log:          log,
+				GitHubClient: client,
+				Scheme:       scheme,
+				MetricProviders: map[string]MetricProviderFactory{
+					fakeMetricType: func(_ *github.Client, _ v1alpha1.MetricSpec) MetricProvider {
+						return &fakeMetricProvider{replicas: tc.computed}
+					},
+				},
+				RunnerListCacheTTL: -1,
+			}
+
+			rd := v1alpha1.RunnerDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "testrd",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.RunnerDeploymentSpec{
+					Template: v1alpha1.RunnerTemplate{
+						Spec: v1alpha1.RunnerSpec{
+							Repository: "test/valid",
+						},
+					},
+				},
+			}
+
+			metrics := tc.metrics
+			if metrics == nil {
+				metrics = []v1alpha1.MetricSpec{{Type: fakeMetricType}, {Type: v1alpha1.AutoscalingMetricTypeOfflineRunners}}
+			}
+
+			hra := v1alpha1.HorizontalRunnerAutoscaler{
+				Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+					MinReplicas: intPtr(1),
+					MaxReplicas: intPtr(10),
+					Metrics:     metrics,
+				},
+			}
+
+			got, err := h.determineDesiredReplicas(context.Background(), rd, hra)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got.Replicas != tc.want {
+				t.Errorf("incorrect desired replicas: want %d, got %d", tc.want, got.Replicas)
+			}
+
+			if got.uncappedReplicas() != tc.wantUncapped {
+				t.Errorf("incorrect uncapped replicas: want %d, got %d", tc.wantUncapped, got.uncappedReplicas())
+			}
+
+			// The offline runners only add to the replicas of the winning metric
+			if got.Type != fakeMetricType {
+				t.Errorf("unexpected winning metric: want %s, got %s", fakeMetricType, got.Type)
+			}
+		})
+	}
+}
+
+func TestDetermineDesiredReplicas_MetricExpression(t *testing.T) {
+	const fakeMetricType = "FakeMetric"
+
+	testcases := []struct {
+		expr     string
+		replicas map[string]int
+		failing  []string
+
+		want         int
+		wantUncapped int
+		err          string
+	}{
+		{
+			expr:         "max(a, ceil(b / 0.7))",
+			replicas:     map[string]int{"a": 2, "b": 3},
+			want:         5,
+			wantUncapped: 5,
+		},
+		// Unlike picking the largest metric, the expression can result in fewer replicas than any of the metrics
+		{
+			expr:         "min(a, b)",
+			replicas:     map[string]int{"a": 2, "b": 3},
+			want:         2,
+			wantUncapped: 2,
+		},
+		// Rounded up without the floating point error adding a replica
+		{
+			expr:         "a * 0.3",
+			replicas:     map[string]int{"a": 10, "b": 1},
+			want:         3,
+			wantUncapped: 3,
+		},
+		// Bounded by minReplicas and maxReplicas
+		{
+			expr:         "a - b",
+			replicas:     map[string]int{"a": 2, "b": 3},
+			want:         1,
+			wantUncapped: 1,
+		},
+		{
+			expr:         "a + b",
+			replicas:     map[string]int{"a": 8, "b": 7},
+			want:         10,
+			wantUncapped: 15,
+		},
+		// The metric unreferenced by the expression may fail
+		{
+			expr:         "a + 1",
+			replicas:     map[string]int{"a": 2},
+			failing:      []string{"b"},
+			want:         3,
+			wantUncapped: 3,
+		},
+		// The referenced one may not
+		{
+			expr:     "max(a, b)",
+			replicas: map[string]int{"a": 2},
+			failing:  []string{"b"},
+			err:      `metric "b" referenced by metricExpression failed`,
+		},
+		// The fake metric observes no busy runners
+		{
+			expr:     "a + busyRunners",
+			replicas: map[string]int{"a": 2, "b": 3},
+			err:      `"busyRunners" has no value`,
+		},
+		{
+			expr:     "a / (b - 3)",
+			replicas: map[string]int{"a": 2, "b": 3},
+			err:      "division by zero",
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		log := zap.New(func(o *zap.Options) {
+			o.Development = true
+		})
+
+		scheme := runtime.NewScheme()
+		_ = clientgoscheme.AddToScheme(scheme)
+		_ = v1alpha1.AddToScheme(scheme)
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			server := fake.NewServer(fake.WithListRunnersResponse(200, fake.RunnersListBody))
+			defer server.Close()
+			client := newGithubClient(server)
+
+			failing := map[string]bool{}
+			for _, name := range tc.failing {
+				failing[name] = true
+			}
+
+			h := &HorizontalRunnerAutoscalerReconciler{
+				Client:       clientfake.NewFakeClientWithScheme(scheme),
+				Log:          log,
+				GitHubClient: client,
+				Scheme:       scheme,
+				MetricProviders: map[string]MetricProviderFactory{
+					fakeMetricType: func(_ *github.Client, metric v1alpha1.MetricSpec) MetricProvider {
+						if failing[metric.Name] {
+							return &fakeMetricProvider{err: errors.New("failed")}
+						}
+						return &fakeMetricProvider{replicas: tc.replicas[metric.Name]}
+					},
+				},
+			}
+
+			rd := v1alpha1.RunnerDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "testrd",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.RunnerDeploymentSpec{
+					Template: v1alpha1.RunnerTemplate{
+						Spec: v1alpha1.RunnerSpec{
+							Repository: "test/valid",
+						},
+					},
+				},
+			}
+
+			hra := v1alpha1.HorizontalRunnerAutoscaler{
+				Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+					MinReplicas: intPtr(1),
+					MaxReplicas: intPtr(10),
+					Metrics: []v1alpha1.MetricSpec{
+						{Type: fakeMetricType, Name: "a"},
+						{Type: fakeMetricType, Name: "b"},
+					},
+					MetricExpression: tc.expr,
+				},
+			}
+
+			got, err := h.determineDesiredReplicas(context.Background(), rd, hra)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error containing %q, got %v", tc.err, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got.Replicas != tc.want {
+				t.Errorf("incorrect desired replicas: want %d, got %d", tc.want, got.Replicas)
+			}
+
+			if got.uncappedReplicas() != tc.wantUncapped {
+				t.Errorf("incorrect uncapped replicas: want %d, got %d", tc.wantUncapped, got.uncappedReplicas())
+			}
+
+			if got.Type != metricTypeMetricExpression {
+				t.Errorf("unexpected metric type: want %s, got %s", metricTypeMetricExpression, got.Type)
+			}
+		})
+	}
+}
+
+func TestDetermineDesiredReplicas_ExternalObject(t *testing.T) {
+	int32Ptr := func(v int32) *int32 {
+		return &v
+	}
+
+	queued := v1alpha1.MetricSpec{Type: v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns}
+
+	testcases := []struct {
+		hpa          *autoscalingv1.HorizontalPodAutoscaler
+		source       *v1alpha1.ExternalObjectMetricSource
+		withQueued   bool
+		want         int
+		wantUncapped int
+		wantMetric   string
+		err          string
+	}{
+		{
+			hpa:          &autoscalingv1.HorizontalPodAutoscaler{Status: autoscalingv1.HorizontalPodAutoscalerStatus{DesiredReplicas: 4}},
+			want:         4,
+			wantUncapped: 4,
+			wantMetric:   v1alpha1.AutoscalingMetricTypeExternalObject,
+		},
+		// Bounded by maxReplicas
+		{
+			hpa:          &autoscalingv1.HorizontalPodAutoscaler{Status: autoscalingv1.HorizontalPodAutoscalerStatus{DesiredReplicas: 12}},
+			want:         10,
+			wantUncapped: 12,
+			wantMetric:   v1alpha1.AutoscalingMetricTypeExternalObject,
+		},
+		{
+			hpa: &autoscalingv1.HorizontalPodAutoscaler{Spec: autoscalingv1.HorizontalPodAutoscalerSpec{MinReplicas: int32Ptr(5)}},
+			source: &v1alpha1.ExternalObjectMetricSource{
+				ReplicasPath: "spec.minReplicas",
+			},
+			want:         5,
+			wantUncapped: 5,
+			wantMetric:   v1alpha1.AutoscalingMetricTypeExternalObject,
+		},
+		// The missing object is skipped in favor of the other metric
+		{
+			withQueued:   true,
+			want:         3,
+			wantUncapped: 3,
+			wantMetric:   v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns,
+		},
+		{
+			err: "metric skipped: HorizontalPodAutoscaler default/keda-hpa-example not found",
+		},
+		// And so is the missing field
+		{
+			hpa: &autoscalingv1.HorizontalPodAutoscaler{},
+			source: &v1alpha1.ExternalObjectMetricSource{
+				ReplicasPath: "spec.minReplicas",
+			},
+			err: "metric skipped: HorizontalPodAutoscaler default/keda-hpa-example has no spec.minReplicas",
+		},
+		{
+			hpa: &autoscalingv1.HorizontalPodAutoscaler{},
+			source: &v1alpha1.ExternalObjectMetricSource{
+				ReplicasPath: "metadata.name",
+			},
+			err: "metadata.name of HorizontalPodAutoscaler default/keda-hpa-example must be a number, but got string",
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		log := zap.New(func(o *zap.Options) {
+			o.Development = true
+		})
+
+		scheme := runtime.NewScheme()
+		_ = clientgoscheme.AddToScheme(scheme)
+		_ = v1alpha1.AddToScheme(scheme)
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			server := fake.NewServer(
+				fake.WithListRepositoryWorkflowRunsResponse(200,
+					`{"total_count": 3, "workflow_runs":[{"status":"queued"}, {"status":"in_progress"}, {"status":"in_progress"}]}"`,
+					`{"total_count": 1, "workflow_runs":[{"status":"queued"}]}"`,
+					`{"total_count": 2, "workflow_runs":[{"status":"in_progress"}, {"status":"in_progress"}]}"`,
+				),
+				fake.WithListWorkflowJobsResponse(200, nil),
+				fake.WithListRunnersResponse(200, fake.RunnersListBody),
+			)
+			defer server.Close()
+			client := newGithubClient(server)
+
+			var objs []runtime.Object
+
+			if tc.hpa != nil {
+				hpa := tc.hpa.DeepCopy()
+				hpa.Name = "keda-hpa-example"
+				hpa.Namespace = "default"
+
+				objs = append(objs, hpa)
+			}
+
+			h := &HorizontalRunnerAutoscalerReconciler{
+				Client:       clientfake.NewFakeClientWithScheme(scheme, objs...),
+				Log:          log,
+				GitHubClient: client,
+			}
+
+			rd := v1alpha1.RunnerDeployment{
+				Spec: v1alpha1.RunnerDeploymentSpec{
+					Template: v1alpha1.RunnerTemplate{
+						Spec: v1alpha1.RunnerSpec{
+							Repository: "test/valid",
+						},
+					},
+				},
+			}
+
+			source := v1alpha1.ExternalObjectMetricSource{}
+			if tc.source != nil {
+				source = *tc.source
+			}
+			source.APIVersion = "autoscaling/v1"
+			source.Kind = "HorizontalPodAutoscaler"
+			source.Name = "keda-hpa-example"
+
+			metrics := []v1alpha1.MetricSpec{
+				{
+					Type:           v1alpha1.AutoscalingMetricTypeExternalObject,
+					ExternalObject: &source,
+				},
+			}
+
+			if tc.withQueued {
+				metrics = append([]v1alpha1.MetricSpec{queued}, metrics...)
+			}
+
+			hra := v1alpha1.HorizontalRunnerAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+				},
+				Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+					MaxReplicas: intPtr(10),
+					MinReplicas: intPtr(1),
+					Metrics:     metrics,
+				},
+			}
+
+			got, err := h.determineDesiredReplicas(context.Background(), rd, hra)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("unexpected error: want %q, got %v", tc.err, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got.Replicas != tc.want {
+				t.Errorf("incorrect desired replicas: want %d, got %d", tc.want, got.Replicas)
+			}
+
+			if got.uncappedReplicas() != tc.wantUncapped {
+				t.Errorf("incorrect uncapped replicas: want %d, got %d", tc.wantUncapped, got.uncappedReplicas())
+			}
+
+			if got.Type != tc.wantMetric {
+				t.Errorf("incorrect winning metric: want %s, got %s", tc.wantMetric, got.Type)
+			}
+		})
+	}
+}
+
+func TestDetermineDesiredReplicas_WeightedSum(t *testing.T) {
+	const fakeMetricType = "FakeMetric"
+
+	testcases := []struct {
+		replicas map[string]int
+		weights  map[string]string
+		failing  []string
+		skipped  []string
+
+		want         int
+		wantUncapped int
+		err          string
+	}{
+		{
+			replicas:     map[string]int{"org": 3, "repo": 4},
+			weights:      map[string]string{"repo": "2"},
+			want:         10,
+			wantUncapped: 11,
+		},
+		{
+			replicas:     map[string]int{"org": 3, "repo": 1},
+			weights:      map[string]string{"repo": "2"},
+			want:         5,
+			wantUncapped: 5,
+		},
+		// Rounded up without the floating point error adding a replica
+		{
+			replicas:     map[string]int{"org": 3, "repo": 10},
+			weights:      map[string]string{"org": "0.5", "repo": "0.3"},
+			want:         5,
+			wantUncapped: 5,
+		},
+		// MinReplicas is applied to the sum rather than to each metric
+		{
+			replicas:     map[string]int{"org": 0, "repo": 0},
+			want:         1,
+			wantUncapped: 1,
+		},
+		// The skipped metric adds nothing
+		{
+			replicas:     map[string]int{"org": 3},
+			weights:      map[string]string{"repo": "2"},
+			skipped:      []string{"repo"},
+			want:         3,
+			wantUncapped: 3,
+		},
+		// The failed one fails the sum
+		{
+			replicas: map[string]int{"org": 3},
+			failing:  []string{"repo"},
+			err:      "summing the weighted metrics",
+		},
+		{
+			replicas: map[string]int{"org": 3, "repo": 1},
+			weights:  map[string]string{"repo": "-1"},
+			err:      "weight must be greater than 0",
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		log := zap.New(func(o *zap.Options) {
+			o.Development = true
+		})
+
+		scheme := runtime.NewScheme()
+		_ = clientgoscheme.AddToScheme(scheme)
+		_ = v1alpha1.AddToScheme(scheme)
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			server := fake.NewServer(fake.WithListRunnersResponse(200, fake.RunnersListBody))
+			defer server.Close()
+			client := newGithubClient(server)
+
+			failing, skipped := map[string]bool{}, map[string]bool{}
+			for _, name := range tc.failing {
+				failing[name] = true
+			}
+			for _, name := range tc.skipped {
+				skipped[name] = true
+			}
+
+			h := &HorizontalRunnerAutoscalerReconciler{
+				Client:       clientfake.NewFakeClientWithScheme(scheme),
+				Log:          log,
+				GitHubClient: client,
+				Scheme:       scheme,
+				MetricProviders: map[string]MetricProviderFactory{
+					fakeMetricType: func(_ *github.Client, metric v1alpha1.MetricSpec) MetricProvider {
+						if failing[metric.Name] {
+							return &fakeMetricProvider{err: errors.New("failed")}
+						}
+						if skipped[metric.Name] {
+							return &fakeMetricProvider{err: fmt.Errorf("%w: not ready", errMetricSkipped)}
+						}
+						return &fakeMetricProvider{replicas: tc.replicas[metric.Name]}
+					},
+				},
+			}
+
+			rd := v1alpha1.RunnerDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "testrd",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.RunnerDeploymentSpec{
+					Template: v1alpha1.RunnerTemplate{
+						Spec: v1alpha1.RunnerSpec{
+							Repository: "test/valid",
+						},
+					},
+				},
+			}
+
+			hra := v1alpha1.HorizontalRunnerAutoscaler{
+				Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+					MinReplicas: intPtr(1),
+					MaxReplicas: intPtr(10),
+					Metrics: []v1alpha1.MetricSpec{
+						{Type: fakeMetricType, Name: "org", Weight: tc.weights["org"]},
+						{Type: fakeMetricType, Name: "repo", Weight: tc.weights["repo"]},
+					},
+					MetricAggregation: v1alpha1.MetricAggregationWeightedSum,
+				},
+			}
+
+			got, err := h.determineDesiredReplicas(context.Background(), rd, hra)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error containing %q, got %v", tc.err, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got.Replicas != tc.want {
+				t.Errorf("incorrect desired replicas: want %d, got %d", tc.want, got.Replicas)
+			}
+
+			if got.uncappedReplicas() != tc.wantUncapped {
+				t.Errorf("incorrect uncapped replicas: want %d, got %d", tc.wantUncapped, got.uncappedReplicas())
+			}
+
+			if got.Type != metricTypeWeightedSum {
+				t.Errorf("unexpected metric type: want %s, got %s", metricTypeWeightedSum, got.Type)
+			}
+		})
+	}
+}
+
+func TestDetermineDesiredReplicas_RecentPushAndPullRequestEvents(t *testing.T) {
+	event := func(typ string, ago time.Duration, payload string) string {
+		return fmt.Sprintf(`{"type": %q, "created_at": %q, "payload": %s}`, typ, time.Now().Add(-ago).UTC().Format(time.RFC3339), payload)
+	}
+
+	// 3 recent push events and 1 recent pull request opened, along with the events not counted by default
+	events := "[" + strings.Join([]string{
+		event("PushEvent", time.Minute, `{}`),
+		event("PushEvent", 2*time.Minute, `{}`),
+		event("PullRequestEvent", 2*time.Minute, `{"action": "opened"}`),
+		event("PullRequestEvent", 3*time.Minute, `{"action": "labeled"}`),
+		event("IssuesEvent", 3*time.Minute, `{"action": "opened"}`),
+		event("PushEvent", 4*time.Minute, `{}`),
+		event("PushEvent", 30*time.Minute, `{}`),
+	}, ",") + "]"
+
+	testcases := []struct {
+		status               int
+		replicasPerEvent     string
+		eventLookbackSeconds *int
+		withQueued           bool
+
+		want       int
+		wantMetric string
+		err        string
+	}{
+		// 4 events with the default replicasPerEvent of 0.25
+		{
+			status:     200,
+			want:       1,
+			wantMetric: v1alpha1.AutoscalingMetricTypeRecentPushAndPullRequestEvents,
+		},
+		{
+			status:           200,
+			replicasPerEvent: "1",
+			want:             4,
+			wantMetric:       v1alpha1.AutoscalingMetricTypeRecentPushAndPullRequestEvents,
+		},
+		// The older push event is counted within the longer lookback
+		{
+			status:               200,
+			replicasPerEvent:     "1",
+			eventLookbackSeconds: intPtr(3600),
+			want:                 5,
+			wantMetric:           v1alpha1.AutoscalingMetricTypeRecentPushAndPullRequestEvents,
+		},
+		// The events scale ahead of the 3 queued and in-progress workflow runs only when they demand more
+		{
+			status:     200,
+			withQueued: true,
+			want:       3,
+			wantMetric: v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns,
+		},
+		{
+			status:           200,
+			replicasPerEvent: "1",
+			withQueued:       true,
+			want:             4,
+			wantMetric:       v1alpha1.AutoscalingMetricTypeRecentPushAndPullRequestEvents,
+		},
+		{
+			status:           200,
+			replicasPerEvent: "0",
+			err:              "replicasPerEvent must be greater than 0",
+		},
+		{
+			status: 500,
+			err:    "failed to list repository events",
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		log := zap.New(func(o *zap.Options) {
+			o.Development = true
+		})
+
+		scheme := runtime.NewScheme()
+		_ = clientgoscheme.AddToScheme(scheme)
+		_ = v1alpha1.AddToScheme(scheme)
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			server := fake.NewServer(
+				fake.WithListRepositoryWorkflowRunsResponse(200,
+					`{"total_count": 3, "workflow_runs":[{"status":"queued"}, {"status":"in_progress"}, {"status":"in_progress"}]}"`,
+					`{"total_count": 1, "workflow_runs":[{"status":"queued"}]}"`,
+					`{"total_count": 2, "workflow_runs":[{"status":"in_progress"}, {"status":"in_progress"}]}"`,
+				),
+				fake.WithListWorkflowJobsResponse(200, nil),
+				fake.WithListRunnersResponse(200, fake.RunnersListBody),
+				fake.WithListRepositoryEventsResponse(tc.status, events),
+			)
+			defer server.Close()
+			client := newGithubClient(server)
+
+			h := &HorizontalRunnerAutoscalerReconciler{
+				Client:       clientfake.NewFakeClientWithScheme(scheme),
+				Log:          log,
+				Recorder:     record.NewFakeRecorder(10),
+				GitHubClient: client,
+			}
+
+			rd := v1alpha1.RunnerDeployment{
+				Spec: v1alpha1.RunnerDeploymentSpec{
+					Template: v1alpha1.RunnerTemplate{
+						Spec: v1alpha1.RunnerSpec{
+							Repository: "test/valid",
+						},
+					},
+				},
+			}
+
+			metrics := []v1alpha1.MetricSpec{
+				{
+					Type:                 v1alpha1.AutoscalingMetricTypeRecentPushAndPullRequestEvents,
+					ReplicasPerEvent:     tc.replicasPerEvent,
+					EventLookbackSeconds: tc.eventLookbackSeconds,
+				},
+			}
+
+			if tc.withQueued {
+				metrics = append([]v1alpha1.MetricSpec{{Type: v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns}}, metrics...)
+			}
+
+			hra := v1alpha1.HorizontalRunnerAutoscaler{
+				Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+					MinReplicas: intPtr(0),
+					MaxReplicas: intPtr(10),
+					Metrics:     metrics,
+				},
+			}
+
+			got, err := h.determineDesiredReplicas(context.Background(), rd, hra)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error containing %q, got %v", tc.err, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got.Replicas != tc.want {
+				t.Errorf("incorrect desired replicas: want %d, got %d", tc.want, got.Replicas)
+			}
+
+			if got.Type != tc.wantMetric {
+				t.Errorf("unexpected winning metric: want %s, got %s", tc.wantMetric, got.Type)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+)
+
+// metricTypeWeightedSum is the type of the metricResult computed by the WeightedSum MetricAggregation.
+const metricTypeWeightedSum = "WeightedSum"
+
+// weightedMetricResult is the result of a metric summed by the WeightedSum MetricAggregation.
+type weightedMetricResult struct {
+	metric v1alpha1.MetricSpec
+	result *metricResult
+}
+
+// getMetricWeight returns the Weight of the metric, defaulting to 1.
+func getMetricWeight(metric v1alpha1.MetricSpec) (float64, error) {
+	if metric.Weight == "" {
+		return 1, nil
+	}
+
+	weight, err := strconv.ParseFloat(metric.Weight, 64)
+	if err != nil {
+		return 0, errors.New("validating autoscaling metrics: spec.autoscaling.metrics[].weight cannot be parsed into a float64")
+	}
+
+	if weight <= 0 || math.IsInf(weight, 0) || math.IsNaN(weight) {
+		return 0, fmt.Errorf("validating autoscaling metrics: spec.autoscaling.metrics[].weight must be greater than 0, but got %s", metric.Weight)
+	}
+
+	return weight, nil
+}
+
+// sumWeightedMetrics computes the desired replicas by adding up the replicas demanded by the metrics, each multiplied by its weight.
+// The demand before MinReplicas is summed, as MinReplicas would otherwise be counted once per metric.
+func sumWeightedMetrics(hra v1alpha1.HorizontalRunnerAutoscaler, results []weightedMetricResult) (*metricResult, error) {
+	var (
+		sum   float64
+		terms []string
+	)
+
+	for _, r := range results {
+		weight, err := getMetricWeight(r.metric)
+		if err != nil {
+			return nil, err
+		}
+
+		demand := r.result.UncappedReplicas
+		if demand < 0 {
+			demand = 0
+		}
+
+		sum += weight * float64(demand)
+
+		name := r.metric.Name
+		if name == "" {
+			name = r.metric.Type
+		}
+
+		terms = append(terms, fmt.Sprintf("%g*%s(%d)", weight, name, demand))
+	}
+
+	// Subtract a small epsilon before rounding up, like replicasForLoad, so that the floating point error doesn't add a replica
+	desired := int(math.Ceil(sum - 1e-9))
+	if desired < 0 {
+		desired = 0
+	}
+
+	replicas := desired
+
+	if min := *hra.Spec.MinReplicas; replicas < min {
+		replicas = min
+	} else if max := *hra.Spec.MaxReplicas; replicas > max {
+		replicas = max
+	}
+
+	return &metricResult{
+		Type:             metricTypeWeightedSum,
+		Replicas:         replicas,
+		ObservedValue:    fmt.Sprintf("%s = %g", strings.Join(terms, " + "), sum),
+		UncappedReplicas: desired,
+	}, nil
+}
@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	"github.com/summerwind/actions-runner-controller/github"
+)
+
+const (
+	// DefaultReferenceDurationSeconds is the default average duration of the workflow runs weighted 1
+	// by the DurationWeightedQueuedAndInProgressWorkflowRuns metric.
+	DefaultReferenceDurationSeconds = 600
+
+	// workflowDurationCacheTTL is the duration for which the average duration of a workflow is reused.
+	// Durations change far slower than the queue, so this is much longer than the runner list cache TTL.
+	workflowDurationCacheTTL = time.Hour
+
+	// workflowDurationSampleSize is the number of the most recently completed runs averaged for the duration of a workflow.
+	workflowDurationSampleSize = 20
+)
+
+// workflowDurationCache keeps the average durations of the recently completed runs of workflows, shared across
+// HorizontalRunnerAutoscalers, so that weighting the workflow runs doesn't list the completed runs on every reconciliation.
+type workflowDurationCache struct {
+	mu sync.Mutex
+
+	entries map[workflowDurationCacheKey]workflowDurationCacheEntry
+}
+
+type workflowDurationCacheKey struct {
+	owner, repository string
+	workflowID        int64
+}
+
+type workflowDurationCacheEntry struct {
+	// average is zero when the workflow has no completed runs to average
+	average        time.Duration
+	expirationTime time.Time
+}
+
+func (c *workflowDurationCache) get(key workflowDurationCacheKey, now time.Time) (time.Duration, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ent, ok := c.entries[key]
+	if !ok || !now.Before(ent.expirationTime) {
+		return 0, false
+	}
+
+	return ent.average, true
+}
+
+func (c *workflowDurationCache) set(key workflowDurationCacheKey, average time.Duration, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = map[workflowDurationCacheKey]workflowDurationCacheEntry{}
+	}
+
+	// Drop the expired entries so that the cache doesn't grow with the workflows no longer run
+	for k, ent := range c.entries {
+		if !now.Before(ent.expirationTime) {
+			delete(c.entries, k)
+		}
+	}
+
+	c.entries[key] = workflowDurationCacheEntry{average: average, expirationTime: now.Add(workflowDurationCacheTTL)}
+}
+
+func getReferenceDuration(metrics v1alpha1.MetricSpec) time.Duration {
+	if metrics.ReferenceDurationSeconds == nil || *metrics.ReferenceDurationSeconds <= 0 {
+		return DefaultReferenceDurationSeconds * time.Second
+	}
+
+	return time.Duration(*metrics.ReferenceDurationSeconds) * time.Second
+}
+
+// workflowRunWeigher returns the function weighting a workflow run of the repository by the average duration of
+// the recently completed runs of its workflow relative to the reference duration of the metric.
+// A workflow without any completed run, or whose completed runs couldn't be listed, is weighted 1 so that its runs count as usual.
+func (r *HorizontalRunnerAutoscalerReconciler) workflowRunWeigher(ctx context.Context, ghc *github.Client, metrics v1alpha1.MetricSpec, owner, repoName string) func(workflowID int64) float64 {
+	reference := getReferenceDuration(metrics)
+
+	return func(workflowID int64) float64 {
+		if workflowID == 0 {
+			return 1
+		}
+
+		average := r.getWorkflowAverageDuration(ctx, ghc, owner, repoName, workflowID)
+		if average <= 0 {
+			return 1
+		}
+
+		return float64(average) / float64(reference)
+	}
+}
+
+// getWorkflowAverageDuration returns the average duration of the recently completed runs of the workflow,
+// or zero when there's no completed run to average.
+// The duration of a run is measured from its creation to its last update, so it includes the time it was queued for.
+func (r *HorizontalRunnerAutoscalerReconciler) getWorkflowAverageDuration(ctx context.Context, ghc *github.Client, owner, repoName string, workflowID int64) time.Duration {
+	key := workflowDurationCacheKey{owner: owner, repository: repoName, workflowID: workflowID}
+
+	if average, ok := r.workflowDurationCache.get(key, time.Now()); ok {
+		return average
+	}
+
+	start := time.Now()
+	runs, err := ghc.ListCompletedWorkflowRuns(ctx, owner, repoName, workflowID, workflowDurationSampleSize)
+	observeGitHubAPICall(ctx, githubAPICallEndpointListCompletedWorkflowRuns, start, err)
+	if err != nil {
+		// Not cached, so that the next reconciliation retries
+		r.Log.Error(err, "Could not list completed workflow runs. Weighting the workflow runs 1", "owner", owner, "repository", repoName, "workflow_id", workflowID)
+
+		return 0
+	}
+
+	var (
+		total time.Duration
+		n     int
+	)
+
+	for _, run := range runs {
+		if run.CreatedAt == nil || run.UpdatedAt == nil {
+			continue
+		}
+
+		d := run.UpdatedAt.Sub(run.CreatedAt.Time)
+		if d <= 0 {
+			continue
+		}
+
+		total += d
+		n++
+	}
+
+	var average time.Duration
+	if n > 0 {
+		average = total / time.Duration(n)
+	}
+
+	r.workflowDurationCache.set(key, average, time.Now())
+
+	return average
+}
@@ -0,0 +1,213 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+)
+
+// GitHubAPIBudgetPartition is the unit by which GitHubAPIBudget is shared.
+type GitHubAPIBudgetPartition string
+
+const (
+	// GitHubAPIBudgetPartitionHorizontalRunnerAutoscaler gives each HorizontalRunnerAutoscaler a budget of its own.
+	GitHubAPIBudgetPartitionHorizontalRunnerAutoscaler GitHubAPIBudgetPartition = "HorizontalRunnerAutoscaler"
+
+	// GitHubAPIBudgetPartitionOrganization shares a budget among the HorizontalRunnerAutoscalers scaling the runners
+	// of the same enterprise, organization, or owner of the repositories.
+	GitHubAPIBudgetPartitionOrganization GitHubAPIBudgetPartition = "Organization"
+
+	// DefaultGitHubAPIBudgetPartition is the GitHubAPIBudgetPartition used when none is set.
+	DefaultGitHubAPIBudgetPartition = GitHubAPIBudgetPartitionHorizontalRunnerAutoscaler
+)
+
+// ParseGitHubAPIBudgetPartition returns the GitHubAPIBudgetPartition named s, or an error if s isn't one of HorizontalRunnerAutoscaler and Organization.
+func ParseGitHubAPIBudgetPartition(s string) (GitHubAPIBudgetPartition, error) {
+	switch p := GitHubAPIBudgetPartition(s); p {
+	case GitHubAPIBudgetPartitionHorizontalRunnerAutoscaler, GitHubAPIBudgetPartitionOrganization:
+		return p, nil
+	}
+
+	return "", fmt.Errorf("invalid github api budget partition %q: must be one of %s and %s", s, GitHubAPIBudgetPartitionHorizontalRunnerAutoscaler, GitHubAPIBudgetPartitionOrganization)
+}
+
+// GitHubAPIBudget is a token bucket per partition, so that a few HorizontalRunnerAutoscalers making many GitHub API calls,
+// e.g. for many repositories, don't starve the others of the rate limit of the credentials they share.
+//
+// Each GitHub API call made for computing the desired replicas consumes a token after the fact, as the number of calls
+// made by a metric isn't known in advance. A reconciliation proceeds as long as its bucket has a token,
+// so the bucket can go into debt, for which the following reconciliations back off without blocking the workers.
+type GitHubAPIBudget struct {
+	// CallsPerMinute is the rate at which the bucket of each partition refills.
+	CallsPerMinute int
+	// Burst is the capacity of the bucket of each partition.
+	// Zero defaults to CallsPerMinute.
+	Burst int
+	// Partition is the unit by which the budget is shared.
+	// Empty defaults to DefaultGitHubAPIBudgetPartition.
+	Partition GitHubAPIBudgetPartition
+
+	mu sync.Mutex
+
+	buckets map[string]*githubAPIBudgetBucket
+}
+
+type githubAPIBudgetBucket struct {
+	tokens float64
+
+	// updatedAt is the time up to which the tokens are refilled
+	updatedAt time.Time
+}
+
+func (b *GitHubAPIBudget) burst() float64 {
+	if b.Burst > 0 {
+		return float64(b.Burst)
+	}
+
+	return float64(b.CallsPerMinute)
+}
+
+// bucket returns the bucket of the key refilled up to now. b.mu must be held.
+func (b *GitHubAPIBudget) bucket(key string, now time.Time) *githubAPIBudgetBucket {
+	if b.buckets == nil {
+		b.buckets = map[string]*githubAPIBudgetBucket{}
+	}
+
+	bucket, ok := b.buckets[key]
+	if !ok {
+		bucket = &githubAPIBudgetBucket{tokens: b.burst(), updatedAt: now}
+		b.buckets[key] = bucket
+	}
+
+	if elapsed := now.Sub(bucket.updatedAt); elapsed > 0 {
+		bucket.tokens += elapsed.Minutes() * float64(b.CallsPerMinute)
+		if burst := b.burst(); bucket.tokens > burst {
+			bucket.tokens = burst
+		}
+
+		bucket.updatedAt = now
+	}
+
+	return bucket
+}
+
+// Wait returns the duration until the bucket of the key has a token, which is zero when it already has one.
+func (b *GitHubAPIBudget) Wait(key string, now time.Time) time.Duration {
+	if b == nil || b.CallsPerMinute <= 0 {
+		return 0
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	bucket := b.bucket(key, now)
+
+	if bucket.tokens >= 1 {
+		return 0
+	}
+
+	return time.Duration((1 - bucket.tokens) / float64(b.CallsPerMinute) * float64(time.Minute))
+}
+
+// Consume takes a token per GitHub API call from the bucket of the key.
+func (b *GitHubAPIBudget) Consume(key string, calls int, now time.Time) {
+	if b == nil || b.CallsPerMinute <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.bucket(key, now).tokens -= float64(calls)
+}
+
+// partitionKey returns the key of the bucket the GitHub API calls made for the HorizontalRunnerAutoscaler are charged to.
+func (b *GitHubAPIBudget) partitionKey(hra v1alpha1.HorizontalRunnerAutoscaler, rd v1alpha1.RunnerDeployment) string {
+	if b.Partition == GitHubAPIBudgetPartitionOrganization {
+		enterprise, organization, repository, err := getScaleTargetScope(rd)
+		if err == nil {
+			switch {
+			case enterprise != "":
+				return "enterprises/" + enterprise
+			case organization != "":
+				return "orgs/" + organization
+			default:
+				return "orgs/" + strings.SplitN(repository, "/", 2)[0]
+			}
+		}
+	}
+
+	return hra.Namespace + "/" + hra.Name
+}
+
+// githubAPIBudgetAccount is what the GitHub API calls made for a HorizontalRunnerAutoscaler are charged to.
+type githubAPIBudgetAccount struct {
+	budget *GitHubAPIBudget
+	key    string
+
+	namespace, name string
+}
+
+type githubAPIBudgetAccountContextKey struct{}
+
+// withGitHubAPIBudgetAccount returns the context charging the GitHub API calls made with it to the budget of the HorizontalRunnerAutoscaler.
+func (r *HorizontalRunnerAutoscalerReconciler) withGitHubAPIBudgetAccount(ctx context.Context, hra v1alpha1.HorizontalRunnerAutoscaler, rd v1alpha1.RunnerDeployment) context.Context {
+	account := &githubAPIBudgetAccount{
+		budget:    r.GitHubAPIBudget,
+		namespace: hra.Namespace,
+		name:      hra.Name,
+	}
+
+	if r.GitHubAPIBudget != nil {
+		account.key = r.GitHubAPIBudget.partitionKey(hra, rd)
+	}
+
+	return context.WithValue(ctx, githubAPIBudgetAccountContextKey{}, account)
+}
+
+// chargeGitHubAPICall charges the GitHub API call made with the context to the budget of the HorizontalRunnerAutoscaler, if any.
+func chargeGitHubAPICall(ctx context.Context, now time.Time) {
+	account, ok := ctx.Value(githubAPIBudgetAccountContextKey{}).(*githubAPIBudgetAccount)
+	if !ok {
+		return
+	}
+
+	metricGitHubAPIBudgetConsumedCalls.WithLabelValues(account.namespace, account.name).Inc()
+
+	account.budget.Consume(account.key, 1, now)
+}
+
+// githubAPIBudgetExhaustedError is returned when the GitHub API budget of the HorizontalRunnerAutoscaler has no token left.
+// Unlike rateLimitedError, it's the controller throttling itself rather than GitHub rejecting the calls.
+type githubAPIBudgetExhaustedError struct {
+	Key     string
+	RetryAt time.Time
+}
+
+func (e *githubAPIBudgetExhaustedError) Error() string {
+	return fmt.Sprintf("the github api budget of %s is exhausted until %s", e.Key, e.RetryAt.Format(time.RFC3339))
+}
+
+// checkGitHubAPIBudget returns githubAPIBudgetExhaustedError when the budget of the HorizontalRunnerAutoscaler has no token
+// for calling GitHub API. It never waits for the budget to refill, so that the caller backs off without blocking the other reconciliations.
+func (r *HorizontalRunnerAutoscalerReconciler) checkGitHubAPIBudget(hra v1alpha1.HorizontalRunnerAutoscaler, rd v1alpha1.RunnerDeployment) error {
+	b := r.GitHubAPIBudget
+	if b == nil {
+		return nil
+	}
+
+	key := b.partitionKey(hra, rd)
+
+	now := time.Now()
+
+	wait := b.Wait(key, now)
+	if wait <= 0 {
+		return nil
+	}
+
+	return &githubAPIBudgetExhaustedError{Key: key, RetryAt: now.Add(wait)}
+}
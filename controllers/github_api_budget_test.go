@@ -0,0 +1,259 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	"github.com/summerwind/actions-runner-controller/github/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestGitHubAPIBudget(t *testing.T) {
+	t0 := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	type consumption struct {
+		key   string
+		at    time.Duration
+		calls int
+	}
+
+	testcases := []struct {
+		name         string
+		budget       *GitHubAPIBudget
+		consumptions []consumption
+		waitAt       time.Duration
+
+		want time.Duration
+	}{
+		{
+			name:   "no consumptions",
+			budget: &GitHubAPIBudget{CallsPerMinute: 60},
+			want:   0,
+		},
+		{
+			name:         "consuming up to the burst",
+			budget:       &GitHubAPIBudget{CallsPerMinute: 60},
+			consumptions: []consumption{{key: "a", calls: 60}},
+			want:         time.Second,
+		},
+		{
+			name:         "consuming beyond the burst",
+			budget:       &GitHubAPIBudget{CallsPerMinute: 60},
+			consumptions: []consumption{{key: "a", calls: 90}},
+			want:         31 * time.Second,
+		},
+		{
+			name:         "refilled over time",
+			budget:       &GitHubAPIBudget{CallsPerMinute: 60},
+			consumptions: []consumption{{key: "a", calls: 90}},
+			waitAt:       31 * time.Second,
+			want:         0,
+		},
+		{
+			name:         "refilled up to the burst",
+			budget:       &GitHubAPIBudget{CallsPerMinute: 60, Burst: 10},
+			consumptions: []consumption{{key: "a", calls: 10}, {key: "a", at: time.Hour, calls: 11}},
+			waitAt:       time.Hour,
+			want:         2 * time.Second,
+		},
+		{
+			name:         "consumed by another key",
+			budget:       &GitHubAPIBudget{CallsPerMinute: 60},
+			consumptions: []consumption{{key: "b", calls: 90}},
+			want:         0,
+		},
+		{
+			name:         "disabled",
+			budget:       &GitHubAPIBudget{},
+			consumptions: []consumption{{key: "a", calls: 90}},
+			want:         0,
+		},
+		{
+			name:         "nil",
+			consumptions: []consumption{{key: "a", calls: 90}},
+			want:         0,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			for _, c := range tc.consumptions {
+				tc.budget.Consume(c.key, c.calls, t0.Add(c.at))
+			}
+
+			if got := tc.budget.Wait("a", t0.Add(tc.waitAt)); got != tc.want {
+				t.Errorf("unexpected wait: want %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestGitHubAPIBudget_PartitionKey(t *testing.T) {
+	hra := v1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "testhra",
+			Namespace: "default",
+		},
+	}
+
+	testcases := []struct {
+		partition GitHubAPIBudgetPartition
+		runner    v1alpha1.RunnerSpec
+
+		want string
+	}{
+		{
+			runner: v1alpha1.RunnerSpec{Organization: "org1"},
+			want:   "default/testhra",
+		},
+		{
+			partition: GitHubAPIBudgetPartitionOrganization,
+			runner:    v1alpha1.RunnerSpec{Organization: "org1"},
+			want:      "orgs/org1",
+		},
+		{
+			partition: GitHubAPIBudgetPartitionOrganization,
+			runner:    v1alpha1.RunnerSpec{Repository: "owner1/repo1"},
+			want:      "orgs/owner1",
+		},
+		{
+			partition: GitHubAPIBudgetPartitionOrganization,
+			runner:    v1alpha1.RunnerSpec{Enterprise: "enterprise1"},
+			want:      "enterprises/enterprise1",
+		},
+		// The RunnerDeployment with no scope has nothing to share the budget with
+		{
+			partition: GitHubAPIBudgetPartitionOrganization,
+			want:      "default/testhra",
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			b := &GitHubAPIBudget{CallsPerMinute: 60, Partition: tc.partition}
+
+			rd := v1alpha1.RunnerDeployment{
+				Spec: v1alpha1.RunnerDeploymentSpec{
+					Template: v1alpha1.RunnerTemplate{
+						Spec: tc.runner,
+					},
+				},
+			}
+
+			if got := b.partitionKey(hra, rd); got != tc.want {
+				t.Errorf("unexpected partition key: want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestParseGitHubAPIBudgetPartition(t *testing.T) {
+	for _, s := range []string{"HorizontalRunnerAutoscaler", "Organization"} {
+		if got, err := ParseGitHubAPIBudgetPartition(s); err != nil {
+			t.Errorf("unexpected error parsing %q: %v", s, err)
+		} else if string(got) != s {
+			t.Errorf("unexpected partition: want %q, got %q", s, got)
+		}
+	}
+
+	if _, err := ParseGitHubAPIBudgetPartition("Repository"); err == nil {
+		t.Error("expected error parsing Repository, got none")
+	}
+}
+
+func TestDetermineDesiredReplicas_GitHubAPIBudget(t *testing.T) {
+	h := newTestReconciler(t, nil, nil, withGitHubResponses(
+		fake.WithListRunnersResponse(200, `{"total_count": 1, "runners": [{"id": 1, "name": "test1", "os": "linux", "status": "online", "busy": true}]}`),
+	))
+	h.RunnerListCacheTTL = -1
+	h.GitHubAPIBudget = &GitHubAPIBudget{CallsPerMinute: 1}
+
+	rd := newTestRD(intPtr(1))
+
+	hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+		MinReplicas: intPtr(1),
+		MaxReplicas: intPtr(10),
+		Metrics:     []v1alpha1.MetricSpec{{Type: v1alpha1.AutoscalingMetricTypePercentageRunnersBusy}},
+	})
+	hra.Name = "testhra-budget"
+
+	if _, err := h.determineDesiredReplicas(context.Background(), *rd, *hra); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := testutil.ToFloat64(metricGitHubAPIBudgetConsumedCalls.WithLabelValues("default", "testhra-budget")); got != 1 {
+		t.Errorf("unexpected consumed calls: want 1, got %v", got)
+	}
+
+	// The budget of 1 call per minute is exhausted by the previous computation
+	_, err := h.determineDesiredReplicas(context.Background(), *rd, *hra)
+
+	var budgetExhausted *githubAPIBudgetExhaustedError
+	if !errors.As(err, &budgetExhausted) {
+		t.Fatalf("expected githubAPIBudgetExhaustedError, got %v", err)
+	}
+
+	if got := testutil.ToFloat64(metricGitHubAPIBudgetConsumedCalls.WithLabelValues("default", "testhra-budget")); got != 1 {
+		t.Errorf("unexpected consumed calls after the budget is exhausted: want 1, got %v", got)
+	}
+}
+
+func TestReconcile_GitHubAPIBudgetExhausted(t *testing.T) {
+	rd := newTestRD(intPtr(3))
+
+	hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+		MinReplicas: intPtr(1),
+		MaxReplicas: intPtr(5),
+		Metrics:     []v1alpha1.MetricSpec{{Type: v1alpha1.AutoscalingMetricTypePercentageRunnersBusy}},
+	})
+
+	budget := &GitHubAPIBudget{CallsPerMinute: 1}
+	budget.Consume("default/testhra", 2, time.Now())
+
+	recorder := record.NewFakeRecorder(10)
+
+	h := newTestReconciler(t, rd, hra)
+	h.Recorder = recorder
+	h.RunnerListCacheTTL = -1
+	h.GitHubAPIBudget = budget
+
+	res := reconcileTestHRA(t, h)
+
+	// The budget in debt by 2 calls refills in 2 minutes
+	if res.RequeueAfter < time.Minute || res.RequeueAfter > 2*time.Minute {
+		t.Errorf("unexpected requeueAfter: %s", res.RequeueAfter)
+	}
+
+	select {
+	case e := <-recorder.Events:
+		if !strings.HasPrefix(e, "Normal GitHubAPIBudgetExhausted ") {
+			t.Errorf("unexpected event: %s", e)
+		}
+	default:
+		t.Errorf("expected GitHubAPIBudgetExhausted event, got none")
+	}
+
+	gotRD := getTestRD(t, h)
+
+	if *gotRD.Spec.Replicas != 3 {
+		t.Errorf("unexpected rd.Spec.Replicas: want 3, got %d", *gotRD.Spec.Replicas)
+	}
+
+	gotHRA := getTestHRA(t, h)
+
+	// The controller throttling itself isn't a failure of the HRA
+	if gotHRA.Status.LastError != nil || len(gotHRA.Status.Conditions) != 0 {
+		t.Errorf("unexpected status: lastError=%v conditions=%+v", gotHRA.Status.LastError, gotHRA.Status.Conditions)
+	}
+}
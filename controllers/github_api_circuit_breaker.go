@@ -0,0 +1,198 @@
+package controllers
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultGitHubAPICircuitBreakerThreshold is the default number of consecutive GitHub API failures
+	// within the window that opens the circuit breaker.
+	DefaultGitHubAPICircuitBreakerThreshold = 10
+
+	// DefaultGitHubAPICircuitBreakerWindow is the default duration within which the consecutive failures are counted.
+	DefaultGitHubAPICircuitBreakerWindow = 5 * time.Minute
+
+	// DefaultGitHubAPICircuitBreakerCooldown is the default duration for which the open circuit breaker skips GitHub API calls
+	// before letting a probe through.
+	DefaultGitHubAPICircuitBreakerCooldown = 5 * time.Minute
+)
+
+// GitHubAPICircuitBreakerState is the state of GitHubAPICircuitBreaker.
+type GitHubAPICircuitBreakerState string
+
+const (
+	// GitHubAPICircuitBreakerClosed lets every reconciliation call GitHub API.
+	GitHubAPICircuitBreakerClosed GitHubAPICircuitBreakerState = "Closed"
+	// GitHubAPICircuitBreakerOpen skips GitHub API calls until the cooldown elapses.
+	GitHubAPICircuitBreakerOpen GitHubAPICircuitBreakerState = "Open"
+	// GitHubAPICircuitBreakerHalfOpen lets a single reconciliation probe GitHub API, whose outcome closes or reopens the breaker.
+	GitHubAPICircuitBreakerHalfOpen GitHubAPICircuitBreakerState = "HalfOpen"
+)
+
+// GitHubAPICircuitBreaker is shared across the HorizontalRunnerAutoscalers, so that a GitHub outage noticed by some of them
+// stops all of them from calling GitHub API until it recovers. Otherwise every reconciliation keeps failing and requeueing
+// during the outage, and the burst of calls made once GitHub recovers risks hitting the rate limit.
+//
+// Only the failures telling GitHub API is unreachable count. Rate limit errors are handled by the backoff of each HorizontalRunnerAutoscaler.
+type GitHubAPICircuitBreaker struct {
+	// Threshold is the number of consecutive failures within Window that opens the breaker.
+	// Zero defaults to DefaultGitHubAPICircuitBreakerThreshold.
+	Threshold int
+	// Window is the duration within which the consecutive failures are counted.
+	// Zero defaults to DefaultGitHubAPICircuitBreakerWindow.
+	Window time.Duration
+	// Cooldown is the duration for which the open breaker skips GitHub API calls before going half-open.
+	// Zero defaults to DefaultGitHubAPICircuitBreakerCooldown.
+	Cooldown time.Duration
+
+	mu sync.Mutex
+
+	state GitHubAPICircuitBreakerState
+
+	// failures are the times of the consecutive failures since the last success, within the window
+	failures []time.Time
+
+	// openedAt is the time the breaker opened, or the time the probe was let through while half-open
+	openedAt time.Time
+}
+
+// Allow returns true when a reconciliation can call GitHub API at now.
+// The first call after the cooldown of the open breaker makes it half-open and is let through as the probe, for which probe is true.
+// Otherwise retryAt is the time the breaker lets the next probe through.
+// While half-open, a probe that didn't record its outcome is given up after another cooldown so that the breaker doesn't get stuck.
+func (b *GitHubAPICircuitBreaker) Allow(now time.Time) (allowed, probe bool, retryAt time.Time) {
+	if b == nil {
+		return true, false, time.Time{}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == "" || b.state == GitHubAPICircuitBreakerClosed {
+		return true, false, time.Time{}
+	}
+
+	retryAt = b.openedAt.Add(b.cooldown())
+
+	if now.Before(retryAt) {
+		return false, false, retryAt
+	}
+
+	b.setState(GitHubAPICircuitBreakerHalfOpen)
+	b.openedAt = now
+
+	return true, true, time.Time{}
+}
+
+// RecordSuccess records that a GitHub API call succeeded, which closes the breaker.
+// It returns true when the breaker was closed by this call.
+func (b *GitHubAPICircuitBreaker) RecordSuccess(now time.Time) (closed bool) {
+	if b == nil {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	closed = b.state == GitHubAPICircuitBreakerOpen || b.state == GitHubAPICircuitBreakerHalfOpen
+
+	b.failures = nil
+	b.setState(GitHubAPICircuitBreakerClosed)
+
+	return closed
+}
+
+// RecordFailure records that a GitHub API call failed at now, which opens the breaker once Threshold consecutive failures
+// are made within Window, or right away when the probe of the half-open breaker failed.
+// It returns true when the breaker was opened by this call.
+func (b *GitHubAPICircuitBreaker) RecordFailure(now time.Time) (opened bool) {
+	if b == nil {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case GitHubAPICircuitBreakerOpen:
+		// Calls let through before the breaker opened can still fail afterwards, which shouldn't extend the cooldown
+		return false
+	case GitHubAPICircuitBreakerHalfOpen:
+		b.failures = nil
+		b.openedAt = now
+		b.setState(GitHubAPICircuitBreakerOpen)
+
+		return true
+	}
+
+	since := now.Add(-b.window())
+
+	var failures []time.Time
+
+	for _, t := range b.failures {
+		if t.After(since) {
+			failures = append(failures, t)
+		}
+	}
+
+	b.failures = append(failures, now)
+
+	if len(b.failures) < b.threshold() {
+		b.setState(GitHubAPICircuitBreakerClosed)
+
+		return false
+	}
+
+	b.failures = nil
+	b.openedAt = now
+	b.setState(GitHubAPICircuitBreakerOpen)
+
+	return true
+}
+
+// State returns the current state of the breaker.
+func (b *GitHubAPICircuitBreaker) State() GitHubAPICircuitBreakerState {
+	if b == nil {
+		return GitHubAPICircuitBreakerClosed
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == "" {
+		return GitHubAPICircuitBreakerClosed
+	}
+
+	return b.state
+}
+
+func (b *GitHubAPICircuitBreaker) setState(state GitHubAPICircuitBreakerState) {
+	b.state = state
+
+	observeGitHubAPICircuitBreakerState(state)
+}
+
+func (b *GitHubAPICircuitBreaker) threshold() int {
+	if b.Threshold <= 0 {
+		return DefaultGitHubAPICircuitBreakerThreshold
+	}
+
+	return b.Threshold
+}
+
+func (b *GitHubAPICircuitBreaker) window() time.Duration {
+	if b.Window <= 0 {
+		return DefaultGitHubAPICircuitBreakerWindow
+	}
+
+	return b.Window
+}
+
+func (b *GitHubAPICircuitBreaker) cooldown() time.Duration {
+	if b.Cooldown <= 0 {
+		return DefaultGitHubAPICircuitBreakerCooldown
+	}
+
+	return b.Cooldown
+}
@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGitHubAPICircuitBreaker(t *testing.T) {
+	t0 := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	type call struct {
+		at      time.Duration
+		succeed bool
+	}
+
+	testcases := []struct {
+		name    string
+		calls   []call
+		allowAt time.Duration
+
+		wantAllowed bool
+		wantProbe   bool
+		wantState   GitHubAPICircuitBreakerState
+	}{
+		{
+			name:        "no calls",
+			allowAt:     time.Minute,
+			wantAllowed: true,
+			wantState:   GitHubAPICircuitBreakerClosed,
+		},
+		{
+			name:        "failing below the threshold",
+			calls:       []call{{at: 0}, {at: time.Minute}},
+			allowAt:     2 * time.Minute,
+			wantAllowed: true,
+			wantState:   GitHubAPICircuitBreakerClosed,
+		},
+		{
+			name:      "failing up to the threshold",
+			calls:     []call{{at: 0}, {at: time.Minute}, {at: 2 * time.Minute}},
+			allowAt:   3 * time.Minute,
+			wantState: GitHubAPICircuitBreakerOpen,
+		},
+		{
+			name:        "failing up to the threshold beyond the window",
+			calls:       []call{{at: 0}, {at: 3 * time.Minute}, {at: 6 * time.Minute}},
+			allowAt:     7 * time.Minute,
+			wantAllowed: true,
+			wantState:   GitHubAPICircuitBreakerClosed,
+		},
+		{
+			name:        "failures interrupted by a success",
+			calls:       []call{{at: 0}, {at: time.Minute}, {at: 90 * time.Second, succeed: true}, {at: 2 * time.Minute}},
+			allowAt:     3 * time.Minute,
+			wantAllowed: true,
+			wantState:   GitHubAPICircuitBreakerClosed,
+		},
+		{
+			name:        "probing after the cooldown",
+			calls:       []call{{at: 0}, {at: time.Minute}, {at: 2 * time.Minute}},
+			allowAt:     12 * time.Minute,
+			wantAllowed: true,
+			wantProbe:   true,
+			wantState:   GitHubAPICircuitBreakerHalfOpen,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			b := &GitHubAPICircuitBreaker{Threshold: 3, Window: 5 * time.Minute, Cooldown: 10 * time.Minute}
+
+			for _, c := range tc.calls {
+				if c.succeed {
+					b.RecordSuccess(t0.Add(c.at))
+				} else {
+					b.RecordFailure(t0.Add(c.at))
+				}
+			}
+
+			allowed, probe, retryAt := b.Allow(t0.Add(tc.allowAt))
+
+			if allowed != tc.wantAllowed {
+				t.Errorf("unexpected allowed: want %v, got %v", tc.wantAllowed, allowed)
+			}
+
+			if probe != tc.wantProbe {
+				t.Errorf("unexpected probe: want %v, got %v", tc.wantProbe, probe)
+			}
+
+			if !allowed && !retryAt.After(t0.Add(tc.allowAt)) {
+				t.Errorf("unexpected retryAt: %s", retryAt)
+			}
+
+			if got := b.State(); got != tc.wantState {
+				t.Errorf("unexpected state: want %s, got %s", tc.wantState, got)
+			}
+		})
+	}
+}
+
+func TestGitHubAPICircuitBreaker_HalfOpen(t *testing.T) {
+	t0 := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	b := &GitHubAPICircuitBreaker{Threshold: 1, Cooldown: 10 * time.Minute}
+
+	if opened := b.RecordFailure(t0); !opened {
+		t.Fatalf("expected the breaker to open")
+	}
+
+	if allowed, probe, _ := b.Allow(t0.Add(10 * time.Minute)); !allowed || !probe {
+		t.Fatalf("expected the probe to be let through")
+	}
+
+	// Only the probe is let through until its outcome is recorded
+	if allowed, _, _ := b.Allow(t0.Add(11 * time.Minute)); allowed {
+		t.Errorf("unexpected call let through while probing")
+	}
+
+	if opened := b.RecordFailure(t0.Add(11 * time.Minute)); !opened {
+		t.Fatalf("expected the failed probe to reopen the breaker")
+	}
+
+	if allowed, _, retryAt := b.Allow(t0.Add(12 * time.Minute)); allowed || !retryAt.Equal(t0.Add(21*time.Minute)) {
+		t.Errorf("expected the cooldown to restart from the failed probe, got allowed=%v retryAt=%s", allowed, retryAt)
+	}
+
+	if allowed, probe, _ := b.Allow(t0.Add(21 * time.Minute)); !allowed || !probe {
+		t.Fatalf("expected the probe to be let through")
+	}
+
+	if closed := b.RecordSuccess(t0.Add(21 * time.Minute)); !closed {
+		t.Fatalf("expected the succeeded probe to close the breaker")
+	}
+
+	if allowed, _, _ := b.Allow(t0.Add(21 * time.Minute)); !allowed {
+		t.Errorf("expected calls to be let through once closed")
+	}
+}
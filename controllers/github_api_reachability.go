@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	gogithub "github.com/google/go-github/v33/github"
+)
+
+const (
+	// DefaultGitHubAPIStalenessWindow is the default duration for which GitHub API calls can keep failing
+	// before the controller is reported as not ready.
+	DefaultGitHubAPIStalenessWindow = 30 * time.Minute
+)
+
+// GitHubAPIReachability tracks the outcomes of GitHub API calls made for autoscaling, so that the readiness probe
+// fails when GitHub API has been unreachable for too long, e.g. due to an invalid token or a network issue.
+//
+// It's meant only for the readiness check. Use it for the liveness check and a GitHub outage would result in restarting the controller.
+type GitHubAPIReachability struct {
+	// StalenessWindow is the duration since the last successful call after which failing calls make the controller not ready.
+	// Zero defaults to DefaultGitHubAPIStalenessWindow.
+	StalenessWindow time.Duration
+
+	mu sync.Mutex
+
+	// lastSuccessTime is the time of the last successful call
+	lastSuccessTime time.Time
+
+	// failingSince is the time of the first failed call since the last successful call, or zero when the last call succeeded.
+	failingSince time.Time
+}
+
+// RecordSuccess records that a GitHub API call succeeded at now.
+func (g *GitHubAPIReachability) RecordSuccess(now time.Time) {
+	if g == nil {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.lastSuccessTime = now
+	g.failingSince = time.Time{}
+}
+
+// RecordFailure records that a GitHub API call failed at now.
+func (g *GitHubAPIReachability) RecordFailure(now time.Time) {
+	if g == nil {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.failingSince.IsZero() {
+		g.failingSince = now
+	}
+}
+
+// Check is the healthz.Checker that fails when no call has succeeded within the staleness window while calls are failing.
+// It never fails before any call is made, so that the controller is ready while there are no HorizontalRunnerAutoscalers.
+func (g *GitHubAPIReachability) Check(_ *http.Request) error {
+	return g.check(time.Now())
+}
+
+func (g *GitHubAPIReachability) check(now time.Time) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	window := g.StalenessWindow
+	if window <= 0 {
+		window = DefaultGitHubAPIStalenessWindow
+	}
+
+	if g.failingSince.IsZero() {
+		return nil
+	}
+
+	since := g.lastSuccessTime
+	if since.IsZero() {
+		since = g.failingSince
+	}
+
+	if now.Sub(since) <= window {
+		return nil
+	}
+
+	if g.lastSuccessTime.IsZero() {
+		return fmt.Errorf("github api calls have been failing since %s without any success", g.failingSince.Format(time.RFC3339))
+	}
+
+	return fmt.Errorf("github api calls have been failing since %s, and the last successful call was at %s", g.failingSince.Format(time.RFC3339), g.lastSuccessTime.Format(time.RFC3339))
+}
+
+// isGitHubAPIUnreachable returns true when err indicates that GitHub API couldn't be reached or refused the request,
+// as opposed to e.g. a validation error of the HorizontalRunnerAutoscaler.
+// Rate limit errors are not included as GitHub API is reachable in that case.
+func isGitHubAPIUnreachable(err error) bool {
+	var (
+		rle  *gogithub.RateLimitError
+		arle *gogithub.AbuseRateLimitError
+		er   *gogithub.ErrorResponse
+		ue   *url.Error
+	)
+
+	if errors.As(err, &rle) || errors.As(err, &arle) {
+		return false
+	}
+
+	return errors.As(err, &er) || errors.As(err, &ue)
+}
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	gogithub "github.com/google/go-github/v33/github"
+)
+
+func TestGitHubAPIReachability(t *testing.T) {
+	t0 := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	type call struct {
+		at      time.Duration
+		succeed bool
+	}
+
+	testcases := []struct {
+		name    string
+		calls   []call
+		checkAt time.Duration
+		wantErr bool
+	}{
+		{
+			name:    "no calls",
+			checkAt: time.Hour,
+		},
+		{
+			name:    "succeeded",
+			calls:   []call{{at: 0, succeed: true}},
+			checkAt: time.Hour,
+		},
+		{
+			name:    "failing within the window",
+			calls:   []call{{at: 0, succeed: true}, {at: 10 * time.Minute}},
+			checkAt: 20 * time.Minute,
+		},
+		{
+			name:    "failing beyond the window",
+			calls:   []call{{at: 0, succeed: true}, {at: 10 * time.Minute}},
+			checkAt: 40 * time.Minute,
+			wantErr: true,
+		},
+		{
+			name:    "failing beyond the window without any success",
+			calls:   []call{{at: 0}, {at: 10 * time.Minute}},
+			checkAt: 40 * time.Minute,
+			wantErr: true,
+		},
+		{
+			name:    "recovered",
+			calls:   []call{{at: 0}, {at: 35 * time.Minute, succeed: true}},
+			checkAt: 40 * time.Minute,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			g := &GitHubAPIReachability{StalenessWindow: 30 * time.Minute}
+
+			for _, c := range tc.calls {
+				if c.succeed {
+					g.RecordSuccess(t0.Add(c.at))
+				} else {
+					g.RecordFailure(t0.Add(c.at))
+				}
+			}
+
+			err := g.check(t0.Add(tc.checkAt))
+			if tc.wantErr && err == nil {
+				t.Errorf("expected error, got none")
+			} else if !tc.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestIsGitHubAPIUnreachable(t *testing.T) {
+	testcases := []struct {
+		err  error
+		want bool
+	}{
+		{err: &url.Error{Op: "Get", URL: "https://api.github.com", Err: errors.New("connection refused")}, want: true},
+		{err: fmt.Errorf("listing runners: %w", &gogithub.ErrorResponse{Response: &http.Response{StatusCode: 401}}), want: true},
+		{err: &gogithub.RateLimitError{}, want: false},
+		{err: &gogithub.AbuseRateLimitError{}, want: false},
+		{err: errors.New("validating autoscaling metrics: invalid"), want: false},
+	}
+
+	for i, tc := range testcases {
+		if got := isGitHubAPIUnreachable(tc.err); got != tc.want {
+			t.Errorf("%d: unexpected result for %v: want %v, got %v", i, tc.err, tc.want, got)
+		}
+	}
+}
@@ -39,8 +39,20 @@ import (
 
 const (
 	scaleTargetKey = "scaleTarget"
+
+	pingEventType = "ping"
 )
 
+// SupportedGitHubWebhookEvents are the types of GitHub webhook events the webhook-based autoscaler can scale on.
+// The ping event is always handled, so it isn't included.
+var SupportedGitHubWebhookEvents = []string{
+	checkRunEventType,
+	checkSuiteEventType,
+	pullRequestEventType,
+	pushEventType,
+	workflowJobEventType,
+}
+
 // HorizontalRunnerAutoscalerGitHubWebhook autoscales a HorizontalRunnerAutoscaler and the RunnerDeployment on each
 // GitHub Webhook received
 type HorizontalRunnerAutoscalerGitHubWebhook struct {
@@ -58,6 +70,31 @@ type HorizontalRunnerAutoscalerGitHubWebhook struct {
 	// Set to empty for letting it watch for all namespaces.
 	WatchNamespace string
 	Name           string
+
+	// WorkflowJobCapacityReservationTTL is the duration of a capacity reservation added on a queued workflow_job event,
+	// used when the matching scale-up trigger doesn't specify its duration.
+	// Defaults to DefaultWorkflowJobCapacityReservationTTL.
+	WorkflowJobCapacityReservationTTL time.Duration
+
+	// EnabledEvents are the types of GitHub webhook events to scale on, out of SupportedGitHubWebhookEvents.
+	// The events of the other types are acknowledged and ignored.
+	// Set to empty for enabling all the supported events.
+	EnabledEvents []string
+}
+
+// isEventEnabled returns true when the webhook events of the type are to be handled.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) isEventEnabled(webhookType string) bool {
+	if webhookType == pingEventType || len(autoscaler.EnabledEvents) == 0 {
+		return true
+	}
+
+	for _, e := range autoscaler.EnabledEvents {
+		if e == webhookType {
+			return true
+		}
+	}
+
+	return false
 }
 
 func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Reconcile(request reconcile.Request) (reconcile.Result, error) {
@@ -102,6 +139,16 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.Respons
 		if err != nil {
 			autoscaler.Log.Error(err, "error validating request body")
 
+			// This is most likely a signature mismatch which the sender is responsible for
+			ok = true
+
+			w.WriteHeader(http.StatusBadRequest)
+
+			msg := err.Error()
+			if written, err := w.Write([]byte(msg)); err != nil {
+				autoscaler.Log.Error(err, "failed writing http error response", "msg", msg, "written", written)
+			}
+
 			return
 		}
 	} else {
@@ -114,7 +161,28 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.Respons
 	}
 
 	webhookType := gogithub.WebHookType(r)
-	event, err := gogithub.ParseWebHook(webhookType, payload)
+
+	if !autoscaler.isEventEnabled(webhookType) {
+		ok = true
+
+		w.WriteHeader(http.StatusOK)
+
+		msg := fmt.Sprintf("ignored %s event as it's not enabled", webhookType)
+
+		if written, err := w.Write([]byte(msg)); err != nil {
+			autoscaler.Log.Error(err, "failed writing http response", "msg", msg, "written", written)
+		}
+
+		return
+	}
+
+	var event interface{}
+
+	if webhookType == workflowJobEventType {
+		event, err = parseWorkflowJobEvent(payload)
+	} else {
+		event, err = gogithub.ParseWebHook(webhookType, payload)
+	}
 	if err != nil {
 		var s string
 		if payload != nil {
@@ -162,6 +230,32 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.Respons
 			e.Repo.Owner.GetType(),
 			autoscaler.MatchCheckRunEvent(e),
 		)
+	case *gogithub.CheckSuiteEvent:
+		target, err = autoscaler.getScaleUpTarget(
+			context.TODO(),
+			log,
+			e.Repo.GetName(),
+			e.Repo.Owner.GetLogin(),
+			e.Repo.Owner.GetType(),
+			autoscaler.MatchCheckSuiteEvent(e),
+		)
+	case *workflowJobEvent:
+		switch e.GetAction() {
+		case workflowJobActionQueued, workflowJobActionCompleted:
+			target, err = autoscaler.getJobScaleUpTarget(context.TODO(), log, e)
+		default:
+			ok = true
+
+			w.WriteHeader(http.StatusOK)
+
+			msg := fmt.Sprintf("ignored workflow_job event with action %q", e.GetAction())
+
+			if written, err := w.Write([]byte(msg)); err != nil {
+				log.Error(err, "failed writing http response", "msg", msg, "written", written)
+			}
+
+			return
+		}
 	case *gogithub.PingEvent:
 		ok = true
 
@@ -183,7 +277,7 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.Respons
 	}
 
 	if err != nil {
-		log.Error(err, "handling check_run event")
+		log.Error(err, "handling event")
 
 		return
 	}
@@ -204,7 +298,20 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.Respons
 		return
 	}
 
-	if err := autoscaler.tryScaleUp(context.TODO(), target); err != nil {
+	amount := 1
+
+	if e, isJob := event.(*workflowJobEvent); isJob && target.coldStart {
+		amount, err = autoscaler.tryScaleFromZero(context.TODO(), target, e)
+	} else if isJob {
+		amount, err = autoscaler.tryScaleForWorkflowJob(context.TODO(), target, e)
+	} else if reservationID, completed, isCheck := getCheckCapacityReservation(event, target); isCheck {
+		amount, err = autoscaler.tryScaleForCheck(context.TODO(), target, reservationID, completed)
+	} else {
+		// The delivery ID is kept as is on redelivery, which makes it the natural ID of the reservation
+		err = autoscaler.tryScaleUp(context.TODO(), target, r.Header.Get("X-GitHub-Delivery"))
+	}
+
+	if err != nil {
 		log.Error(err, "could not scale up")
 
 		return
@@ -214,7 +321,7 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.Respons
 
 	w.WriteHeader(http.StatusOK)
 
-	msg := fmt.Sprintf("scaled %s by 1", target.Name)
+	msg := fmt.Sprintf("scaled %s by %d", target.Name, amount)
 
 	autoscaler.Log.Info(msg)
 
@@ -277,6 +384,9 @@ func matchTriggerConditionAgainstEvent(types []string, eventAction *string) bool
 type ScaleTarget struct {
 	v1alpha1.HorizontalRunnerAutoscaler
 	v1alpha1.ScaleUpTrigger
+
+	// coldStart is true when the target is matched only by its ColdStartReservation rather than a scale-up trigger
+	coldStart bool
 }
 
 func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) searchScaleTargets(hras []v1alpha1.HorizontalRunnerAutoscaler, f func(v1alpha1.ScaleUpTrigger) bool) []ScaleTarget {
@@ -287,6 +397,11 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) searchScaleTargets(hr
 			continue
 		}
 
+		// Capacity reservations would never be applied as the reconciler doesn't scale the other kinds
+		if kind := hra.Spec.ScaleTargetRef.Kind; kind != "" && kind != scaleTargetKindRunnerDeployment {
+			continue
+		}
+
 		for _, scaleUpTrigger := range hra.Spec.ScaleUpTriggers {
 			if !f(scaleUpTrigger) {
 				continue
@@ -310,10 +425,15 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getScaleTarget(ctx co
 
 	targets := autoscaler.searchScaleTargets(hras, f)
 
+	return autoscaler.selectScaleTarget(targets), nil
+}
+
+// selectScaleTarget returns the only scale target, or nil when there's none or it's ambiguous.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) selectScaleTarget(targets []ScaleTarget) *ScaleTarget {
 	n := len(targets)
 
 	if n == 0 {
-		return nil, nil
+		return nil
 	}
 
 	if n > 1 {
@@ -330,10 +450,10 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getScaleTarget(ctx co
 				"or update Repository or Organization fields in your RunnerDeployment resources to fix the ambiguity.",
 			"scaleTargets", strings.Join(scaleTargetIDs, ","))
 
-		return nil, nil
+		return nil
 	}
 
-	return &targets[0], nil
+	return &targets[0]
 }
 
 func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getScaleUpTarget(ctx context.Context, log logr.Logger, repo, owner, ownerType string, f func(v1alpha1.ScaleUpTrigger) bool) (*ScaleTarget, error) {
@@ -366,7 +486,9 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getScaleUpTarget(ctx
 	return nil, nil
 }
 
-func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) tryScaleUp(ctx context.Context, target *ScaleTarget) error {
+// tryScaleUp adds a capacity reservation for the scale up trigger. When reservationID is not empty and a reservation with the ID
+// already exists, the existing one is extended to the new expiration time instead, so that a redelivered event isn't counted twice.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) tryScaleUp(ctx context.Context, target *ScaleTarget, reservationID string) error {
 	if target == nil {
 		return nil
 	}
@@ -381,12 +503,39 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) tryScaleUp(ctx contex
 		amount = target.ScaleUpTrigger.Amount
 	}
 
-	capacityReservations := getValidCapacityReservations(copy)
+	capacityReservations := getRetainedCapacityReservations(copy)
 
-	copy.Spec.CapacityReservations = append(capacityReservations, v1alpha1.CapacityReservation{
-		ExpirationTime: metav1.Time{Time: time.Now().Add(target.ScaleUpTrigger.Duration.Duration)},
-		Replicas:       amount,
-	})
+	expirationTime := metav1.Time{Time: time.Now().Add(target.ScaleUpTrigger.Duration.Duration)}
+
+	var extended bool
+
+	if reservationID != "" {
+		for i := range capacityReservations {
+			r := &capacityReservations[i]
+
+			if r.ReservationID != reservationID {
+				continue
+			}
+
+			if r.ExpirationTime.Before(&expirationTime) {
+				r.ExpirationTime = expirationTime
+			}
+
+			extended = true
+		}
+	}
+
+	if extended {
+		log.V(1).Info("Extending the existing capacity reservation for the redelivered event", "reservationID", reservationID)
+	} else {
+		capacityReservations = append(capacityReservations, v1alpha1.CapacityReservation{
+			ExpirationTime: expirationTime,
+			Replicas:       amount,
+			ReservationID:  reservationID,
+		})
+	}
+
+	copy.Spec.CapacityReservations = capacityReservations
 
 	if err := autoscaler.Client.Update(ctx, copy); err != nil {
 		log.Error(err, "Failed to update horizontalrunnerautoscaler resource")
@@ -402,7 +551,11 @@ func getValidCapacityReservations(autoscaler *v1alpha1.HorizontalRunnerAutoscale
 
 	now := time.Now()
 
-	for _, reservation := range autoscaler.Spec.CapacityReservations {
+	// The reservations specified by duration are resolved here too, so that they aren't dropped
+	// as expired when a webhook event is handled before the reservations are reconciled.
+	reservations, _ := resolveCapacityReservationDurations(autoscaler.Spec.CapacityReservations, now)
+
+	for _, reservation := range reservations {
 		if reservation.ExpirationTime.Time.After(now) {
 			capacityReservations = append(capacityReservations, reservation)
 		}
@@ -411,6 +564,55 @@ func getValidCapacityReservations(autoscaler *v1alpha1.HorizontalRunnerAutoscale
 	return capacityReservations
 }
 
+// getRetainedCapacityReservations returns the capacity reservations to keep on updating them for a webhook event.
+// With HoldCapacityReservationsWhileBusy, the expired ones are kept as is, as only the reconciler knows
+// whether they're still held by busy runners, and prunes them otherwise.
+func getRetainedCapacityReservations(autoscaler *v1alpha1.HorizontalRunnerAutoscaler) []v1alpha1.CapacityReservation {
+	if !autoscaler.Spec.HoldCapacityReservationsWhileBusy {
+		return getValidCapacityReservations(autoscaler)
+	}
+
+	reservations, _ := resolveCapacityReservationDurations(autoscaler.Spec.CapacityReservations, time.Now())
+
+	return reservations
+}
+
+// getCapacityReservationReplicas returns the total replicas of the capacity reservations.
+// The reservations for the same workflow job or with the same ReservationID are counted only once, by the one expiring last.
+// The reservations are sorted beforehand, so that the total doesn't depend on their order.
+func getCapacityReservationReplicas(reservations []v1alpha1.CapacityReservation) int {
+	reservations = sortCapacityReservations(reservations)
+
+	lastByJob := map[int64]int{}
+	lastByID := map[string]int{}
+
+	for i, r := range reservations {
+		if r.WorkflowJobID != 0 {
+			lastByJob[r.WorkflowJobID] = i
+		}
+
+		if r.ReservationID != "" {
+			lastByID[r.ReservationID] = i
+		}
+	}
+
+	var total int
+
+	for i, r := range reservations {
+		if r.WorkflowJobID != 0 && lastByJob[r.WorkflowJobID] != i {
+			continue
+		}
+
+		if r.ReservationID != "" && lastByID[r.ReservationID] != i {
+			continue
+		}
+
+		total += r.Replicas
+	}
+
+	return total
+}
+
 func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) SetupWithManager(mgr ctrl.Manager) error {
 	name := "webhookbasedautoscaler"
 	if autoscaler.Name != "" {
@@ -1,10 +1,20 @@
 package controllers
 
 import (
+	"context"
+	"fmt"
+
 	"github.com/google/go-github/v33/github"
 	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
 )
 
+const (
+	checkRunEventType = "check_run"
+
+	// checkActionCompleted is the action of the check_run and check_suite events sent once the check run or suite completes
+	checkActionCompleted = "completed"
+)
+
 func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) MatchCheckRunEvent(event *github.CheckRunEvent) func(scaleUpTrigger v1alpha1.ScaleUpTrigger) bool {
 	return func(scaleUpTrigger v1alpha1.ScaleUpTrigger) bool {
 		g := scaleUpTrigger.GitHubEvent
@@ -19,6 +29,10 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) MatchCheckRunEvent(ev
 			return false
 		}
 
+		if cr.ReleaseOnCompletion && event.GetAction() == checkActionCompleted {
+			return true
+		}
+
 		if !matchTriggerConditionAgainstEvent(cr.Types, event.Action) {
 			return false
 		}
@@ -30,3 +44,90 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) MatchCheckRunEvent(ev
 		return true
 	}
 }
+
+func checkRunCapacityReservationID(checkRunID int64) string {
+	return fmt.Sprintf("check-run-%d", checkRunID)
+}
+
+// getCheckCapacityReservation returns the ID of the capacity reservation tracking the check run or suite of the event,
+// and whether the event completes it. ok is false unless the scale-up trigger matched the event releases the reservation
+// on completion, in which case the reservation is added the usual way, identified by the delivery ID.
+func getCheckCapacityReservation(event interface{}, target *ScaleTarget) (reservationID string, completed, ok bool) {
+	g := target.ScaleUpTrigger.GitHubEvent
+
+	if g == nil {
+		return "", false, false
+	}
+
+	switch e := event.(type) {
+	case *github.CheckRunEvent:
+		if g.CheckRun == nil || !g.CheckRun.ReleaseOnCompletion || e.GetCheckRun().GetID() == 0 {
+			return "", false, false
+		}
+
+		return checkRunCapacityReservationID(e.GetCheckRun().GetID()), e.GetAction() == checkActionCompleted, true
+	case *github.CheckSuiteEvent:
+		if g.CheckSuite == nil || !g.CheckSuite.ReleaseOnCompletion || e.GetCheckSuite().GetID() == 0 {
+			return "", false, false
+		}
+
+		return checkSuiteCapacityReservationID(e.GetCheckSuite().GetID()), e.GetAction() == checkActionCompleted, true
+	}
+
+	return "", false, false
+}
+
+// tryScaleForCheck adds the capacity reservation identified by reservationID for a check run or suite, and removes it once
+// the check run or suite completes. Like tryScaleUp, a redelivered event extends the existing reservation rather than adding another.
+// It returns the number of replicas added, which is negative on removal and zero when nothing changed.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) tryScaleForCheck(ctx context.Context, target *ScaleTarget, reservationID string, completed bool) (int, error) {
+	if !completed {
+		if err := autoscaler.tryScaleUp(ctx, target, reservationID); err != nil {
+			return 0, err
+		}
+
+		amount := 1
+
+		if target.ScaleUpTrigger.Amount > 0 {
+			amount = target.ScaleUpTrigger.Amount
+		}
+
+		return amount, nil
+	}
+
+	log := autoscaler.Log.WithValues("horizontalrunnerautoscaler", target.HorizontalRunnerAutoscaler.Name)
+
+	copy := target.HorizontalRunnerAutoscaler.DeepCopy()
+
+	var (
+		reservations []v1alpha1.CapacityReservation
+		removed      int
+		found        bool
+	)
+
+	for _, r := range getRetainedCapacityReservations(copy) {
+		if r.ReservationID == reservationID {
+			// Only one reservation is counted per ID, so is the removed one
+			removed = r.Replicas
+			found = true
+
+			continue
+		}
+
+		reservations = append(reservations, r)
+	}
+
+	if !found {
+		return 0, nil
+	}
+
+	copy.Spec.CapacityReservations = reservations
+
+	if err := autoscaler.Client.Update(ctx, copy); err != nil {
+		log.Error(err, "Failed to update horizontalrunnerautoscaler resource")
+
+		return 0, err
+	}
+
+	return -removed, nil
+}
@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/google/go-github/v33/github"
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+)
+
+const (
+	checkSuiteEventType = "check_suite"
+)
+
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) MatchCheckSuiteEvent(event *github.CheckSuiteEvent) func(scaleUpTrigger v1alpha1.ScaleUpTrigger) bool {
+	return func(scaleUpTrigger v1alpha1.ScaleUpTrigger) bool {
+		g := scaleUpTrigger.GitHubEvent
+
+		if g == nil {
+			return false
+		}
+
+		cs := g.CheckSuite
+
+		if cs == nil {
+			return false
+		}
+
+		if cs.ReleaseOnCompletion && event.GetAction() == checkActionCompleted {
+			return true
+		}
+
+		if !matchTriggerConditionAgainstEvent(cs.Types, event.Action) {
+			return false
+		}
+
+		if cs.Status != "" && (event.CheckSuite == nil || event.CheckSuite.Status == nil || *event.CheckSuite.Status != cs.Status) {
+			return false
+		}
+
+		return true
+	}
+}
+
+func checkSuiteCapacityReservationID(checkSuiteID int64) string {
+	return fmt.Sprintf("check-suite-%d", checkSuiteID)
+}
@@ -5,6 +5,10 @@ import (
 	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
 )
 
+const (
+	pullRequestEventType = "pull_request"
+)
+
 func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) MatchPullRequestEvent(event *github.PullRequestEvent) func(scaleUpTrigger v1alpha1.ScaleUpTrigger) bool {
 	return func(scaleUpTrigger v1alpha1.ScaleUpTrigger) bool {
 		g := scaleUpTrigger.GitHubEvent
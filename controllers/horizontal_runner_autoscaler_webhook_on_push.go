@@ -5,6 +5,10 @@ import (
 	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
 )
 
+const (
+	pushEventType = "push"
+)
+
 func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) MatchPushEvent(event *github.PushEvent) func(scaleUpTrigger v1alpha1.ScaleUpTrigger) bool {
 	return func(scaleUpTrigger v1alpha1.ScaleUpTrigger) bool {
 		g := scaleUpTrigger.GitHubEvent
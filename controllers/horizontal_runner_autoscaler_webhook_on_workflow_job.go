@@ -0,0 +1,355 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/google/go-github/v33/github"
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// workflowJobEventType is the value of X-GitHub-Event header for the workflow_job event.
+	// go-github v33 doesn't support the event yet so we parse it by ourselves.
+	workflowJobEventType = "workflow_job"
+
+	workflowJobActionQueued    = "queued"
+	workflowJobActionCompleted = "completed"
+
+	// DefaultWorkflowJobCapacityReservationTTL is the default duration of a capacity reservation added on a queued workflow job,
+	// used when neither the scale-up trigger nor the webhook server specifies one.
+	DefaultWorkflowJobCapacityReservationTTL = 10 * time.Minute
+
+	// DefaultColdStartReservationDuration is the default duration of the capacity reservation added for scaling up from zero.
+	DefaultColdStartReservationDuration = 5 * time.Minute
+
+	coldStartCapacityReservationName = "cold-start"
+)
+
+// https://docs.github.com/en/developers/webhooks-and-events/webhook-events-and-payloads#workflow_job
+type workflowJobEvent struct {
+	Action      *string              `json:"action,omitempty"`
+	WorkflowJob *workflowJob         `json:"workflow_job,omitempty"`
+	Repo        *github.Repository   `json:"repository,omitempty"`
+	Org         *github.Organization `json:"organization,omitempty"`
+	Sender      *github.User         `json:"sender,omitempty"`
+}
+
+type workflowJob struct {
+	ID     *int64   `json:"id,omitempty"`
+	RunID  *int64   `json:"run_id,omitempty"`
+	Status *string  `json:"status,omitempty"`
+	Labels []string `json:"labels,omitempty"`
+}
+
+func (e *workflowJobEvent) GetAction() string {
+	if e == nil || e.Action == nil {
+		return ""
+	}
+	return *e.Action
+}
+
+func (e *workflowJobEvent) GetJobID() int64 {
+	if e == nil || e.WorkflowJob == nil || e.WorkflowJob.ID == nil {
+		return 0
+	}
+	return *e.WorkflowJob.ID
+}
+
+func (e *workflowJobEvent) GetLabels() []string {
+	if e == nil || e.WorkflowJob == nil {
+		return nil
+	}
+	return e.WorkflowJob.Labels
+}
+
+func parseWorkflowJobEvent(payload []byte) (*workflowJobEvent, error) {
+	var e workflowJobEvent
+
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return nil, err
+	}
+
+	if e.Repo == nil || e.Repo.Owner == nil {
+		return nil, fmt.Errorf("workflow_job event is missing repository")
+	}
+
+	return &e, nil
+}
+
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) MatchWorkflowJobEvent(event *workflowJobEvent) func(scaleUpTrigger v1alpha1.ScaleUpTrigger) bool {
+	return func(scaleUpTrigger v1alpha1.ScaleUpTrigger) bool {
+		g := scaleUpTrigger.GitHubEvent
+
+		if g == nil {
+			return false
+		}
+
+		if g.WorkflowJob == nil {
+			return false
+		}
+
+		return v1alpha1.RunnerLabelsMatchJobLabels(event.GetLabels(), g.WorkflowJob.Labels)
+	}
+}
+
+// getJobScaleUpTarget is the workflow_job counterpart of getScaleUpTarget.
+// It additionally ensures that the runners managed by the scale target have all the labels requested by the job.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getJobScaleUpTarget(ctx context.Context, log logr.Logger, event *workflowJobEvent) (*ScaleTarget, error) {
+	repo := event.Repo.GetName()
+	owner := event.Repo.Owner.GetLogin()
+
+	repositoryRunnerKey := owner + "/" + repo
+
+	if target, err := autoscaler.getJobScaleTarget(ctx, log, repositoryRunnerKey, event); err != nil {
+		log.Info("finding repository-wide runner", "repository", repositoryRunnerKey)
+		return nil, err
+	} else if target != nil {
+		log.Info("job scale up target is repository-wide runners", "repository", repo)
+		return target, nil
+	}
+
+	if event.Repo.Owner.GetType() == "User" {
+		return nil, nil
+	}
+
+	if target, err := autoscaler.getJobScaleTarget(ctx, log, owner, event); err != nil {
+		log.Info("finding organizational runner", "organization", owner)
+		return nil, err
+	} else if target != nil {
+		log.Info("job scale up target is organizational runners", "organization", owner)
+		return target, nil
+	}
+
+	log.Info(
+		"Job scale target not found. If this is unexpected, ensure that there is exactly one repository-wide or organizational runner deployment that matches this webhook event and has all the labels of the job",
+		"labels", strings.Join(event.GetLabels(), ","),
+	)
+
+	return nil, nil
+}
+
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getJobScaleTarget(ctx context.Context, log logr.Logger, name string, event *workflowJobEvent) (*ScaleTarget, error) {
+	hras, err := autoscaler.findHRAsByKey(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []ScaleTarget
+
+	for _, t := range append(autoscaler.searchScaleTargets(hras, autoscaler.MatchWorkflowJobEvent(event)), searchColdStartTargets(hras)...) {
+		var rd v1alpha1.RunnerDeployment
+
+		if err := autoscaler.Client.Get(ctx, types.NamespacedName{Namespace: t.Namespace, Name: t.Spec.ScaleTargetRef.Name}, &rd); err != nil {
+			// An HRA whose scale target is missing shouldn't prevent the other HRAs from being scaled for the event
+			if kerrors.IsNotFound(err) {
+				log.Info("Skipping HorizontalRunnerAutoscaler whose scale target is not found", "horizontalrunnerautoscaler", t.Name, "namespace", t.Namespace, "runnerdeployment", t.Spec.ScaleTargetRef.Name)
+
+				continue
+			}
+
+			return nil, err
+		}
+
+		if !v1alpha1.RunnerLabelsMatchJobLabels(rd.Spec.Template.Spec.Labels, event.GetLabels()) {
+			continue
+		}
+
+		targets = append(targets, t)
+	}
+
+	return autoscaler.selectScaleTarget(targets), nil
+}
+
+// searchColdStartTargets returns the HorizontalRunnerAutoscalers having ColdStartReservation but no workflowJob scale-up trigger
+// as the scale targets for workflow_job events.
+func searchColdStartTargets(hras []v1alpha1.HorizontalRunnerAutoscaler) []ScaleTarget {
+	var matched []ScaleTarget
+
+	for _, hra := range hras {
+		if !hra.ObjectMeta.DeletionTimestamp.IsZero() || hra.Spec.ColdStartReservation == nil {
+			continue
+		}
+
+		if kind := hra.Spec.ScaleTargetRef.Kind; kind != "" && kind != scaleTargetKindRunnerDeployment {
+			continue
+		}
+
+		var hasJobTrigger bool
+
+		for _, t := range hra.Spec.ScaleUpTriggers {
+			if t.GitHubEvent != nil && t.GitHubEvent.WorkflowJob != nil {
+				hasJobTrigger = true
+
+				break
+			}
+		}
+
+		if hasJobTrigger {
+			continue
+		}
+
+		matched = append(matched, ScaleTarget{
+			HorizontalRunnerAutoscaler: hra,
+			coldStart:                  true,
+		})
+	}
+
+	return matched
+}
+
+func workflowJobCapacityReservationName(jobID int64) string {
+	return fmt.Sprintf("workflow-job-%d", jobID)
+}
+
+// tryScaleForWorkflowJob adds a capacity reservation for a queued workflow job, and removes it once the job completes.
+// The reservation is identified by the job ID, so that a redelivered event doesn't result in adding or removing it twice,
+// and a queued event for a job that already has a reservation replaces it rather than adding another one,
+// extending its expiration time even when the replicas are unchanged, as the job is still waiting for a runner.
+// It returns the number of replicas added, which is negative on removal and zero when the replicas are unchanged.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) tryScaleForWorkflowJob(ctx context.Context, target *ScaleTarget, event *workflowJobEvent) (int, error) {
+	log := autoscaler.Log.WithValues("horizontalrunnerautoscaler", target.HorizontalRunnerAutoscaler.Name)
+
+	copy := target.HorizontalRunnerAutoscaler.DeepCopy()
+
+	jobID := event.GetJobID()
+	name := workflowJobCapacityReservationName(jobID)
+
+	capacityReservations := getRetainedCapacityReservations(copy)
+
+	var (
+		reservations []v1alpha1.CapacityReservation
+		existing     int
+	)
+
+	for _, r := range capacityReservations {
+		// The reservations added before WorkflowJobID was introduced are identified by the name
+		if r.WorkflowJobID == jobID || (r.WorkflowJobID == 0 && r.Name == name) {
+			existing = r.Replicas
+
+			continue
+		}
+
+		reservations = append(reservations, r)
+	}
+
+	var amount int
+
+	switch event.GetAction() {
+	case workflowJobActionQueued:
+		replicas := 1
+
+		if target.ScaleUpTrigger.Amount > 0 {
+			replicas = target.ScaleUpTrigger.Amount
+		}
+
+		ttl := target.ScaleUpTrigger.Duration.Duration
+		if ttl <= 0 {
+			ttl = autoscaler.WorkflowJobCapacityReservationTTL
+		}
+		if ttl <= 0 {
+			ttl = DefaultWorkflowJobCapacityReservationTTL
+		}
+
+		reservations = append(reservations, v1alpha1.CapacityReservation{
+			Name:           name,
+			ExpirationTime: metav1.Time{Time: time.Now().Add(ttl)},
+			Replicas:       replicas,
+			WorkflowJobID:  jobID,
+		})
+
+		amount = replicas - existing
+	case workflowJobActionCompleted:
+		if existing == 0 {
+			return 0, nil
+		}
+
+		amount = -existing
+	default:
+		return 0, nil
+	}
+
+	copy.Spec.CapacityReservations = reservations
+
+	if err := autoscaler.Client.Update(ctx, copy); err != nil {
+		log.Error(err, "Failed to update horizontalrunnerautoscaler resource")
+
+		return 0, err
+	}
+
+	return amount, nil
+}
+
+// tryScaleFromZero adds the cold start capacity reservation on a queued workflow job while the scale target is scaled to zero.
+// Only one cold start reservation is added at a time, as it's meant only for starting the first runner and the metrics
+// take over once the runner is up. It returns the number of replicas added.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) tryScaleFromZero(ctx context.Context, target *ScaleTarget, event *workflowJobEvent) (int, error) {
+	if event.GetAction() != workflowJobActionQueued {
+		return 0, nil
+	}
+
+	log := autoscaler.Log.WithValues("horizontalrunnerautoscaler", target.HorizontalRunnerAutoscaler.Name)
+
+	var rd v1alpha1.RunnerDeployment
+
+	if err := autoscaler.Client.Get(ctx, types.NamespacedName{Namespace: target.Namespace, Name: target.Spec.ScaleTargetRef.Name}, &rd); err != nil {
+		return 0, err
+	}
+
+	// A nil replicas results in the default of 1 replica, so it's not at zero
+	if rd.Spec.Replicas == nil || *rd.Spec.Replicas > 0 {
+		return 0, nil
+	}
+
+	copy := target.HorizontalRunnerAutoscaler.DeepCopy()
+
+	now := time.Now()
+
+	var capacityReservations []v1alpha1.CapacityReservation
+
+	for _, r := range getRetainedCapacityReservations(copy) {
+		if r.Name == coldStartCapacityReservationName {
+			if r.ExpirationTime.Time.After(now) {
+				return 0, nil
+			}
+
+			// The expired one held while busy is replaced by the new one below
+			continue
+		}
+
+		capacityReservations = append(capacityReservations, r)
+	}
+
+	spec := copy.Spec.ColdStartReservation
+
+	replicas := 1
+	if spec.Replicas > 0 {
+		replicas = spec.Replicas
+	}
+
+	duration := spec.Duration.Duration
+	if duration <= 0 {
+		duration = DefaultColdStartReservationDuration
+	}
+
+	copy.Spec.CapacityReservations = append(capacityReservations, v1alpha1.CapacityReservation{
+		Name:           coldStartCapacityReservationName,
+		ExpirationTime: metav1.Time{Time: now.Add(duration)},
+		Replicas:       replicas,
+	})
+
+	if err := autoscaler.Client.Update(ctx, copy); err != nil {
+		log.Error(err, "Failed to update horizontalrunnerautoscaler resource")
+
+		return 0, err
+	}
+
+	return replicas, nil
+}
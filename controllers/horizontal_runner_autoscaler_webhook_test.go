@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/go-logr/logr"
@@ -11,12 +12,16 @@ import (
 	"io/ioutil"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"math/rand"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 )
@@ -113,6 +118,526 @@ func TestWebhookPing(t *testing.T) {
 	)
 }
 
+func TestWebhookWorkflowJob(t *testing.T) {
+	newEvent := func(action string, labels ...string) *workflowJobEvent {
+		return &workflowJobEvent{
+			Action: github.String(action),
+			WorkflowJob: &workflowJob{
+				ID:     github.Int64(1234),
+				Labels: labels,
+			},
+			Repo: &github.Repository{
+				Name: github.String("myrepo"),
+				Owner: &github.User{
+					Login: github.String("myorg"),
+					Type:  github.String("Organization"),
+				},
+			},
+		}
+	}
+
+	newInitObjs := func(reservations ...actionsv1alpha1.CapacityReservation) []runtime.Object {
+		return []runtime.Object{
+			&actionsv1alpha1.RunnerDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "testrd",
+					Namespace: "default",
+				},
+				Spec: actionsv1alpha1.RunnerDeploymentSpec{
+					Template: actionsv1alpha1.RunnerTemplate{
+						Spec: actionsv1alpha1.RunnerSpec{
+							Organization: "myorg",
+							Labels:       []string{"gpu", "Linux"},
+						},
+					},
+				},
+			},
+			&actionsv1alpha1.HorizontalRunnerAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "testhra",
+					Namespace: "default",
+				},
+				Spec: actionsv1alpha1.HorizontalRunnerAutoscalerSpec{
+					ScaleTargetRef: actionsv1alpha1.ScaleTargetRef{
+						Name: "testrd",
+					},
+					ScaleUpTriggers: []actionsv1alpha1.ScaleUpTrigger{
+						{
+							GitHubEvent: &actionsv1alpha1.GitHubEventScaleUpTriggerSpec{
+								WorkflowJob: &actionsv1alpha1.WorkflowJobSpec{},
+							},
+							Duration: metav1.Duration{Duration: 5 * time.Minute},
+						},
+					},
+					CapacityReservations: reservations,
+				},
+			},
+		}
+	}
+
+	getReservations := func(t *testing.T, webhook *HorizontalRunnerAutoscalerGitHubWebhook) []actionsv1alpha1.CapacityReservation {
+		t.Helper()
+
+		var hra actionsv1alpha1.HorizontalRunnerAutoscaler
+		if err := webhook.Client.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "testhra"}, &hra); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		return hra.Spec.CapacityReservations
+	}
+
+	t.Run("queued", func(t *testing.T) {
+		webhook := testServerWithInitObjs(t, "workflow_job", newEvent("queued", "self-hosted", "linux", "GPU"), 200, "scaled testhra by 1", newInitObjs())
+
+		rs := getReservations(t, webhook)
+		if len(rs) != 1 || rs[0].Name != "workflow-job-1234" || rs[0].WorkflowJobID != 1234 || rs[0].Replicas != 1 {
+			t.Fatalf("unexpected capacity reservations: %+v", rs)
+		}
+
+		if d := time.Until(rs[0].ExpirationTime.Time); d <= 4*time.Minute || d > 5*time.Minute {
+			t.Errorf("unexpected expiration time of the capacity reservation: %s", rs[0].ExpirationTime)
+		}
+	})
+
+	t.Run("queued redelivered", func(t *testing.T) {
+		existing := []actionsv1alpha1.CapacityReservation{
+			{Name: "workflow-job-1234", ExpirationTime: metav1.Time{Time: time.Now().Add(time.Minute)}, Replicas: 1, WorkflowJobID: 1234},
+		}
+
+		webhook := testServerWithInitObjs(t, "workflow_job", newEvent("queued", "gpu"), 200, "scaled testhra by 0", newInitObjs(existing...))
+
+		rs := getReservations(t, webhook)
+		if len(rs) != 1 || rs[0].WorkflowJobID != 1234 || rs[0].Replicas != 1 {
+			t.Fatalf("unexpected capacity reservations: %+v", rs)
+		}
+
+		if d := time.Until(rs[0].ExpirationTime.Time); d <= 4*time.Minute || d > 5*time.Minute {
+			t.Errorf("expected the expiration time of the capacity reservation to be extended, got %s", rs[0].ExpirationTime)
+		}
+	})
+
+	t.Run("queued replacing", func(t *testing.T) {
+		existing := []actionsv1alpha1.CapacityReservation{
+			{Name: "workflow-job-1234", ExpirationTime: metav1.Time{Time: time.Now().Add(time.Minute)}, Replicas: 3, WorkflowJobID: 1234},
+			{Name: "workflow-job-5678", ExpirationTime: metav1.Time{Time: time.Now().Add(time.Minute)}, Replicas: 1, WorkflowJobID: 5678},
+		}
+
+		webhook := testServerWithInitObjs(t, "workflow_job", newEvent("queued", "gpu"), 200, "scaled testhra by -2", newInitObjs(existing...))
+
+		rs := getReservations(t, webhook)
+		if len(rs) != 2 || rs[0].WorkflowJobID != 5678 || rs[1].WorkflowJobID != 1234 || rs[1].Replicas != 1 {
+			t.Fatalf("unexpected capacity reservations: %+v", rs)
+		}
+	})
+
+	t.Run("queued keeping held expired reservation", func(t *testing.T) {
+		existing := []actionsv1alpha1.CapacityReservation{
+			{Name: "workflow-job-5678", ExpirationTime: metav1.Time{Time: time.Now().Add(-time.Minute)}, Replicas: 1, WorkflowJobID: 5678},
+		}
+
+		initObjs := newInitObjs(existing...)
+		initObjs[1].(*actionsv1alpha1.HorizontalRunnerAutoscaler).Spec.HoldCapacityReservationsWhileBusy = true
+
+		webhook := testServerWithInitObjs(t, "workflow_job", newEvent("queued", "gpu"), 200, "scaled testhra by 1", initObjs)
+
+		rs := getReservations(t, webhook)
+		if len(rs) != 2 || rs[0].WorkflowJobID != 5678 || rs[1].WorkflowJobID != 1234 {
+			t.Fatalf("unexpected capacity reservations: %+v", rs)
+		}
+	})
+
+	t.Run("queued with unmatched labels", func(t *testing.T) {
+		webhook := testServerWithInitObjs(t, "workflow_job", newEvent("queued", "self-hosted", "arm64"), 200, "no horizontalrunnerautoscaler to scale for this github event", newInitObjs())
+
+		if rs := getReservations(t, webhook); len(rs) != 0 {
+			t.Fatalf("unexpected capacity reservations: %+v", rs)
+		}
+	})
+
+	t.Run("queued along with autoscaler missing scale target", func(t *testing.T) {
+		initObjs := append(newInitObjs(), &actionsv1alpha1.HorizontalRunnerAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "orphanhra",
+				Namespace: "default",
+			},
+			Spec: actionsv1alpha1.HorizontalRunnerAutoscalerSpec{
+				ScaleTargetRef: actionsv1alpha1.ScaleTargetRef{
+					Name: "missingrd",
+				},
+				ScaleUpTriggers: []actionsv1alpha1.ScaleUpTrigger{
+					{
+						GitHubEvent: &actionsv1alpha1.GitHubEventScaleUpTriggerSpec{
+							WorkflowJob: &actionsv1alpha1.WorkflowJobSpec{},
+						},
+					},
+				},
+			},
+		})
+
+		webhook := testServerWithInitObjs(t, "workflow_job", newEvent("queued", "gpu"), 200, "scaled testhra by 1", initObjs)
+
+		if rs := getReservations(t, webhook); len(rs) != 1 || rs[0].WorkflowJobID != 1234 {
+			t.Fatalf("unexpected capacity reservations: %+v", rs)
+		}
+	})
+
+	t.Run("queued without trigger labels", func(t *testing.T) {
+		initObjs := newInitObjs()
+		initObjs[1].(*actionsv1alpha1.HorizontalRunnerAutoscaler).Spec.ScaleUpTriggers[0].GitHubEvent.WorkflowJob.Labels = []string{"gpu"}
+
+		webhook := testServerWithInitObjs(t, "workflow_job", newEvent("queued", "self-hosted", "linux"), 200, "no horizontalrunnerautoscaler to scale for this github event", initObjs)
+
+		if rs := getReservations(t, webhook); len(rs) != 0 {
+			t.Fatalf("unexpected capacity reservations: %+v", rs)
+		}
+	})
+
+	t.Run("completed", func(t *testing.T) {
+		existing := []actionsv1alpha1.CapacityReservation{
+			{Name: "workflow-job-1234", ExpirationTime: metav1.Time{Time: time.Now().Add(time.Minute)}, Replicas: 1},
+			{Name: "workflow-job-5678", ExpirationTime: metav1.Time{Time: time.Now().Add(time.Minute)}, Replicas: 1},
+		}
+
+		webhook := testServerWithInitObjs(t, "workflow_job", newEvent("completed", "gpu"), 200, "scaled testhra by -1", newInitObjs(existing...))
+
+		rs := getReservations(t, webhook)
+		if len(rs) != 1 || rs[0].Name != "workflow-job-5678" {
+			t.Fatalf("unexpected capacity reservations: %+v", rs)
+		}
+	})
+
+	t.Run("in_progress", func(t *testing.T) {
+		testServerWithInitObjs(t, "workflow_job", newEvent("in_progress", "gpu"), 200, `ignored workflow_job event with action "in_progress"`, newInitObjs())
+	})
+}
+
+func TestWebhookCheckRunReleaseOnCompletion(t *testing.T) {
+	newEvent := func(action, status string) *github.CheckRunEvent {
+		return &github.CheckRunEvent{
+			Action: github.String(action),
+			CheckRun: &github.CheckRun{
+				ID:     github.Int64(1234),
+				Status: github.String(status),
+			},
+			Repo: &github.Repository{
+				Name: github.String("myrepo"),
+				Owner: &github.User{
+					Login: github.String("myorg"),
+					Type:  github.String("Organization"),
+				},
+			},
+		}
+	}
+
+	newInitObjs := func(reservations ...actionsv1alpha1.CapacityReservation) []runtime.Object {
+		return []runtime.Object{
+			&actionsv1alpha1.RunnerDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "testrd",
+					Namespace: "default",
+				},
+				Spec: actionsv1alpha1.RunnerDeploymentSpec{
+					Template: actionsv1alpha1.RunnerTemplate{
+						Spec: actionsv1alpha1.RunnerSpec{
+							Organization: "myorg",
+						},
+					},
+				},
+			},
+			&actionsv1alpha1.HorizontalRunnerAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "testhra",
+					Namespace: "default",
+				},
+				Spec: actionsv1alpha1.HorizontalRunnerAutoscalerSpec{
+					ScaleTargetRef: actionsv1alpha1.ScaleTargetRef{
+						Name: "testrd",
+					},
+					ScaleUpTriggers: []actionsv1alpha1.ScaleUpTrigger{
+						{
+							GitHubEvent: &actionsv1alpha1.GitHubEventScaleUpTriggerSpec{
+								CheckRun: &actionsv1alpha1.CheckRunSpec{
+									Types:               []string{"created"},
+									Status:              "queued",
+									ReleaseOnCompletion: true,
+								},
+							},
+							Amount:   2,
+							Duration: metav1.Duration{Duration: 5 * time.Minute},
+						},
+					},
+					CapacityReservations: reservations,
+				},
+			},
+		}
+	}
+
+	getReservations := func(t *testing.T, webhook *HorizontalRunnerAutoscalerGitHubWebhook) []actionsv1alpha1.CapacityReservation {
+		t.Helper()
+
+		var hra actionsv1alpha1.HorizontalRunnerAutoscaler
+		if err := webhook.Client.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "testhra"}, &hra); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		return hra.Spec.CapacityReservations
+	}
+
+	t.Run("created", func(t *testing.T) {
+		webhook := testServerWithInitObjs(t, "check_run", newEvent("created", "queued"), 200, "scaled testhra by 2", newInitObjs())
+
+		if rs := getReservations(t, webhook); len(rs) != 1 || rs[0].ReservationID != "check-run-1234" || rs[0].Replicas != 2 {
+			t.Fatalf("unexpected capacity reservations: %+v", rs)
+		}
+	})
+
+	t.Run("created redelivered", func(t *testing.T) {
+		existing := []actionsv1alpha1.CapacityReservation{
+			{ExpirationTime: metav1.Time{Time: time.Now().Add(time.Minute)}, Replicas: 2, ReservationID: "check-run-1234"},
+		}
+
+		webhook := testServerWithInitObjs(t, "check_run", newEvent("created", "queued"), 200, "scaled testhra by 2", newInitObjs(existing...))
+
+		rs := getReservations(t, webhook)
+		if len(rs) != 1 || rs[0].ReservationID != "check-run-1234" {
+			t.Fatalf("unexpected capacity reservations: %+v", rs)
+		}
+
+		if d := time.Until(rs[0].ExpirationTime.Time); d <= 4*time.Minute {
+			t.Errorf("expected the capacity reservation to be extended: %s", rs[0].ExpirationTime)
+		}
+	})
+
+	t.Run("completed", func(t *testing.T) {
+		existing := []actionsv1alpha1.CapacityReservation{
+			{ExpirationTime: metav1.Time{Time: time.Now().Add(time.Minute)}, Replicas: 2, ReservationID: "check-run-1234"},
+			{ExpirationTime: metav1.Time{Time: time.Now().Add(time.Minute)}, Replicas: 2, ReservationID: "check-run-5678"},
+		}
+
+		webhook := testServerWithInitObjs(t, "check_run", newEvent("completed", "completed"), 200, "scaled testhra by -2", newInitObjs(existing...))
+
+		if rs := getReservations(t, webhook); len(rs) != 1 || rs[0].ReservationID != "check-run-5678" {
+			t.Fatalf("unexpected capacity reservations: %+v", rs)
+		}
+	})
+
+	t.Run("completed without reservation", func(t *testing.T) {
+		webhook := testServerWithInitObjs(t, "check_run", newEvent("completed", "completed"), 200, "scaled testhra by 0", newInitObjs())
+
+		if rs := getReservations(t, webhook); len(rs) != 0 {
+			t.Fatalf("unexpected capacity reservations: %+v", rs)
+		}
+	})
+
+	t.Run("unmatched status", func(t *testing.T) {
+		testServerWithInitObjs(t, "check_run", newEvent("created", "in_progress"), 200, "no horizontalrunnerautoscaler to scale for this github event", newInitObjs())
+	})
+}
+
+func TestWebhookEnabledEvents(t *testing.T) {
+	testcases := []struct {
+		enabled   []string
+		eventType string
+		event     interface{}
+		want      string
+	}{
+		{
+			eventType: "push",
+			event:     &github.PushEvent{Repo: &github.PushEventRepository{Name: github.String("myrepo")}},
+			want:      "no horizontalrunnerautoscaler to scale for this github event",
+		},
+		{
+			enabled:   []string{"workflow_job"},
+			eventType: "push",
+			event:     &github.PushEvent{Repo: &github.PushEventRepository{Name: github.String("myrepo")}},
+			want:      "ignored push event as it's not enabled",
+		},
+		{
+			enabled:   []string{"check_run", "push"},
+			eventType: "push",
+			event:     &github.PushEvent{Repo: &github.PushEventRepository{Name: github.String("myrepo")}},
+			want:      "no horizontalrunnerautoscaler to scale for this github event",
+		},
+		{
+			enabled:   []string{"workflow_job"},
+			eventType: "ping",
+			event:     &github.PingEvent{Zen: github.String("zen")},
+			want:      "pong",
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			hraWebhook := &HorizontalRunnerAutoscalerGitHubWebhook{
+				Client:        fake.NewFakeClientWithScheme(sc),
+				EnabledEvents: tc.enabled,
+			}
+
+			logs := installTestLogger(hraWebhook)
+
+			defer func() {
+				if t.Failed() {
+					t.Logf("diagnostics: %s", logs.String())
+				}
+			}()
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/", hraWebhook.Handle)
+
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			resp, err := sendWebhook(server, tc.eventType, tc.event)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("unexpected status: want %d, got %d", http.StatusOK, resp.StatusCode)
+			}
+
+			body, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(body) != tc.want {
+				t.Errorf("unexpected body: want %q, got %q", tc.want, string(body))
+			}
+		})
+	}
+}
+
+func TestWebhookWorkflowJobColdStart(t *testing.T) {
+	event := &workflowJobEvent{
+		Action: github.String("queued"),
+		WorkflowJob: &workflowJob{
+			ID:     github.Int64(1234),
+			Labels: []string{"self-hosted"},
+		},
+		Repo: &github.Repository{
+			Name: github.String("myrepo"),
+			Owner: &github.User{
+				Login: github.String("myorg"),
+				Type:  github.String("Organization"),
+			},
+		},
+	}
+
+	newInitObjs := func(replicas *int, reservations ...actionsv1alpha1.CapacityReservation) []runtime.Object {
+		return []runtime.Object{
+			&actionsv1alpha1.RunnerDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "testrd",
+					Namespace: "default",
+				},
+				Spec: actionsv1alpha1.RunnerDeploymentSpec{
+					Template: actionsv1alpha1.RunnerTemplate{
+						Spec: actionsv1alpha1.RunnerSpec{
+							Organization: "myorg",
+						},
+					},
+					Replicas: replicas,
+				},
+			},
+			&actionsv1alpha1.HorizontalRunnerAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "testhra",
+					Namespace: "default",
+				},
+				Spec: actionsv1alpha1.HorizontalRunnerAutoscalerSpec{
+					ScaleTargetRef: actionsv1alpha1.ScaleTargetRef{
+						Name: "testrd",
+					},
+					MinReplicas: intPtr(0),
+					ColdStartReservation: &actionsv1alpha1.ColdStartReservation{
+						Duration: metav1.Duration{Duration: 2 * time.Minute},
+					},
+					CapacityReservations: reservations,
+				},
+			},
+		}
+	}
+
+	getReservations := func(t *testing.T, webhook *HorizontalRunnerAutoscalerGitHubWebhook) []actionsv1alpha1.CapacityReservation {
+		t.Helper()
+
+		var hra actionsv1alpha1.HorizontalRunnerAutoscaler
+		if err := webhook.Client.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "testhra"}, &hra); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		return hra.Spec.CapacityReservations
+	}
+
+	t.Run("at zero", func(t *testing.T) {
+		webhook := testServerWithInitObjs(t, "workflow_job", event, 200, "scaled testhra by 1", newInitObjs(intPtr(0)))
+
+		rs := getReservations(t, webhook)
+		if len(rs) != 1 || rs[0].Name != "cold-start" || rs[0].Replicas != 1 {
+			t.Fatalf("unexpected capacity reservations: %+v", rs)
+		}
+
+		if d := time.Until(rs[0].ExpirationTime.Time); d <= time.Minute || d > 2*time.Minute {
+			t.Errorf("unexpected expiration time of the capacity reservation: %s", rs[0].ExpirationTime)
+		}
+	})
+
+	t.Run("at zero with cold start reservation", func(t *testing.T) {
+		existing := []actionsv1alpha1.CapacityReservation{
+			{Name: "cold-start", ExpirationTime: metav1.Time{Time: time.Now().Add(time.Minute)}, Replicas: 1},
+		}
+
+		webhook := testServerWithInitObjs(t, "workflow_job", event, 200, "scaled testhra by 0", newInitObjs(intPtr(0), existing...))
+
+		if rs := getReservations(t, webhook); len(rs) != 1 {
+			t.Fatalf("unexpected capacity reservations: %+v", rs)
+		}
+	})
+
+	t.Run("not at zero", func(t *testing.T) {
+		webhook := testServerWithInitObjs(t, "workflow_job", event, 200, "scaled testhra by 0", newInitObjs(intPtr(1)))
+
+		if rs := getReservations(t, webhook); len(rs) != 0 {
+			t.Fatalf("unexpected capacity reservations: %+v", rs)
+		}
+	})
+}
+
+func TestWebhookSignatureMismatch(t *testing.T) {
+	hraWebhook := &HorizontalRunnerAutoscalerGitHubWebhook{
+		Client:         fake.NewFakeClientWithScheme(sc),
+		SecretKeyBytes: []byte("secret"),
+	}
+
+	logs := installTestLogger(hraWebhook)
+
+	defer func() {
+		if t.Failed() {
+			t.Logf("diagnostics: %s", logs.String())
+		}
+	}()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", hraWebhook.Handle)
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	resp, err := sendWebhook(server, "ping", &github.PingEvent{Zen: github.String("zen")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unexpected status: want %d, got %d", http.StatusBadRequest, resp.StatusCode)
+	}
+}
+
 func TestGetValidCapacityReservations(t *testing.T) {
 	now := time.Now()
 
@@ -150,6 +675,193 @@ func TestGetValidCapacityReservations(t *testing.T) {
 	}
 }
 
+func TestGetCapacityReservationReplicas(t *testing.T) {
+	testcases := []struct {
+		reservations []actionsv1alpha1.CapacityReservation
+		want         int
+	}{
+		{
+			want: 0,
+		},
+		{
+			reservations: []actionsv1alpha1.CapacityReservation{
+				{Replicas: 1},
+				{Replicas: 2},
+			},
+			want: 3,
+		},
+		// Only the last one is counted per workflow job
+		{
+			reservations: []actionsv1alpha1.CapacityReservation{
+				{Replicas: 2, WorkflowJobID: 1},
+				{Replicas: 1, WorkflowJobID: 2},
+				{Replicas: 3, WorkflowJobID: 1},
+				{Replicas: 1},
+			},
+			want: 5,
+		},
+		// Only the last one is counted per reservation ID
+		{
+			reservations: []actionsv1alpha1.CapacityReservation{
+				{Replicas: 1, ReservationID: "delivery-1"},
+				{Replicas: 1, ReservationID: "delivery-2"},
+				{Replicas: 2, ReservationID: "delivery-1"},
+				{Replicas: 1},
+			},
+			want: 4,
+		},
+	}
+
+	for i, tc := range testcases {
+		if got := getCapacityReservationReplicas(tc.reservations); got != tc.want {
+			t.Errorf("%d: want %d, got %d", i, tc.want, got)
+		}
+	}
+}
+
+func TestGetCapacityReservationReplicas_Stable(t *testing.T) {
+	now := time.Now()
+
+	var reservations []actionsv1alpha1.CapacityReservation
+
+	// Many short reservations overlapping each other, some of which share the expiration time,
+	// and some of which are for the same workflow job or reservation ID as the others
+	for i := 0; i < 30; i++ {
+		reservations = append(reservations, actionsv1alpha1.CapacityReservation{
+			Name:           fmt.Sprintf("r%d", i),
+			ExpirationTime: metav1.Time{Time: now.Add(time.Duration(i%7-3) * time.Minute)},
+			Replicas:       i%3 + 1,
+			WorkflowJobID:  int64(i % 5),
+		})
+
+		reservations = append(reservations, actionsv1alpha1.CapacityReservation{
+			Name:           fmt.Sprintf("d%d", i),
+			ExpirationTime: metav1.Time{Time: now.Add(time.Duration(i%4) * time.Minute)},
+			Replicas:       i%2 + 1,
+			ReservationID:  fmt.Sprintf("delivery-%d", i%6),
+		})
+	}
+
+	heldNames := func(rs []actionsv1alpha1.CapacityReservation) string {
+		var names []string
+
+		for _, r := range holdCapacityReservations(rs, now, 10) {
+			names = append(names, r.Name)
+		}
+
+		sort.Strings(names)
+
+		return strings.Join(names, ",")
+	}
+
+	want := getCapacityReservationReplicas(reservations)
+	wantHeld := heldNames(reservations)
+
+	for i := 0; i < 20; i++ {
+		shuffled := append([]actionsv1alpha1.CapacityReservation{}, reservations...)
+
+		rand.New(rand.NewSource(int64(i))).Shuffle(len(shuffled), func(a, b int) {
+			shuffled[a], shuffled[b] = shuffled[b], shuffled[a]
+		})
+
+		if got := getCapacityReservationReplicas(shuffled); got != want {
+			t.Errorf("%d: want %d, got %d", i, want, got)
+		}
+
+		if got := heldNames(shuffled); got != wantHeld {
+			t.Errorf("%d: unexpected held reservations: want %s, got %s", i, wantHeld, got)
+		}
+	}
+
+	// The one expiring last is counted per reservation ID, regardless of the order
+	for _, rs := range [][]actionsv1alpha1.CapacityReservation{
+		{
+			{Replicas: 3, ReservationID: "delivery-1", ExpirationTime: metav1.Time{Time: now.Add(2 * time.Minute)}},
+			{Replicas: 1, ReservationID: "delivery-1", ExpirationTime: metav1.Time{Time: now.Add(time.Minute)}},
+		},
+		{
+			{Replicas: 1, ReservationID: "delivery-1", ExpirationTime: metav1.Time{Time: now.Add(time.Minute)}},
+			{Replicas: 3, ReservationID: "delivery-1", ExpirationTime: metav1.Time{Time: now.Add(2 * time.Minute)}},
+		},
+	} {
+		if got := getCapacityReservationReplicas(rs); got != 3 {
+			t.Errorf("want 3, got %d", got)
+		}
+	}
+}
+
+func TestTryScaleUp_ReservationID(t *testing.T) {
+	hra := &actionsv1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "testhra",
+			Namespace: "default",
+		},
+	}
+
+	webhook := &HorizontalRunnerAutoscalerGitHubWebhook{
+		Client: fake.NewFakeClientWithScheme(sc, hra),
+	}
+
+	installTestLogger(webhook)
+
+	scaleUp := func(id string, duration time.Duration) []actionsv1alpha1.CapacityReservation {
+		t.Helper()
+
+		var current actionsv1alpha1.HorizontalRunnerAutoscaler
+		if err := webhook.Client.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "testhra"}, &current); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		target := &ScaleTarget{
+			HorizontalRunnerAutoscaler: current,
+			ScaleUpTrigger:             actionsv1alpha1.ScaleUpTrigger{Amount: 2, Duration: metav1.Duration{Duration: duration}},
+		}
+
+		if err := webhook.tryScaleUp(context.Background(), target, id); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if err := webhook.Client.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "testhra"}, &current); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		return current.Spec.CapacityReservations
+	}
+
+	rs := scaleUp("delivery-1", time.Minute)
+	if len(rs) != 1 || rs[0].ReservationID != "delivery-1" || rs[0].Replicas != 2 {
+		t.Fatalf("unexpected capacity reservations: %+v", rs)
+	}
+
+	// The redelivery extends the existing reservation rather than adding another one
+	rs = scaleUp("delivery-1", 10*time.Minute)
+	if len(rs) != 1 || rs[0].Replicas != 2 {
+		t.Fatalf("unexpected capacity reservations after the redelivery: %+v", rs)
+	}
+
+	if d := time.Until(rs[0].ExpirationTime.Time); d <= 9*time.Minute {
+		t.Errorf("unexpected expiration time of the extended capacity reservation: %s", rs[0].ExpirationTime)
+	}
+
+	// The redelivery never shortens the existing reservation
+	rs = scaleUp("delivery-1", time.Minute)
+	if d := time.Until(rs[0].ExpirationTime.Time); len(rs) != 1 || d <= 9*time.Minute {
+		t.Errorf("unexpected capacity reservations after the redelivery: %+v", rs)
+	}
+
+	rs = scaleUp("delivery-2", time.Minute)
+	if len(rs) != 2 || getCapacityReservationReplicas(rs) != 4 {
+		t.Fatalf("unexpected capacity reservations for another delivery: %+v", rs)
+	}
+
+	// Events delivered without the ID are never deduplicated
+	scaleUp("", time.Minute)
+	rs = scaleUp("", time.Minute)
+	if len(rs) != 4 || getCapacityReservationReplicas(rs) != 8 {
+		t.Fatalf("unexpected capacity reservations without the IDs: %+v", rs)
+	}
+}
+
 func installTestLogger(webhook *HorizontalRunnerAutoscalerGitHubWebhook) *bytes.Buffer {
 	logs := &bytes.Buffer{}
 
@@ -166,9 +878,13 @@ func installTestLogger(webhook *HorizontalRunnerAutoscalerGitHubWebhook) *bytes.
 func testServer(t *testing.T, eventType string, event interface{}, wantCode int, wantBody string) {
 	t.Helper()
 
-	hraWebhook := &HorizontalRunnerAutoscalerGitHubWebhook{}
+	testServerWithInitObjs(t, eventType, event, wantCode, wantBody, nil)
+}
 
-	var initObjs []runtime.Object
+func testServerWithInitObjs(t *testing.T, eventType string, event interface{}, wantCode int, wantBody string, initObjs []runtime.Object) *HorizontalRunnerAutoscalerGitHubWebhook {
+	t.Helper()
+
+	hraWebhook := &HorizontalRunnerAutoscalerGitHubWebhook{}
 
 	client := fake.NewFakeClientWithScheme(sc, initObjs...)
 
@@ -211,6 +927,8 @@ func testServer(t *testing.T, eventType string, event interface{}, wantCode int,
 	if string(respBody) != wantBody {
 		t.Fatal("body:", string(respBody))
 	}
+
+	return hraWebhook
 }
 
 func sendWebhook(server *httptest.Server, eventType string, event interface{}) (*http.Response, error) {
@@ -0,0 +1,220 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultAdminAddr is the default address the admin endpoint binds to.
+	// It's bound to localhost by default, as the endpoint isn't authenticated.
+	DefaultAdminAddr = "127.0.0.1:8082"
+
+	// HorizontalRunnerAutoscalerDecisionsPath is the path the admin endpoint serves the decisions of the HorizontalRunnerAutoscalers at.
+	HorizontalRunnerAutoscalerDecisionsPath = "/horizontalrunnerautoscalers"
+)
+
+// HorizontalRunnerAutoscalerDecision is the point-in-time detail of the last scaling decision of a HorizontalRunnerAutoscaler,
+// served by the admin endpoint. It complements the metrics of the HorizontalRunnerAutoscaler that are aggregated over time.
+type HorizontalRunnerAutoscalerDecision struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+
+	// ScaleTarget is the name of the RunnerDeployment scaled by the HorizontalRunnerAutoscaler, or empty when it's selected by labels
+	// and none matches yet.
+	ScaleTarget string `json:"scaleTarget,omitempty"`
+
+	DesiredReplicas *int `json:"desiredReplicas,omitempty"`
+
+	// CurrentReplicas is the desired replicas the scale target currently has, and AvailableReplicas the available ones.
+	// Both are omitted when the scale target can't be found.
+	CurrentReplicas   *int `json:"currentReplicas,omitempty"`
+	AvailableReplicas *int `json:"availableReplicas,omitempty"`
+
+	LastScaleOutTime *metav1.Time `json:"lastScaleOutTime,omitempty"`
+	LastScaleUpTime  *metav1.Time `json:"lastScaleUpTime,omitempty"`
+
+	ActiveCapacityReservations v1alpha1.ActiveCapacityReservations `json:"activeCapacityReservations"`
+
+	Cache HorizontalRunnerAutoscalerCacheState `json:"cache"`
+
+	WinningMetricType string `json:"winningMetricType,omitempty"`
+	Paused            bool   `json:"paused,omitempty"`
+	DryRun            bool   `json:"dryRun,omitempty"`
+
+	ConsecutiveFailures int                 `json:"consecutiveFailures,omitempty"`
+	LastError           *v1alpha1.LastError `json:"lastError,omitempty"`
+}
+
+// HorizontalRunnerAutoscalerCacheState is the state of the cached desired replicas of a HorizontalRunnerAutoscaler.
+type HorizontalRunnerAutoscalerCacheState struct {
+	// Hit is true when the next reconciliation would use the cached desired replicas, unless the inputs change.
+	Hit bool `json:"hit"`
+
+	// DesiredReplicas is the cached desired replicas, set only when Hit is true.
+	DesiredReplicas *int `json:"desiredReplicas,omitempty"`
+
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+}
+
+// HorizontalRunnerAutoscalerDecisionsHandler serves the decisions of all the HorizontalRunnerAutoscalers as a JSON array.
+// Reader is meant to be the client of the manager, so that the decisions are built from the informer cache
+// without hitting the API server on each request.
+type HorizontalRunnerAutoscalerDecisionsHandler struct {
+	Reader client.Reader
+	Log    logr.Logger
+}
+
+func (h *HorizontalRunnerAutoscalerDecisionsHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	decisions, err := h.listDecisions(req.Context(), req.URL.Query().Get("namespace"), time.Now())
+	if err != nil {
+		h.Log.Error(err, "Failed to list horizontalrunnerautoscaler decisions")
+
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(decisions); err != nil {
+		h.Log.Error(err, "Failed to write horizontalrunnerautoscaler decisions")
+	}
+}
+
+func (h *HorizontalRunnerAutoscalerDecisionsHandler) listDecisions(ctx context.Context, namespace string, now time.Time) ([]HorizontalRunnerAutoscalerDecision, error) {
+	var hras v1alpha1.HorizontalRunnerAutoscalerList
+
+	if err := h.Reader.List(ctx, &hras, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(hras.Items, func(i, j int) bool {
+		a, b := hras.Items[i], hras.Items[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+
+		return a.Name < b.Name
+	})
+
+	// Always an array, so that consumers don't need to handle null
+	decisions := []HorizontalRunnerAutoscalerDecision{}
+
+	for _, hra := range hras.Items {
+		d := HorizontalRunnerAutoscalerDecision{
+			Name:                       hra.Name,
+			Namespace:                  hra.Namespace,
+			ScaleTarget:                hra.Spec.ScaleTargetRef.Name,
+			DesiredReplicas:            hra.Status.DesiredReplicas,
+			LastScaleOutTime:           hra.Status.LastSuccessfulScaleOutTime,
+			LastScaleUpTime:            hra.Status.LastScaleUpTime,
+			ActiveCapacityReservations: hra.Status.ActiveCapacityReservations,
+			Cache:                      getCacheState(hra, now),
+			WinningMetricType:          hra.Status.WinningMetricType,
+			Paused:                     isPaused(hra),
+			DryRun:                     hra.Spec.DryRun,
+			ConsecutiveFailures:        hra.Status.ConsecutiveFailures,
+			LastError:                  hra.Status.LastError,
+		}
+
+		if d.ScaleTarget != "" {
+			var rd v1alpha1.RunnerDeployment
+
+			if err := h.Reader.Get(ctx, types.NamespacedName{Namespace: hra.Namespace, Name: d.ScaleTarget}, &rd); err != nil {
+				if !kerrors.IsNotFound(err) {
+					return nil, err
+				}
+			} else {
+				available := rd.Status.AvailableReplicas
+
+				d.CurrentReplicas = rd.Spec.Replicas
+				d.AvailableReplicas = &available
+			}
+		}
+
+		decisions = append(decisions, d)
+	}
+
+	return decisions, nil
+}
+
+// getCacheState returns the state of the cached desired replicas of the HorizontalRunnerAutoscaler as of now.
+// Unlike getCachedDesiredReplicas, it ignores the inputs and the cache bust, as they're known only to the reconciliation.
+func getCacheState(hra v1alpha1.HorizontalRunnerAutoscaler, now time.Time) HorizontalRunnerAutoscalerCacheState {
+	s := HorizontalRunnerAutoscalerCacheState{ExpiresAt: hra.Status.CacheExpiresAt}
+
+	for _, ent := range hra.Status.CacheEntries {
+		if ent.Key != v1alpha1.CacheEntryKeyDesiredReplicas || !now.Before(ent.ExpirationTime.Time) {
+			continue
+		}
+
+		v := ent.Value
+
+		s.Hit = true
+		s.DesiredReplicas = &v
+
+		if s.ExpiresAt == nil {
+			s.ExpiresAt = ent.ExpirationTime.DeepCopy()
+		}
+
+		break
+	}
+
+	return s
+}
+
+// AdminServer serves the admin endpoint. It implements manager.Runnable, so that it's started and stopped along with the manager.
+type AdminServer struct {
+	// Addr is the address the server binds to.
+	Addr    string
+	Handler http.Handler
+	Log     logr.Logger
+}
+
+// Start serves the admin endpoint until stop is closed.
+func (s *AdminServer) Start(stop <-chan struct{}) error {
+	srv := &http.Server{
+		Addr:    s.Addr,
+		Handler: s.Handler,
+	}
+
+	go func() {
+		<-stop
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		if err := srv.Shutdown(ctx); err != nil {
+			s.Log.Error(err, "Failed to shut down the admin server")
+		}
+	}()
+
+	s.Log.Info("Starting the admin server", "addr", s.Addr)
+
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// NeedLeaderElection returns false, so that every replica of the controller serves the admin endpoint.
+func (s *AdminServer) NeedLeaderElection() bool {
+	return false
+}
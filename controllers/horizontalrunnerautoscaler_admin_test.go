@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func TestHorizontalRunnerAutoscalerDecisionsHandler(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = v1alpha1.AddToScheme(scheme)
+
+	now := time.Now()
+
+	rd := &v1alpha1.RunnerDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "testrd", Namespace: "default"},
+		Spec:       v1alpha1.RunnerDeploymentSpec{Replicas: intPtr(3)},
+		Status:     v1alpha1.RunnerDeploymentStatus{AvailableReplicas: 2},
+	}
+
+	cached := &v1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "b", Namespace: "default"},
+		Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+			ScaleTargetRef: v1alpha1.ScaleTargetRef{Name: "testrd"},
+		},
+		Status: v1alpha1.HorizontalRunnerAutoscalerStatus{
+			DesiredReplicas: intPtr(3),
+			CacheEntries: []v1alpha1.CacheEntry{{
+				Key:            v1alpha1.CacheEntryKeyDesiredReplicas,
+				Value:          3,
+				ExpirationTime: metav1.Time{Time: now.Add(5 * time.Minute)},
+			}},
+			ActiveCapacityReservations: v1alpha1.ActiveCapacityReservations{Count: 1, Replicas: 2},
+		},
+	}
+
+	expired := &v1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "default"},
+		Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+			ScaleTargetRef: v1alpha1.ScaleTargetRef{Name: "missing"},
+		},
+		Status: v1alpha1.HorizontalRunnerAutoscalerStatus{
+			CacheEntries: []v1alpha1.CacheEntry{{
+				Key:            v1alpha1.CacheEntryKeyDesiredReplicas,
+				Value:          1,
+				ExpirationTime: metav1.Time{Time: now.Add(-5 * time.Minute)},
+			}},
+		},
+	}
+
+	other := &v1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "c", Namespace: "other"},
+	}
+
+	h := &HorizontalRunnerAutoscalerDecisionsHandler{
+		Reader: clientfake.NewFakeClientWithScheme(scheme, rd, cached, expired, other),
+		Log: zap.New(func(o *zap.Options) {
+			o.Development = true
+		}),
+	}
+
+	t.Run("all namespaces", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, httptest.NewRequest(http.MethodGet, HorizontalRunnerAutoscalerDecisionsPath, nil))
+
+		if res.Code != http.StatusOK {
+			t.Fatalf("unexpected status: %d: %s", res.Code, res.Body.String())
+		}
+
+		var got []HorizontalRunnerAutoscalerDecision
+		if err := json.Unmarshal(res.Body.Bytes(), &got); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(got) != 3 {
+			t.Fatalf("unexpected number of decisions: want 3, got %d", len(got))
+		}
+
+		// Sorted by the namespace and the name
+		if got[0].Name != "a" || got[1].Name != "b" || got[2].Name != "c" {
+			t.Errorf("unexpected order: %s, %s, %s", got[0].Name, got[1].Name, got[2].Name)
+		}
+
+		a, b := got[0], got[1]
+
+		if a.Cache.Hit || a.CurrentReplicas != nil {
+			t.Errorf("unexpected decision of the hra with the expired cache and the missing scale target: %+v", a)
+		}
+
+		if !b.Cache.Hit || b.Cache.DesiredReplicas == nil || *b.Cache.DesiredReplicas != 3 || b.Cache.ExpiresAt == nil {
+			t.Errorf("unexpected cache state: %+v", b.Cache)
+		}
+
+		if b.CurrentReplicas == nil || *b.CurrentReplicas != 3 || b.AvailableReplicas == nil || *b.AvailableReplicas != 2 {
+			t.Errorf("unexpected replicas of the scale target: %+v", b)
+		}
+
+		if b.ActiveCapacityReservations.Replicas != 2 {
+			t.Errorf("unexpected active capacity reservations: %+v", b.ActiveCapacityReservations)
+		}
+	})
+
+	t.Run("namespace", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, httptest.NewRequest(http.MethodGet, HorizontalRunnerAutoscalerDecisionsPath+"?namespace=other", nil))
+
+		var got []HorizontalRunnerAutoscalerDecision
+		if err := json.Unmarshal(res.Body.Bytes(), &got); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(got) != 1 || got[0].Name != "c" {
+			t.Errorf("unexpected decisions: %+v", got)
+		}
+	})
+
+	t.Run("read-only", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, httptest.NewRequest(http.MethodPost, HorizontalRunnerAutoscalerDecisionsPath, nil))
+
+		if res.Code != http.StatusMethodNotAllowed {
+			t.Errorf("unexpected status: want %d, got %d", http.StatusMethodNotAllowed, res.Code)
+		}
+	})
+}
@@ -0,0 +1,205 @@
+package controllers
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// replicaBudget keeps track of the replicas allocated to each HorizontalRunnerAutoscaler out of
+// the reconciler-wide GlobalMaxReplicas, so that the sum of the replicas never exceeds it.
+type replicaBudget struct {
+	mu sync.Mutex
+
+	// entries is nil until it's seeded with all the existing HorizontalRunnerAutoscalers,
+	// so that the replicas of the ones not yet reconciled since the controller started are accounted too.
+	entries map[types.NamespacedName]*replicaBudgetEntry
+}
+
+type replicaBudgetEntry struct {
+	weight int
+
+	// demand is the number of replicas the HorizontalRunnerAutoscaler wants
+	demand int
+
+	// allocated is the number of replicas the HorizontalRunnerAutoscaler is allowed to have
+	allocated int
+}
+
+func getBudgetWeight(hra v1alpha1.HorizontalRunnerAutoscaler) int {
+	if hra.Spec.Weight != nil && *hra.Spec.Weight > 0 {
+		return *hra.Spec.Weight
+	}
+
+	return 1
+}
+
+// allocateFromGlobalBudget returns the number of replicas, up to demand, that the HorizontalRunnerAutoscaler
+// is allowed to have without exceeding GlobalMaxReplicas across all the HorizontalRunnerAutoscalers.
+//
+// Each HorizontalRunnerAutoscaler is guaranteed its share of the budget proportional to its weight, and the shares
+// unused by the ones demanding less are redistributed to the others. It never allocates more than what's left
+// after the current allocations of the others, so a HorizontalRunnerAutoscaler whose share has shrunk gets more
+// only after the others have released theirs on their reconciliations.
+func (r *HorizontalRunnerAutoscalerReconciler) allocateFromGlobalBudget(ctx context.Context, hra v1alpha1.HorizontalRunnerAutoscaler, demand int) (int, error) {
+	b := &r.budget
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.entries == nil {
+		var hraList v1alpha1.HorizontalRunnerAutoscalerList
+		if err := r.List(ctx, &hraList); err != nil {
+			return 0, err
+		}
+
+		b.entries = map[types.NamespacedName]*replicaBudgetEntry{}
+
+		for _, h := range hraList.Items {
+			var replicas int
+			if h.Status.DesiredReplicas != nil {
+				targets, err := r.getScaleTargetCount(ctx, h)
+				if err != nil {
+					return 0, err
+				}
+
+				replicas = *h.Status.DesiredReplicas * targets
+			}
+
+			b.entries[types.NamespacedName{Namespace: h.Namespace, Name: h.Name}] = &replicaBudgetEntry{
+				weight:    getBudgetWeight(h),
+				demand:    replicas,
+				allocated: replicas,
+			}
+		}
+	}
+
+	key := types.NamespacedName{Namespace: hra.Namespace, Name: hra.Name}
+
+	ent, ok := b.entries[key]
+	if !ok {
+		ent = &replicaBudgetEntry{}
+		b.entries[key] = ent
+	}
+
+	ent.weight = getBudgetWeight(hra)
+	ent.demand = demand
+
+	allocated := allocateByWeight(r.GlobalMaxReplicas, b.entries, r.GlobalMaxReplicasShareByDemand)[key]
+
+	var others int
+	for k, e := range b.entries {
+		if k != key {
+			others += e.allocated
+		}
+	}
+
+	if left := r.GlobalMaxReplicas - others; allocated > left {
+		allocated = left
+	}
+
+	if allocated < 0 {
+		allocated = 0
+	}
+
+	ent.allocated = allocated
+
+	return allocated, nil
+}
+
+// releaseGlobalBudget forgets the HorizontalRunnerAutoscaler so that its allocation is made available to the others.
+func (r *HorizontalRunnerAutoscalerReconciler) releaseGlobalBudget(key types.NamespacedName) {
+	b := &r.budget
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.entries, key)
+}
+
+// allocateByWeight splits total among the entries in proportion to their weights, without allocating more than the demand
+// of each entry. The shares left unused by the entries demanding less than their shares are redistributed to the others.
+// When byDemand is true, the weight of each entry is multiplied by its demand, so that an over-subscribed total is split
+// in proportion to the demands rather than equally.
+// The replicas left over by rounding the shares down go to the entries with the largest fractions, so that
+// an over-subscribed total is allocated in full.
+func allocateByWeight(total int, entries map[types.NamespacedName]*replicaBudgetEntry, byDemand bool) map[types.NamespacedName]int {
+	allocations := map[types.NamespacedName]int{}
+
+	var pending []types.NamespacedName
+
+	for k, e := range entries {
+		if e.demand > 0 {
+			pending = append(pending, k)
+		}
+	}
+
+	// Make the result deterministic regardless of the map iteration order
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].String() < pending[j].String()
+	})
+
+	weight := func(k types.NamespacedName) int {
+		e := entries[k]
+
+		if byDemand {
+			return e.weight * e.demand
+		}
+
+		return e.weight
+	}
+
+	remaining := total
+
+	for len(pending) > 0 {
+		var totalWeight int
+		for _, k := range pending {
+			totalWeight += weight(k)
+		}
+
+		var unsatisfied []types.NamespacedName
+
+		for _, k := range pending {
+			e := entries[k]
+
+			if e.demand*totalWeight <= remaining*weight(k) {
+				allocations[k] = e.demand
+			} else {
+				unsatisfied = append(unsatisfied, k)
+			}
+		}
+
+		if len(unsatisfied) == len(pending) {
+			left := remaining
+
+			for _, k := range pending {
+				allocations[k] = remaining * weight(k) / totalWeight
+				left -= allocations[k]
+			}
+
+			// Every entry here demands more than its exact share, so rounding one up never exceeds its demand
+			sort.SliceStable(pending, func(i, j int) bool {
+				return remaining*weight(pending[i])%totalWeight > remaining*weight(pending[j])%totalWeight
+			})
+
+			for i := 0; i < left; i++ {
+				allocations[pending[i]]++
+			}
+
+			break
+		}
+
+		for _, k := range pending {
+			if _, ok := allocations[k]; ok {
+				remaining -= allocations[k]
+			}
+		}
+
+		pending = unsatisfied
+	}
+
+	return allocations
+}
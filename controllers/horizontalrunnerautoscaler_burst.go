@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"math"
+	"time"
+
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// DefaultBurstDurationSeconds is the default duration the full burst credits last while all the replicas up to BurstMaxReplicas are used.
+	DefaultBurstDurationSeconds = 600
+
+	// DefaultBurstRefillSeconds is the default duration the exhausted burst credits take to refill.
+	DefaultBurstRefillSeconds = 3600
+)
+
+// getBurstCreditCapacity returns the capacity of the burst credits in replica-seconds, or zero when bursting isn't enabled.
+func getBurstCreditCapacity(hra v1alpha1.HorizontalRunnerAutoscaler) int {
+	burst, max := hra.Spec.BurstMaxReplicas, hra.Spec.MaxReplicas
+	if burst == nil || max == nil || *burst <= *max {
+		return 0
+	}
+
+	return (*burst - *max) * getIntOrDefault(hra.Spec.BurstDurationSeconds, DefaultBurstDurationSeconds)
+}
+
+// accountBurstCredits returns the burst credits accounted up to now, given that the scale target has had currentReplicas
+// since the last accounting. Each replica above MaxReplicas consumes one credit per second, while the consumed credits
+// refill at the constant rate of the capacity per BurstRefillSeconds, also while bursting.
+// The consumed credits never exceed the capacity, so that the credits start refilling right after they're exhausted.
+//
+// It returns nil when bursting isn't enabled, or the credits are full and the replicas are within MaxReplicas,
+// so that nothing is recorded in the steady state.
+func accountBurstCredits(hra v1alpha1.HorizontalRunnerAutoscaler, currentReplicas int, now time.Time) *v1alpha1.BurstCreditsStatus {
+	capacity := getBurstCreditCapacity(hra)
+	if capacity == 0 {
+		return nil
+	}
+
+	bursting := currentReplicas - *hra.Spec.MaxReplicas
+	if bursting < 0 {
+		bursting = 0
+	}
+
+	var consumed int
+
+	if last := hra.Status.BurstCredits; last != nil {
+		elapsed := now.Sub(last.LastUpdateTime.Time).Seconds()
+		if elapsed < 0 {
+			elapsed = 0
+		}
+
+		refillSeconds := getIntOrDefault(hra.Spec.BurstRefillSeconds, DefaultBurstRefillSeconds)
+
+		refilled := float64(capacity) * elapsed / float64(refillSeconds)
+
+		consumed = int(math.Round(float64(last.ConsumedReplicaSeconds) + float64(bursting)*elapsed - refilled))
+	}
+
+	if consumed < 0 {
+		consumed = 0
+	} else if consumed > capacity {
+		consumed = capacity
+	}
+
+	if consumed == 0 && bursting == 0 {
+		return nil
+	}
+
+	return &v1alpha1.BurstCreditsStatus{
+		ConsumedReplicaSeconds: consumed,
+		LastUpdateTime:         metav1.Time{Time: now},
+	}
+}
+
+// isBurstCreditsExhausted returns true when no burst credits remain.
+func isBurstCreditsExhausted(hra v1alpha1.HorizontalRunnerAutoscaler, credits *v1alpha1.BurstCreditsStatus) bool {
+	capacity := getBurstCreditCapacity(hra)
+
+	return capacity == 0 || (credits != nil && credits.ConsumedReplicaSeconds >= capacity)
+}
+
+// withBurstMaxReplicas returns the HorizontalRunnerAutoscaler with MaxReplicas raised to BurstMaxReplicas while burst credits remain,
+// so that the metrics, the capacity reservations and all the other limits see the burst ceiling as MaxReplicas.
+// The credits are checked only once per reconciliation, so the replicas can exceed MaxReplicas until the next reconciliation after they're exhausted.
+func withBurstMaxReplicas(hra v1alpha1.HorizontalRunnerAutoscaler, credits *v1alpha1.BurstCreditsStatus) v1alpha1.HorizontalRunnerAutoscaler {
+	if isBurstCreditsExhausted(hra, credits) {
+		return hra
+	}
+
+	burst := *hra.Spec.BurstMaxReplicas
+
+	hra.Spec.MaxReplicas = &burst
+
+	return hra
+}
+
+// startBurstCredits returns the burst credits to record once the scale target is scaled to desiredReplicas.
+// The accounting starts on scaling out beyond maxReplicas, the MaxReplicas before the burst ceiling is applied,
+// so that the first interval of the burst is accounted too.
+func startBurstCredits(credits *v1alpha1.BurstCreditsStatus, maxReplicas *int, desiredReplicas int, now time.Time) *v1alpha1.BurstCreditsStatus {
+	if credits != nil || maxReplicas == nil || desiredReplicas <= *maxReplicas {
+		return credits
+	}
+
+	return &v1alpha1.BurstCreditsStatus{LastUpdateTime: metav1.Time{Time: now}}
+}
+
+func burstCreditsEqual(a, b *v1alpha1.BurstCreditsStatus) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return a.ConsumedReplicaSeconds == b.ConsumedReplicaSeconds && a.LastUpdateTime.Equal(&b.LastUpdateTime)
+}
@@ -18,18 +18,29 @@ package controllers
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/summerwind/actions-runner-controller/github"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/uuid"
 
 	"github.com/go-logr/logr"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
@@ -37,18 +48,134 @@ import (
 
 const (
 	DefaultScaleDownDelay = 10 * time.Minute
+
+	// DefaultAbuseRateLimitRetryAfter is the duration to back off on hitting the GitHub API abuse rate limit
+	// without a Retry-After header.
+	DefaultAbuseRateLimitRetryAfter = time.Minute
+
+	// DefaultCacheDurationJitter is the default fraction of the cache duration that is randomly added to or subtracted from
+	// the expiration time of each cache entry.
+	DefaultCacheDurationJitter = 0.1
+
+	// ScaleDownReadinessGateRequeueDelay is the delay to retry scaling down while the scale down readiness gate is active.
+	ScaleDownReadinessGateRequeueDelay = 10 * time.Second
+
+	// DefaultFailureBackoff is the duration to wait before retrying after the first failure to compute the desired replicas.
+	// It doubles on each consecutive failure until it reaches DefaultMaxFailureBackoff.
+	DefaultFailureBackoff = 10 * time.Second
+
+	// DefaultMaxFailureBackoff is the maximum duration to wait before retrying after consecutive failures.
+	DefaultMaxFailureBackoff = 10 * time.Minute
+
+	// MaxReplicasReachedEventInterval is the minimum interval between MaxReplicasReached events of a HorizontalRunnerAutoscaler,
+	// so that a long-lasting shortage of MaxReplicas doesn't result in an event on every reconciliation.
+	MaxReplicasReachedEventInterval = 30 * time.Minute
+
+	// ScaleTargetNotFoundRequeueDelay is the delay to retry reconciling a HorizontalRunnerAutoscaler whose scale target doesn't exist,
+	// so that it recovers once the scale target is created.
+	ScaleTargetNotFoundRequeueDelay = time.Minute
+
+	// ScaleTargetGenerationPendingRequeueDelay is the delay to retry scaling a runnerdeployment whose last update to the spec
+	// is still being reconciled by the runnerdeployment controller.
+	ScaleTargetGenerationPendingRequeueDelay = 5 * time.Second
+
+	// DefaultRequeueInterval is the default interval at which each HorizontalRunnerAutoscaler is reconciled on success.
+	DefaultRequeueInterval = time.Minute
+
+	// DefaultMetricTimeout is the default timeout of evaluating each metric, including the GitHub API calls made for it.
+	DefaultMetricTimeout = 30 * time.Second
+
+	// AnnotationKeyDesiredReplicasOverride is the annotation on a HorizontalRunnerAutoscaler to pin the desired replicas
+	// of the scale target to the specified number, bypassing all the metrics, e.g. for incident response.
+	AnnotationKeyDesiredReplicasOverride = "actions.summerwind.dev/desired-replicas-override"
+
+	// AnnotationKeyCacheBust is the annotation on a HorizontalRunnerAutoscaler whose RFC3339 timestamp value forces the desired replicas
+	// to be recomputed from the metrics when it's newer than the cached one, e.g. for debugging the metrics.
+	// The recomputed desired replicas are cached as usual, so leaving the annotation as is doesn't disable the cache.
+	AnnotationKeyCacheBust = "actions.summerwind.dev/cache-bust"
+
+	// AnnotationKeyPaused is the annotation on a HorizontalRunnerAutoscaler that freezes the desired replicas of the scale target
+	// while its value is "true", e.g. for maintenance. Neither the metrics are computed nor the scale target is updated while paused.
+	AnnotationKeyPaused = "actions.summerwind.dev/paused"
+
+	scaleTargetKindRunnerDeployment = "RunnerDeployment"
+
+	// maxLastErrorMessageLength is the maximum length of the message of the LastError in the status,
+	// which keeps the object small even when e.g. all the metrics fail with long errors.
+	maxLastErrorMessageLength = 1024
 )
 
 // HorizontalRunnerAutoscalerReconciler reconciles a HorizontalRunnerAutoscaler object
 type HorizontalRunnerAutoscalerReconciler struct {
 	client.Client
 	GitHubClient *github.Client
-	Log          logr.Logger
-	Recorder     record.EventRecorder
-	Scheme       *runtime.Scheme
+	// GitHubClientPool provides the clients for the HorizontalRunnerAutoscalers specifying GitHubAppInstallation.
+	// It's nil unless the controller authenticates as a GitHub App.
+	GitHubClientPool *github.ClientPool
+	Log              logr.Logger
+	Recorder         record.EventRecorder
+	Scheme           *runtime.Scheme
+
+	// GitHubConfig is the configuration GitHubClient is created from. Its GitHub Enterprise Server URLs are used for
+	// the clients created from the credentials of GitHubAPICredentialsFrom.
+	GitHubConfig github.Config
 
 	CacheDuration time.Duration
-	Name          string
+	// CacheDurationJitter is the fraction of the cache duration to randomly spread cache expirations by, so that
+	// cache entries of many HorizontalRunnerAutoscalers don't expire, and hit GitHub API, at once.
+	// Zero defaults to DefaultCacheDurationJitter, and a negative value disables the jitter.
+	CacheDurationJitter float64
+	// GlobalMaxReplicas is the maximum number of replicas across all the HorizontalRunnerAutoscalers, which is split among them
+	// by their weights. Zero means unlimited.
+	GlobalMaxReplicas int
+	// GlobalMaxReplicasShareByDemand makes the shares of GlobalMaxReplicas proportional to the demand of each HorizontalRunnerAutoscaler
+	// multiplied by its weight, rather than to the weight alone, so that the ones demanding more get more when the budget is over-subscribed.
+	GlobalMaxReplicasShareByDemand bool
+	// MaxConcurrentReconciles is the maximum number of HorizontalRunnerAutoscalers reconciled concurrently.
+	// Each reconciliation can call GitHub API, so raising it makes the calls burstier and the rate limit is hit sooner
+	// when many HorizontalRunnerAutoscalers share the same credentials.
+	// Zero defaults to 1.
+	MaxConcurrentReconciles int
+	// GitHubAPIReachability, when set, records the outcomes of GitHub API calls made for computing the desired replicas
+	// for the readiness check.
+	GitHubAPIReachability *GitHubAPIReachability
+	// GitHubAPICircuitBreaker, when set, skips computing the desired replicas from GitHub API while it's open,
+	// serving the last cached desired replicas instead.
+	GitHubAPICircuitBreaker *GitHubAPICircuitBreaker
+	// GitHubAPIBudget, when set, limits the rate of GitHub API calls made for computing the desired replicas
+	// by each HorizontalRunnerAutoscaler or organization, so that they share the rate limit fairly.
+	GitHubAPIBudget *GitHubAPIBudget
+	// MetricProviders registers the providers of additional metric types, keyed by the metric type.
+	// A provider registered for a built-in metric type overrides the built-in one.
+	MetricProviders map[string]MetricProviderFactory
+	// MetricTimeout is the timeout of evaluating each metric, so that a hung GitHub API call fails the metric
+	// rather than blocking the reconciliation. Zero defaults to DefaultMetricTimeout.
+	MetricTimeout time.Duration
+	// RunnerListCacheTTL is the duration for which a listing of the runners registered to GitHub is reused
+	// across the HorizontalRunnerAutoscalers sharing the same enterprise, organization, repository, or runner group.
+	// Zero defaults to DefaultRunnerListCacheTTL, and a negative value disables the cache.
+	RunnerListCacheTTL time.Duration
+	// RequeueInterval is the interval at which each HorizontalRunnerAutoscaler is reconciled on success, unless
+	// anything else like the cache expiration requeues it sooner. It's independent of the cache duration,
+	// so reconciling more often than the cache expires doesn't call GitHub API more often.
+	// Zero disables it, leaving the periodic reconciliation to the sync period.
+	RequeueInterval time.Duration
+	// EventVerbosity controls the events emitted on the HorizontalRunnerAutoscalers.
+	// Empty defaults to DefaultEventVerbosity.
+	EventVerbosity EventVerbosity
+	// NilReplicasPolicy controls the current replicas assumed for the RunnerDeployment without spec.replicas.
+	// Empty defaults to DefaultNilReplicasPolicy.
+	NilReplicasPolicy NilReplicasPolicy
+	// UseGraphQL lists the queued and in-progress workflow runs of each repository, along with the numbers of their jobs,
+	// in a single GitHub GraphQL API call rather than paging through them and listing the jobs of each run over REST.
+	// It falls back to REST for the repository when the GraphQL call fails.
+	UseGraphQL bool
+	Name       string
+
+	budget                   replicaBudget
+	runnerListCache          runnerListCache
+	workflowDurationCache    workflowDurationCache
+	githubCredentialsClients githubCredentialsClients
 }
 
 // +kubebuilder:rbac:groups=actions.summerwind.dev,resources=runnerdeployments,verbs=get;list;watch;update;patch
@@ -56,174 +183,1532 @@ type HorizontalRunnerAutoscalerReconciler struct {
 // +kubebuilder:rbac:groups=actions.summerwind.dev,resources=horizontalrunnerautoscalers/finalizers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=actions.summerwind.dev,resources=horizontalrunnerautoscalers/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=actions.summerwind.dev,resources=runnerreplicasets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
 
 func (r *HorizontalRunnerAutoscalerReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	ctx := context.Background()
-	log := r.Log.WithValues("horizontalrunnerautoscaler", req.NamespacedName)
+	// The reconcile ID correlates all the log lines of a reconciliation, including the scaling decision
+	log := r.Log.WithValues("horizontalrunnerautoscaler", req.NamespacedName, "reconcileID", uuid.NewUUID())
 
 	var hra v1alpha1.HorizontalRunnerAutoscaler
 	if err := r.Get(ctx, req.NamespacedName, &hra); err != nil {
+		if kerrors.IsNotFound(err) {
+			deleteHorizontalRunnerAutoscalerMetrics(req.Namespace, req.Name)
+			r.releaseGlobalBudget(req.NamespacedName)
+		}
+
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
 	if !hra.ObjectMeta.DeletionTimestamp.IsZero() {
+		deleteHorizontalRunnerAutoscalerMetrics(req.Namespace, req.Name)
+		r.releaseGlobalBudget(req.NamespacedName)
+
 		return ctrl.Result{}, nil
 	}
 
-	var rd v1alpha1.RunnerDeployment
-	if err := r.Get(ctx, types.NamespacedName{
-		Namespace: req.Namespace,
-		Name:      hra.Spec.ScaleTargetRef.Name,
-	}, &rd); err != nil {
-		return ctrl.Result{}, client.IgnoreNotFound(err)
-	}
+	if isPaused(hra) {
+		msg := fmt.Sprintf("Autoscaling is paused by the %s annotation", AnnotationKeyPaused)
+
+		updated := hra.DeepCopy()
+
+		if setHorizontalRunnerAutoscalerCondition(&updated.Status, newPausedCondition(corev1.ConditionTrue, "PausedByAnnotation", msg), time.Now()) {
+			r.event(&hra, corev1.EventTypeNormal, "Paused", msg)
 
-	if !rd.ObjectMeta.DeletionTimestamp.IsZero() {
+			log.Info(msg)
+
+			if err := r.Status().Update(ctx, updated); err != nil {
+				log.Error(err, "Failed to update horizontalrunnerautoscaler status")
+
+				return ctrl.Result{}, err
+			}
+		}
+
+		// Removing the annotation triggers another reconciliation, which resumes autoscaling
 		return ctrl.Result{}, nil
 	}
 
-	var replicas *int
+	for _, ref := range append([]v1alpha1.ScaleTargetRef{hra.Spec.ScaleTargetRef}, hra.Spec.AdditionalScaleTargetRefs...) {
+		if kind := ref.Kind; kind != "" && kind != scaleTargetKindRunnerDeployment {
+			msg := fmt.Sprintf("Unsupported scale target kind %q. Only %s is supported", kind, scaleTargetKindRunnerDeployment)
 
-	replicasFromCache := r.getDesiredReplicasFromCache(hra)
+			r.event(&hra, corev1.EventTypeWarning, "UnsupportedScaleTargetKind", msg)
 
-	if replicasFromCache != nil {
-		replicas = replicasFromCache
-	} else {
-		var err error
+			log.Info(msg)
 
-		replicas, err = r.computeReplicas(rd, hra)
-		if err != nil {
-			r.Recorder.Event(&hra, corev1.EventTypeNormal, "RunnerAutoscalingFailure", err.Error())
+			r.updateReadyCondition(ctx, log, hra, corev1.ConditionFalse, "UnsupportedScaleTargetKind", msg)
 
-			log.Error(err, "Could not compute replicas")
+			// Retrying doesn't help until the HorizontalRunnerAutoscaler is updated
+			return ctrl.Result{}, nil
+		}
+	}
+
+	var (
+		rd              v1alpha1.RunnerDeployment
+		selectedTargets []v1alpha1.RunnerDeployment
+	)
+
+	if hasScaleTargetSelector(hra) {
+		selected, err := r.getSelectedScaleTargets(ctx, hra)
+		if err != nil {
+			log.Error(err, "Could not list runnerdeployments matching scale target selector")
 
 			return ctrl.Result{}, err
 		}
+
+		if len(selected) == 0 {
+			msg := fmt.Sprintf("No runnerdeployment matches the labels %v", hra.Spec.ScaleTargetRef.MatchLabels)
+
+			r.event(&hra, corev1.EventTypeWarning, "NoScaleTargetMatched", msg)
+
+			log.Info(msg)
+
+			r.updateReadyCondition(ctx, log, hra, corev1.ConditionFalse, "NoScaleTargetMatched", msg)
+
+			// The label changes of runnerdeployments are watched, so there's no need to retry
+			return ctrl.Result{}, nil
+		}
+
+		// The metrics are computed against the first one, and the rest are scaled along with it
+		rd, selectedTargets = selected[0], selected[1:]
+	} else {
+		if err := r.Get(ctx, types.NamespacedName{
+			Namespace: req.Namespace,
+			Name:      hra.Spec.ScaleTargetRef.Name,
+		}, &rd); err != nil {
+			if !kerrors.IsNotFound(err) {
+				return ctrl.Result{}, err
+			}
+
+			msg := fmt.Sprintf("Scale target runnerdeployment %s not found", hra.Spec.ScaleTargetRef.Name)
+
+			// The event is emitted only on the transition, as the same reconciliation is repeated until the scale target is created
+			if !hasReadyConditionReason(hra.Status, "ScaleTargetNotFound") {
+				r.event(&hra, corev1.EventTypeWarning, "TargetMissing", msg)
+
+				log.Info(msg)
+			}
+
+			r.updateReadyCondition(ctx, log, hra, corev1.ConditionFalse, "ScaleTargetNotFound", msg)
+
+			return ctrl.Result{RequeueAfter: ScaleTargetNotFoundRequeueDelay}, nil
+		}
+
+		if !rd.ObjectMeta.DeletionTimestamp.IsZero() {
+			return ctrl.Result{}, nil
+		}
+	}
+
+	additionalTargets, err := r.getAdditionalScaleTargets(ctx, hra)
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			r.event(&hra, corev1.EventTypeWarning, "ScaleTargetNotFound", err.Error())
+
+			r.updateReadyCondition(ctx, log, hra, corev1.ConditionFalse, "ScaleTargetNotFound", err.Error())
+		}
+
+		log.Error(err, "Could not get additional scale targets")
+
+		return ctrl.Result{}, err
 	}
 
-	const defaultReplicas = 1
+	additionalTargets = append(selectedTargets, additionalTargets...)
 
-	currentDesiredReplicas := getIntOrDefault(rd.Spec.Replicas, defaultReplicas)
-	newDesiredReplicas := getIntOrDefault(replicas, defaultReplicas)
+	if !hra.Spec.DryRun {
+		if err := r.syncRunnerIdleTimeout(ctx, log, hra, &rd); err != nil {
+			log.Error(err, "Could not update the runner idle timeout", "runnerdeployment", rd.Name)
 
-	now := time.Now()
+			return ctrl.Result{}, err
+		}
+
+		for i := range additionalTargets {
+			if err := r.syncRunnerIdleTimeout(ctx, log, hra, &additionalTargets[i]); err != nil {
+				log.Error(err, "Could not update the runner idle timeout", "runnerdeployment", additionalTargets[i].Name)
 
-	for _, reservation := range hra.Spec.CapacityReservations {
-		if reservation.ExpirationTime.Time.After(now) {
-			newDesiredReplicas += reservation.Replicas
+				return ctrl.Result{}, err
+			}
 		}
 	}
 
-	if hra.Spec.MaxReplicas != nil && *hra.Spec.MaxReplicas < newDesiredReplicas {
-		newDesiredReplicas = *hra.Spec.MaxReplicas
+	now := time.Now()
+
+	// The reservations specified by duration are persisted with the absolute expiration time along with the pruning below,
+	// so that they don't expire relative to each reconciliation.
+	resolvedReservations, reservationsResolved := resolveCapacityReservationDurations(hra.Spec.CapacityReservations, now)
+	if reservationsResolved {
+		hra.Spec.CapacityReservations = resolvedReservations
 	}
 
-	// Please add more conditions that we can in-place update the newest runnerreplicaset without disruption
-	if currentDesiredReplicas != newDesiredReplicas {
-		copy := rd.DeepCopy()
-		copy.Spec.Replicas = &newDesiredReplicas
+	reservations, err := r.getEffectiveCapacityReservations(ctx, rd, hra, now)
+
+	retainedReservations := reservations
+
+	if err != nil {
+		// Failing to hold the expired reservations shouldn't block autoscaling, so we fall back to the unexpired ones.
+		// They're kept in the spec as is though, as we can't tell which of them are still held by busy runners.
+		log.Error(err, "Could not count busy runners for holding expired capacity reservations")
+
+		retainedReservations = hra.Spec.CapacityReservations
+	}
+
+	// Prune expired capacity reservations so that Spec.CapacityReservations doesn't grow unbounded.
+	// This results in at most one update per reconciliation, and only when there's an expired reservation.
+	// The expired reservations held by busy runners are kept until the runners are no longer busy.
+	if reservationsResolved || len(retainedReservations) != len(hra.Spec.CapacityReservations) {
+		copy := hra.DeepCopy()
+		copy.Spec.CapacityReservations = retainedReservations
 
 		if err := r.Client.Update(ctx, copy); err != nil {
-			log.Error(err, "Failed to update runnerderployment resource")
+			log.Error(err, "Failed to update capacity reservations")
 
 			return ctrl.Result{}, err
 		}
+
+		if reservationsResolved {
+			log.V(1).Info("Resolved the expiration time of capacity reservations specified by duration")
+		}
+
+		if len(retainedReservations) != len(hra.Spec.CapacityReservations) {
+			log.V(1).Info("Pruned expired capacity reservations", "before", len(hra.Spec.CapacityReservations), "after", len(retainedReservations))
+		}
+
+		hra = *copy
 	}
 
-	var updated *v1alpha1.HorizontalRunnerAutoscaler
+	policy, err := r.getPolicy(ctx, hra)
+	if err != nil {
+		log.Error(err, "Could not get policy configmap")
 
-	if hra.Status.DesiredReplicas == nil || *hra.Status.DesiredReplicas != newDesiredReplicas {
-		updated = hra.DeepCopy()
+		return ctrl.Result{}, err
+	}
 
-		if (hra.Status.DesiredReplicas == nil && newDesiredReplicas > 1) ||
-			(hra.Status.DesiredReplicas != nil && newDesiredReplicas > *hra.Status.DesiredReplicas) {
+	if ref := hra.Spec.PolicyRef; ref != nil && ref.Name != "" && policy == nil {
+		r.event(&hra, corev1.EventTypeWarning, "PolicyNotFound", fmt.Sprintf("Configmap %s referenced by spec.policyRef is not found. Falling back to the inline spec", ref.Name))
+	}
 
-			updated.Status.LastSuccessfulScaleOutTime = &metav1.Time{Time: time.Now()}
+	withPolicyApplied, err := withPolicy(hra, policy)
+	if err != nil {
+		r.event(&hra, corev1.EventTypeWarning, "InvalidPolicy", fmt.Sprintf("%v. Falling back to the inline spec", err))
+
+		log.Error(err, "Ignoring invalid policy")
+	}
+
+	override, active, upcoming, err := r.matchScheduledOverrides(log, now, hra)
+	if err != nil {
+		r.event(&hra, corev1.EventTypeWarning, "InvalidScheduledOverride", err.Error())
+
+		log.Error(err, "Could not match scheduled overrides")
+
+		r.updateReadyCondition(ctx, log, hra, corev1.ConditionFalse, "InvalidScheduledOverride", err.Error())
+
+		return ctrl.Result{}, err
+	}
+
+	scheduledOverridesSummary := getScheduledOverridesSummary(override, active, upcoming)
+
+	overridesChanged := !stringPtrEqual(hra.Status.ScheduledOverridesSummary, scheduledOverridesSummary)
+
+	if overridesChanged && active != nil {
+		r.event(&hra, corev1.EventTypeNormal, "ScheduledOverrideActive", *scheduledOverridesSummary)
+	}
+
+	// Scheduled overrides take precedence over the policy, as they are more specific to the HRA.
+	st := withScheduledOverride(withPolicyApplied, override)
+
+	// The current replicas of the scale target without spec.replicas are assumed according to the NilReplicasPolicy
+	currentReplicas := r.getCurrentReplicas(rd)
+
+	// The burst credits are consumed by the replicas the scale target has had above MaxReplicas since the last reconciliation
+	burstCredits := accountBurstCredits(st, getIntOrDefault(currentReplicas, getDefaultReplicas(st)), now)
+
+	if isBurstCreditsExhausted(st, burstCredits) && !isBurstCreditsExhausted(st, hra.Status.BurstCredits) {
+		msg := fmt.Sprintf("Burst credits are exhausted. Capping the replicas of runnerdeployment %s at maxReplicas(%d) until they refill", rd.Name, *st.Spec.MaxReplicas)
+		log.Info(msg)
+		r.event(&hra, corev1.EventTypeWarning, "BurstCreditsExhausted", msg)
+	}
+
+	// maxReplicasBeforeBurst is MaxReplicas the burst credits are accounted against
+	maxReplicasBeforeBurst := st.Spec.MaxReplicas
+
+	st = withBurstMaxReplicas(st, burstCredits)
+
+	var (
+		replicas *int
+		metric   *metricResult
+	)
+
+	var (
+		replicasFromCache *int
+		cacheExpiresAt    *metav1.Time
+	)
+
+	// circuitRetryAt is the time the open GitHub API circuit breaker lets the next probe through
+	var circuitRetryAt *time.Time
+
+	replicasOverride, err := getDesiredReplicasOverride(hra)
+	if err != nil {
+		r.event(&hra, corev1.EventTypeWarning, "InvalidDesiredReplicasOverride", err.Error())
+
+		log.Error(err, "Ignoring invalid desired replicas override")
+	}
+
+	cacheBustTime, err := getCacheBustTime(hra)
+	if err != nil {
+		r.event(&hra, corev1.EventTypeWarning, "InvalidCacheBust", err.Error())
+
+		log.Error(err, "Ignoring invalid cache bust")
+	}
+
+	if replicasOverride != nil {
+		msg := fmt.Sprintf("Desired replicas of runnerdeployment %s are overridden to %d by the %s annotation", rd.Name, *replicasOverride, AnnotationKeyDesiredReplicasOverride)
+
+		r.event(&hra, corev1.EventTypeNormal, "DesiredReplicasOverride", msg)
+
+		log.V(1).Info(msg)
+	} else if isEventsOnly(st) {
+		log.V(1).Info("Deriving the desired replicas from the capacity reservations without the metrics, as scaleMode is EventsOnly")
+	} else if deadline := getScaleDownStallDeadline(st, hra.Status.ScaleDownStalledSince); deadline != nil && !deadline.After(now) {
+		// The cached replicas are the ones deferred by the scale-down delay, so we recompute
+		// to force the scale down once the stall deadline passes.
+		log.V(1).Info("Ignoring the cache as the scale down has been stalled past the deadline", "deadline", deadline.Format(time.RFC3339))
+	} else if !overridesChanged {
+		// A change in the active scheduled override invalidates the cache so that
+		// e.g. an expired override stops affecting the desired replicas right at its EndTime.
+		replicasFromCache, cacheExpiresAt = r.getCachedDesiredReplicas(hra, getCacheInputsKey(st, getIntOrDefault(currentReplicas, getDefaultReplicas(st))), cacheBustTime)
+
+		// The capacity reservations aren't part of the cached replicas, so a reservation just added e.g. by a webhook
+		// takes effect right away by re-summing the reservations on top of the cache, without calling GitHub API.
+		if reserved := getReservedReplicas(st, reservations); replicasFromCache != nil && reserved > 0 {
+			log.V(1).Info("Reusing the cached desired replicas along with the replicas reserved by capacity reservations", "cached", *replicasFromCache, "reserved", reserved)
 		}
+	}
 
-		updated.Status.DesiredReplicas = &newDesiredReplicas
+	if replicasOverride == nil && !isEventsOnly(st) {
+		observeHorizontalRunnerAutoscalerCache(hra.Namespace, hra.Name, replicasFromCache != nil)
 	}
 
-	if replicasFromCache == nil {
-		if updated == nil {
-			updated = hra.DeepCopy()
+	if replicasOverride != nil {
+		replicas = replicasOverride
+	} else if isEventsOnly(st) {
+		metric = getEventsOnlyReplicas(st)
+		replicas = &metric.Replicas
+	} else if replicasFromCache != nil {
+		replicas = replicasFromCache
+	} else if allowed, probe, retryAt := r.allowGitHubAPICalls(st, now); !allowed {
+		circuitRetryAt = &retryAt
+
+		// The stale cache is served as if it were a cache hit, so that it isn't written back as a fresh entry
+		replicasFromCache = getLastCachedDesiredReplicas(hra)
+		if replicasFromCache == nil {
+			log.V(1).Info("Leaving the scale target as is, as the GitHub API circuit breaker is open and there are no cached desired replicas", "retryAt", retryAt.Format(time.RFC3339))
+
+			return ctrl.Result{RequeueAfter: retryAt.Sub(now)}, nil
 		}
 
-		var cacheEntries []v1alpha1.CacheEntry
+		log.V(1).Info("Serving the last cached desired replicas, as the GitHub API circuit breaker is open", "replicas", *replicasFromCache, "retryAt", retryAt.Format(time.RFC3339))
 
-		for _, ent := range updated.Status.CacheEntries {
-			if ent.ExpirationTime.Before(&metav1.Time{Time: now}) {
-				cacheEntries = append(cacheEntries, ent)
+		replicas = replicasFromCache
+	} else {
+		if probe {
+			r.event(&hra, corev1.EventTypeNormal, "GitHubAPICircuitBreakerHalfOpen", "Probing GitHub API for recovery, as the cooldown of the circuit breaker has elapsed")
+		}
+
+		replicas, metric, err = r.computeReplicas(ctx, rd, st)
+
+		// The budget is the controller throttling itself, so the HRA isn't marked as failing for it
+		var budgetExhausted *githubAPIBudgetExhaustedError
+		if errors.As(err, &budgetExhausted) {
+			r.event(&hra, corev1.EventTypeNormal, "GitHubAPIBudgetExhausted", err.Error())
+
+			log.V(1).Info("Backing off until the GitHub API budget refills", "key", budgetExhausted.Key, "retryAt", budgetExhausted.RetryAt)
+
+			// The scale target is left as is, which preserves the last desired replicas during the backoff.
+			requeueAfter := budgetExhausted.RetryAt.Sub(now)
+			if requeueAfter <= 0 {
+				requeueAfter = time.Second
 			}
+
+			return ctrl.Result{RequeueAfter: requeueAfter}, nil
 		}
 
-		var cacheDuration time.Duration
+		var rateLimited *rateLimitedError
+		if errors.As(err, &rateLimited) {
+			r.event(&hra, corev1.EventTypeWarning, "RateLimited", err.Error())
 
-		if r.CacheDuration > 0 {
-			cacheDuration = r.CacheDuration
-		} else {
-			cacheDuration = 10 * time.Minute
+			log.Info("Backing off until the GitHub API rate limit resets", "resetTime", rateLimited.ResetTime)
+
+			// Best-effort as the backoff below is more important than recording the error
+			updated := hra.DeepCopy()
+			updated.Status.LastError = newLastError(err, now)
+			setHorizontalRunnerAutoscalerCondition(&updated.Status, newReadyCondition(corev1.ConditionFalse, "RateLimited", err.Error()), now)
+
+			if err := r.Status().Update(ctx, updated); err != nil {
+				log.Error(err, "Failed to update horizontalrunnerautoscaler status")
+			}
+
+			// The scale target is left as is, which preserves the last desired replicas during the backoff.
+			requeueAfter := rateLimited.ResetTime.Sub(now)
+			if requeueAfter <= 0 {
+				requeueAfter = time.Second
+			}
+
+			return ctrl.Result{RequeueAfter: requeueAfter}, nil
+		}
+
+		if err != nil {
+			failures := hra.Status.ConsecutiveFailures + 1
+			backoff := getFailureBackoff(failures)
+
+			eventType, eventReason, reason := corev1.EventTypeNormal, "RunnerAutoscalingFailure", "GitHubAPIError"
+
+			// Broken credentials are fixed only by updating the secret, so they're surfaced as a warning of their own
+			var credentialsErr *githubAPICredentialsError
+			if errors.As(err, &credentialsErr) {
+				eventType, eventReason, reason = corev1.EventTypeWarning, "GitHubAPICredentialsError", "GitHubAPICredentialsError"
+			}
+
+			r.event(&hra, eventType, eventReason, fmt.Sprintf("%v; backing off for %s after %d consecutive failures", err, backoff, failures))
+
+			log.Error(err, "Could not compute replicas", "consecutiveFailures", failures, "backoff", backoff)
+
+			updated := hra.DeepCopy()
+			updated.Status.ConsecutiveFailures = failures
+			updated.Status.BackoffSeconds = int(backoff / time.Second)
+			updated.Status.LastError = newLastError(err, now)
+			setHorizontalRunnerAutoscalerCondition(&updated.Status, newReadyCondition(corev1.ConditionFalse, reason, err.Error()), now)
+
+			if err := r.Status().Update(ctx, updated); err != nil {
+				log.Error(err, "Failed to update horizontalrunnerautoscaler status")
+
+				return ctrl.Result{}, err
+			}
+
+			// We requeue by ourselves rather than returning the error, so that the backoff is per-HRA and
+			// survives restarts of the controller, and e.g. a broken token doesn't result in constant API calls.
+			return ctrl.Result{RequeueAfter: backoff}, nil
 		}
+	}
+
+	defaultReplicas := getDefaultReplicas(st)
 
-		updated.Status.CacheEntries = append(cacheEntries, v1alpha1.CacheEntry{
-			Key:            v1alpha1.CacheEntryKeyDesiredReplicas,
-			Value:          *replicas,
-			ExpirationTime: metav1.Time{Time: time.Now().Add(cacheDuration)},
-		})
+	decision := decideDesiredReplicas(log, scalingInput{
+		HRA:             st,
+		CurrentReplicas: currentReplicas,
+		ReadyReplicas:   rd.Status.ReadyReplicas,
+		Replicas:        replicas,
+		Metric:          metric,
+		Override:        replicasOverride != nil,
+		Reservations:    reservations,
+		SoftMaxReplicas: getSoftMaxReplicas(override),
+		Now:             now,
+	})
+
+	if decision.ReservedReplicas < decision.UncappedReservedReplicas {
+		msg := fmt.Sprintf("Capping the replicas added by capacity reservations from %d to %d", decision.UncappedReservedReplicas, decision.ReservedReplicas)
+		log.Info(msg)
+		r.event(&hra, corev1.EventTypeWarning, "CapacityReservationsCapped", msg)
 	}
 
-	if updated != nil {
-		if err := r.Status().Update(ctx, updated); err != nil {
-			log.Error(err, "Failed to update horizontalrunnerautoscaler status")
+	if since := decision.ForcedScaleDownStalledSince; since != nil {
+		msg := fmt.Sprintf("Forcing the scale down of runnerdeployment %s from %d to %d replicas, which has been stalled since %s", rd.Name, decision.CurrentReplicas, decision.ForcedScaleDownReplicas, since.Format(time.RFC3339))
+		log.Info(msg)
+		r.event(&hra, corev1.EventTypeNormal, "ScaleDownStallDeadlineExceeded", msg)
+	}
+
+	queueDepthHistory := hra.Status.QueueDepthHistory
+
+	if n := getQueueGrowthConsecutiveIncreases(st); n == 0 {
+		queueDepthHistory = nil
+	} else if metric != nil && metric.QueueDepth != nil {
+		queueDepthHistory = recordQueueDepth(queueDepthHistory, *metric.QueueDepth, n)
+	}
+
+	var uncappedDesiredReplicas *int
+
+	lastMaxReplicasReachedTime := hra.Status.LastMaxReplicasReachedTime
+
+	if max := st.Spec.MaxReplicas; max != nil && decision.UncappedDesiredReplicas > *max {
+		uncapped := decision.UncappedDesiredReplicas
+		uncappedDesiredReplicas = &uncapped
+
+		if lastMaxReplicasReachedTime == nil || !now.Before(lastMaxReplicasReachedTime.Add(MaxReplicasReachedEventInterval)) {
+			msg := fmt.Sprintf("Capping the desired replicas of runnerdeployment %s from %d to maxReplicas(%d). Consider raising maxReplicas", rd.Name, uncapped, *max)
+			log.Info(msg)
+			r.event(&hra, corev1.EventTypeWarning, "MaxReplicasReached", msg)
+
+			lastMaxReplicasReachedTime = &metav1.Time{Time: now}
+		}
+	}
+
+	if decision.CachedReplicas != nil {
+		replicas = decision.CachedReplicas
+	}
+
+	var (
+		currentDesiredReplicas = decision.CurrentReplicas
+		newDesiredReplicas     = decision.DesiredReplicas
+		computedReplicas       = decision.ComputedReplicas
+		reservedReplicas       = decision.ReservedReplicas
+		minReplicas            = decision.MinReplicas
+		maxReplicasApplied     = decision.MaxReplicasApplied
+		scaleDownGated         = decision.ScaleDownGated
+		scaleDownGraceEnd      = decision.ScaleDownGraceEnd
+		lastBusyTime           = decision.LastBusyTime
+		continuousDemandSince  = decision.ContinuousDemandSince
+		scaleDownStalledSince  = decision.ScaleDownStalledSince
+	)
+
+	// The queue growing despite scaling is usually a sign of stuck runners, so we scale to the max rather than following the metrics.
+	// The pending runner pods limit and the global budget below are still honored.
+	queueGrowthDetected := replicasOverride == nil && isQueueGrowthDetected(st, queueDepthHistory)
+
+	if max := st.Spec.MaxReplicas; queueGrowthDetected && max != nil && newDesiredReplicas < *max {
+		if currentDesiredReplicas < *max {
+			msg := fmt.Sprintf("Scaling runnerdeployment %s to maxReplicas(%d) as the queue depth kept increasing on %d consecutive reconciliations: %v", rd.Name, *max, len(queueDepthHistory)-1, queueDepthHistory)
+			log.Info(msg)
+			r.event(&hra, corev1.EventTypeWarning, "QueueGrowthDetected", msg)
+		}
+
+		newDesiredReplicas = *max
+	}
+
+	var blockedByPending bool
+
+	if limit := st.Spec.MaxPendingRunnerPods; limit != nil && newDesiredReplicas > currentDesiredReplicas+1 {
+		pending, err := r.countPendingRunnerPods(ctx, rd)
+		if err != nil {
+			log.Error(err, "Could not count pending runner pods")
 
 			return ctrl.Result{}, err
 		}
+
+		if pending >= *limit {
+			msg := fmt.Sprintf("Limiting the scale up of runnerdeployment %s to %d replicas instead of %d while %d runner pods are pending", rd.Name, currentDesiredReplicas+1, newDesiredReplicas, pending)
+			log.Info(msg)
+			r.event(&hra, corev1.EventTypeWarning, "ScaleBlockedByPending", msg)
+
+			newDesiredReplicas = currentDesiredReplicas + 1
+			blockedByPending = true
+		}
 	}
 
-	return ctrl.Result{}, nil
-}
+	// The global budget is applied last so that the fleet never exceeds it, even with the desired replicas override.
+	if r.GlobalMaxReplicas > 0 {
+		demand := newDesiredReplicas
+		if hra.Spec.DryRun {
+			// Dry runs don't consume the budget, as the scale target is left as is
+			demand = currentDesiredReplicas
+		}
 
-func (r *HorizontalRunnerAutoscalerReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	name := "horizontalrunnerautoscaler-controller"
-	if r.Name != "" {
-		name = r.Name
+		// Every scale target consumes the budget by the desired replicas
+		targets := 1 + len(additionalTargets)
+
+		allocated, err := r.allocateFromGlobalBudget(ctx, hra, demand*targets)
+		if err != nil {
+			log.Error(err, "Could not allocate replicas from the global budget")
+
+			return ctrl.Result{}, err
+		}
+
+		allocated /= targets
+
+		if !hra.Spec.DryRun && allocated < newDesiredReplicas {
+			log.Info("Capping desired replicas by the global max replicas", "desired", newDesiredReplicas, "allocated", allocated, "globalMaxReplicas", r.GlobalMaxReplicas)
+
+			newDesiredReplicas = allocated
+		}
 	}
 
-	r.Recorder = mgr.GetEventRecorderFor(name)
+	var metricType, metricValue string
+	if metric != nil {
+		metricType = metric.Type
+		metricValue = metric.ObservedValue
+	}
 
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&v1alpha1.HorizontalRunnerAutoscaler{}).
-		Named(name).
-		Complete(r)
-}
+	log.V(1).Info(
+		"Decided desired replicas",
+		"cacheHit", replicasFromCache != nil,
+		"override", replicasOverride != nil,
+		"metricType", metricType,
+		"metricValue", metricValue,
+		"computedReplicas", computedReplicas,
+		"reservedReplicas", reservedReplicas,
+		"minReplicas", minReplicas,
+		"maxReplicasApplied", maxReplicasApplied,
+		"blockedByPending", blockedByPending,
+		"queueGrowthDetected", queueGrowthDetected,
+		"current", currentDesiredReplicas,
+		"desired", newDesiredReplicas,
+	)
 
-func (r *HorizontalRunnerAutoscalerReconciler) computeReplicas(rd v1alpha1.RunnerDeployment, hra v1alpha1.HorizontalRunnerAutoscaler) (*int, error) {
-	var computedReplicas *int
+	// The runnerdeployment controller defaults nil replicas to 1 regardless of the autoscaler,
+	// so we always set the replicas explicitly in that case.
+	scaleTargetChanged := rd.Spec.Replicas == nil || currentDesiredReplicas != newDesiredReplicas
 
-	replicas, err := r.determineDesiredReplicas(rd, hra)
-	if err != nil {
-		return nil, err
+	scalingDecision := describeScalingDecision(hra, metric, replicas, replicasFromCache != nil, replicasOverride != nil)
+
+	// Updating the replicas while the runnerdeployment controller is still reconciling the last update to the spec,
+	// e.g. in the middle of a rollout, would fight it, so we retry once it catches up.
+	// The scale is skipped as a whole, so that the scale targets are never scaled apart.
+	if !hra.Spec.DryRun {
+		if pending := getGenerationPendingScaleTarget(append([]v1alpha1.RunnerDeployment{rd}, additionalTargets...), newDesiredReplicas); pending != nil {
+			log.Info(
+				"Deferring the scale until the runnerdeployment controller observes the latest generation of runnerdeployment",
+				"runnerdeployment", pending.Name,
+				"generation", pending.Generation,
+				"observedGeneration", pending.Status.ObservedGeneration,
+				"desired", newDesiredReplicas,
+			)
+
+			return ctrl.Result{RequeueAfter: ScaleTargetGenerationPendingRequeueDelay}, nil
+		}
 	}
 
-	var scaleDownDelay time.Duration
+	// Please add more conditions that we can in-place update the newest runnerreplicaset without disruption
+	if scaleTargetChanged && hra.Spec.DryRun {
+		msg := fmt.Sprintf("Would scale runnerdeployment %s from %d to %d replicas, but skipped due to dryRun: %s", rd.Name, currentDesiredReplicas, newDesiredReplicas, scalingDecision)
+
+		r.event(&hra, corev1.EventTypeNormal, "DryRun", msg)
 
-	if hra.Spec.ScaleDownDelaySecondsAfterScaleUp != nil {
-		scaleDownDelay = time.Duration(*hra.Spec.ScaleDownDelaySecondsAfterScaleUp) * time.Second
-	} else {
-		scaleDownDelay = DefaultScaleDownDelay
+		log.Info(msg)
+	} else if scaleTargetChanged {
+		copy := rd.DeepCopy()
+		copy.Spec.Replicas = &newDesiredReplicas
+
+		if newDesiredReplicas > currentDesiredReplicas && applyScaleOutScheduling(hra, copy) {
+			log.Info("Applying the scale-out scheduling hints to runnerdeployment", "runnerdeployment", rd.Name)
+		}
+
+		r.applyIdleRunnersHint(ctx, log, hra, copy, currentDesiredReplicas, newDesiredReplicas)
+
+		if err := r.Client.Update(ctx, copy); err != nil {
+			log.Error(err, "Failed to update runnerderployment resource")
+
+			r.updateReadyCondition(ctx, log, hra, corev1.ConditionFalse, "ScaleTargetUpdateError", err.Error())
+
+			return ctrl.Result{}, err
+		}
+
+		msg := fmt.Sprintf("Scaled runnerdeployment %s from %d to %d replicas: %s", rd.Name, currentDesiredReplicas, newDesiredReplicas, scalingDecision)
+
+		r.event(&hra, corev1.EventTypeNormal, "ScaledRunnerDeployment", msg)
+
+		log.Info(msg)
+	} else if r.getEventVerbosity() == EventVerbosityAll {
+		msg := fmt.Sprintf("Kept runnerdeployment %s at %d replicas: %s", rd.Name, currentDesiredReplicas, scalingDecision)
+
+		r.event(&hra, corev1.EventTypeNormal, "ScalingDecision", msg)
 	}
 
-	now := time.Now()
+	// A failure to scale any of the additional scale targets is returned before updating the status,
+	// so that the status never reflects a partially applied scale, and the rest are scaled on the retry.
+	for i := range additionalTargets {
+		target := additionalTargets[i]
 
-	if hra.Status.DesiredReplicas == nil ||
-		*hra.Status.DesiredReplicas < *replicas ||
-		hra.Status.LastSuccessfulScaleOutTime == nil ||
-		hra.Status.LastSuccessfulScaleOutTime.Add(scaleDownDelay).Before(now) {
+		if hra.Spec.DryRun || !target.DeletionTimestamp.IsZero() || (target.Spec.Replicas != nil && *target.Spec.Replicas == newDesiredReplicas) {
+			continue
+		}
 
-		computedReplicas = replicas
-	} else {
-		computedReplicas = hra.Status.DesiredReplicas
+		copy := target.DeepCopy()
+		copy.Spec.Replicas = &newDesiredReplicas
+
+		if newDesiredReplicas > getIntOrDefault(target.Spec.Replicas, defaultReplicas) && applyScaleOutScheduling(hra, copy) {
+			log.Info("Applying the scale-out scheduling hints to runnerdeployment", "runnerdeployment", target.Name)
+		}
+
+		r.applyIdleRunnersHint(ctx, log, hra, copy, getIntOrDefault(target.Spec.Replicas, defaultReplicas), newDesiredReplicas)
+
+		if err := r.Client.Update(ctx, copy); err != nil {
+			log.Error(err, "Failed to update runnerderployment resource", "runnerdeployment", target.Name)
+
+			r.updateReadyCondition(ctx, log, hra, corev1.ConditionFalse, "ScaleTargetUpdateError", err.Error())
+
+			return ctrl.Result{}, err
+		}
+
+		msg := fmt.Sprintf("Scaled runnerdeployment %s from %d to %d replicas along with runnerdeployment %s", target.Name, getIntOrDefault(target.Spec.Replicas, defaultReplicas), newDesiredReplicas, rd.Name)
+
+		r.event(&hra, corev1.EventTypeNormal, "ScaledRunnerDeployment", msg)
+
+		log.Info(msg)
+	}
+
+	var updated *v1alpha1.HorizontalRunnerAutoscaler
+
+	if hra.Status.DesiredReplicas == nil || *hra.Status.DesiredReplicas != newDesiredReplicas {
+		updated = hra.DeepCopy()
+
+		if (hra.Status.DesiredReplicas == nil && newDesiredReplicas > 1) ||
+			(hra.Status.DesiredReplicas != nil && newDesiredReplicas > *hra.Status.DesiredReplicas) {
+
+			updated.Status.LastSuccessfulScaleOutTime = &metav1.Time{Time: time.Now()}
+			updated.Status.LastScaleUpTime = &metav1.Time{Time: now}
+			updated.Status.LastScaleOutDesiredReplicas = &newDesiredReplicas
+		}
+
+		updated.Status.DesiredReplicas = &newDesiredReplicas
+	}
+
+	if !hra.Spec.DryRun && currentDesiredReplicas != newDesiredReplicas {
+		if updated == nil {
+			updated = hra.DeepCopy()
+		}
+
+		metricType := hra.Status.WinningMetricType
+		if metric != nil {
+			metricType = metric.Type
+		}
+
+		reason := getScaleEventReason(decision, newDesiredReplicas, replicasOverride != nil)
+
+		updated.Status.ScaleEvents = recordScaleEvent(hra.Status.ScaleEvents, now, currentDesiredReplicas, newDesiredReplicas, reason, metricType)
+	}
+
+	if overridesChanged {
+		if updated == nil {
+			updated = hra.DeepCopy()
+		}
+
+		updated.Status.ScheduledOverridesSummary = scheduledOverridesSummary
+	}
+
+	if hra.Status.ConsecutiveFailures != 0 || hra.Status.BackoffSeconds != 0 || hra.Status.LastError != nil {
+		if updated == nil {
+			updated = hra.DeepCopy()
+		}
+
+		updated.Status.ConsecutiveFailures = 0
+		updated.Status.BackoffSeconds = 0
+		updated.Status.LastError = nil
+	}
+
+	if metric != nil && metric.BusyRunners != nil && (hra.Status.BusyRunners == nil || *hra.Status.BusyRunners != *metric.BusyRunners) {
+		if updated == nil {
+			updated = hra.DeepCopy()
+		}
+
+		updated.Status.BusyRunners = metric.BusyRunners
+		updated.Status.LastBusyTime = lastBusyTime
+	}
+
+	if !hra.Status.ScaleDownStalledSince.Equal(scaleDownStalledSince) {
+		if updated == nil {
+			updated = hra.DeepCopy()
+		}
+
+		updated.Status.ScaleDownStalledSince = scaleDownStalledSince
+	}
+
+	if !intPtrEqual(hra.Status.UncappedDesiredReplicas, uncappedDesiredReplicas) || !hra.Status.LastMaxReplicasReachedTime.Equal(lastMaxReplicasReachedTime) {
+		if updated == nil {
+			updated = hra.DeepCopy()
+		}
+
+		updated.Status.UncappedDesiredReplicas = uncappedDesiredReplicas
+		updated.Status.LastMaxReplicasReachedTime = lastMaxReplicasReachedTime
+	}
+
+	// Nothing is consumed in dryRun, as the scale target isn't scaled
+	if st.Spec.BurstMaxReplicas != nil && !hra.Spec.DryRun {
+		burstCredits = startBurstCredits(burstCredits, maxReplicasBeforeBurst, newDesiredReplicas, now)
+	}
+
+	if !burstCreditsEqual(hra.Status.BurstCredits, burstCredits) {
+		if updated == nil {
+			updated = hra.DeepCopy()
+		}
+
+		updated.Status.BurstCredits = burstCredits
+	}
+
+	if !intSliceEqual(hra.Status.QueueDepthHistory, queueDepthHistory) {
+		if updated == nil {
+			updated = hra.DeepCopy()
+		}
+
+		updated.Status.QueueDepthHistory = queueDepthHistory
+	}
+
+	if !hra.Status.ContinuousDemandSince.Equal(continuousDemandSince) {
+		if updated == nil {
+			updated = hra.DeepCopy()
+		}
+
+		updated.Status.ContinuousDemandSince = continuousDemandSince
+	}
+
+	if metric != nil && hra.Status.WinningMetricType != metric.Type {
+		if updated == nil {
+			updated = hra.DeepCopy()
+		}
+
+		updated.Status.WinningMetricType = metric.Type
+	}
+
+	if historyMetric := getScaleHistoryMetric(st); historyMetric != nil {
+		if metric != nil {
+			if updated == nil {
+				updated = hra.DeepCopy()
+			}
+
+			lookback, bucket := getScaleHistoryLookbackAndBucket(*historyMetric)
+
+			updated.Status.ScaleHistory = recordScaleHistory(hra.Status.ScaleHistory, now, metric.ReactiveReplicas, lookback, bucket)
+		}
+	} else if len(hra.Status.ScaleHistory) > 0 {
+		if updated == nil {
+			updated = hra.DeepCopy()
+		}
+
+		updated.Status.ScaleHistory = nil
+	}
+
+	if window := getScaleDownStabilizationWindow(st); window > 0 {
+		if metric != nil {
+			if updated == nil {
+				updated = hra.DeepCopy()
+			}
+
+			updated.Status.Recommendations = recordReplicasRecommendation(hra.Status.Recommendations, now, metric.Replicas, window)
+		}
+	} else if len(hra.Status.Recommendations) > 0 {
+		if updated == nil {
+			updated = hra.DeepCopy()
+		}
+
+		updated.Status.Recommendations = nil
+	}
+
+	if active := summarizeCapacityReservations(reservations); hra.Status.ActiveCapacityReservations != active {
+		if updated == nil {
+			updated = hra.DeepCopy()
+		}
+
+		updated.Status.ActiveCapacityReservations = active
+	}
+
+	// The override doesn't touch the cache, so that the cached desired replicas computed from the metrics
+	// are reused as usual once the annotation is removed.
+	// EventsOnly has nothing worth caching, as its desired replicas are derived without calling GitHub API.
+	if replicasFromCache == nil && replicasOverride == nil && !isEventsOnly(st) {
+		if updated == nil {
+			updated = hra.DeepCopy()
+		}
+
+		// The runnerdeployment is left as is in dryRun, so the cache is keyed by its current replicas in that case.
+		scaledReplicas := newDesiredReplicas
+		if hra.Spec.DryRun {
+			scaledReplicas = currentDesiredReplicas
+		}
+
+		inputsKey := getCacheInputsKey(st, scaledReplicas)
+
+		var cacheEntries []v1alpha1.CacheEntry
+
+		// The expired entries and the ones superseded by the new entry are dropped, so that the entries don't pile up in the status.
+		for _, ent := range updated.Status.CacheEntries {
+			if !now.Before(ent.ExpirationTime.Time) {
+				continue
+			}
+
+			if ent.Key == v1alpha1.CacheEntryKeyDesiredReplicas && ent.InputsKey == inputsKey {
+				continue
+			}
+
+			cacheEntries = append(cacheEntries, ent)
+		}
+
+		var cacheDuration time.Duration
+
+		if st.Spec.CacheDurationSeconds != nil {
+			cacheDuration = time.Duration(*st.Spec.CacheDurationSeconds) * time.Second
+		} else if r.CacheDuration > 0 {
+			cacheDuration = r.CacheDuration
+		} else {
+			cacheDuration = 10 * time.Minute
+		}
+
+		// An entry of the disabled cache would expire right away, so writing it would only churn the status.
+		if cacheDuration > 0 {
+			cacheExpirationTime := time.Now().Add(jitterDuration(cacheDuration, r.cacheDurationJitter()))
+
+			// Don't let the cache outlive the scale-up delay, so that a deferred scale up happens as soon as the delay elapses.
+			if end := getScaleUpDelayEnd(st, now); end != nil && end.Before(cacheExpirationTime) {
+				cacheExpirationTime = *end
+			}
+
+			// Likewise, the decay proceeds on the next step, and the stabilization releases the scale down as soon as the largest
+			// recommendation leaves the window, rather than on the cache expiration.
+			if metric != nil {
+				if next := getNextScaleDownDecayStep(st, metric.Replicas, now); next != nil && next.Before(cacheExpirationTime) {
+					cacheExpirationTime = *next
+				}
+
+				if end := getScaleDownStabilizationEnd(st, metric.Replicas, now); end != nil && end.Before(cacheExpirationTime) {
+					cacheExpirationTime = *end
+				}
+			}
+
+			cacheEntries = append(cacheEntries, v1alpha1.CacheEntry{
+				Key:            v1alpha1.CacheEntryKeyDesiredReplicas,
+				Value:          *replicas,
+				ExpirationTime: metav1.Time{Time: cacheExpirationTime},
+				CreationTime:   metav1.Time{Time: now},
+				InputsKey:      inputsKey,
+			})
+
+			cacheExpiresAt = &metav1.Time{Time: cacheExpirationTime}
+		}
+
+		updated.Status.CacheEntries = cacheEntries
+	} else if replicasFromCache != nil && circuitRetryAt == nil && !hra.Spec.DryRun && newDesiredReplicas != currentDesiredReplicas {
+		// The scale made from the cache, e.g. by a capacity reservation, would otherwise bust the cache keyed by the replicas
+		// on the next reconciliation, defeating the reuse of the cached replicas computed by the metrics.
+		if cacheEntries, ok := rekeyCachedDesiredReplicas(hra.Status.CacheEntries, getCacheInputsKey(st, currentDesiredReplicas), getCacheInputsKey(st, newDesiredReplicas), now); ok {
+			if updated == nil {
+				updated = hra.DeepCopy()
+			}
+
+			updated.Status.CacheEntries = cacheEntries
+		}
+	}
+
+	if !hra.Status.CacheExpiresAt.Equal(cacheExpiresAt) {
+		if updated == nil {
+			updated = hra.DeepCopy()
+		}
+
+		updated.Status.CacheExpiresAt = cacheExpiresAt
+	}
+
+	var readyMessage string
+	if hra.Spec.DryRun {
+		readyMessage = fmt.Sprintf("Determined %d desired replicas for runnerdeployment %s without scaling it due to dryRun", newDesiredReplicas, rd.Name)
+	} else {
+		readyMessage = fmt.Sprintf("Scaled runnerdeployment %s to %d desired replicas", rd.Name, newDesiredReplicas)
+	}
+
+	{
+		status := hra.Status.DeepCopy()
+		if updated != nil {
+			status = updated.Status.DeepCopy()
+		}
+
+		changed := setHorizontalRunnerAutoscalerCondition(status, newReadyCondition(corev1.ConditionTrue, "ScalingSucceeded", readyMessage), now)
+
+		// The Paused condition is kept only once the HorizontalRunnerAutoscaler has ever been paused
+		if hasHorizontalRunnerAutoscalerCondition(*status, v1alpha1.HorizontalRunnerAutoscalerConditionPaused) {
+			if setHorizontalRunnerAutoscalerCondition(status, newPausedCondition(corev1.ConditionFalse, "Resumed", "Autoscaling is resumed"), now) {
+				changed = true
+			}
+		}
+
+		if changed {
+			if updated == nil {
+				updated = hra.DeepCopy()
+			}
+
+			updated.Status = *status
+		}
+	}
+
+	// Recorded only on the success path, so that one can wait for the controller to have processed the latest spec
+	// by comparing it with metadata.generation.
+	if hra.Status.ObservedGeneration != hra.Generation {
+		if updated == nil {
+			updated = hra.DeepCopy()
+		}
+
+		updated.Status.ObservedGeneration = hra.Generation
+	}
+
+	// All the status changes above are written at once, and only when anything actually changed,
+	// so that a reconciliation which ends up with the same status doesn't result in a write to etcd.
+	if updated != nil && apiequality.Semantic.DeepEqual(hra.Status, updated.Status) {
+		updated = nil
+	}
+
+	if updated != nil {
+		if err := r.Status().Update(ctx, updated); err != nil {
+			log.Error(err, "Failed to update horizontalrunnerautoscaler status")
+
+			return ctrl.Result{}, err
+		}
+	}
+
+	if updated != nil {
+		observeHorizontalRunnerAutoscaler(*updated, rd, now)
+	} else {
+		observeHorizontalRunnerAutoscaler(hra, rd, now)
+	}
+
+	var requeueAfter time.Duration
+
+	// Requeue right at the next boundary of scheduled overrides so that an override
+	// starts and stops affecting the desired replicas on time, regardless of the sync period.
+	if next := getNextScheduledOverrideTransition(active, upcoming); next != nil {
+		requeueAfter = next.Sub(now)
+	}
+
+	if end := getScaleUpDelayEnd(st, now); end != nil && (requeueAfter == 0 || end.Sub(now) < requeueAfter) {
+		requeueAfter = end.Sub(now)
+	}
+
+	if scaleDownGraceEnd != nil && (requeueAfter == 0 || scaleDownGraceEnd.Sub(now) < requeueAfter) {
+		requeueAfter = scaleDownGraceEnd.Sub(now)
+	}
+
+	if deadline := getScaleDownStallDeadline(st, scaleDownStalledSince); deadline != nil && (requeueAfter == 0 || deadline.Sub(now) < requeueAfter) {
+		requeueAfter = deadline.Sub(now)
+	}
+
+	if next := getNextMinReplicasRampStep(st, continuousDemandSince, now); next != nil && (requeueAfter == 0 || next.Sub(now) < requeueAfter) {
+		requeueAfter = next.Sub(now)
+	}
+
+	if metric != nil {
+		if next := getNextScaleDownDecayStep(st, metric.Replicas, now); next != nil && (requeueAfter == 0 || next.Sub(now) < requeueAfter) {
+			requeueAfter = next.Sub(now)
+		}
+
+		if end := getScaleDownStabilizationEnd(st, metric.Replicas, now); end != nil && (requeueAfter == 0 || end.Sub(now) < requeueAfter) {
+			requeueAfter = end.Sub(now)
+		}
+	}
+
+	// Recompute right when the cache lapses, rather than on the next sync period
+	if cacheExpiresAt != nil {
+		if d := cacheExpiresAt.Sub(now); d > 0 && (requeueAfter == 0 || d < requeueAfter) {
+			requeueAfter = d
+		}
+	}
+
+	// Probe GitHub API right when the circuit breaker allows it
+	if circuitRetryAt != nil {
+		if d := circuitRetryAt.Sub(now); d > 0 && (requeueAfter == 0 || d < requeueAfter) {
+			requeueAfter = d
+		}
+	}
+
+	// Retry soon, so that the scale down happens shortly after the runnerdeployment stabilizes.
+	if scaleDownGated && (requeueAfter == 0 || ScaleDownReadinessGateRequeueDelay < requeueAfter) {
+		requeueAfter = ScaleDownReadinessGateRequeueDelay
+	}
+
+	// Reconcile periodically regardless of the sync period, so that e.g. expired capacity reservations are reflected
+	// on time without relying on the webhook-based autoscaler or other changes triggering reconciliations.
+	if r.RequeueInterval > 0 && (requeueAfter == 0 || r.RequeueInterval < requeueAfter) {
+		requeueAfter = r.RequeueInterval
+	}
+
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// allowGitHubAPICalls consults the GitHub API circuit breaker, unless the desired replicas of the HorizontalRunnerAutoscaler
+// are computed without calling GitHub API, in which case a GitHub outage shouldn't stop its autoscaling.
+func (r *HorizontalRunnerAutoscalerReconciler) allowGitHubAPICalls(hra v1alpha1.HorizontalRunnerAutoscaler, now time.Time) (allowed, probe bool, retryAt time.Time) {
+	if !usesGitHubAPIMetrics(hra) {
+		return true, false, time.Time{}
+	}
+
+	return r.GitHubAPICircuitBreaker.Allow(now)
+}
+
+func (r *HorizontalRunnerAutoscalerReconciler) metricTimeout() time.Duration {
+	if r.MetricTimeout <= 0 {
+		return DefaultMetricTimeout
+	}
+
+	return r.MetricTimeout
+}
+
+func (r *HorizontalRunnerAutoscalerReconciler) cacheDurationJitter() float64 {
+	if r.CacheDurationJitter == 0 {
+		return DefaultCacheDurationJitter
+	}
+
+	if r.CacheDurationJitter < 0 {
+		return 0
+	}
+
+	return r.CacheDurationJitter
+}
+
+// jitterDuration returns a random duration within d±d*fraction.
+func jitterDuration(d time.Duration, fraction float64) time.Duration {
+	if fraction <= 0 || d <= 0 {
+		return d
+	}
+
+	return d + time.Duration((rand.Float64()*2-1)*fraction*float64(d))
+}
+
+// newLastError returns the LastError for the error, whose message is truncated to maxLastErrorMessageLength bytes.
+func newLastError(err error, now time.Time) *v1alpha1.LastError {
+	msg := err.Error()
+
+	if len(msg) > maxLastErrorMessageLength {
+		const ellipsis = "..."
+
+		n := maxLastErrorMessageLength - len(ellipsis)
+
+		// Avoid cutting a multi-byte character in the middle, which would make the status invalid UTF-8
+		for n > 0 && !utf8.RuneStart(msg[n]) {
+			n--
+		}
+
+		msg = msg[:n] + ellipsis
+	}
+
+	return &v1alpha1.LastError{Message: msg, Time: metav1.Time{Time: now}}
+}
+
+// getFailureBackoff returns the capped exponential backoff after the specified number of consecutive failures.
+func getFailureBackoff(failures int) time.Duration {
+	backoff := DefaultFailureBackoff
+
+	for i := 1; i < failures; i++ {
+		backoff *= 2
+
+		if backoff >= DefaultMaxFailureBackoff {
+			return DefaultMaxFailureBackoff
+		}
+	}
+
+	return backoff
+}
+
+// describeScalingDecision returns a human-readable summary of where the desired replicas came from,
+// like the winning metric type and its observed value, to be included in scaling events.
+func describeScalingDecision(hra v1alpha1.HorizontalRunnerAutoscaler, metric *metricResult, replicas *int, fromCache, overridden bool) string {
+	if overridden {
+		return fmt.Sprintf("desired replicas overridden by the %s annotation", AnnotationKeyDesiredReplicasOverride)
+	}
+
+	var computed string
+	if replicas != nil {
+		computed = strconv.Itoa(*replicas)
+	} else {
+		computed = "unknown"
+	}
+
+	if fromCache || metric == nil {
+		metricType := hra.Status.WinningMetricType
+		if metricType == "" {
+			metricType = "unknown"
+		}
+
+		return fmt.Sprintf("metric=%s computed=%s cached=%t", metricType, computed, fromCache)
+	}
+
+	return fmt.Sprintf("metric=%s observed=%q computed=%s cached=false", metric.Type, metric.ObservedValue, computed)
+}
+
+// getDefaultReplicas returns the number of replicas assumed when neither the scale target nor the metrics specify one.
+// MinReplicas is the natural default, so that e.g. a warm pool of runners is kept from the very first reconciliation,
+// and an explicit MinReplicas of 0 never results in a floor of 1 replica.
+func getDefaultReplicas(hra v1alpha1.HorizontalRunnerAutoscaler) int {
+	if hra.Spec.MinReplicas != nil && *hra.Spec.MinReplicas >= 0 {
+		return *hra.Spec.MinReplicas
+	}
+
+	return 1
+}
+
+// withinTolerance returns true when the desired replicas differs from the current replicas by
+// no more than tolerancePercent of the current replicas.
+func withinTolerance(current, desired, tolerancePercent int) bool {
+	if tolerancePercent <= 0 || current <= 0 {
+		return false
+	}
+
+	diff := desired - current
+	if diff < 0 {
+		diff = -diff
+	}
+
+	return diff*100 <= current*tolerancePercent
+}
+
+// getDesiredReplicasOverride returns the desired replicas specified via the AnnotationKeyDesiredReplicasOverride annotation,
+// or nil when the annotation is absent.
+func getDesiredReplicasOverride(hra v1alpha1.HorizontalRunnerAutoscaler) (*int, error) {
+	v, ok := hra.Annotations[AnnotationKeyDesiredReplicasOverride]
+	if !ok {
+		return nil, nil
+	}
+
+	replicas, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil {
+		return nil, fmt.Errorf("parsing annotation %s: %w", AnnotationKeyDesiredReplicasOverride, err)
+	}
+
+	if replicas < 0 {
+		return nil, fmt.Errorf("parsing annotation %s: replicas must be greater than or equal to 0, but got %d", AnnotationKeyDesiredReplicasOverride, replicas)
+	}
+
+	return &replicas, nil
+}
+
+// getAdditionalScaleTargets returns the RunnerDeployments referenced by AdditionalScaleTargetRefs.
+// It fails when any of them doesn't exist, so that the scale targets are never scaled apart.
+func (r *HorizontalRunnerAutoscalerReconciler) getAdditionalScaleTargets(ctx context.Context, hra v1alpha1.HorizontalRunnerAutoscaler) ([]v1alpha1.RunnerDeployment, error) {
+	var rds []v1alpha1.RunnerDeployment
+
+	for _, ref := range hra.Spec.AdditionalScaleTargetRefs {
+		var rd v1alpha1.RunnerDeployment
+		if err := r.Get(ctx, types.NamespacedName{Namespace: hra.Namespace, Name: ref.Name}, &rd); err != nil {
+			// Not wrapped, as kerrors.IsNotFound doesn't unwrap errors
+			return nil, err
+		}
+
+		rds = append(rds, rd)
+	}
+
+	return rds, nil
+}
+
+// isPaused returns true when the HorizontalRunnerAutoscaler is paused via the AnnotationKeyPaused annotation.
+func isPaused(hra v1alpha1.HorizontalRunnerAutoscaler) bool {
+	return hra.Annotations[AnnotationKeyPaused] == "true"
+}
+
+// getCacheBustTime returns the time specified via the AnnotationKeyCacheBust annotation, or nil when it's not annotated.
+func getCacheBustTime(hra v1alpha1.HorizontalRunnerAutoscaler) (*time.Time, error) {
+	v, ok := hra.Annotations[AnnotationKeyCacheBust]
+	if !ok {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(v))
+	if err != nil {
+		return nil, fmt.Errorf("parsing annotation %s: %w", AnnotationKeyCacheBust, err)
+	}
+
+	return &t, nil
+}
+
+// updateReadyCondition updates the Ready condition of the HorizontalRunnerAutoscaler in a best-effort manner.
+// It's used on error paths, where the error that is being returned is more important than the failure to update the status.
+func (r *HorizontalRunnerAutoscalerReconciler) updateReadyCondition(ctx context.Context, log logr.Logger, hra v1alpha1.HorizontalRunnerAutoscaler, status corev1.ConditionStatus, reason, message string) {
+	updated := hra.DeepCopy()
+
+	if !setHorizontalRunnerAutoscalerCondition(&updated.Status, newReadyCondition(status, reason, message), time.Now()) {
+		return
+	}
+
+	if err := r.Status().Update(ctx, updated); err != nil {
+		log.Error(err, "Failed to update horizontalrunnerautoscaler status conditions")
+	}
+}
+
+// hasReadyConditionReason returns true when the Ready condition in the status is False with the reason.
+func hasReadyConditionReason(status v1alpha1.HorizontalRunnerAutoscalerStatus, reason string) bool {
+	for _, c := range status.Conditions {
+		if c.Type == v1alpha1.HorizontalRunnerAutoscalerConditionReady {
+			return c.Status == corev1.ConditionFalse && c.Reason == reason
+		}
+	}
+
+	return false
+}
+
+// hasHorizontalRunnerAutoscalerCondition returns true when the status has the condition of the type.
+func hasHorizontalRunnerAutoscalerCondition(status v1alpha1.HorizontalRunnerAutoscalerStatus, condType string) bool {
+	for _, c := range status.Conditions {
+		if c.Type == condType {
+			return true
+		}
+	}
+
+	return false
+}
+
+func newPausedCondition(status corev1.ConditionStatus, reason, message string) v1alpha1.HorizontalRunnerAutoscalerCondition {
+	return v1alpha1.HorizontalRunnerAutoscalerCondition{
+		Type:    v1alpha1.HorizontalRunnerAutoscalerConditionPaused,
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	}
+}
+
+func newReadyCondition(status corev1.ConditionStatus, reason, message string) v1alpha1.HorizontalRunnerAutoscalerCondition {
+	return v1alpha1.HorizontalRunnerAutoscalerCondition{
+		Type:    v1alpha1.HorizontalRunnerAutoscalerConditionReady,
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	}
+}
+
+// setHorizontalRunnerAutoscalerCondition adds or updates the condition of the same type in the status.
+// LastTransitionTime is updated only when the condition's status changes.
+// It returns true when anything in the status has changed.
+func setHorizontalRunnerAutoscalerCondition(status *v1alpha1.HorizontalRunnerAutoscalerStatus, cond v1alpha1.HorizontalRunnerAutoscalerCondition, now time.Time) bool {
+	for i := range status.Conditions {
+		c := &status.Conditions[i]
+
+		if c.Type != cond.Type {
+			continue
+		}
+
+		if c.Status == cond.Status && c.Reason == cond.Reason && c.Message == cond.Message {
+			return false
+		}
+
+		if c.Status != cond.Status {
+			c.LastTransitionTime = metav1.Time{Time: now}
+		}
+
+		c.Status = cond.Status
+		c.Reason = cond.Reason
+		c.Message = cond.Message
+
+		return true
+	}
+
+	cond.LastTransitionTime = metav1.Time{Time: now}
+
+	status.Conditions = append(status.Conditions, cond)
+
+	return true
+}
+
+// matchScheduledOverrides returns the active scheduled override along with its active period, and the closest upcoming period
+// among all the scheduled overrides.
+// When two or more overrides are active, the one defined earlier in the spec wins.
+func (r *HorizontalRunnerAutoscalerReconciler) matchScheduledOverrides(log logr.Logger, now time.Time, hra v1alpha1.HorizontalRunnerAutoscaler) (*v1alpha1.ScheduledOverride, *Period, *Period, error) {
+	var (
+		override         *v1alpha1.ScheduledOverride
+		active, upcoming *Period
+	)
+
+	for i := range hra.Spec.ScheduledOverrides {
+		o := hra.Spec.ScheduledOverrides[i]
+
+		a, u, err := MatchSchedule(
+			now, o.StartTime.Time, o.EndTime.Time,
+			RecurrenceRule{
+				Frequency: o.RecurrenceRule.Frequency,
+				UntilTime: o.RecurrenceRule.UntilTime.Time,
+			},
+		)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("scheduledOverrides[%d]: %w", i, err)
+		}
+
+		log.V(1).Info(
+			"Checked scheduled override",
+			"index", i,
+			"active", a.String(),
+			"upcoming", u.String(),
+		)
+
+		if a != nil && override == nil {
+			override = &o
+			active = a
+		}
+
+		if u != nil && (upcoming == nil || u.StartTime.Before(upcoming.StartTime)) {
+			upcoming = u
+		}
+	}
+
+	return override, active, upcoming, nil
+}
+
+func withScheduledOverride(hra v1alpha1.HorizontalRunnerAutoscaler, override *v1alpha1.ScheduledOverride) v1alpha1.HorizontalRunnerAutoscaler {
+	if override == nil {
+		return hra
+	}
+
+	st := hra.DeepCopy()
+
+	if override.MinReplicas != nil {
+		st.Spec.MinReplicas = override.MinReplicas
+	}
+
+	if override.MaxReplicas != nil {
+		st.Spec.MaxReplicas = override.MaxReplicas
+	}
+
+	return *st
+}
+
+// getSoftMaxReplicas returns the soft max of the active scheduled override, or nil when there's none.
+// It's applied to the replicas computed by the metrics rather than to the spec, as the capacity reservations can exceed it.
+func getSoftMaxReplicas(override *v1alpha1.ScheduledOverride) *int {
+	if override == nil {
+		return nil
+	}
+
+	return override.SoftMaxReplicas
+}
+
+func getScheduledOverridesSummary(override *v1alpha1.ScheduledOverride, active, upcoming *Period) *string {
+	var parts []string
+
+	if active != nil {
+		p := "active"
+
+		if override.MinReplicas != nil {
+			p += fmt.Sprintf(" min=%d", *override.MinReplicas)
+		}
+
+		if override.MaxReplicas != nil {
+			p += fmt.Sprintf(" max=%d", *override.MaxReplicas)
+		}
+
+		if override.SoftMaxReplicas != nil {
+			p += fmt.Sprintf(" softMax=%d", *override.SoftMaxReplicas)
+		}
+
+		p += " time=" + active.String()
+
+		parts = append(parts, p)
+	}
+
+	if upcoming != nil {
+		parts = append(parts, "upcoming time="+upcoming.String())
+	}
+
+	if len(parts) == 0 {
+		return nil
+	}
+
+	summary := strings.Join(parts, ", ")
+
+	return &summary
+}
+
+func getNextScheduledOverrideTransition(active, upcoming *Period) *time.Time {
+	var next *time.Time
+
+	if active != nil {
+		next = &active.EndTime
+	}
+
+	if upcoming != nil && (next == nil || upcoming.StartTime.Before(*next)) {
+		next = &upcoming.StartTime
+	}
+
+	return next
+}
+
+// getGenerationPendingScaleTarget returns the first of the scale targets to be scaled to desiredReplicas whose latest generation
+// hasn't been observed by the runnerdeployment controller yet, or nil when there's none.
+// The zero observed generation is assumed to be up to date, as it's never recorded by the runnerdeployment controllers predating it.
+func getGenerationPendingScaleTarget(targets []v1alpha1.RunnerDeployment, desiredReplicas int) *v1alpha1.RunnerDeployment {
+	for i := range targets {
+		t := targets[i]
+
+		if !t.DeletionTimestamp.IsZero() || (t.Spec.Replicas != nil && *t.Spec.Replicas == desiredReplicas) {
+			continue
+		}
+
+		if observed := t.Status.ObservedGeneration; observed != 0 && observed < t.Generation {
+			return &targets[i]
+		}
+	}
+
+	return nil
+}
+
+func (r *HorizontalRunnerAutoscalerReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	name := "horizontalrunnerautoscaler-controller"
+	if r.Name != "" {
+		name = r.Name
+	}
+
+	r.Recorder = mgr.GetEventRecorderFor(name)
+
+	registerHorizontalRunnerAutoscalerMetrics()
+
+	policyHandler, err := r.setupPolicyWatch(mgr)
+	if err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.HorizontalRunnerAutoscaler{}).
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}}, policyHandler).
+		Watches(&source.Kind{Type: &v1alpha1.RunnerDeployment{}}, r.scaleTargetSelectorHandler()).
+		Named(name).
+		WithOptions(r.controllerOptions()).
+		Complete(r)
+}
+
+func (r *HorizontalRunnerAutoscalerReconciler) controllerOptions() controller.Options {
+	maxConcurrentReconciles := r.MaxConcurrentReconciles
+	if maxConcurrentReconciles <= 0 {
+		maxConcurrentReconciles = 1
+	}
+
+	return controller.Options{
+		MaxConcurrentReconciles: maxConcurrentReconciles,
+	}
+}
+
+func (r *HorizontalRunnerAutoscalerReconciler) computeReplicas(ctx context.Context, rd v1alpha1.RunnerDeployment, hra v1alpha1.HorizontalRunnerAutoscaler) (*int, *metricResult, error) {
+	result, err := r.determineDesiredReplicas(ctx, rd, hra)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return applyScaleDelays(hra, result, time.Now()), result, nil
+}
+
+// getLastBusyTime returns the last time the number of busy runners was observed to drop, including the drop observed by the metric at now.
+func getLastBusyTime(hra v1alpha1.HorizontalRunnerAutoscaler, metric *metricResult, now time.Time) *metav1.Time {
+	if metric != nil && metric.BusyRunners != nil && hra.Status.BusyRunners != nil && *metric.BusyRunners < *hra.Status.BusyRunners {
+		return &metav1.Time{Time: now}
+	}
+
+	return hra.Status.LastBusyTime
+}
+
+// getScaleDownGraceEnd returns the time at which the scale-down grace period since the busy runners last dropped elapses,
+// or nil when no grace period is in effect at `now`.
+func getScaleDownGraceEnd(hra v1alpha1.HorizontalRunnerAutoscaler, lastBusyTime *metav1.Time, now time.Time) *time.Time {
+	if hra.Spec.ScaleDownGraceSeconds == nil || *hra.Spec.ScaleDownGraceSeconds <= 0 || lastBusyTime == nil {
+		return nil
+	}
+
+	end := lastBusyTime.Add(time.Duration(*hra.Spec.ScaleDownGraceSeconds) * time.Second)
+	if !end.After(now) {
+		return nil
+	}
+
+	return &end
+}
+
+// getScaleDownStallDeadline returns the time at which the scale down stalled since stalledSince is forced,
+// or nil when it's not stalled or MaxScaleDownStallSeconds is disabled.
+func getScaleDownStallDeadline(hra v1alpha1.HorizontalRunnerAutoscaler, stalledSince *metav1.Time) *time.Time {
+	if hra.Spec.MaxScaleDownStallSeconds == nil || *hra.Spec.MaxScaleDownStallSeconds <= 0 || stalledSince == nil {
+		return nil
+	}
+
+	deadline := stalledSince.Add(time.Duration(*hra.Spec.MaxScaleDownStallSeconds) * time.Second)
+
+	return &deadline
+}
+
+// getScaleUpDelayEnd returns the time at which the scale-up delay since the last scale up elapses,
+// or nil when no scale-up delay is in effect at `now`.
+func getScaleUpDelayEnd(hra v1alpha1.HorizontalRunnerAutoscaler, now time.Time) *time.Time {
+	if hra.Spec.ScaleUpDelaySeconds == nil || *hra.Spec.ScaleUpDelaySeconds <= 0 || hra.Status.LastScaleUpTime == nil {
+		return nil
+	}
+
+	end := hra.Status.LastScaleUpTime.Add(time.Duration(*hra.Spec.ScaleUpDelaySeconds) * time.Second)
+	if !end.After(now) {
+		return nil
 	}
 
-	return computedReplicas, nil
+	return &end
 }
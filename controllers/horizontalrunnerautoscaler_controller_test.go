@@ -0,0 +1,5469 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	"github.com/summerwind/actions-runner-controller/github"
+	"github.com/summerwind/actions-runner-controller/github/fake"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+// testReconcilerOption customizes the reconciler built by newTestReconciler.
+type testReconcilerOption func(*testReconcilerConfig)
+
+type testReconcilerConfig struct {
+	githubResponses []fake.Option
+	objs            []runtime.Object
+}
+
+// withGitHubResponses overrides the responses of the fake GitHub API server, which defaults to no workflow runs and no busy runners.
+func withGitHubResponses(opts ...fake.Option) testReconcilerOption {
+	return func(c *testReconcilerConfig) {
+		c.githubResponses = append(c.githubResponses, opts...)
+	}
+}
+
+// withObjects adds the objects to the fake client along with the scale target and the autoscaler.
+func withObjects(objs ...runtime.Object) testReconcilerOption {
+	return func(c *testReconcilerConfig) {
+		c.objs = append(c.objs, objs...)
+	}
+}
+
+// newTestReconciler returns the reconciler of the objects, calling the fake GitHub API server that is closed once the test completes.
+// Either of rd and hra can be nil, to test a missing object.
+func newTestReconciler(t *testing.T, rd *v1alpha1.RunnerDeployment, hra *v1alpha1.HorizontalRunnerAutoscaler, opts ...testReconcilerOption) *HorizontalRunnerAutoscalerReconciler {
+	t.Helper()
+
+	noWorkflowRuns := `{"total_count": 0, "workflow_runs":[]}"`
+
+	config := testReconcilerConfig{
+		githubResponses: []fake.Option{
+			fake.WithListRepositoryWorkflowRunsResponse(200, noWorkflowRuns, noWorkflowRuns, noWorkflowRuns),
+			fake.WithListWorkflowJobsResponse(200, nil),
+			fake.WithListRunnersResponse(200, fake.RunnersListBody),
+		},
+	}
+
+	for _, o := range opts {
+		o(&config)
+	}
+
+	server := fake.NewServer(config.githubResponses...)
+	t.Cleanup(server.Close)
+
+	objs := config.objs
+
+	if rd != nil {
+		objs = append(objs, rd)
+	}
+
+	if hra != nil {
+		objs = append(objs, hra)
+	}
+
+	return &HorizontalRunnerAutoscalerReconciler{
+		Client: clientfake.NewFakeClientWithScheme(sc, objs...),
+		Log: zap.New(func(o *zap.Options) {
+			o.Development = true
+		}),
+		Recorder:     record.NewFakeRecorder(10),
+		GitHubClient: newGithubClient(server),
+		Scheme:       sc,
+	}
+}
+
+// newTestRD returns the RunnerDeployment named testrd, which is the scale target of the autoscaler returned by newTestHRA.
+func newTestRD(replicas *int) *v1alpha1.RunnerDeployment {
+	return &v1alpha1.RunnerDeployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "testrd",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.RunnerDeploymentSpec{
+			Template: v1alpha1.RunnerTemplate{
+				Spec: v1alpha1.RunnerSpec{
+					Repository: "test/valid",
+				},
+			},
+			Replicas: replicas,
+		},
+	}
+}
+
+// newTestHRA returns the HorizontalRunnerAutoscaler named testhra, scaling testrd unless the spec has its own scale target.
+func newTestHRA(spec v1alpha1.HorizontalRunnerAutoscalerSpec) *v1alpha1.HorizontalRunnerAutoscaler {
+	if spec.ScaleTargetRef.Name == "" && len(spec.ScaleTargetRef.MatchLabels) == 0 {
+		spec.ScaleTargetRef.Name = "testrd"
+	}
+
+	return &v1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "testhra",
+			Namespace: "default",
+		},
+		Spec: spec,
+	}
+}
+
+// reconcileTestHRA reconciles the autoscaler returned by newTestHRA, failing the test on error.
+func reconcileTestHRA(t *testing.T, h *HorizontalRunnerAutoscalerReconciler) ctrl.Result {
+	t.Helper()
+
+	res, err := h.Reconcile(ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "testhra"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return res
+}
+
+// getTestHRA returns the autoscaler returned by newTestHRA as stored in the fake client.
+func getTestHRA(t *testing.T, h *HorizontalRunnerAutoscalerReconciler) v1alpha1.HorizontalRunnerAutoscaler {
+	t.Helper()
+
+	var hra v1alpha1.HorizontalRunnerAutoscaler
+	if err := h.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "testhra"}, &hra); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return hra
+}
+
+// getTestRD returns the RunnerDeployment returned by newTestRD as stored in the fake client.
+func getTestRD(t *testing.T, h *HorizontalRunnerAutoscalerReconciler) v1alpha1.RunnerDeployment {
+	t.Helper()
+
+	var rd v1alpha1.RunnerDeployment
+	if err := h.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "testrd"}, &rd); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return rd
+}
+
+func TestReconcile_MinReplicas(t *testing.T) {
+	testcases := []struct {
+		replicas     *int
+		min          *int
+		max          *int
+		cached       *int
+		coldStart    bool
+		reservations []v1alpha1.CapacityReservation
+
+		want int
+	}{
+		// The metric says zero, floored by minReplicas
+		{
+			replicas: intPtr(1),
+			min:      intPtr(2),
+			max:      intPtr(5),
+			want:     2,
+		},
+		// The cached value computed before minReplicas was raised, floored by minReplicas
+		{
+			replicas: intPtr(1),
+			min:      intPtr(2),
+			max:      intPtr(5),
+			cached:   intPtr(0),
+			want:     2,
+		},
+		// Capacity reservations are added on top of minReplicas, capped by maxReplicas
+		{
+			replicas: intPtr(1),
+			min:      intPtr(2),
+			max:      intPtr(5),
+			reservations: []v1alpha1.CapacityReservation{
+				{ExpirationTime: metav1.Time{Time: time.Now().Add(time.Hour)}, Replicas: 2},
+				{ExpirationTime: metav1.Time{Time: time.Now().Add(time.Hour)}, Replicas: 2},
+			},
+			want: 5,
+		},
+		// Scale to zero
+		{
+			replicas: intPtr(1),
+			min:      intPtr(0),
+			max:      intPtr(5),
+			want:     0,
+		},
+		// A nil rd.Spec.Replicas is explicitly set to the desired replicas even if it equals the default
+		{
+			min:  intPtr(3),
+			max:  intPtr(5),
+			want: 3,
+		},
+		// An explicit minReplicas of 0 never results in a floor of 1 replica, so that the runners can be scaled to zero
+		{
+			min:  intPtr(0),
+			max:  intPtr(5),
+			want: 0,
+		},
+		// The cold start reservation scales the runners up from zero
+		{
+			replicas:  intPtr(0),
+			min:       intPtr(0),
+			max:       intPtr(5),
+			coldStart: true,
+			reservations: []v1alpha1.CapacityReservation{
+				{Name: "cold-start", ExpirationTime: metav1.Time{Time: time.Now().Add(time.Minute)}, Replicas: 1},
+			},
+			want: 1,
+		},
+		// The runners are scaled back to zero once the cold start reservation expires
+		{
+			replicas:  intPtr(1),
+			min:       intPtr(0),
+			max:       intPtr(5),
+			coldStart: true,
+			reservations: []v1alpha1.CapacityReservation{
+				{Name: "cold-start", ExpirationTime: metav1.Time{Time: time.Now().Add(-time.Minute)}, Replicas: 1},
+			},
+			want: 0,
+		},
+		{
+			replicas:  intPtr(0),
+			min:       intPtr(0),
+			max:       intPtr(5),
+			coldStart: true,
+			want:      0,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			rd := newTestRD(tc.replicas)
+
+			hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+				MinReplicas:          tc.min,
+				MaxReplicas:          tc.max,
+				CapacityReservations: tc.reservations,
+			})
+
+			if tc.coldStart {
+				hra.Spec.ColdStartReservation = &v1alpha1.ColdStartReservation{}
+			}
+
+			if tc.cached != nil {
+				hra.Status.CacheEntries = []v1alpha1.CacheEntry{
+					{
+						Key:            v1alpha1.CacheEntryKeyDesiredReplicas,
+						Value:          *tc.cached,
+						ExpirationTime: metav1.Time{Time: time.Now().Add(time.Hour)},
+					},
+				}
+			}
+
+			// Without the replicas of the scale target, the desired replicas start from the default
+			if tc.replicas == nil {
+				if got := getDefaultReplicas(*hra); got != tc.want {
+					t.Errorf("unexpected default replicas: want %d, got %d", tc.want, got)
+				}
+			}
+
+			h := newTestReconciler(t, rd, hra)
+
+			reconcileTestHRA(t, h)
+
+			got := getTestRD(t, h)
+
+			if got.Spec.Replicas == nil {
+				t.Fatalf("unexpected value of rd.Spec.Replicas: nil")
+			}
+
+			if *got.Spec.Replicas != tc.want {
+				t.Errorf("%d: incorrect desired replicas: want %d, got %d", i, tc.want, *got.Spec.Replicas)
+			}
+		})
+	}
+}
+
+func TestReconcile_PruneExpiredCapacityReservations(t *testing.T) {
+	now := time.Now()
+
+	rd := newTestRD(intPtr(1))
+
+	hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+		MinReplicas: intPtr(1),
+		MaxReplicas: intPtr(5),
+		CapacityReservations: []v1alpha1.CapacityReservation{
+			{Name: "expired1", ExpirationTime: metav1.Time{Time: now.Add(-time.Hour)}, Replicas: 1},
+			{Name: "valid", ExpirationTime: metav1.Time{Time: now.Add(time.Hour)}, Replicas: 2},
+			{Name: "expired2", ExpirationTime: metav1.Time{Time: now.Add(-time.Minute)}, Replicas: 1},
+		},
+	})
+
+	h := newTestReconciler(t, rd, hra)
+
+	reconcileTestHRA(t, h)
+
+	gotHRA := getTestHRA(t, h)
+
+	if n := len(gotHRA.Spec.CapacityReservations); n != 1 || gotHRA.Spec.CapacityReservations[0].Name != "valid" {
+		t.Errorf("unexpected capacity reservations after pruning: %+v", gotHRA.Spec.CapacityReservations)
+	}
+
+	if gotHRA.Status.DesiredReplicas == nil || *gotHRA.Status.DesiredReplicas != 3 {
+		t.Errorf("unexpected status.desiredReplicas: want 3, got %v", gotHRA.Status.DesiredReplicas)
+	}
+
+	gotRD := getTestRD(t, h)
+
+	if gotRD.Spec.Replicas == nil || *gotRD.Spec.Replicas != 3 {
+		t.Errorf("unexpected rd.Spec.Replicas: want 3, got %v", gotRD.Spec.Replicas)
+	}
+}
+
+func TestReconcile_CapacityReservationDuration(t *testing.T) {
+	now := time.Now()
+
+	rd := newTestRD(intPtr(1))
+
+	effective := metav1.Time{Time: now.Add(-2 * time.Hour)}
+
+	hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+		MinReplicas: intPtr(1),
+		MaxReplicas: intPtr(5),
+		CapacityReservations: []v1alpha1.CapacityReservation{
+			{Name: "new", Duration: metav1.Duration{Duration: 5 * time.Minute}, Replicas: 2},
+			// Resolved on a previous reconciliation, and already expired
+			{Name: "resolved", Duration: metav1.Duration{Duration: time.Hour}, EffectiveTime: effective, ExpirationTime: metav1.Time{Time: effective.Add(time.Hour)}, Replicas: 1},
+		},
+	})
+
+	h := newTestReconciler(t, rd, hra)
+
+	reconcileTestHRA(t, h)
+
+	gotHRA := getTestHRA(t, h)
+
+	if n := len(gotHRA.Spec.CapacityReservations); n != 1 || gotHRA.Spec.CapacityReservations[0].Name != "new" {
+		t.Fatalf("unexpected capacity reservations: %+v", gotHRA.Spec.CapacityReservations)
+	}
+
+	got := gotHRA.Spec.CapacityReservations[0]
+
+	if got.EffectiveTime.IsZero() || got.EffectiveTime.Time.Before(now.Add(-time.Second)) {
+		t.Errorf("unexpected effectiveTime: %v", got.EffectiveTime)
+	}
+
+	if want := got.EffectiveTime.Add(5 * time.Minute); !got.ExpirationTime.Time.Equal(want) {
+		t.Errorf("unexpected expirationTime: want %v, got %v", want, got.ExpirationTime)
+	}
+
+	gotRD := getTestRD(t, h)
+
+	if gotRD.Spec.Replicas == nil || *gotRD.Spec.Replicas != 3 {
+		t.Errorf("unexpected rd.Spec.Replicas: want 3, got %v", gotRD.Spec.Replicas)
+	}
+}
+
+func TestReconcile_ActiveCapacityReservations(t *testing.T) {
+	now := time.Now()
+
+	testcases := []struct {
+		reservations []v1alpha1.CapacityReservation
+		status       v1alpha1.ActiveCapacityReservations
+		want         v1alpha1.ActiveCapacityReservations
+	}{
+		{
+			reservations: []v1alpha1.CapacityReservation{
+				{Name: "valid1", ExpirationTime: metav1.Time{Time: now.Add(time.Hour)}, Replicas: 2},
+				{Name: "valid2", ExpirationTime: metav1.Time{Time: now.Add(time.Hour)}, Replicas: 1},
+				{Name: "expired", ExpirationTime: metav1.Time{Time: now.Add(-time.Minute)}, Replicas: 1},
+			},
+			want: v1alpha1.ActiveCapacityReservations{Count: 2, Replicas: 3},
+		},
+		// The reservations for the same workflow job are counted once
+		{
+			reservations: []v1alpha1.CapacityReservation{
+				{ExpirationTime: metav1.Time{Time: now.Add(time.Hour)}, Replicas: 1, WorkflowJobID: 1},
+				{ExpirationTime: metav1.Time{Time: now.Add(time.Hour)}, Replicas: 1, WorkflowJobID: 1},
+			},
+			want: v1alpha1.ActiveCapacityReservations{Count: 2, Replicas: 1},
+		},
+		// Drops to zero once all the reservations expire
+		{
+			reservations: []v1alpha1.CapacityReservation{
+				{Name: "expired", ExpirationTime: metav1.Time{Time: now.Add(-time.Minute)}, Replicas: 2},
+			},
+			status: v1alpha1.ActiveCapacityReservations{Count: 1, Replicas: 2},
+			want:   v1alpha1.ActiveCapacityReservations{},
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			rd := newTestRD(intPtr(1))
+
+			hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+				MinReplicas:          intPtr(1),
+				MaxReplicas:          intPtr(5),
+				CapacityReservations: tc.reservations,
+			})
+
+			hra.Status = v1alpha1.HorizontalRunnerAutoscalerStatus{
+				ActiveCapacityReservations: tc.status,
+			}
+
+			h := newTestReconciler(t, rd, hra)
+
+			reconcileTestHRA(t, h)
+
+			gotHRA := getTestHRA(t, h)
+
+			if got := gotHRA.Status.ActiveCapacityReservations; got != tc.want {
+				t.Errorf("unexpected status.activeCapacityReservations: want %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestReconcile_HoldCapacityReservationsWhileBusy(t *testing.T) {
+	testcases := []struct {
+		hold    bool
+		numBusy int
+
+		// listRunnersStatus is the status of the ListRunners response, which defaults to 200
+		listRunnersStatus int
+
+		want             int
+		wantReservations []string
+	}{
+		{
+			hold:             false,
+			numBusy:          3,
+			want:             2,
+			wantReservations: []string{"valid"},
+		},
+		// No busy runners hold the expired reservations
+		{
+			hold:             true,
+			numBusy:          0,
+			want:             2,
+			wantReservations: []string{"valid"},
+		},
+		// The busy runners outnumber the valid reservation, so the most recently expired one is held
+		{
+			hold:             true,
+			numBusy:          2,
+			want:             3,
+			wantReservations: []string{"valid", "expired-recently"},
+		},
+		{
+			hold:             true,
+			numBusy:          3,
+			want:             5,
+			wantReservations: []string{"expired-long-ago", "valid", "expired-recently"},
+		},
+		// The expired reservations are kept in the spec when the busy runners can't be counted,
+		// while only the valid one is honored in the meantime
+		{
+			hold:              true,
+			numBusy:           3,
+			listRunnersStatus: 500,
+			want:              2,
+			wantReservations:  []string{"expired-long-ago", "valid", "expired-recently"},
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			now := time.Now()
+
+			var runners []string
+			objs := []runtime.Object{}
+
+			for j := 1; j <= 3; j++ {
+				name := fmt.Sprintf("test%d", j)
+
+				runners = append(runners, fmt.Sprintf(`{"id": %d, "name": %q, "os": "linux", "status": "online", "busy": %t}`, j, name, j <= tc.numBusy))
+
+				objs = append(objs, &v1alpha1.Runner{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      name,
+						Namespace: "default",
+					},
+				})
+			}
+
+			runnersList := fmt.Sprintf(`{"total_count": 3, "runners": [%s]}`, strings.Join(runners, ", "))
+
+			listRunnersStatus := tc.listRunnersStatus
+			if listRunnersStatus == 0 {
+				listRunnersStatus = 200
+			}
+
+			rd := newTestRD(intPtr(3))
+
+			hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+				MinReplicas: intPtr(1),
+				MaxReplicas: intPtr(5),
+				CapacityReservations: []v1alpha1.CapacityReservation{
+					{Name: "expired-long-ago", ExpirationTime: metav1.Time{Time: now.Add(-time.Hour)}, Replicas: 2},
+					{Name: "valid", ExpirationTime: metav1.Time{Time: now.Add(time.Hour)}, Replicas: 1},
+					{Name: "expired-recently", ExpirationTime: metav1.Time{Time: now.Add(-time.Minute)}, Replicas: 1},
+				},
+				HoldCapacityReservationsWhileBusy: tc.hold,
+			})
+
+			h := newTestReconciler(t, rd, hra,
+				withGitHubResponses(fake.WithListRunnersResponse(listRunnersStatus, runnersList)),
+				withObjects(objs...),
+			)
+
+			reconcileTestHRA(t, h)
+
+			gotHRA := getTestHRA(t, h)
+
+			var gotReservations []string
+			for _, r := range gotHRA.Spec.CapacityReservations {
+				gotReservations = append(gotReservations, r.Name)
+			}
+
+			if strings.Join(gotReservations, ",") != strings.Join(tc.wantReservations, ",") {
+				t.Errorf("unexpected capacity reservations: want %v, got %v", tc.wantReservations, gotReservations)
+			}
+
+			gotRD := getTestRD(t, h)
+
+			if gotRD.Spec.Replicas == nil || *gotRD.Spec.Replicas != tc.want {
+				t.Errorf("unexpected rd.Spec.Replicas: want %d, got %v", tc.want, gotRD.Spec.Replicas)
+			}
+		})
+	}
+}
+
+func TestReconcile_CacheDurationSeconds(t *testing.T) {
+	testcases := []struct {
+		cacheDurationSeconds *int
+		controllerDuration   time.Duration
+
+		want time.Duration
+	}{
+		// Overridden by the HRA
+		{
+			cacheDurationSeconds: intPtr(60),
+			controllerDuration:   5 * time.Minute,
+			want:                 time.Minute,
+		},
+		// Controller-wide cache duration
+		{
+			controllerDuration: 5 * time.Minute,
+			want:               5 * time.Minute,
+		},
+		// The default
+		{
+			want: 10 * time.Minute,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			rd := newTestRD(nil)
+
+			hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+				MinReplicas:          intPtr(1),
+				MaxReplicas:          intPtr(5),
+				CacheDurationSeconds: tc.cacheDurationSeconds,
+			})
+
+			h := newTestReconciler(t, rd, hra)
+			h.CacheDuration = tc.controllerDuration
+			// Disable the jitter to make the cache expiration deterministic
+			h.CacheDurationJitter = -1
+
+			start := time.Now()
+
+			reconcileTestHRA(t, h)
+
+			got := getTestHRA(t, h)
+
+			if len(got.Status.CacheEntries) != 1 {
+				t.Fatalf("unexpected cache entries: %+v", got.Status.CacheEntries)
+			}
+
+			// The expiration time is serialized in seconds precision
+			d := got.Status.CacheEntries[0].ExpirationTime.Sub(start)
+			if d < tc.want-time.Second || d > tc.want+time.Second {
+				t.Errorf("%d: unexpected cache duration: want %s, got %s", i, tc.want, d)
+			}
+		})
+	}
+}
+
+func TestReconcile_RateLimited(t *testing.T) {
+	resetTime := time.Now().Add(2 * time.Minute)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(resetTime.Unix(), 10))
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"message": "API rate limit exceeded for xxx.xxx.xxx.xxx."}`)
+	}))
+	defer server.Close()
+	client := newGithubClient(server)
+
+	rd := newTestRD(intPtr(3))
+
+	hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+		MinReplicas: intPtr(1),
+		MaxReplicas: intPtr(5),
+	})
+
+	recorder := record.NewFakeRecorder(10)
+
+	h := newTestReconciler(t, rd, hra)
+	h.Recorder = recorder
+	h.GitHubClient = client
+
+	res := reconcileTestHRA(t, h)
+
+	if res.RequeueAfter < time.Minute || res.RequeueAfter > 2*time.Minute {
+		t.Errorf("unexpected requeueAfter: %s", res.RequeueAfter)
+	}
+
+	select {
+	case e := <-recorder.Events:
+		if !strings.HasPrefix(e, "Warning RateLimited ") {
+			t.Errorf("unexpected event: %s", e)
+		}
+	default:
+		t.Errorf("expected RateLimited event, got none")
+	}
+
+	got := getTestRD(t, h)
+
+	if *got.Spec.Replicas != 3 {
+		t.Errorf("unexpected rd.Spec.Replicas: want 3, got %d", *got.Spec.Replicas)
+	}
+
+	gotHRA := getTestHRA(t, h)
+
+	if gotHRA.Status.LastError == nil || !strings.Contains(gotHRA.Status.LastError.Message, "rate limit") {
+		t.Errorf("expected status.lastError to describe the rate limit, got %v", gotHRA.Status.LastError)
+	}
+}
+
+func TestReconcile_DryRun(t *testing.T) {
+	rd := newTestRD(intPtr(1))
+
+	hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+		MinReplicas: intPtr(2),
+		MaxReplicas: intPtr(5),
+		DryRun:      true,
+	})
+
+	recorder := record.NewFakeRecorder(10)
+
+	h := newTestReconciler(t, rd, hra)
+	h.Recorder = recorder
+
+	reconcileTestHRA(t, h)
+
+	gotRD := getTestRD(t, h)
+
+	if *gotRD.Spec.Replicas != 1 {
+		t.Errorf("unexpected rd.Spec.Replicas: want 1, got %d", *gotRD.Spec.Replicas)
+	}
+
+	gotHRA := getTestHRA(t, h)
+
+	if gotHRA.Status.DesiredReplicas == nil || *gotHRA.Status.DesiredReplicas != 2 {
+		t.Errorf("unexpected status.desiredReplicas: want 2, got %v", gotHRA.Status.DesiredReplicas)
+	}
+
+	if len(gotHRA.Status.CacheEntries) != 1 {
+		t.Errorf("unexpected cache entries: %+v", gotHRA.Status.CacheEntries)
+	}
+
+	select {
+	case e := <-recorder.Events:
+		if !strings.HasPrefix(e, "Normal DryRun Would scale runnerdeployment testrd from 1 to 2 replicas") {
+			t.Errorf("unexpected event: %s", e)
+		}
+	default:
+		t.Errorf("expected DryRun event, got none")
+	}
+}
+
+func TestReconcile_ScaleDownStabilization(t *testing.T) {
+	testcases := []struct {
+		current           int
+		max               int
+		maxScaleDownCount *int
+
+		want int
+	}{
+		// current=10, computed=2, removes at most 3
+		{
+			current:           10,
+			max:               10,
+			maxScaleDownCount: intPtr(3),
+			want:              7,
+		},
+		// current=4, computed=2, within the limit
+		{
+			current:           4,
+			max:               10,
+			maxScaleDownCount: intPtr(3),
+			want:              2,
+		},
+		// No stabilization
+		{
+			current: 10,
+			max:     10,
+			want:    2,
+		},
+		// maxReplicas is still a hard limit
+		{
+			current:           10,
+			max:               5,
+			maxScaleDownCount: intPtr(3),
+			want:              5,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			rd := newTestRD(intPtr(tc.current))
+
+			hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+				MinReplicas: intPtr(2),
+				MaxReplicas: intPtr(tc.max),
+			})
+
+			if tc.maxScaleDownCount != nil {
+				hra.Spec.ScaleDownStabilization = &v1alpha1.ScaleDownStabilization{
+					MaxScaleDownCount: tc.maxScaleDownCount,
+				}
+			}
+
+			h := newTestReconciler(t, rd, hra)
+
+			reconcileTestHRA(t, h)
+
+			got := getTestRD(t, h)
+
+			if *got.Spec.Replicas != tc.want {
+				t.Errorf("%d: incorrect desired replicas: want %d, got %d", i, tc.want, *got.Spec.Replicas)
+			}
+		})
+	}
+}
+
+func TestReconcile_ReadyCondition(t *testing.T) {
+	workflowRuns := `{"total_count": 2, "workflow_runs":[{"status":"queued"}, {"status":"in_progress"}]}"`
+	workflowRunsQueued := `{"total_count": 1, "workflow_runs":[{"status":"queued"}]}"`
+	workflowRunsInProgress := `{"total_count": 1, "workflow_runs":[{"status":"in_progress"}]}"`
+
+	testcases := []struct {
+		name       string
+		kind       string
+		newServer  func() *httptest.Server
+		wantStatus corev1.ConditionStatus
+		wantReason string
+	}{
+		{
+			name: "succeeded",
+			newServer: func() *httptest.Server {
+				return fake.NewServer(fake.WithListRepositoryWorkflowRunsResponse(200, workflowRuns, workflowRunsQueued, workflowRunsInProgress), fake.WithListWorkflowJobsResponse(200, nil), fake.WithListRunnersResponse(200, fake.RunnersListBody))
+			},
+			wantStatus: corev1.ConditionTrue,
+			wantReason: "ScalingSucceeded",
+		},
+		{
+			name: "github api error",
+			newServer: func() *httptest.Server {
+				return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					w.WriteHeader(http.StatusInternalServerError)
+					fmt.Fprint(w, `{"message": "internal server error"}`)
+				}))
+			},
+			wantStatus: corev1.ConditionFalse,
+			wantReason: "GitHubAPIError",
+		},
+		{
+			name: "unsupported scale target kind",
+			kind: "RunnerSet",
+			newServer: func() *httptest.Server {
+				return fake.NewServer(fake.WithListRepositoryWorkflowRunsResponse(200, workflowRuns, workflowRunsQueued, workflowRunsInProgress), fake.WithListWorkflowJobsResponse(200, nil), fake.WithListRunnersResponse(200, fake.RunnersListBody))
+			},
+			wantStatus: corev1.ConditionFalse,
+			wantReason: "UnsupportedScaleTargetKind",
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			server := tc.newServer()
+			defer server.Close()
+			client := newGithubClient(server)
+
+			rd := newTestRD(intPtr(1))
+
+			hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+				ScaleTargetRef: v1alpha1.ScaleTargetRef{
+					Kind: tc.kind,
+					Name: "testrd",
+				},
+				MinReplicas: intPtr(1),
+				MaxReplicas: intPtr(5),
+			})
+
+			h := newTestReconciler(t, rd, hra)
+			h.GitHubClient = client
+
+			reconcileTestHRA(t, h)
+
+			got := getTestHRA(t, h)
+
+			if len(got.Status.Conditions) != 1 {
+				t.Fatalf("unexpected conditions: want 1, got %d: %+v", len(got.Status.Conditions), got.Status.Conditions)
+			}
+
+			c := got.Status.Conditions[0]
+
+			if c.Type != v1alpha1.HorizontalRunnerAutoscalerConditionReady {
+				t.Errorf("unexpected condition type: want %s, got %s", v1alpha1.HorizontalRunnerAutoscalerConditionReady, c.Type)
+			}
+
+			if c.Status != tc.wantStatus {
+				t.Errorf("unexpected condition status: want %s, got %s", tc.wantStatus, c.Status)
+			}
+
+			if c.Reason != tc.wantReason {
+				t.Errorf("unexpected condition reason: want %s, got %s", tc.wantReason, c.Reason)
+			}
+
+			if c.LastTransitionTime.IsZero() {
+				t.Errorf("lastTransitionTime is not set")
+			}
+		})
+	}
+}
+
+func TestSetHorizontalRunnerAutoscalerCondition(t *testing.T) {
+	t1 := time.Now().Add(-time.Minute)
+	t2 := time.Now()
+
+	status := v1alpha1.HorizontalRunnerAutoscalerStatus{}
+
+	if !setHorizontalRunnerAutoscalerCondition(&status, newReadyCondition(corev1.ConditionFalse, "GitHubAPIError", "a"), t1) {
+		t.Fatalf("expected the condition to be added")
+	}
+
+	if setHorizontalRunnerAutoscalerCondition(&status, newReadyCondition(corev1.ConditionFalse, "GitHubAPIError", "a"), t2) {
+		t.Errorf("expected no change for the same condition")
+	}
+
+	if !setHorizontalRunnerAutoscalerCondition(&status, newReadyCondition(corev1.ConditionFalse, "GitHubAPIError", "b"), t2) {
+		t.Errorf("expected the message to be updated")
+	}
+
+	if !status.Conditions[0].LastTransitionTime.Time.Equal(t1) {
+		t.Errorf("lastTransitionTime should not change without a status transition: got %s", status.Conditions[0].LastTransitionTime)
+	}
+
+	if !setHorizontalRunnerAutoscalerCondition(&status, newReadyCondition(corev1.ConditionTrue, "ScalingSucceeded", "c"), t2) {
+		t.Errorf("expected the status to be updated")
+	}
+
+	if len(status.Conditions) != 1 {
+		t.Fatalf("unexpected conditions: want 1, got %d", len(status.Conditions))
+	}
+
+	if !status.Conditions[0].LastTransitionTime.Time.Equal(t2) {
+		t.Errorf("lastTransitionTime should change on a status transition: got %s", status.Conditions[0].LastTransitionTime)
+	}
+}
+
+func TestReconcile_DesiredReplicasOverride(t *testing.T) {
+	testcases := []struct {
+		annotations      map[string]string
+		want             int
+		wantEvent        string
+		wantCacheEntries int
+	}{
+		{
+			annotations:      nil,
+			want:             2,
+			wantCacheEntries: 1,
+		},
+		{
+			annotations:      map[string]string{AnnotationKeyDesiredReplicasOverride: "4"},
+			want:             4,
+			wantEvent:        "Normal DesiredReplicasOverride Desired replicas of runnerdeployment testrd are overridden to 4",
+			wantCacheEntries: 0,
+		},
+		{
+			// The override bypasses minReplicas
+			annotations:      map[string]string{AnnotationKeyDesiredReplicasOverride: "0"},
+			want:             0,
+			wantEvent:        "Normal DesiredReplicasOverride Desired replicas of runnerdeployment testrd are overridden to 0",
+			wantCacheEntries: 0,
+		},
+		{
+			// The override is still clamped by maxReplicas
+			annotations:      map[string]string{AnnotationKeyDesiredReplicasOverride: "10"},
+			want:             5,
+			wantEvent:        "Normal DesiredReplicasOverride Desired replicas of runnerdeployment testrd are overridden to 10",
+			wantCacheEntries: 0,
+		},
+		{
+			annotations:      map[string]string{AnnotationKeyDesiredReplicasOverride: "many"},
+			want:             2,
+			wantEvent:        "Warning InvalidDesiredReplicasOverride parsing annotation " + AnnotationKeyDesiredReplicasOverride,
+			wantCacheEntries: 1,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			rd := newTestRD(intPtr(3))
+
+			hra := &v1alpha1.HorizontalRunnerAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "testhra",
+					Namespace:   "default",
+					Annotations: tc.annotations,
+				},
+				Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+					ScaleTargetRef: v1alpha1.ScaleTargetRef{
+						Name: "testrd",
+					},
+					MinReplicas: intPtr(2),
+					MaxReplicas: intPtr(5),
+				},
+			}
+
+			recorder := record.NewFakeRecorder(10)
+
+			h := newTestReconciler(t, rd, hra)
+			h.Recorder = recorder
+
+			reconcileTestHRA(t, h)
+
+			gotRD := getTestRD(t, h)
+
+			if *gotRD.Spec.Replicas != tc.want {
+				t.Errorf("unexpected rd.Spec.Replicas: want %d, got %d", tc.want, *gotRD.Spec.Replicas)
+			}
+
+			gotHRA := getTestHRA(t, h)
+
+			if len(gotHRA.Status.CacheEntries) != tc.wantCacheEntries {
+				t.Errorf("unexpected cache entries: want %d, got %+v", tc.wantCacheEntries, gotHRA.Status.CacheEntries)
+			}
+
+			if tc.wantEvent == "" {
+				return
+			}
+
+			select {
+			case e := <-recorder.Events:
+				if !strings.HasPrefix(e, tc.wantEvent) {
+					t.Errorf("unexpected event: %s", e)
+				}
+			default:
+				t.Errorf("expected event %q, got none", tc.wantEvent)
+			}
+		})
+	}
+}
+
+func TestJitterDuration(t *testing.T) {
+	d := 10 * time.Minute
+
+	if got := jitterDuration(d, 0); got != d {
+		t.Errorf("unexpected duration without jitter: want %s, got %s", d, got)
+	}
+
+	var spread bool
+
+	for i := 0; i < 100; i++ {
+		got := jitterDuration(d, 0.1)
+
+		if got < 9*time.Minute || got > 11*time.Minute {
+			t.Fatalf("jittered duration out of range: %s", got)
+		}
+
+		if got != d {
+			spread = true
+		}
+	}
+
+	if !spread {
+		t.Errorf("expected the duration to be jittered")
+	}
+}
+
+func TestReconcile_ScalingEvent(t *testing.T) {
+	workflowRuns := `{"total_count": 3, "workflow_runs":[{"status":"queued"}, {"status":"in_progress"}, {"status":"in_progress"}]}"`
+	workflowRunsQueued := `{"total_count": 1, "workflow_runs":[{"status":"queued"}]}"`
+	workflowRunsInProgress := `{"total_count": 2, "workflow_runs":[{"status":"in_progress"}, {"status":"in_progress"}]}"`
+
+	testcases := []struct {
+		replicas  int
+		status    v1alpha1.HorizontalRunnerAutoscalerStatus
+		wantEvent string
+	}{
+		{
+			replicas:  1,
+			wantEvent: `Normal ScaledRunnerDeployment Scaled runnerdeployment testrd from 1 to 3 replicas: metric=TotalNumberOfQueuedAndInProgressWorkflowRuns observed="1 queued and 2 in-progress workflow runs and jobs" computed=3 cached=false`,
+		},
+		{
+			replicas: 1,
+			status: v1alpha1.HorizontalRunnerAutoscalerStatus{
+				WinningMetricType: v1alpha1.AutoscalingMetricTypePercentageRunnersBusy,
+				CacheEntries: []v1alpha1.CacheEntry{
+					{
+						Key:            v1alpha1.CacheEntryKeyDesiredReplicas,
+						Value:          2,
+						ExpirationTime: metav1.Time{Time: time.Now().Add(time.Hour)},
+					},
+				},
+			},
+			wantEvent: `Normal ScaledRunnerDeployment Scaled runnerdeployment testrd from 1 to 2 replicas: metric=PercentageRunnersBusy computed=2 cached=true`,
+		},
+		// No event is emitted without a scale change
+		{
+			replicas: 3,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			rd := newTestRD(intPtr(tc.replicas))
+
+			hra := &v1alpha1.HorizontalRunnerAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "testhra",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+					ScaleTargetRef: v1alpha1.ScaleTargetRef{
+						Name: "testrd",
+					},
+					MinReplicas: intPtr(1),
+					MaxReplicas: intPtr(5),
+				},
+				Status: tc.status,
+			}
+
+			recorder := record.NewFakeRecorder(10)
+
+			h := newTestReconciler(t, rd, hra, withGitHubResponses(fake.WithListRepositoryWorkflowRunsResponse(200, workflowRuns, workflowRunsQueued, workflowRunsInProgress)))
+			h.Recorder = recorder
+
+			reconcileTestHRA(t, h)
+
+			select {
+			case e := <-recorder.Events:
+				if e != tc.wantEvent {
+					t.Errorf("unexpected event: want %q, got %q", tc.wantEvent, e)
+				}
+			default:
+				if tc.wantEvent != "" {
+					t.Errorf("expected event %q, got none", tc.wantEvent)
+				}
+			}
+		})
+	}
+}
+
+func TestReconcile_FailureBackoff(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"message": "Bad credentials"}`)
+	}))
+	defer failing.Close()
+
+	rd := newTestRD(intPtr(1))
+
+	hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+		MinReplicas: intPtr(1),
+		MaxReplicas: intPtr(5),
+	})
+
+	recorder := record.NewFakeRecorder(10)
+
+	h := newTestReconciler(t, rd, hra)
+	h.Recorder = recorder
+
+	succeeding := h.GitHubClient
+	h.GitHubClient = newGithubClient(failing)
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "testhra"}}
+
+	for i, want := range []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second} {
+		res, err := h.Reconcile(req)
+		if err != nil {
+			t.Fatalf("%d: unexpected error: %v", i, err)
+		}
+
+		if res.RequeueAfter != want {
+			t.Errorf("%d: unexpected requeueAfter: want %s, got %s", i, want, res.RequeueAfter)
+		}
+
+		got := getTestHRA(t, h)
+
+		if got.Status.ConsecutiveFailures != i+1 {
+			t.Errorf("%d: unexpected status.consecutiveFailures: want %d, got %d", i, i+1, got.Status.ConsecutiveFailures)
+		}
+
+		if got.Status.BackoffSeconds != int(want/time.Second) {
+			t.Errorf("%d: unexpected status.backoffSeconds: want %d, got %d", i, int(want/time.Second), got.Status.BackoffSeconds)
+		}
+
+		if got.Status.LastError == nil || got.Status.LastError.Message == "" || got.Status.LastError.Time.IsZero() {
+			t.Errorf("%d: expected status.lastError to be set, got %v", i, got.Status.LastError)
+		}
+
+		select {
+		case e := <-recorder.Events:
+			if !strings.HasPrefix(e, "Normal RunnerAutoscalingFailure ") || !strings.HasSuffix(e, fmt.Sprintf("backing off for %s after %d consecutive failures", want, i+1)) {
+				t.Errorf("%d: unexpected event: %s", i, e)
+			}
+		default:
+			t.Errorf("%d: expected RunnerAutoscalingFailure event, got none", i)
+		}
+	}
+
+	h.GitHubClient = succeeding
+
+	reconcileTestHRA(t, h)
+
+	got := getTestHRA(t, h)
+
+	if got.Status.ConsecutiveFailures != 0 || got.Status.BackoffSeconds != 0 || got.Status.LastError != nil {
+		t.Errorf("expected the failures to be reset on success, got consecutiveFailures=%d backoffSeconds=%d lastError=%v", got.Status.ConsecutiveFailures, got.Status.BackoffSeconds, got.Status.LastError)
+	}
+}
+
+func TestNewLastError(t *testing.T) {
+	now := time.Now()
+
+	testcases := []struct {
+		msg  string
+		want string
+	}{
+		{
+			msg:  "short error",
+			want: "short error",
+		},
+		{
+			msg:  strings.Repeat("a", maxLastErrorMessageLength),
+			want: strings.Repeat("a", maxLastErrorMessageLength),
+		},
+		{
+			msg:  strings.Repeat("a", maxLastErrorMessageLength+1),
+			want: strings.Repeat("a", maxLastErrorMessageLength-3) + "...",
+		},
+		// A multi-byte character straddling the limit is dropped as a whole
+		{
+			msg:  strings.Repeat("a", maxLastErrorMessageLength-4) + "\u00e9" + strings.Repeat("a", 10),
+			want: strings.Repeat("a", maxLastErrorMessageLength-4) + "...",
+		},
+	}
+
+	for i, tc := range testcases {
+		got := newLastError(errors.New(tc.msg), now)
+
+		if got.Message != tc.want {
+			t.Errorf("%d: unexpected message: want %q, got %q", i, tc.want, got.Message)
+		}
+
+		if !got.Time.Time.Equal(now) {
+			t.Errorf("%d: unexpected time: want %s, got %s", i, now, got.Time)
+		}
+	}
+}
+
+func TestGetFailureBackoff(t *testing.T) {
+	testcases := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 1, want: 10 * time.Second},
+		{failures: 2, want: 20 * time.Second},
+		{failures: 6, want: 320 * time.Second},
+		{failures: 7, want: 10 * time.Minute},
+		{failures: 100, want: 10 * time.Minute},
+	}
+
+	for _, tc := range testcases {
+		if got := getFailureBackoff(tc.failures); got != tc.want {
+			t.Errorf("unexpected backoff after %d failures: want %s, got %s", tc.failures, tc.want, got)
+		}
+	}
+}
+
+func TestReconcile_PolicyRef(t *testing.T) {
+	testcases := []struct {
+		policy    map[string]string
+		want      int
+		wantEvent string
+	}{
+		{
+			policy: map[string]string{"minReplicas": "3", "maxReplicas": "4"},
+			want:   3,
+		},
+		// maxReplicas from the policy caps minReplicas from the inline spec
+		{
+			policy:    map[string]string{"maxReplicas": "1"},
+			want:      2,
+			wantEvent: "Warning InvalidPolicy validating configmap testpolicy: minReplicas (2) cannot be greater than maxReplicas (1). Falling back to the inline spec",
+		},
+		{
+			policy:    map[string]string{"minReplicas": "three"},
+			want:      2,
+			wantEvent: "Warning InvalidPolicy parsing minReplicas of configmap testpolicy",
+		},
+		{
+			want:      2,
+			wantEvent: "Warning PolicyNotFound Configmap testpolicy referenced by spec.policyRef is not found. Falling back to the inline spec",
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			rd := newTestRD(intPtr(1))
+
+			hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+				PolicyRef: &v1alpha1.PolicyRef{
+					Name: "testpolicy",
+				},
+				MinReplicas: intPtr(2),
+				MaxReplicas: intPtr(5),
+			})
+
+			var objs []runtime.Object
+
+			if tc.policy != nil {
+				objs = append(objs, &corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "testpolicy",
+						Namespace: "default",
+					},
+					Data: tc.policy,
+				})
+			}
+
+			recorder := record.NewFakeRecorder(10)
+
+			h := newTestReconciler(t, rd, hra, withObjects(objs...))
+			h.Recorder = recorder
+
+			reconcileTestHRA(t, h)
+
+			gotRD := getTestRD(t, h)
+
+			if *gotRD.Spec.Replicas != tc.want {
+				t.Errorf("unexpected rd.Spec.Replicas: want %d, got %d", tc.want, *gotRD.Spec.Replicas)
+			}
+
+			gotHRA := getTestHRA(t, h)
+
+			if *gotHRA.Spec.MinReplicas != 2 {
+				t.Errorf("the policy must not be persisted to the spec: got minReplicas %d", *gotHRA.Spec.MinReplicas)
+			}
+
+			if tc.wantEvent == "" {
+				return
+			}
+
+			select {
+			case e := <-recorder.Events:
+				if !strings.HasPrefix(e, tc.wantEvent) {
+					t.Errorf("unexpected event: want %q, got %q", tc.wantEvent, e)
+				}
+			default:
+				t.Errorf("expected event %q, got none", tc.wantEvent)
+			}
+		})
+	}
+}
+
+func TestReconcile_ScaleDownReadinessGate(t *testing.T) {
+	testcases := []struct {
+		gate             bool
+		ready            int
+		max              int
+		want             int
+		wantRequeueAfter time.Duration
+	}{
+		// Not stabilized yet
+		{
+			gate:             true,
+			ready:            1,
+			max:              5,
+			want:             3,
+			wantRequeueAfter: ScaleDownReadinessGateRequeueDelay,
+		},
+		// Stabilized
+		{
+			gate:  true,
+			ready: 3,
+			max:   5,
+			want:  1,
+		},
+		// MaxReplicas is still honored
+		{
+			gate:             true,
+			ready:            1,
+			max:              2,
+			want:             2,
+			wantRequeueAfter: ScaleDownReadinessGateRequeueDelay,
+		},
+		{
+			gate:  false,
+			ready: 1,
+			max:   5,
+			want:  1,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			rd := &v1alpha1.RunnerDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "testrd",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.RunnerDeploymentSpec{
+					Template: v1alpha1.RunnerTemplate{
+						Spec: v1alpha1.RunnerSpec{
+							Repository: "test/valid",
+						},
+					},
+					Replicas: intPtr(3),
+				},
+				Status: v1alpha1.RunnerDeploymentStatus{
+					ReadyReplicas: tc.ready,
+				},
+			}
+
+			hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+				MinReplicas:            intPtr(1),
+				MaxReplicas:            intPtr(tc.max),
+				ScaleDownReadinessGate: tc.gate,
+			})
+
+			h := newTestReconciler(t, rd, hra)
+
+			res := reconcileTestHRA(t, h)
+
+			wantRequeueAfter := tc.wantRequeueAfter
+
+			// Requeued at the cache expiry unless the gate retries sooner
+			if wantRequeueAfter == 0 {
+				gotHRA := getTestHRA(t, h)
+
+				if gotHRA.Status.CacheExpiresAt == nil {
+					t.Fatalf("missing status.cacheExpiresAt")
+				}
+
+				wantRequeueAfter = time.Until(gotHRA.Status.CacheExpiresAt.Time)
+			}
+
+			// The status is persisted in the precision of seconds
+			if d := res.RequeueAfter - wantRequeueAfter; d < -time.Second || d > time.Second {
+				t.Errorf("unexpected requeueAfter: want %s, got %s", wantRequeueAfter, res.RequeueAfter)
+			}
+
+			gotRD := getTestRD(t, h)
+
+			if *gotRD.Spec.Replicas != tc.want {
+				t.Errorf("unexpected rd.Spec.Replicas: want %d, got %d", tc.want, *gotRD.Spec.Replicas)
+			}
+		})
+	}
+}
+
+func TestReconcile_TolerancePercent(t *testing.T) {
+	queuedWorkflowRuns := func(n int) string {
+		var runs []string
+		for i := 0; i < n; i++ {
+			runs = append(runs, `{"status":"queued"}`)
+		}
+		return fmt.Sprintf(`{"total_count": %d, "workflow_runs":[%s]}`, n, strings.Join(runs, ","))
+	}
+
+	testcases := []struct {
+		tolerance   int
+		queued      int
+		min         int
+		reservation int
+		want        int
+	}{
+		// 10 to 11 is within 10% of tolerance
+		{
+			tolerance: 10,
+			queued:    11,
+			min:       1,
+			want:      10,
+		},
+		{
+			tolerance: 10,
+			queued:    9,
+			min:       1,
+			want:      10,
+		},
+		// 10 to 13 is not
+		{
+			tolerance: 10,
+			queued:    13,
+			min:       1,
+			want:      13,
+		},
+		{
+			tolerance: 0,
+			queued:    11,
+			min:       1,
+			want:      11,
+		},
+		// Capacity reservations force the change
+		{
+			tolerance:   10,
+			queued:      10,
+			min:         1,
+			reservation: 1,
+			want:        11,
+		},
+		// MinReplicas forces the change
+		{
+			tolerance: 10,
+			queued:    10,
+			min:       11,
+			want:      11,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			rd := newTestRD(intPtr(10))
+
+			hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+				MinReplicas:      intPtr(tc.min),
+				MaxReplicas:      intPtr(20),
+				TolerancePercent: tc.tolerance,
+			})
+
+			if tc.reservation > 0 {
+				hra.Spec.CapacityReservations = []v1alpha1.CapacityReservation{
+					{
+						Name:           "test",
+						ExpirationTime: metav1.Time{Time: time.Now().Add(time.Hour)},
+						Replicas:       tc.reservation,
+					},
+				}
+			}
+
+			h := newTestReconciler(t, rd, hra, withGitHubResponses(fake.WithListRepositoryWorkflowRunsResponse(200, queuedWorkflowRuns(tc.queued), queuedWorkflowRuns(tc.queued), queuedWorkflowRuns(0))))
+
+			reconcileTestHRA(t, h)
+
+			gotRD := getTestRD(t, h)
+
+			if *gotRD.Spec.Replicas != tc.want {
+				t.Errorf("unexpected rd.Spec.Replicas: want %d, got %d", tc.want, *gotRD.Spec.Replicas)
+			}
+		})
+	}
+}
+
+func TestWithinTolerance(t *testing.T) {
+	testcases := []struct {
+		current, desired, tolerance int
+		want                        bool
+	}{
+		{current: 10, desired: 11, tolerance: 10, want: true},
+		{current: 10, desired: 9, tolerance: 10, want: true},
+		{current: 10, desired: 12, tolerance: 10, want: false},
+		{current: 10, desired: 10, tolerance: 0, want: false},
+		{current: 0, desired: 1, tolerance: 100, want: false},
+		{current: 3, desired: 4, tolerance: 30, want: false},
+		{current: 3, desired: 4, tolerance: 34, want: true},
+	}
+
+	for i, tc := range testcases {
+		if got := withinTolerance(tc.current, tc.desired, tc.tolerance); got != tc.want {
+			t.Errorf("%d: unexpected result for %d to %d with %d%%: want %v, got %v", i, tc.current, tc.desired, tc.tolerance, tc.want, got)
+		}
+	}
+}
+
+func TestReconcile_ScaleDownGraceSeconds(t *testing.T) {
+	testcases := []struct {
+		grace        *int
+		busyRunners  *int
+		lastBusyTime time.Duration
+
+		want             int
+		wantRequeue      bool
+		wantLastBusyTime bool
+	}{
+		// The busy runners dropped from 2 to 0 just now
+		{
+			grace:            intPtr(60),
+			busyRunners:      intPtr(2),
+			want:             3,
+			wantRequeue:      true,
+			wantLastBusyTime: true,
+		},
+		// The busy runners dropped a minute ago
+		{
+			grace:            intPtr(300),
+			busyRunners:      intPtr(0),
+			lastBusyTime:     -time.Minute,
+			want:             3,
+			wantRequeue:      true,
+			wantLastBusyTime: true,
+		},
+		// The grace period has elapsed
+		{
+			grace:            intPtr(60),
+			busyRunners:      intPtr(0),
+			lastBusyTime:     -2 * time.Minute,
+			want:             1,
+			wantLastBusyTime: true,
+		},
+		// The busy runners have never been observed
+		{
+			grace: intPtr(60),
+			want:  1,
+		},
+		{
+			busyRunners:      intPtr(2),
+			want:             1,
+			wantLastBusyTime: true,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			rd := newTestRD(intPtr(3))
+
+			hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+				MinReplicas:           intPtr(1),
+				MaxReplicas:           intPtr(5),
+				ScaleDownGraceSeconds: tc.grace,
+			})
+
+			hra.Status = v1alpha1.HorizontalRunnerAutoscalerStatus{
+				BusyRunners: tc.busyRunners,
+			}
+
+			if tc.lastBusyTime != 0 {
+				hra.Status.LastBusyTime = &metav1.Time{Time: time.Now().Add(tc.lastBusyTime)}
+			}
+
+			h := newTestReconciler(t, rd, hra)
+			h.CacheDurationJitter = -1
+
+			res := reconcileTestHRA(t, h)
+
+			if tc.wantRequeue && (res.RequeueAfter <= 0 || res.RequeueAfter > time.Duration(*tc.grace)*time.Second) {
+				t.Errorf("unexpected requeueAfter: want within %ds, got %s", *tc.grace, res.RequeueAfter)
+			} else if !tc.wantRequeue && res.RequeueAfter.Round(time.Second) != 10*time.Minute {
+				// Requeued only at the expiry of the desired replicas cached for the default duration
+				t.Errorf("unexpected requeueAfter: want 10m0s, got %s", res.RequeueAfter)
+			}
+
+			gotRD := getTestRD(t, h)
+
+			if *gotRD.Spec.Replicas != tc.want {
+				t.Errorf("unexpected rd.Spec.Replicas: want %d, got %d", tc.want, *gotRD.Spec.Replicas)
+			}
+
+			got := getTestHRA(t, h)
+
+			if got.Status.BusyRunners == nil || *got.Status.BusyRunners != 0 {
+				t.Errorf("unexpected status.busyRunners: want 0, got %v", got.Status.BusyRunners)
+			}
+
+			if tc.wantLastBusyTime != (got.Status.LastBusyTime != nil) {
+				t.Errorf("unexpected status.lastBusyTime: %v", got.Status.LastBusyTime)
+			}
+		})
+	}
+}
+
+func TestReconcile_GlobalMaxReplicas(t *testing.T) {
+	workflowRuns := `{"total_count": 3, "workflow_runs":[{"status":"queued"}, {"status":"queued"}, {"status":"queued"}]}"`
+	noWorkflowRuns := `{"total_count": 0, "workflow_runs":[]}"`
+
+	var objs []runtime.Object
+
+	for _, name := range []string{"a", "b"} {
+		objs = append(objs,
+			&v1alpha1.RunnerDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "testrd-" + name,
+					Namespace: "default",
+				},
+				Spec: v1alpha1.RunnerDeploymentSpec{
+					Template: v1alpha1.RunnerTemplate{
+						Spec: v1alpha1.RunnerSpec{
+							Repository: "test/valid",
+						},
+					},
+					Replicas: intPtr(0),
+				},
+			},
+			&v1alpha1.HorizontalRunnerAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "testhra-" + name,
+					Namespace: "default",
+				},
+				Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+					ScaleTargetRef: v1alpha1.ScaleTargetRef{
+						Name: "testrd-" + name,
+					},
+					MinReplicas: intPtr(0),
+					MaxReplicas: intPtr(5),
+				},
+			},
+		)
+	}
+
+	h := newTestReconciler(t, nil, nil,
+		withGitHubResponses(fake.WithListRepositoryWorkflowRunsResponse(200, workflowRuns, workflowRuns, noWorkflowRuns)),
+		withObjects(objs...),
+	)
+	h.Recorder = record.NewFakeRecorder(100)
+	h.GlobalMaxReplicas = 4
+
+	steps := []struct {
+		name string
+		want int
+	}{
+		// b has no demand yet so a gets all it wants
+		{name: "a", want: 3},
+		// b's share is 2, but only 1 is left until a releases its excess
+		{name: "b", want: 1},
+		{name: "a", want: 2},
+		{name: "b", want: 2},
+	}
+
+	for i, s := range steps {
+		if _, err := h.Reconcile(ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "testhra-" + s.name}}); err != nil {
+			t.Fatalf("%d: unexpected error: %v", i, err)
+		}
+
+		var rd v1alpha1.RunnerDeployment
+		if err := h.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "testrd-" + s.name}, &rd); err != nil {
+			t.Fatalf("%d: unexpected error: %v", i, err)
+		}
+
+		if *rd.Spec.Replicas != s.want {
+			t.Errorf("%d: unexpected replicas of testrd-%s: want %d, got %d", i, s.name, s.want, *rd.Spec.Replicas)
+		}
+	}
+}
+
+func TestAllocateByWeight(t *testing.T) {
+	key := func(name string) types.NamespacedName {
+		return types.NamespacedName{Namespace: "default", Name: name}
+	}
+
+	testcases := []struct {
+		total    int
+		entries  map[types.NamespacedName]*replicaBudgetEntry
+		byDemand bool
+		want     map[types.NamespacedName]int
+	}{
+		{
+			total: 10,
+			entries: map[types.NamespacedName]*replicaBudgetEntry{
+				key("a"): {weight: 1, demand: 3},
+				key("b"): {weight: 1, demand: 3},
+			},
+			want: map[types.NamespacedName]int{key("a"): 3, key("b"): 3},
+		},
+		{
+			total: 10,
+			entries: map[types.NamespacedName]*replicaBudgetEntry{
+				key("a"): {weight: 1, demand: 10},
+				key("b"): {weight: 4, demand: 10},
+			},
+			want: map[types.NamespacedName]int{key("a"): 2, key("b"): 8},
+		},
+		// The share unused by c is redistributed to a and b
+		{
+			total: 10,
+			entries: map[types.NamespacedName]*replicaBudgetEntry{
+				key("a"): {weight: 1, demand: 10},
+				key("b"): {weight: 1, demand: 10},
+				key("c"): {weight: 1, demand: 2},
+				key("d"): {weight: 1, demand: 0},
+			},
+			want: map[types.NamespacedName]int{key("a"): 4, key("b"): 4, key("c"): 2},
+		},
+		// The replica left over by rounding goes to the one with the largest fraction, ties broken by the name
+		{
+			total: 10,
+			entries: map[types.NamespacedName]*replicaBudgetEntry{
+				key("a"): {weight: 1, demand: 10},
+				key("b"): {weight: 1, demand: 10},
+				key("c"): {weight: 1, demand: 10},
+			},
+			want: map[types.NamespacedName]int{key("a"): 4, key("b"): 3, key("c"): 3},
+		},
+		{
+			total: 10,
+			entries: map[types.NamespacedName]*replicaBudgetEntry{
+				key("a"): {weight: 1, demand: 10},
+				key("b"): {weight: 2, demand: 10},
+				key("c"): {weight: 4, demand: 10},
+			},
+			want: map[types.NamespacedName]int{key("a"): 1, key("b"): 3, key("c"): 6},
+		},
+		// Over-subscribed by twice, split in proportion to the demands
+		{
+			total: 10,
+			entries: map[types.NamespacedName]*replicaBudgetEntry{
+				key("a"): {weight: 1, demand: 4},
+				key("b"): {weight: 1, demand: 16},
+			},
+			byDemand: true,
+			want:     map[types.NamespacedName]int{key("a"): 2, key("b"): 8},
+		},
+		// The weights still apply on top of the demands
+		{
+			total: 10,
+			entries: map[types.NamespacedName]*replicaBudgetEntry{
+				key("a"): {weight: 3, demand: 10},
+				key("b"): {weight: 1, demand: 10},
+				key("c"): {weight: 1, demand: 0},
+			},
+			byDemand: true,
+			want:     map[types.NamespacedName]int{key("a"): 8, key("b"): 2},
+		},
+		{
+			total: 10,
+			entries: map[types.NamespacedName]*replicaBudgetEntry{
+				key("a"): {weight: 1, demand: 5},
+				key("b"): {weight: 1, demand: 15},
+				key("c"): {weight: 1, demand: 10},
+			},
+			byDemand: true,
+			want:     map[types.NamespacedName]int{key("a"): 2, key("b"): 5, key("c"): 3},
+		},
+		// Not over-subscribed
+		{
+			total: 10,
+			entries: map[types.NamespacedName]*replicaBudgetEntry{
+				key("a"): {weight: 1, demand: 2},
+				key("b"): {weight: 1, demand: 3},
+			},
+			byDemand: true,
+			want:     map[types.NamespacedName]int{key("a"): 2, key("b"): 3},
+		},
+	}
+
+	for i, tc := range testcases {
+		got := allocateByWeight(tc.total, tc.entries, tc.byDemand)
+
+		if len(got) != len(tc.want) {
+			t.Errorf("%d: unexpected allocations: want %v, got %v", i, tc.want, got)
+
+			continue
+		}
+
+		for k, v := range tc.want {
+			if got[k] != v {
+				t.Errorf("%d: unexpected allocation for %s: want %d, got %d", i, k, v, got[k])
+			}
+		}
+	}
+}
+
+func TestControllerOptions(t *testing.T) {
+	testcases := []struct {
+		maxConcurrentReconciles int
+		want                    int
+	}{
+		{maxConcurrentReconciles: 0, want: 1},
+		{maxConcurrentReconciles: -1, want: 1},
+		{maxConcurrentReconciles: 10, want: 10},
+	}
+
+	for _, tc := range testcases {
+		r := &HorizontalRunnerAutoscalerReconciler{MaxConcurrentReconciles: tc.maxConcurrentReconciles}
+
+		if got := r.controllerOptions().MaxConcurrentReconciles; got != tc.want {
+			t.Errorf("unexpected MaxConcurrentReconciles for %d: want %d, got %d", tc.maxConcurrentReconciles, tc.want, got)
+		}
+	}
+}
+
+func TestReconcile_DecisionLog(t *testing.T) {
+	workflowRuns := `{"total_count": 2, "workflow_runs":[{"status":"queued"}, {"status":"queued"}]}"`
+	noWorkflowRuns := `{"total_count": 0, "workflow_runs":[]}"`
+
+	rd := newTestRD(intPtr(1))
+
+	hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+		MinReplicas: intPtr(1),
+		MaxReplicas: intPtr(2),
+		CapacityReservations: []v1alpha1.CapacityReservation{
+			{Name: "valid", ExpirationTime: metav1.Time{Time: time.Now().Add(time.Hour)}, Replicas: 1},
+		},
+	})
+
+	logs := &bytes.Buffer{}
+
+	h := newTestReconciler(t, rd, hra, withGitHubResponses(fake.WithListRepositoryWorkflowRunsResponse(200, workflowRuns, workflowRuns, noWorkflowRuns)))
+	h.Log = &testLogger{name: "testlog", writer: logs}
+
+	reconcileTestHRA(t, h)
+
+	var decision string
+	for _, line := range strings.Split(logs.String(), "\n") {
+		if strings.Contains(line, "Decided desired replicas") {
+			decision = line
+		}
+	}
+
+	if decision == "" {
+		t.Fatalf("decision log not found in:\n%s", logs.String())
+	}
+
+	for _, want := range []string{
+		"reconcileID=",
+		"cacheHit=false",
+		"metricType=TotalNumberOfQueuedAndInProgressWorkflowRuns",
+		"computedReplicas=2",
+		"reservedReplicas=1",
+		"maxReplicasApplied=true",
+		"desired=2",
+	} {
+		if !strings.Contains(decision, want) {
+			t.Errorf("decision log is missing %q: %s", want, decision)
+		}
+	}
+}
+
+func TestReconcile_MaxPendingRunnerPods(t *testing.T) {
+	boolPtr := func(v bool) *bool {
+		return &v
+	}
+
+	workflowRuns := `{"total_count": 5, "workflow_runs":[{"status":"queued"}, {"status":"queued"}, {"status":"queued"}, {"status":"queued"}, {"status":"queued"}]}"`
+	noWorkflowRuns := `{"total_count": 0, "workflow_runs":[]}"`
+
+	testcases := []struct {
+		limit *int
+
+		// phases is the phase of the pod of each runner of the scale target
+		phases []corev1.PodPhase
+
+		// otherPending is the number of pending pods of the runners of another runnerdeployment
+		otherPending int
+
+		want      int
+		wantEvent string
+	}{
+		// The limit is disabled by default
+		{
+			phases: []corev1.PodPhase{corev1.PodPending, corev1.PodPending},
+			want:   5,
+		},
+		// Too many pods are pending, so the scale up is limited to one replica
+		{
+			limit:     intPtr(2),
+			phases:    []corev1.PodPhase{corev1.PodPending, corev1.PodPending},
+			want:      3,
+			wantEvent: "Warning ScaleBlockedByPending Limiting the scale up of runnerdeployment testrd to 3 replicas instead of 5 while 2 runner pods are pending",
+		},
+		// Fewer pods than the limit are pending
+		{
+			limit:  intPtr(2),
+			phases: []corev1.PodPhase{corev1.PodPending, corev1.PodRunning},
+			want:   5,
+		},
+		// The pending pods of the other runnerdeployment aren't counted
+		{
+			limit:        intPtr(1),
+			phases:       []corev1.PodPhase{corev1.PodRunning, corev1.PodRunning},
+			otherPending: 2,
+			want:         5,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			rd := &v1alpha1.RunnerDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "testrd",
+					Namespace: "default",
+					UID:       "testrd-uid",
+				},
+				Spec: v1alpha1.RunnerDeploymentSpec{
+					Template: v1alpha1.RunnerTemplate{
+						Spec: v1alpha1.RunnerSpec{
+							Repository: "test/valid",
+						},
+					},
+					Replicas: intPtr(len(tc.phases)),
+				},
+			}
+
+			hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+				MinReplicas:          intPtr(1),
+				MaxReplicas:          intPtr(10),
+				MaxPendingRunnerPods: tc.limit,
+			})
+
+			var objs []runtime.Object
+
+			controlledBy := func(kind, name string, uid types.UID) []metav1.OwnerReference {
+				return []metav1.OwnerReference{{APIVersion: "actions.summerwind.dev/v1alpha1", Kind: kind, Name: name, UID: uid, Controller: boolPtr(true)}}
+			}
+
+			addRunners := func(rdName string, rdUID types.UID, phases []corev1.PodPhase) {
+				rsName := rdName + "-rs"
+				rsUID := types.UID(rsName + "-uid")
+
+				objs = append(objs, &v1alpha1.RunnerReplicaSet{
+					ObjectMeta: metav1.ObjectMeta{Name: rsName, Namespace: "default", UID: rsUID, OwnerReferences: controlledBy("RunnerDeployment", rdName, rdUID)},
+				})
+
+				for j, phase := range phases {
+					name := fmt.Sprintf("%s-%d", rsName, j)
+					uid := types.UID(name + "-uid")
+
+					objs = append(objs,
+						&v1alpha1.Runner{
+							ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: uid, OwnerReferences: controlledBy("RunnerReplicaSet", rsName, rsUID)},
+						},
+						&corev1.Pod{
+							ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", OwnerReferences: controlledBy("Runner", name, uid)},
+							Status:     corev1.PodStatus{Phase: phase},
+						},
+					)
+				}
+			}
+
+			addRunners("testrd", rd.UID, tc.phases)
+
+			var otherPhases []corev1.PodPhase
+			for j := 0; j < tc.otherPending; j++ {
+				otherPhases = append(otherPhases, corev1.PodPending)
+			}
+			addRunners("otherrd", "otherrd-uid", otherPhases)
+
+			recorder := record.NewFakeRecorder(10)
+
+			h := newTestReconciler(t, rd, hra,
+				withGitHubResponses(fake.WithListRepositoryWorkflowRunsResponse(200, workflowRuns, workflowRuns, noWorkflowRuns)),
+				withObjects(objs...),
+			)
+			h.Recorder = recorder
+
+			reconcileTestHRA(t, h)
+
+			gotRD := getTestRD(t, h)
+
+			if gotRD.Spec.Replicas == nil || *gotRD.Spec.Replicas != tc.want {
+				t.Errorf("unexpected rd.Spec.Replicas: want %d, got %v", tc.want, gotRD.Spec.Replicas)
+			}
+
+			var gotEvent string
+
+			for len(recorder.Events) > 0 {
+				if e := <-recorder.Events; strings.Contains(e, "ScaleBlockedByPending") {
+					gotEvent = e
+				}
+			}
+
+			if gotEvent != tc.wantEvent {
+				t.Errorf("unexpected event: want %q, got %q", tc.wantEvent, gotEvent)
+			}
+		})
+	}
+}
+
+func TestReconcile_MaxScaleDownStallSeconds(t *testing.T) {
+	testcases := []struct {
+		maxStall     *int
+		stalledSince time.Duration
+		cached       bool
+
+		want             int
+		wantStalledSince bool
+		wantEvent        bool
+	}{
+		// The scale down deferred by the scale-down delay starts stalling
+		{
+			maxStall:         intPtr(60),
+			want:             3,
+			wantStalledSince: true,
+		},
+		// Stalled within the deadline
+		{
+			maxStall:         intPtr(60),
+			stalledSince:     -30 * time.Second,
+			want:             3,
+			wantStalledSince: true,
+		},
+		// Stalled past the deadline, so the scale down is forced
+		{
+			maxStall:     intPtr(60),
+			stalledSince: -2 * time.Minute,
+			want:         1,
+			wantEvent:    true,
+		},
+		// Stalled past the deadline, and the cached replicas are ignored
+		{
+			maxStall:     intPtr(60),
+			stalledSince: -2 * time.Minute,
+			cached:       true,
+			want:         1,
+			wantEvent:    true,
+		},
+		// Disabled, so the stall is untracked
+		{
+			stalledSince: -2 * time.Minute,
+			want:         3,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			rd := newTestRD(intPtr(3))
+
+			hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+				MinReplicas:              intPtr(1),
+				MaxReplicas:              intPtr(5),
+				MaxScaleDownStallSeconds: tc.maxStall,
+			})
+
+			hra.Status = v1alpha1.HorizontalRunnerAutoscalerStatus{
+				// The last scale out within the default scale-down delay defers the scale down
+				DesiredReplicas:            intPtr(3),
+				LastSuccessfulScaleOutTime: &metav1.Time{Time: time.Now().Add(-time.Minute)},
+			}
+
+			if tc.stalledSince != 0 {
+				hra.Status.ScaleDownStalledSince = &metav1.Time{Time: time.Now().Add(tc.stalledSince)}
+			}
+
+			if tc.cached {
+				hra.Status.CacheEntries = []v1alpha1.CacheEntry{
+					{Key: v1alpha1.CacheEntryKeyDesiredReplicas, Value: 3, ExpirationTime: metav1.Time{Time: time.Now().Add(time.Hour)}},
+				}
+			}
+
+			recorder := record.NewFakeRecorder(10)
+
+			h := newTestReconciler(t, rd, hra)
+			h.Recorder = recorder
+
+			res := reconcileTestHRA(t, h)
+
+			gotRD := getTestRD(t, h)
+
+			if gotRD.Spec.Replicas == nil || *gotRD.Spec.Replicas != tc.want {
+				t.Errorf("unexpected rd.Spec.Replicas: want %d, got %v", tc.want, gotRD.Spec.Replicas)
+			}
+
+			gotHRA := getTestHRA(t, h)
+
+			if got := gotHRA.Status.ScaleDownStalledSince != nil; got != tc.wantStalledSince {
+				t.Errorf("unexpected status.scaleDownStalledSince: want set=%v, got %v", tc.wantStalledSince, gotHRA.Status.ScaleDownStalledSince)
+			}
+
+			if tc.wantStalledSince && (res.RequeueAfter <= 0 || res.RequeueAfter > time.Duration(*tc.maxStall)*time.Second) {
+				t.Errorf("unexpected requeueAfter: want within %ds, got %s", *tc.maxStall, res.RequeueAfter)
+			}
+
+			var gotEvent bool
+
+			for len(recorder.Events) > 0 {
+				if e := <-recorder.Events; strings.Contains(e, "ScaleDownStallDeadlineExceeded") {
+					gotEvent = true
+				}
+			}
+
+			if gotEvent != tc.wantEvent {
+				t.Errorf("unexpected ScaleDownStallDeadlineExceeded event: want %v, got %v", tc.wantEvent, gotEvent)
+			}
+		})
+	}
+}
+
+func TestReconcile_MaxCapacityReservationReplicas(t *testing.T) {
+	workflowRuns := `{"total_count": 2, "workflow_runs":[{"status":"queued"}, {"status":"queued"}]}"`
+	noWorkflowRuns := `{"total_count": 0, "workflow_runs":[]}"`
+
+	testcases := []struct {
+		max      *int
+		reserved int
+
+		want      int
+		wantEvent string
+	}{
+		// 2 queued runs plus 10 reserved replicas, capped by maxReplicas
+		{
+			reserved: 10,
+			want:     10,
+		},
+		// The reservations are capped before maxReplicas
+		{
+			max:       intPtr(3),
+			reserved:  10,
+			want:      5,
+			wantEvent: "Warning CapacityReservationsCapped Capping the replicas added by capacity reservations from 10 to 3",
+		},
+		// Within the cap
+		{
+			max:      intPtr(3),
+			reserved: 2,
+			want:     4,
+		},
+		{
+			max:       intPtr(0),
+			reserved:  2,
+			want:      2,
+			wantEvent: "Warning CapacityReservationsCapped Capping the replicas added by capacity reservations from 2 to 0",
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			rd := newTestRD(intPtr(1))
+
+			hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+				MinReplicas:                    intPtr(1),
+				MaxReplicas:                    intPtr(10),
+				MaxCapacityReservationReplicas: tc.max,
+				CapacityReservations: []v1alpha1.CapacityReservation{
+					{Name: "webhook", ExpirationTime: metav1.Time{Time: time.Now().Add(time.Hour)}, Replicas: tc.reserved},
+				},
+			})
+
+			recorder := record.NewFakeRecorder(10)
+
+			h := newTestReconciler(t, rd, hra, withGitHubResponses(fake.WithListRepositoryWorkflowRunsResponse(200, workflowRuns, workflowRuns, noWorkflowRuns)))
+			h.Recorder = recorder
+
+			reconcileTestHRA(t, h)
+
+			gotRD := getTestRD(t, h)
+
+			if gotRD.Spec.Replicas == nil || *gotRD.Spec.Replicas != tc.want {
+				t.Errorf("unexpected rd.Spec.Replicas: want %d, got %v", tc.want, gotRD.Spec.Replicas)
+			}
+
+			var gotEvent string
+
+			for len(recorder.Events) > 0 {
+				if e := <-recorder.Events; strings.Contains(e, "CapacityReservationsCapped") {
+					gotEvent = e
+				}
+			}
+
+			if gotEvent != tc.wantEvent {
+				t.Errorf("unexpected event: want %q, got %q", tc.wantEvent, gotEvent)
+			}
+		})
+	}
+}
+
+func TestReconcile_CacheInputsKey(t *testing.T) {
+	workflowRuns := `{"total_count": 2, "workflow_runs":[{"status":"queued"}, {"status":"queued"}]}"`
+	noWorkflowRuns := `{"total_count": 0, "workflow_runs":[]}"`
+
+	testcases := []struct {
+		inputsKey string
+
+		want          int
+		wantInputsKey string
+	}{
+		// Entries without the inputs key are honored until they expire
+		{
+			inputsKey:     "",
+			want:          5,
+			wantInputsKey: "",
+		},
+		// The entry served on scaling the runnerdeployment is rekeyed by the replicas it was scaled to
+		{
+			inputsKey:     "replicas=1,minReplicas=1,maxReplicas=10",
+			want:          5,
+			wantInputsKey: "replicas=5,minReplicas=1,maxReplicas=10",
+		},
+		// The runnerdeployment was scaled by someone else since the entry was cached
+		{
+			inputsKey:     "replicas=5,minReplicas=1,maxReplicas=10",
+			want:          2,
+			wantInputsKey: "replicas=2,minReplicas=1,maxReplicas=10",
+		},
+		// maxReplicas changed since the entry was cached
+		{
+			inputsKey:     "replicas=1,minReplicas=1,maxReplicas=20",
+			want:          2,
+			wantInputsKey: "replicas=2,minReplicas=1,maxReplicas=10",
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			rd := newTestRD(intPtr(1))
+
+			hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+				MinReplicas: intPtr(1),
+				MaxReplicas: intPtr(10),
+			})
+
+			hra.Status = v1alpha1.HorizontalRunnerAutoscalerStatus{
+				CacheEntries: []v1alpha1.CacheEntry{
+					{
+						Key:            v1alpha1.CacheEntryKeyDesiredReplicas,
+						Value:          5,
+						ExpirationTime: metav1.Time{Time: time.Now().Add(time.Hour)},
+						InputsKey:      tc.inputsKey,
+					},
+				},
+			}
+
+			h := newTestReconciler(t, rd, hra, withGitHubResponses(fake.WithListRepositoryWorkflowRunsResponse(200, workflowRuns, workflowRuns, noWorkflowRuns)))
+
+			reconcileTestHRA(t, h)
+
+			gotRD := getTestRD(t, h)
+
+			if gotRD.Spec.Replicas == nil || *gotRD.Spec.Replicas != tc.want {
+				t.Errorf("unexpected rd.Spec.Replicas: want %d, got %v", tc.want, gotRD.Spec.Replicas)
+			}
+
+			gotHRA := getTestHRA(t, h)
+
+			var gotInputsKey string
+			for _, ent := range gotHRA.Status.CacheEntries {
+				if ent.Key == v1alpha1.CacheEntryKeyDesiredReplicas {
+					gotInputsKey = ent.InputsKey
+				}
+			}
+
+			if gotInputsKey != tc.wantInputsKey {
+				t.Errorf("unexpected cache inputs key: want %q, got %q", tc.wantInputsKey, gotInputsKey)
+			}
+		})
+	}
+}
+
+func TestReconcile_CacheEntriesBounded(t *testing.T) {
+	workflowRuns := `{"total_count": 2, "workflow_runs":[{"status":"queued"}, {"status":"queued"}]}"`
+
+	rd := newTestRD(intPtr(1))
+
+	// The cache bust newer than any entry makes every reconciliation miss the cache
+	hra := &v1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "testhra",
+			Namespace:   "default",
+			Annotations: map[string]string{AnnotationKeyCacheBust: time.Now().Add(time.Hour).Format(time.RFC3339)},
+		},
+		Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+			ScaleTargetRef: v1alpha1.ScaleTargetRef{
+				Name: "testrd",
+			},
+			MinReplicas: intPtr(1),
+			MaxReplicas: intPtr(10),
+		},
+		Status: v1alpha1.HorizontalRunnerAutoscalerStatus{
+			CacheEntries: []v1alpha1.CacheEntry{
+				{
+					Key:            v1alpha1.CacheEntryKeyDesiredReplicas,
+					Value:          3,
+					ExpirationTime: metav1.Time{Time: time.Now().Add(-time.Minute)},
+					InputsKey:      "replicas=3,minReplicas=1,maxReplicas=10",
+				},
+			},
+		},
+	}
+
+	h := newTestReconciler(t, rd, hra, withGitHubResponses(fake.WithListRepositoryWorkflowRunsResponse(200, workflowRuns, workflowRuns, workflowRuns)))
+	h.Recorder = record.NewFakeRecorder(100)
+
+	for i := 0; i < 5; i++ {
+		if _, err := h.Reconcile(ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "testhra"}}); err != nil {
+			t.Fatalf("unexpected error on reconciliation %d: %v", i, err)
+		}
+	}
+
+	gotHRA := getTestHRA(t, h)
+
+	// The expired entry is dropped, and each miss replaces the entry of the same inputs
+	if n := len(gotHRA.Status.CacheEntries); n != 1 {
+		t.Fatalf("unexpected number of cache entries: want 1, got %d: %+v", n, gotHRA.Status.CacheEntries)
+	}
+
+	if got := gotHRA.Status.CacheEntries[0]; got.Value != 4 || got.InputsKey != "replicas=4,minReplicas=1,maxReplicas=10" {
+		t.Errorf("unexpected cache entry: %+v", got)
+	}
+}
+
+func TestReconcile_MinReplicasRamp(t *testing.T) {
+	workflowRuns := `{"total_count": 2, "workflow_runs":[{"status":"queued"}, {"status":"queued"}]}"`
+	noWorkflowRuns := `{"total_count": 0, "workflow_runs":[]}"`
+
+	testcases := []struct {
+		runs        string
+		demandSince time.Duration
+		noRamp      bool
+
+		want            int
+		wantDemandSince bool
+	}{
+		// The demand starts being tracked
+		{
+			runs:            workflowRuns,
+			want:            2,
+			wantDemandSince: true,
+		},
+		// One replica is added to the minimum replicas per minute of continuous demand
+		{
+			runs:            workflowRuns,
+			demandSince:     -(3*time.Minute + 30*time.Second),
+			want:            4,
+			wantDemandSince: true,
+		},
+		// Up to the max replicas of the ramp
+		{
+			runs:            workflowRuns,
+			demandSince:     -10 * time.Minute,
+			want:            5,
+			wantDemandSince: true,
+		},
+		// The ramp is reset on idle
+		{
+			runs:        noWorkflowRuns,
+			demandSince: -10 * time.Minute,
+			want:        1,
+		},
+		// Without the ramp, the demand isn't tracked
+		{
+			runs:        workflowRuns,
+			demandSince: -10 * time.Minute,
+			noRamp:      true,
+			want:        2,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			rd := newTestRD(intPtr(1))
+
+			hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+				MinReplicas: intPtr(1),
+				MaxReplicas: intPtr(10),
+			})
+
+			if !tc.noRamp {
+				hra.Spec.MinReplicasRamp = &v1alpha1.MinReplicasRamp{MaxReplicas: 5, StepSeconds: 60}
+			}
+
+			if tc.demandSince != 0 {
+				hra.Status.ContinuousDemandSince = &metav1.Time{Time: time.Now().Add(tc.demandSince)}
+			}
+
+			h := newTestReconciler(t, rd, hra, withGitHubResponses(fake.WithListRepositoryWorkflowRunsResponse(200, tc.runs, tc.runs, noWorkflowRuns)))
+
+			res := reconcileTestHRA(t, h)
+
+			gotRD := getTestRD(t, h)
+
+			if gotRD.Spec.Replicas == nil || *gotRD.Spec.Replicas != tc.want {
+				t.Errorf("unexpected rd.Spec.Replicas: want %d, got %v", tc.want, gotRD.Spec.Replicas)
+			}
+
+			gotHRA := getTestHRA(t, h)
+
+			if got := gotHRA.Status.ContinuousDemandSince != nil; got != tc.wantDemandSince {
+				t.Errorf("unexpected status.continuousDemandSince: want set=%v, got %v", tc.wantDemandSince, gotHRA.Status.ContinuousDemandSince)
+			}
+
+			// Requeued right at the next step until the ramp reaches its max replicas
+			if tc.wantDemandSince && tc.want < 5 && (res.RequeueAfter <= 0 || res.RequeueAfter > time.Minute) {
+				t.Errorf("unexpected requeueAfter: %v", res.RequeueAfter)
+			}
+		})
+	}
+}
+
+func TestReconcile_CacheBust(t *testing.T) {
+	workflowRuns := `{"total_count": 2, "workflow_runs":[{"status":"queued"}, {"status":"queued"}]}"`
+	noWorkflowRuns := `{"total_count": 0, "workflow_runs":[]}"`
+
+	now := time.Now()
+
+	testcases := []struct {
+		annotation string
+
+		want      int
+		wantEvent string
+	}{
+		{
+			want: 5,
+		},
+		// Newer than the cached desired replicas
+		{
+			annotation: now.Format(time.RFC3339),
+			want:       2,
+		},
+		// Older than the cached desired replicas
+		{
+			annotation: now.Add(-2 * time.Hour).Format(time.RFC3339),
+			want:       5,
+		},
+		{
+			annotation: "now",
+			want:       5,
+			wantEvent:  "InvalidCacheBust",
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			rd := newTestRD(intPtr(1))
+
+			hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+				MinReplicas: intPtr(1),
+				MaxReplicas: intPtr(10),
+			})
+
+			hra.Status = v1alpha1.HorizontalRunnerAutoscalerStatus{
+				CacheEntries: []v1alpha1.CacheEntry{
+					{
+						Key:            v1alpha1.CacheEntryKeyDesiredReplicas,
+						Value:          5,
+						ExpirationTime: metav1.Time{Time: now.Add(time.Hour)},
+						CreationTime:   metav1.Time{Time: now.Add(-time.Hour)},
+					},
+				},
+			}
+
+			if tc.annotation != "" {
+				hra.Annotations = map[string]string{AnnotationKeyCacheBust: tc.annotation}
+			}
+
+			recorder := record.NewFakeRecorder(10)
+
+			h := newTestReconciler(t, rd, hra, withGitHubResponses(fake.WithListRepositoryWorkflowRunsResponse(200, workflowRuns, workflowRuns, noWorkflowRuns)))
+			h.Recorder = recorder
+
+			reconcileTestHRA(t, h)
+
+			gotRD := getTestRD(t, h)
+
+			if gotRD.Spec.Replicas == nil || *gotRD.Spec.Replicas != tc.want {
+				t.Errorf("unexpected rd.Spec.Replicas: want %d, got %v", tc.want, gotRD.Spec.Replicas)
+			}
+
+			var gotEvent string
+
+			for len(recorder.Events) > 0 {
+				if e := <-recorder.Events; strings.Contains(e, "InvalidCacheBust") {
+					gotEvent = "InvalidCacheBust"
+				}
+			}
+
+			if gotEvent != tc.wantEvent {
+				t.Errorf("unexpected event: want %q, got %q", tc.wantEvent, gotEvent)
+			}
+
+			gotHRA := getTestHRA(t, h)
+
+			// The annotation left as is doesn't bust the desired replicas cached after it
+			bustTime, _ := getCacheBustTime(gotHRA)
+			if got := h.getDesiredReplicasFromCache(gotHRA, getCacheInputsKey(gotHRA, tc.want), bustTime); got == nil || *got != tc.want {
+				t.Errorf("unexpected cached desired replicas: want %d, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+// failingUpdateClient fails updating the object of the name, for testing partial failures.
+type failingUpdateClient struct {
+	client.Client
+
+	name string
+}
+
+func (c *failingUpdateClient) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	if rd, ok := obj.(*v1alpha1.RunnerDeployment); ok && rd.Name == c.name {
+		return fmt.Errorf("update of %s failed", c.name)
+	}
+
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func TestReconcile_AdditionalScaleTargets(t *testing.T) {
+	workflowRuns := `{"total_count": 2, "workflow_runs":[{"status":"queued"}, {"status":"queued"}]}"`
+	noWorkflowRuns := `{"total_count": 0, "workflow_runs":[]}"`
+
+	testcases := []struct {
+		missing    bool
+		failUpdate string
+
+		wantErr        bool
+		wantReplicas   int
+		wantArm        int
+		wantStatusNone bool
+		wantEvent      string
+	}{
+		{
+			wantReplicas: 2,
+			wantArm:      2,
+		},
+		// No scale target is scaled while any of them doesn't exist
+		{
+			missing:        true,
+			wantErr:        true,
+			wantReplicas:   1,
+			wantStatusNone: true,
+			wantEvent:      "ScaleTargetNotFound",
+		},
+		// The status isn't updated after the partial failure, so that it's retried
+		{
+			failUpdate:     "testrd-arm",
+			wantErr:        true,
+			wantReplicas:   2,
+			wantArm:        1,
+			wantStatusNone: true,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+				AdditionalScaleTargetRefs: []v1alpha1.ScaleTargetRef{
+					{Name: "testrd-arm"},
+				},
+				MinReplicas: intPtr(1),
+				MaxReplicas: intPtr(10),
+			})
+
+			var objs []runtime.Object
+			if !tc.missing {
+				arm := newTestRD(intPtr(1))
+				arm.Name = "testrd-arm"
+				objs = append(objs, arm)
+			}
+
+			recorder := record.NewFakeRecorder(10)
+
+			h := newTestReconciler(t, newTestRD(intPtr(1)), hra,
+				withGitHubResponses(fake.WithListRepositoryWorkflowRunsResponse(200, workflowRuns, workflowRuns, noWorkflowRuns)),
+				withObjects(objs...),
+			)
+			h.Client = &failingUpdateClient{Client: h.Client, name: tc.failUpdate}
+			h.Recorder = recorder
+
+			_, err := h.Reconcile(ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "testhra"}})
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("unexpected error: want error=%v, got %v", tc.wantErr, err)
+			}
+
+			gotRD := getTestRD(t, h)
+
+			if gotRD.Spec.Replicas == nil || *gotRD.Spec.Replicas != tc.wantReplicas {
+				t.Errorf("unexpected rd.Spec.Replicas: want %d, got %v", tc.wantReplicas, gotRD.Spec.Replicas)
+			}
+
+			if !tc.missing {
+				var gotArm v1alpha1.RunnerDeployment
+				if err := h.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "testrd-arm"}, &gotArm); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				if gotArm.Spec.Replicas == nil || *gotArm.Spec.Replicas != tc.wantArm {
+					t.Errorf("unexpected rd.Spec.Replicas of the additional scale target: want %d, got %v", tc.wantArm, gotArm.Spec.Replicas)
+				}
+			}
+
+			gotHRA := getTestHRA(t, h)
+
+			if got := gotHRA.Status.DesiredReplicas == nil && len(gotHRA.Status.CacheEntries) == 0; got != tc.wantStatusNone {
+				t.Errorf("unexpected status: desiredReplicas=%v cacheEntries=%+v", gotHRA.Status.DesiredReplicas, gotHRA.Status.CacheEntries)
+			}
+
+			var gotEvent string
+
+			for len(recorder.Events) > 0 {
+				if e := <-recorder.Events; strings.Contains(e, "ScaleTargetNotFound") {
+					gotEvent = "ScaleTargetNotFound"
+				}
+			}
+
+			if gotEvent != tc.wantEvent {
+				t.Errorf("unexpected event: want %q, got %q", tc.wantEvent, gotEvent)
+			}
+		})
+	}
+}
+
+func TestReconcile_ObservedGeneration(t *testing.T) {
+	workflowRuns := `{"total_count": 2, "workflow_runs":[{"status":"queued"}, {"status":"queued"}]}"`
+	noWorkflowRuns := `{"total_count": 0, "workflow_runs":[]}"`
+
+	testcases := []struct {
+		additionalScaleTargetRefs []v1alpha1.ScaleTargetRef
+
+		want int64
+	}{
+		{
+			want: 3,
+		},
+		// Not updated when the reconciliation fails
+		{
+			additionalScaleTargetRefs: []v1alpha1.ScaleTargetRef{{Name: "missing"}},
+			want:                      2,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			rd := newTestRD(intPtr(1))
+
+			hra := &v1alpha1.HorizontalRunnerAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "testhra",
+					Namespace:  "default",
+					Generation: 3,
+				},
+				Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+					ScaleTargetRef: v1alpha1.ScaleTargetRef{
+						Name: "testrd",
+					},
+					AdditionalScaleTargetRefs: tc.additionalScaleTargetRefs,
+					MinReplicas:               intPtr(1),
+					MaxReplicas:               intPtr(10),
+				},
+				Status: v1alpha1.HorizontalRunnerAutoscalerStatus{
+					ObservedGeneration: 2,
+				},
+			}
+
+			h := newTestReconciler(t, rd, hra, withGitHubResponses(fake.WithListRepositoryWorkflowRunsResponse(200, workflowRuns, workflowRuns, noWorkflowRuns)))
+
+			_, _ = h.Reconcile(ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "testhra"}})
+
+			gotHRA := getTestHRA(t, h)
+
+			if gotHRA.Status.ObservedGeneration != tc.want {
+				t.Errorf("unexpected status.observedGeneration: want %d, got %d", tc.want, gotHRA.Status.ObservedGeneration)
+			}
+		})
+	}
+}
+
+func TestReconcile_ScaleDownDelayAnchor(t *testing.T) {
+	testcases := []struct {
+		anchor       string
+		lastScaleOut time.Duration
+		lastBusyTime time.Duration
+		busyRunners  *int
+
+		want int
+	}{
+		{
+			lastScaleOut: -10 * time.Minute,
+			lastBusyTime: -time.Minute,
+			want:         1,
+		},
+		{
+			anchor:       v1alpha1.ScaleDownDelayAnchorLastScaleOut,
+			lastScaleOut: -time.Minute,
+			lastBusyTime: -10 * time.Minute,
+			want:         3,
+		},
+		{
+			anchor:       v1alpha1.ScaleDownDelayAnchorLastBusy,
+			lastScaleOut: -10 * time.Minute,
+			lastBusyTime: -time.Minute,
+			want:         3,
+		},
+		{
+			anchor:       v1alpha1.ScaleDownDelayAnchorLastBusy,
+			lastScaleOut: -time.Minute,
+			lastBusyTime: -10 * time.Minute,
+			want:         1,
+		},
+		// The busy runners dropped from 2 to 0 just now
+		{
+			anchor:       v1alpha1.ScaleDownDelayAnchorLastBusy,
+			lastScaleOut: -10 * time.Minute,
+			busyRunners:  intPtr(2),
+			want:         3,
+		},
+		// The busy runners have never been observed
+		{
+			anchor:       v1alpha1.ScaleDownDelayAnchorLastBusy,
+			lastScaleOut: -time.Minute,
+			want:         1,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			rd := newTestRD(intPtr(3))
+
+			hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+				MinReplicas:                       intPtr(1),
+				MaxReplicas:                       intPtr(5),
+				ScaleDownDelaySecondsAfterScaleUp: intPtr(300),
+				ScaleDownDelayAnchor:              tc.anchor,
+			})
+
+			hra.Status = v1alpha1.HorizontalRunnerAutoscalerStatus{
+				DesiredReplicas:            intPtr(3),
+				LastSuccessfulScaleOutTime: &metav1.Time{Time: time.Now().Add(tc.lastScaleOut)},
+				BusyRunners:                tc.busyRunners,
+			}
+
+			if tc.lastBusyTime != 0 {
+				hra.Status.LastBusyTime = &metav1.Time{Time: time.Now().Add(tc.lastBusyTime)}
+			}
+
+			h := newTestReconciler(t, rd, hra)
+
+			reconcileTestHRA(t, h)
+
+			gotRD := getTestRD(t, h)
+
+			if *gotRD.Spec.Replicas != tc.want {
+				t.Errorf("unexpected rd.Spec.Replicas: want %d, got %d", tc.want, *gotRD.Spec.Replicas)
+			}
+		})
+	}
+}
+
+func TestSimulateScaling(t *testing.T) {
+	testcases := []struct {
+		name           string
+		reservations   []v1alpha1.CapacityReservation
+		annotations    map[string]string
+		softMax        *int
+		scaleMode      string
+		metricReplicas int
+
+		want        int
+		wantMaxCap  bool
+		wantSoftCap bool
+		wantReserve int
+	}{
+		{
+			name:           "metric",
+			metricReplicas: 3,
+			want:           3,
+		},
+		{
+			name:           "min floor",
+			metricReplicas: 0,
+			want:           1,
+		},
+		{
+			name: "reservations capped by max",
+			reservations: []v1alpha1.CapacityReservation{
+				{ExpirationTime: metav1.Time{Time: time.Now().Add(time.Hour)}, Replicas: 4},
+			},
+			metricReplicas: 3,
+			want:           5,
+			wantMaxCap:     true,
+			wantReserve:    4,
+		},
+		{
+			name:           "override",
+			annotations:    map[string]string{AnnotationKeyDesiredReplicasOverride: "4"},
+			metricReplicas: 1,
+			want:           4,
+		},
+		{
+			name:           "soft max",
+			softMax:        intPtr(2),
+			metricReplicas: 4,
+			want:           2,
+			wantSoftCap:    true,
+		},
+		{
+			name:           "soft max below the metric",
+			softMax:        intPtr(2),
+			metricReplicas: 1,
+			want:           1,
+		},
+		{
+			name:    "reservations beyond soft max",
+			softMax: intPtr(2),
+			reservations: []v1alpha1.CapacityReservation{
+				{ExpirationTime: metav1.Time{Time: time.Now().Add(time.Hour)}, Replicas: 2},
+			},
+			metricReplicas: 4,
+			want:           4,
+			wantSoftCap:    true,
+			wantReserve:    2,
+		},
+		{
+			name:    "reservations beyond soft max capped by max",
+			softMax: intPtr(2),
+			reservations: []v1alpha1.CapacityReservation{
+				{ExpirationTime: metav1.Time{Time: time.Now().Add(time.Hour)}, Replicas: 4},
+			},
+			metricReplicas: 4,
+			want:           5,
+			wantMaxCap:     true,
+			wantSoftCap:    true,
+			wantReserve:    4,
+		},
+		{
+			name:           "override beyond soft max",
+			softMax:        intPtr(2),
+			annotations:    map[string]string{AnnotationKeyDesiredReplicasOverride: "4"},
+			metricReplicas: 1,
+			want:           4,
+		},
+		{
+			name:           "events only",
+			scaleMode:      v1alpha1.ScaleModeEventsOnly,
+			metricReplicas: 4,
+			want:           1,
+		},
+		{
+			name:      "events only with reservations",
+			scaleMode: v1alpha1.ScaleModeEventsOnly,
+			reservations: []v1alpha1.CapacityReservation{
+				{ExpirationTime: metav1.Time{Time: time.Now().Add(time.Hour)}, Replicas: 2},
+			},
+			metricReplicas: 4,
+			want:           3,
+			wantReserve:    2,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			rd := v1alpha1.RunnerDeployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "testrd", Namespace: "default"},
+				Spec:       v1alpha1.RunnerDeploymentSpec{Replicas: intPtr(2)},
+			}
+
+			hra := v1alpha1.HorizontalRunnerAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "testhra", Namespace: "default", Annotations: tc.annotations},
+				Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+					ScaleTargetRef:       v1alpha1.ScaleTargetRef{Name: "testrd"},
+					MinReplicas:          intPtr(1),
+					MaxReplicas:          intPtr(5),
+					CapacityReservations: tc.reservations,
+					ScaleMode:            tc.scaleMode,
+				},
+			}
+
+			if tc.softMax != nil {
+				hra.Spec.ScheduledOverrides = []v1alpha1.ScheduledOverride{
+					{
+						StartTime:       metav1.Time{Time: time.Now().Add(-time.Hour)},
+						EndTime:         metav1.Time{Time: time.Now().Add(time.Hour)},
+						SoftMaxReplicas: tc.softMax,
+					},
+				}
+			}
+
+			got, err := SimulateScaling(zap.New(), hra, rd, "", tc.metricReplicas, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got.DesiredReplicas != tc.want {
+				t.Errorf("unexpected desired replicas: want %d, got %d", tc.want, got.DesiredReplicas)
+			}
+
+			if got.MaxReplicasApplied != tc.wantMaxCap {
+				t.Errorf("unexpected max replicas applied: want %v, got %v", tc.wantMaxCap, got.MaxReplicasApplied)
+			}
+
+			if got.SoftMaxReplicasApplied != tc.wantSoftCap {
+				t.Errorf("unexpected soft max replicas applied: want %v, got %v", tc.wantSoftCap, got.SoftMaxReplicasApplied)
+			}
+
+			if got.ReservedReplicas != tc.wantReserve {
+				t.Errorf("unexpected reserved replicas: want %d, got %d", tc.wantReserve, got.ReservedReplicas)
+			}
+		})
+	}
+}
+
+func TestSimulateScaling_NilReplicasPolicy(t *testing.T) {
+	// The scale up is limited to 1 replica above the current replicas in all the cases
+	testcases := []struct {
+		policy   NilReplicasPolicy
+		observed *int
+
+		want int
+	}{
+		{
+			observed: intPtr(4),
+			want:     2,
+		},
+		{
+			policy:   NilReplicasPolicyObserved,
+			observed: intPtr(4),
+			want:     5,
+		},
+		{
+			policy: NilReplicasPolicyObserved,
+			want:   2,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			rd := v1alpha1.RunnerDeployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "testrd", Namespace: "default"},
+				Status:     v1alpha1.RunnerDeploymentStatus{Replicas: tc.observed},
+			}
+
+			hra := v1alpha1.HorizontalRunnerAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "testhra", Namespace: "default"},
+				Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+					ScaleTargetRef:  v1alpha1.ScaleTargetRef{Name: "testrd"},
+					MinReplicas:     intPtr(1),
+					MaxReplicas:     intPtr(10),
+					MaxScaleUpCount: intPtr(1),
+				},
+			}
+
+			got, err := SimulateScaling(zap.New(), hra, rd, tc.policy, 8, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got.DesiredReplicas != tc.want {
+				t.Errorf("unexpected desired replicas: want %d, got %d", tc.want, got.DesiredReplicas)
+			}
+		})
+	}
+}
+
+func TestReconcile_MetricTimeout(t *testing.T) {
+	const hungMetricType = "HungMetric"
+
+	rd := newTestRD(intPtr(3))
+
+	hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+		MinReplicas: intPtr(1),
+		MaxReplicas: intPtr(10),
+		Metrics:     []v1alpha1.MetricSpec{{Type: hungMetricType}},
+	})
+
+	hra.Status = v1alpha1.HorizontalRunnerAutoscalerStatus{
+		DesiredReplicas: intPtr(3),
+	}
+
+	h := newTestReconciler(t, rd, hra)
+	h.MetricProviders = map[string]MetricProviderFactory{
+		hungMetricType: func(_ *github.Client, _ v1alpha1.MetricSpec) MetricProvider {
+			return &fakeMetricProvider{hang: true}
+		},
+	}
+	h.MetricTimeout = 10 * time.Millisecond
+
+	res := reconcileTestHRA(t, h)
+
+	if res.RequeueAfter != DefaultFailureBackoff {
+		t.Errorf("unexpected requeueAfter: want %s, got %s", DefaultFailureBackoff, res.RequeueAfter)
+	}
+
+	gotRD := getTestRD(t, h)
+
+	// The timed out metric must not scale the runner deployment down to minReplicas
+	if *gotRD.Spec.Replicas != 3 {
+		t.Errorf("unexpected rd.Spec.Replicas: want 3, got %d", *gotRD.Spec.Replicas)
+	}
+
+	gotHRA := getTestHRA(t, h)
+
+	if gotHRA.Status.DesiredReplicas == nil || *gotHRA.Status.DesiredReplicas != 3 {
+		t.Errorf("unexpected status.desiredReplicas: want 3, got %v", gotHRA.Status.DesiredReplicas)
+	}
+
+	if gotHRA.Status.ConsecutiveFailures != 1 {
+		t.Errorf("unexpected status.consecutiveFailures: want 1, got %d", gotHRA.Status.ConsecutiveFailures)
+	}
+}
+
+func TestReconcile_MaxReplicasReached(t *testing.T) {
+	const fakeMetricType = "FakeMetric"
+
+	testcases := []struct {
+		demand         int
+		lastReached    time.Duration
+		statusUncapped *int
+
+		wantReplicas int
+		wantUncapped *int
+		wantEvent    bool
+	}{
+		{
+			demand:       12,
+			wantReplicas: 10,
+			wantUncapped: intPtr(12),
+			wantEvent:    true,
+		},
+		// Rate-limited
+		{
+			demand:       12,
+			lastReached:  -time.Minute,
+			wantReplicas: 10,
+			wantUncapped: intPtr(12),
+		},
+		{
+			demand:       12,
+			lastReached:  -MaxReplicasReachedEventInterval,
+			wantReplicas: 10,
+			wantUncapped: intPtr(12),
+			wantEvent:    true,
+		},
+		// Cleared once the demand fits in maxReplicas
+		{
+			demand:         5,
+			statusUncapped: intPtr(12),
+			wantReplicas:   5,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			rd := newTestRD(intPtr(1))
+
+			hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+				MinReplicas: intPtr(1),
+				MaxReplicas: intPtr(10),
+				Metrics:     []v1alpha1.MetricSpec{{Type: fakeMetricType}},
+			})
+
+			hra.Status = v1alpha1.HorizontalRunnerAutoscalerStatus{
+				UncappedDesiredReplicas: tc.statusUncapped,
+			}
+
+			var lastReached *metav1.Time
+			if tc.lastReached != 0 {
+				lastReached = &metav1.Time{Time: time.Now().Add(tc.lastReached).Truncate(time.Second)}
+				hra.Status.LastMaxReplicasReachedTime = lastReached
+			}
+
+			recorder := record.NewFakeRecorder(10)
+
+			h := newTestReconciler(t, rd, hra)
+			h.Recorder = recorder
+			h.MetricProviders = map[string]MetricProviderFactory{
+				fakeMetricType: func(_ *github.Client, _ v1alpha1.MetricSpec) MetricProvider {
+					return &fakeMetricProvider{replicas: tc.demand}
+				},
+			}
+
+			reconcileTestHRA(t, h)
+
+			gotRD := getTestRD(t, h)
+
+			if *gotRD.Spec.Replicas != tc.wantReplicas {
+				t.Errorf("unexpected rd.Spec.Replicas: want %d, got %d", tc.wantReplicas, *gotRD.Spec.Replicas)
+			}
+
+			gotHRA := getTestHRA(t, h)
+
+			if !intPtrEqual(gotHRA.Status.UncappedDesiredReplicas, tc.wantUncapped) {
+				t.Errorf("unexpected status.uncappedDesiredReplicas: want %v, got %v", tc.wantUncapped, gotHRA.Status.UncappedDesiredReplicas)
+			}
+
+			var gotEvent bool
+			for len(recorder.Events) > 0 {
+				if e := <-recorder.Events; strings.Contains(e, "MaxReplicasReached") {
+					gotEvent = true
+				}
+			}
+
+			if gotEvent != tc.wantEvent {
+				t.Errorf("unexpected MaxReplicasReached event: want %v, got %v", tc.wantEvent, gotEvent)
+			}
+
+			if !tc.wantEvent && !gotHRA.Status.LastMaxReplicasReachedTime.Equal(lastReached) {
+				t.Errorf("status.lastMaxReplicasReachedTime should not change without an event: want %v, got %v", lastReached, gotHRA.Status.LastMaxReplicasReachedTime)
+			}
+		})
+	}
+}
+
+func TestReconcile_ScaleDownDecay(t *testing.T) {
+	const fakeMetricType = "FakeMetric"
+
+	halfLife := 10 * time.Minute
+
+	testcases := []struct {
+		min       int
+		demand    int
+		halfLives float64
+
+		want        int
+		wantRequeue bool
+	}{
+		// Decays from 9 toward 1 by halving the difference of 8 per half-life
+		{min: 1, demand: 1, halfLives: 0, want: 9, wantRequeue: true},
+		{min: 1, demand: 1, halfLives: 1, want: 5, wantRequeue: true},
+		{min: 1, demand: 1, halfLives: 2, want: 3, wantRequeue: true},
+		{min: 1, demand: 1, halfLives: 10, want: 1},
+		// The decay is toward minReplicas when the demand is lower
+		{min: 4, demand: 1, halfLives: 2, want: 5, wantRequeue: true},
+		{min: 4, demand: 1, halfLives: 10, want: 4},
+		// Scaling up isn't affected
+		{min: 1, demand: 10, halfLives: 1, want: 10},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			rd := newTestRD(intPtr(9))
+
+			hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+				MinReplicas:                   intPtr(tc.min),
+				MaxReplicas:                   intPtr(10),
+				Metrics:                       []v1alpha1.MetricSpec{{Type: fakeMetricType}},
+				ScaleDownDecayHalfLifeSeconds: intPtr(int(halfLife / time.Second)),
+				// Disabled so that the requeue at the cache expiry isn't mistaken for the one at the next decay step
+				CacheDurationSeconds: intPtr(0),
+			})
+
+			hra.Status = v1alpha1.HorizontalRunnerAutoscalerStatus{
+				DesiredReplicas:             intPtr(9),
+				LastScaleOutDesiredReplicas: intPtr(9),
+				LastSuccessfulScaleOutTime:  &metav1.Time{Time: time.Now().Add(-time.Duration(tc.halfLives * float64(halfLife)))},
+			}
+
+			h := newTestReconciler(t, rd, hra)
+			h.MetricProviders = map[string]MetricProviderFactory{
+				fakeMetricType: func(_ *github.Client, _ v1alpha1.MetricSpec) MetricProvider {
+					return &fakeMetricProvider{replicas: tc.demand}
+				},
+			}
+
+			res := reconcileTestHRA(t, h)
+
+			gotRD := getTestRD(t, h)
+
+			if *gotRD.Spec.Replicas != tc.want {
+				t.Errorf("unexpected rd.Spec.Replicas: want %d, got %d", tc.want, *gotRD.Spec.Replicas)
+			}
+
+			if gotRequeue := res.RequeueAfter > 0; gotRequeue != tc.wantRequeue {
+				t.Errorf("unexpected requeue for the next decay step: want %v, got %s", tc.wantRequeue, res.RequeueAfter)
+			}
+		})
+	}
+}
+
+func TestReconcile_ScaleTargetSelector(t *testing.T) {
+	workflowRuns := `{"total_count": 2, "workflow_runs":[{"status":"queued"}, {"status":"queued"}]}"`
+	noWorkflowRuns := `{"total_count": 0, "workflow_runs":[]}"`
+
+	testcases := []struct {
+		matchLabels map[string]string
+
+		wantReplicas map[string]int
+		wantEvent    string
+	}{
+		{
+			matchLabels:  map[string]string{"team": "example"},
+			wantReplicas: map[string]int{"testrd-a": 2, "testrd-b": 2, "testrd-other": 1},
+		},
+		// Nothing is scaled when no runnerdeployment matches
+		{
+			matchLabels:  map[string]string{"team": "missing"},
+			wantReplicas: map[string]int{"testrd-a": 1, "testrd-b": 1, "testrd-other": 1},
+			wantEvent:    "NoScaleTargetMatched",
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			newRD := func(name, team string) *v1alpha1.RunnerDeployment {
+				return &v1alpha1.RunnerDeployment{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      name,
+						Namespace: "default",
+						Labels:    map[string]string{"team": team},
+					},
+					Spec: v1alpha1.RunnerDeploymentSpec{
+						Template: v1alpha1.RunnerTemplate{
+							Spec: v1alpha1.RunnerSpec{
+								Repository: "test/valid",
+							},
+						},
+						Replicas: intPtr(1),
+					},
+				}
+			}
+
+			hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+				ScaleTargetRef: v1alpha1.ScaleTargetRef{
+					MatchLabels: tc.matchLabels,
+				},
+				MinReplicas: intPtr(1),
+				MaxReplicas: intPtr(10),
+			})
+
+			recorder := record.NewFakeRecorder(10)
+
+			h := newTestReconciler(t, nil, nil,
+				withGitHubResponses(fake.WithListRepositoryWorkflowRunsResponse(200, workflowRuns, workflowRuns, noWorkflowRuns)),
+				withObjects(newRD("testrd-a", "example"), newRD("testrd-b", "example"), newRD("testrd-other", "other"), hra),
+			)
+			h.Recorder = recorder
+
+			reconcileTestHRA(t, h)
+
+			for name, want := range tc.wantReplicas {
+				var got v1alpha1.RunnerDeployment
+				if err := h.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: name}, &got); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				if got.Spec.Replicas == nil || *got.Spec.Replicas != want {
+					t.Errorf("unexpected rd.Spec.Replicas of %s: want %d, got %v", name, want, got.Spec.Replicas)
+				}
+			}
+
+			var gotEvent string
+
+			for len(recorder.Events) > 0 {
+				if e := <-recorder.Events; strings.Contains(e, "NoScaleTargetMatched") {
+					gotEvent = "NoScaleTargetMatched"
+				}
+			}
+
+			if gotEvent != tc.wantEvent {
+				t.Errorf("unexpected event: want %q, got %q", tc.wantEvent, gotEvent)
+			}
+		})
+	}
+}
+
+func TestReconcile_ScaleDownStabilizationWindow(t *testing.T) {
+	const fakeMetricType = "FakeMetric"
+
+	window := 5 * time.Minute
+
+	rec := func(ago time.Duration, replicas int) v1alpha1.ReplicasRecommendation {
+		return v1alpha1.ReplicasRecommendation{Time: metav1.Time{Time: time.Now().Add(-ago)}, Replicas: replicas}
+	}
+
+	testcases := []struct {
+		recommendations []v1alpha1.ReplicasRecommendation
+		demand          int
+
+		want                int
+		wantRecommendations []int
+		wantRequeueWithin   time.Duration
+	}{
+		// The scale down is held at the largest recommendation within the window
+		{
+			recommendations:     []v1alpha1.ReplicasRecommendation{rec(4*time.Minute, 8), rec(2*time.Minute, 5)},
+			demand:              2,
+			want:                8,
+			wantRecommendations: []int{8, 5, 2},
+			wantRequeueWithin:   time.Minute,
+		},
+		// The recommendations out of the window are pruned
+		{
+			recommendations:     []v1alpha1.ReplicasRecommendation{rec(6*time.Minute, 8), rec(2*time.Minute, 5)},
+			demand:              2,
+			want:                5,
+			wantRecommendations: []int{5, 2},
+			wantRequeueWithin:   3 * time.Minute,
+		},
+		// Nothing is held without recommendations larger than the demand
+		{
+			recommendations:     []v1alpha1.ReplicasRecommendation{rec(2*time.Minute, 2)},
+			demand:              3,
+			want:                3,
+			wantRecommendations: []int{3},
+		},
+		// Scaling up isn't affected
+		{
+			recommendations:     []v1alpha1.ReplicasRecommendation{rec(2*time.Minute, 8)},
+			demand:              9,
+			want:                9,
+			wantRecommendations: []int{9},
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			rd := newTestRD(intPtr(8))
+
+			hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+				MinReplicas:                         intPtr(1),
+				MaxReplicas:                         intPtr(10),
+				Metrics:                             []v1alpha1.MetricSpec{{Type: fakeMetricType}},
+				ScaleDownStabilizationWindowSeconds: intPtr(int(window / time.Second)),
+			})
+
+			hra.Status = v1alpha1.HorizontalRunnerAutoscalerStatus{
+				DesiredReplicas: intPtr(8),
+				Recommendations: tc.recommendations,
+			}
+
+			h := newTestReconciler(t, rd, hra)
+			h.MetricProviders = map[string]MetricProviderFactory{
+				fakeMetricType: func(_ *github.Client, _ v1alpha1.MetricSpec) MetricProvider {
+					return &fakeMetricProvider{replicas: tc.demand}
+				},
+			}
+
+			res := reconcileTestHRA(t, h)
+
+			gotRD := getTestRD(t, h)
+
+			if *gotRD.Spec.Replicas != tc.want {
+				t.Errorf("unexpected rd.Spec.Replicas: want %d, got %d", tc.want, *gotRD.Spec.Replicas)
+			}
+
+			gotHRA := getTestHRA(t, h)
+
+			var gotRecommendations []int
+			for _, r := range gotHRA.Status.Recommendations {
+				gotRecommendations = append(gotRecommendations, r.Replicas)
+			}
+
+			if fmt.Sprint(gotRecommendations) != fmt.Sprint(tc.wantRecommendations) {
+				t.Errorf("unexpected status.recommendations: want %v, got %v", tc.wantRecommendations, gotRecommendations)
+			}
+
+			if tc.wantRequeueWithin > 0 && (res.RequeueAfter <= 0 || res.RequeueAfter > tc.wantRequeueWithin) {
+				t.Errorf("unexpected requeue for the recommendation leaving the window: want within %s, got %s", tc.wantRequeueWithin, res.RequeueAfter)
+			}
+		})
+	}
+}
+
+func TestReconcile_ScaleTargetMissing(t *testing.T) {
+	hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+		MinReplicas: intPtr(1),
+		MaxReplicas: intPtr(3),
+		Metrics:     []v1alpha1.MetricSpec{{Type: "FakeMetric"}},
+	})
+
+	recorder := record.NewFakeRecorder(10)
+
+	h := newTestReconciler(t, nil, hra)
+	h.Recorder = recorder
+	h.MetricProviders = map[string]MetricProviderFactory{
+		"FakeMetric": func(_ *github.Client, _ v1alpha1.MetricSpec) MetricProvider {
+			return &fakeMetricProvider{replicas: 2}
+		},
+	}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "testhra"}}
+
+	reconcile := func(wantRequeue time.Duration, wantStatus corev1.ConditionStatus, wantReason string, wantEvents int) {
+		t.Helper()
+
+		res, err := h.Reconcile(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if res.RequeueAfter != wantRequeue {
+			t.Errorf("unexpected requeue: want %s, got %s", wantRequeue, res.RequeueAfter)
+		}
+
+		var gotHRA v1alpha1.HorizontalRunnerAutoscaler
+		if err := h.Get(context.Background(), req.NamespacedName, &gotHRA); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(gotHRA.Status.Conditions) != 1 || gotHRA.Status.Conditions[0].Status != wantStatus || gotHRA.Status.Conditions[0].Reason != wantReason {
+			t.Errorf("unexpected conditions: want %s %s, got %+v", wantStatus, wantReason, gotHRA.Status.Conditions)
+		}
+
+		var gotEvents int
+
+		for len(recorder.Events) > 0 {
+			if e := <-recorder.Events; strings.Contains(e, "TargetMissing") {
+				gotEvents++
+			}
+		}
+
+		if gotEvents != wantEvents {
+			t.Errorf("unexpected number of TargetMissing events: want %d, got %d", wantEvents, gotEvents)
+		}
+	}
+
+	reconcile(ScaleTargetNotFoundRequeueDelay, corev1.ConditionFalse, "ScaleTargetNotFound", 1)
+
+	// The event isn't repeated while the scale target remains missing
+	reconcile(ScaleTargetNotFoundRequeueDelay, corev1.ConditionFalse, "ScaleTargetNotFound", 0)
+
+	rd := newTestRD(intPtr(1))
+
+	if err := h.Create(context.Background(), rd); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// It recovers once the scale target is created
+	if _, err := h.Reconcile(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var gotHRA v1alpha1.HorizontalRunnerAutoscaler
+	if err := h.Get(context.Background(), req.NamespacedName, &gotHRA); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if gotHRA.Status.DesiredReplicas == nil || *gotHRA.Status.DesiredReplicas != 2 || len(gotHRA.Status.Conditions) != 1 || gotHRA.Status.Conditions[0].Status != corev1.ConditionTrue {
+		t.Errorf("unexpected status after the scale target is created: desiredReplicas=%v conditions=%+v", gotHRA.Status.DesiredReplicas, gotHRA.Status.Conditions)
+	}
+}
+
+func TestReconcile_QueueGrowthPanic(t *testing.T) {
+	testcases := []struct {
+		panic   *v1alpha1.QueueGrowthPanic
+		history []int
+		current int
+		queued  int
+
+		wantReplicas int
+		wantHistory  []int
+		wantEvent    bool
+	}{
+		// Increased on 2 consecutive reconciliations
+		{
+			panic:        &v1alpha1.QueueGrowthPanic{ConsecutiveIncreases: 2},
+			history:      []int{1, 2},
+			current:      1,
+			queued:       3,
+			wantReplicas: 10,
+			wantHistory:  []int{1, 2, 3},
+			wantEvent:    true,
+		},
+		// Held at maxReplicas while the queue keeps growing, without emitting the event again
+		{
+			panic:        &v1alpha1.QueueGrowthPanic{ConsecutiveIncreases: 2},
+			history:      []int{1, 2, 3},
+			current:      10,
+			queued:       4,
+			wantReplicas: 10,
+			wantHistory:  []int{2, 3, 4},
+		},
+		// Not yet sustained
+		{
+			panic:        &v1alpha1.QueueGrowthPanic{ConsecutiveIncreases: 2},
+			history:      []int{1},
+			current:      1,
+			queued:       3,
+			wantReplicas: 3,
+			wantHistory:  []int{1, 3},
+		},
+		// Restarted as the queue stopped growing
+		{
+			panic:        &v1alpha1.QueueGrowthPanic{ConsecutiveIncreases: 2},
+			history:      []int{1, 2},
+			current:      1,
+			queued:       2,
+			wantReplicas: 2,
+			wantHistory:  []int{2},
+		},
+		// Cleared once disabled
+		{
+			history:      []int{1, 2},
+			current:      1,
+			queued:       3,
+			wantReplicas: 3,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			var runs []string
+			for j := 0; j < tc.queued; j++ {
+				runs = append(runs, `{"status":"queued"}`)
+			}
+
+			noWorkflowRuns := `{"total_count": 0, "workflow_runs":[]}"`
+			queuedWorkflowRuns := fmt.Sprintf(`{"total_count": %d, "workflow_runs":[%s]}"`, tc.queued, strings.Join(runs, ", "))
+
+			rd := newTestRD(intPtr(tc.current))
+
+			hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+				MinReplicas:      intPtr(1),
+				MaxReplicas:      intPtr(10),
+				QueueGrowthPanic: tc.panic,
+			})
+
+			hra.Status = v1alpha1.HorizontalRunnerAutoscalerStatus{
+				DesiredReplicas:   intPtr(tc.current),
+				QueueDepthHistory: tc.history,
+			}
+
+			recorder := record.NewFakeRecorder(10)
+
+			h := newTestReconciler(t, rd, hra, withGitHubResponses(fake.WithListRepositoryWorkflowRunsResponse(200, queuedWorkflowRuns, queuedWorkflowRuns, noWorkflowRuns)))
+			h.Recorder = recorder
+
+			reconcileTestHRA(t, h)
+
+			gotRD := getTestRD(t, h)
+
+			if *gotRD.Spec.Replicas != tc.wantReplicas {
+				t.Errorf("unexpected rd.Spec.Replicas: want %d, got %d", tc.wantReplicas, *gotRD.Spec.Replicas)
+			}
+
+			gotHRA := getTestHRA(t, h)
+
+			if !intSliceEqual(gotHRA.Status.QueueDepthHistory, tc.wantHistory) {
+				t.Errorf("unexpected status.queueDepthHistory: want %v, got %v", tc.wantHistory, gotHRA.Status.QueueDepthHistory)
+			}
+
+			var gotEvent bool
+			for len(recorder.Events) > 0 {
+				if e := <-recorder.Events; strings.Contains(e, "QueueGrowthDetected") {
+					gotEvent = true
+				}
+			}
+
+			if gotEvent != tc.wantEvent {
+				t.Errorf("unexpected QueueGrowthDetected event: want %v, got %v", tc.wantEvent, gotEvent)
+			}
+		})
+	}
+}
+
+func TestReconcile_Paused(t *testing.T) {
+	const fakeMetricType = "FakeMetric"
+
+	paused := v1alpha1.HorizontalRunnerAutoscalerCondition{
+		Type:    v1alpha1.HorizontalRunnerAutoscalerConditionPaused,
+		Status:  corev1.ConditionTrue,
+		Reason:  "PausedByAnnotation",
+		Message: fmt.Sprintf("Autoscaling is paused by the %s annotation", AnnotationKeyPaused),
+	}
+
+	testcases := []struct {
+		annotations map[string]string
+		conditions  []v1alpha1.HorizontalRunnerAutoscalerCondition
+
+		wantReplicas     int
+		wantComputed     bool
+		wantPaused       corev1.ConditionStatus
+		wantPausedReason string
+		wantEvent        bool
+	}{
+		{
+			annotations:      map[string]string{AnnotationKeyPaused: "true"},
+			wantReplicas:     1,
+			wantPaused:       corev1.ConditionTrue,
+			wantPausedReason: "PausedByAnnotation",
+			wantEvent:        true,
+		},
+		// Already paused
+		{
+			annotations:      map[string]string{AnnotationKeyPaused: "true"},
+			conditions:       []v1alpha1.HorizontalRunnerAutoscalerCondition{paused},
+			wantReplicas:     1,
+			wantPaused:       corev1.ConditionTrue,
+			wantPausedReason: "PausedByAnnotation",
+		},
+		// Resumed by removing the annotation
+		{
+			conditions:       []v1alpha1.HorizontalRunnerAutoscalerCondition{paused},
+			wantReplicas:     5,
+			wantComputed:     true,
+			wantPaused:       corev1.ConditionFalse,
+			wantPausedReason: "Resumed",
+		},
+		// Only "true" pauses it
+		{
+			annotations:  map[string]string{AnnotationKeyPaused: "false"},
+			wantReplicas: 5,
+			wantComputed: true,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			rd := newTestRD(intPtr(1))
+
+			hra := &v1alpha1.HorizontalRunnerAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "testhra",
+					Namespace:   "default",
+					Annotations: tc.annotations,
+				},
+				Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+					ScaleTargetRef: v1alpha1.ScaleTargetRef{
+						Name: "testrd",
+					},
+					MinReplicas: intPtr(1),
+					MaxReplicas: intPtr(10),
+					Metrics:     []v1alpha1.MetricSpec{{Type: fakeMetricType}},
+				},
+				Status: v1alpha1.HorizontalRunnerAutoscalerStatus{
+					Conditions: tc.conditions,
+				},
+			}
+
+			recorder := record.NewFakeRecorder(10)
+
+			var computed bool
+
+			h := newTestReconciler(t, rd, hra)
+			h.Recorder = recorder
+			h.MetricProviders = map[string]MetricProviderFactory{
+				fakeMetricType: func(_ *github.Client, _ v1alpha1.MetricSpec) MetricProvider {
+					computed = true
+
+					return &fakeMetricProvider{replicas: 5}
+				},
+			}
+
+			reconcileTestHRA(t, h)
+
+			if computed != tc.wantComputed {
+				t.Errorf("unexpected metric computation: want %v, got %v", tc.wantComputed, computed)
+			}
+
+			gotRD := getTestRD(t, h)
+
+			if *gotRD.Spec.Replicas != tc.wantReplicas {
+				t.Errorf("unexpected rd.Spec.Replicas: want %d, got %d", tc.wantReplicas, *gotRD.Spec.Replicas)
+			}
+
+			gotHRA := getTestHRA(t, h)
+
+			var gotPaused *v1alpha1.HorizontalRunnerAutoscalerCondition
+			for i := range gotHRA.Status.Conditions {
+				if c := &gotHRA.Status.Conditions[i]; c.Type == v1alpha1.HorizontalRunnerAutoscalerConditionPaused {
+					gotPaused = c
+				}
+			}
+
+			if tc.wantPaused == "" {
+				if gotPaused != nil {
+					t.Errorf("unexpected Paused condition: %v", *gotPaused)
+				}
+			} else if gotPaused == nil {
+				t.Errorf("missing Paused condition")
+			} else if gotPaused.Status != tc.wantPaused || gotPaused.Reason != tc.wantPausedReason {
+				t.Errorf("unexpected Paused condition: want %s/%s, got %s/%s", tc.wantPaused, tc.wantPausedReason, gotPaused.Status, gotPaused.Reason)
+			}
+
+			var gotEvent bool
+			for len(recorder.Events) > 0 {
+				if e := <-recorder.Events; strings.Contains(e, "Paused") {
+					gotEvent = true
+				}
+			}
+
+			if gotEvent != tc.wantEvent {
+				t.Errorf("unexpected Paused event: want %v, got %v", tc.wantEvent, gotEvent)
+			}
+		})
+	}
+}
+
+func TestReconcile_RunnerIdleTimeout(t *testing.T) {
+	testcases := []struct {
+		timeout     *int
+		dryRun      bool
+		annotations map[string]string
+		env         []corev1.EnvVar
+
+		wantAnnotation string
+		wantEnv        []corev1.EnvVar
+	}{
+		{
+			timeout:        intPtr(300),
+			wantAnnotation: "300",
+			wantEnv:        []corev1.EnvVar{{Name: EnvVarRunnerIdleTimeout, Value: "300"}},
+		},
+		// The existing env var is updated in place
+		{
+			timeout:        intPtr(60),
+			annotations:    map[string]string{AnnotationKeyRunnerIdleTimeout: "300"},
+			env:            []corev1.EnvVar{{Name: "FOO", Value: "foo"}, {Name: EnvVarRunnerIdleTimeout, Value: "300"}},
+			wantAnnotation: "60",
+			wantEnv:        []corev1.EnvVar{{Name: "FOO", Value: "foo"}, {Name: EnvVarRunnerIdleTimeout, Value: "60"}},
+		},
+		// The env var is removed once unset
+		{
+			annotations: map[string]string{AnnotationKeyRunnerIdleTimeout: "300"},
+			env:         []corev1.EnvVar{{Name: EnvVarRunnerIdleTimeout, Value: "300"}, {Name: "FOO", Value: "foo"}},
+			wantEnv:     []corev1.EnvVar{{Name: "FOO", Value: "foo"}},
+		},
+		// The env var not set by the HRA is kept
+		{
+			env:     []corev1.EnvVar{{Name: EnvVarRunnerIdleTimeout, Value: "600"}},
+			wantEnv: []corev1.EnvVar{{Name: EnvVarRunnerIdleTimeout, Value: "600"}},
+		},
+		// Not propagated due to dryRun
+		{
+			timeout: intPtr(300),
+			dryRun:  true,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			newRD := func(name string) *v1alpha1.RunnerDeployment {
+				return &v1alpha1.RunnerDeployment{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        name,
+						Namespace:   "default",
+						Annotations: tc.annotations,
+					},
+					Spec: v1alpha1.RunnerDeploymentSpec{
+						Template: v1alpha1.RunnerTemplate{
+							Spec: v1alpha1.RunnerSpec{
+								Repository: "test/valid",
+								Env:        tc.env,
+							},
+						},
+						Replicas: intPtr(1),
+					},
+				}
+			}
+
+			hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+				AdditionalScaleTargetRefs: []v1alpha1.ScaleTargetRef{
+					{Name: "testrd-arm"},
+				},
+				MinReplicas:              intPtr(1),
+				MaxReplicas:              intPtr(10),
+				RunnerIdleTimeoutSeconds: tc.timeout,
+				DryRun:                   tc.dryRun,
+			})
+
+			h := newTestReconciler(t, nil, nil, withObjects(newRD("testrd"), newRD("testrd-arm"), hra))
+
+			reconcileTestHRA(t, h)
+
+			wantEnv := tc.wantEnv
+			if tc.dryRun {
+				wantEnv = tc.env
+			}
+
+			for _, name := range []string{"testrd", "testrd-arm"} {
+				var gotRD v1alpha1.RunnerDeployment
+				if err := h.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: name}, &gotRD); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				if got := gotRD.Annotations[AnnotationKeyRunnerIdleTimeout]; got != tc.wantAnnotation {
+					t.Errorf("unexpected %s annotation of %s: want %q, got %q", AnnotationKeyRunnerIdleTimeout, name, tc.wantAnnotation, got)
+				}
+
+				if got := gotRD.Spec.Template.Spec.Env; !reflect.DeepEqual(got, wantEnv) {
+					t.Errorf("unexpected env of %s: want %v, got %v", name, wantEnv, got)
+				}
+			}
+		})
+	}
+}
+
+// statusUpdateCountingClient counts the status updates, for testing that nothing is written when nothing changed.
+type statusUpdateCountingClient struct {
+	client.Client
+
+	updates int
+}
+
+func (c *statusUpdateCountingClient) Status() client.StatusWriter {
+	return &countingStatusWriter{StatusWriter: c.Client.Status(), c: c}
+}
+
+type countingStatusWriter struct {
+	client.StatusWriter
+
+	c *statusUpdateCountingClient
+}
+
+func (w *countingStatusWriter) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	w.c.updates++
+
+	return w.StatusWriter.Update(ctx, obj, opts...)
+}
+
+func TestReconcile_NoStatusUpdateWhenUnchanged(t *testing.T) {
+	const fakeMetricType = "FakeMetric"
+
+	testcases := []struct {
+		cacheDurationSeconds *int
+		replicas             int
+		nextReplicas         int
+
+		wantUpdates int
+	}{
+		// Cached
+		{
+			replicas:     3,
+			nextReplicas: 3,
+			wantUpdates:  0,
+		},
+		// Recomputed by the disabled cache, without any change
+		{
+			cacheDurationSeconds: intPtr(0),
+			replicas:             3,
+			nextReplicas:         3,
+			wantUpdates:          0,
+		},
+		// Recomputed by the disabled cache, with the desired replicas changed
+		{
+			cacheDurationSeconds: intPtr(0),
+			replicas:             3,
+			nextReplicas:         4,
+			wantUpdates:          1,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			rd := newTestRD(intPtr(1))
+
+			hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+				MinReplicas:          intPtr(1),
+				MaxReplicas:          intPtr(10),
+				CacheDurationSeconds: tc.cacheDurationSeconds,
+				Metrics:              []v1alpha1.MetricSpec{{Type: fakeMetricType}},
+			})
+
+			replicas := tc.replicas
+
+			h := newTestReconciler(t, rd, hra)
+			h.MetricProviders = map[string]MetricProviderFactory{
+				fakeMetricType: func(_ *github.Client, _ v1alpha1.MetricSpec) MetricProvider {
+					return &fakeMetricProvider{replicas: replicas}
+				},
+			}
+
+			c := &statusUpdateCountingClient{Client: h.Client}
+			h.Client = c
+
+			reconcileTestHRA(t, h)
+
+			if c.updates != 1 {
+				t.Fatalf("unexpected status updates on the first reconciliation: want 1, got %d", c.updates)
+			}
+
+			c.updates = 0
+			replicas = tc.nextReplicas
+
+			reconcileTestHRA(t, h)
+
+			if c.updates != tc.wantUpdates {
+				t.Errorf("unexpected status updates: want %d, got %d", tc.wantUpdates, c.updates)
+			}
+		})
+	}
+}
+
+func TestReconcile_CacheExpiresAt(t *testing.T) {
+	const fakeMetricType = "FakeMetric"
+
+	now := time.Now()
+
+	cached := v1alpha1.CacheEntry{
+		Key:            v1alpha1.CacheEntryKeyDesiredReplicas,
+		Value:          2,
+		ExpirationTime: metav1.Time{Time: now.Add(5 * time.Minute)},
+		CreationTime:   metav1.Time{Time: now.Add(-5 * time.Minute)},
+	}
+
+	testcases := []struct {
+		cacheDurationSeconds *int
+		annotations          map[string]string
+		cacheEntries         []v1alpha1.CacheEntry
+
+		// wantExpiresIn is the duration until the expected status.cacheExpiresAt, or 0 when it's expected to be unset
+		wantExpiresIn time.Duration
+	}{
+		// Computed and cached
+		{
+			cacheDurationSeconds: intPtr(600),
+			wantExpiresIn:        10 * time.Minute,
+		},
+		// Already cached
+		{
+			cacheDurationSeconds: intPtr(600),
+			cacheEntries:         []v1alpha1.CacheEntry{cached},
+			wantExpiresIn:        5 * time.Minute,
+		},
+		// Nothing is cached while the cache is disabled
+		{
+			cacheDurationSeconds: intPtr(0),
+		},
+		// Nor while the desired replicas are overridden
+		{
+			cacheDurationSeconds: intPtr(600),
+			annotations:          map[string]string{AnnotationKeyDesiredReplicasOverride: "3"},
+			cacheEntries:         []v1alpha1.CacheEntry{cached},
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			rd := newTestRD(intPtr(1))
+
+			hra := &v1alpha1.HorizontalRunnerAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "testhra",
+					Namespace:   "default",
+					Annotations: tc.annotations,
+				},
+				Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+					ScaleTargetRef: v1alpha1.ScaleTargetRef{
+						Name: "testrd",
+					},
+					MinReplicas:          intPtr(1),
+					MaxReplicas:          intPtr(10),
+					CacheDurationSeconds: tc.cacheDurationSeconds,
+					Metrics:              []v1alpha1.MetricSpec{{Type: fakeMetricType}},
+				},
+				Status: v1alpha1.HorizontalRunnerAutoscalerStatus{
+					CacheEntries:   tc.cacheEntries,
+					CacheExpiresAt: &metav1.Time{Time: now.Add(time.Minute)},
+				},
+			}
+
+			h := newTestReconciler(t, rd, hra)
+			h.CacheDurationJitter = -1
+			h.MetricProviders = map[string]MetricProviderFactory{
+				fakeMetricType: func(_ *github.Client, _ v1alpha1.MetricSpec) MetricProvider {
+					return &fakeMetricProvider{replicas: 3}
+				},
+			}
+
+			res := reconcileTestHRA(t, h)
+
+			gotHRA := getTestHRA(t, h)
+
+			got := gotHRA.Status.CacheExpiresAt
+
+			if tc.wantExpiresIn == 0 {
+				if got != nil {
+					t.Errorf("unexpected status.cacheExpiresAt: %s", got.Format(time.RFC3339))
+				}
+
+				if res.RequeueAfter != 0 {
+					t.Errorf("unexpected requeueAfter: %s", res.RequeueAfter)
+				}
+
+				return
+			}
+
+			// The status is persisted in the precision of seconds
+			want := now.Add(tc.wantExpiresIn)
+
+			if got == nil {
+				t.Fatalf("missing status.cacheExpiresAt")
+			} else if d := got.Sub(want); d < -time.Second || d > time.Second {
+				t.Errorf("unexpected status.cacheExpiresAt: want %s, got %s", want.Format(time.RFC3339), got.Format(time.RFC3339))
+			}
+
+			if d := res.RequeueAfter - tc.wantExpiresIn; d < -time.Second || d > time.Second {
+				t.Errorf("unexpected requeueAfter: want %s, got %s", tc.wantExpiresIn, res.RequeueAfter)
+			}
+		})
+	}
+}
+
+func TestReconcile_ScaleOutScheduling(t *testing.T) {
+	const fakeMetricType = "FakeMetric"
+
+	gpu := corev1.Toleration{Key: "nvidia.com/gpu", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule}
+	spot := corev1.Toleration{Key: "spot", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule}
+
+	scheduling := &v1alpha1.ScaleOutScheduling{
+		NodeSelector: map[string]string{"accelerator": "nvidia-tesla-t4", "pool": "gpu"},
+		Tolerations:  []corev1.Toleration{gpu},
+	}
+
+	testcases := []struct {
+		replicas     int
+		nodeSelector map[string]string
+		tolerations  []corev1.Toleration
+
+		wantNodeSelector map[string]string
+		wantTolerations  []corev1.Toleration
+	}{
+		// Scaled out
+		{
+			replicas:         3,
+			wantNodeSelector: map[string]string{"accelerator": "nvidia-tesla-t4", "pool": "gpu"},
+			wantTolerations:  []corev1.Toleration{gpu},
+		},
+		// The ones set on the runnerdeployment win
+		{
+			replicas:         3,
+			nodeSelector:     map[string]string{"pool": "gpu-large"},
+			tolerations:      []corev1.Toleration{spot, gpu},
+			wantNodeSelector: map[string]string{"accelerator": "nvidia-tesla-t4", "pool": "gpu-large"},
+			wantTolerations:  []corev1.Toleration{spot, gpu},
+		},
+		// Not applied without scaling out
+		{
+			replicas: 2,
+		},
+		{
+			replicas: 1,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			rd := &v1alpha1.RunnerDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "testrd",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.RunnerDeploymentSpec{
+					Template: v1alpha1.RunnerTemplate{
+						Spec: v1alpha1.RunnerSpec{
+							Repository:   "test/valid",
+							NodeSelector: tc.nodeSelector,
+							Tolerations:  tc.tolerations,
+						},
+					},
+					Replicas: intPtr(2),
+				},
+			}
+
+			hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+				MinReplicas:                       intPtr(1),
+				MaxReplicas:                       intPtr(10),
+				ScaleDownDelaySecondsAfterScaleUp: intPtr(0),
+				ScaleOutScheduling:                scheduling,
+				Metrics:                           []v1alpha1.MetricSpec{{Type: fakeMetricType}},
+			})
+
+			h := newTestReconciler(t, rd, hra)
+			h.MetricProviders = map[string]MetricProviderFactory{
+				fakeMetricType: func(_ *github.Client, _ v1alpha1.MetricSpec) MetricProvider {
+					return &fakeMetricProvider{replicas: tc.replicas}
+				},
+			}
+
+			reconcileTestHRA(t, h)
+
+			gotRD := getTestRD(t, h)
+
+			if *gotRD.Spec.Replicas != tc.replicas {
+				t.Errorf("unexpected rd.Spec.Replicas: want %d, got %d", tc.replicas, *gotRD.Spec.Replicas)
+			}
+
+			if got := gotRD.Spec.Template.Spec.NodeSelector; !reflect.DeepEqual(got, tc.wantNodeSelector) {
+				t.Errorf("unexpected node selector: want %v, got %v", tc.wantNodeSelector, got)
+			}
+
+			if got := gotRD.Spec.Template.Spec.Tolerations; !reflect.DeepEqual(got, tc.wantTolerations) {
+				t.Errorf("unexpected tolerations: want %v, got %v", tc.wantTolerations, got)
+			}
+		})
+	}
+}
+
+func TestReconcile_GitHubAPICircuitBreaker(t *testing.T) {
+	const fakeMetricType = "FakeMetric"
+
+	now := time.Now()
+
+	expired := v1alpha1.CacheEntry{
+		Key:            v1alpha1.CacheEntryKeyDesiredReplicas,
+		Value:          2,
+		ExpirationTime: metav1.Time{Time: now.Add(-5 * time.Minute)},
+		CreationTime:   metav1.Time{Time: now.Add(-15 * time.Minute)},
+	}
+
+	testcases := []struct {
+		open         bool
+		cacheEntries []v1alpha1.CacheEntry
+
+		want             int
+		wantRequeueAfter time.Duration
+	}{
+		// Computed as usual while closed
+		{
+			cacheEntries:     []v1alpha1.CacheEntry{expired},
+			want:             3,
+			wantRequeueAfter: 10 * time.Minute,
+		},
+		// The expired cache is served while open
+		{
+			open:             true,
+			cacheEntries:     []v1alpha1.CacheEntry{expired},
+			want:             2,
+			wantRequeueAfter: 5 * time.Minute,
+		},
+		// The scale target is left as is while open without any cache
+		{
+			open:             true,
+			want:             1,
+			wantRequeueAfter: 5 * time.Minute,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			rd := newTestRD(intPtr(1))
+
+			hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+				MinReplicas:          intPtr(1),
+				MaxReplicas:          intPtr(10),
+				CacheDurationSeconds: intPtr(600),
+				Metrics:              []v1alpha1.MetricSpec{{Type: fakeMetricType}},
+			})
+
+			hra.Status = v1alpha1.HorizontalRunnerAutoscalerStatus{
+				CacheEntries: tc.cacheEntries,
+			}
+
+			breaker := &GitHubAPICircuitBreaker{Threshold: 1, Cooldown: 5 * time.Minute}
+			if tc.open {
+				breaker.RecordFailure(now)
+			}
+
+			h := newTestReconciler(t, rd, hra)
+			h.CacheDurationJitter = -1
+			h.GitHubAPICircuitBreaker = breaker
+			h.MetricProviders = map[string]MetricProviderFactory{
+				fakeMetricType: func(_ *github.Client, _ v1alpha1.MetricSpec) MetricProvider {
+					return &fakeMetricProvider{replicas: 3}
+				},
+			}
+
+			res := reconcileTestHRA(t, h)
+
+			gotRD := getTestRD(t, h)
+
+			if got := *gotRD.Spec.Replicas; got != tc.want {
+				t.Errorf("unexpected replicas: want %d, got %d", tc.want, got)
+			}
+
+			if d := res.RequeueAfter - tc.wantRequeueAfter; d < -time.Second || d > time.Second {
+				t.Errorf("unexpected requeueAfter: want %s, got %s", tc.wantRequeueAfter, res.RequeueAfter)
+			}
+		})
+	}
+}
+
+func TestReconcile_MaxScaleUpCount(t *testing.T) {
+	const fakeMetricType = "FakeMetric"
+
+	testcases := []struct {
+		current         int
+		computed        int
+		maxScaleUpCount *int
+		reservations    []v1alpha1.CapacityReservation
+
+		want int
+	}{
+		// current=2, computed=20, adds at most 5
+		{
+			current:         2,
+			computed:        20,
+			maxScaleUpCount: intPtr(5),
+			want:            7,
+		},
+		// Within the limit
+		{
+			current:         2,
+			computed:        5,
+			maxScaleUpCount: intPtr(5),
+			want:            5,
+		},
+		// No limit
+		{
+			current:  2,
+			computed: 20,
+			want:     20,
+		},
+		// Capacity reservations are exempt from the limit
+		{
+			current:         2,
+			computed:        20,
+			maxScaleUpCount: intPtr(5),
+			reservations:    []v1alpha1.CapacityReservation{{ExpirationTime: metav1.Time{Time: time.Now().Add(time.Hour)}, Replicas: 3}},
+			want:            10,
+		},
+		// Scale downs aren't affected
+		{
+			current:         10,
+			computed:        2,
+			maxScaleUpCount: intPtr(5),
+			want:            2,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			rd := newTestRD(intPtr(tc.current))
+
+			hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+				MinReplicas:          intPtr(1),
+				MaxReplicas:          intPtr(30),
+				MaxScaleUpCount:      tc.maxScaleUpCount,
+				CapacityReservations: tc.reservations,
+				Metrics:              []v1alpha1.MetricSpec{{Type: fakeMetricType}},
+			})
+
+			h := newTestReconciler(t, rd, hra)
+			h.MetricProviders = map[string]MetricProviderFactory{
+				fakeMetricType: func(_ *github.Client, _ v1alpha1.MetricSpec) MetricProvider {
+					return &fakeMetricProvider{replicas: tc.computed}
+				},
+			}
+
+			reconcileTestHRA(t, h)
+
+			got := getTestRD(t, h)
+
+			if *got.Spec.Replicas != tc.want {
+				t.Errorf("incorrect desired replicas: want %d, got %d", tc.want, *got.Spec.Replicas)
+			}
+		})
+	}
+}
+
+func TestReconcile_BurstMaxReplicas(t *testing.T) {
+	const fakeMetricType = "FakeMetric"
+
+	now := time.Now()
+
+	credits := func(consumed int, ago time.Duration) *v1alpha1.BurstCreditsStatus {
+		return &v1alpha1.BurstCreditsStatus{ConsumedReplicaSeconds: consumed, LastUpdateTime: metav1.Time{Time: now.Add(-ago)}}
+	}
+
+	// maxReplicas=10, burstMaxReplicas=15, and the default duration and refill result in the capacity of 3000 replica-seconds
+	// refilling at 50 replica-seconds per minute
+	testcases := []struct {
+		burst    bool
+		current  int
+		computed int
+		credits  *v1alpha1.BurstCreditsStatus
+
+		want         int
+		wantConsumed *int
+		wantEvent    bool
+	}{
+		// Capped by maxReplicas without burst
+		{
+			current:  5,
+			computed: 20,
+			want:     10,
+		},
+		// Scaling out beyond maxReplicas starts the accounting
+		{
+			burst:        true,
+			current:      5,
+			computed:     20,
+			want:         15,
+			wantConsumed: intPtr(0),
+		},
+		// 5 replicas above maxReplicas for a minute consume 300, of which 50 is refilled
+		{
+			burst:        true,
+			current:      15,
+			computed:     20,
+			credits:      credits(0, time.Minute),
+			want:         15,
+			wantConsumed: intPtr(250),
+		},
+		// Exhausted
+		{
+			burst:        true,
+			current:      15,
+			computed:     20,
+			credits:      credits(2900, time.Minute),
+			want:         10,
+			wantConsumed: intPtr(3000),
+			wantEvent:    true,
+		},
+		// Exhausted credits are refilling
+		{
+			burst:        true,
+			current:      10,
+			computed:     20,
+			credits:      credits(3000, time.Minute),
+			want:         15,
+			wantConsumed: intPtr(2950),
+		},
+		// Fully refilled
+		{
+			burst:    true,
+			current:  10,
+			computed: 5,
+			credits:  credits(100, 10*time.Minute),
+			want:     5,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			rd := newTestRD(intPtr(tc.current))
+
+			hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+				MinReplicas: intPtr(1),
+				MaxReplicas: intPtr(10),
+				Metrics:     []v1alpha1.MetricSpec{{Type: fakeMetricType}},
+			})
+
+			hra.Status = v1alpha1.HorizontalRunnerAutoscalerStatus{
+				BurstCredits: tc.credits,
+			}
+
+			if tc.burst {
+				hra.Spec.BurstMaxReplicas = intPtr(15)
+			}
+
+			recorder := record.NewFakeRecorder(10)
+
+			h := newTestReconciler(t, rd, hra)
+			h.Recorder = recorder
+			h.MetricProviders = map[string]MetricProviderFactory{
+				fakeMetricType: func(_ *github.Client, _ v1alpha1.MetricSpec) MetricProvider {
+					return &fakeMetricProvider{replicas: tc.computed}
+				},
+			}
+
+			reconcileTestHRA(t, h)
+
+			got := getTestRD(t, h)
+
+			if *got.Spec.Replicas != tc.want {
+				t.Errorf("incorrect desired replicas: want %d, got %d", tc.want, *got.Spec.Replicas)
+			}
+
+			gotHRA := getTestHRA(t, h)
+
+			gotCredits := gotHRA.Status.BurstCredits
+
+			if tc.wantConsumed == nil {
+				if gotCredits != nil {
+					t.Errorf("unexpected burst credits: %+v", *gotCredits)
+				}
+			} else if gotCredits == nil {
+				t.Errorf("missing burst credits: want %d consumed", *tc.wantConsumed)
+			} else if d := gotCredits.ConsumedReplicaSeconds - *tc.wantConsumed; d < -5 || d > 5 {
+				// The last update time loses the sub-second precision on the round trip, which is off by up to 5 replica-seconds
+				t.Errorf("incorrect consumed burst credits: want %d, got %d", *tc.wantConsumed, gotCredits.ConsumedReplicaSeconds)
+			}
+
+			var gotEvent bool
+
+			for len(recorder.Events) > 0 {
+				if strings.Contains(<-recorder.Events, "BurstCreditsExhausted") {
+					gotEvent = true
+				}
+			}
+
+			if gotEvent != tc.wantEvent {
+				t.Errorf("unexpected BurstCreditsExhausted event: want %v, got %v", tc.wantEvent, gotEvent)
+			}
+		})
+	}
+}
+
+func TestReconcile_RequeueInterval(t *testing.T) {
+	const fakeMetricType = "FakeMetric"
+
+	now := time.Now()
+
+	testcases := []struct {
+		interval     time.Duration
+		cacheEntries []v1alpha1.CacheEntry
+
+		wantRequeueAfter time.Duration
+	}{
+		// Requeued only on the cache expiration without the interval
+		{
+			wantRequeueAfter: 10 * time.Minute,
+		},
+		{
+			interval:         time.Minute,
+			wantRequeueAfter: time.Minute,
+		},
+		// The cache expiration comes sooner
+		{
+			interval:         20 * time.Minute,
+			wantRequeueAfter: 10 * time.Minute,
+		},
+		{
+			interval: time.Minute,
+			cacheEntries: []v1alpha1.CacheEntry{
+				{
+					Key:            v1alpha1.CacheEntryKeyDesiredReplicas,
+					Value:          3,
+					ExpirationTime: metav1.Time{Time: now.Add(30 * time.Second)},
+					CreationTime:   metav1.Time{Time: now.Add(-5 * time.Minute)},
+				},
+			},
+			wantRequeueAfter: 30 * time.Second,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			rd := newTestRD(intPtr(3))
+
+			hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+				MinReplicas:          intPtr(1),
+				MaxReplicas:          intPtr(10),
+				CacheDurationSeconds: intPtr(600),
+				Metrics:              []v1alpha1.MetricSpec{{Type: fakeMetricType}},
+			})
+
+			hra.Status = v1alpha1.HorizontalRunnerAutoscalerStatus{
+				CacheEntries: tc.cacheEntries,
+			}
+
+			h := newTestReconciler(t, rd, hra)
+			h.CacheDurationJitter = -1
+			h.RequeueInterval = tc.interval
+			h.MetricProviders = map[string]MetricProviderFactory{
+				fakeMetricType: func(_ *github.Client, _ v1alpha1.MetricSpec) MetricProvider {
+					return &fakeMetricProvider{replicas: 3}
+				},
+			}
+
+			res := reconcileTestHRA(t, h)
+
+			if d := res.RequeueAfter - tc.wantRequeueAfter; d < -time.Second || d > time.Second {
+				t.Errorf("unexpected requeueAfter: want %s, got %s", tc.wantRequeueAfter, res.RequeueAfter)
+			}
+		})
+	}
+}
+
+func TestReconcile_CacheReusedWithCapacityReservations(t *testing.T) {
+	// The cached desired replicas are 0 in all the cases, to which only the reservations are added
+	testcases := []struct {
+		current                        int
+		reserved                       int
+		maxCapacityReservationReplicas *int
+
+		want int
+	}{
+		// A job is queued but no runner exists while the cache is fresh
+		{
+			current:  0,
+			reserved: 2,
+			want:     2,
+		},
+		// The current replicas already cover the reservation
+		{
+			current:  2,
+			reserved: 2,
+			want:     2,
+		},
+		// No reservation
+		{
+			current: 0,
+			want:    0,
+		},
+		// The demand of the reservations is capped by maxCapacityReservationReplicas
+		{
+			current:                        0,
+			reserved:                       5,
+			maxCapacityReservationReplicas: intPtr(1),
+			want:                           1,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			var calls int
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				calls++
+
+				w.WriteHeader(http.StatusInternalServerError)
+			}))
+			defer server.Close()
+
+			rd := newTestRD(intPtr(tc.current))
+
+			hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+				MinReplicas:                    intPtr(0),
+				MaxReplicas:                    intPtr(10),
+				MaxCapacityReservationReplicas: tc.maxCapacityReservationReplicas,
+				Metrics: []v1alpha1.MetricSpec{
+					{
+						Type:            v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns,
+						RepositoryNames: []string{"valid"},
+					},
+				},
+			})
+
+			hra.Status = v1alpha1.HorizontalRunnerAutoscalerStatus{
+				CacheEntries: []v1alpha1.CacheEntry{
+					{
+						Key:            v1alpha1.CacheEntryKeyDesiredReplicas,
+						Value:          0,
+						ExpirationTime: metav1.Time{Time: time.Now().Add(time.Hour)},
+						InputsKey:      fmt.Sprintf("replicas=%d,minReplicas=0,maxReplicas=10", tc.current),
+					},
+				},
+			}
+
+			if tc.reserved > 0 {
+				hra.Spec.CapacityReservations = []v1alpha1.CapacityReservation{
+					{
+						ExpirationTime: metav1.Time{Time: time.Now().Add(time.Hour)},
+						Replicas:       tc.reserved,
+					},
+				}
+			}
+
+			h := newTestReconciler(t, rd, hra)
+			h.GitHubClient = newGithubClient(server)
+
+			// The second reconciliation sees the replicas scaled by the first one, which shouldn't bust the cache either
+			for j := 0; j < 2; j++ {
+				reconcileTestHRA(t, h)
+
+				got := getTestRD(t, h)
+
+				if *got.Spec.Replicas != tc.want {
+					t.Errorf("incorrect desired replicas on reconciliation %d: want %d, got %d", j, tc.want, *got.Spec.Replicas)
+				}
+
+				if calls != 0 {
+					t.Errorf("unexpected github api calls on reconciliation %d: want 0, got %d", j, calls)
+				}
+			}
+		})
+	}
+}
+
+func TestReconcile_EventVerbosity(t *testing.T) {
+	const fakeMetricType = "FakeMetric"
+
+	testcases := []struct {
+		verbosity EventVerbosity
+		current   int
+		computed  int
+
+		wantReasons []string
+	}{
+		{
+			verbosity:   "",
+			current:     1,
+			computed:    3,
+			wantReasons: []string{"ScaledRunnerDeployment"},
+		},
+		{
+			verbosity: "",
+			current:   3,
+			computed:  3,
+		},
+		{
+			verbosity:   EventVerbosityChanges,
+			current:     1,
+			computed:    3,
+			wantReasons: []string{"ScaledRunnerDeployment"},
+		},
+		{
+			verbosity:   EventVerbosityAll,
+			current:     1,
+			computed:    3,
+			wantReasons: []string{"ScaledRunnerDeployment"},
+		},
+		{
+			verbosity:   EventVerbosityAll,
+			current:     3,
+			computed:    3,
+			wantReasons: []string{"ScalingDecision"},
+		},
+		{
+			verbosity: EventVerbosityOff,
+			current:   1,
+			computed:  3,
+		},
+		// Off silences the warnings too
+		{
+			verbosity: EventVerbosityOff,
+			current:   1,
+			computed:  20,
+		},
+		{
+			verbosity:   EventVerbosityChanges,
+			current:     1,
+			computed:    20,
+			wantReasons: []string{"MaxReplicasReached", "ScaledRunnerDeployment"},
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			rd := newTestRD(intPtr(tc.current))
+
+			hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+				MinReplicas: intPtr(1),
+				MaxReplicas: intPtr(10),
+				Metrics:     []v1alpha1.MetricSpec{{Type: fakeMetricType}},
+			})
+
+			hra.Status = v1alpha1.HorizontalRunnerAutoscalerStatus{
+				DesiredReplicas: intPtr(tc.current),
+			}
+
+			recorder := record.NewFakeRecorder(10)
+
+			h := newTestReconciler(t, rd, hra)
+			h.Recorder = recorder
+			h.EventVerbosity = tc.verbosity
+			h.MetricProviders = map[string]MetricProviderFactory{
+				fakeMetricType: func(_ *github.Client, _ v1alpha1.MetricSpec) MetricProvider {
+					return &fakeMetricProvider{replicas: tc.computed}
+				},
+			}
+
+			reconcileTestHRA(t, h)
+
+			var gotReasons []string
+
+			for len(recorder.Events) > 0 {
+				// Each event is formatted as "<type> <reason> <message>"
+				gotReasons = append(gotReasons, strings.Fields(<-recorder.Events)[1])
+			}
+
+			if !reflect.DeepEqual(gotReasons, tc.wantReasons) {
+				t.Errorf("unexpected event reasons: want %v, got %v", tc.wantReasons, gotReasons)
+			}
+		})
+	}
+}
+
+func TestParseEventVerbosity(t *testing.T) {
+	testcases := []struct {
+		s       string
+		want    EventVerbosity
+		wantErr bool
+	}{
+		{s: "Off", want: EventVerbosityOff},
+		{s: "Changes", want: EventVerbosityChanges},
+		{s: "All", want: EventVerbosityAll},
+		{s: "all", wantErr: true},
+		{s: "", wantErr: true},
+	}
+
+	for _, tc := range testcases {
+		got, err := ParseEventVerbosity(tc.s)
+
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%q: unexpected error: want error %v, got %v", tc.s, tc.wantErr, err)
+		}
+
+		if got != tc.want {
+			t.Errorf("%q: unexpected event verbosity: want %q, got %q", tc.s, tc.want, got)
+		}
+	}
+}
+
+func TestReconcile_ScaleTargetGenerationPending(t *testing.T) {
+	const fakeMetricType = "FakeMetric"
+
+	type generation struct {
+		generation, observed int64
+	}
+
+	// The metric computes 3 replicas, while the runnerdeployments have 1
+	testcases := []struct {
+		rd      generation
+		other   generation
+		dryRun  bool
+		current int
+
+		want             int
+		wantRequeueAfter time.Duration
+	}{
+		// The runnerdeployment controller is still reconciling the last update
+		{
+			rd:               generation{3, 2},
+			current:          1,
+			want:             1,
+			wantRequeueAfter: ScaleTargetGenerationPendingRequeueDelay,
+		},
+		{
+			rd:      generation{3, 3},
+			current: 1,
+			want:    3,
+		},
+		// The runnerdeployment controller doesn't record the observed generation
+		{
+			rd:      generation{3, 0},
+			current: 1,
+			want:    3,
+		},
+		// The scale targets are scaled together
+		{
+			rd:               generation{3, 3},
+			other:            generation{5, 4},
+			current:          1,
+			want:             1,
+			wantRequeueAfter: ScaleTargetGenerationPendingRequeueDelay,
+		},
+		// Nothing to update
+		{
+			rd:      generation{3, 2},
+			other:   generation{5, 4},
+			current: 3,
+			want:    3,
+		},
+		// Dry runs never update the runnerdeployments
+		{
+			rd:      generation{3, 2},
+			dryRun:  true,
+			current: 1,
+			want:    1,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			newRD := func(name string, g generation) *v1alpha1.RunnerDeployment {
+				return &v1alpha1.RunnerDeployment{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:       name,
+						Namespace:  "default",
+						Generation: g.generation,
+					},
+					Spec: v1alpha1.RunnerDeploymentSpec{
+						Template: v1alpha1.RunnerTemplate{
+							Spec: v1alpha1.RunnerSpec{
+								Repository: "test/valid",
+							},
+						},
+						Replicas: intPtr(tc.current),
+					},
+					Status: v1alpha1.RunnerDeploymentStatus{
+						ObservedGeneration: g.observed,
+					},
+				}
+			}
+
+			hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+				AdditionalScaleTargetRefs: []v1alpha1.ScaleTargetRef{
+					{Name: "testrd-other"},
+				},
+				MinReplicas: intPtr(1),
+				MaxReplicas: intPtr(10),
+				Metrics:     []v1alpha1.MetricSpec{{Type: fakeMetricType}},
+				DryRun:      tc.dryRun,
+			})
+
+			h := newTestReconciler(t, nil, nil, withObjects(newRD("testrd", tc.rd), newRD("testrd-other", tc.other), hra))
+			h.RequeueInterval = time.Hour
+			h.MetricProviders = map[string]MetricProviderFactory{
+				fakeMetricType: func(_ *github.Client, _ v1alpha1.MetricSpec) MetricProvider {
+					return &fakeMetricProvider{replicas: 3}
+				},
+			}
+
+			res := reconcileTestHRA(t, h)
+
+			if tc.wantRequeueAfter != 0 && res.RequeueAfter != tc.wantRequeueAfter {
+				t.Errorf("unexpected requeueAfter: want %s, got %s", tc.wantRequeueAfter, res.RequeueAfter)
+			}
+
+			for _, name := range []string{"testrd", "testrd-other"} {
+				var got v1alpha1.RunnerDeployment
+				if err := h.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: name}, &got); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				if *got.Spec.Replicas != tc.want {
+					t.Errorf("incorrect desired replicas of %s: want %d, got %d", name, tc.want, *got.Spec.Replicas)
+				}
+			}
+
+			gotHRA := getTestHRA(t, h)
+
+			// The deferred scale isn't recorded, so that it's retried as is
+			if tc.wantRequeueAfter != 0 && gotHRA.Status.DesiredReplicas != nil {
+				t.Errorf("unexpected status.desiredReplicas: want nil, got %d", *gotHRA.Status.DesiredReplicas)
+			}
+		})
+	}
+}
+
+func TestReconcile_IdleRunnersHint(t *testing.T) {
+	const fakeMetricType = "FakeMetric"
+
+	// test3 is busy, and test4 isn't a runner of the runnerdeployment
+	runnersListBody := `
+{
+  "total_count": 4,
+  "runners": [
+    {"id": 1, "name": "test1", "os": "linux", "status": "online", "busy": false},
+    {"id": 2, "name": "test2", "os": "linux", "status": "offline", "busy": false},
+    {"id": 3, "name": "test3", "os": "linux", "status": "online", "busy": true},
+    {"id": 4, "name": "test4", "os": "linux", "status": "online", "busy": false}
+  ]
+}
+`
+
+	testcases := []struct {
+		computed          int
+		annotations       map[string]string
+		listRunnersStatus int
+
+		wantReplicas int
+		wantHint     *string
+	}{
+		{
+			computed:     1,
+			wantReplicas: 1,
+			wantHint:     pointer.StringPtr("test1,test2"),
+		},
+		// The stale hint is removed on scaling up
+		{
+			computed:     5,
+			annotations:  map[string]string{AnnotationKeyIdleRunners: "test1"},
+			wantReplicas: 5,
+		},
+		// The failure to list the runners doesn't block the scale down
+		{
+			computed:          1,
+			annotations:       map[string]string{AnnotationKeyIdleRunners: "test1"},
+			listRunnersStatus: 500,
+			wantReplicas:      1,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			listRunnersStatus := 200
+			if tc.listRunnersStatus != 0 {
+				listRunnersStatus = tc.listRunnersStatus
+			}
+
+			rd := &v1alpha1.RunnerDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "testrd",
+					Namespace:   "default",
+					UID:         "testrd-uid",
+					Annotations: tc.annotations,
+				},
+				Spec: v1alpha1.RunnerDeploymentSpec{
+					Template: v1alpha1.RunnerTemplate{
+						Spec: v1alpha1.RunnerSpec{
+							Repository: "test/valid",
+						},
+					},
+					Replicas: intPtr(3),
+				},
+			}
+
+			controllerRef := func(kind, name string, uid types.UID) []metav1.OwnerReference {
+				controller := true
+				return []metav1.OwnerReference{{APIVersion: v1alpha1.GroupVersion.String(), Kind: kind, Name: name, UID: uid, Controller: &controller}}
+			}
+
+			rs := &v1alpha1.RunnerReplicaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "testrd-abc",
+					Namespace:       "default",
+					UID:             "testrd-abc-uid",
+					OwnerReferences: controllerRef("RunnerDeployment", "testrd", rd.UID),
+				},
+			}
+
+			objs := []runtime.Object{rs}
+
+			for _, name := range []string{"test1", "test2", "test3"} {
+				objs = append(objs, &v1alpha1.Runner{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:            name,
+						Namespace:       "default",
+						OwnerReferences: controllerRef("RunnerReplicaSet", rs.Name, rs.UID),
+					},
+				})
+			}
+
+			objs = append(objs, &v1alpha1.Runner{ObjectMeta: metav1.ObjectMeta{Name: "test4", Namespace: "default"}})
+
+			hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+				MinReplicas: intPtr(1),
+				MaxReplicas: intPtr(10),
+				Metrics:     []v1alpha1.MetricSpec{{Type: fakeMetricType}},
+			})
+
+			h := newTestReconciler(t, rd, hra, withGitHubResponses(fake.WithListRunnersResponse(listRunnersStatus, runnersListBody)), withObjects(objs...))
+			h.MetricProviders = map[string]MetricProviderFactory{
+				fakeMetricType: func(_ *github.Client, _ v1alpha1.MetricSpec) MetricProvider {
+					return &fakeMetricProvider{replicas: tc.computed}
+				},
+			}
+			h.RunnerListCacheTTL = -1
+
+			reconcileTestHRA(t, h)
+
+			got := getTestRD(t, h)
+
+			if got.Spec.Replicas == nil || *got.Spec.Replicas != tc.wantReplicas {
+				t.Errorf("unexpected replicas: want %d, got %v", tc.wantReplicas, got.Spec.Replicas)
+			}
+
+			hint, ok := got.Annotations[AnnotationKeyIdleRunners]
+			if tc.wantHint == nil {
+				if ok {
+					t.Errorf("unexpected %s annotation: %q", AnnotationKeyIdleRunners, hint)
+				}
+			} else if hint != *tc.wantHint {
+				t.Errorf("unexpected %s annotation: want %q, got %q", AnnotationKeyIdleRunners, *tc.wantHint, hint)
+			}
+		})
+	}
+}
+
+func TestSortRunnersByIdleHint(t *testing.T) {
+	newRunners := func(names ...string) []v1alpha1.Runner {
+		var runners []v1alpha1.Runner
+		for _, name := range names {
+			runners = append(runners, v1alpha1.Runner{ObjectMeta: metav1.ObjectMeta{Name: name}})
+		}
+		return runners
+	}
+
+	testcases := []struct {
+		annotations map[string]string
+		want        []string
+	}{
+		{
+			want: []string{"a", "b", "c", "d"},
+		},
+		{
+			annotations: map[string]string{AnnotationKeyIdleRunners: "d,b"},
+			want:        []string{"b", "d", "a", "c"},
+		},
+		// Unknown runners in the hint are ignored
+		{
+			annotations: map[string]string{AnnotationKeyIdleRunners: "c,x"},
+			want:        []string{"c", "a", "b", "d"},
+		},
+	}
+
+	for i, tc := range testcases {
+		rs := v1alpha1.RunnerReplicaSet{ObjectMeta: metav1.ObjectMeta{Annotations: tc.annotations}}
+
+		runners := newRunners("a", "b", "c", "d")
+
+		sortRunnersByIdleHint(rs, runners)
+
+		var got []string
+		for _, r := range runners {
+			got = append(got, r.Name)
+		}
+
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("case %d: unexpected order: want %v, got %v", i, tc.want, got)
+		}
+	}
+}
+
+func TestReconcile_EventsOnly(t *testing.T) {
+	testcases := []struct {
+		current      int
+		reservations []int
+
+		want int
+	}{
+		// MinReplicas plus the active reservations
+		{
+			current:      1,
+			reservations: []int{1, 2},
+			want:         4,
+		},
+		// Bounded by maxReplicas
+		{
+			current:      1,
+			reservations: []int{3, 3},
+			want:         5,
+		},
+		// The expired reservations scale it down to MinReplicas right away, regardless of the scale down delay
+		{
+			current: 3,
+			want:    1,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			var calls int
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				calls++
+
+				w.WriteHeader(http.StatusInternalServerError)
+			}))
+			defer server.Close()
+
+			rd := newTestRD(intPtr(tc.current))
+
+			var reservations []v1alpha1.CapacityReservation
+			for j, r := range tc.reservations {
+				reservations = append(reservations, v1alpha1.CapacityReservation{
+					Name:           fmt.Sprintf("reservation%d", j),
+					ExpirationTime: metav1.Time{Time: time.Now().Add(10 * time.Minute)},
+					Replicas:       r,
+				})
+			}
+
+			hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+				MinReplicas:          intPtr(1),
+				MaxReplicas:          intPtr(5),
+				ScaleMode:            v1alpha1.ScaleModeEventsOnly,
+				ScaleUpTriggers:      []v1alpha1.ScaleUpTrigger{{GitHubEvent: &v1alpha1.GitHubEventScaleUpTriggerSpec{}, Amount: 1}},
+				CapacityReservations: reservations,
+			})
+
+			hra.Status = v1alpha1.HorizontalRunnerAutoscalerStatus{
+				DesiredReplicas:            intPtr(tc.current),
+				LastSuccessfulScaleOutTime: &metav1.Time{Time: time.Now().Add(-time.Minute)},
+			}
+
+			h := newTestReconciler(t, rd, hra)
+			h.GitHubClient = newGithubClient(server)
+
+			reconcileTestHRA(t, h)
+
+			gotRD := getTestRD(t, h)
+
+			if *gotRD.Spec.Replicas != tc.want {
+				t.Errorf("unexpected rd.Spec.Replicas: want %d, got %d", tc.want, *gotRD.Spec.Replicas)
+			}
+
+			if calls != 0 {
+				t.Errorf("unexpected GitHub API calls: want 0, got %d", calls)
+			}
+
+			gotHRA := getTestHRA(t, h)
+
+			if len(gotHRA.Status.CacheEntries) != 0 {
+				t.Errorf("unexpected cache entries: %v", gotHRA.Status.CacheEntries)
+			}
+		})
+	}
+}
+
+func TestReconcile_ScaleEvents(t *testing.T) {
+	const fakeMetricType = "FakeMetric"
+
+	past := func(n int) []v1alpha1.ScaleEvent {
+		var events []v1alpha1.ScaleEvent
+		for i := 0; i < n; i++ {
+			events = append(events, v1alpha1.ScaleEvent{
+				Time:         metav1.Time{Time: time.Now().Add(time.Duration(i-n) * time.Hour)},
+				FromReplicas: i,
+				ToReplicas:   i + 1,
+				Reason:       v1alpha1.ScaleEventReasonMetric,
+				Metric:       fakeMetricType,
+			})
+		}
+		return events
+	}
+
+	testcases := []struct {
+		computed     int
+		reservations []int
+		override     string
+		dryRun       bool
+		events       []v1alpha1.ScaleEvent
+
+		wantEvents int
+		wantLast   *v1alpha1.ScaleEvent
+	}{
+		{
+			computed:   4,
+			wantEvents: 1,
+			wantLast:   &v1alpha1.ScaleEvent{FromReplicas: 2, ToReplicas: 4, Reason: v1alpha1.ScaleEventReasonMetric, Metric: fakeMetricType},
+		},
+		{
+			computed:     2,
+			reservations: []int{2},
+			wantEvents:   1,
+			wantLast:     &v1alpha1.ScaleEvent{FromReplicas: 2, ToReplicas: 4, Reason: v1alpha1.ScaleEventReasonCapacityReservation},
+		},
+		{
+			computed:   2,
+			override:   "5",
+			wantEvents: 1,
+			wantLast:   &v1alpha1.ScaleEvent{FromReplicas: 2, ToReplicas: 5, Reason: v1alpha1.ScaleEventReasonOverride},
+		},
+		{
+			computed:   0,
+			wantEvents: 1,
+			wantLast:   &v1alpha1.ScaleEvent{FromReplicas: 2, ToReplicas: 1, Reason: v1alpha1.ScaleEventReasonMinReplicas},
+		},
+		// The oldest events are dropped beyond the limit
+		{
+			computed:   4,
+			events:     past(10),
+			wantEvents: 10,
+			wantLast:   &v1alpha1.ScaleEvent{FromReplicas: 2, ToReplicas: 4, Reason: v1alpha1.ScaleEventReasonMetric, Metric: fakeMetricType},
+		},
+		// Nothing is recorded while the replicas are unchanged
+		{
+			computed:   2,
+			events:     past(3),
+			wantEvents: 3,
+		},
+		{
+			computed: 4,
+			dryRun:   true,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			rd := newTestRD(intPtr(2))
+
+			var reservations []v1alpha1.CapacityReservation
+			for j, r := range tc.reservations {
+				reservations = append(reservations, v1alpha1.CapacityReservation{
+					Name:           fmt.Sprintf("reservation%d", j),
+					ExpirationTime: metav1.Time{Time: time.Now().Add(10 * time.Minute)},
+					Replicas:       r,
+				})
+			}
+
+			hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+				MinReplicas:                       intPtr(1),
+				MaxReplicas:                       intPtr(10),
+				ScaleDownDelaySecondsAfterScaleUp: intPtr(0),
+				Metrics:                           []v1alpha1.MetricSpec{{Type: fakeMetricType}},
+				CapacityReservations:              reservations,
+				DryRun:                            tc.dryRun,
+			})
+
+			hra.Status = v1alpha1.HorizontalRunnerAutoscalerStatus{
+				DesiredReplicas: intPtr(2),
+				ScaleEvents:     tc.events,
+			}
+
+			if tc.override != "" {
+				hra.Annotations = map[string]string{AnnotationKeyDesiredReplicasOverride: tc.override}
+			}
+
+			h := newTestReconciler(t, rd, hra)
+			h.MetricProviders = map[string]MetricProviderFactory{
+				fakeMetricType: func(_ *github.Client, _ v1alpha1.MetricSpec) MetricProvider {
+					return &fakeMetricProvider{replicas: tc.computed}
+				},
+			}
+
+			reconcileTestHRA(t, h)
+
+			got := getTestHRA(t, h)
+
+			events := got.Status.ScaleEvents
+
+			if len(events) != tc.wantEvents {
+				t.Fatalf("unexpected number of scale events: want %d, got %d: %v", tc.wantEvents, len(events), events)
+			}
+
+			if tc.wantLast == nil {
+				return
+			}
+
+			last := events[len(events)-1]
+			last.Time = metav1.Time{}
+
+			if !reflect.DeepEqual(last, *tc.wantLast) {
+				t.Errorf("unexpected last scale event: want %+v, got %+v", *tc.wantLast, last)
+			}
+
+			// The events are kept in order, dropping the oldest ones
+			if len(tc.events) == maxScaleEvents && events[0].FromReplicas != 1 {
+				t.Errorf("unexpected first scale event: want the one from 1 replicas, got %+v", events[0])
+			}
+		})
+	}
+}
+
+func TestGetScaleEventReason(t *testing.T) {
+	testcases := []struct {
+		decision   scalingDecision
+		desired    int
+		overridden bool
+
+		want string
+	}{
+		{
+			decision: scalingDecision{ComputedReplicas: 3, MinReplicas: 1},
+			desired:  3,
+			want:     v1alpha1.ScaleEventReasonMetric,
+		},
+		// The metrics demanding no more than MinReplicas, which they clamp their replicas by
+		{
+			decision: scalingDecision{ComputedReplicas: 1, MinReplicas: 1},
+			desired:  1,
+			want:     v1alpha1.ScaleEventReasonMinReplicas,
+		},
+		// The cached replicas below MinReplicas raised by the ramp
+		{
+			decision: scalingDecision{ComputedReplicas: 1, MinReplicas: 3},
+			desired:  3,
+			want:     v1alpha1.ScaleEventReasonMinReplicas,
+		},
+		// The scale down to MinReplicas limited by MaxScaleDownCount
+		{
+			decision: scalingDecision{ComputedReplicas: 1, MinReplicas: 1},
+			desired:  4,
+			want:     v1alpha1.ScaleEventReasonMetric,
+		},
+		{
+			decision: scalingDecision{ComputedReplicas: 0, ReservedReplicas: 2, MinReplicas: 0},
+			desired:  2,
+			want:     v1alpha1.ScaleEventReasonCapacityReservation,
+		},
+		{
+			decision:   scalingDecision{ComputedReplicas: 5, ReservedReplicas: 2, MinReplicas: 1},
+			desired:    5,
+			overridden: true,
+			want:       v1alpha1.ScaleEventReasonOverride,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			if got := getScaleEventReason(tc.decision, tc.desired, tc.overridden); got != tc.want {
+				t.Errorf("unexpected reason: want %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestReconcile_NilReplicasPolicy(t *testing.T) {
+	const fakeMetricType = "FakeMetric"
+
+	// The scale up is limited to 1 replica above the current replicas in all the cases
+	testcases := []struct {
+		policy   NilReplicasPolicy
+		replicas *int
+		observed *int
+		demand   int
+
+		want int
+	}{
+		// MinReplicas is assumed regardless of the observed replicas by default
+		{
+			observed: intPtr(4),
+			demand:   8,
+			want:     2,
+		},
+		{
+			policy:   NilReplicasPolicyMinReplicas,
+			observed: intPtr(4),
+			demand:   8,
+			want:     2,
+		},
+		// The observed replicas are adopted as the current replicas
+		{
+			policy:   NilReplicasPolicyObserved,
+			observed: intPtr(4),
+			demand:   8,
+			want:     5,
+		},
+		{
+			policy:   NilReplicasPolicyObserved,
+			observed: intPtr(4),
+			demand:   1,
+			want:     1,
+		},
+		// Falls back to MinReplicas until the observed replicas are recorded
+		{
+			policy: NilReplicasPolicyObserved,
+			demand: 8,
+			want:   2,
+		},
+		// spec.replicas takes precedence over the observed replicas
+		{
+			policy:   NilReplicasPolicyObserved,
+			replicas: intPtr(2),
+			observed: intPtr(4),
+			demand:   8,
+			want:     3,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			rd := &v1alpha1.RunnerDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "testrd",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.RunnerDeploymentSpec{
+					Template: v1alpha1.RunnerTemplate{
+						Spec: v1alpha1.RunnerSpec{
+							Repository: "test/valid",
+						},
+					},
+					Replicas: tc.replicas,
+				},
+				Status: v1alpha1.RunnerDeploymentStatus{
+					Replicas: tc.observed,
+				},
+			}
+
+			hra := newTestHRA(v1alpha1.HorizontalRunnerAutoscalerSpec{
+				MinReplicas:     intPtr(1),
+				MaxReplicas:     intPtr(10),
+				MaxScaleUpCount: intPtr(1),
+				Metrics:         []v1alpha1.MetricSpec{{Type: fakeMetricType}},
+			})
+
+			h := newTestReconciler(t, rd, hra)
+			h.NilReplicasPolicy = tc.policy
+			h.MetricProviders = map[string]MetricProviderFactory{
+				fakeMetricType: func(_ *github.Client, _ v1alpha1.MetricSpec) MetricProvider {
+					return &fakeMetricProvider{replicas: tc.demand}
+				},
+			}
+
+			reconcileTestHRA(t, h)
+
+			got := getTestRD(t, h)
+
+			if got.Spec.Replicas == nil || *got.Spec.Replicas != tc.want {
+				t.Errorf("incorrect desired replicas: want %d, got %v", tc.want, got.Spec.Replicas)
+			}
+		})
+	}
+}
+
+func TestParseNilReplicasPolicy(t *testing.T) {
+	for _, s := range []string{"MinReplicas", "Observed"} {
+		if got, err := ParseNilReplicasPolicy(s); err != nil {
+			t.Errorf("unexpected error parsing %q: %v", s, err)
+		} else if string(got) != s {
+			t.Errorf("unexpected policy: want %q, got %q", s, got)
+		}
+	}
+
+	if _, err := ParseNilReplicasPolicy("One"); err == nil {
+		t.Error("expected error parsing One, got none")
+	}
+}
@@ -0,0 +1,424 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// scalingInput is everything the scaling decision depends on, so that the decision can be made without a cluster nor GitHub API.
+type scalingInput struct {
+	// HRA is the HorizontalRunnerAutoscaler with the policy and the scheduled override applied.
+	HRA v1alpha1.HorizontalRunnerAutoscaler
+
+	// CurrentReplicas is the desired replicas the scale target currently has.
+	CurrentReplicas *int
+
+	// ReadyReplicas is the number of ready replicas of the scale target.
+	ReadyReplicas int
+
+	// Replicas is the desired replicas computed by the metrics, taken from the cache, or overridden by the annotation.
+	Replicas *int
+
+	// Metric is the winning metric, or nil when the replicas didn't come from the metrics.
+	Metric *metricResult
+
+	// Override is true when the replicas are overridden by the annotation.
+	Override bool
+
+	// Reservations are the capacity reservations in effect.
+	Reservations []v1alpha1.CapacityReservation
+
+	// SoftMaxReplicas is the soft max of the active scheduled override capping only the replicas computed by the metrics,
+	// or nil when there's none.
+	SoftMaxReplicas *int
+
+	Now time.Time
+}
+
+// scalingDecision is the desired replicas decided from the scalingInput, along with why it's decided so.
+type scalingDecision struct {
+	CurrentReplicas int
+	DesiredReplicas int
+
+	// ComputedReplicas is the input replicas before the reservations and the clamps are applied.
+	ComputedReplicas int
+
+	ReservedReplicas int
+
+	// UncappedReservedReplicas is the replicas reserved before MaxCapacityReservationReplicas is applied.
+	UncappedReservedReplicas int
+
+	// MinReplicas is MinReplicas raised by the ramp.
+	MinReplicas int
+
+	MaxReplicasApplied bool
+
+	// SoftMaxReplicasApplied is true when the replicas computed by the metrics are capped by the soft max of the scheduled override.
+	SoftMaxReplicasApplied bool
+
+	// MaxScaleUpCountApplied is true when the scale up is limited to MaxScaleUpCount replicas above the current replicas.
+	MaxScaleUpCountApplied bool
+
+	// UncappedDesiredReplicas is the desired replicas before MaxReplicas is applied, including the demand of the metrics beyond MaxReplicas.
+	UncappedDesiredReplicas int
+
+	// ScaleDownGated is true when the scale down is deferred by the ScaleDownReadinessGate.
+	ScaleDownGated bool
+
+	// ScaleDownGraceEnd is the end of the grace period deferring the scale down, or nil when nothing is deferred by it.
+	ScaleDownGraceEnd *time.Time
+
+	LastBusyTime          *metav1.Time
+	ContinuousDemandSince *metav1.Time
+	ScaleDownStalledSince *metav1.Time
+
+	// ForcedScaleDownStalledSince is the time since which the scale down forced by MaxScaleDownStallSeconds had been stalled,
+	// or nil when the scale down isn't forced.
+	ForcedScaleDownStalledSince *metav1.Time
+	ForcedScaleDownReplicas     int
+
+	// CachedReplicas is the replicas to cache in place of the input replicas, or nil to cache the input replicas as is.
+	CachedReplicas *int
+}
+
+// decideDesiredReplicas applies the capacity reservations, MinReplicas, MaxReplicas and all the delays deferring scale downs
+// to the input replicas. It's free of side effects other than logging, so that it can be used for simulating scaling decisions.
+func decideDesiredReplicas(log logr.Logger, in scalingInput) scalingDecision {
+	st := in.HRA
+	now := in.Now
+	reservations := in.Reservations
+
+	defaultReplicas := getDefaultReplicas(st)
+
+	currentDesiredReplicas := getIntOrDefault(in.CurrentReplicas, defaultReplicas)
+	newDesiredReplicas := getIntOrDefault(in.Replicas, defaultReplicas)
+
+	d := scalingDecision{CurrentReplicas: currentDesiredReplicas}
+
+	d.LastBusyTime = getLastBusyTime(st, in.Metric, now)
+
+	scaleDownGraceEnd := getScaleDownGraceEnd(st, d.LastBusyTime, now)
+
+	// The override pins the desired replicas as is, so that neither capacity reservations, MinReplicas nor
+	// the scale down stabilization affects it. MaxReplicas is still honored below.
+	d.ComputedReplicas = newDesiredReplicas
+
+	continuousDemandSince := st.Status.ContinuousDemandSince
+
+	if st.Spec.MinReplicasRamp == nil {
+		continuousDemandSince = nil
+	} else if !in.Override {
+		if d.ComputedReplicas > getIntOrDefault(st.Spec.MinReplicas, 0) {
+			if continuousDemandSince == nil {
+				continuousDemandSince = &metav1.Time{Time: now}
+			}
+		} else {
+			continuousDemandSince = nil
+		}
+	}
+
+	d.ContinuousDemandSince = continuousDemandSince
+
+	minReplicas := getRampedMinReplicas(st, continuousDemandSince, now)
+
+	d.MinReplicas = minReplicas
+
+	var reservedReplicas int
+
+	if !in.Override {
+		// The soft max caps only the demand of the metrics, so that the reservations added below can still exceed it
+		if soft := in.SoftMaxReplicas; soft != nil && newDesiredReplicas > *soft {
+			log.V(1).Info(
+				"Capping the replicas computed by the metrics by the soft max of the scheduled override",
+				"computed", newDesiredReplicas,
+				"softMaxReplicas", *soft,
+			)
+
+			newDesiredReplicas = *soft
+			d.SoftMaxReplicasApplied = true
+		}
+
+		// Likewise, the step limit applies only to the demand of the metrics, as the reservations represent committed work
+		if max := st.Spec.MaxScaleUpCount; max != nil && *max > 0 && newDesiredReplicas > currentDesiredReplicas+*max {
+			log.V(1).Info(
+				"Limiting the scale up by the max scale up count",
+				"current", currentDesiredReplicas,
+				"computed", newDesiredReplicas,
+				"maxScaleUpCount", *max,
+			)
+
+			newDesiredReplicas = currentDesiredReplicas + *max
+			d.MaxScaleUpCountApplied = true
+		}
+
+		reservedReplicas = getCapacityReservationReplicas(reservations)
+
+		d.UncappedReservedReplicas = reservedReplicas
+
+		if max := st.Spec.MaxCapacityReservationReplicas; max != nil && reservedReplicas > *max {
+			reservedReplicas = *max
+		}
+
+		newDesiredReplicas += reservedReplicas
+
+		// MinReplicas is applied as a floor regardless of where the desired replicas came from,
+		// so that e.g. a cached value computed before MinReplicas was raised never results in scaling below it.
+		// It's raised by the ramp while the demand continues.
+		if newDesiredReplicas < minReplicas {
+			newDesiredReplicas = minReplicas
+		}
+
+		// Drain gradually by removing at most MaxScaleDownCount replicas per reconciliation.
+		// MaxReplicas is still honored as a hard limit below.
+		if s := st.Spec.ScaleDownStabilization; s != nil && s.MaxScaleDownCount != nil && *s.MaxScaleDownCount > 0 &&
+			newDesiredReplicas < currentDesiredReplicas-*s.MaxScaleDownCount {
+
+			newDesiredReplicas = currentDesiredReplicas - *s.MaxScaleDownCount
+		}
+
+		if scaleDownGraceEnd != nil && newDesiredReplicas < currentDesiredReplicas {
+			log.V(1).Info(
+				"Deferring scale down until the grace period since the busy runners dropped elapses",
+				"current", currentDesiredReplicas,
+				"desired", newDesiredReplicas,
+				"until", scaleDownGraceEnd.Format(time.RFC3339),
+			)
+
+			newDesiredReplicas = currentDesiredReplicas
+		} else {
+			// Don't requeue for the grace period when it doesn't defer anything
+			scaleDownGraceEnd = nil
+		}
+
+		if st.Spec.ScaleDownReadinessGate && newDesiredReplicas < currentDesiredReplicas && in.ReadyReplicas != currentDesiredReplicas {
+			log.V(1).Info(
+				"Deferring scale down until the runnerdeployment stabilizes",
+				"ready", in.ReadyReplicas,
+				"current", currentDesiredReplicas,
+				"desired", newDesiredReplicas,
+			)
+
+			newDesiredReplicas = currentDesiredReplicas
+			d.ScaleDownGated = true
+		}
+
+		// Capacity reservations represent concrete demand, so a change is never ignored while any of them is active.
+		// Neither is the one required to satisfy MinReplicas and MaxReplicas.
+		if len(reservations) == 0 &&
+			withinTolerance(currentDesiredReplicas, newDesiredReplicas, st.Spec.TolerancePercent) &&
+			currentDesiredReplicas >= minReplicas &&
+			(st.Spec.MaxReplicas == nil || currentDesiredReplicas <= *st.Spec.MaxReplicas) {
+
+			if newDesiredReplicas != currentDesiredReplicas {
+				log.V(1).Info(
+					"Ignoring the change of desired replicas within the tolerance",
+					"current", currentDesiredReplicas,
+					"desired", newDesiredReplicas,
+					"tolerancePercent", st.Spec.TolerancePercent,
+				)
+			}
+
+			newDesiredReplicas = currentDesiredReplicas
+		}
+	}
+
+	d.ReservedReplicas = reservedReplicas
+	d.ScaleDownGraceEnd = scaleDownGraceEnd
+
+	scaleDownStalledSince := st.Status.ScaleDownStalledSince
+
+	if s := st.Spec.MaxScaleDownStallSeconds; s == nil || *s <= 0 {
+		scaleDownStalledSince = nil
+	} else if !in.Override && in.Metric != nil {
+		// The replicas computed by the metrics before the scale-down delay, plus the reservations, is what we'd scale down to
+		// if nothing deferred the scale down.
+		lower := in.Metric.Replicas
+		if soft := in.SoftMaxReplicas; soft != nil && lower > *soft {
+			lower = *soft
+		}
+
+		lower += reservedReplicas
+		if lower < minReplicas {
+			lower = minReplicas
+		}
+
+		if lower >= currentDesiredReplicas || newDesiredReplicas < currentDesiredReplicas {
+			scaleDownStalledSince = nil
+		} else {
+			if scaleDownStalledSince == nil {
+				scaleDownStalledSince = &metav1.Time{Time: now}
+			}
+
+			if deadline := getScaleDownStallDeadline(st, scaleDownStalledSince); !deadline.After(now) {
+				d.ForcedScaleDownStalledSince = scaleDownStalledSince
+				d.ForcedScaleDownReplicas = lower
+
+				newDesiredReplicas = lower
+				scaleDownStalledSince = nil
+
+				// Cache the replicas computed by the metrics rather than the deferred ones,
+				// so that the next reconciliation doesn't scale it back up from the cache.
+				d.CachedReplicas = &in.Metric.Replicas
+			}
+		}
+	}
+
+	d.ScaleDownStalledSince = scaleDownStalledSince
+
+	// The metrics cap their replicas by MaxReplicas on their own, so their actual demand is added back here
+	d.UncappedDesiredReplicas = newDesiredReplicas
+	if in.Metric != nil && !in.Override {
+		if demand := in.Metric.uncappedReplicas() + reservedReplicas; demand > d.UncappedDesiredReplicas {
+			d.UncappedDesiredReplicas = demand
+		}
+	}
+
+	if st.Spec.MaxReplicas != nil && *st.Spec.MaxReplicas < newDesiredReplicas {
+		newDesiredReplicas = *st.Spec.MaxReplicas
+		d.MaxReplicasApplied = true
+	}
+
+	d.DesiredReplicas = newDesiredReplicas
+
+	return d
+}
+
+// applyScaleDelays returns the replicas computed by the metric, deferred by ScaleDownDelaySecondsAfterScaleUp, or decayed by
+// ScaleDownDecayHalfLifeSeconds, stabilized by ScaleDownStabilizationWindowSeconds, and deferred by ScaleUpDelaySeconds
+// since the last desired replicas recorded in the status.
+func applyScaleDelays(hra v1alpha1.HorizontalRunnerAutoscaler, result *metricResult, now time.Time) *int {
+	var computedReplicas *int
+
+	replicas := &result.Replicas
+
+	var scaleDownDelay time.Duration
+
+	if hra.Spec.ScaleDownDelaySecondsAfterScaleUp != nil {
+		scaleDownDelay = time.Duration(*hra.Spec.ScaleDownDelaySecondsAfterScaleUp) * time.Second
+	} else {
+		scaleDownDelay = DefaultScaleDownDelay
+	}
+
+	scaleDownDelayAnchor := hra.Status.LastSuccessfulScaleOutTime
+	if hra.Spec.ScaleDownDelayAnchor == v1alpha1.ScaleDownDelayAnchorLastBusy {
+		scaleDownDelayAnchor = getLastBusyTime(hra, result, now)
+	}
+
+	if decayed, ok := getScaleDownDecayReplicas(hra, *replicas, now); ok {
+		computedReplicas = &decayed
+	} else if getScaleDownDecayHalfLife(hra) > 0 ||
+		hra.Status.DesiredReplicas == nil ||
+		*hra.Status.DesiredReplicas < *replicas ||
+		scaleDownDelayAnchor == nil ||
+		scaleDownDelayAnchor.Add(scaleDownDelay).Before(now) {
+
+		computedReplicas = replicas
+	} else {
+		computedReplicas = hra.Status.DesiredReplicas
+	}
+
+	// The scale down goes no lower than the largest recommendation within the stabilization window
+	if stabilized, ok := getScaleDownStabilizedReplicas(hra, *computedReplicas, now); ok {
+		computedReplicas = &stabilized
+	}
+
+	// Defer the scale up until the scale-up delay elapses.
+	// Capacity reservations are added afterwards by the caller so that they can bypass the delay.
+	if hra.Status.DesiredReplicas != nil &&
+		*hra.Status.DesiredReplicas < *computedReplicas &&
+		getScaleUpDelayEnd(hra, now) != nil {
+
+		computedReplicas = hra.Status.DesiredReplicas
+	}
+
+	return computedReplicas
+}
+
+// ScalingSimulation is the scaling decision made by SimulateScaling.
+type ScalingSimulation struct {
+	// DesiredReplicas is the desired replicas the scale target would be scaled to.
+	DesiredReplicas int
+
+	// ComputedReplicas is the metric replicas after the scale-down and scale-up delays are applied.
+	ComputedReplicas int
+
+	// ReservedReplicas is the replicas added by the capacity reservations.
+	ReservedReplicas int
+
+	// MinReplicas is MinReplicas in effect, which may be raised by the ramp.
+	MinReplicas int
+
+	// MaxReplicasApplied is true when the desired replicas are capped by MaxReplicas.
+	MaxReplicasApplied bool
+
+	// SoftMaxReplicasApplied is true when the replicas computed by the metrics are capped by the soft max of the scheduled override.
+	SoftMaxReplicasApplied bool
+
+	// MaxScaleUpCountApplied is true when the scale up is limited by MaxScaleUpCount.
+	MaxScaleUpCountApplied bool
+}
+
+// SimulateScaling decides the desired replicas of the RunnerDeployment the same way as the controller does at the current time,
+// with metricReplicas in place of the replicas computed by the metrics, so that scaling decisions can be simulated offline
+// e.g. for capacity planning. busyRunners is the number of busy runners observed by the metric, or nil when it's unknown.
+// Both are ignored in the EventsOnly scale mode, as the controller doesn't poll the metrics then.
+// nilReplicasPolicy is the NilReplicasPolicy of the controller, or empty for DefaultNilReplicasPolicy.
+//
+// The cached desired replicas, the policy ConfigMap, the capacity reservations held while busy, the pending runner pods, and the global budget
+// aren't simulated, as they depend on the cluster or GitHub API.
+func SimulateScaling(log logr.Logger, hra v1alpha1.HorizontalRunnerAutoscaler, rd v1alpha1.RunnerDeployment, nilReplicasPolicy NilReplicasPolicy, metricReplicas int, busyRunners *int) (*ScalingSimulation, error) {
+	now := time.Now()
+
+	r := &HorizontalRunnerAutoscalerReconciler{Log: log, NilReplicasPolicy: nilReplicasPolicy}
+
+	override, _, _, err := r.matchScheduledOverrides(log, now, hra)
+	if err != nil {
+		return nil, err
+	}
+
+	st := withScheduledOverride(hra, override)
+
+	currentReplicas := r.getCurrentReplicas(rd)
+
+	st = withBurstMaxReplicas(st, accountBurstCredits(st, getIntOrDefault(currentReplicas, getDefaultReplicas(st)), now))
+
+	replicasOverride, err := getDesiredReplicasOverride(hra)
+	if err != nil {
+		return nil, err
+	}
+
+	in := scalingInput{
+		HRA:             st,
+		CurrentReplicas: currentReplicas,
+		ReadyReplicas:   rd.Status.ReadyReplicas,
+		Override:        replicasOverride != nil,
+		Reservations:    getValidCapacityReservations(&st),
+		SoftMaxReplicas: getSoftMaxReplicas(override),
+		Now:             now,
+	}
+
+	if replicasOverride != nil {
+		in.Replicas = replicasOverride
+	} else if isEventsOnly(st) {
+		in.Metric = getEventsOnlyReplicas(st)
+		in.Replicas = &in.Metric.Replicas
+	} else {
+		in.Metric = &metricResult{Replicas: metricReplicas, BusyRunners: busyRunners}
+		in.Replicas = applyScaleDelays(st, in.Metric, now)
+	}
+
+	d := decideDesiredReplicas(log, in)
+
+	return &ScalingSimulation{
+		DesiredReplicas:        d.DesiredReplicas,
+		ComputedReplicas:       d.ComputedReplicas,
+		ReservedReplicas:       d.ReservedReplicas,
+		MinReplicas:            d.MinReplicas,
+		MaxReplicasApplied:     d.MaxReplicasApplied,
+		SoftMaxReplicasApplied: d.SoftMaxReplicasApplied,
+		MaxScaleUpCountApplied: d.MaxScaleUpCountApplied,
+	}, nil
+}
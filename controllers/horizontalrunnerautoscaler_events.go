@@ -0,0 +1,53 @@
+package controllers
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// EventVerbosity controls which events the HorizontalRunnerAutoscalerReconciler emits,
+// so that the events of a large fleet don't overwhelm the events API.
+type EventVerbosity string
+
+const (
+	// EventVerbosityOff emits no events.
+	EventVerbosityOff EventVerbosity = "Off"
+
+	// EventVerbosityChanges emits the events on scaling the scale targets, along with the ones on the other changes
+	// and the problems worth noticing, like a scheduled override becoming active or a missing scale target.
+	EventVerbosityChanges EventVerbosity = "Changes"
+
+	// EventVerbosityAll also emits an event on every scaling decision, including the ones leaving the replicas as is.
+	EventVerbosityAll EventVerbosity = "All"
+
+	// DefaultEventVerbosity is the EventVerbosity used when none is set.
+	DefaultEventVerbosity = EventVerbosityChanges
+)
+
+// ParseEventVerbosity returns the EventVerbosity named s, or an error if s isn't one of Off, Changes, and All.
+func ParseEventVerbosity(s string) (EventVerbosity, error) {
+	switch v := EventVerbosity(s); v {
+	case EventVerbosityOff, EventVerbosityChanges, EventVerbosityAll:
+		return v, nil
+	}
+
+	return "", fmt.Errorf("invalid event verbosity %q: must be one of %s, %s, and %s", s, EventVerbosityOff, EventVerbosityChanges, EventVerbosityAll)
+}
+
+func (r *HorizontalRunnerAutoscalerReconciler) getEventVerbosity() EventVerbosity {
+	if r.EventVerbosity == "" {
+		return DefaultEventVerbosity
+	}
+
+	return r.EventVerbosity
+}
+
+// event records the event unless the events are turned off by EventVerbosity.
+func (r *HorizontalRunnerAutoscalerReconciler) event(object runtime.Object, eventtype, reason, message string) {
+	if r.getEventVerbosity() == EventVerbosityOff {
+		return
+	}
+
+	r.Recorder.Event(object, eventtype, reason, message)
+}
@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// AnnotationKeyIdleRunners is the annotation on a RunnerDeployment, and the RunnerReplicaSet it propagates to, listing the comma-separated names
+// of the runners that GitHub reported not busy when the HorizontalRunnerAutoscaler scaled it down, so that they're deleted before the others.
+// It's only a best-effort hint, as a runner can pick up a job right after the listing.
+// The RunnerReplicaSet controller still checks each runner is not busy before deleting it.
+const AnnotationKeyIdleRunners = "actions.summerwind.dev/idle-runners"
+
+// getIdleRunners returns the sorted names of the runners of the RunnerDeployment that GitHub reports not busy.
+func (r *HorizontalRunnerAutoscalerReconciler) getIdleRunners(ctx context.Context, hra v1alpha1.HorizontalRunnerAutoscaler, rd v1alpha1.RunnerDeployment) ([]string, error) {
+	var rsList v1alpha1.RunnerReplicaSetList
+	if err := r.List(ctx, &rsList, client.InNamespace(rd.Namespace)); err != nil {
+		return nil, err
+	}
+
+	var runnerList v1alpha1.RunnerList
+	if err := r.List(ctx, &runnerList, client.InNamespace(rd.Namespace)); err != nil {
+		return nil, err
+	}
+
+	mine := map[string]bool{}
+
+	for i := range rsList.Items {
+		rs := &rsList.Items[i]
+
+		if !metav1.IsControlledBy(rs, &rd) {
+			continue
+		}
+
+		for j := range runnerList.Items {
+			if runner := &runnerList.Items[j]; metav1.IsControlledBy(runner, rs) {
+				mine[runner.Name] = true
+			}
+		}
+	}
+
+	if len(mine) == 0 {
+		return nil, nil
+	}
+
+	ghc, err := r.getGitHubClient(ctx, hra)
+	if err != nil {
+		return nil, err
+	}
+
+	enterprise, organization, repository, err := getScaleTargetScope(rd)
+	if err != nil {
+		return nil, err
+	}
+
+	runners, err := r.listRunners(ctx, ghc, enterprise, organization, repository)
+	if err != nil {
+		return nil, err
+	}
+
+	var idle []string
+
+	for _, runner := range runners {
+		if mine[runner.GetName()] && !runner.GetBusy() {
+			idle = append(idle, runner.GetName())
+		}
+	}
+
+	sort.Strings(idle)
+
+	return idle, nil
+}
+
+// applyIdleRunnersHint annotates the RunnerDeployment being scaled from currentReplicas to desiredReplicas
+// with the idle runners to be deleted first on scaling down, or removes the stale annotation otherwise.
+// A failure to list the runners only results in no hint, as the RunnerReplicaSet controller never deletes busy runners anyway.
+func (r *HorizontalRunnerAutoscalerReconciler) applyIdleRunnersHint(ctx context.Context, log logr.Logger, hra v1alpha1.HorizontalRunnerAutoscaler, rd *v1alpha1.RunnerDeployment, currentReplicas, desiredReplicas int) {
+	var idle []string
+
+	if desiredReplicas < currentReplicas {
+		ctx, cancel := context.WithTimeout(r.withGitHubAPIBudgetAccount(ctx, hra, *rd), r.metricTimeout())
+		defer cancel()
+
+		var err error
+
+		idle, err = r.getIdleRunners(ctx, hra, *rd)
+		if err != nil {
+			log.Error(err, "Could not list the idle runners to hint the scale down of runnerdeployment", "runnerdeployment", rd.Name)
+		}
+	}
+
+	if len(idle) == 0 {
+		delete(rd.Annotations, AnnotationKeyIdleRunners)
+
+		return
+	}
+
+	if rd.Annotations == nil {
+		rd.Annotations = map[string]string{}
+	}
+
+	rd.Annotations[AnnotationKeyIdleRunners] = strings.Join(idle, ",")
+
+	log.V(1).Info("Hinting the idle runners to be deleted first on scaling down runnerdeployment", "runnerdeployment", rd.Name, "idleRunners", idle)
+}
+
+// sortRunnersByIdleHint moves the runners listed in the AnnotationKeyIdleRunners annotation of the RunnerReplicaSet
+// to the front, keeping the order of the rest, so that the hinted ones are checked and deleted first on scaling down.
+func sortRunnersByIdleHint(rs v1alpha1.RunnerReplicaSet, runners []v1alpha1.Runner) {
+	hint, ok := rs.Annotations[AnnotationKeyIdleRunners]
+	if !ok {
+		return
+	}
+
+	idle := map[string]bool{}
+	for _, name := range strings.Split(hint, ",") {
+		idle[name] = true
+	}
+
+	sort.SliceStable(runners, func(i, j int) bool {
+		return idle[runners[i].Name] && !idle[runners[j].Name]
+	})
+}
@@ -0,0 +1,202 @@
+package controllers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	hraMetricLabelNamespace = "namespace"
+	hraMetricLabelName      = "horizontalrunnerautoscaler"
+)
+
+const (
+	githubAPICallMetricLabelEndpoint = "endpoint"
+	githubAPICallMetricLabelResult   = "result"
+
+	githubAPICallEndpointListRepositoryWorkflowRuns = "ListRepositoryWorkflowRuns"
+	githubAPICallEndpointListWorkflowJobs           = "ListWorkflowJobs"
+	githubAPICallEndpointListCompletedWorkflowRuns  = "ListCompletedWorkflowRuns"
+	githubAPICallEndpointListRunners                = "ListRunners"
+	githubAPICallEndpointListRunnerGroupRunners     = "ListRunnerGroupRunners"
+	githubAPICallEndpointListRepositoryEvents       = "ListRepositoryEvents"
+	githubAPICallEndpointListWorkflowRunsGraphQL    = "ListWorkflowRunsGraphQL"
+
+	githubAPICallResultSuccess     = "success"
+	githubAPICallResultError       = "error"
+	githubAPICallResultRateLimited = "rate_limited"
+)
+
+const (
+	githubAPICircuitBreakerMetricLabelState = "state"
+)
+
+var (
+	hraMetricLabels = []string{hraMetricLabelNamespace, hraMetricLabelName}
+
+	githubAPICallMetricLabels = []string{githubAPICallMetricLabelEndpoint, githubAPICallMetricLabelResult}
+
+	metricHRADesiredReplicas = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "horizontalrunnerautoscaler_desired_replicas",
+			Help: "The number of desired replicas of the scale target, including capacity reservations",
+		},
+		hraMetricLabels,
+	)
+	metricHRACurrentReplicas = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "horizontalrunnerautoscaler_current_replicas",
+			Help: "The number of available replicas of the scale target",
+		},
+		hraMetricLabels,
+	)
+	metricHRASecondsSinceLastSuccessfulScaleOut = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "horizontalrunnerautoscaler_seconds_since_last_successful_scale_out",
+			Help: "The number of seconds elapsed since the last successful scale out, as of the last reconciliation",
+		},
+		hraMetricLabels,
+	)
+	metricHRACacheHits = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "horizontalrunnerautoscaler_cache_hits_total",
+			Help: "The number of reconciliations that used the cached desired replicas",
+		},
+		hraMetricLabels,
+	)
+	metricHRACacheMisses = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "horizontalrunnerautoscaler_cache_misses_total",
+			Help: "The number of reconciliations that computed the desired replicas due to no valid cache entry",
+		},
+		hraMetricLabels,
+	)
+
+	metricGitHubAPICalls = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "horizontalrunnerautoscaler_github_api_calls_total",
+			Help: "The number of GitHub API calls made for computing the desired replicas, by endpoint and result",
+		},
+		githubAPICallMetricLabels,
+	)
+	metricGitHubAPICallDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "horizontalrunnerautoscaler_github_api_call_duration_seconds",
+			Help:    "The latency of GitHub API calls made for computing the desired replicas, by endpoint and result",
+			Buckets: prometheus.DefBuckets,
+		},
+		githubAPICallMetricLabels,
+	)
+	metricGitHubAPIBudgetConsumedCalls = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "horizontalrunnerautoscaler_github_api_budget_consumed_calls_total",
+			Help: "The number of GitHub API calls made for computing the desired replicas of each HorizontalRunnerAutoscaler, charged to its GitHub API budget when enabled",
+		},
+		hraMetricLabels,
+	)
+	metricGitHubAPICircuitBreakerState = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "horizontalrunnerautoscaler_github_api_circuit_breaker_state",
+			Help: "The state of the circuit breaker skipping GitHub API calls during an outage, which is 1 for the current state and 0 for the others",
+		},
+		[]string{githubAPICircuitBreakerMetricLabelState},
+	)
+
+	registerHRAMetricsOnce sync.Once
+)
+
+// registerHorizontalRunnerAutoscalerMetrics registers the metrics to the controller-runtime metrics registry.
+// It can safely be called more than once, e.g. when two or more managers are set up in the same process.
+func registerHorizontalRunnerAutoscalerMetrics() {
+	registerHRAMetricsOnce.Do(func() {
+		metrics.Registry.MustRegister(
+			metricHRADesiredReplicas,
+			metricHRACurrentReplicas,
+			metricHRASecondsSinceLastSuccessfulScaleOut,
+			metricHRACacheHits,
+			metricHRACacheMisses,
+			metricGitHubAPICalls,
+			metricGitHubAPICallDuration,
+			metricGitHubAPIBudgetConsumedCalls,
+			metricGitHubAPICircuitBreakerState,
+		)
+	})
+}
+
+func observeHorizontalRunnerAutoscaler(hra v1alpha1.HorizontalRunnerAutoscaler, rd v1alpha1.RunnerDeployment, now time.Time) {
+	labels := prometheus.Labels{
+		hraMetricLabelNamespace: hra.Namespace,
+		hraMetricLabelName:      hra.Name,
+	}
+
+	if hra.Status.DesiredReplicas != nil {
+		metricHRADesiredReplicas.With(labels).Set(float64(*hra.Status.DesiredReplicas))
+	}
+
+	metricHRACurrentReplicas.With(labels).Set(float64(rd.Status.AvailableReplicas))
+
+	if hra.Status.LastSuccessfulScaleOutTime != nil {
+		metricHRASecondsSinceLastSuccessfulScaleOut.With(labels).Set(now.Sub(hra.Status.LastSuccessfulScaleOutTime.Time).Seconds())
+	}
+}
+
+func observeHorizontalRunnerAutoscalerCache(namespace, name string, hit bool) {
+	if hit {
+		metricHRACacheHits.WithLabelValues(namespace, name).Inc()
+	} else {
+		metricHRACacheMisses.WithLabelValues(namespace, name).Inc()
+	}
+}
+
+// observeGitHubAPICall records the result and the latency of the GitHub API call that started at start,
+// and charges it to the GitHub API budget of the HorizontalRunnerAutoscaler the context is made for.
+// It's called only when the desired replicas are actually computed, so the reconciliations that used the cache aren't counted.
+func observeGitHubAPICall(ctx context.Context, endpoint string, start time.Time, err error) {
+	chargeGitHubAPICall(ctx, start)
+
+	result := githubAPICallResultSuccess
+
+	if err != nil {
+		if getRateLimitResetTime(err, start) != nil {
+			result = githubAPICallResultRateLimited
+		} else {
+			result = githubAPICallResultError
+		}
+	}
+
+	metricGitHubAPICalls.WithLabelValues(endpoint, result).Inc()
+	metricGitHubAPICallDuration.WithLabelValues(endpoint, result).Observe(time.Since(start).Seconds())
+}
+
+func observeGitHubAPICircuitBreakerState(state GitHubAPICircuitBreakerState) {
+	for _, s := range []GitHubAPICircuitBreakerState{GitHubAPICircuitBreakerClosed, GitHubAPICircuitBreakerOpen, GitHubAPICircuitBreakerHalfOpen} {
+		var v float64
+		if s == state {
+			v = 1
+		}
+
+		metricGitHubAPICircuitBreakerState.WithLabelValues(string(s)).Set(v)
+	}
+}
+
+// deleteHorizontalRunnerAutoscalerMetrics removes all the series for the HorizontalRunnerAutoscaler, so that
+// stale series don't linger after its deletion.
+func deleteHorizontalRunnerAutoscalerMetrics(namespace, name string) {
+	for _, c := range []interface {
+		DeleteLabelValues(...string) bool
+	}{
+		metricHRADesiredReplicas,
+		metricHRACurrentReplicas,
+		metricHRASecondsSinceLastSuccessfulScaleOut,
+		metricHRACacheHits,
+		metricHRACacheMisses,
+		metricGitHubAPIBudgetConsumedCalls,
+	} {
+		c.DeleteLabelValues(namespace, name)
+	}
+}
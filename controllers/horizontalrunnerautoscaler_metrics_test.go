@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v33/github"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestHorizontalRunnerAutoscalerMetrics(t *testing.T) {
+	now := time.Now()
+
+	hra := v1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "testhra-metrics",
+		},
+		Status: v1alpha1.HorizontalRunnerAutoscalerStatus{
+			DesiredReplicas:            intPtr(3),
+			LastSuccessfulScaleOutTime: &metav1.Time{Time: now.Add(-time.Minute)},
+		},
+	}
+
+	rd := v1alpha1.RunnerDeployment{
+		Status: v1alpha1.RunnerDeploymentStatus{
+			AvailableReplicas: 2,
+		},
+	}
+
+	observeHorizontalRunnerAutoscaler(hra, rd, now)
+	observeHorizontalRunnerAutoscalerCache(hra.Namespace, hra.Name, true)
+	observeHorizontalRunnerAutoscalerCache(hra.Namespace, hra.Name, false)
+	observeHorizontalRunnerAutoscalerCache(hra.Namespace, hra.Name, false)
+
+	if got := testutil.ToFloat64(metricHRADesiredReplicas.WithLabelValues("default", "testhra-metrics")); got != 3 {
+		t.Errorf("unexpected desired replicas: want 3, got %v", got)
+	}
+
+	if got := testutil.ToFloat64(metricHRACurrentReplicas.WithLabelValues("default", "testhra-metrics")); got != 2 {
+		t.Errorf("unexpected current replicas: want 2, got %v", got)
+	}
+
+	if got := testutil.ToFloat64(metricHRASecondsSinceLastSuccessfulScaleOut.WithLabelValues("default", "testhra-metrics")); got != 60 {
+		t.Errorf("unexpected seconds since last successful scale out: want 60, got %v", got)
+	}
+
+	if got := testutil.ToFloat64(metricHRACacheHits.WithLabelValues("default", "testhra-metrics")); got != 1 {
+		t.Errorf("unexpected cache hits: want 1, got %v", got)
+	}
+
+	if got := testutil.ToFloat64(metricHRACacheMisses.WithLabelValues("default", "testhra-metrics")); got != 2 {
+		t.Errorf("unexpected cache misses: want 2, got %v", got)
+	}
+
+	deleteHorizontalRunnerAutoscalerMetrics(hra.Namespace, hra.Name)
+
+	if metricHRADesiredReplicas.DeleteLabelValues("default", "testhra-metrics") || metricHRACacheMisses.DeleteLabelValues("default", "testhra-metrics") {
+		t.Errorf("series for the deleted horizontalrunnerautoscaler still exist")
+	}
+}
+
+func TestObserveGitHubAPICall(t *testing.T) {
+	start := time.Now()
+
+	observeGitHubAPICall(context.Background(), "TestEndpoint", start, nil)
+	observeGitHubAPICall(context.Background(), "TestEndpoint", start, nil)
+	observeGitHubAPICall(context.Background(), "TestEndpoint", start, errors.New("internal server error"))
+	observeGitHubAPICall(context.Background(), "TestEndpoint", start, &github.RateLimitError{Rate: github.Rate{Reset: github.Timestamp{Time: start.Add(time.Minute)}}})
+
+	for result, want := range map[string]float64{
+		githubAPICallResultSuccess:     2,
+		githubAPICallResultError:       1,
+		githubAPICallResultRateLimited: 1,
+	} {
+		if got := testutil.ToFloat64(metricGitHubAPICalls.WithLabelValues("TestEndpoint", result)); got != want {
+			t.Errorf("unexpected %s calls: want %v, got %v", result, want, got)
+		}
+	}
+}
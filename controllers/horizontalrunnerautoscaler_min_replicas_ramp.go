@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// getRampedMinReplicas returns MinReplicas raised by one step of the ramp per StepSeconds elapsed since demandSince,
+// up to the MaxReplicas of the ramp. It returns MinReplicas as is when the ramp is disabled or there's no continuous demand.
+func getRampedMinReplicas(hra v1alpha1.HorizontalRunnerAutoscaler, demandSince *metav1.Time, now time.Time) int {
+	minReplicas := getIntOrDefault(hra.Spec.MinReplicas, 0)
+
+	ramp := hra.Spec.MinReplicasRamp
+	if ramp == nil || ramp.StepSeconds <= 0 || demandSince == nil {
+		return minReplicas
+	}
+
+	steps := int(now.Sub(demandSince.Time) / (time.Duration(ramp.StepSeconds) * time.Second))
+
+	ramped := minReplicas + steps*getIntOrDefault(ramp.StepReplicas, 1)
+	if ramped > ramp.MaxReplicas {
+		ramped = ramp.MaxReplicas
+	}
+
+	if ramped < minReplicas {
+		return minReplicas
+	}
+
+	return ramped
+}
+
+// getNextMinReplicasRampStep returns the time the ramp raises the minimum replicas next,
+// or nil when there's no continuous demand or the ramp has already reached its MaxReplicas.
+func getNextMinReplicasRampStep(hra v1alpha1.HorizontalRunnerAutoscaler, demandSince *metav1.Time, now time.Time) *time.Time {
+	ramp := hra.Spec.MinReplicasRamp
+	if ramp == nil || ramp.StepSeconds <= 0 || demandSince == nil {
+		return nil
+	}
+
+	if getRampedMinReplicas(hra, demandSince, now) >= ramp.MaxReplicas {
+		return nil
+	}
+
+	step := time.Duration(ramp.StepSeconds) * time.Second
+
+	next := demandSince.Add((now.Sub(demandSince.Time)/step + 1) * step)
+
+	return &next
+}
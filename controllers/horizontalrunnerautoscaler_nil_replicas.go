@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+)
+
+// NilReplicasPolicy controls the current replicas the HorizontalRunnerAutoscalerReconciler assumes
+// for the scale target whose spec.replicas is nil, e.g. the RunnerDeployment just created without it.
+type NilReplicasPolicy string
+
+const (
+	// NilReplicasPolicyMinReplicas assumes MinReplicas, or 1 when MinReplicas isn't set either.
+	NilReplicasPolicyMinReplicas NilReplicasPolicy = "MinReplicas"
+
+	// NilReplicasPolicyObserved adopts the replicas the RunnerDeployment controller observed and recorded
+	// in status.desiredReplicas, so that taking over an existing RunnerDeployment doesn't scale it unexpectedly.
+	// It falls back to NilReplicasPolicyMinReplicas until the status is recorded.
+	NilReplicasPolicyObserved NilReplicasPolicy = "Observed"
+
+	// DefaultNilReplicasPolicy is the NilReplicasPolicy used when none is set.
+	DefaultNilReplicasPolicy = NilReplicasPolicyMinReplicas
+)
+
+// ParseNilReplicasPolicy returns the NilReplicasPolicy named s, or an error if s isn't one of MinReplicas and Observed.
+func ParseNilReplicasPolicy(s string) (NilReplicasPolicy, error) {
+	switch p := NilReplicasPolicy(s); p {
+	case NilReplicasPolicyMinReplicas, NilReplicasPolicyObserved:
+		return p, nil
+	}
+
+	return "", fmt.Errorf("invalid nil replicas policy %q: must be one of %s and %s", s, NilReplicasPolicyMinReplicas, NilReplicasPolicyObserved)
+}
+
+func (r *HorizontalRunnerAutoscalerReconciler) getNilReplicasPolicy() NilReplicasPolicy {
+	if r.NilReplicasPolicy == "" {
+		return DefaultNilReplicasPolicy
+	}
+
+	return r.NilReplicasPolicy
+}
+
+// getCurrentReplicas returns the current replicas of the RunnerDeployment according to the NilReplicasPolicy,
+// or nil to let the callers assume getDefaultReplicas.
+func (r *HorizontalRunnerAutoscalerReconciler) getCurrentReplicas(rd v1alpha1.RunnerDeployment) *int {
+	if rd.Spec.Replicas != nil {
+		return rd.Spec.Replicas
+	}
+
+	if r.getNilReplicasPolicy() == NilReplicasPolicyObserved && rd.Status.Replicas != nil {
+		observed := *rd.Status.Replicas
+
+		return &observed
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// countPendingRunnerPods returns the number of Pending pods of the runners managed by the RunnerDeployment,
+// following the owner references from the RunnerDeployment to its runnerreplicasets, runners, and pods.
+func (r *HorizontalRunnerAutoscalerReconciler) countPendingRunnerPods(ctx context.Context, rd v1alpha1.RunnerDeployment) (int, error) {
+	var rsList v1alpha1.RunnerReplicaSetList
+	if err := r.List(ctx, &rsList, client.InNamespace(rd.Namespace)); err != nil {
+		return 0, err
+	}
+
+	replicaSets := map[types.UID]bool{}
+	for i := range rsList.Items {
+		if metav1.IsControlledBy(&rsList.Items[i], &rd) {
+			replicaSets[rsList.Items[i].UID] = true
+		}
+	}
+
+	if len(replicaSets) == 0 {
+		return 0, nil
+	}
+
+	var runnerList v1alpha1.RunnerList
+	if err := r.List(ctx, &runnerList, client.InNamespace(rd.Namespace)); err != nil {
+		return 0, err
+	}
+
+	runners := map[types.UID]bool{}
+	for _, runner := range runnerList.Items {
+		if ref := metav1.GetControllerOf(&runner); ref != nil && replicaSets[ref.UID] {
+			runners[runner.UID] = true
+		}
+	}
+
+	if len(runners) == 0 {
+		return 0, nil
+	}
+
+	var podList corev1.PodList
+	if err := r.List(ctx, &podList, client.InNamespace(rd.Namespace)); err != nil {
+		return 0, err
+	}
+
+	var pending int
+	for _, pod := range podList.Items {
+		if ref := metav1.GetControllerOf(&pod); ref != nil && runners[ref.UID] && pod.Status.Phase == corev1.PodPending {
+			pending++
+		}
+	}
+
+	return pending, nil
+}
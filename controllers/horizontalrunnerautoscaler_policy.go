@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	policyRefKey = "spec.policyRef.name"
+
+	policyKeyMinReplicas           = "minReplicas"
+	policyKeyMaxReplicas           = "maxReplicas"
+	policyKeyScaleDownDelaySeconds = "scaleDownDelaySeconds"
+)
+
+// getPolicy returns the ConfigMap referenced by the HorizontalRunnerAutoscaler's PolicyRef, or nil when
+// it has no PolicyRef or the ConfigMap doesn't exist.
+func (r *HorizontalRunnerAutoscalerReconciler) getPolicy(ctx context.Context, hra v1alpha1.HorizontalRunnerAutoscaler) (*corev1.ConfigMap, error) {
+	if hra.Spec.PolicyRef == nil || hra.Spec.PolicyRef.Name == "" {
+		return nil, nil
+	}
+
+	var cm corev1.ConfigMap
+
+	if err := r.Get(ctx, types.NamespacedName{Namespace: hra.Namespace, Name: hra.Spec.PolicyRef.Name}, &cm); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+
+	return &cm, nil
+}
+
+// withPolicy returns a copy of the HorizontalRunnerAutoscaler whose spec is overridden by the policy ConfigMap.
+// The HorizontalRunnerAutoscaler is returned as is when any of the values is invalid, so that
+// a broken policy never results in a partially applied one.
+func withPolicy(hra v1alpha1.HorizontalRunnerAutoscaler, cm *corev1.ConfigMap) (v1alpha1.HorizontalRunnerAutoscaler, error) {
+	if cm == nil {
+		return hra, nil
+	}
+
+	parse := func(key string) (*int, error) {
+		v, ok := cm.Data[key]
+		if !ok {
+			return nil, nil
+		}
+
+		i, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s of configmap %s: %w", key, cm.Name, err)
+		}
+
+		if i < 0 {
+			return nil, fmt.Errorf("parsing %s of configmap %s: must be greater than or equal to 0, but got %d", key, cm.Name, i)
+		}
+
+		return &i, nil
+	}
+
+	min, err := parse(policyKeyMinReplicas)
+	if err != nil {
+		return hra, err
+	}
+
+	max, err := parse(policyKeyMaxReplicas)
+	if err != nil {
+		return hra, err
+	}
+
+	scaleDownDelay, err := parse(policyKeyScaleDownDelaySeconds)
+	if err != nil {
+		return hra, err
+	}
+
+	copy := hra.DeepCopy()
+
+	if min != nil {
+		copy.Spec.MinReplicas = min
+	}
+
+	if max != nil {
+		copy.Spec.MaxReplicas = max
+	}
+
+	if scaleDownDelay != nil {
+		copy.Spec.ScaleDownDelaySecondsAfterScaleUp = scaleDownDelay
+	}
+
+	if copy.Spec.MinReplicas != nil && copy.Spec.MaxReplicas != nil && *copy.Spec.MinReplicas > *copy.Spec.MaxReplicas {
+		return hra, fmt.Errorf("validating configmap %s: minReplicas (%d) cannot be greater than maxReplicas (%d)", cm.Name, *copy.Spec.MinReplicas, *copy.Spec.MaxReplicas)
+	}
+
+	return *copy, nil
+}
+
+// setupPolicyWatch indexes HorizontalRunnerAutoscalers by their PolicyRef and returns the handler
+// that enqueues the HorizontalRunnerAutoscalers referencing a ConfigMap on its change.
+func (r *HorizontalRunnerAutoscalerReconciler) setupPolicyWatch(mgr manager.Manager) (handler.EventHandler, error) {
+	if err := mgr.GetFieldIndexer().IndexField(&v1alpha1.HorizontalRunnerAutoscaler{}, policyRefKey, func(rawObj runtime.Object) []string {
+		hra := rawObj.(*v1alpha1.HorizontalRunnerAutoscaler)
+
+		if hra.Spec.PolicyRef == nil || hra.Spec.PolicyRef.Name == "" {
+			return nil
+		}
+
+		return []string{hra.Spec.PolicyRef.Name}
+	}); err != nil {
+		return nil, err
+	}
+
+	return &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(o handler.MapObject) []reconcile.Request {
+			var hraList v1alpha1.HorizontalRunnerAutoscalerList
+
+			if err := r.List(context.Background(), &hraList, client.InNamespace(o.Meta.GetNamespace()), client.MatchingFields{policyRefKey: o.Meta.GetName()}); err != nil {
+				r.Log.Error(err, "Failed to list horizontalrunnerautoscalers referencing configmap", "namespace", o.Meta.GetNamespace(), "configmap", o.Meta.GetName())
+
+				return nil
+			}
+
+			var reqs []reconcile.Request
+
+			for _, hra := range hraList.Items {
+				reqs = append(reqs, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: hra.Namespace, Name: hra.Name}})
+			}
+
+			return reqs
+		}),
+	}, nil
+}
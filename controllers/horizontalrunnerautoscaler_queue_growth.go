@@ -0,0 +1,37 @@
+package controllers
+
+import (
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+)
+
+func getQueueGrowthConsecutiveIncreases(hra v1alpha1.HorizontalRunnerAutoscaler) int {
+	if hra.Spec.QueueGrowthPanic == nil || hra.Spec.QueueGrowthPanic.ConsecutiveIncreases <= 0 {
+		return 0
+	}
+
+	return hra.Spec.QueueGrowthPanic.ConsecutiveIncreases
+}
+
+// recordQueueDepth returns the history with the queue depth appended when it's larger than the last one,
+// or the history restarted from the queue depth otherwise.
+// It keeps at most consecutiveIncreases+1 queue depths, which is enough to tell the growth is sustained.
+func recordQueueDepth(history []int, depth, consecutiveIncreases int) []int {
+	if len(history) == 0 || history[len(history)-1] >= depth {
+		return []int{depth}
+	}
+
+	h := append(append([]int{}, history...), depth)
+
+	if len(h) > consecutiveIncreases+1 {
+		h = h[len(h)-consecutiveIncreases-1:]
+	}
+
+	return h
+}
+
+// isQueueGrowthDetected returns true when the history says the queue depth increased on ConsecutiveIncreases consecutive reconciliations.
+func isQueueGrowthDetected(hra v1alpha1.HorizontalRunnerAutoscaler, history []int) bool {
+	n := getQueueGrowthConsecutiveIncreases(hra)
+
+	return n > 0 && len(history) > n
+}
@@ -0,0 +1,160 @@
+package controllers
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// resolveCapacityReservationDurations returns the capacity reservations with the ones specified by Duration converted
+// to the absolute ExpirationTime relative to now, which is recorded as the EffectiveTime.
+// The reservations with ExpirationTime are left as is, so that each reservation is resolved only on its first observation.
+// It returns true when any reservation is resolved.
+func resolveCapacityReservationDurations(reservations []v1alpha1.CapacityReservation, now time.Time) ([]v1alpha1.CapacityReservation, bool) {
+	var resolved bool
+
+	result := make([]v1alpha1.CapacityReservation, len(reservations))
+
+	for i, r := range reservations {
+		if r.ExpirationTime.IsZero() && r.Duration.Duration > 0 {
+			r.EffectiveTime = metav1.Time{Time: now}
+			r.ExpirationTime = metav1.Time{Time: now.Add(r.Duration.Duration)}
+			resolved = true
+		}
+
+		result[i] = r
+	}
+
+	return result, resolved
+}
+
+// sortCapacityReservations returns a copy of the capacity reservations sorted by capacityReservationLess.
+// The spec keeps the reservations in the order they were added, so they are sorted only for processing,
+// like summing them, whose result then doesn't depend on the order the concurrent webhook events added them in.
+func sortCapacityReservations(reservations []v1alpha1.CapacityReservation) []v1alpha1.CapacityReservation {
+	sorted := make([]v1alpha1.CapacityReservation, len(reservations))
+	copy(sorted, reservations)
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return capacityReservationLess(sorted[i], sorted[j])
+	})
+
+	return sorted
+}
+
+// capacityReservationLess orders the capacity reservations by ExpirationTime, and then EffectiveTime.
+// The remaining fields break the ties, so that the reservations are totally ordered.
+func capacityReservationLess(a, b v1alpha1.CapacityReservation) bool {
+	if !a.ExpirationTime.Equal(&b.ExpirationTime) {
+		return a.ExpirationTime.Before(&b.ExpirationTime)
+	}
+
+	if !a.EffectiveTime.Equal(&b.EffectiveTime) {
+		return a.EffectiveTime.Before(&b.EffectiveTime)
+	}
+
+	if a.Replicas != b.Replicas {
+		return a.Replicas < b.Replicas
+	}
+
+	if a.WorkflowJobID != b.WorkflowJobID {
+		return a.WorkflowJobID < b.WorkflowJobID
+	}
+
+	if a.ReservationID != b.ReservationID {
+		return a.ReservationID < b.ReservationID
+	}
+
+	return a.Name < b.Name
+}
+
+// getEffectiveCapacityReservations returns the capacity reservations to be honored, which are the unexpired ones and,
+// when HoldCapacityReservationsWhileBusy is enabled, the expired ones still held by busy runners.
+func (r *HorizontalRunnerAutoscalerReconciler) getEffectiveCapacityReservations(ctx context.Context, rd v1alpha1.RunnerDeployment, hra v1alpha1.HorizontalRunnerAutoscaler, now time.Time) ([]v1alpha1.CapacityReservation, error) {
+	valid := getValidCapacityReservations(&hra)
+
+	if !hra.Spec.HoldCapacityReservationsWhileBusy || len(valid) == len(hra.Spec.CapacityReservations) {
+		return valid, nil
+	}
+
+	ghc, err := r.getGitHubClient(ctx, hra)
+	if err != nil {
+		return valid, err
+	}
+
+	counts, err := r.countRunners(ctx, ghc, rd, 0)
+	if err != nil {
+		return valid, err
+	}
+
+	return holdCapacityReservations(hra.Spec.CapacityReservations, now, counts.busy), nil
+}
+
+// holdCapacityReservations returns the unexpired reservations plus the expired ones held by the busy runners.
+// Expired reservations are held from the most recently expired one, as long as the busy runners outnumber
+// the replicas reserved so far, so that the busy runners are never scaled down due to their reservations expiring.
+// The reservations are returned in the original order.
+func holdCapacityReservations(reservations []v1alpha1.CapacityReservation, now time.Time, numRunnersBusy int) []v1alpha1.CapacityReservation {
+	var (
+		valid   []v1alpha1.CapacityReservation
+		expired []int
+	)
+
+	for i, r := range reservations {
+		if r.ExpirationTime.Time.After(now) {
+			valid = append(valid, r)
+		} else {
+			expired = append(expired, i)
+		}
+	}
+
+	// Reversed, so that the most recently expired one comes first
+	sort.SliceStable(expired, func(a, b int) bool {
+		return capacityReservationLess(reservations[expired[b]], reservations[expired[a]])
+	})
+
+	reserved := getCapacityReservationReplicas(valid)
+
+	held := map[int]bool{}
+
+	for _, i := range expired {
+		if reserved >= numRunnersBusy {
+			break
+		}
+
+		held[i] = true
+		reserved += reservations[i].Replicas
+	}
+
+	var effective []v1alpha1.CapacityReservation
+
+	for i, r := range reservations {
+		if r.ExpirationTime.Time.After(now) || held[i] {
+			effective = append(effective, r)
+		}
+	}
+
+	return effective
+}
+
+// getReservedReplicas returns the replicas added by the capacity reservations, capped by MaxCapacityReservationReplicas.
+func getReservedReplicas(hra v1alpha1.HorizontalRunnerAutoscaler, reservations []v1alpha1.CapacityReservation) int {
+	reserved := getCapacityReservationReplicas(reservations)
+
+	if max := hra.Spec.MaxCapacityReservationReplicas; max != nil && reserved > *max {
+		reserved = *max
+	}
+
+	return reserved
+}
+
+// summarizeCapacityReservations returns the number of the reservations in effect and the replicas they reserve.
+func summarizeCapacityReservations(reservations []v1alpha1.CapacityReservation) v1alpha1.ActiveCapacityReservations {
+	return v1alpha1.ActiveCapacityReservations{
+		Count:    len(reservations),
+		Replicas: getCapacityReservationReplicas(reservations),
+	}
+}
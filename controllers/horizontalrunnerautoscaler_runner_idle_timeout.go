@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/go-logr/logr"
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// EnvVarRunnerIdleTimeout is the env var of the runner container telling the runner to exit
+	// when it hasn't picked up any job within the number of seconds.
+	EnvVarRunnerIdleTimeout = "RUNNER_IDLE_TIMEOUT"
+
+	// AnnotationKeyRunnerIdleTimeout is the annotation on a RunnerDeployment recording the RUNNER_IDLE_TIMEOUT
+	// set by the HorizontalRunnerAutoscaler, so that only the env var set by it is removed once RunnerIdleTimeoutSeconds is unset.
+	AnnotationKeyRunnerIdleTimeout = "actions.summerwind.dev/runner-idle-timeout-seconds"
+)
+
+// withRunnerIdleTimeout returns the copy of the RunnerDeployment whose runners have the RUNNER_IDLE_TIMEOUT env var
+// set to RunnerIdleTimeoutSeconds of the HorizontalRunnerAutoscaler, or nil when it's already in sync.
+func withRunnerIdleTimeout(hra v1alpha1.HorizontalRunnerAutoscaler, rd v1alpha1.RunnerDeployment) *v1alpha1.RunnerDeployment {
+	annotated, managed := rd.Annotations[AnnotationKeyRunnerIdleTimeout]
+
+	envIndex := -1
+	for i, e := range rd.Spec.Template.Spec.Env {
+		if e.Name == EnvVarRunnerIdleTimeout {
+			envIndex = i
+			break
+		}
+	}
+
+	if hra.Spec.RunnerIdleTimeoutSeconds == nil {
+		if !managed {
+			return nil
+		}
+
+		copy := rd.DeepCopy()
+		delete(copy.Annotations, AnnotationKeyRunnerIdleTimeout)
+
+		if envIndex >= 0 {
+			env := copy.Spec.Template.Spec.Env
+			copy.Spec.Template.Spec.Env = append(env[:envIndex:envIndex], env[envIndex+1:]...)
+		}
+
+		return copy
+	}
+
+	timeout := strconv.Itoa(*hra.Spec.RunnerIdleTimeoutSeconds)
+
+	if managed && annotated == timeout && envIndex >= 0 && rd.Spec.Template.Spec.Env[envIndex] == (corev1.EnvVar{Name: EnvVarRunnerIdleTimeout, Value: timeout}) {
+		return nil
+	}
+
+	copy := rd.DeepCopy()
+
+	if copy.Annotations == nil {
+		copy.Annotations = map[string]string{}
+	}
+
+	copy.Annotations[AnnotationKeyRunnerIdleTimeout] = timeout
+
+	env := corev1.EnvVar{Name: EnvVarRunnerIdleTimeout, Value: timeout}
+
+	if envIndex >= 0 {
+		copy.Spec.Template.Spec.Env[envIndex] = env
+	} else {
+		copy.Spec.Template.Spec.Env = append(copy.Spec.Template.Spec.Env, env)
+	}
+
+	return copy
+}
+
+// syncRunnerIdleTimeout updates the RunnerDeployment in place with the runner idle timeout of the HorizontalRunnerAutoscaler.
+// It results in a rollout of the runners, as the env var is a part of the runner template.
+func (r *HorizontalRunnerAutoscalerReconciler) syncRunnerIdleTimeout(ctx context.Context, log logr.Logger, hra v1alpha1.HorizontalRunnerAutoscaler, rd *v1alpha1.RunnerDeployment) error {
+	if !rd.DeletionTimestamp.IsZero() {
+		return nil
+	}
+
+	updated := withRunnerIdleTimeout(hra, *rd)
+	if updated == nil {
+		return nil
+	}
+
+	if err := r.Client.Update(ctx, updated); err != nil {
+		return err
+	}
+
+	log.Info("Updated the runner idle timeout of runnerdeployment", "runnerdeployment", rd.Name, "runnerIdleTimeoutSeconds", updated.Annotations[AnnotationKeyRunnerIdleTimeout])
+
+	*rd = *updated
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"math"
+	"time"
+
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+)
+
+func getScaleDownDecayHalfLife(hra v1alpha1.HorizontalRunnerAutoscaler) time.Duration {
+	if hra.Spec.ScaleDownDecayHalfLifeSeconds == nil || *hra.Spec.ScaleDownDecayHalfLifeSeconds <= 0 {
+		return 0
+	}
+
+	return time.Duration(*hra.Spec.ScaleDownDecayHalfLifeSeconds) * time.Second
+}
+
+// getScaleDownDecayPeak returns the desired replicas at the last scale out, which is the current desired replicas
+// for the status recorded before LastScaleOutDesiredReplicas was introduced.
+func getScaleDownDecayPeak(hra v1alpha1.HorizontalRunnerAutoscaler) int {
+	return getIntOrDefault(hra.Status.LastScaleOutDesiredReplicas, *hra.Status.DesiredReplicas)
+}
+
+// getScaleDownDecayReplicas returns the replicas decayed from the desired replicas at the last scale out toward target,
+// rounded to the nearest integer so that it eventually reaches target. It never exceeds the current desired replicas.
+// It returns false when the decay is disabled or target isn't a scale down.
+func getScaleDownDecayReplicas(hra v1alpha1.HorizontalRunnerAutoscaler, target int, now time.Time) (int, bool) {
+	halfLife := getScaleDownDecayHalfLife(hra)
+	if halfLife == 0 || hra.Status.DesiredReplicas == nil || hra.Status.LastSuccessfulScaleOutTime == nil || *hra.Status.DesiredReplicas <= target {
+		return 0, false
+	}
+
+	peak := getScaleDownDecayPeak(hra)
+	if peak <= target {
+		return target, true
+	}
+
+	elapsed := now.Sub(hra.Status.LastSuccessfulScaleOutTime.Time)
+	if elapsed < 0 {
+		elapsed = 0
+	}
+
+	excess := float64(peak-target) * math.Pow(0.5, elapsed.Seconds()/halfLife.Seconds())
+
+	replicas := target + int(math.Round(excess))
+	if replicas > *hra.Status.DesiredReplicas {
+		replicas = *hra.Status.DesiredReplicas
+	}
+
+	return replicas, true
+}
+
+// getNextScaleDownDecayStep returns the time the decayed replicas drop by one next, or nil when they've already reached target.
+func getNextScaleDownDecayStep(hra v1alpha1.HorizontalRunnerAutoscaler, target int, now time.Time) *time.Time {
+	replicas, ok := getScaleDownDecayReplicas(hra, target, now)
+	if !ok || replicas <= target {
+		return nil
+	}
+
+	// The decayed excess is rounded down to one replica less once it goes below the current excess minus 0.5
+	threshold := float64(replicas-target) - 0.5
+
+	halfLives := math.Log2(float64(getScaleDownDecayPeak(hra)-target) / threshold)
+
+	next := hra.Status.LastSuccessfulScaleOutTime.Add(time.Duration(halfLives * float64(getScaleDownDecayHalfLife(hra))))
+
+	// Guard against the floating point error resulting in a busy loop
+	if !next.After(now) {
+		next = now.Add(time.Second)
+	}
+
+	return &next
+}
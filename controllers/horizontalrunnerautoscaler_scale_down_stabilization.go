@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// maxReplicasRecommendations guards against the recommendations growing unbounded, e.g. when the system clock goes backward.
+// The recommendations are otherwise bounded by the number of distinct replicas, as they're kept in descending order.
+const maxReplicasRecommendations = 100
+
+func getScaleDownStabilizationWindow(hra v1alpha1.HorizontalRunnerAutoscaler) time.Duration {
+	if hra.Spec.ScaleDownStabilizationWindowSeconds == nil || *hra.Spec.ScaleDownStabilizationWindowSeconds <= 0 {
+		return 0
+	}
+
+	return time.Duration(*hra.Spec.ScaleDownStabilizationWindowSeconds) * time.Second
+}
+
+// recordReplicasRecommendation returns the recommendations with the replicas recommended at now appended.
+// The ones out of the window, and the ones no larger than the replicas, are dropped as they never win the max within the window again.
+func recordReplicasRecommendation(recommendations []v1alpha1.ReplicasRecommendation, now time.Time, replicas int, window time.Duration) []v1alpha1.ReplicasRecommendation {
+	var recs []v1alpha1.ReplicasRecommendation
+
+	for _, rec := range recommendations {
+		if !rec.Time.Add(window).After(now) || rec.Replicas <= replicas {
+			continue
+		}
+
+		recs = append(recs, rec)
+	}
+
+	recs = append(recs, v1alpha1.ReplicasRecommendation{Time: metav1.Time{Time: now}, Replicas: replicas})
+
+	if len(recs) > maxReplicasRecommendations {
+		recs = recs[len(recs)-maxReplicasRecommendations:]
+	}
+
+	return recs
+}
+
+// getLargestReplicasRecommendation returns the largest recommendation within the window, or nil when there's none.
+func getLargestReplicasRecommendation(hra v1alpha1.HorizontalRunnerAutoscaler, now time.Time) *v1alpha1.ReplicasRecommendation {
+	window := getScaleDownStabilizationWindow(hra)
+	if window == 0 {
+		return nil
+	}
+
+	var largest *v1alpha1.ReplicasRecommendation
+
+	for i := range hra.Status.Recommendations {
+		rec := &hra.Status.Recommendations[i]
+
+		if !rec.Time.Add(window).After(now) {
+			continue
+		}
+
+		if largest == nil || rec.Replicas > largest.Replicas {
+			largest = rec
+		}
+	}
+
+	return largest
+}
+
+// getScaleDownStabilizedReplicas returns the largest recommendation within the window, which never exceeds the current desired replicas.
+// It returns false when the window is disabled, target isn't a scale down, or no recommendation within the window is larger than target.
+func getScaleDownStabilizedReplicas(hra v1alpha1.HorizontalRunnerAutoscaler, target int, now time.Time) (int, bool) {
+	if hra.Status.DesiredReplicas == nil || *hra.Status.DesiredReplicas <= target {
+		return 0, false
+	}
+
+	largest := getLargestReplicasRecommendation(hra, now)
+	if largest == nil || largest.Replicas <= target {
+		return 0, false
+	}
+
+	replicas := largest.Replicas
+	if replicas > *hra.Status.DesiredReplicas {
+		replicas = *hra.Status.DesiredReplicas
+	}
+
+	return replicas, true
+}
+
+// getScaleDownStabilizationEnd returns the time the largest recommendation holding the scale down to target leaves the window,
+// or nil when nothing is held.
+func getScaleDownStabilizationEnd(hra v1alpha1.HorizontalRunnerAutoscaler, target int, now time.Time) *time.Time {
+	if _, ok := getScaleDownStabilizedReplicas(hra, target, now); !ok {
+		return nil
+	}
+
+	end := getLargestReplicasRecommendation(hra, now).Time.Add(getScaleDownStabilizationWindow(hra))
+
+	return &end
+}
@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// maxScaleEvents is the number of the scale events kept in the status, so that the audit trail doesn't bloat the object.
+const maxScaleEvents = 10
+
+// recordScaleEvent appends the scale from `from` to `to` replicas to the scale events, dropping the oldest ones beyond maxScaleEvents.
+func recordScaleEvent(events []v1alpha1.ScaleEvent, now time.Time, from, to int, reason, metricType string) []v1alpha1.ScaleEvent {
+	event := v1alpha1.ScaleEvent{
+		Time:         metav1.Time{Time: now},
+		FromReplicas: from,
+		ToReplicas:   to,
+		Reason:       reason,
+	}
+
+	if reason == v1alpha1.ScaleEventReasonMetric {
+		event.Metric = metricType
+	}
+
+	if n := len(events) + 1 - maxScaleEvents; n > 0 {
+		events = events[n:]
+	}
+
+	// Copied, so that the status of the HorizontalRunnerAutoscaler the events are taken from isn't modified
+	return append(append([]v1alpha1.ScaleEvent{}, events...), event)
+}
+
+// getScaleEventReason returns what drove the desired replicas decided by d.
+// The metrics clamp their replicas by MinReplicas on their own, so the scale to MinReplicas is attributed to MinReplicas
+// whenever the metrics demand no more than it, including the one by EventsOnly, whose replicas are MinReplicas.
+func getScaleEventReason(d scalingDecision, desiredReplicas int, overridden bool) string {
+	switch {
+	case overridden:
+		return v1alpha1.ScaleEventReasonOverride
+	case d.ReservedReplicas > 0:
+		return v1alpha1.ScaleEventReasonCapacityReservation
+	case desiredReplicas == d.MinReplicas && d.ComputedReplicas <= d.MinReplicas:
+		return v1alpha1.ScaleEventReasonMinReplicas
+	}
+
+	return v1alpha1.ScaleEventReasonMetric
+}
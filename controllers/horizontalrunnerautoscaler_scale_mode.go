@@ -0,0 +1,29 @@
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+)
+
+// metricTypeEventsOnly is the type of the metricResult standing in for the metrics in the EventsOnly scale mode.
+const metricTypeEventsOnly = "EventsOnly"
+
+// isEventsOnly returns true when the desired replicas of the HorizontalRunnerAutoscaler are derived
+// only from the capacity reservations added by the webhook-based autoscaler, without polling the metrics.
+func isEventsOnly(hra v1alpha1.HorizontalRunnerAutoscaler) bool {
+	return hra.Spec.ScaleMode == v1alpha1.ScaleModeEventsOnly
+}
+
+// getEventsOnlyReplicas returns MinReplicas as the replicas computed by the metrics in the EventsOnly scale mode,
+// on top of which the active capacity reservations are added as usual.
+// The scale delays are not applied, as the reservations expire on their own.
+func getEventsOnlyReplicas(hra v1alpha1.HorizontalRunnerAutoscaler) *metricResult {
+	minReplicas := getDefaultReplicas(hra)
+
+	return &metricResult{
+		Type:          metricTypeEventsOnly,
+		Replicas:      minReplicas,
+		ObservedValue: fmt.Sprintf("minReplicas=%d", minReplicas),
+	}
+}
@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"reflect"
+
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// applyScaleOutScheduling merges the ScaleOutScheduling of the HorizontalRunnerAutoscaler into the runner template
+// of the RunnerDeployment, and returns true when anything changed.
+// The node selector keys and the tolerations already set on the RunnerDeployment win,
+// so that the scaling hints never override the placement configured on it.
+func applyScaleOutScheduling(hra v1alpha1.HorizontalRunnerAutoscaler, rd *v1alpha1.RunnerDeployment) bool {
+	s := hra.Spec.ScaleOutScheduling
+	if s == nil {
+		return false
+	}
+
+	spec := &rd.Spec.Template.Spec
+
+	var changed bool
+
+	for k, v := range s.NodeSelector {
+		if _, ok := spec.NodeSelector[k]; ok {
+			continue
+		}
+
+		if spec.NodeSelector == nil {
+			spec.NodeSelector = map[string]string{}
+		}
+
+		spec.NodeSelector[k] = v
+		changed = true
+	}
+
+	for _, t := range s.Tolerations {
+		if hasToleration(spec.Tolerations, t) {
+			continue
+		}
+
+		spec.Tolerations = append(spec.Tolerations, t)
+		changed = true
+	}
+
+	return changed
+}
+
+func hasToleration(tolerations []corev1.Toleration, t corev1.Toleration) bool {
+	for _, existing := range tolerations {
+		if reflect.DeepEqual(existing, t) {
+			return true
+		}
+	}
+
+	return false
+}
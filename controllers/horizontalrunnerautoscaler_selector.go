@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"context"
+	"sort"
+
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// hasScaleTargetSelector returns true when the HorizontalRunnerAutoscaler selects its scale targets by labels instead of the name.
+func hasScaleTargetSelector(hra v1alpha1.HorizontalRunnerAutoscaler) bool {
+	return hra.Spec.ScaleTargetRef.Name == "" && len(hra.Spec.ScaleTargetRef.MatchLabels) > 0
+}
+
+// getSelectedScaleTargets returns the RunnerDeployments matching the MatchLabels of ScaleTargetRef sorted by name,
+// excluding the ones being deleted.
+func (r *HorizontalRunnerAutoscalerReconciler) getSelectedScaleTargets(ctx context.Context, hra v1alpha1.HorizontalRunnerAutoscaler) ([]v1alpha1.RunnerDeployment, error) {
+	var rdList v1alpha1.RunnerDeploymentList
+	if err := r.List(ctx, &rdList, client.InNamespace(hra.Namespace), client.MatchingLabels(hra.Spec.ScaleTargetRef.MatchLabels)); err != nil {
+		return nil, err
+	}
+
+	var rds []v1alpha1.RunnerDeployment
+
+	for _, rd := range rdList.Items {
+		if !rd.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		rds = append(rds, rd)
+	}
+
+	sort.SliceStable(rds, func(i, j int) bool {
+		return rds[i].Name < rds[j].Name
+	})
+
+	return rds, nil
+}
+
+// getScaleTargetCount returns the number of the scale targets of the HorizontalRunnerAutoscaler, each scaled to the desired replicas.
+func (r *HorizontalRunnerAutoscalerReconciler) getScaleTargetCount(ctx context.Context, hra v1alpha1.HorizontalRunnerAutoscaler) (int, error) {
+	if !hasScaleTargetSelector(hra) {
+		return 1 + len(hra.Spec.AdditionalScaleTargetRefs), nil
+	}
+
+	rds, err := r.getSelectedScaleTargets(ctx, hra)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(rds) + len(hra.Spec.AdditionalScaleTargetRefs), nil
+}
+
+// scaleTargetSelectorHandler enqueues the HorizontalRunnerAutoscalers selecting a RunnerDeployment by its labels
+// on its creation and deletion, and on its update only when the labels have changed,
+// so that the HorizontalRunnerAutoscaler doesn't get reconciled again on every scale it applies.
+func (r *HorizontalRunnerAutoscalerReconciler) scaleTargetSelectorHandler() handler.EventHandler {
+	enqueue := func(q workqueue.RateLimitingInterface, namespace string, rdLabels ...map[string]string) {
+		var hraList v1alpha1.HorizontalRunnerAutoscalerList
+
+		if err := r.List(context.Background(), &hraList, client.InNamespace(namespace)); err != nil {
+			r.Log.Error(err, "Failed to list horizontalrunnerautoscalers selecting runnerdeployment", "namespace", namespace)
+
+			return
+		}
+
+		for _, hra := range hraList.Items {
+			if !hasScaleTargetSelector(hra) {
+				continue
+			}
+
+			selector := labels.SelectorFromSet(hra.Spec.ScaleTargetRef.MatchLabels)
+
+			for _, l := range rdLabels {
+				if selector.Matches(labels.Set(l)) {
+					q.Add(reconcile.Request{NamespacedName: types.NamespacedName{Namespace: hra.Namespace, Name: hra.Name}})
+
+					break
+				}
+			}
+		}
+	}
+
+	return handler.Funcs{
+		CreateFunc: func(e event.CreateEvent, q workqueue.RateLimitingInterface) {
+			enqueue(q, e.Meta.GetNamespace(), e.Meta.GetLabels())
+		},
+		UpdateFunc: func(e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+			if labels.Equals(e.MetaOld.GetLabels(), e.MetaNew.GetLabels()) {
+				return
+			}
+
+			enqueue(q, e.MetaNew.GetNamespace(), e.MetaOld.GetLabels(), e.MetaNew.GetLabels())
+		},
+		DeleteFunc: func(e event.DeleteEvent, q workqueue.RateLimitingInterface) {
+			enqueue(q, e.Meta.GetNamespace(), e.Meta.GetLabels())
+		},
+		GenericFunc: func(e event.GenericEvent, q workqueue.RateLimitingInterface) {
+			enqueue(q, e.Meta.GetNamespace(), e.Meta.GetLabels())
+		},
+	}
+}
@@ -0,0 +1,168 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	"github.com/summerwind/actions-runner-controller/github"
+)
+
+// MetricProvider computes the desired replicas of the scale target for a metric of a HorizontalRunnerAutoscaler.
+// The desired replicas are capped by MinReplicas and MaxReplicas by the caller.
+type MetricProvider interface {
+	DesiredReplicas(ctx context.Context, rd v1alpha1.RunnerDeployment, hra v1alpha1.HorizontalRunnerAutoscaler) (int, error)
+}
+
+// MetricProviderFactory returns the MetricProvider evaluating the metric.
+// The GitHub client is the one authenticated for the HorizontalRunnerAutoscaler being reconciled.
+type MetricProviderFactory func(ghc *github.Client, metric v1alpha1.MetricSpec) MetricProvider
+
+// metricResultProvider is implemented by the built-in providers, so that the observed value and the busy runners
+// are reported along with the desired replicas.
+type metricResultProvider interface {
+	MetricProvider
+
+	metricResult(ctx context.Context, rd v1alpha1.RunnerDeployment, hra v1alpha1.HorizontalRunnerAutoscaler) (*metricResult, error)
+}
+
+type builtinMetricProvider struct {
+	calculate func(ctx context.Context, rd v1alpha1.RunnerDeployment, hra v1alpha1.HorizontalRunnerAutoscaler) (*metricResult, error)
+}
+
+func (p *builtinMetricProvider) DesiredReplicas(ctx context.Context, rd v1alpha1.RunnerDeployment, hra v1alpha1.HorizontalRunnerAutoscaler) (int, error) {
+	res, err := p.metricResult(ctx, rd, hra)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.Replicas, nil
+}
+
+func (p *builtinMetricProvider) metricResult(ctx context.Context, rd v1alpha1.RunnerDeployment, hra v1alpha1.HorizontalRunnerAutoscaler) (*metricResult, error) {
+	return p.calculate(ctx, rd, hra)
+}
+
+// builtinMetricProviders returns the registry of the metric types supported out of the box, keyed by the metric type.
+func (r *HorizontalRunnerAutoscalerReconciler) builtinMetricProviders() map[string]MetricProviderFactory {
+	return map[string]MetricProviderFactory{
+		v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns: func(ghc *github.Client, metric v1alpha1.MetricSpec) MetricProvider {
+			return &builtinMetricProvider{calculate: func(ctx context.Context, rd v1alpha1.RunnerDeployment, hra v1alpha1.HorizontalRunnerAutoscaler) (*metricResult, error) {
+				return r.calculateReplicasByQueuedAndInProgressWorkflowRuns(ctx, ghc, rd, hra, metric)
+			}}
+		},
+		v1alpha1.AutoscalingMetricTypeDurationWeightedQueuedAndInProgressWorkflowRuns: func(ghc *github.Client, metric v1alpha1.MetricSpec) MetricProvider {
+			return &builtinMetricProvider{calculate: func(ctx context.Context, rd v1alpha1.RunnerDeployment, hra v1alpha1.HorizontalRunnerAutoscaler) (*metricResult, error) {
+				return r.calculateReplicasByQueuedAndInProgressWorkflowRuns(ctx, ghc, rd, hra, metric)
+			}}
+		},
+		v1alpha1.AutoscalingMetricTypePercentageRunnersBusy: func(ghc *github.Client, metric v1alpha1.MetricSpec) MetricProvider {
+			return &builtinMetricProvider{calculate: func(ctx context.Context, rd v1alpha1.RunnerDeployment, hra v1alpha1.HorizontalRunnerAutoscaler) (*metricResult, error) {
+				return r.calculateReplicasByPercentageRunnersBusy(ctx, ghc, rd, hra, metric)
+			}}
+		},
+		v1alpha1.AutoscalingMetricTypePercentageRunnerGroupBusy: func(ghc *github.Client, metric v1alpha1.MetricSpec) MetricProvider {
+			return &builtinMetricProvider{calculate: func(ctx context.Context, rd v1alpha1.RunnerDeployment, hra v1alpha1.HorizontalRunnerAutoscaler) (*metricResult, error) {
+				return r.calculateReplicasByPercentageRunnerGroupBusy(ctx, ghc, rd, hra, metric)
+			}}
+		},
+		v1alpha1.AutoscalingMetricTypeRecentPushAndPullRequestEvents: func(ghc *github.Client, metric v1alpha1.MetricSpec) MetricProvider {
+			return &builtinMetricProvider{calculate: func(ctx context.Context, rd v1alpha1.RunnerDeployment, hra v1alpha1.HorizontalRunnerAutoscaler) (*metricResult, error) {
+				return r.calculateReplicasByRecentPushAndPullRequestEvents(ctx, ghc, rd, hra, metric)
+			}}
+		},
+		v1alpha1.AutoscalingMetricTypeHistoricalDesiredReplicas: func(_ *github.Client, metric v1alpha1.MetricSpec) MetricProvider {
+			return &builtinMetricProvider{calculate: func(_ context.Context, _ v1alpha1.RunnerDeployment, hra v1alpha1.HorizontalRunnerAutoscaler) (*metricResult, error) {
+				return r.calculateReplicasByHistoricalDesiredReplicas(hra, metric)
+			}}
+		},
+		v1alpha1.AutoscalingMetricTypeOfflineRunners: func(ghc *github.Client, _ v1alpha1.MetricSpec) MetricProvider {
+			return &builtinMetricProvider{calculate: func(ctx context.Context, rd v1alpha1.RunnerDeployment, hra v1alpha1.HorizontalRunnerAutoscaler) (*metricResult, error) {
+				return r.calculateReplicasByOfflineRunners(ctx, ghc, rd, hra)
+			}}
+		},
+		v1alpha1.AutoscalingMetricTypeHTTPEndpoint: func(_ *github.Client, metric v1alpha1.MetricSpec) MetricProvider {
+			return &builtinMetricProvider{calculate: func(ctx context.Context, _ v1alpha1.RunnerDeployment, hra v1alpha1.HorizontalRunnerAutoscaler) (*metricResult, error) {
+				return r.calculateReplicasByHTTPEndpoint(ctx, hra, metric)
+			}}
+		},
+		v1alpha1.AutoscalingMetricTypePrometheus: func(_ *github.Client, metric v1alpha1.MetricSpec) MetricProvider {
+			return &builtinMetricProvider{calculate: func(ctx context.Context, _ v1alpha1.RunnerDeployment, hra v1alpha1.HorizontalRunnerAutoscaler) (*metricResult, error) {
+				return r.calculateReplicasByPrometheus(ctx, hra, metric)
+			}}
+		},
+		v1alpha1.AutoscalingMetricTypeExternalObject: func(_ *github.Client, metric v1alpha1.MetricSpec) MetricProvider {
+			return &builtinMetricProvider{calculate: func(ctx context.Context, _ v1alpha1.RunnerDeployment, hra v1alpha1.HorizontalRunnerAutoscaler) (*metricResult, error) {
+				return r.calculateReplicasByExternalObject(ctx, hra, metric)
+			}}
+		},
+	}
+}
+
+// getMetricProviderFactory returns the factory of the provider for the metric type.
+// MetricProviders takes precedence over the built-in ones, so that a built-in metric type can be overridden too.
+func (r *HorizontalRunnerAutoscalerReconciler) getMetricProviderFactory(metricType string) (MetricProviderFactory, bool) {
+	if f, ok := r.MetricProviders[metricType]; ok {
+		return f, true
+	}
+
+	f, ok := r.builtinMetricProviders()[metricType]
+
+	return f, ok
+}
+
+// calculateReplicasByMetric evaluates the metric by the provider registered for its type.
+// The evaluation is bounded by the metric timeout, after which the metric fails like on any other GitHub API error.
+func (r *HorizontalRunnerAutoscalerReconciler) calculateReplicasByMetric(ghc *github.Client, rd v1alpha1.RunnerDeployment, hra v1alpha1.HorizontalRunnerAutoscaler, metric v1alpha1.MetricSpec) (*metricResult, error) {
+	timeout := r.metricTimeout()
+
+	ctx, cancel := context.WithTimeout(r.withGitHubAPIBudgetAccount(context.Background(), hra, rd), timeout)
+	defer cancel()
+
+	f, ok := r.getMetricProviderFactory(metric.Type)
+	if !ok {
+		return nil, fmt.Errorf("validting autoscaling metrics: unsupported metric type %q", metric.Type)
+	}
+
+	p := f(ghc, metric)
+
+	var (
+		res *metricResult
+		err error
+	)
+
+	if rp, ok := p.(metricResultProvider); ok {
+		res, err = rp.metricResult(ctx, rd, hra)
+	} else {
+		res, err = calculateReplicasByProvider(ctx, p, rd, hra)
+	}
+
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("timed out after %s: %w", timeout, err)
+		}
+
+		return nil, err
+	}
+
+	res.Type = metric.Type
+
+	return res, nil
+}
+
+func calculateReplicasByProvider(ctx context.Context, p MetricProvider, rd v1alpha1.RunnerDeployment, hra v1alpha1.HorizontalRunnerAutoscaler) (*metricResult, error) {
+	desired, err := p.DesiredReplicas(ctx, rd, hra)
+	if err != nil {
+		return nil, err
+	}
+
+	replicas := desired
+
+	if min := *hra.Spec.MinReplicas; replicas < min {
+		replicas = min
+	} else if max := *hra.Spec.MaxReplicas; replicas > max {
+		replicas = max
+	}
+
+	return &metricResult{Replicas: replicas, ObservedValue: fmt.Sprintf("%d replicas desired", desired), UncappedReplicas: desired}, nil
+}
@@ -117,6 +117,29 @@ func (r *RunnerDeploymentReconciler) Reconcile(req ctrl.Request) (ctrl.Result, e
 		return ctrl.Result{}, nil
 	}
 
+	// Aggregate the statuses of all the runner replica sets, so that e.g. HorizontalRunnerAutoscaler can tell
+	// whether the runner deployment has stabilized.
+	var available, ready int
+
+	for _, rs := range myRunnerReplicaSets {
+		available += rs.Status.AvailableReplicas
+		ready += rs.Status.ReadyReplicas
+	}
+
+	if rd.Status.AvailableReplicas != available || rd.Status.ReadyReplicas != ready {
+		updated := rd.DeepCopy()
+		updated.Status.AvailableReplicas = available
+		updated.Status.ReadyReplicas = ready
+
+		if err := r.Status().Update(ctx, updated); err != nil {
+			log.Error(err, "Failed to update runnerdeployment status")
+
+			return ctrl.Result{}, err
+		}
+
+		rd = *updated
+	}
+
 	newestTemplateHash, ok := getTemplateHash(newestSet)
 	if !ok {
 		log.Info("Failed to get template hash of newest runnerreplicaset resource. It must be in an invalid state. Please manually delete the runnerreplicaset so that it is recreated")
@@ -148,10 +171,24 @@ func (r *RunnerDeploymentReconciler) Reconcile(req ctrl.Request) (ctrl.Result, e
 	currentDesiredReplicas := getIntOrDefault(newestSet.Spec.Replicas, defaultReplicas)
 	newDesiredReplicas := getIntOrDefault(desiredRS.Spec.Replicas, defaultReplicas)
 
+	currentIdleRunners, hinted := newestSet.Annotations[AnnotationKeyIdleRunners]
+	newIdleRunners, hinting := rd.Annotations[AnnotationKeyIdleRunners]
+
 	// Please add more conditions that we can in-place update the newest runnerreplicaset without disruption
-	if currentDesiredReplicas != newDesiredReplicas {
+	// The idle runners hint is carried over in place, as it's only meaningful to the upcoming scale down.
+	if currentDesiredReplicas != newDesiredReplicas || currentIdleRunners != newIdleRunners || hinted != hinting {
 		newestSet.Spec.Replicas = &newDesiredReplicas
 
+		if hinting {
+			if newestSet.Annotations == nil {
+				newestSet.Annotations = map[string]string{}
+			}
+
+			newestSet.Annotations[AnnotationKeyIdleRunners] = newIdleRunners
+		} else {
+			delete(newestSet.Annotations, AnnotationKeyIdleRunners)
+		}
+
 		if err := r.Client.Update(ctx, newestSet); err != nil {
 			log.Error(err, "Failed to update runnerreplicaset resource")
 
@@ -161,6 +198,21 @@ func (r *RunnerDeploymentReconciler) Reconcile(req ctrl.Request) (ctrl.Result, e
 		return ctrl.Result{}, err
 	}
 
+	// The newest runnerreplicaset reflects the spec at this point, so that e.g. HorizontalRunnerAutoscaler can tell
+	// whether the last update to the spec is still being reconciled.
+	if rd.Status.ObservedGeneration != rd.Generation {
+		updated := rd.DeepCopy()
+		updated.Status.ObservedGeneration = rd.Generation
+
+		if err := r.Status().Update(ctx, updated); err != nil {
+			log.Error(err, "Failed to update runnerdeployment status")
+
+			return ctrl.Result{}, err
+		}
+
+		rd = *updated
+	}
+
 	// Do we old runner replica sets that should eventually deleted?
 	if len(oldSets) > 0 {
 		readyReplicas := newestSet.Status.ReadyReplicas
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"context"
+	"fmt"
 	"github.com/google/go-cmp/cmp"
 	"k8s.io/apimachinery/pkg/runtime"
 	"testing"
@@ -10,6 +11,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
 	ctrl "sigs.k8s.io/controller-runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
@@ -17,6 +19,7 @@ import (
 	. "github.com/onsi/gomega"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	actionsv1alpha1 "github.com/summerwind/actions-runner-controller/api/v1alpha1"
 )
@@ -55,6 +58,159 @@ func TestNewRunnerReplicaSet(t *testing.T) {
 	}
 }
 
+func TestReconcile_RunnerDeploymentStatus(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := actionsv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	rd := &actionsv1alpha1.RunnerDeployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "example",
+			Namespace:  "default",
+			Generation: 2,
+		},
+		Spec: actionsv1alpha1.RunnerDeploymentSpec{
+			Replicas: intPtr(3),
+			Template: actionsv1alpha1.RunnerTemplate{
+				Spec: actionsv1alpha1.RunnerSpec{
+					Repository: "test/valid",
+				},
+			},
+		},
+	}
+
+	r := &RunnerDeploymentReconciler{
+		Log:    logf.Log,
+		Scheme: scheme,
+	}
+
+	rs, err := r.newRunnerReplicaSet(*rd)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	rs.Name = "example-abc"
+	rs.Status.AvailableReplicas = 3
+	rs.Status.ReadyReplicas = 2
+
+	r.Client = clientfake.NewFakeClientWithScheme(scheme, rd, rs)
+
+	if _, err := r.Reconcile(ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "example"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got actionsv1alpha1.RunnerDeployment
+	if err := r.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "example"}, &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got.Status.AvailableReplicas != 3 || got.Status.ReadyReplicas != 2 {
+		t.Errorf("unexpected status: want availableReplicas=3 and readyReplicas=2, got %+v", got.Status)
+	}
+
+	// The newest runnerreplicaset already reflects the spec
+	if got.Status.ObservedGeneration != 2 {
+		t.Errorf("unexpected status.observedGeneration: want 2, got %d", got.Status.ObservedGeneration)
+	}
+}
+
+func TestReconcile_RunnerDeploymentIdleRunnersHint(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := actionsv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	testcases := []struct {
+		rsReplicas int
+		rsHint     *string
+		rdHint     *string
+		wantHint   *string
+	}{
+		// Carried over along with the replicas
+		{
+			rsReplicas: 3,
+			rdHint:     pointer.StringPtr("example-abc-1,example-abc-2"),
+			wantHint:   pointer.StringPtr("example-abc-1,example-abc-2"),
+		},
+		{
+			rsReplicas: 2,
+			rsHint:     pointer.StringPtr("example-abc-1"),
+			rdHint:     pointer.StringPtr("example-abc-2"),
+			wantHint:   pointer.StringPtr("example-abc-2"),
+		},
+		// The stale hint is removed
+		{
+			rsReplicas: 3,
+			rsHint:     pointer.StringPtr("example-abc-1"),
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			rd := &actionsv1alpha1.RunnerDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "example",
+					Namespace: "default",
+				},
+				Spec: actionsv1alpha1.RunnerDeploymentSpec{
+					Replicas: intPtr(2),
+					Template: actionsv1alpha1.RunnerTemplate{
+						Spec: actionsv1alpha1.RunnerSpec{
+							Repository: "test/valid",
+						},
+					},
+				},
+			}
+
+			if tc.rdHint != nil {
+				rd.Annotations = map[string]string{AnnotationKeyIdleRunners: *tc.rdHint}
+			}
+
+			r := &RunnerDeploymentReconciler{
+				Log:    logf.Log,
+				Scheme: scheme,
+			}
+
+			rs, err := r.newRunnerReplicaSet(*rd)
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			rs.Name = "example-abc"
+			rs.Spec.Replicas = intPtr(tc.rsReplicas)
+
+			if tc.rsHint != nil {
+				rs.Annotations = map[string]string{AnnotationKeyIdleRunners: *tc.rsHint}
+			}
+
+			r.Client = clientfake.NewFakeClientWithScheme(scheme, rd, rs)
+
+			if _, err := r.Reconcile(ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "example"}}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got actionsv1alpha1.RunnerReplicaSet
+			if err := r.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "example-abc"}, &got); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got.Spec.Replicas == nil || *got.Spec.Replicas != 2 {
+				t.Errorf("unexpected replicas: want 2, got %v", got.Spec.Replicas)
+			}
+
+			hint, ok := got.Annotations[AnnotationKeyIdleRunners]
+			if tc.wantHint == nil {
+				if ok {
+					t.Errorf("unexpected %s annotation: %q", AnnotationKeyIdleRunners, hint)
+				}
+			} else if hint != *tc.wantHint {
+				t.Errorf("unexpected %s annotation: want %q, got %q", AnnotationKeyIdleRunners, *tc.wantHint, hint)
+			}
+		})
+	}
+}
+
 // SetupDeploymentTest will set up a testing environment.
 // This includes:
 // * creating a Namespace to be used during the test
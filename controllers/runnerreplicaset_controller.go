@@ -104,9 +104,17 @@ func (r *RunnerReplicaSetReconciler) Reconcile(req ctrl.Request) (ctrl.Result, e
 	if available > desired {
 		n := available - desired
 
+		sortRunnersByIdleHint(rs, myRunners)
+
 		// get runners that are currently not busy
 		var notBusy []v1alpha1.Runner
 		for _, runner := range myRunners {
+			// Stop once enough runners are found, which spares the GitHub API calls for checking the rest,
+			// most of the time when the runners hinted idle come first.
+			if len(notBusy) >= n {
+				break
+			}
+
 			busy, err := r.GitHubClient.IsRunnerBusy(ctx, runner.Spec.Enterprise, runner.Spec.Organization, runner.Spec.Repository, runner.Name)
 			if err != nil {
 				notRegistered := false
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	FrequencyDaily   = "Daily"
+	FrequencyWeekly  = "Weekly"
+	FrequencyMonthly = "Monthly"
+	FrequencyYearly  = "Yearly"
+)
+
+type RecurrenceRule struct {
+	Frequency string
+	UntilTime time.Time
+}
+
+type Period struct {
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+func (r *Period) String() string {
+	if r == nil {
+		return ""
+	}
+
+	return r.StartTime.Format(time.RFC3339) + "-" + r.EndTime.Format(time.RFC3339)
+}
+
+// MatchSchedule returns the period that is active at `now`, and the period that starts next after `now`.
+// Either of them can be nil when there's no such period.
+func MatchSchedule(now time.Time, startTime time.Time, endTime time.Time, recurrenceRule RecurrenceRule) (*Period, *Period, error) {
+	if !endTime.After(startTime) {
+		return nil, nil, fmt.Errorf("invalid schedule: endTime %s must be after startTime %s", endTime.Format(time.RFC3339), startTime.Format(time.RFC3339))
+	}
+
+	var years, months, days int
+
+	switch recurrenceRule.Frequency {
+	case FrequencyDaily:
+		days = 1
+	case FrequencyWeekly:
+		days = 7
+	case FrequencyMonthly:
+		months = 1
+	case FrequencyYearly:
+		years = 1
+	case "":
+		if now.Before(startTime) {
+			return nil, &Period{StartTime: startTime, EndTime: endTime}, nil
+		}
+
+		if now.Before(endTime) {
+			return &Period{StartTime: startTime, EndTime: endTime}, nil, nil
+		}
+
+		return nil, nil, nil
+	default:
+		return nil, nil, fmt.Errorf(`invalid freq %q: It must be one of "Daily", "Weekly", "Monthly", and "Yearly"`, recurrenceRule.Frequency)
+	}
+
+	duration := endTime.Sub(startTime)
+
+	var active, upcoming *Period
+
+	for i := 0; ; i++ {
+		s := startTime.AddDate(i*years, i*months, i*days)
+
+		if !recurrenceRule.UntilTime.IsZero() && s.After(recurrenceRule.UntilTime) {
+			break
+		}
+
+		e := s.Add(duration)
+
+		if now.Before(s) {
+			upcoming = &Period{StartTime: s, EndTime: e}
+
+			break
+		}
+
+		if now.Before(e) {
+			active = &Period{StartTime: s, EndTime: e}
+		}
+	}
+
+	return active, upcoming, nil
+}
@@ -0,0 +1,207 @@
+package controllers
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func TestMatchSchedule(t *testing.T) {
+	mustParse := func(s string) time.Time {
+		v, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			panic(err)
+		}
+		return v
+	}
+
+	testcases := []struct {
+		now      string
+		start    string
+		end      string
+		freq     string
+		until    string
+		active   string
+		upcoming string
+		err      bool
+	}{
+		// Not recurring, before, during, at the end of, and after the period
+		{
+			now:      "2021-05-08T00:00:00Z",
+			start:    "2021-05-08T01:00:00Z",
+			end:      "2021-05-08T02:00:00Z",
+			upcoming: "2021-05-08T01:00:00Z-2021-05-08T02:00:00Z",
+		},
+		{
+			now:    "2021-05-08T01:30:00Z",
+			start:  "2021-05-08T01:00:00Z",
+			end:    "2021-05-08T02:00:00Z",
+			active: "2021-05-08T01:00:00Z-2021-05-08T02:00:00Z",
+		},
+		{
+			now:   "2021-05-08T02:00:00Z",
+			start: "2021-05-08T01:00:00Z",
+			end:   "2021-05-08T02:00:00Z",
+		},
+		{
+			now:   "2021-05-09T00:00:00Z",
+			start: "2021-05-08T01:00:00Z",
+			end:   "2021-05-08T02:00:00Z",
+		},
+		// Daily
+		{
+			now:      "2021-05-10T01:30:00Z",
+			start:    "2021-05-08T01:00:00Z",
+			end:      "2021-05-08T02:00:00Z",
+			freq:     "Daily",
+			active:   "2021-05-10T01:00:00Z-2021-05-10T02:00:00Z",
+			upcoming: "2021-05-11T01:00:00Z-2021-05-11T02:00:00Z",
+		},
+		{
+			now:      "2021-05-10T03:00:00Z",
+			start:    "2021-05-08T01:00:00Z",
+			end:      "2021-05-08T02:00:00Z",
+			freq:     "Daily",
+			upcoming: "2021-05-11T01:00:00Z-2021-05-11T02:00:00Z",
+		},
+		// Weekly, until the next occurrence
+		{
+			now:    "2021-05-15T01:30:00Z",
+			start:  "2021-05-08T01:00:00Z",
+			end:    "2021-05-08T02:00:00Z",
+			freq:   "Weekly",
+			until:  "2021-05-16T00:00:00Z",
+			active: "2021-05-15T01:00:00Z-2021-05-15T02:00:00Z",
+		},
+		// Monthly
+		{
+			now:      "2021-06-01T00:00:00Z",
+			start:    "2021-05-08T01:00:00Z",
+			end:      "2021-05-08T02:00:00Z",
+			freq:     "Monthly",
+			upcoming: "2021-06-08T01:00:00Z-2021-06-08T02:00:00Z",
+		},
+		// Yearly
+		{
+			now:      "2022-05-08T01:00:00Z",
+			start:    "2021-05-08T01:00:00Z",
+			end:      "2021-05-08T02:00:00Z",
+			freq:     "Yearly",
+			active:   "2022-05-08T01:00:00Z-2022-05-08T02:00:00Z",
+			upcoming: "2023-05-08T01:00:00Z-2023-05-08T02:00:00Z",
+		},
+		// Invalid
+		{
+			now:   "2021-05-08T00:00:00Z",
+			start: "2021-05-08T01:00:00Z",
+			end:   "2021-05-08T02:00:00Z",
+			freq:  "Hourly",
+			err:   true,
+		},
+		{
+			now:   "2021-05-08T00:00:00Z",
+			start: "2021-05-08T02:00:00Z",
+			end:   "2021-05-08T01:00:00Z",
+			err:   true,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			var until time.Time
+			if tc.until != "" {
+				until = mustParse(tc.until)
+			}
+
+			active, upcoming, err := MatchSchedule(mustParse(tc.now), mustParse(tc.start), mustParse(tc.end), RecurrenceRule{
+				Frequency: tc.freq,
+				UntilTime: until,
+			})
+			if tc.err {
+				if err == nil {
+					t.Fatal("expected error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := active.String(); got != tc.active {
+				t.Errorf("unexpected active period: want %q, got %q", tc.active, got)
+			}
+
+			if got := upcoming.String(); got != tc.upcoming {
+				t.Errorf("unexpected upcoming period: want %q, got %q", tc.upcoming, got)
+			}
+		})
+	}
+}
+
+func TestMatchScheduledOverrides_EarliestDefinedWins(t *testing.T) {
+	now := time.Now()
+
+	hra := v1alpha1.HorizontalRunnerAutoscaler{
+		Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+			MinReplicas: intPtr(1),
+			MaxReplicas: intPtr(10),
+			ScheduledOverrides: []v1alpha1.ScheduledOverride{
+				{
+					StartTime:   metav1.Time{Time: now.Add(-time.Hour)},
+					EndTime:     metav1.Time{Time: now.Add(time.Hour)},
+					MinReplicas: intPtr(3),
+				},
+				{
+					StartTime:   metav1.Time{Time: now.Add(-time.Minute)},
+					EndTime:     metav1.Time{Time: now.Add(time.Minute)},
+					MinReplicas: intPtr(5),
+					MaxReplicas: intPtr(5),
+				},
+				{
+					StartTime:   metav1.Time{Time: now.Add(30 * time.Minute)},
+					EndTime:     metav1.Time{Time: now.Add(2 * time.Hour)},
+					MinReplicas: intPtr(0),
+				},
+			},
+		},
+	}
+
+	r := &HorizontalRunnerAutoscalerReconciler{}
+
+	log := zap.New(func(o *zap.Options) {
+		o.Development = true
+	})
+
+	override, active, upcoming, err := r.matchScheduledOverrides(log, now, hra)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if override == nil || *override.MinReplicas != 3 {
+		t.Fatalf("unexpected override: want the first one, got %+v", override)
+	}
+
+	if !active.EndTime.Equal(now.Add(time.Hour)) {
+		t.Errorf("unexpected active period: %s", active)
+	}
+
+	if !upcoming.StartTime.Equal(now.Add(30 * time.Minute)) {
+		t.Errorf("unexpected upcoming period: %s", upcoming)
+	}
+
+	st := withScheduledOverride(hra, override)
+
+	if *st.Spec.MinReplicas != 3 || *st.Spec.MaxReplicas != 10 {
+		t.Errorf("unexpected overridden min/max: min=%d, max=%d", *st.Spec.MinReplicas, *st.Spec.MaxReplicas)
+	}
+
+	if next := getNextScheduledOverrideTransition(active, upcoming); !next.Equal(now.Add(30 * time.Minute)) {
+		t.Errorf("unexpected next transition: %s", next)
+	}
+}
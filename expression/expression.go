@@ -0,0 +1,476 @@
+// Package expression implements the small arithmetic expression language used to combine the values of
+// autoscaling metrics, like `max(queue, ceil(busy / 0.7))`.
+//
+// An expression consists of numbers, variables, the binary operators +, -, * and /, the unary -, parentheses,
+// and the functions max, min, ceil and floor. It has no other way to reach outside of the variables given to Eval,
+// so it's safe to evaluate the expressions written by anyone who can create the resources.
+package expression
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// MaxLength is the maximum length of an expression, which bounds the cost of parsing and evaluating it.
+const MaxLength = 1024
+
+// Functions are the names of the functions supported in expressions, which can't be used as variables.
+var Functions = []string{"ceil", "floor", "max", "min"}
+
+// Expression is a parsed expression.
+type Expression struct {
+	root node
+	src  string
+}
+
+// Parse parses the expression.
+func Parse(s string) (*Expression, error) {
+	if len(s) > MaxLength {
+		return nil, fmt.Errorf("expression must be at most %d characters long, but got %d", MaxLength, len(s))
+	}
+
+	p := &parser{src: s}
+
+	p.next()
+
+	root, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind != tokenEOF {
+		return nil, p.errorf("unexpected %s", p.tok)
+	}
+
+	return &Expression{root: root, src: s}, nil
+}
+
+// String returns the expression as it was parsed.
+func (e *Expression) String() string {
+	return e.src
+}
+
+// Variables returns the names of the variables referenced by the expression, sorted and deduplicated.
+func (e *Expression) Variables() []string {
+	seen := map[string]bool{}
+
+	e.root.variables(seen)
+
+	var vars []string
+	for v := range seen {
+		vars = append(vars, v)
+	}
+
+	sort.Strings(vars)
+
+	return vars
+}
+
+// Eval evaluates the expression with the values of the variables.
+// It fails on referencing a variable missing in vars, and on dividing by zero.
+func (e *Expression) Eval(vars map[string]float64) (float64, error) {
+	return e.root.eval(vars)
+}
+
+type node interface {
+	eval(vars map[string]float64) (float64, error)
+	variables(seen map[string]bool)
+}
+
+type numberNode float64
+
+func (n numberNode) eval(map[string]float64) (float64, error) {
+	return float64(n), nil
+}
+
+func (n numberNode) variables(map[string]bool) {}
+
+type variableNode string
+
+func (n variableNode) eval(vars map[string]float64) (float64, error) {
+	v, ok := vars[string(n)]
+	if !ok {
+		return 0, fmt.Errorf("variable %q has no value", string(n))
+	}
+
+	return v, nil
+}
+
+func (n variableNode) variables(seen map[string]bool) {
+	seen[string(n)] = true
+}
+
+type negateNode struct {
+	x node
+}
+
+func (n negateNode) eval(vars map[string]float64) (float64, error) {
+	x, err := n.x.eval(vars)
+	if err != nil {
+		return 0, err
+	}
+
+	return -x, nil
+}
+
+func (n negateNode) variables(seen map[string]bool) {
+	n.x.variables(seen)
+}
+
+type binaryNode struct {
+	op   byte
+	x, y node
+}
+
+func (n binaryNode) eval(vars map[string]float64) (float64, error) {
+	x, err := n.x.eval(vars)
+	if err != nil {
+		return 0, err
+	}
+
+	y, err := n.y.eval(vars)
+	if err != nil {
+		return 0, err
+	}
+
+	switch n.op {
+	case '+':
+		return x + y, nil
+	case '-':
+		return x - y, nil
+	case '*':
+		return x * y, nil
+	case '/':
+		if y == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+
+		return x / y, nil
+	}
+
+	return 0, fmt.Errorf("unknown operator %q", n.op)
+}
+
+func (n binaryNode) variables(seen map[string]bool) {
+	n.x.variables(seen)
+	n.y.variables(seen)
+}
+
+type callNode struct {
+	fn   string
+	args []node
+}
+
+func (n callNode) eval(vars map[string]float64) (float64, error) {
+	args := make([]float64, len(n.args))
+
+	for i, a := range n.args {
+		v, err := a.eval(vars)
+		if err != nil {
+			return 0, err
+		}
+
+		args[i] = v
+	}
+
+	switch n.fn {
+	case "ceil":
+		return math.Ceil(args[0]), nil
+	case "floor":
+		return math.Floor(args[0]), nil
+	case "max":
+		v := args[0]
+		for _, a := range args[1:] {
+			v = math.Max(v, a)
+		}
+
+		return v, nil
+	case "min":
+		v := args[0]
+		for _, a := range args[1:] {
+			v = math.Min(v, a)
+		}
+
+		return v, nil
+	}
+
+	return 0, fmt.Errorf("unknown function %q", n.fn)
+}
+
+func (n callNode) variables(seen map[string]bool) {
+	for _, a := range n.args {
+		a.variables(seen)
+	}
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenIdent
+	tokenOperator
+	tokenLeftParen
+	tokenRightParen
+	tokenComma
+	tokenInvalid
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of expression"
+	}
+
+	return fmt.Sprintf("%q", t.text)
+}
+
+// parser is a recursive descent parser of the grammar below.
+// The nesting is bounded by MaxLength, as each level takes at least a character.
+//
+//	expr    = term { ("+" | "-") term }
+//	term    = unary { ("*" | "/") unary }
+//	unary   = "-" unary | primary
+//	primary = number | ident | ident "(" expr { "," expr } ")" | "(" expr ")"
+type parser struct {
+	src string
+	pos int
+	tok token
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("at position %d: %s", p.tok.pos+1, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) next() {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+
+	start := p.pos
+
+	if p.pos >= len(p.src) {
+		p.tok = token{kind: tokenEOF, pos: start}
+		return
+	}
+
+	c := p.src[p.pos]
+
+	switch {
+	case c == '+' || c == '-' || c == '*' || c == '/':
+		p.pos++
+		p.tok = token{kind: tokenOperator, text: string(c), pos: start}
+	case c == '(':
+		p.pos++
+		p.tok = token{kind: tokenLeftParen, text: "(", pos: start}
+	case c == ')':
+		p.pos++
+		p.tok = token{kind: tokenRightParen, text: ")", pos: start}
+	case c == ',':
+		p.pos++
+		p.tok = token{kind: tokenComma, text: ",", pos: start}
+	case isDigit(c) || c == '.':
+		for p.pos < len(p.src) && (isDigit(p.src[p.pos]) || p.src[p.pos] == '.') {
+			p.pos++
+		}
+
+		p.tok = token{kind: tokenNumber, text: p.src[start:p.pos], pos: start}
+	case isIdentStart(c):
+		for p.pos < len(p.src) && (isIdentStart(p.src[p.pos]) || isDigit(p.src[p.pos])) {
+			p.pos++
+		}
+
+		p.tok = token{kind: tokenIdent, text: p.src[start:p.pos], pos: start}
+	default:
+		p.pos++
+		p.tok = token{kind: tokenInvalid, text: string(c), pos: start}
+	}
+}
+
+func (p *parser) parseExpr() (node, error) {
+	x, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.tok.kind == tokenOperator && (p.tok.text == "+" || p.tok.text == "-") {
+		op := p.tok.text[0]
+
+		p.next()
+
+		y, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+
+		x = binaryNode{op: op, x: x, y: y}
+	}
+
+	return x, nil
+}
+
+func (p *parser) parseTerm() (node, error) {
+	x, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.tok.kind == tokenOperator && (p.tok.text == "*" || p.tok.text == "/") {
+		op := p.tok.text[0]
+
+		p.next()
+
+		y, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		x = binaryNode{op: op, x: x, y: y}
+	}
+
+	return x, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.tok.kind == tokenOperator && p.tok.text == "-" {
+		p.next()
+
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		return negateNode{x: x}, nil
+	}
+
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	switch p.tok.kind {
+	case tokenNumber:
+		v, err := strconv.ParseFloat(p.tok.text, 64)
+		if err != nil || math.IsInf(v, 0) {
+			return nil, p.errorf("invalid number %s", p.tok)
+		}
+
+		p.next()
+
+		return numberNode(v), nil
+	case tokenIdent:
+		name := p.tok.text
+
+		p.next()
+
+		if p.tok.kind != tokenLeftParen {
+			if isFunction(name) {
+				return nil, p.errorf("function %q must be called with arguments", name)
+			}
+
+			return variableNode(name), nil
+		}
+
+		return p.parseCall(name)
+	case tokenLeftParen:
+		p.next()
+
+		x, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+
+		if p.tok.kind != tokenRightParen {
+			return nil, p.errorf("expected \")\", but got %s", p.tok)
+		}
+
+		p.next()
+
+		return x, nil
+	}
+
+	return nil, p.errorf("unexpected %s", p.tok)
+}
+
+func (p *parser) parseCall(name string) (node, error) {
+	if !isFunction(name) {
+		return nil, p.errorf("unknown function %q, must be one of %s", name, strings.Join(Functions, ", "))
+	}
+
+	// Consume the left parenthesis
+	p.next()
+
+	var args []node
+
+	for {
+		a, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+
+		args = append(args, a)
+
+		if p.tok.kind == tokenComma {
+			p.next()
+			continue
+		}
+
+		if p.tok.kind != tokenRightParen {
+			return nil, p.errorf("expected \",\" or \")\", but got %s", p.tok)
+		}
+
+		p.next()
+
+		break
+	}
+
+	switch name {
+	case "ceil", "floor":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("function %q takes 1 argument, but got %d", name, len(args))
+		}
+	}
+
+	return callNode{fn: name, args: args}, nil
+}
+
+func isFunction(name string) bool {
+	for _, f := range Functions {
+		if f == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsValidVariable returns true when name can be referenced as a variable in expressions.
+func IsValidVariable(name string) bool {
+	if name == "" || isFunction(name) || !isIdentStart(name[0]) {
+		return false
+	}
+
+	for i := 1; i < len(name); i++ {
+		if !isIdentStart(name[i]) && !isDigit(name[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
@@ -0,0 +1,117 @@
+package expression
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEval(t *testing.T) {
+	vars := map[string]float64{"queued": 3, "busy": 7, "_x1": 0.5}
+
+	testcases := []struct {
+		expr string
+		want float64
+		err  string
+	}{
+		{expr: "1", want: 1},
+		{expr: "1.5", want: 1.5},
+		{expr: ".5", want: 0.5},
+		{expr: "queued", want: 3},
+		{expr: "queued + busy * 2", want: 17},
+		{expr: "(queued + busy) * 2", want: 20},
+		{expr: "busy - queued - 1", want: 3},
+		{expr: "busy / 2 / 2", want: 1.75},
+		{expr: "-queued", want: -3},
+		{expr: "--queued", want: 3},
+		{expr: "busy - -queued", want: 10},
+		{expr: "_x1 * 4", want: 2},
+		{expr: "max(queued, ceil(busy / 0.7))", want: 10},
+		{expr: "max(queued)", want: 3},
+		{expr: "min(queued, busy, 1)", want: 1},
+		{expr: "ceil(busy / 2)", want: 4},
+		{expr: "floor(busy / 2)", want: 3},
+		{expr: " max ( queued , busy ) ", want: 7},
+		{expr: "queued / (busy - 7)", err: "division by zero"},
+		{expr: "unknown + 1", err: `variable "unknown" has no value`},
+		{expr: "", err: "unexpected end of expression"},
+		{expr: "1 +", err: "at position 4: unexpected end of expression"},
+		{expr: "1 2", err: `unexpected "2"`},
+		{expr: "(1", err: `expected ")"`},
+		{expr: "max(1", err: `expected "," or ")"`},
+		{expr: "max()", err: `unexpected ")"`},
+		{expr: "max", err: `function "max" must be called with arguments`},
+		{expr: "ceil(1, 2)", err: `function "ceil" takes 1 argument, but got 2`},
+		{expr: "round(1)", err: `unknown function "round"`},
+		{expr: "queued % 2", err: `unexpected "%"`},
+		{expr: "1.2.3", err: "invalid number"},
+		{expr: "1" + strings.Repeat(" ", MaxLength), err: "must be at most 1024 characters long"},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+
+		t.Run(tc.expr, func(t *testing.T) {
+			e, err := Parse(tc.expr)
+			if err == nil {
+				var got float64
+
+				got, err = e.Eval(vars)
+				if err == nil {
+					if tc.err != "" {
+						t.Fatalf("expected error containing %q, got none", tc.err)
+					}
+
+					if got != tc.want {
+						t.Errorf("incorrect result: want %v, got %v", tc.want, got)
+					}
+
+					return
+				}
+			}
+
+			if tc.err == "" {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("expected error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestVariables(t *testing.T) {
+	e, err := Parse("max(queued, ceil(busy / 0.7), queued + 1)")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want, got := []string{"busy", "queued"}, e.Variables(); !reflect.DeepEqual(want, got) {
+		t.Errorf("incorrect variables: want %v, got %v", want, got)
+	}
+
+	if want, got := "max(queued, ceil(busy / 0.7), queued + 1)", e.String(); want != got {
+		t.Errorf("incorrect string: want %q, got %q", want, got)
+	}
+}
+
+func TestIsValidVariable(t *testing.T) {
+	testcases := map[string]bool{
+		"queued":   true,
+		"_queued":  true,
+		"queued_2": true,
+		"Queued":   true,
+		"":         false,
+		"2queued":  false,
+		"queued-2": false,
+		"max":      false,
+		"ceil":     false,
+	}
+
+	for name, want := range testcases {
+		if got := IsValidVariable(name); got != want {
+			t.Errorf("IsValidVariable(%q): want %v, got %v", name, want, got)
+		}
+	}
+}
@@ -21,6 +21,23 @@ const (
     {"id": 2, "name": "test2", "os": "linux", "status": "offline", "busy": false}
   ]
 }
+`
+
+	RunnerGroupsListBody = `
+{
+  "total_count": 2,
+  "runner_groups": [
+    {"id": 1, "name": "Default", "visibility": "all", "default": true},
+    {"id": 2, "name": "test-group", "visibility": "selected", "default": false}
+  ]
+}
+`
+
+	EmptyRunnersListBody = `
+{
+  "total_count": 0,
+  "runners": []
+}
 `
 )
 
@@ -87,6 +104,11 @@ func NewServer(opts ...Option) *httptest.Server {
 		o(&config)
 	}
 
+	listRunnerGroupRunners := config.FixedResponses.ListRunnerGroupRunners
+	if listRunnerGroupRunners == nil {
+		listRunnerGroupRunners = DefaultListRunnersHandler()
+	}
+
 	routes := map[string]http.Handler{
 		// For CreateRegistrationToken
 		"/repos/test/valid/actions/runners/registration-token": &Handler{
@@ -199,11 +221,44 @@ func NewServer(opts ...Option) *httptest.Server {
 			Body:   "",
 		},
 
+		// For auto-scaling based on the utilization of the runner group
+		"/orgs/test/actions/runner-groups": &Handler{
+			Status: http.StatusOK,
+			Body:   RunnerGroupsListBody,
+		},
+		"/orgs/test/actions/runner-groups/1/runners": &Handler{
+			Status: http.StatusOK,
+			Body:   EmptyRunnersListBody,
+		},
+		"/orgs/test/actions/runner-groups/2/runners": listRunnerGroupRunners,
+		"/enterprises/test/actions/runner-groups": &Handler{
+			Status: http.StatusOK,
+			Body:   RunnerGroupsListBody,
+		},
+		"/enterprises/test/actions/runner-groups/1/runners": &Handler{
+			Status: http.StatusOK,
+			Body:   EmptyRunnersListBody,
+		},
+		"/enterprises/test/actions/runner-groups/2/runners": listRunnerGroupRunners,
+		"/orgs/error/actions/runner-groups": &Handler{
+			Status: http.StatusBadRequest,
+			Body:   "",
+		},
+
 		// For auto-scaling based on the number of queued(pending) workflow runs
 		"/repos/test/valid/actions/runs": config.FixedResponses.ListRepositoryWorkflowRuns,
 
 		// For auto-scaling based on the number of queued(pending) workflow jobs
 		"/repos/test/valid/actions/runs/": config.FixedResponses.ListWorkflowJobs,
+
+		// For weighting the workflow runs by the durations of their completed runs
+		"/repos/test/valid/actions/workflows/": config.FixedResponses.ListWorkflowRunsByID,
+
+		// For auto-scaling based on the number of recent push and pull_request events
+		"/repos/test/valid/events": config.FixedResponses.ListRepositoryEvents,
+
+		// For listing the workflow runs via GitHub GraphQL API
+		"/graphql": config.FixedResponses.GraphQL,
 	}
 
 	mux := http.NewServeMux()
@@ -5,7 +5,11 @@ import "net/http"
 type FixedResponses struct {
 	ListRepositoryWorkflowRuns *Handler
 	ListWorkflowJobs           *MapHandler
+	ListWorkflowRunsByID       *MapHandler
 	ListRunners                http.Handler
+	ListRunnerGroupRunners     http.Handler
+	ListRepositoryEvents       *Handler
+	GraphQL                    *Handler
 }
 
 type Option func(*ServerConfig)
@@ -32,6 +36,16 @@ func WithListWorkflowJobsResponse(status int, bodies map[int]string) Option {
 	}
 }
 
+// WithListWorkflowRunsByIDResponse sets the responses for listing the runs of the workflows of the repository "test/valid", keyed by the workflow ID.
+func WithListWorkflowRunsByIDResponse(status int, bodies map[int]string) Option {
+	return func(c *ServerConfig) {
+		c.FixedResponses.ListWorkflowRunsByID = &MapHandler{
+			Status: status,
+			Bodies: bodies,
+		}
+	}
+}
+
 func WithListRunnersResponse(status int, body string) Option {
 	return func(c *ServerConfig) {
 		c.FixedResponses.ListRunners = &ListRunnersHandler{
@@ -41,6 +55,36 @@ func WithListRunnersResponse(status int, body string) Option {
 	}
 }
 
+// WithListRunnerGroupRunnersResponse sets the response for listing the runners of the runner group named "test-group".
+func WithListRunnerGroupRunnersResponse(status int, body string) Option {
+	return func(c *ServerConfig) {
+		c.FixedResponses.ListRunnerGroupRunners = &ListRunnersHandler{
+			Status: status,
+			Body:   body,
+		}
+	}
+}
+
+// WithListRepositoryEventsResponse sets the response for listing the events of the repository "test/valid".
+func WithListRepositoryEventsResponse(status int, body string) Option {
+	return func(c *ServerConfig) {
+		c.FixedResponses.ListRepositoryEvents = &Handler{
+			Status: status,
+			Body:   body,
+		}
+	}
+}
+
+// WithGraphQLResponse sets the response for the queries to GitHub GraphQL API.
+func WithGraphQLResponse(status int, body string) Option {
+	return func(c *ServerConfig) {
+		c.FixedResponses.GraphQL = &Handler{
+			Status: status,
+			Body:   body,
+		}
+	}
+}
+
 func WithFixedResponses(responses *FixedResponses) Option {
 	return func(c *ServerConfig) {
 		c.FixedResponses = responses
@@ -17,11 +17,14 @@ import (
 
 // Config contains configuration for Github client
 type Config struct {
-	EnterpriseURL     string `split_words:"true"`
-	AppID             int64  `split_words:"true"`
-	AppInstallationID int64  `split_words:"true"`
-	AppPrivateKey     string `split_words:"true"`
-	Token             string
+	EnterpriseURL string `split_words:"true"`
+	// EnterpriseUploadURL is the upload URL of GitHub Enterprise Server.
+	// Defaults to the one derived from EnterpriseURL.
+	EnterpriseUploadURL string `split_words:"true"`
+	AppID               int64  `split_words:"true"`
+	AppInstallationID   int64  `split_words:"true"`
+	AppPrivateKey       string `split_words:"true"`
+	Token               string
 }
 
 // Client wraps GitHub client with some additional
@@ -52,6 +55,46 @@ func (c *Config) NewClient() (*Client, error) {
 		}
 		transport = tr
 	}
+
+	return c.newClientWithTransport(transport)
+}
+
+// Credentials are the credentials to authenticate with GitHub API in place of the ones in Config.
+// Either Token, or all of AppID, AppInstallationID and AppPrivateKey must be set.
+type Credentials struct {
+	Token             string
+	AppID             int64
+	AppInstallationID int64
+	// AppPrivateKey is the private key of the GitHub App in PEM.
+	AppPrivateKey []byte
+}
+
+// NewClientWithCredentials creates a Github Client for the same GitHub or GitHub Enterprise Server as NewClient,
+// authenticated with the credentials instead of the ones in the config.
+func (c *Config) NewClientWithCredentials(creds Credentials) (*Client, error) {
+	var transport http.RoundTripper
+	if len(creds.Token) > 0 {
+		transport = oauth2.NewClient(context.Background(), oauth2.StaticTokenSource(&oauth2.Token{AccessToken: creds.Token})).Transport
+	} else {
+		tr, err := ghinstallation.New(http.DefaultTransport, creds.AppID, creds.AppInstallationID, creds.AppPrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("authentication failed: %v", err)
+		}
+		if len(c.EnterpriseURL) > 0 {
+			githubAPIURL, err := getEnterpriseApiUrl(c.EnterpriseURL)
+			if err != nil {
+				return nil, fmt.Errorf("enterprise url incorrect: %v", err)
+			}
+			tr.BaseURL = githubAPIURL
+		}
+		transport = tr
+	}
+
+	return c.newClientWithTransport(transport)
+}
+
+// newClientWithTransport creates a Github Client that authenticates requests via the transport.
+func (c *Config) newClientWithTransport(transport http.RoundTripper) (*Client, error) {
 	transport = metrics.Transport{Transport: transport}
 	httpClient := &http.Client{Transport: transport}
 
@@ -59,7 +102,7 @@ func (c *Config) NewClient() (*Client, error) {
 	var githubBaseURL string
 	if len(c.EnterpriseURL) > 0 {
 		var err error
-		client, err = github.NewEnterpriseClient(c.EnterpriseURL, c.EnterpriseURL, httpClient)
+		client, err = github.NewEnterpriseClient(c.EnterpriseURL, c.getEnterpriseUploadURL(), httpClient)
 		if err != nil {
 			return nil, fmt.Errorf("enterprise client creation failed: %v", err)
 		}
@@ -77,6 +120,31 @@ func (c *Config) NewClient() (*Client, error) {
 	}, nil
 }
 
+// getEnterpriseUploadURL returns the upload URL of GitHub Enterprise Server.
+// go-github appends "api/uploads/" to it, so the one derived from EnterpriseURL is the URL without "api/v3".
+func (c *Config) getEnterpriseUploadURL() string {
+	if len(c.EnterpriseUploadURL) > 0 {
+		return c.EnterpriseUploadURL
+	}
+
+	return strings.TrimSuffix(strings.TrimSuffix(c.EnterpriseURL, "/"), "/api/v3")
+}
+
+// CheckReachability returns an error when GitHub API can't be reached at the base URL,
+// which is useful for catching a misconfigured GitHub Enterprise Server URL at startup.
+func (c *Client) CheckReachability(ctx context.Context) error {
+	req, err := c.Client.NewRequest("GET", "meta", nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err := c.Client.Do(ctx, req, nil); err != nil {
+		return fmt.Errorf("github api at %s is unreachable: %w", c.Client.BaseURL, err)
+	}
+
+	return nil
+}
+
 // GetRegistrationToken returns a registration token tied with the name of repository and runner.
 func (c *Client) GetRegistrationToken(ctx context.Context, enterprise, org, repo, name string) (*github.RegistrationToken, error) {
 	c.mu.Lock()
@@ -247,7 +315,7 @@ func (c *Client) listRepositoryWorkflowRuns(ctx context.Context, user string, re
 		list, res, err := c.Client.Actions.ListRepositoryWorkflowRuns(ctx, user, repoName, &opts)
 
 		if err != nil {
-			return workflowRuns, fmt.Errorf("failed to list workflow runs: %v", err)
+			return workflowRuns, fmt.Errorf("failed to list workflow runs: %w", err)
 		}
 
 		workflowRuns = append(workflowRuns, list.WorkflowRuns...)
@@ -260,6 +328,207 @@ func (c *Client) listRepositoryWorkflowRuns(ctx context.Context, user string, re
 	return workflowRuns, nil
 }
 
+// ListCompletedWorkflowRuns lists the most recently completed runs of the workflow, up to limit.
+// Unlike the other listings, it doesn't page through all the runs, as the recent ones are enough to tell how long a run takes.
+func (c *Client) ListCompletedWorkflowRuns(ctx context.Context, user string, repoName string, workflowID int64, limit int) ([]*github.WorkflowRun, error) {
+	opts := github.ListWorkflowRunsOptions{
+		ListOptions: github.ListOptions{
+			PerPage: limit,
+		},
+		Status: "completed",
+	}
+
+	list, _, err := c.Client.Actions.ListWorkflowRunsByID(ctx, user, repoName, workflowID, &opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list completed workflow runs: %w", err)
+	}
+
+	return list.WorkflowRuns, nil
+}
+
+// ListRepositoryEventsSince lists the events of the repository created at or after since.
+// As GitHub lists the events newest first, it stops paging at the first page reaching an older event.
+func (c *Client) ListRepositoryEventsSince(ctx context.Context, user string, repoName string, since time.Time) ([]*github.Event, error) {
+	var events []*github.Event
+
+	opts := github.ListOptions{
+		PerPage: 100,
+	}
+
+	for {
+		list, res, err := c.Client.Activity.ListRepositoryEvents(ctx, user, repoName, &opts)
+		if err != nil {
+			return events, fmt.Errorf("failed to list repository events: %w", err)
+		}
+
+		var reached bool
+
+		for _, e := range list {
+			if e.GetCreatedAt().Before(since) {
+				reached = true
+
+				continue
+			}
+
+			events = append(events, e)
+		}
+
+		if reached || res.NextPage == 0 {
+			break
+		}
+		opts.Page = res.NextPage
+	}
+
+	return events, nil
+}
+
+// WorkflowJob is github.WorkflowJob with the labels requested by the job via `runs-on`.
+// go-github v33 doesn't support the labels field yet.
+type WorkflowJob struct {
+	github.WorkflowJob
+
+	Labels []string `json:"labels,omitempty"`
+}
+
+type workflowJobs struct {
+	TotalCount *int           `json:"total_count,omitempty"`
+	Jobs       []*WorkflowJob `json:"jobs,omitempty"`
+}
+
+// ListWorkflowJobs lists the jobs of the workflow run, along with their labels.
+func (c *Client) ListWorkflowJobs(ctx context.Context, user string, repoName string, runID int64) ([]*WorkflowJob, error) {
+	var jobs []*WorkflowJob
+
+	page := 1
+
+	for {
+		req, err := c.Client.NewRequest("GET", fmt.Sprintf("repos/%s/%s/actions/runs/%d/jobs?per_page=100&page=%d", user, repoName, runID, page), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var list workflowJobs
+
+		res, err := c.Client.Do(ctx, req, &list)
+		if err != nil {
+			return jobs, fmt.Errorf("failed to list workflow jobs: %w", err)
+		}
+
+		jobs = append(jobs, list.Jobs...)
+		if res.NextPage == 0 {
+			break
+		}
+		page = res.NextPage
+	}
+
+	return jobs, nil
+}
+
+// RunnerGroup is a self-hosted runner group of an organization or an enterprise.
+// go-github v33 doesn't support runner groups yet.
+type RunnerGroup struct {
+	ID         *int64  `json:"id,omitempty"`
+	Name       *string `json:"name,omitempty"`
+	Visibility *string `json:"visibility,omitempty"`
+	Default    *bool   `json:"default,omitempty"`
+}
+
+type runnerGroups struct {
+	TotalCount   *int           `json:"total_count,omitempty"`
+	RunnerGroups []*RunnerGroup `json:"runner_groups,omitempty"`
+}
+
+type RunnerGroupNotFound struct {
+	runnerGroupName string
+}
+
+func (e *RunnerGroupNotFound) Error() string {
+	return fmt.Sprintf("runner group %q not found", e.runnerGroupName)
+}
+
+// ListRunnerGroupRunners lists the runners registered in the runner group of the enterprise or the organization.
+// It returns RunnerGroupNotFound when there's no runner group named name.
+func (c *Client) ListRunnerGroupRunners(ctx context.Context, enterprise, org, name string) ([]*github.Runner, error) {
+	prefix, err := getRunnerGroupsPathPrefix(enterprise, org)
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := c.findRunnerGroup(ctx, prefix, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var runners []*github.Runner
+
+	page := 1
+
+	for {
+		req, err := c.Client.NewRequest("GET", fmt.Sprintf("%s/actions/runner-groups/%d/runners?per_page=100&page=%d", prefix, *group.ID, page), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var list github.Runners
+
+		res, err := c.Client.Do(ctx, req, &list)
+		if err != nil {
+			return runners, fmt.Errorf("failed to list runner group runners: %w", err)
+		}
+
+		runners = append(runners, list.Runners...)
+		if res.NextPage == 0 {
+			break
+		}
+		page = res.NextPage
+	}
+
+	return runners, nil
+}
+
+func (c *Client) findRunnerGroup(ctx context.Context, prefix, name string) (*RunnerGroup, error) {
+	page := 1
+
+	for {
+		req, err := c.Client.NewRequest("GET", fmt.Sprintf("%s/actions/runner-groups?per_page=100&page=%d", prefix, page), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var list runnerGroups
+
+		res, err := c.Client.Do(ctx, req, &list)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list runner groups: %w", err)
+		}
+
+		for _, g := range list.RunnerGroups {
+			if g.ID != nil && g.Name != nil && *g.Name == name {
+				return g, nil
+			}
+		}
+
+		if res.NextPage == 0 {
+			break
+		}
+		page = res.NextPage
+	}
+
+	return nil, &RunnerGroupNotFound{runnerGroupName: name}
+}
+
+// getRunnerGroupsPathPrefix returns the path prefix of the runner groups API.
+// Runner groups are available only for organizations and enterprises.
+func getRunnerGroupsPathPrefix(enterprise, org string) (string, error) {
+	if len(org) > 0 {
+		return fmt.Sprintf("orgs/%s", org), nil
+	}
+	if len(enterprise) > 0 {
+		return fmt.Sprintf("enterprises/%s", enterprise), nil
+	}
+	return "", fmt.Errorf("runner groups are available only for organizations and enterprises")
+}
+
 // Validates enterprise, organisation and repo arguments. Both are optional, but at least one should be specified
 func getEnterpriseOrganisationAndRepo(enterprise, org, repo string) (string, string, string, error) {
 	if len(repo) > 0 {
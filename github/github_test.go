@@ -2,8 +2,18 @@ package github
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -102,6 +112,42 @@ func TestListRunners(t *testing.T) {
 	}
 }
 
+func TestListRunnerGroupRunners(t *testing.T) {
+	tests := []struct {
+		enterprise string
+		org        string
+		group      string
+		length     int
+		notFound   bool
+		err        bool
+	}{
+		{enterprise: "", org: "test", group: "test-group", length: 2, err: false},
+		{enterprise: "", org: "test", group: "Default", length: 0, err: false},
+		{enterprise: "", org: "test", group: "missing", length: 0, notFound: true, err: true},
+		{enterprise: "", org: "error", group: "test-group", length: 0, err: true},
+		{enterprise: "test", org: "", group: "test-group", length: 2, err: false},
+		{enterprise: "", org: "", group: "test-group", length: 0, err: true},
+	}
+
+	client := newTestClient()
+	for i, tt := range tests {
+		runners, err := client.ListRunnerGroupRunners(context.Background(), tt.enterprise, tt.org, tt.group)
+		if !tt.err && err != nil {
+			t.Errorf("[%d] unexpected error: %v", i, err)
+		}
+		if tt.err && err == nil {
+			t.Errorf("[%d] expected error, but got none", i)
+		}
+		var notFound *RunnerGroupNotFound
+		if tt.notFound != errors.As(err, &notFound) {
+			t.Errorf("[%d] unexpected error: %v", i, err)
+		}
+		if tt.length != len(runners) {
+			t.Errorf("[%d] unexpected runners list: %v", i, runners)
+		}
+	}
+}
+
 func TestRemoveRunner(t *testing.T) {
 	tests := []struct {
 		enterprise string
@@ -152,3 +198,182 @@ func TestCleanup(t *testing.T) {
 		t.Errorf("expired token still exists")
 	}
 }
+
+func TestClientPool(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyFile := filepath.Join(t.TempDir(), "key.pem")
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/app/installations/1", func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, `{"id": 1}`)
+	})
+	mux.HandleFunc("/api/v3/orgs/test/installation", func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, `{"id": 1}`)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message": "Not Found"}`)
+	})
+
+	appServer := httptest.NewServer(mux)
+	defer appServer.Close()
+
+	c := Config{
+		EnterpriseURL: appServer.URL,
+		AppID:         123,
+		AppPrivateKey: keyFile,
+	}
+
+	pool, err := c.NewClientPool()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := context.Background()
+
+	client, err := pool.GetInstallationClient(ctx, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if again, _ := pool.GetInstallationClient(ctx, 1); again != client {
+		t.Errorf("expected the client to be cached")
+	}
+
+	if orgClient, err := pool.GetOrganizationClient(ctx, "test"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if orgClient != client {
+		t.Errorf("expected the client of the installation on the organization")
+	}
+
+	if _, err := pool.GetInstallationClient(ctx, 2); err == nil || !strings.Contains(err.Error(), "github app 123 cannot access installation 2") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if _, err := pool.GetOrganizationClient(ctx, "unknown"); err == nil || !strings.Contains(err.Error(), "github app 123 is not installed on organization unknown") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestEnterpriseClient(t *testing.T) {
+	var paths []string
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/", func(w http.ResponseWriter, req *http.Request) {
+		paths = append(paths, req.URL.Path)
+
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/runner-groups"):
+			fmt.Fprint(w, fake.RunnerGroupsListBody)
+		case strings.HasSuffix(req.URL.Path, "/jobs"):
+			fmt.Fprint(w, `{"total_count": 0, "jobs": []}`)
+		case strings.HasSuffix(req.URL.Path, "/runs"):
+			fmt.Fprint(w, `{"total_count": 0, "workflow_runs": []}`)
+		default:
+			fmt.Fprint(w, fake.RunnersListBody)
+		}
+	})
+	// GitHub Enterprise Server serves the GraphQL API next to the REST API
+	mux.HandleFunc("/api/graphql", func(w http.ResponseWriter, req *http.Request) {
+		paths = append(paths, req.URL.Path)
+
+		fmt.Fprint(w, `{"data": {"repository": {"refs": {"nodes": []}, "pullRequests": {"nodes": []}}}}`)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
+		t.Errorf("unexpected request outside of the enterprise api: %s", req.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	enterpriseServer := httptest.NewServer(mux)
+	defer enterpriseServer.Close()
+
+	tests := []struct {
+		enterpriseURL       string
+		enterpriseUploadURL string
+		wantUploadURL       string
+	}{
+		{enterpriseURL: enterpriseServer.URL, wantUploadURL: enterpriseServer.URL + "/api/uploads/"},
+		{enterpriseURL: enterpriseServer.URL + "/api/v3/", wantUploadURL: enterpriseServer.URL + "/api/uploads/"},
+		{enterpriseURL: enterpriseServer.URL, enterpriseUploadURL: "https://uploads.example.com/", wantUploadURL: "https://uploads.example.com/api/uploads/"},
+	}
+
+	for i, tt := range tests {
+		paths = nil
+
+		c := Config{
+			EnterpriseURL:       tt.enterpriseURL,
+			EnterpriseUploadURL: tt.enterpriseUploadURL,
+			Token:               "token",
+		}
+
+		client, err := c.NewClient()
+		if err != nil {
+			t.Fatalf("[%d] unexpected error: %v", i, err)
+		}
+
+		if want := enterpriseServer.URL + "/api/v3/"; client.BaseURL.String() != want {
+			t.Errorf("[%d] unexpected base url: want %s, got %s", i, want, client.BaseURL)
+		}
+
+		if client.UploadURL.String() != tt.wantUploadURL {
+			t.Errorf("[%d] unexpected upload url: want %s, got %s", i, tt.wantUploadURL, client.UploadURL)
+		}
+
+		if want := enterpriseServer.URL + "/"; client.GithubBaseURL != want {
+			t.Errorf("[%d] unexpected github base url: want %s, got %s", i, want, client.GithubBaseURL)
+		}
+
+		ctx := context.Background()
+
+		if err := client.CheckReachability(ctx); err != nil {
+			t.Errorf("[%d] unexpected error: %v", i, err)
+		}
+		if _, err := client.ListRunners(ctx, "", "test", ""); err != nil {
+			t.Errorf("[%d] unexpected error: %v", i, err)
+		}
+		if _, err := client.ListRunnerGroupRunners(ctx, "", "test", "test-group"); err != nil {
+			t.Errorf("[%d] unexpected error: %v", i, err)
+		}
+		if _, err := client.ListRepositoryWorkflowRuns(ctx, "test", "valid"); err != nil {
+			t.Errorf("[%d] unexpected error: %v", i, err)
+		}
+		if _, err := client.ListWorkflowJobs(ctx, "test", "valid", 1); err != nil {
+			t.Errorf("[%d] unexpected error: %v", i, err)
+		}
+		if _, err := client.ListWorkflowRunsGraphQL(ctx, "test", "valid"); err != nil {
+			t.Errorf("[%d] unexpected error: %v", i, err)
+		}
+
+		if len(paths) == 0 {
+			t.Errorf("[%d] expected requests to the enterprise api", i)
+		}
+	}
+}
+
+func TestCheckReachability(t *testing.T) {
+	unreachable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer unreachable.Close()
+
+	c := Config{
+		EnterpriseURL: unreachable.URL,
+		Token:         "token",
+	}
+
+	client, err := c.NewClient()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := client.CheckReachability(context.Background()); err == nil || !strings.Contains(err.Error(), "is unreachable") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
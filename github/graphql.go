@@ -0,0 +1,188 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// workflowRunsQuery lists the GitHub Actions check suites, each of which is a workflow run, of the heads of the branches
+// and the open pull requests, along with the number of their queued and in-progress check runs, which are the jobs.
+// The jobs are counted by totalCount rather than listed, so that the query stays within the node limit of GitHub GraphQL API.
+const workflowRunsQuery = `query($owner: String!, $name: String!) {
+  repository(owner: $owner, name: $name) {
+    refs(refPrefix: "refs/heads/", first: 100) {
+      pageInfo { hasNextPage }
+      nodes { target { ...workflowRuns } }
+    }
+    pullRequests(states: OPEN, first: 100) {
+      pageInfo { hasNextPage }
+      nodes { commits(last: 1) { nodes { commit { ...workflowRuns } } } }
+    }
+  }
+}
+
+fragment workflowRuns on Commit {
+  checkSuites(first: 20) {
+    nodes {
+      status
+      workflowRun { databaseId workflow { databaseId } }
+      queued: checkRuns(first: 1, filterBy: {status: QUEUED}) { totalCount }
+      inProgress: checkRuns(first: 1, filterBy: {status: IN_PROGRESS}) { totalCount }
+    }
+  }
+}`
+
+// GraphQLWorkflowRun is a queued or in-progress workflow run listed via GitHub GraphQL API.
+type GraphQLWorkflowRun struct {
+	ID         int64
+	WorkflowID int64
+	// Status is the status of the run in the vocabulary of the REST API, like "queued" and "in_progress".
+	Status string
+
+	// QueuedJobs and InProgressJobs are the numbers of the jobs of the run by status.
+	QueuedJobs, InProgressJobs int
+}
+
+type graphQLCheckSuites struct {
+	CheckSuites struct {
+		Nodes []struct {
+			Status      string
+			WorkflowRun *struct {
+				DatabaseID int64 `json:"databaseId"`
+				Workflow   struct {
+					DatabaseID int64 `json:"databaseId"`
+				}
+			}
+			Queued     struct{ TotalCount int }
+			InProgress struct{ TotalCount int }
+		}
+	}
+}
+
+type graphQLPageInfo struct {
+	HasNextPage bool
+}
+
+type workflowRunsQueryResult struct {
+	Data struct {
+		Repository *struct {
+			Refs struct {
+				PageInfo graphQLPageInfo
+				Nodes    []struct {
+					Target graphQLCheckSuites
+				}
+			}
+			PullRequests struct {
+				PageInfo graphQLPageInfo
+				Nodes    []struct {
+					Commits struct {
+						Nodes []struct {
+							Commit graphQLCheckSuites
+						}
+					}
+				}
+			}
+		}
+	}
+	Errors []struct {
+		Message string
+	}
+}
+
+// graphQLURL returns the URL of the GraphQL API of the GitHub or GitHub Enterprise Server the client calls.
+func (c *Client) graphQLURL() string {
+	u := *c.Client.BaseURL
+
+	// GitHub Enterprise Server serves the GraphQL API at /api/graphql, next to the REST API at /api/v3/
+	if strings.HasSuffix(u.Path, "/api/v3/") {
+		u.Path = strings.TrimSuffix(u.Path, "v3/") + "graphql"
+	} else {
+		u.Path += "graphql"
+	}
+
+	return u.String()
+}
+
+// ListWorkflowRunsGraphQL lists the queued and in-progress workflow runs of the repository in a single GitHub GraphQL API call,
+// which the REST API takes a call per page of the runs plus one per run to list its jobs.
+//
+// Only the runs of the heads of the branches and the open pull requests are listed. It returns an error when the repository
+// has more of them than a single query can cover, in which case the caller should fall back to ListRepositoryWorkflowRuns.
+func (c *Client) ListWorkflowRunsGraphQL(ctx context.Context, owner, repoName string) ([]GraphQLWorkflowRun, error) {
+	body := map[string]interface{}{
+		"query": workflowRunsQuery,
+		"variables": map[string]string{
+			"owner": owner,
+			"name":  repoName,
+		},
+	}
+
+	req, err := c.Client.NewRequest("POST", c.graphQLURL(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	var result workflowRunsQueryResult
+
+	if _, err := c.Client.Do(ctx, req, &result); err != nil {
+		return nil, fmt.Errorf("failed to query workflow runs: %w", err)
+	}
+
+	if len(result.Errors) > 0 {
+		return nil, fmt.Errorf("failed to query workflow runs: %s", result.Errors[0].Message)
+	}
+
+	repo := result.Data.Repository
+	if repo == nil {
+		return nil, fmt.Errorf("failed to query workflow runs: repository %s/%s not found", owner, repoName)
+	}
+
+	if repo.Refs.PageInfo.HasNextPage || repo.PullRequests.PageInfo.HasNextPage {
+		return nil, errors.New("failed to query workflow runs: too many branches or open pull requests for a single query")
+	}
+
+	var suites []graphQLCheckSuites
+
+	for _, n := range repo.Refs.Nodes {
+		suites = append(suites, n.Target)
+	}
+
+	for _, n := range repo.PullRequests.Nodes {
+		for _, commit := range n.Commits.Nodes {
+			suites = append(suites, commit.Commit)
+		}
+	}
+
+	var (
+		runs []GraphQLWorkflowRun
+		seen = map[int64]bool{}
+	)
+
+	for _, s := range suites {
+		for _, n := range s.CheckSuites.Nodes {
+			// The check suites of other apps than GitHub Actions have no workflow run
+			if n.WorkflowRun == nil || n.Status == "COMPLETED" {
+				continue
+			}
+
+			// A head of a branch can be the head of a pull request as well
+			if seen[n.WorkflowRun.DatabaseID] {
+				continue
+			}
+
+			seen[n.WorkflowRun.DatabaseID] = true
+
+			runs = append(runs, GraphQLWorkflowRun{
+				ID:             n.WorkflowRun.DatabaseID,
+				WorkflowID:     n.WorkflowRun.Workflow.DatabaseID,
+				Status:         strings.ToLower(n.Status),
+				QueuedJobs:     n.Queued.TotalCount,
+				InProgressJobs: n.InProgress.TotalCount,
+			})
+		}
+	}
+
+	return runs, nil
+}
@@ -0,0 +1,108 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/bradleyfalzon/ghinstallation"
+)
+
+// ClientPool maintains a Github Client per installation of the GitHub App, so that
+// runners of many organizations can be managed under a single GitHub App.
+// Each client caches the installation access token and refreshes it a minute before it expires.
+type ClientPool struct {
+	config        Config
+	appsTransport *ghinstallation.AppsTransport
+
+	// appClient is authenticated as the GitHub App itself, which is used only for looking up installations.
+	appClient *Client
+
+	mu sync.Mutex
+
+	clients map[int64]*Client
+
+	// installationIDs is the installation IDs by organization
+	installationIDs map[string]int64
+}
+
+// NewClientPool creates a ClientPool for the GitHub App specified by the AppID and the AppPrivateKey.
+// The AppInstallationID is ignored.
+func (c *Config) NewClientPool() (*ClientPool, error) {
+	if c.AppID == 0 || c.AppPrivateKey == "" {
+		return nil, fmt.Errorf("github app id and private key are required for per-installation clients")
+	}
+
+	tr, err := ghinstallation.NewAppsTransportKeyFromFile(http.DefaultTransport, c.AppID, c.AppPrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("authentication failed: %v", err)
+	}
+	if len(c.EnterpriseURL) > 0 {
+		githubAPIURL, err := getEnterpriseApiUrl(c.EnterpriseURL)
+		if err != nil {
+			return nil, fmt.Errorf("enterprise url incorrect: %v", err)
+		}
+		tr.BaseURL = githubAPIURL
+	}
+
+	appClient, err := c.newClientWithTransport(tr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ClientPool{
+		config:          *c,
+		appsTransport:   tr,
+		appClient:       appClient,
+		clients:         map[int64]*Client{},
+		installationIDs: map[string]int64{},
+	}, nil
+}
+
+// GetInstallationClient returns the client authenticated as the installation.
+// It returns an error when the installation doesn't exist or the GitHub App can't access it.
+func (p *ClientPool) GetInstallationClient(ctx context.Context, installationID int64) (*Client, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if client, ok := p.clients[installationID]; ok {
+		return client, nil
+	}
+
+	if _, _, err := p.appClient.Apps.GetInstallation(ctx, installationID); err != nil {
+		return nil, fmt.Errorf("github app %d cannot access installation %d: %w", p.config.AppID, installationID, err)
+	}
+
+	client, err := p.config.newClientWithTransport(ghinstallation.NewFromAppsTransport(p.appsTransport, installationID))
+	if err != nil {
+		return nil, err
+	}
+
+	p.clients[installationID] = client
+
+	return client, nil
+}
+
+// GetOrganizationClient returns the client authenticated as the installation on the organization.
+// It returns an error when the GitHub App isn't installed on the organization.
+func (p *ClientPool) GetOrganizationClient(ctx context.Context, org string) (*Client, error) {
+	p.mu.Lock()
+	id, ok := p.installationIDs[org]
+	p.mu.Unlock()
+
+	if !ok {
+		installation, _, err := p.appClient.Apps.FindOrganizationInstallation(ctx, org)
+		if err != nil {
+			return nil, fmt.Errorf("github app %d is not installed on organization %s: %w", p.config.AppID, org, err)
+		}
+
+		id = installation.GetID()
+
+		p.mu.Lock()
+		p.installationIDs[org] = id
+		p.mu.Unlock()
+	}
+
+	return p.GetInstallationClient(ctx, id)
+}
@@ -17,8 +17,10 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
 	"strings"
 	"time"
@@ -31,6 +33,7 @@ import (
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	// +kubebuilder:scaffold:imports
 )
@@ -58,8 +61,32 @@ func main() {
 		ghClient *github.Client
 
 		metricsAddr          string
+		healthProbeAddr      string
+		adminAddr            string
 		enableLeaderElection bool
 		syncPeriod           time.Duration
+		cacheDurationJitter  float64
+		globalMaxReplicas    int
+
+		globalMaxReplicasShareByDemand bool
+
+		hraMaxConcurrentReconciles int
+		metricTimeout              time.Duration
+		runnerListCacheTTL         time.Duration
+		requeueInterval            time.Duration
+		eventVerbosity             string
+		nilReplicasPolicy          string
+		useGraphQL                 bool
+
+		gitHubAPIStalenessWindow time.Duration
+
+		gitHubAPICircuitBreakerThreshold int
+		gitHubAPICircuitBreakerWindow    time.Duration
+		gitHubAPICircuitBreakerCooldown  time.Duration
+
+		gitHubAPIBudgetCallsPerMinute int
+		gitHubAPIBudgetBurst          int
+		gitHubAPIBudgetPartition      string
 
 		runnerImage string
 		dockerImage string
@@ -74,15 +101,36 @@ func main() {
 	}
 
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
+	flag.StringVar(&healthProbeAddr, "health-probe-addr", ":8081", "The address the health probe endpoints /healthz and /readyz bind to.")
+	flag.StringVar(&adminAddr, "admin-addr", controllers.DefaultAdminAddr, "The address the read-only admin endpoint serving the scaling decisions of all the HorizontalRunnerAutoscalers as JSON at /horizontalrunnerautoscalers binds to. It isn't authenticated, so it defaults to localhost. Set to empty to disable")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. Enabling this will ensure there is only one active controller manager.")
 	flag.StringVar(&runnerImage, "runner-image", defaultRunnerImage, "The image name of self-hosted runner container.")
 	flag.StringVar(&dockerImage, "docker-image", defaultDockerImage, "The image name of docker sidecar container.")
+	flag.StringVar(&c.EnterpriseURL, "github-enterprise-url", c.EnterpriseURL, "The URL of GitHub Enterprise Server, like https://github.example.com/. Defaults to GitHub.com")
+	flag.StringVar(&c.EnterpriseUploadURL, "github-enterprise-upload-url", c.EnterpriseUploadURL, "The upload URL of GitHub Enterprise Server. Defaults to the one derived from --github-enterprise-url")
 	flag.StringVar(&c.Token, "github-token", c.Token, "The personal access token of GitHub.")
 	flag.Int64Var(&c.AppID, "github-app-id", c.AppID, "The application ID of GitHub App.")
 	flag.Int64Var(&c.AppInstallationID, "github-app-installation-id", c.AppInstallationID, "The installation ID of GitHub App.")
 	flag.StringVar(&c.AppPrivateKey, "github-app-private-key", c.AppPrivateKey, "The path of a private key file to authenticate as a GitHub App")
 	flag.DurationVar(&syncPeriod, "sync-period", 10*time.Minute, "Determines the minimum frequency at which K8s resources managed by this controller are reconciled. When you use autoscaling, set to a lower value like 10 minute, because this corresponds to the minimum time to react on demand change")
+	flag.Float64Var(&cacheDurationJitter, "cache-duration-jitter", controllers.DefaultCacheDurationJitter, "The fraction of the cache duration of desired replicas computed by HorizontalRunnerAutoscaler, by which each cache expiration is randomly spread to avoid hitting GitHub API for all the HorizontalRunnerAutoscalers at once. Set to a negative value to disable")
+	flag.IntVar(&globalMaxReplicas, "global-max-replicas", 0, "The maximum number of replicas across all the HorizontalRunnerAutoscalers, split among them by their spec.weight. Zero means unlimited")
+	flag.BoolVar(&globalMaxReplicasShareByDemand, "global-max-replicas-share-by-demand", false, "Split --global-max-replicas among the HorizontalRunnerAutoscalers in proportion to their demands multiplied by their spec.weight, rather than to their spec.weight alone, when the demands exceed it")
+	flag.IntVar(&hraMaxConcurrentReconciles, "horizontal-runner-autoscaler-max-concurrent-reconciles", 1, "The maximum number of HorizontalRunnerAutoscalers reconciled concurrently. Raising it reduces the reconciliation lag with many HorizontalRunnerAutoscalers, at the cost of bursts of GitHub API calls that exhaust the rate limit sooner")
+	flag.DurationVar(&metricTimeout, "metric-timeout", controllers.DefaultMetricTimeout, "The timeout of evaluating each autoscaling metric of HorizontalRunnerAutoscaler, including the GitHub API calls made for it. A metric that timed out fails and the autoscaling is retried with the backoff, leaving the replicas as is")
+	flag.DurationVar(&runnerListCacheTTL, "runner-list-cache-ttl", controllers.DefaultRunnerListCacheTTL, "The duration for which a listing of the runners registered to GitHub is reused across the HorizontalRunnerAutoscalers sharing the same organization or runner group. Set to a negative value to disable")
+	flag.DurationVar(&requeueInterval, "requeue-interval", controllers.DefaultRequeueInterval, "The interval at which each HorizontalRunnerAutoscaler is reconciled on success, unless the cache expiration or anything else requeues it sooner, so that the autoscaling reacts without webhooks while the sync period is long. The desired replicas are still served from the cache until it expires. Set to 0 to disable")
+	flag.StringVar(&eventVerbosity, "event-verbosity", string(controllers.DefaultEventVerbosity), "The events emitted on HorizontalRunnerAutoscalers. Off emits none, Changes emits the ones on scaling and the other changes and problems worth noticing, and All also emits one on every scaling decision, including the ones leaving the replicas as is")
+	flag.StringVar(&nilReplicasPolicy, "nil-replicas-policy", string(controllers.DefaultNilReplicasPolicy), "The current replicas assumed by HorizontalRunnerAutoscaler for the RunnerDeployment without spec.replicas. MinReplicas assumes spec.minReplicas of the HorizontalRunnerAutoscaler, or 1 when unset, and Observed adopts status.desiredReplicas of the RunnerDeployment, falling back to MinReplicas until it's recorded")
+	flag.BoolVar(&useGraphQL, "use-graphql", false, "List the queued and in-progress workflow runs of each repository for the TotalNumberOfQueuedAndInProgressWorkflowRuns metric in a single GitHub GraphQL API call, rather than paging through them and listing the jobs of each run over the REST API. Falls back to the REST API when the GraphQL call fails")
+	flag.DurationVar(&gitHubAPIStalenessWindow, "github-api-staleness-window", controllers.DefaultGitHubAPIStalenessWindow, "The duration for which GitHub API calls can keep failing without any success before /readyz reports the controller as not ready")
+	flag.IntVar(&gitHubAPICircuitBreakerThreshold, "github-api-circuit-breaker-threshold", controllers.DefaultGitHubAPICircuitBreakerThreshold, "The number of consecutive failures of GitHub API calls across the HorizontalRunnerAutoscalers within --github-api-circuit-breaker-window that opens the circuit breaker, which skips GitHub API calls and serves the cached desired replicas until --github-api-circuit-breaker-cooldown elapses. Set to zero to disable")
+	flag.DurationVar(&gitHubAPICircuitBreakerWindow, "github-api-circuit-breaker-window", controllers.DefaultGitHubAPICircuitBreakerWindow, "The duration within which the consecutive failures of GitHub API calls are counted for opening the circuit breaker")
+	flag.DurationVar(&gitHubAPICircuitBreakerCooldown, "github-api-circuit-breaker-cooldown", controllers.DefaultGitHubAPICircuitBreakerCooldown, "The duration for which the open circuit breaker skips GitHub API calls before letting a single reconciliation probe GitHub API for recovery")
+	flag.IntVar(&gitHubAPIBudgetCallsPerMinute, "github-api-budget-calls-per-minute", 0, "The number of GitHub API calls per minute each partition of the HorizontalRunnerAutoscalers, given by --github-api-budget-partition, can make for computing the desired replicas, so that a few of them making many calls don't starve the others of the shared rate limit. Zero means unlimited")
+	flag.IntVar(&gitHubAPIBudgetBurst, "github-api-budget-burst", 0, "The number of GitHub API calls each partition can make at once on top of --github-api-budget-calls-per-minute. Defaults to --github-api-budget-calls-per-minute")
+	flag.StringVar(&gitHubAPIBudgetPartition, "github-api-budget-partition", string(controllers.DefaultGitHubAPIBudgetPartition), "The unit sharing a GitHub API budget. HorizontalRunnerAutoscaler gives each HorizontalRunnerAutoscaler a budget of its own, and Organization shares one among the HorizontalRunnerAutoscalers of the same enterprise, organization, or repository owner")
 	flag.Var(&commonRunnerLabels, "common-runner-labels", "Runner labels in the K1=V1,K2=V2,... format that are inherited all the runners created by the controller. See https://github.com/summerwind/actions-runner-controller/issues/321 for more information")
 	flag.Parse()
 
@@ -90,20 +138,57 @@ func main() {
 		o.Development = true
 	})
 
+	hraEventVerbosity, err := controllers.ParseEventVerbosity(eventVerbosity)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+
+	hraNilReplicasPolicy, err := controllers.ParseNilReplicasPolicy(nilReplicasPolicy)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+
 	ghClient, err = c.NewClient()
 	if err != nil {
 		fmt.Fprintln(os.Stderr, "Error: Client creation failed.", err)
 		os.Exit(1)
 	}
 
+	// The pool enables HorizontalRunnerAutoscalers to autoscale runners of any installation of the GitHub App
+	var ghClientPool *github.ClientPool
+	if len(c.Token) == 0 && c.AppID != 0 {
+		ghClientPool, err = c.NewClientPool()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error: Client pool creation failed.", err)
+			os.Exit(1)
+		}
+	}
+
 	ctrl.SetLogger(logger)
 
+	setupLog.Info("Using GitHub API", "baseURL", ghClient.BaseURL.String(), "uploadURL", ghClient.UploadURL.String(), "githubBaseURL", ghClient.GithubBaseURL)
+
+	// A misconfigured GitHub Enterprise Server URL would otherwise surface only as autoscaling and registration failures later
+	if len(c.EnterpriseURL) > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		err := ghClient.CheckReachability(ctx)
+		cancel()
+
+		if err != nil {
+			setupLog.Error(err, "unable to reach GitHub Enterprise Server")
+			os.Exit(1)
+		}
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme:             scheme,
-		MetricsBindAddress: metricsAddr,
-		LeaderElection:     enableLeaderElection,
-		Port:               9443,
-		SyncPeriod:         &syncPeriod,
+		Scheme:                 scheme,
+		MetricsBindAddress:     metricsAddr,
+		HealthProbeBindAddress: healthProbeAddr,
+		LeaderElection:         enableLeaderElection,
+		Port:                   9443,
+		SyncPeriod:             &syncPeriod,
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
@@ -148,12 +233,55 @@ func main() {
 		os.Exit(1)
 	}
 
+	gitHubAPIReachability := &controllers.GitHubAPIReachability{
+		StalenessWindow: gitHubAPIStalenessWindow,
+	}
+
+	var gitHubAPIBudget *controllers.GitHubAPIBudget
+	if gitHubAPIBudgetCallsPerMinute > 0 {
+		partition, err := controllers.ParseGitHubAPIBudgetPartition(gitHubAPIBudgetPartition)
+		if err != nil {
+			setupLog.Error(err, "invalid --github-api-budget-partition")
+			os.Exit(1)
+		}
+
+		gitHubAPIBudget = &controllers.GitHubAPIBudget{
+			CallsPerMinute: gitHubAPIBudgetCallsPerMinute,
+			Burst:          gitHubAPIBudgetBurst,
+			Partition:      partition,
+		}
+	}
+
+	var gitHubAPICircuitBreaker *controllers.GitHubAPICircuitBreaker
+	if gitHubAPICircuitBreakerThreshold > 0 {
+		gitHubAPICircuitBreaker = &controllers.GitHubAPICircuitBreaker{
+			Threshold: gitHubAPICircuitBreakerThreshold,
+			Window:    gitHubAPICircuitBreakerWindow,
+			Cooldown:  gitHubAPICircuitBreakerCooldown,
+		}
+	}
+
 	horizontalRunnerAutoscaler := &controllers.HorizontalRunnerAutoscalerReconciler{
-		Client:        mgr.GetClient(),
-		Log:           ctrl.Log.WithName("controllers").WithName("HorizontalRunnerAutoscaler"),
-		Scheme:        mgr.GetScheme(),
-		GitHubClient:  ghClient,
-		CacheDuration: syncPeriod - 10*time.Second,
+		Client:                         mgr.GetClient(),
+		Log:                            ctrl.Log.WithName("controllers").WithName("HorizontalRunnerAutoscaler"),
+		Scheme:                         mgr.GetScheme(),
+		GitHubClient:                   ghClient,
+		GitHubClientPool:               ghClientPool,
+		GitHubConfig:                   c,
+		CacheDuration:                  syncPeriod - 10*time.Second,
+		CacheDurationJitter:            cacheDurationJitter,
+		GlobalMaxReplicas:              globalMaxReplicas,
+		GlobalMaxReplicasShareByDemand: globalMaxReplicasShareByDemand,
+		GitHubAPIReachability:          gitHubAPIReachability,
+		GitHubAPICircuitBreaker:        gitHubAPICircuitBreaker,
+		GitHubAPIBudget:                gitHubAPIBudget,
+		MaxConcurrentReconciles:        hraMaxConcurrentReconciles,
+		MetricTimeout:                  metricTimeout,
+		RunnerListCacheTTL:             runnerListCacheTTL,
+		RequeueInterval:                requeueInterval,
+		EventVerbosity:                 hraEventVerbosity,
+		NilReplicasPolicy:              hraNilReplicasPolicy,
+		UseGraphQL:                     useGraphQL,
 	}
 
 	if err = horizontalRunnerAutoscaler.SetupWithManager(mgr); err != nil {
@@ -173,8 +301,35 @@ func main() {
 		setupLog.Error(err, "unable to create webhook", "webhook", "RunnerReplicaSet")
 		os.Exit(1)
 	}
+	if err = (&actionsv1alpha1.HorizontalRunnerAutoscaler{}).SetupWebhookWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create webhook", "webhook", "HorizontalRunnerAutoscaler")
+		os.Exit(1)
+	}
 	// +kubebuilder:scaffold:builder
 
+	if adminAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle(controllers.HorizontalRunnerAutoscalerDecisionsPath, &controllers.HorizontalRunnerAutoscalerDecisionsHandler{
+			Reader: mgr.GetClient(),
+			Log:    ctrl.Log.WithName("admin"),
+		})
+
+		if err := mgr.Add(&controllers.AdminServer{Addr: adminAddr, Handler: mux, Log: ctrl.Log.WithName("admin")}); err != nil {
+			setupLog.Error(err, "unable to add admin server")
+			os.Exit(1)
+		}
+	}
+
+	// The liveness check doesn't depend on GitHub API, so that a GitHub outage doesn't result in restarting the controller
+	if err := mgr.AddHealthzCheck("ping", healthz.Ping); err != nil {
+		setupLog.Error(err, "unable to add healthz check")
+		os.Exit(1)
+	}
+	if err := mgr.AddReadyzCheck("github-api", gitHubAPIReachability.Check); err != nil {
+		setupLog.Error(err, "unable to add readyz check")
+		os.Exit(1)
+	}
+
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
 		setupLog.Error(err, "problem running manager")
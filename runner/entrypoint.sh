@@ -67,5 +67,18 @@ for f in runsvc.sh RunnerService.js; do
   sudo mv {patched,bin}/${f}
 done
 
+# Stop the runner when it hasn't picked up any job within RUNNER_IDLE_TIMEOUT seconds.
+# As it runs at most one job due to --once, a worker log in _diag tells that it has picked up one.
+# $$ is still the PID of runsvc.sh after the exec below, which gracefully stops the runner on SIGTERM.
+if [ -n "${RUNNER_IDLE_TIMEOUT}" ]; then
+  (
+    sleep "${RUNNER_IDLE_TIMEOUT}"
+    if ! ls ./_diag/Worker_*.log > /dev/null 2>&1; then
+      echo "Stopping the runner as it has been idle for ${RUNNER_IDLE_TIMEOUT} seconds" 1>&2
+      kill -TERM $$
+    fi
+  ) &
+fi
+
 unset RUNNER_NAME RUNNER_REPO RUNNER_TOKEN
 exec ./bin/runsvc.sh --once